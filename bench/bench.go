@@ -0,0 +1,237 @@
+// Package bench benchmarks the engines with a fixed set of queries, measuring
+// how fast they respond, how many movies they find, how many of their links are
+// dead and how often their pages fail to parse. Runs are kept in a bbolt
+// database so the scoreboard shows which engines are worth using over time
+package bench
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+// DefaultQueries : the queries engines are benchmarked with, common titles most
+// sites have some movies or series for
+var DefaultQueries = []string{"avengers", "spider man", "batman", "love", "the office"}
+
+// Defaults of a Bench
+const (
+	DefaultTimeout    = 30 * time.Second
+	DefaultParallel   = 4
+	DefaultLinkSample = 3
+)
+
+// Result : How an engine did on the queries of a benchmark. Results of several
+// runs add up, see Add
+type Result struct {
+	Engine       string
+	Searches     int
+	Failures     int // searches which returned an error
+	ParseErrors  int // pages and movies which could not be parsed
+	Movies       int
+	LinksChecked int
+	DeadLinks    int
+	Latency      time.Duration `json:"-"` // mean latency of the searches
+	LatencyMS    int64
+	Score        float64 // see Score
+}
+
+// Add : Add the searches and links of other to r, the latency staying the mean
+// of every search
+func (r *Result) Add(other Result) {
+	if searches := r.Searches + other.Searches; searches > 0 {
+		r.Latency = (r.Latency*time.Duration(r.Searches) + other.Latency*time.Duration(other.Searches)) / time.Duration(searches)
+	}
+	r.Searches += other.Searches
+	r.Failures += other.Failures
+	r.ParseErrors += other.ParseErrors
+	r.Movies += other.Movies
+	r.LinksChecked += other.LinksChecked
+	r.DeadLinks += other.DeadLinks
+	r.update()
+}
+
+// update : set the derived fields of r
+func (r *Result) update() {
+	r.LatencyMS = r.Latency.Milliseconds()
+	r.Score = r.score()
+}
+
+// score : How reliable the engine is from 0 to 1, the share of its searches which
+// succeeded times the shares of its movies which could be parsed and of its links
+// which work. 0 when it found nothing
+func (r *Result) score() float64 {
+	if r.Searches == 0 || r.Movies == 0 {
+		return 0
+	}
+	score := float64(r.Searches-r.Failures) / float64(r.Searches)
+	score *= float64(r.Movies) / float64(r.Movies+r.ParseErrors)
+	if r.LinksChecked > 0 {
+		score *= 1 - float64(r.DeadLinks)/float64(r.LinksChecked)
+	}
+	return score
+}
+
+// DeadRatio : the share of the checked links which were dead
+func (r Result) DeadRatio() float64 {
+	if r.LinksChecked == 0 {
+		return 0
+	}
+	return float64(r.DeadLinks) / float64(r.LinksChecked)
+}
+
+// Run : A benchmark of engines at a time
+type Run struct {
+	Time    time.Time
+	Queries []string
+	Results []Result // best first, see Sort
+}
+
+// Sort : Sort results by score, the fastest engines first among those scoring the same
+func Sort(results []Result) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Latency < results[j].Latency
+	})
+}
+
+// Bench : Benchmarks engines with queries
+type Bench struct {
+	Queries    []string      // DefaultQueries when empty
+	Timeout    time.Duration // of every search and link check, DefaultTimeout when 0
+	Parallel   int           // engines benchmarked at once, DefaultParallel when 0
+	LinkSample int           // links of the movies of every search checked, none when 0
+}
+
+// Run : Search every engine for every query, Parallel engines at a time, and
+// check a sample of the links they found. Dead links are also added to the link
+// statistics of the engines used to rank their movies. Each engine searches for
+// one query at a time so it is not slowed by its own searches. The sites are
+// always scraped, cached pages and results would hide how the engines perform
+func (b *Bench) Run(ctx context.Context, engines map[string]engine.Engine) Run {
+	ctx = engine.WithRefresh(ctx)
+	queries := b.Queries
+	if len(queries) == 0 {
+		queries = DefaultQueries
+	}
+	parallel := b.Parallel
+	if parallel <= 0 {
+		parallel = DefaultParallel
+	}
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	run := Run{Time: time.Now(), Queries: queries, Results: make([]Result, len(names))}
+	var wg sync.WaitGroup
+	indexes := make(chan int)
+	for w := 0; w < parallel && w < len(names); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				run.Results[i] = b.benchEngine(ctx, names[i], engines[names[i]], queries)
+			}
+		}()
+	}
+	for i := range names {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	Sort(run.Results)
+	return run
+}
+
+// benchEngine : the result of the engine named name on queries
+func (b *Bench) benchEngine(ctx context.Context, name string, e engine.Engine, queries []string) Result {
+	timeout := b.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	result := Result{Engine: name}
+	var latency time.Duration
+	for _, query := range queries {
+		if ctx.Err() != nil {
+			break
+		}
+		var parseErrors int64
+		searchCtx, cancel := context.WithTimeout(engine.WithParseErrorCount(ctx, &parseErrors), timeout)
+		start := time.Now()
+		found, err := e.Search(searchCtx, query)
+		latency += time.Since(start)
+		cancel()
+		result.Searches++
+		result.ParseErrors += int(atomic.LoadInt64(&parseErrors))
+		if err != nil {
+			result.Failures++
+			if errors.Is(err, engine.ErrParseFailure) {
+				result.ParseErrors++
+			}
+			continue
+		}
+		result.Movies += len(found.Movies)
+		if b.LinkSample <= 0 || len(found.Movies) == 0 {
+			continue
+		}
+		sample := found.Movies
+		if len(sample) > b.LinkSample {
+			sample = sample[:b.LinkSample]
+		}
+		for j := range sample {
+			if sample[j].Source == "" {
+				sample[j].Source = name
+			}
+			// only the main link, the links of every episode of a series would
+			// outweigh the movies
+			sample[j].SDownloadLink = nil
+		}
+		_, reports := engine.VerifyLinks(ctx, sample, len(sample), timeout, false)
+		for _, report := range reports {
+			for _, check := range report.Links {
+				result.LinksChecked++
+				if check.Dead {
+					result.DeadLinks++
+				}
+			}
+		}
+	}
+	if result.Searches > 0 {
+		result.Latency = latency / time.Duration(result.Searches)
+	}
+	result.update()
+	return result
+}
+
+// Summarize : The results of every engine over the runs, best first
+func Summarize(runs []Run) []Result {
+	totals := map[string]*Result{}
+	var names []string
+	for _, run := range runs {
+		for _, result := range run.Results {
+			total, ok := totals[result.Engine]
+			if !ok {
+				total = &Result{Engine: result.Engine}
+				totals[result.Engine] = total
+				names = append(names, result.Engine)
+			}
+			total.Add(result)
+		}
+	}
+	sort.Strings(names)
+	results := make([]Result, len(names))
+	for i, name := range names {
+		results[i] = *totals[name]
+	}
+	Sort(results)
+	return results
+}
@@ -0,0 +1,115 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+// fakeEngine : an engine finding movies with links, or failing with err
+type fakeEngine struct {
+	links  []string
+	err    error
+	cached int // searches which could be answered from a cache
+}
+
+func (e *fakeEngine) Search(ctx context.Context, param ...string) (engine.SearchResult, error) {
+	if !engine.Refreshing(ctx) {
+		e.cached++
+	}
+	result := engine.SearchResult{Query: param[0]}
+	for i, link := range e.links {
+		u, _ := url.Parse(link)
+		result.Movies = append(result.Movies, engine.Movie{Index: i, Title: param[0], DownloadLink: u})
+	}
+	return result, e.err
+}
+
+func (e *fakeEngine) List(ctx context.Context, page int) (engine.SearchResult, error) {
+	return e.Search(ctx, "")
+}
+
+func (e *fakeEngine) Capabilities() engine.Capabilities {
+	return engine.Capabilities{}
+}
+
+func (e *fakeEngine) String() string {
+	return "Fake"
+}
+
+func TestBench(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dead.mp4" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	b := &Bench{Queries: []string{"avengers", "batman"}, Timeout: 5 * time.Second, LinkSample: 2}
+	engines := map[string]engine.Engine{
+		"good":   &fakeEngine{links: []string{server.URL + "/1.mp4", server.URL + "/2.mp4", server.URL + "/3.mp4"}},
+		"rotten": &fakeEngine{links: []string{server.URL + "/1.mp4", server.URL + "/dead.mp4"}},
+		"broken": &fakeEngine{err: fmt.Errorf("%w: no movies", engine.ErrParseFailure)},
+	}
+	run := b.Run(context.Background(), engines)
+	if len(run.Results) != 3 {
+		t.Fatalf("Expected a result for every engine, got %+v", run.Results)
+	}
+	good, rotten, broken := run.Results[0], run.Results[1], run.Results[2]
+	if good.Engine != "good" || good.Score != 1 || good.Movies != 6 || good.LinksChecked != 4 || good.DeadLinks != 0 {
+		t.Errorf("Expected the good engine first with every link working, got %+v", good)
+	}
+	if rotten.Engine != "rotten" || rotten.Score != 0.5 || rotten.DeadLinks != 2 {
+		t.Errorf("Expected half of the links of the rotten engine dead, got %+v", rotten)
+	}
+	if broken.Engine != "broken" || broken.Score != 0 || broken.Failures != 2 || broken.ParseErrors != 2 {
+		t.Errorf("Expected the broken engine last with its parse errors, got %+v", broken)
+	}
+	// the sites are searched, not the cached pages or results
+	for name, e := range engines {
+		if cached := e.(*fakeEngine).cached; cached != 0 {
+			t.Errorf("Expected %s to scrape every search, %d could be cached", name, cached)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "bench.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.MaxRuns = 2
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		run := Run{Time: start.Add(time.Duration(i) * time.Minute), Results: []Result{
+			{Engine: "fzmovies", Searches: 5, Failures: i, Movies: 10, Latency: time.Duration(i+1) * time.Second},
+			{Engine: "netnaija", Searches: 5, Movies: 10, Latency: time.Second},
+		}}
+		if err = s.Add(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs, err := s.History(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || !runs[0].Time.Equal(start.Add(2*time.Minute)) {
+		t.Fatalf("Expected the last 2 runs, most recent first, got %+v", runs)
+	}
+
+	results := Summarize(runs)
+	if results[0].Engine != "netnaija" || results[0].Score != 1 {
+		t.Errorf("Expected netnaija first, got %+v", results)
+	}
+	// 3 of the last 10 searches of fzmovies failed, taking 2.5s on average
+	if fz := results[1]; fz.Searches != 10 || fz.Score != 0.7 || fz.Latency != 2500*time.Millisecond {
+		t.Errorf("Expected the results of fzmovies added up, got %+v", fz)
+	}
+}
@@ -0,0 +1,110 @@
+package bench
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var runsBucket = []byte("runs")
+
+// DefaultMaxRuns : runs kept in the history, the oldest are dropped
+const DefaultMaxRuns = 100
+
+// Store : The history of the benchmarks, the database is only opened for the
+// duration of an operation like the jobs
+type Store struct {
+	path    string
+	MaxRuns int // DefaultMaxRuns when 0
+}
+
+// Open : Open (or create) the history stored at path
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := s.withDB(true, func(b *bolt.Bucket) error { return nil }); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// withDB : run fn in a transaction on the runs bucket
+func (s *Store) withDB(writable bool, fn func(*bolt.Bucket) error) error {
+	db, err := bolt.Open(s.path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if !writable {
+		return db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket(runsBucket)
+			if b == nil {
+				return nil
+			}
+			return fn(b)
+		})
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(runsBucket)
+		if err != nil {
+			return err
+		}
+		return fn(b)
+	})
+}
+
+// Add : Add run to the history, dropping the oldest runs past MaxRuns
+func (s *Store) Add(run Run) error {
+	for i := range run.Results {
+		run.Results[i].update()
+	}
+	v, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	// keys sort in the order the runs were made
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(run.Time.UnixNano()))
+	max := s.MaxRuns
+	if max <= 0 {
+		max = DefaultMaxRuns
+	}
+	return s.withDB(true, func(b *bolt.Bucket) error {
+		if err := b.Put(key, v); err != nil {
+			return err
+		}
+		var keys [][]byte
+		b.ForEach(func(k, _ []byte) error {
+			keys = append(keys, append([]byte(nil), k...))
+			return nil
+		})
+		for i := 0; i < len(keys)-max; i++ {
+			if err := b.Delete(keys[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// History : The last limit runs, the most recent first, or every run when limit is 0
+func (s *Store) History(limit int) ([]Run, error) {
+	var runs []Run
+	err := s.withDB(false, func(b *bolt.Bucket) error {
+		c := b.Cursor()
+		for k, v := c.Last(); k != nil && (limit <= 0 || len(runs) < limit); k, v = c.Prev() {
+			var run Run
+			if err := json.Unmarshal(v, &run); err != nil {
+				return err
+			}
+			// latencies are stored in milliseconds
+			for i := range run.Results {
+				run.Results[i].Latency = time.Duration(run.Results[i].LatencyMS) * time.Millisecond
+			}
+			runs = append(runs, run)
+		}
+		return nil
+	})
+	return runs, err
+}
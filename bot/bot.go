@@ -0,0 +1,94 @@
+// Package bot serves the engines to chat platforms, so that a community can
+// search movies and get their download links without running gophie itself
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+// SearchFunc : Searches for movies matching query, e.g engine.SearchAll
+type SearchFunc func(ctx context.Context, query string) (engine.SearchResult, error)
+
+// MaxResults : most results offered for a search
+const MaxResults = 10
+
+// label : a short description of movie e.g "Jumanji (2017) 1080p 1.2 GB - NetNaija"
+func label(movie engine.Movie) string {
+	parts := []string{movie.Title}
+	if movie.Year > 0 && !strings.Contains(movie.Title, fmt.Sprint(movie.Year)) {
+		parts[0] += fmt.Sprintf(" (%d)", movie.Year)
+	}
+	if q := movie.Quality.String(); q != "" && !strings.Contains(movie.Title, q) {
+		parts = append(parts, q)
+	}
+	if movie.SizeBytes > 0 {
+		parts = append(parts, engine.FormatSize(movie.SizeBytes))
+	} else if movie.Size != "" {
+		parts = append(parts, movie.Size)
+	}
+	text := strings.Join(parts, " ")
+	if movie.Source != "" {
+		text += " - " + movie.Source
+	}
+	return text
+}
+
+// links : the download links of movie, those of every episode of series
+func links(movie engine.Movie) []string {
+	var urls []string
+	if movie.DownloadLink != nil {
+		urls = append(urls, movie.DownloadLink.String())
+	}
+	keys := make([]string, 0, len(movie.SDownloadLink))
+	for key := range movie.SDownloadLink {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		link := movie.SDownloadLink[key]
+		if link != nil && (movie.DownloadLink == nil || link.String() != movie.DownloadLink.String()) {
+			urls = append(urls, key+": "+link.String())
+		}
+	}
+	return urls
+}
+
+// results : the results of the latest searches, so that a chosen result can be
+// found again from the short ID sent along with the choices
+type results struct {
+	mu       sync.Mutex
+	next     int
+	searches map[int][]engine.Movie
+	size     int // searches kept
+}
+
+func newResults(size int) *results {
+	return &results{searches: map[int][]engine.Movie{}, size: size}
+}
+
+// add : keep movies, returning the ID of the search
+func (r *results) add(movies []engine.Movie) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next++
+	r.searches[r.next] = movies
+	delete(r.searches, r.next-r.size)
+	return r.next
+}
+
+// get : the movie at index of the search id
+func (r *results) get(id, index int) (engine.Movie, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	movies := r.searches[id]
+	if index < 0 || index >= len(movies) {
+		return engine.Movie{}, false
+	}
+	return movies[index], true
+}
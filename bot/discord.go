@@ -0,0 +1,316 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+)
+
+// DiscordCommand : name of the slash command of the bot, /gophie search <title>
+const DiscordCommand = "gophie"
+
+// most embeds of a reply, each of up to 6000 characters of a message
+const maxDiscordEmbeds = 5
+
+// discordFollowupTimeout : how long a search may take, interaction tokens are valid for 15 minutes
+const discordFollowupTimeout = 10 * time.Minute
+
+// Types of interactions and of the responses to them
+const (
+	discordPing                = 1
+	discordApplicationCommand  = 2
+	discordPong                = 1
+	discordDeferredChannelSend = 5
+)
+
+// option types of slash commands
+const (
+	discordSubCommand = 1
+	discordString     = 3
+)
+
+// Discord : Answers the slash commands of a Discord application, sent to the
+// interactions endpoint URL it is served at. /gophie search <title> searches
+// the engines and replies with an embed of each result
+type Discord struct {
+	PublicKey     ed25519.PublicKey // of the application, verifying the interactions are from Discord
+	ApplicationID string
+	Token         string // of the bot, only needed to register the command
+	Search        SearchFunc
+	BaseURL       string // defaults to https://discord.com/api/v10
+	Client        *http.Client
+
+	pending sync.WaitGroup
+}
+
+// ParseDiscordKey : The public key of an application, hex encoded as shown on
+// the Discord developer portal
+func ParseDiscordKey(key string) (ed25519.PublicKey, error) {
+	b, err := hex.DecodeString(strings.TrimSpace(key))
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, errors.New("Invalid discord public key, copy it from the developer portal")
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+func (d *Discord) String() string {
+	return "discord application " + d.ApplicationID
+}
+
+type discordOption struct {
+	Name    string          `json:"name"`
+	Type    int             `json:"type"`
+	Value   interface{}     `json:"value,omitempty"`
+	Options []discordOption `json:"options,omitempty"`
+}
+
+type discordInteraction struct {
+	Type  int    `json:"type"`
+	Token string `json:"token"`
+	Data  struct {
+		Name    string          `json:"name"`
+		Options []discordOption `json:"options"`
+	} `json:"data"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type discordImage struct {
+	URL string `json:"url"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Thumbnail   *discordImage  `json:"thumbnail,omitempty"`
+	Fields      []discordField `json:"fields,omitempty"`
+}
+
+type discordMessage struct {
+	Content string         `json:"content"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+// endpoint : URL of path in the Discord API
+func (d *Discord) endpoint(path string) string {
+	base := d.BaseURL
+	if base == "" {
+		base = "https://discord.com/api/v10"
+	}
+	return strings.TrimSuffix(base, "/") + path
+}
+
+// call : send body as JSON to path of the Discord API
+func (d *Discord) call(ctx context.Context, method, path string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.endpoint(path), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if d.Token != "" {
+		req.Header.Set("Authorization", "Bot "+d.Token)
+	}
+	resp, err := client(d.Client).Do(req)
+	if err != nil {
+		// the URL of followups holds the interaction token
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("discord: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		message, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("discord responded with %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// RegisterCommands : Register /gophie search <title> as the command of the application
+func (d *Discord) RegisterCommands(ctx context.Context) error {
+	if d.Token == "" {
+		return errors.New("A discord bot token is required to register commands")
+	}
+	command := map[string]interface{}{
+		"name":        DiscordCommand,
+		"description": "Search movies and series",
+		"options": []map[string]interface{}{{
+			"type":        discordSubCommand,
+			"name":        "search",
+			"description": "Search movies and series by title",
+			"options": []map[string]interface{}{{
+				"type":        discordString,
+				"name":        "title",
+				"description": "Title of the movie or series",
+				"required":    true,
+			}},
+		}},
+	}
+	return d.call(ctx, http.MethodPut, fmt.Sprintf("/applications/%s/commands", d.ApplicationID), []interface{}{command})
+}
+
+// verify : whether r is signed by Discord, returning its body
+func (d *Discord) verify(r *http.Request) ([]byte, bool) {
+	signature, err := hex.DecodeString(r.Header.Get("X-Signature-Ed25519"))
+	if err != nil || len(signature) != ed25519.SignatureSize || len(d.PublicKey) != ed25519.PublicKeySize {
+		return nil, false
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, 1<<20))
+	if err != nil {
+		return nil, false
+	}
+	message := append([]byte(r.Header.Get("X-Signature-Timestamp")), body...)
+	return body, ed25519.Verify(d.PublicKey, message, signature)
+}
+
+// ServeHTTP : Answer an interaction. Searches take longer than Discord waits for
+// a response, so they are answered later by editing the deferred response
+func (d *Discord) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, ok := d.verify(r)
+	if !ok {
+		http.Error(w, "Invalid Signature", http.StatusUnauthorized)
+		return
+	}
+	var interaction discordInteraction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		http.Error(w, "Invalid Interaction", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch interaction.Type {
+	case discordPing:
+		fmt.Fprintf(w, `{"type":%d}`, discordPong)
+		return
+	case discordApplicationCommand:
+	default:
+		http.Error(w, "Unsupported Interaction", http.StatusBadRequest)
+		return
+	}
+
+	query := commandQuery(interaction.Data.Options)
+	if interaction.Data.Name != DiscordCommand || query == "" {
+		fmt.Fprint(w, `{"type":4,"data":{"content":"Use /gophie search <title>","flags":64}}`)
+		return
+	}
+	fmt.Fprintf(w, `{"type":%d}`, discordDeferredChannelSend)
+	d.pending.Add(1)
+	go func() {
+		defer d.pending.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), discordFollowupTimeout)
+		defer cancel()
+		if err := d.answer(ctx, interaction.Token, query); err != nil {
+			log.Errorf("Could not answer the discord search for %s: %v", query, err)
+		}
+	}()
+}
+
+// Wait : Wait for the searches still being answered
+func (d *Discord) Wait() {
+	d.pending.Wait()
+}
+
+// commandQuery : the title of /gophie search <title>
+func commandQuery(options []discordOption) string {
+	for _, option := range options {
+		if option.Type == discordSubCommand && option.Name == "search" {
+			for _, arg := range option.Options {
+				if title, ok := arg.Value.(string); ok && arg.Name == "title" {
+					return strings.TrimSpace(title)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// answer : search query and edit the deferred response of the interaction with the results
+func (d *Discord) answer(ctx context.Context, token, query string) error {
+	message := discordMessage{Embeds: []discordEmbed{}}
+	result, err := d.Search(ctx, query)
+	switch {
+	case err != nil:
+		log.Warnf("Discord search for %s failed: %v", query, err)
+		message.Content = "The search failed, try again later"
+	case len(result.Movies) == 0:
+		message.Content = "No results for " + query
+	default:
+		message.Content = fmt.Sprintf("%d results for %s", len(result.Movies), query)
+		for i, movie := range result.Movies {
+			if i == maxDiscordEmbeds {
+				break
+			}
+			message.Embeds = append(message.Embeds, embed(movie))
+		}
+	}
+	return d.call(ctx, http.MethodPatch, fmt.Sprintf("/webhooks/%s/%s/messages/@original", d.ApplicationID, token), message)
+}
+
+// truncate : text cut to at most n characters
+func truncate(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// embed : the embed of movie, showing its cover photo, description, size and download link
+func embed(movie engine.Movie) discordEmbed {
+	e := discordEmbed{
+		Title:       truncate(movie.Title, 256),
+		Description: truncate(movie.Description, 300),
+	}
+	if movie.CoverPhotoLink != "" {
+		e.Thumbnail = &discordImage{URL: movie.CoverPhotoLink}
+	}
+	if movie.DownloadLink != nil && (movie.DownloadLink.Scheme == "http" || movie.DownloadLink.Scheme == "https") {
+		e.URL = movie.DownloadLink.String()
+	}
+	if movie.Year > 0 {
+		e.Fields = append(e.Fields, discordField{Name: "Year", Value: fmt.Sprint(movie.Year), Inline: true})
+	}
+	if q := movie.Quality.String(); q != "" {
+		e.Fields = append(e.Fields, discordField{Name: "Quality", Value: q, Inline: true})
+	}
+	size := engine.FormatSize(movie.SizeBytes)
+	if size == "" {
+		size = movie.Size
+	}
+	if size != "" {
+		e.Fields = append(e.Fields, discordField{Name: "Size", Value: size, Inline: true})
+	}
+	if movie.Source != "" {
+		e.Fields = append(e.Fields, discordField{Name: "Source", Value: movie.Source, Inline: true})
+	}
+	if urls := links(movie); len(urls) > 0 {
+		e.Fields = append(e.Fields, discordField{Name: "Download", Value: truncate(strings.Join(urls, "\n"), 1024)})
+	}
+	return e
+}
@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+func TestDiscord(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var edited []discordMessage
+	var paths []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		var message discordMessage
+		json.NewDecoder(r.Body).Decode(&message)
+		edited = append(edited, message)
+	}))
+	defer api.Close()
+
+	link, _ := url.Parse("https://films.example/jumanji.mp4")
+	magnet, _ := url.Parse("magnet:?xt=urn:btih:c12fe1")
+	d := &Discord{
+		PublicKey:     public,
+		ApplicationID: "app",
+		BaseURL:       api.URL,
+		Search: func(ctx context.Context, query string) (engine.SearchResult, error) {
+			return engine.SearchResult{Query: query, Movies: []engine.Movie{
+				{Title: "Jumanji", Year: 2017, Description: "Four teenagers are sucked into a video game", CoverPhotoLink: "https://films.example/jumanji.jpg", SizeBytes: 700 << 20, DownloadLink: link, Source: "NetNaija"},
+				{Title: "Jumanji 1995", DownloadLink: magnet, Source: "1337x"},
+			}}, nil
+		},
+	}
+	interact := func(body string, sign bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		signature := make([]byte, ed25519.SignatureSize)
+		if sign {
+			signature = ed25519.Sign(private, []byte("1600000000"+body))
+		}
+		r.Header.Set("X-Signature-Ed25519", hex.EncodeToString(signature))
+		r.Header.Set("X-Signature-Timestamp", "1600000000")
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := interact(`{"type":1}`, false); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected unsigned interactions to be rejected, got %d", w.Code)
+	}
+	if w := interact(`{"type":1}`, true); w.Body.String() != `{"type":1}` {
+		t.Errorf("Expected pings to be answered, got %s", w.Body)
+	}
+
+	command := `{"type":2,"token":"interaction","data":{"name":"gophie","options":[{"name":"search","type":1,"options":[{"name":"title","type":3,"value":"Jumanji"}]}]}}`
+	if w := interact(command, true); w.Body.String() != `{"type":5}` {
+		t.Fatalf("Expected the search to be deferred, got %s", w.Body)
+	}
+	d.Wait()
+	if len(edited) != 1 || paths[0] != "PATCH /webhooks/app/interaction/messages/@original" {
+		t.Fatalf("Expected the deferred response to be edited, got %v", paths)
+	}
+	message := edited[0]
+	if message.Content != "2 results for Jumanji" || len(message.Embeds) != 2 {
+		t.Fatalf("Unexpected message %+v", message)
+	}
+	first, second := message.Embeds[0], message.Embeds[1]
+	if first.URL != link.String() || first.Thumbnail == nil || first.Thumbnail.URL != "https://films.example/jumanji.jpg" {
+		t.Errorf("Expected the link and cover photo in the embed, got %+v", first)
+	}
+	var fields []string
+	for _, field := range first.Fields {
+		fields = append(fields, field.Name+"="+field.Value)
+	}
+	if got := strings.Join(fields, ","); got != "Year=2017,Size=700.0 MB,Source=NetNaija,Download="+link.String() {
+		t.Errorf("Unexpected fields %s", got)
+	}
+	// magnet links cannot be the URL of embeds
+	if second.URL != "" || !strings.Contains(second.Fields[len(second.Fields)-1].Value, "magnet:") {
+		t.Errorf("Expected the magnet link in a field, got %+v", second)
+	}
+
+	var registered bytes.Buffer
+	register := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bot token" || r.URL.Path != "/applications/app/commands" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		registered.ReadFrom(r.Body)
+	}))
+	defer register.Close()
+	d.BaseURL, d.Token = register.URL, "token"
+	if err := d.RegisterCommands(context.Background()); err != nil || !strings.Contains(registered.String(), `"name":"gophie"`) {
+		t.Errorf("Expected the command to be registered, got %v %s", err, registered.String())
+	}
+}
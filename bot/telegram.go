@@ -0,0 +1,332 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+)
+
+// MaxTelegramUpload : largest file a Telegram bot may upload
+const MaxTelegramUpload = 50 << 20
+
+// errTooLarge : the file is larger than the bot may upload
+var errTooLarge = errors.New("file is too large to be uploaded")
+
+// telegramPollTimeout : how long a poll for updates waits for one
+const telegramPollTimeout = 30 * time.Second
+
+// Telegram : A Telegram bot searching the engines for the titles it is sent and
+// replying with buttons of the results. Choosing one replies with its download
+// links, or with the file itself when Upload is set and it is small enough
+type Telegram struct {
+	Token   string
+	Search  SearchFunc
+	Upload  bool   // upload files of at most MaxUpload rather than sending their links
+	BaseURL string // defaults to https://api.telegram.org
+	Client  *http.Client
+
+	MaxUpload int64 // defaults to MaxTelegramUpload
+
+	once    sync.Once
+	results *results
+	offset  int
+}
+
+type telegramChat struct {
+	ID int64 `json:"id"`
+}
+
+type telegramMessage struct {
+	MessageID int          `json:"message_id"`
+	Chat      telegramChat `json:"chat"`
+	Text      string       `json:"text"`
+}
+
+type telegramCallback struct {
+	ID      string           `json:"id"`
+	Data    string           `json:"data"`
+	Message *telegramMessage `json:"message"`
+}
+
+// TelegramUpdate : An update of the Telegram Bot API, a message sent to the bot
+// or a button of one of its messages being pressed
+type TelegramUpdate struct {
+	UpdateID      int               `json:"update_id"`
+	Message       *telegramMessage  `json:"message"`
+	CallbackQuery *telegramCallback `json:"callback_query"`
+}
+
+type telegramButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+type telegramKeyboard struct {
+	InlineKeyboard [][]telegramButton `json:"inline_keyboard"`
+}
+
+type telegramReply struct {
+	ChatID                int64             `json:"chat_id"`
+	Text                  string            `json:"text"`
+	ReplyMarkup           *telegramKeyboard `json:"reply_markup,omitempty"`
+	DisableWebPagePreview bool              `json:"disable_web_page_preview,omitempty"`
+}
+
+func (t *Telegram) String() string {
+	return "telegram bot"
+}
+
+// endpoint : URL of a method of the Bot API
+func (t *Telegram) endpoint(method string) string {
+	base := t.BaseURL
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	return fmt.Sprintf("%s/bot%s/%s", strings.TrimSuffix(base, "/"), t.Token, method)
+}
+
+// do : send req to the Bot API, decoding the result into result if not nil
+func (t *Telegram) do(req *http.Request, result interface{}) error {
+	resp, err := client(t.Client).Do(req)
+	if err != nil {
+		// the URL holds the bot token, keep it out of logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram: %w", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		OK          bool
+		Description string
+		Result      json.RawMessage
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("telegram responded with %s", resp.Status)
+	}
+	if !body.OK {
+		return fmt.Errorf("telegram: %s", body.Description)
+	}
+	if result != nil {
+		return json.Unmarshal(body.Result, result)
+	}
+	return nil
+}
+
+// call : call method of the Bot API with params as JSON
+func (t *Telegram) call(ctx context.Context, method string, params, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint(method), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return t.do(req, result)
+}
+
+// Run : Answer the updates of the bot until ctx is done
+func (t *Telegram) Run(ctx context.Context) error {
+	if t.Token == "" {
+		return errors.New("A telegram bot token is required")
+	}
+	var me struct {
+		Username string `json:"username"`
+	}
+	if err := t.call(ctx, "getMe", struct{}{}, &me); err != nil {
+		return err
+	}
+	log.Infof("Telegram bot @%s is running", me.Username)
+	for ctx.Err() == nil {
+		var updates []TelegramUpdate
+		err := t.call(ctx, "getUpdates", map[string]interface{}{
+			"offset":          t.offset,
+			"timeout":         int(telegramPollTimeout.Seconds()),
+			"allowed_updates": []string{"message", "callback_query"},
+		}, &updates)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Warnf("Could not poll telegram updates: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		for _, update := range updates {
+			t.offset = update.UpdateID + 1
+			go t.HandleUpdate(ctx, update)
+		}
+	}
+	return ctx.Err()
+}
+
+// HandleUpdate : Search the title of a message, or reply with the result whose
+// button was pressed
+func (t *Telegram) HandleUpdate(ctx context.Context, update TelegramUpdate) {
+	t.once.Do(func() { t.results = newResults(100) })
+	var err error
+	switch {
+	case update.Message != nil:
+		err = t.handleMessage(ctx, update.Message)
+	case update.CallbackQuery != nil:
+		err = t.handleCallback(ctx, update.CallbackQuery)
+	}
+	if err != nil {
+		log.Errorf("Could not answer telegram update %d: %v", update.UpdateID, err)
+	}
+}
+
+// send : send text to chat
+func (t *Telegram) send(ctx context.Context, chat int64, text string, keyboard *telegramKeyboard) error {
+	return t.call(ctx, "sendMessage", telegramReply{ChatID: chat, Text: text, ReplyMarkup: keyboard, DisableWebPagePreview: true}, nil)
+}
+
+func (t *Telegram) handleMessage(ctx context.Context, message *telegramMessage) error {
+	query := strings.TrimSpace(message.Text)
+	if strings.HasPrefix(query, "/") {
+		// /search title, /start and /help
+		command := strings.SplitN(query, " ", 2)
+		if !strings.HasPrefix(command[0], "/search") || len(command) == 1 {
+			return t.send(ctx, message.Chat.ID, "Send me the title of a movie or series and pick one of the results to get its download link", nil)
+		}
+		query = strings.TrimSpace(command[1])
+	}
+	if query == "" {
+		return nil
+	}
+	result, err := t.Search(ctx, query)
+	if err != nil {
+		log.Warnf("Telegram search for %s failed: %v", query, err)
+		return t.send(ctx, message.Chat.ID, "The search failed, try again later", nil)
+	}
+	movies := result.Movies
+	if len(movies) == 0 {
+		return t.send(ctx, message.Chat.ID, "No results for "+query, nil)
+	}
+	if len(movies) > MaxResults {
+		movies = movies[:MaxResults]
+	}
+	id := t.results.add(movies)
+	keyboard := &telegramKeyboard{}
+	for i, movie := range movies {
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []telegramButton{{
+			Text:         label(movie),
+			CallbackData: fmt.Sprintf("%d:%d", id, i),
+		}})
+	}
+	return t.send(ctx, message.Chat.ID, fmt.Sprintf("%d results for %s", len(result.Movies), query), keyboard)
+}
+
+func (t *Telegram) handleCallback(ctx context.Context, callback *telegramCallback) error {
+	// the button stops showing as loading
+	if err := t.call(ctx, "answerCallbackQuery", map[string]string{"callback_query_id": callback.ID}, nil); err != nil {
+		return err
+	}
+	if callback.Message == nil {
+		return nil
+	}
+	chat := callback.Message.Chat.ID
+	var id, index int
+	if _, err := fmt.Sscanf(callback.Data, "%d:%d", &id, &index); err != nil {
+		return err
+	}
+	movie, ok := t.results.get(id, index)
+	if !ok {
+		return t.send(ctx, chat, "This search is too old, search again", nil)
+	}
+	maxUpload := t.MaxUpload
+	if maxUpload <= 0 {
+		maxUpload = MaxTelegramUpload
+	}
+	if t.Upload && movie.DownloadLink != nil && !movie.IsSeries && movie.SizeBytes > 0 && movie.SizeBytes <= maxUpload {
+		err := t.upload(ctx, chat, movie, maxUpload)
+		if err == nil {
+			return nil
+		}
+		log.Warnf("Could not upload %s to telegram, sending its link: %v", movie.Title, err)
+	}
+	urls := links(movie)
+	if len(urls) == 0 {
+		return t.send(ctx, chat, "No download link was found for "+movie.Title, nil)
+	}
+	return t.send(ctx, chat, label(movie)+"\n\n"+strings.Join(urls, "\n"), nil)
+}
+
+// upload : send the file of movie to chat, streamed from its download link,
+// failing when it is larger than maxUpload
+func (t *Telegram) upload(ctx context.Context, chat int64, movie engine.Movie, maxUpload int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, movie.DownloadLink.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client(t.Client).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download responded with %s", resp.Status)
+	}
+	if resp.ContentLength > maxUpload {
+		return errTooLarge
+	}
+	name := path.Base(movie.DownloadLink.Path)
+	if name == "/" || name == "." || path.Ext(name) == "" {
+		name = movie.Title + ".mp4"
+	}
+
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		form.WriteField("chat_id", strconv.FormatInt(chat, 10))
+		form.WriteField("caption", label(movie))
+		part, err := form.CreateFormFile("document", name)
+		if err == nil {
+			var n int64
+			n, err = io.Copy(part, io.LimitReader(resp.Body, maxUpload+1))
+			if n > maxUpload {
+				err = errTooLarge
+			}
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+	upload, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint("sendDocument"), body)
+	if err != nil {
+		body.Close()
+		return err
+	}
+	upload.Header.Set("Content-Type", form.FormDataContentType())
+	err = t.do(upload, nil)
+	body.Close()
+	return err
+}
+
+func client(c *http.Client) *http.Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c
+}
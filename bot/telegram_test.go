@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+// fakeTelegram : a Bot API recording the calls made to it
+type fakeTelegram struct {
+	mu      sync.Mutex
+	calls   []string
+	replies []telegramReply
+	files   []string
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, method)
+	switch method {
+	case "sendMessage":
+		var reply telegramReply
+		json.NewDecoder(r.Body).Decode(&reply)
+		f.replies = append(f.replies, reply)
+	case "sendDocument":
+		file, header, err := r.FormFile("document")
+		if err != nil || r.FormValue("chat_id") != "42" {
+			fmt.Fprint(w, `{"ok":false,"description":"Bad Request: no document"}`)
+			return
+		}
+		content, _ := ioutil.ReadAll(file)
+		f.files = append(f.files, header.Filename+":"+string(content))
+	}
+	fmt.Fprint(w, `{"ok":true,"result":{}}`)
+}
+
+func TestTelegram(t *testing.T) {
+	api := &fakeTelegram{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "movie file")
+	}))
+	defer files.Close()
+
+	small, _ := url.Parse(files.URL + "/jumanji.mp4")
+	large, _ := url.Parse("https://films.example/jumanji-2160p.mkv")
+	bot := &Telegram{
+		Token:   "token",
+		BaseURL: server.URL,
+		Upload:  true,
+		Search: func(ctx context.Context, query string) (engine.SearchResult, error) {
+			if query != "Jumanji" {
+				return engine.SearchResult{Query: query}, nil
+			}
+			return engine.SearchResult{Query: query, Movies: []engine.Movie{
+				{Title: "Jumanji", Year: 2017, SizeBytes: 10, DownloadLink: small, Source: "NetNaija"},
+				{Title: "Jumanji 2160p", SizeBytes: 4 << 30, DownloadLink: large, Source: "FzMovies"},
+			}}, nil
+		},
+	}
+	ctx := context.Background()
+	message := func(text string) TelegramUpdate {
+		return TelegramUpdate{Message: &telegramMessage{Chat: telegramChat{ID: 42}, Text: text}}
+	}
+
+	bot.HandleUpdate(ctx, message("/search Jumanji"))
+	if len(api.replies) != 1 || api.replies[0].ReplyMarkup == nil || len(api.replies[0].ReplyMarkup.InlineKeyboard) != 2 {
+		t.Fatalf("Expected a button for every result, got %+v", api.replies)
+	}
+	buttons := api.replies[0].ReplyMarkup.InlineKeyboard
+	if buttons[0][0].Text != "Jumanji (2017) 10 B - NetNaija" {
+		t.Errorf("Unexpected button %q", buttons[0][0].Text)
+	}
+
+	choose := func(i int) TelegramUpdate {
+		return TelegramUpdate{CallbackQuery: &telegramCallback{
+			ID:      "callback",
+			Data:    buttons[i][0].CallbackData,
+			Message: &telegramMessage{Chat: telegramChat{ID: 42}},
+		}}
+	}
+	// small files are uploaded, others are linked
+	bot.HandleUpdate(ctx, choose(0))
+	if len(api.files) != 1 || api.files[0] != "jumanji.mp4:movie file" {
+		t.Errorf("Expected the small file to be uploaded, got %v", api.files)
+	}
+	bot.HandleUpdate(ctx, choose(1))
+	if last := api.replies[len(api.replies)-1]; !strings.Contains(last.Text, large.String()) {
+		t.Errorf("Expected the link of the large file, got %q", last.Text)
+	}
+
+	bot.HandleUpdate(ctx, message("Unknown"))
+	if last := api.replies[len(api.replies)-1]; last.Text != "No results for Unknown" {
+		t.Errorf("Unexpected reply %q", last.Text)
+	}
+	want := "sendMessage answerCallbackQuery sendDocument answerCallbackQuery sendMessage sendMessage"
+	if got := strings.Join(api.calls, " "); got != want {
+		t.Errorf("Expected the calls %s, got %s", want, got)
+	}
+
+	// errors do not reveal the token in the URL
+	bot.BaseURL = "http://127.0.0.1:1"
+	if err := bot.Run(ctx); err == nil || strings.Contains(err.Error(), "token") {
+		t.Errorf("Expected the error without the token, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package cast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultTimeout : how long devices are looked for on the network
+const DefaultTimeout = 3 * time.Second
+
+// ErrNoDevice : no device matching the requested one was found
+var ErrNoDevice = errors.New("No cast device found")
+
+// Kind : the protocol spoken by a device
+type Kind string
+
+// Kinds of cast devices
+const (
+	Chromecast Kind = "chromecast"
+	DLNA       Kind = "dlna"
+)
+
+// Device : a renderer on the local network that can play a stream
+type Device struct {
+	Name       string // friendly name shown by the device
+	Kind       Kind
+	Addr       string // host:port of a Chromecast
+	ControlURL string // AVTransport control URL of a DLNA renderer
+}
+
+// Host : the host of the device on the network
+func (d Device) Host() string {
+	if d.Kind == DLNA {
+		if u, err := url.Parse(d.ControlURL); err == nil {
+			return u.Hostname()
+		}
+	}
+	host, _, _ := net.SplitHostPort(d.Addr)
+	return host
+}
+
+func (d Device) String() string {
+	return fmt.Sprintf("%s (%s)", d.Name, d.Kind)
+}
+
+// Player : controls playback on a device
+type Player interface {
+	// Load : Start playing the stream at url on the device
+	Load(ctx context.Context, url, title, contentType string) error
+	Play(ctx context.Context) error
+	Pause(ctx context.Context) error
+	// Seek : Move playback to position from the start of the stream
+	Seek(ctx context.Context, position time.Duration) error
+	// Position : How far playback is from the start of the stream
+	Position(ctx context.Context) (time.Duration, error)
+	Stop(ctx context.Context) error
+	Close() error
+}
+
+// Discover : Look for Chromecast and DLNA devices on the local network for timeout
+// An error is only returned when neither could be searched for
+func Discover(ctx context.Context, timeout time.Duration) ([]Device, error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		devices []Device
+		errs    []error
+	)
+	for _, discover := range []func(context.Context) ([]Device, error){discoverChromecasts, discoverDLNA} {
+		wg.Add(1)
+		go func(discover func(context.Context) ([]Device, error)) {
+			defer wg.Done()
+			found, err := discover(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Debugf("Could not look for cast devices: %v", err)
+				errs = append(errs, err)
+			}
+			devices = append(devices, found...)
+		}(discover)
+	}
+	wg.Wait()
+	if len(errs) == 2 {
+		return nil, errs[0]
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return strings.ToLower(devices[i].Name) < strings.ToLower(devices[j].Name)
+	})
+	return devices, nil
+}
+
+// FindDevice : The device whose name is or contains name, ignoring case
+func FindDevice(devices []Device, name string) (Device, error) {
+	var matches []Device
+	for _, device := range devices {
+		if strings.EqualFold(device.Name, name) {
+			return device, nil
+		}
+		if strings.Contains(strings.ToLower(device.Name), strings.ToLower(name)) {
+			matches = append(matches, device)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Device{}, fmt.Errorf("%w: %s", ErrNoDevice, name)
+	case 1:
+		return matches[0], nil
+	}
+	return Device{}, fmt.Errorf("%s matches %d devices, use the full name", name, len(matches))
+}
+
+// Connect : Connect to the device for playback
+func Connect(ctx context.Context, device Device) (Player, error) {
+	switch device.Kind {
+	case Chromecast:
+		return dialChromecast(ctx, device.Addr)
+	case DLNA:
+		return &dlnaPlayer{controlURL: device.ControlURL}, nil
+	}
+	return nil, fmt.Errorf("Unsupported cast device %s", device)
+}
+
+// LocalIP : The address of this machine the device can reach it at
+func LocalIP(device Device) (net.IP, error) {
+	conn, err := net.Dial("udp", net.JoinHostPort(device.Host(), "9"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
@@ -0,0 +1,256 @@
+package cast
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestClock(t *testing.T) {
+	tests := map[string]time.Duration{
+		"1:02:03":      time.Hour + 2*time.Minute + 3*time.Second,
+		"2:03":         2*time.Minute + 3*time.Second,
+		"45":           45 * time.Second,
+		"0:00:10.500":  10 * time.Second,
+		" 00:01:00 ":   time.Minute,
+		"01:00:00.000": time.Hour,
+	}
+	for clock, position := range tests {
+		if got, err := ParseClock(clock); err != nil || got != position {
+			t.Errorf("ParseClock(%q) = %v, %v, expected %v", clock, got, err, position)
+		}
+	}
+	for _, clock := range []string{"", "a:b", "1:2:3:4", "-5"} {
+		if _, err := ParseClock(clock); err == nil {
+			t.Errorf("Expected %q not to parse", clock)
+		}
+	}
+	if got := formatClock(time.Hour + 2*time.Minute + 3*time.Second); got != "1:02:03" {
+		t.Errorf("Unexpected clock %s", got)
+	}
+}
+
+func TestFindDevice(t *testing.T) {
+	devices := []Device{{Name: "Living Room TV"}, {Name: "Bedroom TV"}, {Name: "Living Room Speaker"}}
+	if d, err := FindDevice(devices, "bedroom"); err != nil || d.Name != "Bedroom TV" {
+		t.Errorf("Expected Bedroom TV, got %v %v", d, err)
+	}
+	if d, err := FindDevice(devices, "living room tv"); err != nil || d.Name != "Living Room TV" {
+		t.Errorf("Expected the device with the full name, got %v %v", d, err)
+	}
+	if _, err := FindDevice(devices, "living"); err == nil {
+		t.Errorf("Expected several matching devices to be refused")
+	}
+	if _, err := FindDevice(devices, "kitchen"); !errors.Is(err, ErrNoDevice) {
+		t.Errorf("Expected no device, got %v", err)
+	}
+}
+
+func TestDLNA(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		actions []string
+		bodies  []string
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/description.xml", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0"><device><friendlyName>Living Room TV</friendlyName>
+<deviceList><device><friendlyName>Renderer</friendlyName><serviceList>
+<service><serviceType>urn:schemas-upnp-org:service:RenderingControl:1</serviceType><controlURL>/rc</controlURL></service>
+<service><serviceType>urn:schemas-upnp-org:service:AVTransport:1</serviceType><controlURL>control/av</controlURL></service>
+</serviceList></device></deviceList></device></root>`)
+	})
+	mux.HandleFunc("/control/av", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		action := r.Header.Get("SOAPAction")
+		mu.Lock()
+		actions = append(actions, action[strings.Index(action, "#")+1:len(action)-1])
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		switch {
+		case strings.Contains(action, "GetPositionInfo"):
+			io.WriteString(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:GetPositionInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1"><Track>1</Track><RelTime>0:01:30</RelTime></u:GetPositionInfoResponse></s:Body></s:Envelope>`)
+		case strings.Contains(action, "Pause"):
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault><detail><UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>701</errorCode><errorDescription>Transition not available</errorDescription></UPnPError></detail></s:Fault></s:Body></s:Envelope>`)
+		}
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	ctx := context.Background()
+	device, err := describeDLNA(ctx, ts.URL+"/description.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.Name != "Renderer" || device.Kind != DLNA || device.ControlURL != ts.URL+"/control/av" {
+		t.Errorf("Unexpected device %+v", device)
+	}
+	if device.Host() != "127.0.0.1" {
+		t.Errorf("Unexpected host %s", device.Host())
+	}
+
+	player, err := Connect(ctx, device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = player.Load(ctx, "http://192.168.1.2:8765/Jumanji.mp4?a=1&b=2", "Jumanji & Co", "video/mp4"); err != nil {
+		t.Fatal(err)
+	}
+	if err = player.Seek(ctx, 90*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if position, err := player.Position(ctx); err != nil || position != 90*time.Second {
+		t.Errorf("Expected position 1m30s, got %v %v", position, err)
+	}
+	if err = player.Pause(ctx); err == nil || !strings.Contains(err.Error(), "Transition not available (701)") {
+		t.Errorf("Expected the UPnP error to be returned, got %v", err)
+	}
+	player.Stop(ctx)
+
+	if strings.Join(actions, ",") != "SetAVTransportURI,Play,Seek,GetPositionInfo,Pause,Stop" {
+		t.Errorf("Unexpected actions %v", actions)
+	}
+	if !strings.Contains(bodies[0], "<CurrentURI>http://192.168.1.2:8765/Jumanji.mp4?a=1&amp;b=2</CurrentURI>") ||
+		!strings.Contains(bodies[0], "&lt;dc:title&gt;Jumanji &amp;amp; Co&lt;/dc:title&gt;") {
+		t.Errorf("Expected the stream and its escaped metadata, got %s", bodies[0])
+	}
+	if !strings.Contains(bodies[2], "<InstanceID>0</InstanceID><Unit>REL_TIME</Unit><Target>0:01:30</Target>") {
+		t.Errorf("Unexpected seek %s", bodies[2])
+	}
+}
+
+func TestParseMDNSResponse(t *testing.T) {
+	name := func(s string) dnsmessage.Name { return dnsmessage.MustNewName(s) }
+	header := func(n string, typ dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name(n), Type: typ, Class: dnsmessage.ClassINET, TTL: 120}
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	b.StartAnswers()
+	b.PTRResource(header(chromecastService, dnsmessage.TypePTR), dnsmessage.PTRResource{PTR: name("Chromecast-abc." + chromecastService)})
+	b.PTRResource(header(chromecastService, dnsmessage.TypePTR), dnsmessage.PTRResource{PTR: name("Chromecast-def." + chromecastService)})
+	b.StartAdditionals()
+	b.SRVResource(header("Chromecast-abc."+chromecastService, dnsmessage.TypeSRV), dnsmessage.SRVResource{Port: 8009, Target: name("abc.local.")})
+	b.TXTResource(header("Chromecast-abc."+chromecastService, dnsmessage.TypeTXT), dnsmessage.TXTResource{TXT: []string{"id=abc", "fn=Living Room TV"}})
+	b.AResource(header("abc.local.", dnsmessage.TypeA), dnsmessage.AResource{A: [4]byte{192, 168, 1, 20}})
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	devices, err := parseMDNSResponse(msg, &net.UDPAddr{IP: net.IPv4(192, 168, 1, 30), Port: 5353})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("Expected 2 devices, got %+v", devices)
+	}
+	if devices[0] != (Device{Name: "Living Room TV", Kind: Chromecast, Addr: "192.168.1.20:8009"}) {
+		t.Errorf("Unexpected device %+v", devices[0])
+	}
+	// without records of its own the device is reached where the response came from
+	if devices[1] != (Device{Name: "Chromecast-def", Kind: Chromecast, Addr: "192.168.1.30:8009"}) {
+		t.Errorf("Unexpected device %+v", devices[1])
+	}
+}
+
+// fakeChromecast : answers the messages of a player like the default media receiver
+func fakeChromecast(t *testing.T, conn net.Conn, received chan<- castMessage) {
+	defer close(received)
+	for {
+		m, err := readCastMessage(conn)
+		if err != nil {
+			return
+		}
+		received <- m
+		var payload map[string]interface{}
+		json.Unmarshal([]byte(m.Payload), &payload)
+		reply := map[string]interface{}{"requestId": payload["requestId"]}
+		switch payload["type"] {
+		case "LAUNCH":
+			reply["type"] = "RECEIVER_STATUS"
+			reply["status"] = map[string]interface{}{
+				"applications": []map[string]interface{}{{"appId": defaultMediaReceiver, "transportId": "web-5"}},
+			}
+		case "LOAD", "PLAY", "PAUSE", "SEEK", "GET_STATUS":
+			reply["type"] = "MEDIA_STATUS"
+			reply["status"] = []map[string]interface{}{{"mediaSessionId": 7, "currentTime": 12.5, "playerState": "PLAYING"}}
+		case "STOP":
+			reply["type"] = "LOAD_FAILED"
+		default:
+			continue
+		}
+		b, _ := json.Marshal(reply)
+		writeCastMessage(conn, castMessage{Source: m.Destination, Destination: m.Source, Namespace: m.Namespace, Payload: string(b)})
+	}
+}
+
+func TestChromecast(t *testing.T) {
+	client, server := net.Pipe()
+	received := make(chan castMessage, 100)
+	go fakeChromecast(t, server, received)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	player, err := newChromecastPlayer(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = player.Load(ctx, "http://192.168.1.2:8765/Jumanji.mp4", "Jumanji", "video/mp4"); err != nil {
+		t.Fatal(err)
+	}
+	if player.transportID != "web-5" || player.sessionID != 7 {
+		t.Errorf("Expected the media session of the receiver app, got %s %d", player.transportID, player.sessionID)
+	}
+	if err = player.Seek(ctx, 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if position, err := player.Position(ctx); err != nil || position != 12500*time.Millisecond {
+		t.Errorf("Expected position 12.5s, got %v %v", position, err)
+	}
+	if err = player.Stop(ctx); err == nil || !strings.Contains(err.Error(), "LOAD_FAILED") {
+		t.Errorf("Expected errors of the receiver to be returned, got %v", err)
+	}
+	player.Close()
+
+	var messages []string
+	for m := range received {
+		var payload map[string]interface{}
+		json.Unmarshal([]byte(m.Payload), &payload)
+		messages = append(messages, m.Destination+" "+payload["type"].(string))
+		if payload["type"] == "SEEK" && (payload["currentTime"] != 30.0 || payload["mediaSessionId"] != 7.0) {
+			t.Errorf("Unexpected seek %s", m.Payload)
+		}
+		if payload["type"] == "LOAD" && !strings.Contains(m.Payload, `"contentId":"http://192.168.1.2:8765/Jumanji.mp4"`) {
+			t.Errorf("Unexpected load %s", m.Payload)
+		}
+	}
+	expected := "receiver-0 CONNECT,receiver-0 LAUNCH,web-5 CONNECT,web-5 LOAD,web-5 SEEK,web-5 GET_STATUS,web-5 STOP,web-5 CLOSE,receiver-0 CLOSE"
+	if strings.Join(messages, ",") != expected {
+		t.Errorf("Unexpected messages\n%s\nexpected\n%s", strings.Join(messages, ","), expected)
+	}
+}
+
+func TestCastMessage(t *testing.T) {
+	m := castMessage{Source: senderID, Destination: receiverID, Namespace: namespaceReceiver, Payload: `{"type":"GET_STATUS"}`}
+	r, w := net.Pipe()
+	go func() {
+		writeCastMessage(w, m)
+		w.Close()
+	}()
+	got, err := readCastMessage(r)
+	if err != nil || got != m {
+		t.Errorf("Expected %+v back, got %+v %v", m, got, err)
+	}
+}
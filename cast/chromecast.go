@@ -0,0 +1,504 @@
+package cast
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	mdnsAddr          = "224.0.0.251:5353"
+	chromecastService = "_googlecast._tcp.local."
+	chromecastPort    = 8009
+
+	// default media receiver app playing plain media urls
+	defaultMediaReceiver = "CC1AD845"
+
+	senderID   = "sender-0"
+	receiverID = "receiver-0"
+
+	namespaceConnection = "urn:x-cast:com.google.cast.tp.connection"
+	namespaceHeartbeat  = "urn:x-cast:com.google.cast.tp.heartbeat"
+	namespaceReceiver   = "urn:x-cast:com.google.cast.receiver"
+	namespaceMedia      = "urn:x-cast:com.google.cast.media"
+
+	maxMessageBytes = 64 << 10
+)
+
+// discoverChromecasts : Find Chromecasts answering an mDNS query until ctx is done
+func discoverChromecasts(ctx context.Context) ([]Device, error) {
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	addr, err := net.ResolveUDPAddr("udp4", mdnsAddr)
+	if err != nil {
+		return nil, err
+	}
+	query, err := mdnsQuery()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.WriteTo(query, addr); err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	}
+
+	var devices []Device
+	seen := map[string]bool{}
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			// the deadline ends the search
+			return devices, nil
+		}
+		found, err := parseMDNSResponse(buf[:n], from)
+		if err != nil {
+			log.Debugf("Ignoring mDNS response from %s: %v", from, err)
+			continue
+		}
+		for _, device := range found {
+			if !seen[device.Addr] {
+				seen[device.Addr] = true
+				devices = append(devices, device)
+			}
+		}
+	}
+}
+
+// mdnsQuery : a query for the Chromecasts on the network
+func mdnsQuery() ([]byte, error) {
+	name, err := dnsmessage.NewName(chromecastService)
+	if err != nil {
+		return nil, err
+	}
+	msg := dnsmessage.Message{
+		Questions: []dnsmessage.Question{{Name: name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}},
+	}
+	return msg.Pack()
+}
+
+// parseMDNSResponse : The Chromecasts announced in an mDNS response sent by from
+func parseMDNSResponse(b []byte, from net.Addr) ([]Device, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(b); err != nil {
+		return nil, err
+	}
+	var (
+		instances []string
+		targets   = map[string]dnsmessage.SRVResource{}
+		names     = map[string]string{}
+		ips       = map[string]net.IP{}
+	)
+	for _, r := range append(msg.Answers, msg.Additionals...) {
+		name := r.Header.Name.String()
+		switch body := r.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if strings.EqualFold(name, chromecastService) {
+				instances = append(instances, body.PTR.String())
+			}
+		case *dnsmessage.SRVResource:
+			targets[name] = *body
+		case *dnsmessage.TXTResource:
+			for _, txt := range body.TXT {
+				if strings.HasPrefix(txt, "fn=") {
+					names[name] = strings.TrimPrefix(txt, "fn=")
+				}
+			}
+		case *dnsmessage.AResource:
+			ips[name] = net.IP(body.A[:])
+		}
+	}
+
+	var devices []Device
+	for _, instance := range instances {
+		host, port := "", chromecastPort
+		if srv, ok := targets[instance]; ok {
+			port = int(srv.Port)
+			if ip, ok := ips[srv.Target.String()]; ok {
+				host = ip.String()
+			}
+		}
+		if host == "" {
+			if udp, ok := from.(*net.UDPAddr); ok {
+				host = udp.IP.String()
+			}
+		}
+		name := names[instance]
+		if name == "" {
+			name = strings.TrimSuffix(instance, "."+chromecastService)
+		}
+		devices = append(devices, Device{
+			Name: name,
+			Kind: Chromecast,
+			Addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		})
+	}
+	return devices, nil
+}
+
+// castMessage : a message of the Chromecast protocol, sent as a protobuf with
+// a string payload
+type castMessage struct {
+	Source      string
+	Destination string
+	Namespace   string
+	Payload     string
+}
+
+// Fields of the CastMessage protobuf
+const (
+	fieldProtocolVersion = 1
+	fieldSourceID        = 2
+	fieldDestinationID   = 3
+	fieldNamespace       = 4
+	fieldPayloadType     = 5
+	fieldPayloadUTF8     = 6
+)
+
+// marshal : The message as a CastMessage protobuf
+func (m castMessage) marshal() []byte {
+	var b []byte
+	b = protowire.AppendTag(b, fieldProtocolVersion, protowire.VarintType)
+	b = protowire.AppendVarint(b, 0) // CASTV2_1_0
+	b = protowire.AppendTag(b, fieldSourceID, protowire.BytesType)
+	b = protowire.AppendString(b, m.Source)
+	b = protowire.AppendTag(b, fieldDestinationID, protowire.BytesType)
+	b = protowire.AppendString(b, m.Destination)
+	b = protowire.AppendTag(b, fieldNamespace, protowire.BytesType)
+	b = protowire.AppendString(b, m.Namespace)
+	b = protowire.AppendTag(b, fieldPayloadType, protowire.VarintType)
+	b = protowire.AppendVarint(b, 0) // STRING
+	b = protowire.AppendTag(b, fieldPayloadUTF8, protowire.BytesType)
+	b = protowire.AppendString(b, m.Payload)
+	return b
+}
+
+// unmarshalCastMessage : Parse a CastMessage protobuf, binary payloads are skipped
+func unmarshalCastMessage(b []byte) (castMessage, error) {
+	var m castMessage
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return m, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ == protowire.BytesType {
+			value, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return m, protowire.ParseError(n)
+			}
+			switch num {
+			case fieldSourceID:
+				m.Source = value
+			case fieldDestinationID:
+				m.Destination = value
+			case fieldNamespace:
+				m.Namespace = value
+			case fieldPayloadUTF8:
+				m.Payload = value
+			}
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return m, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return m, nil
+}
+
+// writeCastMessage : Write m prefixed by its length
+func writeCastMessage(w io.Writer, m castMessage) error {
+	b := m.marshal()
+	frame := make([]byte, 4+len(b))
+	binary.BigEndian.PutUint32(frame, uint32(len(b)))
+	copy(frame[4:], b)
+	_, err := w.Write(frame)
+	return err
+}
+
+// readCastMessage : Read a message prefixed by its length
+func readCastMessage(r io.Reader) (castMessage, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return castMessage{}, err
+	}
+	size := binary.BigEndian.Uint32(length[:])
+	if size > maxMessageBytes {
+		return castMessage{}, fmt.Errorf("Cast message of %d bytes is too large", size)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return castMessage{}, err
+	}
+	return unmarshalCastMessage(b)
+}
+
+// castPayload : the JSON payload of cast messages, only the fields used are parsed
+type castPayload struct {
+	Type      string          `json:"type"`
+	RequestID int             `json:"requestId"`
+	Reason    string          `json:"reason"`
+	Status    json.RawMessage `json:"status"`
+}
+
+// receiverStatus : the status of the receiver listing its running apps
+type receiverStatus struct {
+	Applications []struct {
+		AppID       string `json:"appId"`
+		TransportID string `json:"transportId"`
+	} `json:"applications"`
+}
+
+// mediaStatus : the status of the media playing on the receiver app
+type mediaStatus struct {
+	MediaSessionID int     `json:"mediaSessionId"`
+	CurrentTime    float64 `json:"currentTime"`
+	PlayerState    string  `json:"playerState"`
+}
+
+// chromecastPlayer : plays media on a Chromecast with its default media receiver
+type chromecastPlayer struct {
+	conn net.Conn
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  int
+	waiting map[int]chan castPayload
+	err     error // why the connection was closed
+
+	transportID string // the media receiver app
+	sessionID   int    // the loaded media
+}
+
+// dialChromecast : Connect to the Chromecast at addr
+func dialChromecast(ctx context.Context, addr string) (*chromecastPlayer, error) {
+	dialer := &tls.Dialer{
+		// Chromecasts use self signed certificates
+		Config: &tls.Config{InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return newChromecastPlayer(conn)
+}
+
+// newChromecastPlayer : Player connected to the receiver of a Chromecast over conn
+func newChromecastPlayer(conn net.Conn) (*chromecastPlayer, error) {
+	p := &chromecastPlayer{conn: conn, waiting: map[int]chan castPayload{}}
+	go p.readLoop()
+	if err := p.send(receiverID, namespaceConnection, map[string]interface{}{"type": "CONNECT"}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// readLoop : Answer heartbeats and hand responses to the requests waiting for them
+func (p *chromecastPlayer) readLoop() {
+	for {
+		m, err := readCastMessage(p.conn)
+		if err != nil {
+			p.mu.Lock()
+			p.err = err
+			for id, ch := range p.waiting {
+				close(ch)
+				delete(p.waiting, id)
+			}
+			p.mu.Unlock()
+			return
+		}
+		var payload castPayload
+		if err := json.Unmarshal([]byte(m.Payload), &payload); err != nil {
+			continue
+		}
+		switch {
+		case m.Namespace == namespaceHeartbeat && payload.Type == "PING":
+			p.send(m.Source, namespaceHeartbeat, map[string]interface{}{"type": "PONG"})
+		case m.Namespace == namespaceConnection && payload.Type == "CLOSE":
+			p.conn.Close()
+		case payload.RequestID != 0:
+			p.mu.Lock()
+			ch, ok := p.waiting[payload.RequestID]
+			delete(p.waiting, payload.RequestID)
+			p.mu.Unlock()
+			if ok {
+				ch <- payload
+			}
+		}
+	}
+}
+
+// send : Send payload to destination without waiting for a response
+func (p *chromecastPlayer) send(destination, namespace string, payload interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	return writeCastMessage(p.conn, castMessage{
+		Source:      senderID,
+		Destination: destination,
+		Namespace:   namespace,
+		Payload:     string(b),
+	})
+}
+
+// request : Send payload with a new request id and wait for the response to it
+func (p *chromecastPlayer) request(ctx context.Context, destination, namespace string, payload map[string]interface{}) (castPayload, error) {
+	ch := make(chan castPayload, 1)
+	p.mu.Lock()
+	if p.err != nil {
+		p.mu.Unlock()
+		return castPayload{}, p.err
+	}
+	p.nextID++
+	id := p.nextID
+	p.waiting[id] = ch
+	p.mu.Unlock()
+
+	payload["requestId"] = id
+	if err := p.send(destination, namespace, payload); err != nil {
+		return castPayload{}, err
+	}
+	select {
+	case <-ctx.Done():
+		p.mu.Lock()
+		delete(p.waiting, id)
+		p.mu.Unlock()
+		return castPayload{}, ctx.Err()
+	case response, ok := <-ch:
+		if !ok {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return castPayload{}, fmt.Errorf("Chromecast closed the connection: %v", p.err)
+		}
+		switch response.Type {
+		case "LOAD_FAILED", "LOAD_CANCELLED", "INVALID_REQUEST", "INVALID_PLAYER_STATE", "LAUNCH_ERROR":
+			return response, fmt.Errorf("Chromecast answered %s %s", response.Type, response.Reason)
+		}
+		return response, nil
+	}
+}
+
+// media : Send a media command for the loaded media and keep its status
+func (p *chromecastPlayer) media(ctx context.Context, payload map[string]interface{}) (mediaStatus, error) {
+	if p.transportID == "" {
+		return mediaStatus{}, errors.New("Nothing is playing on the Chromecast")
+	}
+	if p.sessionID != 0 {
+		payload["mediaSessionId"] = p.sessionID
+	}
+	response, err := p.request(ctx, p.transportID, namespaceMedia, payload)
+	if err != nil {
+		return mediaStatus{}, err
+	}
+	var statuses []mediaStatus
+	json.Unmarshal(response.Status, &statuses)
+	if len(statuses) == 0 {
+		return mediaStatus{}, nil
+	}
+	p.sessionID = statuses[0].MediaSessionID
+	return statuses[0], nil
+}
+
+// Load : Start the default media receiver and play the stream at url in it
+func (p *chromecastPlayer) Load(ctx context.Context, url, title, contentType string) error {
+	response, err := p.request(ctx, receiverID, namespaceReceiver, map[string]interface{}{
+		"type":  "LAUNCH",
+		"appId": defaultMediaReceiver,
+	})
+	if err != nil {
+		return err
+	}
+	var status receiverStatus
+	json.Unmarshal(response.Status, &status)
+	for _, app := range status.Applications {
+		if app.AppID == defaultMediaReceiver {
+			p.transportID = app.TransportID
+		}
+	}
+	if p.transportID == "" {
+		return errors.New("Chromecast did not start its media receiver")
+	}
+	if err = p.send(p.transportID, namespaceConnection, map[string]interface{}{"type": "CONNECT"}); err != nil {
+		return err
+	}
+	p.sessionID = 0
+	_, err = p.media(ctx, map[string]interface{}{
+		"type":        "LOAD",
+		"autoplay":    true,
+		"currentTime": 0,
+		"media": map[string]interface{}{
+			"contentId":   url,
+			"contentType": contentType,
+			"streamType":  "BUFFERED",
+			"metadata": map[string]interface{}{
+				"metadataType": 0,
+				"title":        title,
+			},
+		},
+	})
+	return err
+}
+
+// Play : Resume playback
+func (p *chromecastPlayer) Play(ctx context.Context) error {
+	_, err := p.media(ctx, map[string]interface{}{"type": "PLAY"})
+	return err
+}
+
+// Pause : Pause playback
+func (p *chromecastPlayer) Pause(ctx context.Context) error {
+	_, err := p.media(ctx, map[string]interface{}{"type": "PAUSE"})
+	return err
+}
+
+// Seek : Move playback to position
+func (p *chromecastPlayer) Seek(ctx context.Context, position time.Duration) error {
+	_, err := p.media(ctx, map[string]interface{}{"type": "SEEK", "currentTime": position.Seconds()})
+	return err
+}
+
+// Position : How far playback is
+func (p *chromecastPlayer) Position(ctx context.Context) (time.Duration, error) {
+	status, err := p.media(ctx, map[string]interface{}{"type": "GET_STATUS"})
+	return time.Duration(status.CurrentTime * float64(time.Second)), err
+}
+
+// Stop : Stop playback
+func (p *chromecastPlayer) Stop(ctx context.Context) error {
+	_, err := p.media(ctx, map[string]interface{}{"type": "STOP"})
+	return err
+}
+
+// Close : Disconnect from the Chromecast, what is playing keeps playing
+func (p *chromecastPlayer) Close() error {
+	if p.transportID != "" {
+		p.send(p.transportID, namespaceConnection, map[string]interface{}{"type": "CLOSE"})
+	}
+	p.send(receiverID, namespaceConnection, map[string]interface{}{"type": "CLOSE"})
+	return p.conn.Close()
+}
@@ -0,0 +1,276 @@
+package cast
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ssdpAddr            = "239.255.255.250:1900"
+	avTransportService  = "urn:schemas-upnp-org:service:AVTransport:1"
+	soapEnvelopeFormat  = `<?xml version="1.0" encoding="utf-8"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body><u:%[1]s xmlns:u="%[2]s">%[3]s</u:%[1]s></s:Body></s:Envelope>`
+	didlMetadataFormat  = `<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/"><item id="0" parentID="-1" restricted="1"><dc:title>%s</dc:title><upnp:class>object.item.videoItem</upnp:class><res protocolInfo="http-get:*:%s:*">%s</res></item></DIDL-Lite>`
+	ssdpSearchRequest   = "M-SEARCH * HTTP/1.1\r\nHOST: " + ssdpAddr + "\r\nMAN: \"ssdp:discover\"\r\nMX: 2\r\nST: " + avTransportService + "\r\n\r\n"
+	maxDescriptionBytes = 1 << 20
+)
+
+// deviceDescription : the parts of a UPnP device description used to find its AVTransport service
+type deviceDescription struct {
+	URLBase string     `xml:"URLBase"`
+	Device  upnpDevice `xml:"device"`
+}
+
+type upnpDevice struct {
+	FriendlyName string `xml:"friendlyName"`
+	Services     []struct {
+		ServiceType string `xml:"serviceType"`
+		ControlURL  string `xml:"controlURL"`
+	} `xml:"serviceList>service"`
+	Devices []upnpDevice `xml:"deviceList>device"`
+}
+
+// avTransport : the friendly name and control URL of the first AVTransport
+// service of the device or its embedded devices
+func (d upnpDevice) avTransport() (string, string, bool) {
+	for _, service := range d.Services {
+		if strings.HasPrefix(service.ServiceType, "urn:schemas-upnp-org:service:AVTransport:") {
+			return d.FriendlyName, service.ControlURL, true
+		}
+	}
+	for _, embedded := range d.Devices {
+		if name, control, ok := embedded.avTransport(); ok {
+			return name, control, true
+		}
+	}
+	return "", "", false
+}
+
+// discoverDLNA : Find media renderers answering an SSDP search until ctx is done
+func discoverDLNA(ctx context.Context) ([]Device, error) {
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	addr, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.WriteTo([]byte(ssdpSearchRequest), addr); err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	}
+
+	var devices []Device
+	seen := map[string]bool{}
+	buf := make([]byte, 2048)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			// the deadline ends the search
+			return devices, nil
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+		location := resp.Header.Get("Location")
+		if location == "" || seen[location] {
+			continue
+		}
+		seen[location] = true
+		device, err := describeDLNA(ctx, location)
+		if err != nil {
+			continue
+		}
+		devices = append(devices, device)
+	}
+}
+
+// describeDLNA : The renderer described at location
+func describeDLNA(ctx context.Context, location string) (Device, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return Device{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Device{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Device{}, fmt.Errorf("Description of %s returned %s", location, resp.Status)
+	}
+	var description deviceDescription
+	if err = xml.NewDecoder(io.LimitReader(resp.Body, maxDescriptionBytes)).Decode(&description); err != nil {
+		return Device{}, err
+	}
+	name, control, ok := description.Device.avTransport()
+	if !ok {
+		return Device{}, fmt.Errorf("%s is not a media renderer", location)
+	}
+	base, err := url.Parse(location)
+	if err != nil {
+		return Device{}, err
+	}
+	if description.URLBase != "" {
+		if u, err := url.Parse(description.URLBase); err == nil {
+			base = u
+		}
+	}
+	controlURL, err := base.Parse(control)
+	if err != nil {
+		return Device{}, err
+	}
+	if name == "" {
+		name = base.Host
+	}
+	return Device{Name: name, Kind: DLNA, ControlURL: controlURL.String()}, nil
+}
+
+// dlnaPlayer : controls a renderer through its AVTransport SOAP actions
+type dlnaPlayer struct {
+	controlURL string
+}
+
+// soapArg : an argument of a SOAP action, they are sent in order
+type soapArg struct {
+	name, value string
+}
+
+// soapFault : the error returned by a UPnP action
+type soapFault struct {
+	Code        int    `xml:"Body>Fault>detail>UPnPError>errorCode"`
+	Description string `xml:"Body>Fault>detail>UPnPError>errorDescription"`
+}
+
+// call : Invoke the AVTransport action with args returning the response body
+func (p *dlnaPlayer) call(ctx context.Context, action string, args ...soapArg) ([]byte, error) {
+	var body bytes.Buffer
+	args = append([]soapArg{{"InstanceID", "0"}}, args...)
+	for _, arg := range args {
+		body.WriteString("<" + arg.name + ">")
+		xml.EscapeText(&body, []byte(arg.value))
+		body.WriteString("</" + arg.name + ">")
+	}
+	envelope := fmt.Sprintf(soapEnvelopeFormat, action, avTransportService, body.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.controlURL, strings.NewReader(envelope))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, avTransportService, action))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxDescriptionBytes))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var fault soapFault
+		if xml.Unmarshal(b, &fault) == nil && fault.Description != "" {
+			return nil, fmt.Errorf("%s failed: %s (%d)", action, fault.Description, fault.Code)
+		}
+		return nil, fmt.Errorf("%s failed with status %s", action, resp.Status)
+	}
+	return b, nil
+}
+
+// Load : Set the stream of the renderer and start playing it
+func (p *dlnaPlayer) Load(ctx context.Context, url, title, contentType string) error {
+	var escapedTitle, escapedURL bytes.Buffer
+	xml.EscapeText(&escapedTitle, []byte(title))
+	xml.EscapeText(&escapedURL, []byte(url))
+	metadata := fmt.Sprintf(didlMetadataFormat, escapedTitle.String(), contentType, escapedURL.String())
+	if _, err := p.call(ctx, "SetAVTransportURI", soapArg{"CurrentURI", url}, soapArg{"CurrentURIMetaData", metadata}); err != nil {
+		return err
+	}
+	return p.Play(ctx)
+}
+
+// Play : Resume playback
+func (p *dlnaPlayer) Play(ctx context.Context) error {
+	_, err := p.call(ctx, "Play", soapArg{"Speed", "1"})
+	return err
+}
+
+// Pause : Pause playback
+func (p *dlnaPlayer) Pause(ctx context.Context) error {
+	_, err := p.call(ctx, "Pause")
+	return err
+}
+
+// Seek : Move playback to position
+func (p *dlnaPlayer) Seek(ctx context.Context, position time.Duration) error {
+	_, err := p.call(ctx, "Seek", soapArg{"Unit", "REL_TIME"}, soapArg{"Target", formatClock(position)})
+	return err
+}
+
+// Position : How far playback is
+func (p *dlnaPlayer) Position(ctx context.Context) (time.Duration, error) {
+	b, err := p.call(ctx, "GetPositionInfo")
+	if err != nil {
+		return 0, err
+	}
+	var info struct {
+		RelTime string `xml:"Body>GetPositionInfoResponse>RelTime"`
+	}
+	if err = xml.Unmarshal(b, &info); err != nil {
+		return 0, err
+	}
+	return ParseClock(info.RelTime)
+}
+
+// Stop : Stop playback
+func (p *dlnaPlayer) Stop(ctx context.Context) error {
+	_, err := p.call(ctx, "Stop")
+	return err
+}
+
+// Close : nothing is kept open with a renderer
+func (p *dlnaPlayer) Close() error {
+	return nil
+}
+
+// formatClock : position as H:MM:SS
+func formatClock(position time.Duration) string {
+	seconds := int(position / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// ParseClock : Parse a position such as 1:02:03, 2:03 or 45
+func ParseClock(clock string) (time.Duration, error) {
+	clock = strings.TrimSpace(clock)
+	// fractions of a second are left out
+	if i := strings.Index(clock, "."); i >= 0 {
+		clock = clock[:i]
+	}
+	var position time.Duration
+	parts := strings.Split(clock, ":")
+	if len(parts) > 3 || clock == "" {
+		return 0, fmt.Errorf("Invalid position %q, use H:MM:SS", clock)
+	}
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("Invalid position %q, use H:MM:SS", clock)
+		}
+		position = position*60 + time.Duration(n)*time.Second
+	}
+	return position, nil
+}
@@ -16,24 +16,42 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/gorilla/handlers"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
+	"github.com/go-phie/gophie/bench"
 	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/index"
+	"github.com/go-phie/gophie/jobs"
+	"github.com/go-phie/gophie/logging"
+	"github.com/go-phie/gophie/rpc"
+	"github.com/go-phie/gophie/subtitle"
+	"github.com/go-phie/gophie/thumbnail"
 )
 
 var (
-	port string
+	port         string
+	grpcPort     string
+	apiRateLimit int
+	// how long requests in flight are drained for on shutdown
+	shutdownTimeout time.Duration
 	// WhiteListedHosts Array of IPs and Hosts allowed to access the server
 	WhiteListedHosts []string
 )
@@ -67,16 +85,68 @@ func isValidRemote(r *http.Request) bool {
 		refererURL.Host != "" && contains(WhiteListedHosts, refererURL.Host))
 }
 
+var (
+	apiLimiter     *rateLimiter
+	apiLimiterOnce sync.Once
+)
+
+// apiKeys : The keys allowed to use the API, ACCESS_SECRET and the api-keys
+// config (GOPHIE_API_KEYS as a comma separated list). None means the API is open
+func apiKeys() map[string]bool {
+	keys := map[string]bool{}
+	if secret := os.Getenv("ACCESS_SECRET"); secret != "" {
+		keys[secret] = true
+	}
+	for _, value := range viper.GetStringSlice("api-keys") {
+		for _, key := range strings.Split(value, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys[key] = true
+			}
+		}
+	}
+	return keys
+}
+
+// requestKey : API key of a request sent as a bearer token, in the X-API-Key header
+// or as the api_key query parameter, for clients such as feed readers which cannot set headers
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if key := extractToken(r); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("api_key")
+}
+
+// getAPILimiter : The limiter of the requests to the HTTP and gRPC APIs, allowing
+// api-rate-limit per minute
+func getAPILimiter() *rateLimiter {
+	apiLimiterOnce.Do(func() {
+		apiLimiter = newRateLimiter(viper.GetInt("api-rate-limit"), time.Minute)
+	})
+	return apiLimiter
+}
+
+// authenticateRequest : Only let requests with a valid API key or from a white listed
+// host through when API keys are set, and limit the requests of every valid key (or
+// IP for requests without one) to api-rate-limit per minute
 func authenticateRequest(handler http.HandlerFunc) http.HandlerFunc {
-	accessToken := os.Getenv("ACCESS_SECRET")
+	keys := apiKeys()
+	limiter := getAPILimiter()
 	return func(w http.ResponseWriter, r *http.Request) {
-		requestToken := extractToken(r)
-		// It's Either you have accessToken set
-		// Or you don't have it set but have remoteURL config set
-		if isValidRemote(r) || requestToken == accessToken {
-			handler.ServeHTTP(w, r)
-		} else {
+		key := requestKey(r)
+		if len(keys) > 0 && !keys[key] && !isValidRemote(r) {
 			accessDeniedHandler(w, r)
+			return
+		}
+		// keys which are not checked could be changed on every request to get around the limit
+		client, _, _ := net.SplitHostPort(r.RemoteAddr)
+		if keys[key] {
+			client = "key:" + key
+		}
+		if limiter.allow(w, client) {
+			handler.ServeHTTP(w, r)
 		}
 	}
 }
@@ -97,6 +167,57 @@ func getDefaultsMiddleware(handler http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// engineErrorHandler : respond to a failed search or list with a status matching the error
+func engineErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	logging.FromContext(r.Context()).Errorf("%s failed: %v", r.URL, err)
+	switch {
+	case errors.Is(err, engine.ErrEngineUnavailable):
+		http.Error(w, "Engine Unavailable", http.StatusBadGateway)
+	case errors.Is(err, engine.ErrInvalidQuery):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, "Request Cancelled", http.StatusGatewayTimeout)
+	default:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// requestFilter : the result filter set with the query parameters of a request
+func requestFilter(r *http.Request) (resultFilter, error) {
+	return queryFilter(r.URL.Query())
+}
+
+// queryFilter : the result filter set with the query parameters q
+func queryFilter(q url.Values) (resultFilter, error) {
+	var err error
+	filter := resultFilter{
+		Quality:    q.Get("quality"),
+		MinSize:    q.Get("min_size"),
+		MaxSize:    q.Get("max_size"),
+		SeriesOnly: q.Get("series") == "true",
+		Sort:       q.Get("sort"),
+		Descending: q.Get("order") == "desc",
+		RankBy:     q.Get("rank_by"),
+	}
+	for _, source := range q["source"] {
+		filter.Sources = append(filter.Sources, strings.Split(source, ",")...)
+	}
+	for _, language := range q["lang"] {
+		filter.Languages = append(filter.Languages, strings.Split(language, ",")...)
+	}
+	if year := q.Get("year_from"); year != "" {
+		if filter.YearFrom, err = strconv.Atoi(year); err != nil {
+			return filter, errors.New("year_from must be a number")
+		}
+	}
+	if year := q.Get("year_to"); year != "" {
+		if filter.YearTo, err = strconv.Atoi(year); err != nil {
+			return filter, errors.New("year_to must be a number")
+		}
+	}
+	return filter, filter.validate()
+}
+
 func accessDeniedHandler(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Unauthorized Access", http.StatusUnauthorized)
 	return
@@ -125,11 +246,16 @@ func ListHandler(w http.ResponseWriter, r *http.Request) {
 		err     error
 	)
 	eng := r.URL.Query().Get("engine")
-	site, err := engine.GetEngine(eng)
+	site, err := getEngine(eng)
 	if site == nil {
 		http.Error(w, "Invalid Engine Param", http.StatusBadRequest)
 		return
 	}
+	filter, err := requestFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if r.URL.Query().Get("page") == "" {
 		pageNum = 1
 	} else {
@@ -140,16 +266,198 @@ func ListHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	result := site.List(pageNum)
-	b, err := json.Marshal(result.Movies)
+	mode, err := engine.ParseScrapeMode(r.URL.Query().Get("mode"))
 	if err != nil {
-		log.Fatal("failed to serialize response: ", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !engine.Supports(site, mode) {
+		http.Error(w, fmt.Sprintf("%s does not list %s movies", eng, mode), http.StatusBadRequest)
+		return
+	}
+
+	perPage := 0
+	if value := r.URL.Query().Get("per_page"); value != "" {
+		if perPage, err = strconv.Atoi(value); err != nil || perPage < 1 || perPage > maxPerPage {
+			http.Error(w, fmt.Sprintf("per_page must be a number from 1 to %d", maxPerPage), http.StatusBadRequest)
+			return
+		}
+	}
+	start := listCursor{Page: pageNum}
+	if next := r.URL.Query().Get("next"); next != "" {
+		if start, err = parseListCursor(next); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	movies, pages, err := listPages(r.Context(), site, mode, filter, start, perPage)
+	if err != nil {
+		engineErrorHandler(w, r, err)
+		return
+	}
+	b, err := json.Marshal(movies)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to serialize response: ", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
+	pages.setHeaders(w, r, start.Page)
+	setEngineErrors(w, pages.Errors)
 	w.Header().Add("Content-Type", "application/json")
 	w.Write(b)
 }
 
+// setEngineErrors : Report the failures of the engines which did not stop a
+// search or list, the movies of the response are then partial, as JSON in the
+// X-Engine-Errors header
+func setEngineErrors(w http.ResponseWriter, errs []engine.EngineError) {
+	if len(errs) == 0 {
+		return
+	}
+	b, err := json.Marshal(errs)
+	if err != nil {
+		return
+	}
+	w.Header().Add("Access-Control-Expose-Headers", "X-Engine-Errors")
+	w.Header().Set("X-Engine-Errors", string(b))
+}
+
+// maxPerPage : most movies a list response can be paged to
+const maxPerPage = 100
+
+// listCursor : where a list response stopped, the page of the site and how many
+// of its (filtered) movies were already returned
+type listCursor struct {
+	Page   int
+	Offset int
+}
+
+// String : the opaque next param of the cursor
+func (c listCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", c.Page, c.Offset)))
+}
+
+// parseListCursor : the cursor of a next param
+func parseListCursor(next string) (listCursor, error) {
+	var c listCursor
+	b, err := base64.RawURLEncoding.DecodeString(next)
+	if err == nil {
+		_, err = fmt.Sscanf(string(b), "%d.%d", &c.Page, &c.Offset)
+	}
+	if err != nil || c.Page < 1 || c.Offset < 0 {
+		return c, errors.New("Invalid next cursor")
+	}
+	return c, nil
+}
+
+// listedPages : what a list response tells about the pages after it
+type listedPages struct {
+	TotalPages int
+	Next       *listCursor          // nil on the last page
+	Errors     []engine.EngineError // failures of the site which did not stop the list
+}
+
+// setHeaders : the pagination headers of a list response starting on page
+func (p listedPages) setHeaders(w http.ResponseWriter, r *http.Request, page int) {
+	w.Header().Set("Access-Control-Expose-Headers", "X-Page, X-Total-Pages, X-Has-Next, X-Next-Cursor, Link")
+	w.Header().Set("X-Page", strconv.Itoa(page))
+	if p.TotalPages > 0 {
+		w.Header().Set("X-Total-Pages", strconv.Itoa(p.TotalPages))
+	}
+	w.Header().Set("X-Has-Next", strconv.FormatBool(p.Next != nil))
+	if p.Next == nil {
+		return
+	}
+	q := r.URL.Query()
+	q.Del("page")
+	q.Set("next", p.Next.String())
+	w.Header().Set("X-Next-Cursor", p.Next.String())
+	w.Header().Set("Link", fmt.Sprintf("<%s?%s>; rel=\"next\"", r.URL.Path, q.Encode()))
+}
+
+// listPages : the movies listed by site from start on, perPage of them walking
+// as many pages of the site as needed (at most engine.MaxPages), or the rest of
+// the page of start when perPage is 0
+func listPages(ctx context.Context, site engine.Engine, mode engine.ScrapeMode, filter resultFilter,
+	start listCursor, perPage int) ([]engine.Movie, listedPages, error) {
+	movies := []engine.Movie{}
+	var pages listedPages
+	cursor := start
+	for fetched := 0; ; fetched++ {
+		if fetched == engine.MaxPages {
+			pages.Next = &cursor
+			return movies, pages, nil
+		}
+		result, err := engine.ListBy(ctx, site, mode, cursor.Page)
+		if err != nil {
+			if fetched > 0 {
+				// what was found is returned, the next request retries the page
+				pages.Next = &cursor
+				logging.FromContext(ctx).WithField("engine", site.String()).Warnf("Stopped at page %d: %v", cursor.Page, err)
+				pages.Errors = append(pages.Errors, engine.EngineError{
+					Engine: site.String(), Stage: engine.SearchStage, Message: fmt.Sprintf("page %d: %v", cursor.Page, err),
+				})
+				return movies, pages, nil
+			}
+			return nil, pages, err
+		}
+		pages.TotalPages = result.TotalPages
+		pages.Errors = append(pages.Errors, result.Errors...)
+		found := filter.apply(result).Movies
+		if cursor.Offset > len(found) {
+			cursor.Offset = len(found)
+		}
+		found = found[cursor.Offset:]
+		if perPage > 0 && len(movies)+len(found) > perPage {
+			taken := perPage - len(movies)
+			movies = append(movies, found[:taken]...)
+			pages.Next = &listCursor{Page: cursor.Page, Offset: cursor.Offset + taken}
+			return movies, pages, nil
+		}
+		movies = append(movies, found...)
+		if !result.HasNext || len(result.Movies) == 0 {
+			return movies, pages, nil
+		}
+		cursor = listCursor{Page: cursor.Page + 1}
+		if perPage == 0 || len(movies) == perPage {
+			pages.Next = &cursor
+			return movies, pages, nil
+		}
+	}
+}
+
+var (
+	imageProxy     *thumbnail.Proxy
+	imageProxyOnce sync.Once
+)
+
+// imageHosts : the hosts cover photos are proxied from, the sites of the engines
+// and the image-hosts config (GOPHIE_IMAGE_HOSTS as a comma separated list)
+func imageHosts() []string {
+	hosts := engine.Hosts()
+	for _, value := range viper.GetStringSlice("image-hosts") {
+		for _, host := range strings.Split(value, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts
+}
+
+// ImageHandler : serves thumbnails of cover photos, cached in the cache directory
+func ImageHandler(w http.ResponseWriter, r *http.Request) {
+	imageProxyOnce.Do(func() {
+		imageProxy = &thumbnail.Proxy{Hosts: imageHosts(), Client: &http.Client{Timeout: 30 * time.Second}}
+		if !viper.GetBool("no-cache") && viper.GetString("cache-dir") != "" {
+			imageProxy.Dir = path.Join(viper.GetString("cache-dir"), "thumbnails")
+		}
+	})
+	enableCors(&w)
+	imageProxy.ServeHTTP(w, r)
+}
+
 // SearchHandler : handles search requests
 func SearchHandler(w http.ResponseWriter, r *http.Request) {
 	var (
@@ -163,6 +471,11 @@ func SearchHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Query param must be added to url", http.StatusBadRequest)
 		return
 	}
+	filter, err := requestFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	if r.URL.Query().Get("page") == "" {
 		pageNum = 1
@@ -174,23 +487,196 @@ func SearchHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	site, err = engine.GetEngine(r.URL.Query().Get("engine"))
+	// all searches every available engine at once
+	if strings.ToLower(r.URL.Query().Get("engine")) == "all" {
+		logging.FromContext(r.Context()).WithFields(log.Fields{"engine": "all", "query": query}).Info("Processing search request")
+		result, err = engine.SearchAll(r.Context(), query)
+		indexMovies(result.Movies)
+	} else {
+		site, err = getEngine(r.URL.Query().Get("engine"))
+		if err != nil {
+			http.Error(w, "Invalid Engine Param", http.StatusBadRequest)
+			return
+		}
+		logging.FromContext(r.Context()).WithFields(log.Fields{"engine": site.String(), "query": query}).Info("Processing search request")
+		result, err = engine.Search(r.Context(), site, query, strconv.Itoa(pageNum))
+	}
 	if err != nil {
-		http.Error(w, "Invalid Engine Param", http.StatusBadRequest)
+		engineErrorHandler(w, r, err)
 		return
 	}
-	log.Infof("Processing search Request for engine=%s and query=%s", site, query)
-	result = site.Search(query, strconv.Itoa(pageNum))
+	result = filter.apply(result)
+	setEngineErrors(w, result.Errors)
 
 	// dump results
 	b, err := json.Marshal(result.Movies)
 	if err != nil {
-		log.Error("failed to serialize response: ", err)
+		logging.FromContext(r.Context()).Error("failed to serialize response: ", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write(b)
+	logging.FromContext(r.Context()).WithField("query", query).Debug("Completed search")
+}
+
+// LocalSearchHandler : handles searches of the local index of the movies found
+// by earlier searches and lists, answered without reaching the sites
+func LocalSearchHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("query")
+	if query == "" {
+		http.Error(w, "Query param must be added to url", http.StatusBadRequest)
+		return
+	}
+	limit := index.DefaultLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 {
+			http.Error(w, "Limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+	filter, err := requestFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	idx := openIndex()
+	if idx == nil {
+		http.Error(w, "The local index is unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	result, err := idx.Search(r.Context(), query, limit)
+	if err != nil {
+		engineErrorHandler(w, r, err)
+		return
+	}
+	result = filter.apply(result)
+	b, err := json.Marshal(result.Movies)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to serialize response: ", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write(b)
+}
+
+// StreamSearchHandler : handles search requests by pushing every movie as a
+// Server-Sent Event as soon as it is scraped. A "done" event ends the stream
+func StreamSearchHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming Unsupported", http.StatusInternalServerError)
+		return
+	}
+	query := r.URL.Query().Get("query")
+	if query == "" {
+		http.Error(w, "Query param must be added to url", http.StatusBadRequest)
+		return
+	}
+	page := r.URL.Query().Get("page")
+	if page == "" {
+		page = "1"
+	} else if _, err := strconv.Atoi(page); err != nil {
+		http.Error(w, "Page must be a number", http.StatusBadRequest)
+		return
+	}
+	// movies are pushed as they are scraped so they are filtered but cannot be sorted
+	filter, err := requestFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	engines := map[string]engine.Engine{}
+	names := []string{r.URL.Query().Get("engine")}
+	if strings.ToLower(names[0]) == "all" {
+		names = names[:0]
+		for name := range allEngines() {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		site, err := getEngine(name)
+		if err != nil {
+			http.Error(w, "Invalid Engine Param", http.StatusBadRequest)
+			return
+		}
+		engines[name] = site
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	logging.FromContext(r.Context()).WithFields(log.Fields{"engine": r.URL.Query().Get("engine"), "query": query}).Info("Processing stream search request")
+	count := 0
+	err = engine.StreamSearchAll(r.Context(), engines, func(movie engine.Movie) {
+		if !filter.keep(movie) {
+			return
+		}
+		b, err := json.Marshal(&movie)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("failed to serialize movie: ", err)
+			return
+		}
+		count++
+		fmt.Fprintf(w, "event: movie\ndata: %s\n\n", b)
+		flusher.Flush()
+	}, query, page)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("%s failed: %v", r.URL, err)
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", http.StatusText(http.StatusBadGateway))
+	}
+	done, _ := json.Marshal(struct {
+		Query string
+		Count int
+	}{query, count})
+	fmt.Fprintf(w, "event: done\ndata: %s\n\n", done)
+	flusher.Flush()
+	logging.FromContext(r.Context()).WithFields(log.Fields{"query": query, "results": count}).Debug("Completed stream search")
+}
+
+// SubtitleHandler : handles subtitle requests
+// ?query= lists the matching subtitles and ?id= redirects to the subtitle file
+func SubtitleHandler(w http.ResponseWriter, r *http.Request) {
+	provider := getSubtitleProvider()
+	if id := r.URL.Query().Get("id"); id != "" {
+		link, err := provider.Link(r.Context(), subtitle.Subtitle{ID: id})
+		if err != nil {
+			logging.FromContext(r.Context()).Errorf("%s failed: %v", r.URL, err)
+			http.Error(w, "Subtitle Unavailable", http.StatusBadGateway)
+			return
+		}
+		http.Redirect(w, r, link.String(), http.StatusFound)
+		return
+	}
+
+	query := r.URL.Query().Get("query")
+	if query == "" {
+		http.Error(w, "Query or id param must be added to url", http.StatusBadRequest)
+		return
+	}
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = "en"
+	}
+	subtitles, err := provider.Search(r.Context(), subtitle.QueryForMovie(engine.Movie{Title: query}, lang))
+	if errors.Is(err, subtitle.ErrNotFound) {
+		subtitles = []subtitle.Subtitle{}
+	} else if err != nil {
+		logging.FromContext(r.Context()).Errorf("%s failed: %v", r.URL, err)
+		http.Error(w, "Subtitle Provider Unavailable", http.StatusBadGateway)
+		return
+	}
+	b, err := json.Marshal(subtitles)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to serialize response: ", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 	w.Write(b)
-	log.Debug("Completed search for ", query)
 }
 
 // EngineHandler : handles Engine Listing
@@ -203,21 +689,21 @@ func EngineHandler(w http.ResponseWriter, r *http.Request) {
 		err      error
 	)
 	if eng != "" {
-		site, err := engine.GetEngine(eng)
+		site, err := findEngine(eng)
 		if err != nil {
 			http.Error(w, "Invalid Engine Param", http.StatusBadRequest)
 			return
 		}
 		response, err = json.Marshal(site)
 		if err != nil {
-			log.Error("failed to serialize response: ", err)
+			logging.FromContext(r.Context()).Error("failed to serialize response: ", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 	} else {
-		response, err = json.Marshal(engine.GetEngines())
+		response, err = json.Marshal(allEngines())
 		if err != nil {
-			log.Error("failed to serialize response: ", err)
+			logging.FromContext(r.Context()).Error("failed to serialize response: ", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
@@ -225,29 +711,229 @@ func EngineHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(response)
 }
 
+// CapabilitiesHandler : reports the modes every engine, or the engine named by
+// the engine param, lists movies in
+func CapabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	w.Header().Add("Content-Type", "application/json")
+	var names []string
+	if eng := r.URL.Query().Get("engine"); eng != "" {
+		names = append(names, eng)
+	}
+	engines, err := checkEngines(names)
+	if err != nil {
+		http.Error(w, "Invalid Engine Param", http.StatusBadRequest)
+		return
+	}
+	b, err := json.Marshal(engine.ReportCapabilities(engines))
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to serialize response: ", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write(b)
+}
+
+// healthTimeout : how long every engine has to respond to a health check
+const healthTimeout = 10 * time.Second
+
+// HealthHandler : reports which engines are up and their latency
+// ?engine= checks a single engine and responds 503 when it is down
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	w.Header().Add("Content-Type", "application/json")
+	var names []string
+	if eng := r.URL.Query().Get("engine"); eng != "" {
+		names = append(names, eng)
+	}
+	engines, err := checkEngines(names)
+	if err != nil {
+		http.Error(w, "Invalid Engine Param", http.StatusBadRequest)
+		return
+	}
+	statuses := engine.CheckHealth(r.Context(), engines, healthTimeout)
+	b, err := json.Marshal(statuses)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to serialize response: ", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if len(names) > 0 && !statuses[0].Up {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(b)
+}
+
+// defaultBenchRuns : runs of gophie bench the scoreboard of /bench adds up by default
+const defaultBenchRuns = 10
+
+// BenchHandler : reports the scoreboard of the engines over the last runs of
+// gophie bench, best first
+func BenchHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	w.Header().Add("Content-Type", "application/json")
+	runs := defaultBenchRuns
+	if n := r.URL.Query().Get("runs"); n != "" {
+		var err error
+		if runs, err = strconv.Atoi(n); err != nil || runs < 1 {
+			http.Error(w, "Runs must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+	results := []bench.Result{}
+	store, err := openBench()
+	if err == nil {
+		var history []bench.Run
+		if history, err = store.History(runs); err == nil && len(history) > 0 {
+			results = bench.Summarize(history)
+		}
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Could not read the benchmarks: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	b, err := json.Marshal(results)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to serialize response: ", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write(b)
+}
+
+// serveAPI : Serve server on lis until ctx is done, then stop accepting requests
+// and wait up to timeout for those in flight. Scrapes still running past the
+// timeout are cancelled
+func serveAPI(ctx context.Context, server *http.Server, lis net.Listener, timeout time.Duration) error {
+	// requests outlive ctx so that they can be drained
+	requests, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	server.BaseContext = func(net.Listener) context.Context { return requests }
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(lis) }()
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Infof("Shutting down, waiting up to %s for requests in flight", timeout)
+	drain, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(drain); err != nil {
+		log.Warnf("Cancelled the requests still in flight after %s", timeout)
+		cancelRequests()
+		server.Close()
+	}
+	return nil
+}
+
+// serveGRPC : Serve the gRPC API on port until ctx is done, then wait up to
+// timeout for the calls in flight before returning
+func serveGRPC(ctx context.Context, port string, timeout time.Duration) {
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server := rpc.NewGRPCServer(getEngine, apiKeys(), getAPILimiter().allowCall)
+	go func() {
+		<-ctx.Done()
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(timeout):
+			server.Stop()
+		}
+	}()
+	log.Info("gRPC listening on ", port)
+	if err := server.Serve(lis); err != nil {
+		log.Fatal(err)
+	}
+}
+
 // apiCmd represents the api command
 var apiCmd = &cobra.Command{
 	Use:   "api",
 	Short: "host gophie as an API on a PORT env variable, fallback to set argument",
-	Long:  ``,
+	Long: `Api
+	SIGINT and SIGTERM stop the server gracefully: no more requests are accepted, those
+	in flight are given --shutdown-timeout to complete and the result cache is closed
+	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		r := http.NewServeMux()
-		r.HandleFunc("/search", authenticateRequest(getDefaultsMiddleware(SearchHandler)))
-		r.HandleFunc("/list", authenticateRequest(getDefaultsMiddleware(ListHandler)))
-		r.HandleFunc("/engine", EngineHandler)
-		r.HandleFunc("/", DocHandler)
-
-		log.Info("listening on ", port)
-		_, err := strconv.Atoi(port)
-		if err != nil {
-			log.Fatal(err)
-		}
-		loggedRouter := handlers.LoggingHandler(os.Stdout, r)
-		log.Fatal(http.ListenAndServe(":"+port, loggedRouter))
+		runAPI(cmd.Context())
+		closeResultCache()
+		closeIndex()
+		log.Info("Server stopped")
 	},
 }
 
+// runAPI : Serve the API, and the gRPC API when grpc-port is set, until ctx is done
+func runAPI(ctx context.Context) {
+	// the API lists, searches and serves the hosts of every engine
+	loadPlugins()
+	r := http.NewServeMux()
+	routes := apiRoutes()
+	for _, route := range routes {
+		r.HandleFunc(route.pattern(), route.handler())
+	}
+	r.HandleFunc("/docs", SwaggerHandler)
+	r.HandleFunc("/docs/openapi.json", OpenAPIHandler(apiDocument(routes)))
+	r.HandleFunc("/", DocHandler)
+
+	log.Info("listening on ", port)
+	_, err := strconv.Atoi(port)
+	if err != nil {
+		log.Fatal(err)
+	}
+	timeout := viper.GetDuration("shutdown-timeout")
+	grpcStopped := make(chan struct{})
+	go func() {
+		defer close(grpcStopped)
+		if grpcPort != "" {
+			serveGRPC(ctx, grpcPort, timeout)
+		}
+	}()
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatal(err)
+	}
+	downloadsStopped := runDownloads(ctx, viper.GetInt("download-workers"))
+	jobsStopped := runJobs(ctx, viper.GetInt("job-workers"))
+	server := &http.Server{Handler: logging.Handler(r)}
+	if err = serveAPI(ctx, server, lis, timeout); err != nil {
+		log.Fatal(err)
+	}
+	<-grpcStopped
+	<-downloadsStopped
+	<-jobsStopped
+}
+
+// addAPIFlags : Add the flags of the API server to cmd
+func addAPIFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&port, "port", "p", "3000", "Port to run application server on")
+	cmd.Flags().IntVar(&apiRateLimit, "api-rate-limit", 60, "Requests allowed per minute for every API key or IP, 0 for no limit")
+	cmd.Flags().StringVar(&grpcPort, "grpc-port", "", "Port to run the gRPC server on alongside the application server, disabled when empty")
+	cmd.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second, "How long requests in flight are given to complete when the server is stopped")
+	cmd.Flags().Int("download-workers", 0, "Download the queued movies this many at a time while serving, reporting their progress at /downloads")
+	cmd.Flags().Int("job-workers", jobs.DefaultWorkers, "Searches submitted to /jobs/search run this many at a time, 0 disables jobs")
+	cmd.Flags().Duration("job-ttl", jobs.DefaultTTL, "How long finished jobs are kept for /jobs/{id}")
+	cmd.Flags().StringSlice("image-hosts", []string{"image.tmdb.org", "m.media-amazon.com"}, "Hosts /image proxies cover photos from besides the sites of the engines")
+}
+
+// bindAPIFlags : Read the config of the API server from the flags of cmd
+func bindAPIFlags(cmd *cobra.Command) {
+	for _, name := range []string{"api-rate-limit", "shutdown-timeout", "image-hosts", "download-workers", "job-workers", "job-ttl"} {
+		viper.BindPFlag(name, cmd.Flags().Lookup(name))
+	}
+}
+
 func init() {
-	apiCmd.Flags().StringVarP(&port, "port", "p", "3000", "Port to run application server on")
+	addAPIFlags(apiCmd)
+	bindAPIFlags(apiCmd)
 	rootCmd.AddCommand(apiCmd)
 }
@@ -1,9 +1,28 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/go-phie/gophie/bench"
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/jobs"
+	"github.com/go-phie/gophie/logging"
+	"github.com/go-phie/gophie/queue"
+	"github.com/spf13/viper"
+	"golang.org/x/net/websocket"
 )
 
 func TestSearchAPI(t *testing.T) {
@@ -11,6 +30,9 @@ func TestSearchAPI(t *testing.T) {
 	defer ts.Close()
 
 	res, _ := http.Get(ts.URL + "?query=good+boys&engine=mycoolmoviez")
+	if res.StatusCode == http.StatusBadGateway {
+		t.Skip("Engine source site is unreachable")
+	}
 	if res.StatusCode != 200 {
 		t.Errorf("Server failing")
 	}
@@ -21,7 +43,565 @@ func TestListAPI(t *testing.T) {
 	defer ts.Close()
 
 	res, _ := http.Get(ts.URL + "?page=1&engine=fzmovies")
+	if res.StatusCode == http.StatusBadGateway {
+		t.Skip("Engine source site is unreachable")
+	}
 	if res.StatusCode != 200 {
 		t.Errorf("Server failing")
 	}
 }
+
+func TestListAPIModes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(ListHandler))
+	defer ts.Close()
+
+	// fzmovies only lists its latest movies
+	for _, params := range []string{"&mode=newest", "&mode=trending"} {
+		res, _ := http.Get(ts.URL + "?engine=fzmovies" + params)
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected %s to be rejected, got %d", params, res.StatusCode)
+		}
+	}
+
+	caps := httptest.NewServer(http.HandlerFunc(CapabilitiesHandler))
+	defer caps.Close()
+	res, err := http.Get(caps.URL + "?engine=yts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var reports []struct {
+		Engine string
+		Modes  []string
+	}
+	if err = json.NewDecoder(res.Body).Decode(&reports); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].Engine != "yts" || len(reports[0].Modes) != 4 || reports[0].Modes[1] != "trending" {
+		t.Errorf("Unexpected capabilities %+v", reports)
+	}
+}
+
+func TestSearchAPIFilterParams(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(SearchHandler))
+	defer ts.Close()
+
+	for _, params := range []string{"&sort=title", "&year_from=last", "&rank_by=size", "&lang=klingon"} {
+		res, _ := http.Get(ts.URL + "?query=good+boys&engine=mycoolmoviez" + params)
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected %s to be rejected, got %d", params, res.StatusCode)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	limiter := newRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	for i, allowed := range []bool{true, true, false} {
+		w := httptest.NewRecorder()
+		if limiter.allow(w, "key:a") != allowed {
+			t.Errorf("Request %d: expected allowed to be %v", i, allowed)
+		}
+		if w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Reset") != "1060" {
+			t.Errorf("Unexpected rate limit headers %v", w.Header())
+		}
+		if !allowed && (w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "61") {
+			t.Errorf("Expected 429 with Retry-After, got %d %v", w.Code, w.Header())
+		}
+	}
+	// other clients have their own limit
+	if !limiter.allow(httptest.NewRecorder(), "key:b") {
+		t.Error("Expected another key to be allowed")
+	}
+	now = now.Add(time.Minute)
+	w := httptest.NewRecorder()
+	if !limiter.allow(w, "key:a") || w.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("Expected the limit to reset after a minute, got %v", w.Header())
+	}
+}
+
+func TestRateLimiterWindows(t *testing.T) {
+	now := time.Unix(1000, 0)
+	limiter := newRateLimiter(1, time.Minute)
+	limiter.max = 3
+	limiter.now = func() time.Time { return now }
+
+	// the oldest window is dropped for new clients once max are kept
+	for i := 0; i < 5; i++ {
+		limiter.allow(httptest.NewRecorder(), fmt.Sprintf("10.0.0.%d", i))
+		now = now.Add(time.Second)
+	}
+	if len(limiter.windows) != 3 || limiter.windows["10.0.0.1"] != nil || limiter.windows["10.0.0.4"] == nil {
+		t.Errorf("Expected the windows of the 3 latest clients, got %v", limiter.windows)
+	}
+}
+
+func TestRateLimitedClients(t *testing.T) {
+	limiter := getAPILimiter()
+	defer func() { apiLimiter = limiter }()
+	apiLimiter = newRateLimiter(1, time.Minute)
+	handler := authenticateRequest(func(w http.ResponseWriter, r *http.Request) {})
+
+	// without API keys, the keys sent are not checked and do not get their own limit
+	for i, code := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodGet, "/search", nil)
+		r.Header.Set("X-API-Key", fmt.Sprintf("made-up-%d", i))
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != code {
+			t.Errorf("Request %d: expected %d, got %d", i, code, w.Code)
+		}
+	}
+
+	// valid keys do
+	viper.Set("api-keys", []string{"first"})
+	defer viper.Set("api-keys", nil)
+	handler = authenticateRequest(func(w http.ResponseWriter, r *http.Request) {})
+	r := httptest.NewRequest(http.MethodGet, "/search", nil)
+	r.Header.Set("X-API-Key", "first")
+	w := httptest.NewRecorder()
+	if handler(w, r); w.Code != http.StatusOK {
+		t.Errorf("Expected a valid key to have its own limit, got %d", w.Code)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	viper.Set("api-keys", []string{"first,second"})
+	defer viper.Set("api-keys", nil)
+	handler := authenticateRequest(func(w http.ResponseWriter, r *http.Request) {})
+
+	for key, code := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "second": http.StatusOK} {
+		r := httptest.NewRequest(http.MethodGet, "/search", nil)
+		r.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != code {
+			t.Errorf("Expected %d for key %q, got %d", code, key, w.Code)
+		}
+		if code == http.StatusOK && w.Header().Get("X-RateLimit-Remaining") == "" {
+			t.Errorf("Expected rate limit headers, got %v", w.Header())
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	routes := apiRoutes()
+	ts := httptest.NewServer(OpenAPIHandler(apiDocument(routes)))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var doc struct {
+		OpenAPI    string
+		Paths      map[string]map[string]interface{}
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]interface{}
+			}
+		}
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI == "" {
+		t.Error("Expected the OpenAPI version to be set")
+	}
+	for _, route := range routes {
+		method := strings.ToLower(route.Method)
+		if method == "" {
+			method = "get"
+		}
+		if doc.Paths[route.Path][method] == nil {
+			t.Errorf("Expected %s to be documented", route.Path)
+		}
+	}
+	// links are served as strings rather than the fields of url.URL
+	if kind := doc.Components.Schemas["Movie"].Properties["DownloadLink"]["type"]; kind != "string" {
+		t.Errorf("Expected Movie.DownloadLink to be a string, got %v", kind)
+	}
+	if kind := doc.Components.Schemas["Engine"].Properties["BaseURL"]["type"]; kind != "string" {
+		t.Errorf("Expected Engine.BaseURL to be a string, got %v", kind)
+	}
+}
+
+func TestHealthAPI(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(HealthHandler))
+	defer ts.Close()
+
+	res, _ := http.Get(ts.URL + "?engine=unknown")
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected an unknown engine to be rejected, got %d", res.StatusCode)
+	}
+}
+
+func TestServeAPIShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-release:
+			w.Write([]byte("done"))
+		case <-r.Context().Done():
+		}
+	})
+
+	serve := func(timeout time.Duration) (context.CancelFunc, chan error, string) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, stop := context.WithCancel(context.Background())
+		stopped := make(chan error, 1)
+		go func() { stopped <- serveAPI(ctx, &http.Server{Handler: mux}, lis, timeout) }()
+		return stop, stopped, "http://" + lis.Addr().String() + "/slow"
+	}
+
+	// requests in flight complete once the server is stopped
+	stop, stopped, url := serve(5 * time.Second)
+	responses := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			responses <- err.Error()
+			return
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		responses <- string(body)
+	}()
+	<-started
+	stop()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if body := <-responses; body != "done" {
+		t.Errorf("Expected the request in flight to complete, got %q", body)
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("Expected the server to stop cleanly, got %v", err)
+	}
+	if _, err := http.Get(url); err == nil {
+		t.Error("Expected no requests to be accepted once stopped")
+	}
+
+	// those outliving the timeout are cancelled
+	started, release = make(chan struct{}), make(chan struct{})
+	stop, stopped, url = serve(50 * time.Millisecond)
+	go http.Get(url)
+	<-started
+	stop()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the request to be cancelled after the timeout")
+	}
+}
+
+func TestRSSFeed(t *testing.T) {
+	link, _ := url.Parse("https://films.example/jumanji.mp4")
+	feed := newRSSFeed("FzMovies latest movies", "http://localhost/list", []engine.Movie{
+		{Title: "Jumanji", Year: 2017, SizeBytes: 700 << 20, DownloadLink: link, UploadDate: "2020-05-01"},
+		{Title: "Jumanji 1995", MagnetLink: "magnet:?xt=urn:btih:c12fe1"},
+	})
+	b, err := xml.Marshal(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<rss version="2.0">`,
+		`<title>Jumanji (2017)</title>`,
+		`<enclosure url="https://films.example/jumanji.mp4" length="734003200" type="video/mp4"></enclosure>`,
+		`<pubDate>Fri, 01 May 2020 00:00:00 +0000</pubDate>`,
+		`<enclosure url="magnet:?xt=urn:btih:c12fe1" length="0" type="application/x-bittorrent"></enclosure>`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("Expected %s in the feed %s", want, b)
+		}
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(RSSHandler))
+	defer ts.Close()
+	for _, path := range []string{"/rss/unknown", "/rss/fzmovies?mode=trending"} {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected %s to be rejected, got %d", path, res.StatusCode)
+		}
+	}
+}
+
+// pagedLister : lists 5 movies on each of its 3 pages
+type pagedLister struct{ listed []int }
+
+func (e *pagedLister) Search(ctx context.Context, param ...string) (engine.SearchResult, error) {
+	return engine.SearchResult{}, nil
+}
+func (e *pagedLister) List(ctx context.Context, page int) (engine.SearchResult, error) {
+	e.listed = append(e.listed, page)
+	result := engine.SearchResult{Page: page, TotalPages: 3, HasNext: page < 3}
+	for i := 0; i < 5; i++ {
+		result.Movies = append(result.Movies, engine.Movie{Title: fmt.Sprintf("%d-%d", page, i)})
+	}
+	return result, nil
+}
+func (e *pagedLister) Capabilities() engine.Capabilities {
+	return engine.Capabilities{Pagination: true}
+}
+func (e *pagedLister) String() string { return "Paged" }
+
+func TestListPages(t *testing.T) {
+	if _, err := parseListCursor("bm9wZQ"); err == nil {
+		t.Error("Expected an invalid cursor to be rejected")
+	}
+	if c, err := parseListCursor(listCursor{Page: 2, Offset: 3}.String()); err != nil || c != (listCursor{Page: 2, Offset: 3}) {
+		t.Errorf("Expected the cursor back, got %+v %v", c, err)
+	}
+
+	e := &pagedLister{}
+	var titles []string
+	start := listCursor{Page: 1}
+	for {
+		movies, pages, err := listPages(context.Background(), e, engine.Latest, resultFilter{}, start, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pages.TotalPages != 3 {
+			t.Errorf("Expected 3 pages, got %d", pages.TotalPages)
+		}
+		for _, movie := range movies {
+			titles = append(titles, movie.Title)
+		}
+		if pages.Next == nil {
+			break
+		}
+		start = *pages.Next
+	}
+	if len(titles) != 15 || titles[4] != "1-4" || titles[14] != "3-4" {
+		t.Errorf("Expected every movie once, got %v", titles)
+	}
+
+	// without per_page a response is the rest of a page of the site
+	movies, pages, _ := listPages(context.Background(), e, engine.Latest, resultFilter{}, listCursor{Page: 2, Offset: 1}, 0)
+	if len(movies) != 4 || pages.Next == nil || *pages.Next != (listCursor{Page: 3}) {
+		t.Errorf("Expected the rest of page 2, got %d movies and %+v", len(movies), pages.Next)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/list?engine=paged&page=2&per_page=4", nil)
+	pages.setHeaders(w, r, 2)
+	if w.Header().Get("X-Has-Next") != "true" || w.Header().Get("X-Total-Pages") != "3" ||
+		w.Header().Get("Link") != `</list?engine=paged&next=`+pages.Next.String()+`&per_page=4>; rel="next"` {
+		t.Errorf("Unexpected pagination headers %v", w.Header())
+	}
+}
+
+func TestDownloadsAPI(t *testing.T) {
+	flag := rootCmd.PersistentFlags().Lookup("cache-dir")
+	flag.Value.Set(t.TempDir())
+	flag.Changed = true
+	defer func() {
+		flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+	}()
+	link, _ := url.Parse("https://downloads.example/jumanji.mp4")
+	q, err := queue.Open(filepath.Join(viper.GetString("cache-dir"), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, _ := q.Add(engine.Movie{Title: "Jumanji", DownloadLink: link})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/downloads", DownloadsHandler)
+	mux.HandleFunc("/downloads/events", DownloadEventsHandler)
+	ts := httptest.NewServer(logging.Handler(mux))
+	defer ts.Close()
+
+	var downloads []queue.Download
+	res, err := http.Get(ts.URL + "/downloads?status=queued")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(res.Body).Decode(&downloads)
+	res.Body.Close()
+	if len(downloads) != 1 || downloads[0].ID != item.ID || downloads[0].Percent != -1 {
+		t.Errorf("Expected the queued download, got %+v", downloads)
+	}
+
+	// events are only pushed while the API downloads
+	eventsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/downloads/events"
+	if _, err = websocket.Dial(eventsURL, "", ts.URL); err == nil {
+		t.Error("Expected no events without --download-workers")
+	}
+	downloadMonitor = queue.NewMonitor()
+	defer func() { downloadMonitor = nil }()
+	ws, err := websocket.Dial(eventsURL, "", ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	var event queue.Event
+	if err = websocket.JSON.Receive(ws, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != queue.StatusEvent || event.Download.ID != item.ID || event.Download.Movie.Title != "Jumanji" {
+		t.Errorf("Expected the status of the queued download, got %+v", event)
+	}
+	downloadMonitor.Close()
+	if err = websocket.JSON.Receive(ws, &event); err == nil {
+		t.Error("Expected the WebSocket to be closed with the monitor")
+	}
+}
+
+func TestJobsAPI(t *testing.T) {
+	flag := rootCmd.PersistentFlags().Lookup("cache-dir")
+	flag.Value.Set(t.TempDir())
+	flag.Changed = true
+	defer func() {
+		flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := runJobs(ctx, 1)
+	defer func() {
+		cancel()
+		<-stopped
+		jobRunner = nil
+	}()
+
+	mux := http.NewServeMux()
+	for _, route := range apiRoutes() {
+		mux.HandleFunc(route.pattern(), route.handler())
+	}
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/jobs/search?query=jumanji")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected jobs to be submitted with POST only, got %s", res.Status)
+	}
+	res, err = http.PostForm(ts.URL+"/jobs/search", url.Values{"query": {"jumanji"}, "pages": {"0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected invalid pages to be refused, got %s", res.Status)
+	}
+
+	res, err = http.PostForm(ts.URL+"/jobs/search", url.Values{"query": {"jumanji year:2017"}, "engine": {"all"}, "quality": {"1080p"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job jobs.Job
+	json.NewDecoder(res.Body).Decode(&job)
+	res.Body.Close()
+	if res.StatusCode != http.StatusAccepted || res.Header.Get("Location") != "/jobs/"+job.ID ||
+		job.Query != "jumanji year:2017" || job.Filter.Get("quality") != "1080p" {
+		t.Errorf("Expected the job submitted, got %s %+v", res.Status, job)
+	}
+	res, err = http.Get(ts.URL + res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("Expected the job at its location, got %s", res.Status)
+	}
+	res, err = http.Get(ts.URL + "/jobs/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected no job, got %s", res.Status)
+	}
+}
+
+func TestLocalSearchAPI(t *testing.T) {
+	flag := rootCmd.PersistentFlags().Lookup("cache-dir")
+	flag.Value.Set(t.TempDir())
+	flag.Changed = true
+	localIndex, localIndexOnce = nil, sync.Once{}
+	defer func() {
+		closeIndex()
+		localIndex, localIndexOnce = nil, sync.Once{}
+		flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+	}()
+	link, _ := url.Parse("https://downloads.example/jumanji.mp4")
+	indexMovies([]engine.Movie{{Title: "Jumanji", Year: 1995, Source: "NetNaija", DownloadLink: link}})
+
+	ts := httptest.NewServer(logging.Handler(http.HandlerFunc(LocalSearchHandler)))
+	defer ts.Close()
+	var movies []engine.Movie
+	res, err := http.Get(ts.URL + "/local-search?query=juman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(res.Body).Decode(&movies)
+	res.Body.Close()
+	if len(movies) != 1 || movies[0].Title != "Jumanji" {
+		t.Errorf("Expected the indexed movie, got %+v", movies)
+	}
+	for _, params := range []string{"", "query=jumanji&limit=none", "query=jumanji&lang=klingon"} {
+		res, err = http.Get(ts.URL + "/local-search?" + params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected %q to be refused, got %s", params, res.Status)
+		}
+	}
+}
+
+func TestBenchAPI(t *testing.T) {
+	flag := rootCmd.PersistentFlags().Lookup("cache-dir")
+	flag.Value.Set(t.TempDir())
+	flag.Changed = true
+	defer func() {
+		flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+	}()
+	ts := httptest.NewServer(http.HandlerFunc(BenchHandler))
+	defer ts.Close()
+	var results []bench.Result
+	get := func(params string) *http.Response {
+		res, err := http.Get(ts.URL + "/bench?" + params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = nil
+		json.NewDecoder(res.Body).Decode(&results)
+		res.Body.Close()
+		return res
+	}
+	if res := get(""); res.StatusCode != http.StatusOK || results == nil || len(results) != 0 {
+		t.Errorf("Expected an empty scoreboard before any run, got %s %+v", res.Status, results)
+	}
+
+	store, err := openBench()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Add(bench.Run{Time: time.Now(), Results: []bench.Result{{Engine: "netnaija", Searches: 5, Movies: 20}}})
+	if get("runs=5"); len(results) != 1 || results[0].Engine != "netnaija" || results[0].Score != 1 {
+		t.Errorf("Expected the scoreboard of the run, got %+v", results)
+	}
+	if res := get("runs=none"); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected invalid runs to be refused, got %s", res.Status)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-phie/gophie/bench"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	benchQueries    []string
+	benchTimeout    time.Duration
+	benchParallel   int
+	benchLinkSample int
+	benchHistory    int
+)
+
+// openBench : Open the history of the benchmarks kept in the cache directory
+func openBench() (*bench.Store, error) {
+	return bench.Open(path.Join(viper.GetString("cache-dir"), "bench.db"))
+}
+
+// printScoreboard : the results of the engines, best first
+func printScoreboard(out io.Writer, results []bench.Result) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ENGINE\tSCORE\tLATENCY\tSEARCHES\tFAILED\tMOVIES\tPARSE ERRORS\tDEAD LINKS")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%.2f\t%s\t%d\t%d\t%d\t%d\t%d/%d (%.0f%%)\n", r.Engine, r.Score, r.Latency.Round(time.Millisecond),
+			r.Searches, r.Failures, r.Movies, r.ParseErrors, r.DeadLinks, r.LinksChecked, 100*r.DeadRatio())
+	}
+	w.Flush()
+}
+
+// benchCmd represents the bench command
+var benchCmd = &cobra.Command{
+	Use:   "bench [engine...]",
+	Short: "benchmark how reliable the engines are",
+	Long: `Bench
+			gophie bench
+			gophie bench netnaija fzmovies --queries avengers,joker
+			gophie bench --history 10
+
+	Every engine (or the given engines) is searched for a fixed set of queries, measuring how
+	long it takes, how many movies it finds, how many of them could not be parsed and how many
+	of a sample of their links are dead. Engines are scored from 0 to 1 by the share of their
+	searches which succeeded, of their movies which were parsed and of their links which work.
+	Runs are kept in the cache directory, --history shows the scoreboard of the last runs
+	without running a new one. Dead links also rank the movies of the engine in search results
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := openBench()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if benchHistory > 0 {
+			runs, err := store.History(benchHistory)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if len(runs) == 0 {
+				fmt.Println("No benchmarks yet, run gophie bench")
+				return
+			}
+			fmt.Printf("%d runs since %s\n\n", len(runs), runs[len(runs)-1].Time.Format("2006-01-02 15:04"))
+			printScoreboard(os.Stdout, bench.Summarize(runs))
+			return
+		}
+		engines, err := checkEngines(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		b := &bench.Bench{Queries: benchQueries, Timeout: benchTimeout, Parallel: benchParallel, LinkSample: benchLinkSample}
+		log.Infof("Benchmarking %d engines with %d queries", len(engines), len(b.Queries))
+		run := b.Run(cmd.Context(), engines)
+		if cmd.Context().Err() != nil {
+			log.Fatal(cmd.Context().Err())
+		}
+		if err = store.Add(run); err != nil {
+			log.Warnf("Could not save the benchmark: %v", err)
+		}
+		printScoreboard(os.Stdout, run.Results)
+	},
+}
+
+func init() {
+	benchCmd.Flags().StringSliceVar(&benchQueries, "queries", bench.DefaultQueries, "Queries every engine is searched for")
+	benchCmd.Flags().DurationVar(&benchTimeout, "timeout", bench.DefaultTimeout, "How long every search and link check may take")
+	benchCmd.Flags().IntVar(&benchParallel, "parallel", bench.DefaultParallel, "Number of engines benchmarked at once")
+	benchCmd.Flags().IntVar(&benchLinkSample, "links", bench.DefaultLinkSample, "Links checked of the movies found by every search, 0 to check none")
+	benchCmd.Flags().IntVar(&benchHistory, "history", 0, "Show the scoreboard of the last runs instead of running a benchmark")
+	rootCmd.AddCommand(benchCmd)
+}
@@ -0,0 +1,161 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/go-phie/gophie/bot"
+	"github.com/go-phie/gophie/logging"
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	botUpload        bool
+	botPort          string
+	discordPublicKey string
+	discordAppID     string
+	discordToken     string
+)
+
+// botSearch : Search every engine, or the one selected with --engine, applying the CLI filters
+func botSearch(cmd *cobra.Command) bot.SearchFunc {
+	filter := cliFilter()
+	if err := filter.validate(); err != nil {
+		log.Fatal(err)
+	}
+	if !cmd.Flags().Changed("engine") {
+		loadPlugins()
+		return func(ctx context.Context, query string) (engine.SearchResult, error) {
+			result, err := engine.SearchAll(ctx, cliQuery(query))
+			indexMovies(result.Movies)
+			return filter.apply(result), err
+		}
+	}
+	e, err := getEngine(viper.GetString("engine"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return func(ctx context.Context, query string) (engine.SearchResult, error) {
+		result, err := engine.Search(ctx, e, cliQuery(query))
+		return filter.apply(result), err
+	}
+}
+
+// serveDiscord : Serve the interactions of the discord application on port until
+// ctx is done, registering its command first when the bot token is set
+func serveDiscord(ctx context.Context, d *bot.Discord, port string) {
+	if d.Token != "" {
+		if err := d.RegisterCommands(ctx); err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("Registered /%s search for discord application %s", bot.DiscordCommand, d.ApplicationID)
+	}
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("Serving discord interactions on port %s", port)
+	server := &http.Server{Handler: logging.Handler(d)}
+	if err = serveAPI(ctx, server, lis, viper.GetDuration("shutdown-timeout")); err != nil {
+		log.Fatal(err)
+	}
+	d.Wait()
+}
+
+// botCmd represents the bot command
+var botCmd = &cobra.Command{
+	Use:   "bot",
+	Short: "run a chat bot searching movies for its users",
+	Long: `Bot
+			gophie bot --telegram-token <token>
+			gophie bot --telegram-token <token> --engine netnaija --upload
+			gophie bot --discord-public-key <key> --discord-app-id <id> --discord-token <token> --port 3000
+
+	The telegram bot searches every engine, or the engine selected with --engine, for the titles
+	it is sent and replies with a button for every result. Pressing one replies with its download
+	links, or with the file itself with --upload when it is small enough for the platform.
+	The discord application answers /gophie search <title> with an embed of every result, sent
+	to the interactions endpoint URL served on --port. Its command is registered when
+	--discord-token is set. The tokens and keys can also be set with GOPHIE_TELEGRAM_TOKEN,
+	GOPHIE_DISCORD_PUBLIC_KEY, GOPHIE_DISCORD_APP_ID and GOPHIE_DISCORD_TOKEN
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		token := telegramToken
+		if token == "" {
+			token = viper.GetString("telegram-token")
+		}
+		publicKey := viper.GetString("discord-public-key")
+		if token == "" && publicKey == "" {
+			log.Fatal("--telegram-token or --discord-public-key is needed to run a bot")
+		}
+		search := botSearch(cmd)
+
+		var wg sync.WaitGroup
+		if token != "" {
+			telegram := &bot.Telegram{Token: token, Search: search, Upload: botUpload}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := telegram.Run(ctx); err != nil && ctx.Err() == nil {
+					log.Fatal(err)
+				}
+			}()
+		}
+		if publicKey != "" {
+			key, err := bot.ParseDiscordKey(publicKey)
+			if err != nil {
+				log.Fatal(err)
+			}
+			discord := &bot.Discord{
+				PublicKey:     key,
+				ApplicationID: viper.GetString("discord-app-id"),
+				Token:         viper.GetString("discord-token"),
+				Search:        search,
+			}
+			if discord.ApplicationID == "" {
+				log.Fatal("--discord-app-id is needed to answer discord commands")
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				serveDiscord(ctx, discord, botPort)
+			}()
+		}
+		wg.Wait()
+	},
+}
+
+func init() {
+	// shared with the telegram notifications of watch
+	botCmd.Flags().StringVar(&telegramToken, "telegram-token", "", "Token of the telegram bot")
+	botCmd.Flags().BoolVar(&botUpload, "upload", false, "Upload files small enough for the platform instead of sending their links")
+	botCmd.Flags().StringVar(&discordPublicKey, "discord-public-key", "", "Public key of the discord application, verifying its interactions")
+	botCmd.Flags().StringVar(&discordAppID, "discord-app-id", "", "ID of the discord application")
+	botCmd.Flags().StringVar(&discordToken, "discord-token", "", "Token of the discord bot, to register the slash command")
+	botCmd.Flags().StringVarP(&botPort, "port", "p", "3000", "Port the discord interactions endpoint is served on")
+	viper.BindPFlag("discord-public-key", botCmd.Flags().Lookup("discord-public-key"))
+	viper.BindPFlag("discord-app-id", botCmd.Flags().Lookup("discord-app-id"))
+	viper.BindPFlag("discord-token", botCmd.Flags().Lookup("discord-token"))
+	rootCmd.AddCommand(botCmd)
+}
@@ -0,0 +1,245 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-phie/gophie/cast"
+	"github.com/go-phie/gophie/downloader"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	castDevice  string
+	castAddr    string
+	castTimeout time.Duration
+	listDevices bool
+)
+
+// how far forward and back skip
+const castSkip = 30 * time.Second
+
+const castHelp = `Controls: p pause/play, f forward 30s, b back 30s, s <h:mm:ss> seek, q stop`
+
+// castCmd represents the cast command
+var castCmd = &cobra.Command{
+	Use:   "cast [title]",
+	Short: "cast a movie to a Chromecast or DLNA device",
+	Long: `Cast
+			gophie cast --list
+			gophie cast Jumanji --device "Living Room TV"
+
+	The selected movie is served on the local network at --addr and played on the
+	Chromecast or DLNA renderer named by --device, which is asked for when there are
+	several devices. Playback is controlled by typing commands followed by enter
+	` + castHelp + `
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		fmt.Println("Looking for devices...")
+		devices, err := cast.Discover(ctx, castTimeout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if listDevices {
+			printDevices(devices)
+			return
+		}
+		device, err := selectDevice(devices, castDevice)
+		if err != nil {
+			log.Fatal(err)
+		}
+		movie := selectStreamMovie(ctx, strings.Join(args, " "))
+
+		ip, err := cast.LocalIP(device)
+		if err != nil {
+			log.Fatal(err)
+		}
+		listener, proxy, err := listenStream(movie, castAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ctx, stop := context.WithCancel(ctx)
+		defer stop()
+		go func() {
+			if err := serveStreamUntil(ctx, listener, proxy); err != nil {
+				log.Error(err)
+			}
+		}()
+
+		player, err := cast.Connect(ctx, device)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer player.Close()
+		link, contentType := castLink(ip, listener, proxy)
+		if err = player.Load(ctx, link, movie.Title, contentType); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Casting %s to %s\n%s\n", movie.Title, device, castHelp)
+		controlCast(ctx, player, os.Stdin)
+	},
+}
+
+func init() {
+	castCmd.Flags().StringVarP(&castDevice, "device", "d", "", "Name of the device to cast to")
+	castCmd.Flags().StringVar(&castAddr, "addr", ":8765", "Address the device streams the movie from")
+	castCmd.Flags().DurationVar(&castTimeout, "timeout", cast.DefaultTimeout, "How long to look for devices")
+	castCmd.Flags().BoolVar(&listDevices, "list", false, "List the devices found and exit")
+	rootCmd.AddCommand(castCmd)
+}
+
+// printDevices : Print a table of the devices found
+func printDevices(devices []cast.Device) {
+	if len(devices) == 0 {
+		fmt.Println("No devices found")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tKIND\tHOST")
+	for _, device := range devices {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", device.Name, device.Kind, device.Host())
+	}
+	w.Flush()
+}
+
+// selectDevice : The device named name, asking for one when name is empty and
+// there are several
+func selectDevice(devices []cast.Device, name string) (cast.Device, error) {
+	if name != "" {
+		return cast.FindDevice(devices, name)
+	}
+	switch len(devices) {
+	case 0:
+		return cast.Device{}, cast.ErrNoDevice
+	case 1:
+		return devices[0], nil
+	}
+	var names []string
+	for _, device := range devices {
+		names = append(names, device.String())
+	}
+	index, _ := SelectOpts("Cast to", names)
+	return devices[index], nil
+}
+
+// castLink : URL of the stream of proxy on listener for a device reaching this
+// host at ip, and the content type of the file
+func castLink(ip net.IP, listener net.Listener, proxy *downloader.Proxy) (string, string) {
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	contentType := mime.TypeByExtension(path.Ext(proxy.Path()))
+	if contentType == "" {
+		contentType = "video/mp4"
+	}
+	return streamLink(net.JoinHostPort(ip.String(), port), proxy), contentType
+}
+
+// controlCast : Apply the playback commands read from r until q is given, r
+// ends or ctx is cancelled, then stop playback
+func controlCast(ctx context.Context, player cast.Player, r io.Reader) {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	paused := false
+control:
+	for {
+		select {
+		case <-ctx.Done():
+			break control
+		case line, ok := <-lines:
+			if !ok {
+				break control
+			}
+			quit, err := castCommand(ctx, player, line, &paused)
+			if err != nil {
+				fmt.Println(err)
+			}
+			if quit {
+				break control
+			}
+		}
+	}
+	// the context may be cancelled already
+	stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := player.Stop(stopCtx); err != nil {
+		log.Debugf("Could not stop playback: %v", err)
+	}
+}
+
+// castCommand : Apply a playback command, reporting whether to quit
+func castCommand(ctx context.Context, player cast.Player, line string, paused *bool) (bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	switch fields[0] {
+	case "q", "quit", "stop":
+		return true, nil
+	case "p", "pause", "play":
+		var err error
+		if *paused {
+			err = player.Play(ctx)
+		} else {
+			err = player.Pause(ctx)
+		}
+		if err == nil {
+			*paused = !*paused
+		}
+		return false, err
+	case "f", "b":
+		position, err := player.Position(ctx)
+		if err != nil {
+			return false, err
+		}
+		if fields[0] == "f" {
+			position += castSkip
+		} else {
+			position -= castSkip
+		}
+		if position < 0 {
+			position = 0
+		}
+		return false, player.Seek(ctx, position)
+	case "s", "seek":
+		if len(fields) < 2 {
+			return false, fmt.Errorf("Give the position to seek to, e.g s 1:02:03")
+		}
+		position, err := cast.ParseClock(fields[1])
+		if err != nil {
+			return false, err
+		}
+		return false, player.Seek(ctx, position)
+	}
+	return false, fmt.Errorf("Unknown command %q\n%s", fields[0], castHelp)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePlayer : records the playback commands it is given
+type fakePlayer struct {
+	position time.Duration
+	calls    []string
+}
+
+func (p *fakePlayer) Load(ctx context.Context, url, title, contentType string) error {
+	p.calls = append(p.calls, "load")
+	return nil
+}
+func (p *fakePlayer) Play(ctx context.Context) error {
+	p.calls = append(p.calls, "play")
+	return nil
+}
+func (p *fakePlayer) Pause(ctx context.Context) error {
+	p.calls = append(p.calls, "pause")
+	return nil
+}
+func (p *fakePlayer) Seek(ctx context.Context, position time.Duration) error {
+	p.calls = append(p.calls, fmt.Sprintf("seek %s", position))
+	p.position = position
+	return nil
+}
+func (p *fakePlayer) Position(ctx context.Context) (time.Duration, error) {
+	return p.position, nil
+}
+func (p *fakePlayer) Stop(ctx context.Context) error {
+	p.calls = append(p.calls, "stop")
+	return nil
+}
+func (p *fakePlayer) Close() error { return nil }
+
+func TestControlCast(t *testing.T) {
+	player := &fakePlayer{position: 10 * time.Second}
+	input := "p\np\nf\nb\nb\ns 1:02:03\nx\n\nq\np\n"
+	controlCast(context.Background(), player, strings.NewReader(input))
+	expected := "pause,play,seek 40s,seek 10s,seek 0s,seek 1h2m3s,stop"
+	if got := strings.Join(player.calls, ","); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	// playback is stopped when the input ends
+	player = &fakePlayer{}
+	controlCast(context.Background(), player, strings.NewReader("s 1:00\n"))
+	if got := strings.Join(player.calls, ","); got != "seek 1m0s,stop" {
+		t.Errorf("Expected playback to stop at the end of input, got %s", got)
+	}
+}
+
+func TestCastLink(t *testing.T) {
+	movie := streamSource(t, "movie")
+	listener, proxy, err := listenStream(movie, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go serveStreamUntil(ctx, listener, proxy)
+
+	// devices are given a link they can fetch the title with spaces at
+	link, contentType := castLink(net.ParseIP("127.0.0.1"), listener, proxy)
+	if contentType != "video/mp4" {
+		t.Errorf("Expected an mp4 stream, got %s", contentType)
+	}
+	resp, err := http.Get(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "movie" {
+		t.Errorf("Expected the movie at %s, got %s %q", link, resp.Status, body)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var checkTimeout time.Duration
+
+// checkEngines : the engines named by args, every engine when there are none
+func checkEngines(args []string) (map[string]engine.Engine, error) {
+	if len(args) == 0 {
+		return allEngines(), nil
+	}
+	engines := map[string]engine.Engine{}
+	for _, name := range args {
+		e, err := findEngine(name)
+		if err != nil {
+			return nil, err
+		}
+		engines[strings.ToLower(name)] = e
+	}
+	return engines, nil
+}
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check [engine...]",
+	Short: "check which engines are up",
+	Long: `Check
+			gophie check
+			gophie check netnaija fzmovies --timeout 5s
+
+	Every engine (or the given engines) is probed with a lightweight request to its site
+	and reported as up or down with the time it took to respond. Exits with status 1 when
+	an engine is down
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		engines, err := checkEngines(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		statuses := engine.CheckHealth(cmd.Context(), engines, checkTimeout)
+		down := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ENGINE\tSTATUS\tLATENCY\tERROR")
+		for _, status := range statuses {
+			state := "up"
+			if !status.Up {
+				state = "down"
+				down++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Engine, state, status.Latency.Round(time.Millisecond), status.Error)
+		}
+		w.Flush()
+		if down > 0 {
+			fmt.Printf("\n%d of %d engines are down\n", down, len(statuses))
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	checkCmd.Flags().DurationVar(&checkTimeout, "timeout", 10*time.Second, "How long every engine has to respond")
+	rootCmd.AddCommand(checkCmd)
+}
@@ -27,6 +27,9 @@ import (
 var clearCacheCmd = &cobra.Command{
 	Use:   "clear-cache",
 	Short: "Clears the Gophie Cache",
+	Long: `Clear Cache
+			Removes both the cached pages of the source sites and the cached search results
+	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		err := os.RemoveAll(viper.GetString("cache-dir"))
 		if err != nil {
@@ -0,0 +1,117 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"strings"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/refresh"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// refreshModes : the modes of the refresh-modes config, a list or comma separated values
+func refreshModes() ([]engine.ScrapeMode, error) {
+	var modes []engine.ScrapeMode
+	for _, value := range viper.GetStringSlice("refresh-modes") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			mode, err := engine.ParseScrapeMode(name)
+			if err != nil {
+				return nil, err
+			}
+			modes = append(modes, mode)
+		}
+	}
+	return modes, nil
+}
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "host the API and keep the lists of every engine warm in the cache",
+	Long: `Daemon
+			gophie daemon
+			gophie daemon --refresh-interval 10m --refresh-modes latest,trending,popular --refresh-pages 3
+
+	Serves the API like gophie api, and lists the movies of every engine in the
+	--refresh-modes they support every --refresh-interval, scraping the sites and
+	storing the lists in the result cache. List requests are then answered from
+	the cache instead of waiting for the sites. The result cache can only be opened
+	by one process, so the daemon serves the API itself
+	`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindAPIFlags(cmd)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if viper.GetBool("no-cache") {
+			log.Fatal("The daemon keeps the result cache warm, it cannot run with --no-cache")
+		}
+		modes, err := refreshModes()
+		if err != nil {
+			log.Fatal(err)
+		}
+		interval := viper.GetDuration("refresh-interval")
+		if ttl := viper.GetDuration("cache-ttl"); interval >= ttl {
+			log.Warnf("Lists expire from the cache after %s, before they are refreshed every %s, lower --refresh-interval or raise --cache-ttl", ttl, interval)
+		}
+		engines := map[string]engine.Engine{}
+		for name := range allEngines() {
+			e, err := getEngine(name)
+			if err != nil {
+				log.Fatal(err)
+			}
+			engines[name] = e
+		}
+		if resultCache == nil {
+			log.Fatal("The result cache could not be opened")
+		}
+		refresher := &refresh.Refresher{
+			Engines:  engines,
+			New:      getEngine,
+			Modes:    modes,
+			Pages:    viper.GetInt("refresh-pages"),
+			Interval: interval,
+		}
+		ctx := cmd.Context()
+		refreshed := make(chan struct{})
+		go func() {
+			defer close(refreshed)
+			log.Infof("Refreshing the lists of %d engines every %s", len(engines), interval)
+			refresher.Run(ctx)
+		}()
+		runAPI(ctx)
+		<-refreshed
+		closeResultCache()
+		closeIndex()
+		log.Info("Daemon stopped")
+	},
+}
+
+func init() {
+	addAPIFlags(daemonCmd)
+	daemonCmd.Flags().Duration("refresh-interval", refresh.DefaultInterval, "How often the lists of the engines are refreshed")
+	daemonCmd.Flags().StringSlice("refresh-modes", []string{"latest", "trending"}, "Modes the engines are listed in, those an engine does not list are skipped")
+	daemonCmd.Flags().Int("refresh-pages", 1, "Pages of every list refreshed")
+	viper.BindPFlag("refresh-interval", daemonCmd.Flags().Lookup("refresh-interval"))
+	viper.BindPFlag("refresh-modes", daemonCmd.Flags().Lookup("refresh-modes"))
+	viper.BindPFlag("refresh-pages", daemonCmd.Flags().Lookup("refresh-pages"))
+	rootCmd.AddCommand(daemonCmd)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"sync"
+
+	"github.com/go-phie/gophie/desktop"
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/queue"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// alertDownload : Show a desktop notification of the movie downloaded to file,
+// or of its download failing with err, when notifications are on
+func alertDownload(movie engine.Movie, file string, err error) {
+	if !viper.GetBool("notify") {
+		return
+	}
+	if notifyErr := desktop.Download(movie, file, err); notifyErr != nil {
+		log.Debugf("Could not show a desktop notification: %v", notifyErr)
+	}
+}
+
+// alertDrained : Count the downloads of the worker which completed and failed,
+// and show them in a desktop notification once the queue is done
+func alertDrained(worker *queue.Worker) {
+	if !viper.GetBool("notify") {
+		return
+	}
+	var (
+		mu                sync.Mutex
+		completed, failed int
+	)
+	onComplete, onFailed := worker.OnComplete, worker.OnFailed
+	worker.OnComplete = func(item queue.Item) {
+		if onComplete != nil {
+			onComplete(item)
+		}
+		mu.Lock()
+		completed++
+		mu.Unlock()
+	}
+	worker.OnFailed = func(item queue.Item) {
+		if onFailed != nil {
+			onFailed(item)
+		}
+		mu.Lock()
+		failed++
+		mu.Unlock()
+	}
+	worker.OnDrained = func() {
+		mu.Lock()
+		c, f := completed, failed
+		completed, failed = 0, 0
+		mu.Unlock()
+		if err := desktop.QueueDrained(c, f); err != nil {
+			log.Debugf("Could not show a desktop notification: %v", err)
+		}
+	}
+}
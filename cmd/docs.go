@@ -0,0 +1,484 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/go-phie/gophie/bench"
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/index"
+	"github.com/go-phie/gophie/jobs"
+	"github.com/go-phie/gophie/metrics"
+	"github.com/go-phie/gophie/openapi"
+	"github.com/go-phie/gophie/queue"
+	"github.com/go-phie/gophie/subtitle"
+	"github.com/go-phie/gophie/thumbnail"
+)
+
+// apiRoute : a route of the API, used both to serve it and to document it
+type apiRoute struct {
+	Path        string
+	Method      string // GET when empty
+	Name        string // Name of the route in metrics and the OpenAPI operation id
+	Summary     string
+	Description string
+	Handler     http.HandlerFunc
+	Params      []openapi.Parameter
+	// Responses : documented responses, the JSON bodies use the schemas of the API document
+	Responses func(doc *openapi.Document) map[string]openapi.Response
+	// Auth : the route needs an API key (or a white listed host) when API keys are set
+	Auth bool
+	// Defaults : the route sets the default engine and JSON content type
+	Defaults bool
+}
+
+// queryParam : an optional query parameter of a route
+func queryParam(name, kind, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: kind}}
+}
+
+var (
+	engineParam        = queryParam("engine", "string", "Engine to use, all for every engine (search only). Default is fzmovies")
+	pageParam          = queryParam("page", "integer", "Page of results. Default is 1")
+	modeParam          = queryParam("mode", "string", "Order of the movies listed: latest, trending, popular or top_rated. Default is latest, see /capabilities for the modes of each engine")
+	queryParamRequired = openapi.Parameter{
+		Name: "query", In: "query", Required: true, Description: "What to search for, with the operators year:2019, year:2015-2020 and genre:action,comedy", Schema: &openapi.Schema{Type: "string"},
+	}
+)
+
+// filterParams : the query parameters read by requestFilter
+func filterParams() []openapi.Parameter {
+	return []openapi.Parameter{
+		queryParam("quality", "string", "Only return results of a resolution or format e.g 1080p, BluRay"),
+		queryParam("min_size", "string", "Only return results of at least a size e.g 700MB"),
+		queryParam("max_size", "string", "Only return results of at most a size e.g 2GB"),
+		queryParam("year_from", "integer", "Only return results released in or after a year"),
+		queryParam("year_to", "integer", "Only return results released in or before a year"),
+		queryParam("source", "string", "Only return results from these engines, comma separated"),
+		queryParam("lang", "string", "Only return results in these languages, as ISO 639-1 codes e.g en,ko or names e.g korean, comma separated"),
+		queryParam("series", "boolean", "Only return series"),
+		{Name: "sort", In: "query", Description: "Sort results by year or size", Schema: &openapi.Schema{Type: "string", Enum: []string{"year", "size"}}},
+		{Name: "order", In: "query", Description: "Order of sorted results", Schema: &openapi.Schema{Type: "string", Enum: []string{"asc", "desc"}, Default: "asc"}},
+		{Name: "rank_by", In: "query", Description: "Rank results by relevance score or a single criterion before sorting, results of all engines are ranked by score", Schema: &openapi.Schema{Type: "string", Enum: []string{"score", "title", "year", "quality", "source"}}},
+	}
+}
+
+// movieResponses : responses of the routes returning movies
+func movieResponses(doc *openapi.Document) map[string]openapi.Response {
+	return map[string]openapi.Response{
+		"200": openapi.JSON("Movies found", openapi.ArrayOf(doc.SchemaOf(engine.Movie{}))),
+		"400": {Description: "Invalid parameters"},
+		"401": {Description: "Missing or invalid API key"},
+		"429": {Description: "Rate limit exceeded"},
+		"502": {Description: "Engine unavailable"},
+		"504": {Description: "Request cancelled"},
+	}
+}
+
+// engineErrorsHeader : the X-Engine-Errors header of responses whose movies are partial
+var engineErrorsHeader = openapi.Header{
+	Description: "JSON array of the failures of the engines which did not stop the request, with the Engine, " +
+		"the Stage (search, parse or download) and the Message of each. Left out when every engine succeeded",
+	Schema: &openapi.Schema{Type: "string"},
+}
+
+// partialMovieResponses : movieResponses of routes which may respond with the
+// movies found before engines failed
+func partialMovieResponses(doc *openapi.Document) map[string]openapi.Response {
+	responses := movieResponses(doc)
+	ok := responses["200"]
+	ok.Headers = map[string]openapi.Header{"X-Engine-Errors": engineErrorsHeader}
+	responses["200"] = ok
+	return responses
+}
+
+// apiRoutes : the routes served by the api command
+func apiRoutes() []apiRoute {
+	return []apiRoute{
+		{
+			Path: "/search", Name: "search", Summary: "Search movies",
+			Description: "Search an engine, or every engine at once, for movies matching a query",
+			Handler:     SearchHandler, Auth: true, Defaults: true,
+			Params:    append([]openapi.Parameter{queryParamRequired, engineParam, pageParam}, filterParams()...),
+			Responses: partialMovieResponses,
+		},
+		{
+			Path: "/local-search", Name: "local_search", Summary: "Search the local index",
+			Description: "Search the movies found by earlier searches and lists of every engine, kept in a full-text index " +
+				"on the server, instantly and without reaching the sites. Titles weigh the most, descriptions, cast and genres are searched too",
+			Handler: LocalSearchHandler, Auth: true, Defaults: true,
+			Params: append([]openapi.Parameter{queryParamRequired,
+				queryParam("limit", "integer", fmt.Sprintf("Movies returned, the best matches. Default is %d", index.DefaultLimit)),
+			}, filterParams()...),
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				responses := movieResponses(doc)
+				delete(responses, "502")
+				responses["503"] = openapi.Response{Description: "The local index is unavailable"}
+				return responses
+			},
+		},
+		{
+			Path: "/list", Name: "list", Summary: "List recent movies",
+			Description: "List the most recently uploaded, trending, popular or top rated movies of an engine",
+			Handler:     ListHandler, Auth: true, Defaults: true,
+			Params: append([]openapi.Parameter{engineParam, pageParam, modeParam,
+				queryParam("per_page", "integer", "Movies per response, from 1 to 100. Default is every movie of the page of the site"),
+				queryParam("next", "string", "Cursor of the X-Next-Cursor header of the previous response, to continue from where it stopped"),
+			}, filterParams()...),
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				responses := movieResponses(doc)
+				ok := responses["200"]
+				ok.Headers = map[string]openapi.Header{
+					"X-Engine-Errors": engineErrorsHeader,
+					"X-Page":          {Description: "Page of the site the movies start on", Schema: &openapi.Schema{Type: "integer"}},
+					"X-Total-Pages":   {Description: "Pages of the site, left out when the site does not tell", Schema: &openapi.Schema{Type: "integer"}},
+					"X-Has-Next":      {Description: "Whether more movies follow", Schema: &openapi.Schema{Type: "boolean"}},
+					"X-Next-Cursor":   {Description: "The next param of the following movies, left out on the last page", Schema: &openapi.Schema{Type: "string"}},
+					"Link":            {Description: "The URL of the following movies as rel=\"next\"", Schema: &openapi.Schema{Type: "string"}},
+				}
+				responses["200"] = ok
+				return responses
+			},
+		},
+		{
+			Path: "/rss/{engine}", Name: "rss", Summary: "RSS feed of recent movies",
+			Description: "List movies like /list as an RSS 2.0 feed, with the download or magnet link of every movie as " +
+				"the enclosure of its item, for feed readers and download automation. Feed readers can pass the API key as api_key",
+			Handler: RSSHandler, Auth: true,
+			Params: append([]openapi.Parameter{
+				{Name: "engine", In: "path", Required: true, Description: "Engine to list", Schema: &openapi.Schema{Type: "string"}},
+				pageParam, modeParam, queryParam("api_key", "string", "API key, for clients which cannot set headers"),
+			}, filterParams()...),
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				responses := movieResponses(doc)
+				responses["200"] = openapi.Response{Description: "RSS feed of the movies found", Content: map[string]openapi.MediaType{
+					"application/rss+xml": {Schema: &openapi.Schema{Type: "string"}},
+				}}
+				return responses
+			},
+		},
+		{
+			Path: "/stream/search", Name: "stream_search", Summary: "Stream search results",
+			Description: "Search like /search but push every movie as a Server-Sent \"movie\" event as soon as it is scraped. " +
+				"Results are filtered but not sorted, a \"done\" event with the Query and Count ends the stream",
+			Handler: StreamSearchHandler, Auth: true, Defaults: true,
+			Params: append([]openapi.Parameter{queryParamRequired, engineParam, pageParam}, filterParams()...),
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				return map[string]openapi.Response{
+					"200": {Description: "Stream of movie events", Content: map[string]openapi.MediaType{
+						"text/event-stream": {Schema: doc.SchemaOf(engine.Movie{})},
+					}},
+					"400": {Description: "Invalid parameters"},
+					"401": {Description: "Missing or invalid API key"},
+					"429": {Description: "Rate limit exceeded"},
+				}
+			},
+		},
+		{
+			Path: "/subtitle", Name: "subtitle", Summary: "Search subtitles",
+			Description: "List the subtitles matching a query, or redirect to the subtitle file of an id",
+			Handler:     SubtitleHandler, Auth: true, Defaults: true,
+			Params: []openapi.Parameter{
+				queryParam("query", "string", "Title to search subtitles for, required without id"),
+				queryParam("lang", "string", "Language of the subtitles. Default is en"),
+				queryParam("id", "string", "Subtitle to redirect to the file of"),
+			},
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				return map[string]openapi.Response{
+					"200": openapi.JSON("Subtitles found", openapi.ArrayOf(doc.SchemaOf(subtitle.Subtitle{}))),
+					"302": {Description: "Redirect to the subtitle file of id"},
+					"400": {Description: "Missing query or id"},
+					"401": {Description: "Missing or invalid API key"},
+					"502": {Description: "Subtitle provider unavailable"},
+				}
+			},
+		},
+		{
+			Path: "/engine", Name: "engine", Summary: "List engines",
+			Description: "List every engine by name, or describe the engine named by the engine param",
+			Handler:     EngineHandler,
+			Params:      []openapi.Parameter{queryParam("engine", "string", "Engine to describe")},
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				engineSchema := doc.SchemaOf(engine.Props{})
+				return map[string]openapi.Response{
+					"200": openapi.JSON("Engines", &openapi.Schema{OneOf: []*openapi.Schema{
+						{Type: "object", AdditionalProperties: engineSchema},
+						engineSchema,
+					}}),
+					"400": {Description: "Invalid engine param"},
+				}
+			},
+		},
+		{
+			Path: "/image", Name: "image", Summary: "Thumbnail of a cover photo",
+			Description: "Fetch a cover photo from the site of an engine, resize it to a thumbnail and cache it, so that " +
+				"frontends get images which load fast and are not refused for hotlinking. Images can pass the API key as api_key",
+			Handler: ImageHandler, Auth: true,
+			Params: []openapi.Parameter{
+				{Name: "url", In: "query", Required: true, Description: "Link of the image e.g the CoverPhotoLink of a movie", Schema: &openapi.Schema{Type: "string"}},
+				{Name: "size", In: "query", Description: "Width of the thumbnail: small (154px), medium (342px) or large (500px)", Schema: &openapi.Schema{Type: "string", Enum: []string{"small", "medium", "large"}, Default: thumbnail.DefaultSize}},
+				queryParam("api_key", "string", "API key, for clients which cannot set headers"),
+			},
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				return map[string]openapi.Response{
+					"200": {Description: "JPEG thumbnail", Content: map[string]openapi.MediaType{
+						"image/jpeg": {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+					}},
+					"400": {Description: "Invalid parameters"},
+					"401": {Description: "Missing or invalid API key"},
+					"403": {Description: "The image is not on the site of an engine"},
+					"429": {Description: "Rate limit exceeded"},
+					"502": {Description: "The image could not be fetched"},
+				}
+			},
+		},
+		{
+			Path: "/capabilities", Name: "capabilities", Summary: "Engine capabilities",
+			Description: "List the modes every engine, or the engine named by the engine param, lists movies in",
+			Handler:     CapabilitiesHandler,
+			Params:      []openapi.Parameter{queryParam("engine", "string", "Engine to describe")},
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				return map[string]openapi.Response{
+					"200": openapi.JSON("Capabilities of the engines", openapi.ArrayOf(doc.SchemaOf(engine.CapabilityReport{}))),
+					"400": {Description: "Invalid engine param"},
+				}
+			},
+		},
+		{
+			Path: "/health", Name: "health", Summary: "Check engines",
+			Description: "Probe the site of every engine, or the engine named by the engine param, and report whether it is up",
+			Handler:     HealthHandler, Auth: true,
+			Params: []openapi.Parameter{queryParam("engine", "string", "Engine to check")},
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				return map[string]openapi.Response{
+					"200": openapi.JSON("Status of the engines", openapi.ArrayOf(doc.SchemaOf(engine.HealthStatus{}))),
+					"400": {Description: "Invalid engine param"},
+					"401": {Description: "Missing or invalid API key"},
+					"503": openapi.JSON("The engine named by the engine param is down", openapi.ArrayOf(doc.SchemaOf(engine.HealthStatus{}))),
+				}
+			},
+		},
+		{
+			Path: "/bench", Name: "bench", Summary: "Scoreboard of the engines",
+			Description: "The results of the engines over the last runs of gophie bench, best first. Engines are scored from 0 to 1 " +
+				"by the share of their searches which succeeded, of their movies which were parsed and of their links which work",
+			Handler: BenchHandler, Auth: true,
+			Params: []openapi.Parameter{
+				queryParam("runs", "integer", fmt.Sprintf("Last runs added up. Default is %d", defaultBenchRuns)),
+			},
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				return map[string]openapi.Response{
+					"200": openapi.JSON("Results of the engines", openapi.ArrayOf(doc.SchemaOf(bench.Result{}))),
+					"400": {Description: "Invalid runs param"},
+					"401": {Description: "Missing or invalid API key"},
+				}
+			},
+		},
+		{
+			Path: "/downloads", Name: "downloads", Summary: "List downloads",
+			Description: "List the downloads of the queue with their percentage and, while the API downloads them " +
+				"with --download-workers, their speed in bytes per second and the seconds left as ETA",
+			Handler: DownloadsHandler, Auth: true,
+			Params: []openapi.Parameter{
+				{Name: "status", In: "query", Description: "Only list the downloads of a status", Schema: &openapi.Schema{Type: "string", Enum: []string{
+					string(queue.Queued), string(queue.Downloading), string(queue.Paused), string(queue.Completed), string(queue.Failed),
+				}}},
+			},
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				return map[string]openapi.Response{
+					"200": openapi.JSON("Downloads", openapi.ArrayOf(doc.SchemaOf(queue.Download{}))),
+					"401": {Description: "Missing or invalid API key"},
+				}
+			},
+		},
+		{
+			Path: "/downloads/events", Name: "download_events", Summary: "Download events",
+			Description: "A WebSocket pushing a status event for every download of the queue, then an event every time " +
+				"a download progresses, starts, stops, completes, fails or is removed. Events are JSON objects with " +
+				"a Type of progress, status or removed and the Download. WebSockets can pass the API key as api_key",
+			Handler: DownloadEventsHandler, Auth: true,
+			Params: []openapi.Parameter{queryParam("api_key", "string", "API key, for browsers which cannot set the headers of WebSockets")},
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				return map[string]openapi.Response{
+					"101": {Description: "WebSocket of the events, see the Event schema"},
+					"401": {Description: "Missing or invalid API key"},
+					"503": {Description: "The API does not download, it runs without --download-workers"},
+				}
+			},
+		},
+		{
+			Path: "/jobs/search", Method: http.MethodPost, Name: "job_search", Summary: "Search movies in the background",
+			Description: "Submit a search like /search, which runs in the background so that slow searches of many pages " +
+				"or of every engine are not cut short by timeouts, and respond right away with its job. The job is polled " +
+				"at /jobs/{id}, or POSTed to the webhook once finished. Params can be sent in the query or as a form",
+			Handler: JobSearchHandler, Auth: true, Defaults: true,
+			Params: append([]openapi.Parameter{queryParamRequired, engineParam,
+				queryParam("pages", "integer", "Pages of the engine searched. Default is 1"),
+				queryParam("webhook", "string", "URL the job is POSTed to as JSON once it is done or failed"),
+			}, filterParams()...),
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				responses := map[string]openapi.Response{
+					"202": openapi.JSON("Job submitted, pending until a worker runs it", doc.SchemaOf(jobs.Job{})),
+					"400": {Description: "Invalid parameters"},
+					"401": {Description: "Missing or invalid API key"},
+					"405": {Description: "Jobs are submitted with POST"},
+					"429": {Description: "Rate limit exceeded"},
+					"503": {Description: "The API does not run jobs, it runs with --job-workers 0"},
+				}
+				ok := responses["202"]
+				ok.Headers = map[string]openapi.Header{
+					"Location": {Description: "Where the job is polled", Schema: &openapi.Schema{Type: "string"}},
+				}
+				responses["202"] = ok
+				return responses
+			},
+		},
+		{
+			Path: "/jobs/{id}", Name: "job", Summary: "Job of a search",
+			Description: "The status of a job submitted to /jobs/search, with the movies found once it is done " +
+				"or the error once it failed. Finished jobs are kept for --job-ttl",
+			Handler: JobHandler, Auth: true, Defaults: true,
+			Params: []openapi.Parameter{
+				{Name: "id", In: "path", Required: true, Description: "ID of the job", Schema: &openapi.Schema{Type: "string"}},
+			},
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				return map[string]openapi.Response{
+					"200": openapi.JSON("The job", doc.SchemaOf(jobs.Job{})),
+					"401": {Description: "Missing or invalid API key"},
+					"404": {Description: "No job with the ID, or it finished more than --job-ttl ago"},
+					"503": {Description: "The API does not run jobs, it runs with --job-workers 0"},
+				}
+			},
+		},
+		{
+			Path: "/metrics", Name: "metrics", Summary: "Prometheus metrics",
+			Description: "Metrics of the engines and the API in the Prometheus text format",
+			Handler:     metrics.Handler().ServeHTTP, Auth: true,
+			Responses: func(doc *openapi.Document) map[string]openapi.Response {
+				return map[string]openapi.Response{
+					"200": {Description: "Metrics", Content: map[string]openapi.MediaType{
+						"text/plain": {Schema: &openapi.Schema{Type: "string"}},
+					}},
+					"401": {Description: "Missing or invalid API key"},
+				}
+			},
+		},
+	}
+}
+
+// pattern : the pattern the route is served at, /rss/{engine} is served at /rss/
+func (route apiRoute) pattern() string {
+	if i := strings.Index(route.Path, "{"); i >= 0 {
+		return route.Path[:i]
+	}
+	return route.Path
+}
+
+// handler : the handler of the route wrapped in the middlewares it needs
+func (route apiRoute) handler() http.HandlerFunc {
+	handler := route.Handler
+	if route.Method != "" {
+		handler = methodMiddleware(route.Method, handler)
+	}
+	if route.Defaults {
+		handler = getDefaultsMiddleware(handler)
+	}
+	if route.Auth {
+		handler = authenticateRequest(handler)
+	}
+	if route.Path == "/metrics" {
+		// scrapes of the metrics are not counted in them
+		return handler
+	}
+	return metrics.InstrumentHandler(route.Name, handler)
+}
+
+// methodMiddleware : only let requests of method through to handler
+func methodMiddleware(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}
+}
+
+// apiDocument : The OpenAPI document of routes
+func apiDocument(routes []apiRoute) *openapi.Document {
+	doc := openapi.New("Gophie", Version, "Search and list movies from different sources. "+
+		"When API keys are set, requests need one as a bearer token or in the X-API-Key header")
+	doc.Components.SecuritySchemes["bearer"] = &openapi.SecurityScheme{Type: "http", Scheme: "bearer"}
+	doc.Components.SecuritySchemes["apiKey"] = &openapi.SecurityScheme{Type: "apiKey", In: "header", Name: "X-API-Key"}
+	doc.Register("Episode", engine.EpisodeJSON{}, engine.Episode{})
+	doc.Register("Season", engine.Season{})
+	doc.Register("Movie", engine.MovieJSON{}, engine.Movie{})
+	doc.Register("Engine", engine.PropsJSON{}, engine.Props{})
+	doc.Register("Subtitle", subtitle.Subtitle{})
+	doc.Register("Health", engine.HealthStatus{})
+	doc.Register("BenchResult", bench.Result{})
+	doc.Register("Download", queue.Download{})
+	doc.Register("Event", queue.Event{})
+	doc.Register("Job", jobs.Job{})
+
+	for _, route := range routes {
+		op := &openapi.Operation{
+			Summary:     route.Summary,
+			Description: route.Description,
+			OperationID: route.Name,
+			Parameters:  route.Params,
+			Responses:   route.Responses(doc),
+		}
+		if route.Auth {
+			op.Security = []map[string][]string{{"bearer": {}}, {"apiKey": {}}}
+		}
+		if route.Method == http.MethodPost {
+			doc.Post(route.Path, op)
+		} else {
+			doc.Get(route.Path, op)
+		}
+	}
+	return doc
+}
+
+// OpenAPIHandler : serves the OpenAPI document of the API
+func OpenAPIHandler(doc *openapi.Document) http.HandlerFunc {
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		enableCors(&w)
+		w.Header().Add("Content-Type", "application/json")
+		w.Write(b)
+	}
+}
+
+// swaggerUI : page rendering the OpenAPI document at /docs/openapi.json
+const swaggerUI = `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>Gophie API</title>
+    <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@4/swagger-ui.css">
+</head>
+<body>
+    <div id="swagger-ui"></div>
+    <script src="https://unpkg.com/swagger-ui-dist@4/swagger-ui-bundle.js"></script>
+    <script>
+        window.ui = SwaggerUIBundle({ url: "/docs/openapi.json", dom_id: "#swagger-ui" });
+    </script>
+</body>
+</html>
+`
+
+// SwaggerHandler : renders Swagger UI for the OpenAPI document
+func SwaggerHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "text/html")
+	w.Write([]byte(swaggerUI))
+}
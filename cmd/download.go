@@ -0,0 +1,151 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-phie/gophie/downloader"
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	downloadName   string
+	downloadSeason int
+	seasonParallel int
+)
+
+// downloadCmd represents the download command
+var downloadCmd = &cobra.Command{
+	Use:   "download",
+	Short: "download a movie by title or direct link",
+	Long: `Download
+			gophie download The Longest Nights
+			gophie download https://example.com/movie.mp4 --name "The Longest Nights"
+			gophie download --season 2 Devs -e o2tvseries
+
+	When given a title, the movie is searched for on the selected engine and can be picked
+	from the results. When given a link, the file is downloaded directly.
+	Interrupted downloads are resumed from where they stopped when started again.
+	Use --chunks to split large files into several parts downloaded concurrently.
+	With --season every episode of that season of the selected series is downloaded,
+	--parallel at a time, into a directory of the season. Episodes which fail are
+	retried once the others are done.
+	`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		query := strings.Join(args, " ")
+		if downloadSeason > 0 {
+			if err := downloadSeasonOf(cmd.Context(), query, downloadSeason); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		link, err := url.Parse(query)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			searchPager(cmd.Context(), query)
+			return
+		}
+
+		name := downloadName
+		if name == "" {
+			name = strings.TrimSuffix(link.Host, "/")
+		}
+		movie := engine.Movie{Title: name, DownloadLink: link, Source: link.Host}
+		if !confirmDownload(movie) {
+			return
+		}
+		d := downloader.NewMovieDownloader(&movie, viper.GetString("output-dir"))
+		if downloadName == "" {
+			// without a name the file keeps the name it has on the server
+			d.Template = ""
+		}
+		d.OnProgress = downloader.NewProgressBar()
+		if err = d.DownloadFile(); err != nil {
+			notifyDownload(movie, "", err)
+			log.Fatal(err)
+		}
+		finishDownload(movie, d.Path(), d.Vars)
+	},
+}
+
+func init() {
+	downloadCmd.Flags().StringVar(&downloadName, "name", "", "Name to give a download started from a link")
+	downloadCmd.Flags().IntVar(&downloadSeason, "season", 0, "Download every episode of this season of a series")
+	downloadCmd.Flags().IntVar(&seasonParallel, "parallel", downloader.DefaultParallel, "Number of episodes of a season to download at once")
+	rootCmd.AddCommand(downloadCmd)
+}
+
+// downloadSeasonOf : Download every episode of season of the series selected
+// from the results of query
+func downloadSeasonOf(ctx context.Context, query string, season int) error {
+	selectedEngine, err := getEngine(viper.GetString("engine"))
+	if err != nil {
+		return err
+	}
+	series := processSearch(ctx, selectedEngine, compResult, query, "1")
+	if !series.IsSeries {
+		return fmt.Errorf("%s is not a series", series.Title)
+	}
+	onProgress, finish := downloader.NewCombinedProgressBar()
+	download := &downloader.SeasonDownload{
+		Series:     &series,
+		Season:     season,
+		Dir:        viper.GetString("output-dir"),
+		Parallel:   seasonParallel,
+		OnProgress: onProgress,
+		OnEpisode: func(d downloader.EpisodeDownload) {
+			if d.Err != nil {
+				notifyDownload(episodeMovie(series, d), "", d.Err)
+				return
+			}
+			fileDownload(episodeMovie(series, d), d.Downloader.Path(), d.Downloader.Vars, true)
+		},
+	}
+	downloads, err := download.Run(ctx)
+	finish()
+	// scanned once for the whole season
+	scanMediaLibrary()
+	for _, d := range downloads {
+		if d.Err != nil {
+			fmt.Printf("%s failed: %v\n", d.Label, d.Err)
+		}
+	}
+	if err == nil {
+		fmt.Printf("Downloaded %d episodes to %s\n", len(downloads), downloader.SeasonDir(download.Dir, &series, season))
+	}
+	return err
+}
+
+// episodeMovie : The movie of a downloaded episode of series, as recorded in the library
+func episodeMovie(series engine.Movie, d downloader.EpisodeDownload) engine.Movie {
+	return engine.Movie{
+		Title:          series.Title + " " + d.Label,
+		Year:           series.Year,
+		IsSeries:       false,
+		Source:         series.Source,
+		DownloadLink:   d.Episode.DownloadLink,
+		CoverPhotoLink: series.CoverPhotoLink,
+		Description:    series.Description,
+		Size:           d.Episode.Size,
+	}
+}
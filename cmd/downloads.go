@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/go-phie/gophie/logging"
+	"github.com/go-phie/gophie/queue"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"golang.org/x/net/websocket"
+)
+
+// downloadMonitor : the progress of the downloads of the API server, nil unless
+// it runs with --download-workers
+var downloadMonitor *queue.Monitor
+
+// runDownloads : Download the queued movies workers at a time until ctx is done,
+// reporting their progress to downloadMonitor. The channel is closed once the
+// downloads stopped
+func runDownloads(ctx context.Context, workers int) <-chan struct{} {
+	stopped := make(chan struct{})
+	if workers <= 0 {
+		close(stopped)
+		return stopped
+	}
+	downloadMonitor = queue.NewMonitor()
+	worker := newQueueWorker(workers)
+	worker.Monitor = downloadMonitor
+	log.Infof("Downloading queued movies %d at a time", workers)
+	go func() {
+		defer close(stopped)
+		if err := worker.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Errorf("Downloads stopped: %v", err)
+		}
+	}()
+	// WebSockets are not closed by the shutdown of the server
+	go func() {
+		<-ctx.Done()
+		downloadMonitor.Close()
+	}()
+	return stopped
+}
+
+// listDownloads : the downloads of the queue with their progress
+func listDownloads() ([]queue.Download, error) {
+	q, err := queue.Open(path.Join(viper.GetString("cache-dir"), "queue.db"))
+	if err != nil {
+		return nil, err
+	}
+	items, err := q.List()
+	if err != nil {
+		return nil, err
+	}
+	return downloadMonitor.Downloads(items), nil
+}
+
+// DownloadsHandler : lists the downloads of the queue with their percentage, speed
+// and time left, ?status= lists those of a status only
+func DownloadsHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	w.Header().Add("Content-Type", "application/json")
+	downloads, err := listDownloads()
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Could not read the download queue: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if status := strings.ToLower(r.URL.Query().Get("status")); status != "" {
+		var kept []queue.Download
+		for _, download := range downloads {
+			if string(download.Status) == status {
+				kept = append(kept, download)
+			}
+		}
+		downloads = kept
+	}
+	if downloads == nil {
+		downloads = []queue.Download{}
+	}
+	b, err := json.Marshal(downloads)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to serialize response: ", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Write(b)
+}
+
+// DownloadEventsHandler : pushes the status of every download of the queue, then
+// their progress as they are downloaded, over a WebSocket
+func DownloadEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if downloadMonitor == nil {
+		http.Error(w, "Downloads are not running, start the API with --download-workers", http.StatusServiceUnavailable)
+		return
+	}
+	server := websocket.Server{
+		// frontends are served from other origins, as for the CORS of the API
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   downloadEvents,
+	}
+	server.ServeHTTP(w, r)
+}
+
+// downloadEvents : send the events of the downloads to ws until it is closed
+// by the client or the server stops
+func downloadEvents(ws *websocket.Conn) {
+	defer ws.Close()
+	events, unsubscribe := downloadMonitor.Subscribe()
+	defer unsubscribe()
+	downloads, err := listDownloads()
+	if err != nil {
+		logging.FromContext(ws.Request().Context()).Errorf("Could not read the download queue: %v", err)
+		return
+	}
+	for _, download := range downloads {
+		if err = websocket.JSON.Send(ws, &queue.Event{Type: queue.StatusEvent, Download: download}); err != nil {
+			return
+		}
+	}
+	// clients only send to close the connection
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, ws)
+		close(closed)
+	}()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err = websocket.JSON.Send(ws, &event); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
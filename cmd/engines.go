@@ -18,6 +18,9 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/go-phie/gophie/engine"
 	log "github.com/sirupsen/logrus"
@@ -34,8 +37,13 @@ var engineCmd = &cobra.Command{
 
 		gophie engine list (All available engines)
 		gophie engine show (Details about a particular engine)
+		gophie engines --verbose (What every engine supports)
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if verbose {
+			printCapabilities(allEngines())
+			return
+		}
 		fmt.Println(`Engines Summaries and List
 
 	gophie engine list - All available engines
@@ -48,9 +56,18 @@ var listEngineCmd = &cobra.Command{
 	Use:   "list",
 	Short: "lists all available engines",
 	Run: func(cmd *cobra.Command, args []string) {
+		engines := allEngines()
+		if verbose {
+			printCapabilities(engines)
+			return
+		}
 		fmt.Println("Available Engines")
-		for k, v := range engine.GetEngines() {
-			fmt.Printf("\t%s: %s\n", k, v)
+		for _, report := range engine.ReportCapabilities(engines) {
+			var modes []string
+			for _, mode := range report.Modes {
+				modes = append(modes, mode.String())
+			}
+			fmt.Printf("\t%s: %s [%s]\n", report.Engine, engines[report.Engine], strings.Join(modes, ", "))
 		}
 	},
 }
@@ -61,7 +78,7 @@ var showEngineCmd = &cobra.Command{
 	Short: "Show summary of engine ",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		e, err := engine.GetEngine(args[0])
+		e, err := findEngine(args[0])
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -78,3 +95,30 @@ func init() {
 	engineCmd.AddCommand(listEngineCmd)
 	rootCmd.AddCommand(engineCmd)
 }
+
+// yesNo : a capability as printed
+func yesNo(supported bool) string {
+	if supported {
+		return "yes"
+	}
+	return "no"
+}
+
+// printCapabilities : Print a table of what every engine supports
+func printCapabilities(engines map[string]engine.Engine) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ENGINE\tMODES\tLANGUAGES\tSERIES\tPAGES\tYEAR\tLINKS\tPROXY\tANTI-BOT\tJS")
+	for _, report := range engine.ReportCapabilities(engines) {
+		var modes []string
+		for _, mode := range report.Modes {
+			modes = append(modes, mode.String())
+		}
+		links := "direct"
+		if report.Magnet {
+			links = "magnet"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", report.Engine, strings.Join(modes, ","), strings.Join(report.Languages, ","),
+			yesNo(report.Series), yesNo(report.Pagination), yesNo(report.SearchByYear), links, yesNo(report.NeedsProxy), yesNo(report.AntiBot), yesNo(report.JavaScript))
+	}
+	w.Flush()
+}
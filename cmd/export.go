@@ -0,0 +1,264 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-phie/gophie/downloader"
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/export"
+	"github.com/go-phie/gophie/queue"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	exportList     bool
+	exportQueue    bool
+	selectExported bool
+	importDownload bool
+)
+
+// selectMovies : Pick movies of result one after another until done
+func selectMovies(result engine.SearchResult) engine.SearchResult {
+	const done = "Done"
+	selected := engine.SearchResult{Query: result.Query}
+	remaining := append([]engine.Movie{}, result.Movies...)
+	for len(remaining) > 0 {
+		items := []string{fmt.Sprintf("%s (%d selected)", done, len(selected.Movies))}
+		for _, movie := range remaining {
+			items = append(items, movie.Title)
+		}
+		index, _ := SelectOpts("Select the movies", items)
+		if index == 0 {
+			break
+		}
+		selected.Movies = append(selected.Movies, remaining[index-1])
+		remaining = append(remaining[:index-1], remaining[index:]...)
+	}
+	return selected
+}
+
+// queuedResult : the downloads of the queue which are not completed
+func queuedResult() engine.SearchResult {
+	items, err := openQueue().List()
+	if err != nil {
+		log.Fatal(err)
+	}
+	result := engine.SearchResult{Query: "Download queue", Movies: []engine.Movie{}}
+	for _, item := range items {
+		if item.Status != queue.Completed {
+			result.Movies = append(result.Movies, item.Movie)
+		}
+	}
+	return result
+}
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export <file> [query]",
+	Short: "save search results, a list or the download queue to a file",
+	Long: `Export
+			gophie export results.json The Longest Nights
+			gophie export trending.json --list --mode trending -e yts --select
+			gophie export queue.json --queue
+
+	Saves the movies found by a search, the movies listed with --list or the downloads
+	of the queue which are not completed with --queue, so they can be imported and
+	downloaded with gophie import on another machine. The filters of search apply,
+	and --select picks the movies saved. The file is written to stdout when it is -
+	`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if exportList || exportQueue {
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+		return cobra.MinimumNArgs(2)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		var result engine.SearchResult
+		switch {
+		case exportQueue:
+			result = queuedResult()
+		case exportList:
+			mode, err := engine.ParseScrapeMode(listMode)
+			if err != nil {
+				log.Fatal(err)
+			}
+			e, err := getEngine(viper.GetString("engine"))
+			if err != nil {
+				log.Fatal(err)
+			}
+			result = fetchResult(ctx, func() (engine.SearchResult, error) { return engine.ListBy(ctx, e, mode, pageNum) })
+		default:
+			e, err := getEngine(viper.GetString("engine"))
+			if err != nil {
+				log.Fatal(err)
+			}
+			query := strings.Join(args[1:], " ")
+			result = fetchResult(ctx, func() (engine.SearchResult, error) { return searchEngine(ctx, e, query) })
+		}
+		if selectExported && len(result.Movies) > 0 {
+			result = selectMovies(result)
+		}
+
+		f := export.New(result)
+		var err error
+		if args[0] == "-" {
+			err = export.Write(os.Stdout, f)
+		} else {
+			err = export.WriteFile(args[0], f)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("Exported %d movies to %s", len(f.Movies), args[0])
+	},
+}
+
+// importMovies : the movies of result which can be downloaded, series replaced by
+// their episodes. Magnet links are printed as they are opened by torrent clients
+func importMovies(result engine.SearchResult) []engine.Movie {
+	var movies []engine.Movie
+	for _, movie := range result.Movies {
+		switch {
+		case movie.MagnetLink != "":
+			log.Infof("%s is a torrent, open it in a torrent client: %s", movie.Title, movie.MagnetLink)
+		case len(movie.SDownloadLink) > 0 || len(movie.Seasons) > 0:
+			movies = append(movies, episodesResult(movie).Movies...)
+		case movie.DownloadLink == nil || movie.DownloadLink.String() == "":
+			log.Warnf("%s has no download link, skipping it", movie.Title)
+		default:
+			movies = append(movies, movie)
+		}
+	}
+	return movies
+}
+
+// alreadyDownloaded : whether movie is in the library, it is then skipped
+func alreadyDownloaded(movie engine.Movie) bool {
+	entry, found, err := openLibrary().Find(movie)
+	if err != nil || !found {
+		return false
+	}
+	log.Infof("%s was already downloaded to %s, skipping it", movie.Title, entry.Path)
+	return true
+}
+
+// downloadImported : Download movies one after another, failures are reported at the end
+func downloadImported(ctx context.Context, movies []engine.Movie) error {
+	failed := 0
+	for i := range movies {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		movie := &movies[i]
+		if alreadyDownloaded(*movie) {
+			continue
+		}
+		d := downloader.NewMovieDownloader(movie, viper.GetString("output-dir"))
+		d.OnProgress = downloader.NewProgressBar()
+		if err := d.DownloadFileContext(ctx); err != nil {
+			log.Errorf("Could not download %s: %v", movie.Title, err)
+			notifyDownload(*movie, "", err)
+			failed++
+			continue
+		}
+		finishDownload(*movie, d.Path(), d.Vars)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d downloads failed", failed, len(movies))
+	}
+	return nil
+}
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "queue or download the movies of a file saved with gophie export",
+	Long: `Import
+			gophie import results.json
+			gophie import results.json --select --download
+			ssh laptop gophie export - Jumanji | gophie import -
+
+	Adds the movies of a file saved with gophie export, or printed by gophie search
+	--output json, to the download queue for gophie queue run. With --download they
+	are downloaded right away instead, one after another. Series are replaced by
+	their episodes and movies in the library are skipped. The file is read from
+	stdin when it is -
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var (
+			f   export.File
+			err error
+		)
+		if args[0] == "-" {
+			f, err = export.Read(os.Stdin)
+		} else {
+			f, err = export.ReadFile(args[0])
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if f.Host != "" {
+			log.Infof("Importing %d movies found on %s on %s", len(f.Movies), f.Host, f.Exported.Local().Format("2006-01-02 15:04"))
+		}
+		result := f.Result()
+		if selectExported {
+			result = selectMovies(result)
+		}
+		movies := importMovies(result)
+		if importDownload {
+			if err = downloadImported(cmd.Context(), movies); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		q := openQueue()
+		queued := 0
+		for _, movie := range movies {
+			if alreadyDownloaded(movie) {
+				continue
+			}
+			item, err := q.Add(movie)
+			if err != nil {
+				log.Warnf("Could not queue %s: %v", movie.Title, err)
+				continue
+			}
+			queued++
+			log.Debugf("Queued %s as download %d", item.Movie.Title, item.ID)
+		}
+		fmt.Printf("Queued %d movies, download them with gophie queue run\n", queued)
+	},
+}
+
+func init() {
+	exportCmd.Flags().BoolVar(&exportList, "list", false, "Export the movies listed by the engine instead of searching")
+	exportCmd.Flags().StringVarP(&listMode, "mode", "m", "latest", "Movies to list with --list: latest, trending, popular or top_rated")
+	exportCmd.Flags().IntVarP(&pageNum, "page", "p", 1, "Page of the list exported with --list")
+	exportCmd.Flags().IntVar(&searchPages, "pages", 1, "Number of result pages to search and merge")
+	exportCmd.Flags().BoolVar(&exportQueue, "queue", false, "Export the downloads of the queue which are not completed")
+	exportCmd.Flags().BoolVar(&selectExported, "select", false, "Pick the movies exported")
+	importCmd.Flags().BoolVar(&selectExported, "select", false, "Pick the movies imported")
+	importCmd.Flags().BoolVar(&importDownload, "download", false, "Download the movies right away instead of queueing them")
+	rootCmd.AddCommand(exportCmd, importCmd)
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/jobs"
+	"github.com/go-phie/gophie/logging"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// jobRunner : runs the searches submitted to /jobs/search, nil unless the API
+// runs with --job-workers
+var jobRunner *jobs.Runner
+
+// jobParams : the params of /jobs/search which are not filters of the movies found
+var jobParams = map[string]bool{"query": true, "engine": true, "pages": true, "webhook": true, "api_key": true}
+
+// runJobs : Run the submitted jobs workers at a time until ctx is done. The
+// channel is closed once the jobs stopped
+func runJobs(ctx context.Context, workers int) <-chan struct{} {
+	stopped := make(chan struct{})
+	if workers <= 0 {
+		close(stopped)
+		return stopped
+	}
+	store, err := jobs.Open(path.Join(viper.GetString("cache-dir"), "jobs.db"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	jobRunner = jobs.NewRunner(store, workers, searchJob)
+	jobRunner.TTL = viper.GetDuration("job-ttl")
+	jobRunner.Client = &http.Client{Timeout: 30 * time.Second}
+	go func() {
+		defer close(stopped)
+		if err := jobRunner.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Errorf("Jobs stopped: %v", err)
+		}
+	}()
+	return stopped
+}
+
+// searchJob : Search like /search for the movies of req, through its pages
+func searchJob(ctx context.Context, req jobs.Request) ([]engine.Movie, error) {
+	filter, err := queryFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	var result engine.SearchResult
+	if strings.ToLower(req.Engine) == "all" {
+		result, err = engine.SearchAll(ctx, req.Query)
+		indexMovies(result.Movies)
+	} else {
+		site, engineErr := getEngine(req.Engine)
+		if engineErr != nil {
+			return nil, engineErr
+		}
+		pages := req.Pages
+		if pages <= 0 {
+			pages = 1
+		}
+		result, err = engine.SearchPages(ctx, site, req.Query, pages)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return filter.apply(result).Movies, nil
+}
+
+// jobRequest : the job submitted by r, checked so that it can only fail on the sites
+func jobRequest(r *http.Request) (jobs.Request, error) {
+	req := jobs.Request{Query: r.FormValue("query"), Engine: r.FormValue("engine"), Webhook: r.FormValue("webhook"), Pages: 1}
+	if req.Query == "" {
+		return req, errors.New("Query param must be added")
+	}
+	if _, err := engine.ParseSearch(req.Query); err != nil {
+		return req, err
+	}
+	if strings.ToLower(req.Engine) != "all" {
+		if _, err := findEngine(req.Engine); err != nil {
+			return req, errors.New("Invalid Engine Param")
+		}
+	}
+	if pages := r.FormValue("pages"); pages != "" {
+		var err error
+		if req.Pages, err = strconv.Atoi(pages); err != nil || req.Pages < 1 || req.Pages > engine.MaxPages {
+			return req, fmt.Errorf("pages must be a number from 1 to %d", engine.MaxPages)
+		}
+	}
+	if req.Webhook != "" {
+		if u, err := url.Parse(req.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return req, errors.New("webhook must be an http or https URL")
+		}
+	}
+	for name, values := range r.Form {
+		if !jobParams[name] {
+			if req.Filter == nil {
+				req.Filter = url.Values{}
+			}
+			req.Filter[name] = values
+		}
+	}
+	_, err := queryFilter(req.Filter)
+	return req, err
+}
+
+// writeJob : respond with job as JSON
+func writeJob(w http.ResponseWriter, r *http.Request, status int, job jobs.Job) {
+	b, err := json.Marshal(job)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to serialize response: ", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// JobSearchHandler : submits a search run in the background and responds with
+// its job right away, to be polled at /jobs/{id}
+func JobSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if jobRunner == nil {
+		http.Error(w, "Jobs are not running, start the API with --job-workers", http.StatusServiceUnavailable)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, err := jobRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	job, err := jobRunner.Submit(req)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Could not submit job: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	logging.FromContext(r.Context()).WithField("job", job.ID).Info("Submitted search job")
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJob(w, r, http.StatusAccepted, job)
+}
+
+// JobHandler : responds with the job of /jobs/{id}, with the movies found once it is done
+func JobHandler(w http.ResponseWriter, r *http.Request) {
+	if jobRunner == nil {
+		http.Error(w, "Jobs are not running, start the API with --job-workers", http.StatusServiceUnavailable)
+		return
+	}
+	job, err := jobRunner.Store.Get(path.Base(r.URL.Path))
+	if errors.Is(err, jobs.ErrNotFound) {
+		http.Error(w, "Job Not Found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Could not read job: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	writeJob(w, r, http.StatusOK, job)
+}
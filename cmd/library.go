@@ -0,0 +1,152 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/library"
+	"github.com/manifoldco/promptui"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// openLibrary : Open the library of downloaded movies kept in the cache directory
+func openLibrary() *library.Library {
+	l, err := library.Open(path.Join(viper.GetString("cache-dir"), "library.db"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return l
+}
+
+// recordDownload : Add a movie downloaded to path to the library
+func recordDownload(movie engine.Movie, file string) {
+	entry, err := library.NewEntry(movie, file)
+	if err == nil {
+		_, err = openLibrary().Add(entry)
+	}
+	if err != nil {
+		log.Warnf("Could not add %s to the library: %v", movie.Title, err)
+	}
+}
+
+// confirmDownload : Whether to download movie, asking first when it is already in the library
+func confirmDownload(movie engine.Movie) bool {
+	entry, found, err := openLibrary().Find(movie)
+	if err != nil {
+		log.Warnf("Could not check the library: %v", err)
+		return true
+	}
+	if !found {
+		return true
+	}
+	log.Warnf("%s was already downloaded to %s on %s", movie.Title, entry.Path, entry.DownloadedAt.Format("2006-01-02"))
+	prompt := promptui.Prompt{Label: "Download it again", IsConfirm: true}
+	_, err = prompt.Run()
+	return err == nil
+}
+
+// printLibrary : Print library entries as a table
+func printLibrary(entries []library.Entry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tSOURCE\tSIZE\tDOWNLOADED\tPATH")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", entry.ID, entry.Title, entry.Source,
+			engine.FormatSize(entry.Size), entry.DownloadedAt.Format("2006-01-02 15:04"), entry.Path)
+	}
+	w.Flush()
+}
+
+var libraryCmd = &cobra.Command{
+	Use:   "library",
+	Short: "list, search and remove downloaded movies",
+	Long: `Library
+			gophie library list
+			gophie library search batman
+			gophie library remove 3
+
+	Every movie downloaded by gophie is recorded in the library with where it was saved
+	and its checksum. Downloading a movie in the library again asks for confirmation
+	`,
+	// list the library when no subcommand is given
+	Run: func(cmd *cobra.Command, args []string) {
+		libraryListCmd.Run(cmd, args)
+	},
+}
+
+var libraryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the downloaded movies",
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := openLibrary().List()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("The library is empty, movies are added once downloaded")
+			return
+		}
+		printLibrary(entries)
+	},
+}
+
+var librarySearchCmd = &cobra.Command{
+	Use:   "search <title>",
+	Short: "search the downloaded movies by title",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := openLibrary().Search(strings.Join(args, " "))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No downloaded movie matches", strings.Join(args, " "))
+			return
+		}
+		printLibrary(entries)
+	},
+}
+
+var libraryRemoveCmd = &cobra.Command{
+	Use:   "remove <id>...",
+	Short: "remove movies from the library, the files are kept",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		l := openLibrary()
+		for _, arg := range args {
+			id, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				log.Fatalf("%s is not the ID of a movie, see gophie library list", arg)
+			}
+			if err = l.Remove(id); err != nil {
+				log.Fatal(err)
+			}
+		}
+	},
+}
+
+func init() {
+	libraryCmd.AddCommand(libraryListCmd, librarySearchCmd, libraryRemoveCmd)
+	rootCmd.AddCommand(libraryCmd)
+}
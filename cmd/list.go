@@ -16,7 +16,12 @@ limitations under the License.
 package cmd
 
 import (
-	"github.com/go-phie/gophie/downloader"
+	"context"
+	"errors"
+	"os"
+	"path"
+
+	"github.com/go-phie/gophie/crawl"
 	"github.com/go-phie/gophie/engine"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -26,46 +31,34 @@ import (
 
 var (
 	pageNum    int
+	listMode   string
+	scrapeMode engine.ScrapeMode
+	// Crawl the list page after page, from where the last crawl stopped with continueCrawl
+	crawlList     bool
+	continueCrawl bool
+	crawlPages    int
 	compResult = engine.SearchResult{
 		Query:  "",
 		Movies: []engine.Movie{},
 	}
 )
 
-func listPager(pageNum int) {
-	selectedEngine, err := engine.GetEngine(viper.GetString("engine"))
+func listPager(ctx context.Context, pageNum int) {
+	selectedEngine, err := getEngine(viper.GetString("engine"))
 	if err != nil {
 		log.Fatal(err)
 	}
-	selectedMovie := processList(pageNum, selectedEngine, compResult)
+	selectedMovie := processList(ctx, pageNum, selectedEngine, compResult)
 	log.Debugf("Movie: %v\n", selectedMovie)
 	// Start Movie Download
-	if len(selectedMovie.SDownloadLink) < 1 {
-		if err = downloader.DownloadMovie(&selectedMovie, viper.GetString("output-dir")); err != nil {
+	if len(selectedMovie.SDownloadLink) < 1 && len(selectedMovie.Seasons) < 1 {
+		if err = downloadSelectedMovie(ctx, &selectedMovie); err != nil {
 			log.Fatal(err)
 		}
 	} else {
-		var movieArray []engine.Movie
-		index := 0
-		for key, val := range selectedMovie.SDownloadLink {
-			movieArray = append(movieArray,
-				engine.Movie{
-					Index:          index,
-					Title:          key,
-					IsSeries:       false,
-					Source:         selectedMovie.Source,
-					DownloadLink:   val,
-					CoverPhotoLink: selectedMovie.CoverPhotoLink,
-					Description:    selectedMovie.Description,
-				})
-			index++
-		}
-		searchResult := engine.SearchResult{
-			Query:  selectedMovie.Title + " EPISODES",
-			Movies: movieArray,
-		}
-		selectedMovie = processList(pageNum, selectedEngine, searchResult)
-		if err = downloader.DownloadMovie(&selectedMovie, viper.GetString("output-dir")); err != nil {
+		searchResult := episodesResult(selectedMovie)
+		selectedMovie = processList(ctx, pageNum, selectedEngine, searchResult)
+		if err = downloadSelectedMovie(ctx, &selectedMovie); err != nil {
 			log.Fatal(err)
 		}
 	}
@@ -75,19 +68,119 @@ func listPager(pageNum int) {
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "lists the recent movies by page number",
-	Long:  ``,
+	Long: `List
+			gophie list --page 2
+			gophie list --mode trending -e yts
+
+			gophie list --crawl --pages 50 -e netnaija
+			gophie list --continue -e netnaija
+
+	--mode lists the latest, trending, popular or top_rated movies of engines which
+	support it, see gophie engines list for the modes of each engine
+
+	--crawl scrapes the list page after page from --page into the local index,
+	--continue resumes the last crawl of the engine and mode where it stopped
+	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		listPager(pageNum)
+		var err error
+		if scrapeMode, err = engine.ParseScrapeMode(listMode); err != nil {
+			log.Fatal(err)
+		}
+		if crawlList || continueCrawl {
+			runCrawl(cmd.Context())
+			return
+		}
+		if outputFormat != "" {
+			printList(cmd.Context(), pageNum)
+			return
+		}
+		listPager(cmd.Context(), pageNum)
 	},
 }
 
 func init() {
 	listCmd.Flags().IntVarP(&pageNum, "page", "p", 1, "Page Number to search and return from")
+	listCmd.Flags().StringVarP(&listMode, "mode", "m", "latest", "Movies to list: latest, trending, popular or top_rated")
+	listCmd.Flags().BoolVar(&crawlList, "crawl", false, "Scrape the list page after page from --page, adding the movies to the local index")
+	listCmd.Flags().BoolVar(&continueCrawl, "continue", false, "Continue the last crawl of the engine and mode from where it stopped")
+	listCmd.Flags().IntVar(&crawlPages, "pages", 0, "Most pages crawled, every page up to the last when 0")
+	addOutputFlags(listCmd)
 	rootCmd.AddCommand(listCmd)
 }
 
+// printList : Print the movies of a page in the chosen output format
+func printList(ctx context.Context, pageNum int) {
+	selectedEngine, err := getEngine(viper.GetString("engine"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = printResult(ctx, os.Stdout, func() (engine.SearchResult, error) { return engine.ListBy(ctx, selectedEngine, scrapeMode, pageNum) })
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runCrawl : Crawl the list of the engine from --page, or from where its last
+// crawl stopped with --continue, and print the movies found with --output
+func runCrawl(ctx context.Context) {
+	filter := cliFilter()
+	if err := filter.validate(); err != nil {
+		log.Fatal(err)
+	}
+	selectedEngine, err := getEngine(viper.GetString("engine"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !engine.Supports(selectedEngine, scrapeMode) {
+		log.Fatalf("%s does not list %s movies", selectedEngine, scrapeMode)
+	}
+	store, err := crawl.Open(path.Join(viper.GetString("cache-dir"), "crawl.db"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	state := crawl.New(selectedEngine, scrapeMode, pageNum)
+	if continueCrawl {
+		stored, found, err := store.Get(selectedEngine.String(), scrapeMode)
+		switch {
+		case err != nil:
+			log.Fatal(err)
+		case found && stored.Done:
+			log.Infof("The %s movies of %s were crawled up to the last page, start again with --crawl", scrapeMode, selectedEngine)
+			return
+		case found:
+			state = stored
+			log.Infof("Continuing the crawl of %s from page %d", selectedEngine, state.Page)
+		default:
+			log.Infof("No crawl of %s to continue, starting from page %d", selectedEngine, state.Page)
+		}
+	}
+	var movies []engine.Movie
+	scraped := state.Movies
+	state, err = crawl.Crawl(ctx, store, selectedEngine, state, crawlPages, func(movie engine.Movie) error {
+		movie.Index = len(movies)
+		movies = append(movies, movie)
+		return nil
+	})
+	switch {
+	case errors.Is(err, context.Canceled):
+		log.Infof("Crawl stopped at page %d, continue it with --continue", state.Page)
+	case err != nil:
+		log.Warnf("Crawl stopped at page %d: %v, continue it with --continue", state.Page, err)
+	case state.Done:
+		log.Infof("Crawled %s up to its last page, %d movies", selectedEngine, state.Movies)
+	default:
+		log.Infof("Crawled %d movies, continue from page %d with --continue", state.Movies-scraped, state.Page)
+	}
+	if outputFormat != "" {
+		result := filter.apply(engine.SearchResult{Query: selectedEngine.String(), Movies: movies})
+		if err = writeResult(os.Stdout, result, outputFormat, omitEmpty); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
 // Just abstract away the listing process so that it can be reused in other commands
-func processList(pageNum int, e engine.Engine, retrievedResult engine.SearchResult) engine.Movie {
+func processList(ctx context.Context, pageNum int, e engine.Engine, retrievedResult engine.SearchResult) engine.Movie {
 	// Initialize process and show loader on terminal and store result in result
 	var (
 		result      engine.SearchResult
@@ -96,7 +189,7 @@ func processList(pageNum int, e engine.Engine, retrievedResult engine.SearchResu
 		items       []string
 	)
 	if reflect.DeepEqual(retrievedResult, compResult) {
-		result = ProcessFetchTask(func() engine.SearchResult { return e.List(pageNum) })
+		result = ProcessFetchTask(ctx, func() (engine.SearchResult, error) { return engine.ListBy(ctx, e, scrapeMode, pageNum) })
 		items = append(result.Titles(), []string{">>> Next Page"}...)
 		if pageNum != 1 {
 			items = append([]string{"<<< Previous Page"}, items...)
@@ -105,17 +198,17 @@ func processList(pageNum int, e engine.Engine, retrievedResult engine.SearchResu
 
 		if choiceIndex != len(items)-1 {
 			if choiceIndex == 0 && pageNum != 1 {
-				listPager(pageNum - 1)
+				listPager(ctx, pageNum-1)
 			}
 		} else {
-			listPager(pageNum + 1)
+			listPager(ctx, pageNum+1)
 		}
 	} else {
 		result = retrievedResult
 		items = append([]string{"<<< MAIN PAGE"}, result.Titles()...)
 		choiceIndex, choice = SelectOpts(result.Query, items)
 		if choiceIndex == 0 {
-			listPager(pageNum)
+			listPager(ctx, pageNum)
 		}
 	}
 	selectedMovie, err := result.GetMovieByTitle(choice)
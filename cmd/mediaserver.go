@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-phie/gophie/downloader"
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/mediaserver"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	mediaLib     *mediaserver.Library
+	mediaLibOnce sync.Once
+)
+
+// mediaLibraryConfig : media-library in the config file
+type mediaLibraryConfig struct {
+	mediaserver.Library `mapstructure:",squash"`
+	Jellyfin            *mediaserver.Jellyfin
+	Plex                *mediaserver.Plex
+}
+
+// mediaLibrary : The media library of the config file downloads are filed into,
+// nil when none is configured or it is invalid
+func mediaLibrary() *mediaserver.Library {
+	mediaLibOnce.Do(func() {
+		if !viper.IsSet("media-library") {
+			return
+		}
+		var config mediaLibraryConfig
+		if err := viper.UnmarshalKey("media-library", &config); err != nil {
+			log.Warnf("Invalid media-library config: %v", err)
+			return
+		}
+		if err := config.Validate(); err != nil {
+			log.Warn(err)
+			return
+		}
+		client := &http.Client{Timeout: 30 * time.Second}
+		if config.Jellyfin != nil && config.Jellyfin.URL != "" {
+			config.Jellyfin.Client = client
+			config.Scanners = append(config.Scanners, config.Jellyfin)
+		}
+		if config.Plex != nil && config.Plex.URL != "" {
+			config.Plex.Client = client
+			config.Scanners = append(config.Scanners, config.Plex)
+		}
+		mediaLib = &config.Library
+	})
+	return mediaLib
+}
+
+// fileDownload : File the movie downloaded to file into the media library,
+// then record it in the library and notify the webhooks with its path there
+func fileDownload(movie engine.Movie, file string, vars downloader.TemplateVars, episode bool) {
+	if l := mediaLibrary(); l != nil {
+		if dest, err := l.Import(file, vars, episode); err != nil {
+			log.Warn(err)
+		} else {
+			file = dest
+		}
+	}
+	recordDownload(movie, file)
+	notifyDownload(movie, file, nil)
+}
+
+// scanMediaLibrary : Ask the media servers to scan the media library for the
+// downloads filed into it
+func scanMediaLibrary() {
+	l := mediaLibrary()
+	if l == nil || len(l.Scanners) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := l.Scan(ctx); err != nil {
+		log.Warnf("Could not scan the media library: %v", err)
+	}
+}
+
+// finishDownload : File the movie downloaded to file into the media library
+// and have it scanned, recording it and notifying the webhooks
+func finishDownload(movie engine.Movie, file string, vars downloader.TemplateVars) {
+	fileDownload(movie, file, vars, false)
+	scanMediaLibrary()
+}
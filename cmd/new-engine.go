@@ -0,0 +1,81 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/scaffold"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// The engine generated by new-engine
+	scaffoldEngine scaffold.Engine
+	scaffoldDir    string
+	scaffoldForce  bool
+)
+
+// newEngineCmd : writes the skeleton of a new engine into a checkout of gophie
+var newEngineCmd = &cobra.Command{
+	Use:   "new-engine <name>",
+	Short: "Generates the skeleton of a new engine and its test",
+	Long: `New Engine
+			gophie new-engine MovieHub --base-url https://moviehub.example
+			gophie new-engine MovieHub --base-url https://moviehub.example --search-path /search --query-param q
+
+	Writes engine/<name>.go, with the Props, collector callbacks and Search and List
+	methods of an engine to fill in with the selectors of the site, and
+	enginetest/<name>_test.go testing it against fixtures recorded from the site.
+	Run it from the root of a checkout of gophie or point --dir at one
+	`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		scaffoldEngine.Name = args[0]
+		if engine.EngineRegistered(scaffoldEngine.Name) {
+			log.Fatalf("There is already an engine named %s", scaffoldEngine.Name)
+		}
+		written, err := scaffoldEngine.Write(scaffoldDir, scaffoldForce)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, path := range written {
+			fmt.Println("Wrote", path)
+		}
+		fmt.Printf(`
+Fill in the TODOs with the selectors of the site, then record its responses and
+check the movies found:
+
+	GOPHIE_RECORD=1 GOPHIE_UPDATE_GOLDEN=1 go test ./enginetest -run 'Test%[1]s'
+	go test ./enginetest -run 'Test%[1]s'
+	go run . search jumanji -e %[2]s
+`, scaffoldEngine.Type(), strings.ToLower(scaffoldEngine.Name))
+	},
+}
+
+func init() {
+	newEngineCmd.Flags().StringVar(&scaffoldEngine.BaseURL, "base-url", "", "URL of the site the engine scrapes")
+	newEngineCmd.Flags().StringVar(&scaffoldEngine.SearchPath, "search-path", "/", "Path of the search page of the site")
+	newEngineCmd.Flags().StringVar(&scaffoldEngine.ListPath, "list-path", "/", "Path of the page listing the latest uploads of the site")
+	newEngineCmd.Flags().StringVar(&scaffoldEngine.QueryParam, "query-param", "s", "Query parameter of the searches of the site")
+	newEngineCmd.Flags().StringVar(&scaffoldDir, "dir", ".", "Root of the checkout of gophie the engine is written into")
+	newEngineCmd.Flags().BoolVar(&scaffoldForce, "force", false, "Replace the files of an engine generated before")
+	newEngineCmd.MarkFlagRequired("base-url")
+	rootCmd.AddCommand(newEngineCmd)
+}
@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	// Format to print results in instead of selecting one to download
+	outputFormat string
+	// Leave out empty fields of printed results
+	omitEmpty bool
+)
+
+// column : a field of printed results
+type column struct {
+	Name  string
+	Value func(m engine.Movie) interface{}
+}
+
+// kept : whether the column is printed even when empty, the index is how movies are picked
+func (c column) kept() bool {
+	return c.Name == "Index"
+}
+
+// linkString : a link as printed, empty when there is none
+func linkString(link *url.URL) string {
+	if link == nil {
+		return ""
+	}
+	return link.String()
+}
+
+// columns : the fields of printed results, in the order they are printed in
+var columns = []column{
+	{"Index", func(m engine.Movie) interface{} { return m.Index }},
+	{"Title", func(m engine.Movie) interface{} { return m.Title }},
+	{"Year", func(m engine.Movie) interface{} { return m.Year }},
+	{"Quality", func(m engine.Movie) interface{} { return m.Quality.String() }},
+	{"Size", func(m engine.Movie) interface{} { return m.Size }},
+	{"SizeBytes", func(m engine.Movie) interface{} { return m.SizeBytes }},
+	{"Source", func(m engine.Movie) interface{} { return m.Source }},
+	{"IsSeries", func(m engine.Movie) interface{} { return m.IsSeries }},
+	{"Translation", func(m engine.Movie) interface{} { return string(m.Translation) }},
+	{"Language", func(m engine.Movie) interface{} { return m.Language }},
+	{"Region", func(m engine.Movie) interface{} { return m.Region }},
+	{"DownloadLink", func(m engine.Movie) interface{} { return linkString(m.DownloadLink) }},
+	{"DirectLink", func(m engine.Movie) interface{} { return linkString(m.DirectLink) }},
+	{"MagnetLink", func(m engine.Movie) interface{} { return m.MagnetLink }},
+	{"SubtitleLink", func(m engine.Movie) interface{} { return linkString(m.SubtitleLink) }},
+	{"ImdbLink", func(m engine.Movie) interface{} { return m.ImdbLink }},
+	{"CoverPhotoLink", func(m engine.Movie) interface{} { return m.CoverPhotoLink }},
+	{"PosterLink", func(m engine.Movie) interface{} { return m.PosterLink }},
+	{"UploadDate", func(m engine.Movie) interface{} { return m.UploadDate }},
+	{"Category", func(m engine.Movie) interface{} { return m.Category }},
+	{"Genres", func(m engine.Movie) interface{} { return m.Genres }},
+	{"Cast", func(m engine.Movie) interface{} { return m.Cast }},
+	{"Tags", func(m engine.Movie) interface{} { return m.Tags }},
+	{"CanonicalTitle", func(m engine.Movie) interface{} { return m.CanonicalTitle }},
+	{"Rating", func(m engine.Movie) interface{} { return m.Rating }},
+	{"Score", func(m engine.Movie) interface{} { return m.Score }},
+	{"Runtime", func(m engine.Movie) interface{} { return m.Runtime }},
+	{"Description", func(m engine.Movie) interface{} { return strings.TrimSpace(m.Description) }},
+}
+
+// tableColumns : the fields short enough to be printed as a table
+var tableColumns = []string{"Index", "Title", "Year", "Quality", "Size", "Source", "Language", "IsSeries"}
+
+// isEmpty : whether a field value is its zero value
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return v == ""
+	case int:
+		return v == 0
+	case int64:
+		return v == 0
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	case json.RawMessage:
+		return len(v) == 0 || string(v) == "[]"
+	}
+	return value == nil
+}
+
+// formatValue : a field value as printed in csv and table output
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if !v {
+			return ""
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// validOutputFormat : whether results can be printed in format
+func validOutputFormat(format string) bool {
+	switch format {
+	case "json", "csv", "table":
+		return true
+	}
+	return false
+}
+
+// writeResult : Print the movies of result in format. With omitEmpty, empty
+// fields are left out of JSON objects and columns empty for every movie are
+// left out of csv and table output
+func writeResult(w io.Writer, result engine.SearchResult, format string, omitEmpty bool) error {
+	return writeMovies(w, result.Movies, format, omitEmpty)
+}
+
+// writeGroups : Print the canonical movie of every group like writeResult, with
+// its other copies in an Alternatives column
+func writeGroups(w io.Writer, groups []engine.MovieGroup, format string, omitEmpty bool) error {
+	movies := make([]engine.Movie, len(groups))
+	alternatives := map[int][]engine.Movie{}
+	for i, group := range groups {
+		movies[i] = group.Movie
+		alternatives[group.Movie.Index] = group.Alternatives
+	}
+	alternativesColumn := column{"Alternatives", func(m engine.Movie) interface{} {
+		copies := alternatives[m.Index]
+		if format == "json" {
+			// the copies are objects of the same fields, in an array
+			var b strings.Builder
+			writeObjects(&b, copies, columns, omitEmpty, "    ")
+			return json.RawMessage(b.String())
+		}
+		labels := make([]string, len(copies))
+		for i, copy := range copies {
+			labels[i] = copyLabel(copy)
+		}
+		return strings.Join(labels, "; ")
+	}}
+	return writeMovies(w, movies, format, omitEmpty, alternativesColumn)
+}
+
+// writeMovies : Print movies in format with the columns of the format and extra
+func writeMovies(w io.Writer, movies []engine.Movie, format string, omitEmpty bool, extra ...column) error {
+	selected := columns
+	if format == "table" {
+		selected = []column{}
+		for _, name := range tableColumns {
+			for _, c := range columns {
+				if c.Name == name {
+					selected = append(selected, c)
+				}
+			}
+		}
+	}
+	selected = append(append([]column{}, selected...), extra...)
+	if omitEmpty && format != "json" {
+		used := []column{}
+		for _, c := range selected {
+			for _, movie := range movies {
+				if c.kept() || !isEmpty(c.Value(movie)) {
+					used = append(used, c)
+					break
+				}
+			}
+		}
+		selected = used
+	}
+
+	switch format {
+	case "json":
+		return writeJSON(w, movies, selected, omitEmpty)
+	case "csv":
+		out := csv.NewWriter(w)
+		header := make([]string, len(selected))
+		for i, c := range selected {
+			header[i] = c.Name
+		}
+		out.Write(header)
+		for _, movie := range movies {
+			record := make([]string, len(selected))
+			for i, c := range selected {
+				record[i] = formatValue(c.Value(movie))
+			}
+			out.Write(record)
+		}
+		out.Flush()
+		return out.Error()
+	case "table":
+		out := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		header := make([]string, len(selected))
+		for i, c := range selected {
+			header[i] = strings.ToUpper(c.Name)
+		}
+		fmt.Fprintln(out, strings.Join(header, "\t"))
+		for _, movie := range movies {
+			row := make([]string, len(selected))
+			for i, c := range selected {
+				// tabs and new lines would break the alignment
+				row[i] = strings.Join(strings.Fields(formatValue(c.Value(movie))), " ")
+			}
+			fmt.Fprintln(out, strings.Join(row, "\t"))
+		}
+		return out.Flush()
+	}
+	return fmt.Errorf("unsupported output %q, use json, csv or table", format)
+}
+
+// writeJSON : Print movies as a JSON array of objects with their fields in the
+// order of the columns
+func writeJSON(w io.Writer, movies []engine.Movie, selected []column, omitEmpty bool) error {
+	var b strings.Builder
+	if err := writeObjects(&b, movies, selected, omitEmpty, ""); err != nil {
+		return err
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeObjects : Write movies as a JSON array of objects, indented by indent
+func writeObjects(b *strings.Builder, movies []engine.Movie, selected []column, omitEmpty bool, indent string) error {
+	b.WriteString("[")
+	for i, movie := range movies {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n" + indent + "  {")
+		written := 0
+		for _, c := range selected {
+			value := c.Value(movie)
+			if omitEmpty && !c.kept() && isEmpty(value) {
+				continue
+			}
+			encoded, err := json.Marshal(value)
+			if raw, ok := value.(json.RawMessage); ok {
+				encoded, err = raw, nil
+			}
+			if err != nil {
+				return err
+			}
+			if written > 0 {
+				b.WriteString(",")
+			}
+			fmt.Fprintf(b, "\n%s    %q: %s", indent, c.Name, encoded)
+			written++
+		}
+		b.WriteString("\n" + indent + "  }")
+	}
+	if len(movies) > 0 {
+		b.WriteString("\n" + indent)
+	}
+	b.WriteString("]")
+	return nil
+}
+
+// addOutputFlags : Add the flags choosing how results are printed to cmd
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&outputFormat, "output", "", "Print the results as json, csv or table instead of selecting one to download")
+	cmd.Flags().BoolVar(&omitEmpty, "omit-empty", false, "Leave out empty fields of printed results")
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if outputFormat != "" && !validOutputFormat(outputFormat) {
+			return fmt.Errorf("unsupported output %q, use json, csv or table", outputFormat)
+		}
+		return nil
+	}
+}
+
+// printResult : Fetch a result and print it in the format chosen with --output
+func printResult(ctx context.Context, w io.Writer, fn fetchFunc) error {
+	result := fetchResult(ctx, fn)
+	if viper.GetBool("group-duplicates") {
+		return writeGroups(w, engine.GroupDuplicates(result.Movies), outputFormat, omitEmpty)
+	}
+	return writeResult(w, result, outputFormat, omitEmpty)
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+func TestWriteResult(t *testing.T) {
+	link, _ := url.Parse("https://fz.example/jumanji.mp4")
+	result := engine.SearchResult{Movies: []engine.Movie{
+		{Index: 0, Title: "Jumanji", Year: 1995, DownloadLink: link, Source: "FzMovies", Quality: engine.ParseQuality("720p")},
+		{Index: 1, Title: "Jumanji: The Next Level", Source: "FzMovies", Description: "Back\tto the\ngame"},
+	}}
+
+	var out bytes.Buffer
+	if err := writeResult(&out, result, "json", true); err != nil {
+		t.Fatal(err)
+	}
+	var movies []map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &movies); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", out.String(), err)
+	}
+	if len(movies) != 2 || movies[0]["DownloadLink"] != link.String() || movies[0]["Quality"] != "720p" {
+		t.Errorf("Unexpected movies %v", movies)
+	}
+	if _, ok := movies[1]["Year"]; ok {
+		t.Errorf("Expected empty fields to be omitted, got %v", movies[1])
+	}
+	if !strings.HasPrefix(out.String(), "[\n  {\n    \"Index\": 0,\n    \"Title\": \"Jumanji\"") {
+		t.Errorf("Expected fields in a stable order, got %s", out.String())
+	}
+
+	out.Reset()
+	if err := writeResult(&out, result, "csv", false); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || len(records[0]) != len(columns) || records[0][0] != "Index" || records[1][1] != "Jumanji" {
+		t.Errorf("Unexpected csv %v", records)
+	}
+
+	out.Reset()
+	if err := writeResult(&out, result, "table", true); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "INDEX") || strings.Contains(lines[0], "ISSERIES") {
+		t.Errorf("Unexpected table %q", out.String())
+	}
+
+	if writeResult(&out, result, "xml", false) == nil || validOutputFormat("xml") {
+		t.Errorf("Expected unsupported formats to be rejected")
+	}
+}
+
+func TestWriteGroups(t *testing.T) {
+	groups := engine.GroupDuplicates([]engine.Movie{
+		{Index: 0, Title: "Jumanji (1995)", Source: "FzMovies", Quality: engine.ParseQuality("720p")},
+		{Index: 1, Title: "Jumanji", Year: 1995, Source: "NetNaija", Quality: engine.ParseQuality("1080p"), SizeBytes: 2 << 30},
+		{Index: 2, Title: "Jumanji: The Next Level", Source: "FzMovies"},
+	})
+	var out bytes.Buffer
+	if err := writeGroups(&out, groups, "json", true); err != nil {
+		t.Fatal(err)
+	}
+	var movies []struct {
+		Title        string
+		Alternatives []map[string]interface{}
+	}
+	if err := json.Unmarshal(out.Bytes(), &movies); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", out.String(), err)
+	}
+	if len(movies) != 2 || len(movies[0].Alternatives) != 1 || movies[0].Alternatives[0]["Source"] != "NetNaija" || movies[1].Alternatives != nil {
+		t.Errorf("Unexpected groups %s", out.String())
+	}
+
+	out.Reset()
+	if err := writeGroups(&out, groups, "table", true); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], "ALTERNATIVES") || !strings.HasSuffix(lines[1], "NetNaija 1080p 2.0 GB") {
+		t.Errorf("Unexpected table %q", out.String())
+	}
+}
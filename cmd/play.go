@@ -0,0 +1,90 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/player"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// playCmd represents the play command
+var playCmd = &cobra.Command{
+	Use:   "play [title]",
+	Short: "play a movie in mpv or VLC",
+	Long: `Play
+			gophie play Jumanji
+			gophie play Jumanji --player-command "vlc --fullscreen"
+
+	The selected movie is resolved to the direct link of its file and played in the
+	player of --player-command, mpv or VLC when it is not set. {url} and {title} in
+	the command are replaced by the link and the title of the movie, the link is
+	appended otherwise. The cookies, User-Agent and Referer the host requires are
+	passed to mpv and VLC, players which cannot send them are given a local URL
+	adding them instead
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		p, err := player.Parse(viper.GetString("player-command"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		movie := selectStreamMovie(cmd.Context(), strings.Join(args, " "))
+		if err := engine.ResolveMovieLink(cmd.Context(), &movie); err != nil {
+			log.Warnf("Could not resolve the direct link of %s, playing its download link: %v", movie.Title, err)
+		}
+		if err := playWith(cmd.Context(), p, movie); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// playWith : Play movie with p until it exits or ctx is cancelled, through a
+// local URL when p cannot send the headers the host of the movie requires
+func playWith(ctx context.Context, p *player.Player, movie engine.Movie) error {
+	header := engine.LinkHeaders(&movie)
+	link := movie.Link().String()
+	if !p.Supports(header) {
+		listener, proxy, err := listenStream(movie, "127.0.0.1:0")
+		if err != nil {
+			return err
+		}
+		proxy.Header = header
+		serveCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		go func() {
+			if err := serveStreamUntil(serveCtx, listener, proxy); err != nil {
+				log.Error(err)
+			}
+		}()
+		link, header = streamLink(listener.Addr().String(), proxy), nil
+	}
+	c := p.Cmd(ctx, link, movie.Title, header)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	log.Infof("Playing %s with %s", movie.Title, p.Name())
+	return c.Run()
+}
+
+func init() {
+	playCmd.Flags().String("player-command", "", "Command of the player, mpv or vlc when empty")
+	viper.BindPFlag("player-command", playCmd.Flags().Lookup("player-command"))
+	rootCmd.AddCommand(playCmd)
+}
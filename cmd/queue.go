@@ -0,0 +1,219 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-phie/gophie/downloader"
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/queue"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	queueName    string
+	queueWorkers int
+)
+
+// openQueue : Open the download queue kept in the cache directory
+func openQueue() *queue.Queue {
+	q, err := queue.Open(path.Join(viper.GetString("cache-dir"), "queue.db"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return q
+}
+
+// selectMovie : Search the selected engine for query and pick a movie, or an
+// episode of a series, to download
+func selectMovie(ctx context.Context, query string) engine.Movie {
+	e, err := getEngine(viper.GetString("engine"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	result := ProcessFetchTask(ctx, func() (engine.SearchResult, error) { return searchEngine(ctx, e, query) })
+	movie, err := pickMovie(result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(movie.SDownloadLink) > 0 || len(movie.Seasons) > 0 {
+		episodes := episodesResult(movie)
+		_, choice := SelectOpts(episodes.Query, episodes.Titles())
+		if movie, err = episodes.GetMovieByTitle(choice); err != nil {
+			log.Fatal(err)
+		}
+	}
+	return movie
+}
+
+// queueID : The ID of a download in the queue given as an argument
+func queueID(arg string) uint64 {
+	id, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil {
+		log.Fatalf("%s is not the ID of a download, see gophie queue list", arg)
+	}
+	return id
+}
+
+// addCmd represents the add command
+var addCmd = &cobra.Command{
+	Use:   "add",
+	Short: "add a movie to the download queue by title or direct link",
+	Long: `Add
+			gophie add The Longest Nights
+			gophie add https://example.com/movie.mp4 --name "The Longest Nights"
+
+	Queued movies are downloaded by gophie queue run
+	`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var movie engine.Movie
+		query := strings.Join(args, " ")
+		link, err := url.Parse(query)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			movie = selectMovie(cmd.Context(), query)
+		} else {
+			movie = engine.Movie{Title: queueName, DownloadLink: link, Source: link.Host}
+			if movie.Title == "" {
+				movie.Title = path.Base(link.Path)
+			}
+		}
+		if !confirmDownload(movie) {
+			return
+		}
+		item, err := openQueue().Add(movie)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Queued %s as download %d\n", item.Movie.Title, item.ID)
+	},
+}
+
+// queueCmd represents the queue command
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "manage and run the download queue",
+	Long: `Queue
+			gophie queue list
+			gophie queue run --workers 3
+			gophie queue pause 2
+
+	Movies added with gophie add are downloaded a few at a time by gophie queue run.
+	Downloads stopped by pausing them or by stopping the worker keep what was
+	downloaded and are resumed from there
+	`,
+	// list the queue when no subcommand is given
+	Run: func(cmd *cobra.Command, args []string) {
+		queueListCmd.Run(cmd, args)
+	},
+}
+
+var queueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the downloads in the queue",
+	Run: func(cmd *cobra.Command, args []string) {
+		items, err := openQueue().List()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(items) == 0 {
+			fmt.Println("The download queue is empty, add movies with gophie add")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSTATUS\tPROGRESS\tTITLE")
+		for _, item := range items {
+			progress := "-"
+			if p := item.Progress(); p >= 0 {
+				progress = fmt.Sprintf("%.1f%%", p)
+			}
+			status := string(item.Status)
+			if item.Retries > 0 {
+				status += fmt.Sprintf(" (retry %d)", item.Retries)
+			}
+			if item.Error != "" {
+				status += ": " + item.Error
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", item.ID, status, progress, item.Movie.Title)
+		}
+		w.Flush()
+	},
+}
+
+var queueRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "download the queued movies until stopped with ctrl-C",
+	Run: func(cmd *cobra.Command, args []string) {
+		worker := newQueueWorker(queueWorkers)
+		log.Infof("Downloading queued movies %d at a time, stop with ctrl-C", queueWorkers)
+		if err := worker.Run(cmd.Context()); err != nil && cmd.Context().Err() == nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// newQueueWorker : A worker downloading the queued movies workers at a time into
+// the output directory, filing them into the media library, recording them in
+// the library and notifying the webhooks
+func newQueueWorker(workers int) *queue.Worker {
+	worker := queue.NewWorker(openQueue(), workers, viper.GetString("output-dir"))
+	worker.OnComplete = func(item queue.Item) {
+		finishDownload(item.Movie, item.Path, downloader.MovieVars(&item.Movie))
+	}
+	notifyQueued(worker)
+	alertDrained(worker)
+	return worker
+}
+
+// queueAction : A command changing the downloads with the IDs given
+func queueAction(use, short string, action func(q *queue.Queue, id uint64) error) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " <id>...",
+		Short: short,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			q := openQueue()
+			for _, arg := range args {
+				if err := action(q, queueID(arg)); err != nil {
+					log.Fatal(err)
+				}
+			}
+		},
+	}
+}
+
+func init() {
+	addCmd.Flags().StringVar(&queueName, "name", "", "Name to give a download added from a link")
+	queueRunCmd.Flags().IntVarP(&queueWorkers, "workers", "w", queue.DefaultWorkers, "Number of movies downloaded at the same time")
+	queueCmd.AddCommand(
+		queueListCmd,
+		queueRunCmd,
+		queueAction("pause", "pause downloads, keeping what was downloaded", (*queue.Queue).Pause),
+		queueAction("resume", "resume paused or failed downloads", (*queue.Queue).Resume),
+		queueAction("remove", "remove downloads from the queue, downloaded files are kept", (*queue.Queue).Remove),
+	)
+	rootCmd.AddCommand(addCmd, queueCmd)
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Windows kept before expired ones are swept, and the oldest dropped when none has expired
+const maxRateWindows = 10000
+
+// rateLimiter : Limits every client (API key or IP) to a number of requests per window
+type rateLimiter struct {
+	limit   int // requests allowed per window, 0 or less for no limit
+	window  time.Duration
+	max     int // windows kept, see maxRateWindows
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+	now     func() time.Time
+}
+
+// rateWindow : requests made by a client in the current window
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, max: maxRateWindows, windows: map[string]*rateWindow{}, now: time.Now}
+}
+
+// take : Count a request of client, returning whether it is allowed, the requests left
+// and when the window resets
+func (l *rateLimiter) take(client string) (bool, int, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	w, ok := l.windows[client]
+	if !ok && len(l.windows) >= l.max {
+		l.sweep(now)
+	}
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.windows[client] = w
+	}
+	reset := w.start.Add(l.window)
+	if w.count >= l.limit {
+		return false, 0, reset
+	}
+	w.count++
+	return true, l.limit - w.count, reset
+}
+
+// sweep : Drop the expired windows, or the oldest one when none has expired so
+// that the windows kept stay under max however many clients there are
+func (l *rateLimiter) sweep(now time.Time) {
+	var (
+		oldest string
+		start  time.Time
+	)
+	for c, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, c)
+		} else if start.IsZero() || w.start.Before(start) {
+			oldest, start = c, w.start
+		}
+	}
+	if len(l.windows) >= l.max {
+		delete(l.windows, oldest)
+	}
+}
+
+// allowCall : Count a call of client, returning whether it is within the limit
+func (l *rateLimiter) allowCall(client string) bool {
+	if l == nil || l.limit <= 0 {
+		return true
+	}
+	ok, _, _ := l.take(client)
+	return ok
+}
+
+// allow : Count a request of client setting the X-RateLimit-* headers, responding
+// with 429 Too Many Requests when the client is over its limit
+func (l *rateLimiter) allow(w http.ResponseWriter, client string) bool {
+	if l == nil || l.limit <= 0 {
+		return true
+	}
+	ok, remaining, reset := l.take(client)
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(l.limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	if !ok {
+		retry := int(reset.Sub(l.now()).Seconds() + 1)
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+	}
+	return ok
+}
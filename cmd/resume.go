@@ -31,7 +31,7 @@ var ResumeCmd = &cobra.Command{
 	Use:   "resume",
 	Short: "resume downloads for previously stopped movies",
 	Long: `Resume
-			Gophie makes use of the annie downloader to resume previous downloads
+			Gophie resumes previous downloads from where they stopped using range requests
 	
 	It stores the list of the downloads and gives you the option to remove or resume
 	download
@@ -64,7 +64,11 @@ var ResumeCmd = &cobra.Command{
 			log.Fatalf("Prompt failed: %v\n", err)
 		}
 		selectedDownloader := resume[choiceIndex]
-		selectedDownloader.DownloadFile()
+		selectedDownloader.OnProgress = downloader.NewProgressBar()
+		selectedDownloader.Space = downloader.ConfiguredSpaceGuard()
+		if err = selectedDownloader.DownloadFile(); err != nil {
+			log.Fatal(err)
+		}
 	},
 }
 
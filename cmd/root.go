@@ -16,12 +16,21 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path"
 	"runtime"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/go-phie/gophie/downloader"
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/logging"
+	"github.com/go-phie/gophie/plugins"
+	"github.com/go-phie/gophie/transport"
 	homedir "github.com/mitchellh/go-homedir"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -48,6 +57,65 @@ var (
 	ignoreCache bool
 	// use Chrome Driver
 	useChromeDriver bool
+	// Number of concurrent ranges to download files in
+	chunks int
+	// Skip the search result cache
+	noCache bool
+	// How long search results are cached for
+	cacheTTL time.Duration
+	// Do not add the movies found to the local index
+	noIndex bool
+	// API Keys of metadata providers used to enrich results
+	tmdbAPIKey string
+	omdbAPIKey string
+	// API Key of OpenSubtitles used to search subtitles
+	openSubtitlesAPIKey string
+	// Config file to read settings such as the http client configuration from
+	configFile string
+	// Proxy to reach the source sites through
+	proxy string
+	// Reach the source sites and download through Tor
+	useTor   bool
+	torProxy string
+	// Resolution or format results are filtered by
+	quality string
+	// Years and genres searched for, see engine.ParseQuery
+	year   string
+	genres []string
+	// Years, sources, languages and kind results are filtered by
+	yearFrom   int
+	yearTo     int
+	minSize    string
+	maxSize    string
+	sources    []string
+	languages  []string
+	seriesOnly bool
+	// Order of results
+	sortBy     string
+	descending bool
+	// Templates of the paths downloads are saved at
+	filenameTemplate string
+	episodeTemplate  string
+	// Check the download links of results and drop those which are dead
+	verifyLinks bool
+	// Follow the download links of results to the files they lead to
+	resolveLinks bool
+	// Criterion results are ranked by
+	rankBy string
+	// List the copies of a movie from several engines once
+	groupDuplicates bool
+	// Level and format of the logs
+	logLevel  string
+	logFormat string
+	// Bandwidth downloads are limited to, and at which times of day
+	limitRate    string
+	rateSchedule []string
+	// Free space downloads must leave on the disk
+	minFree     string
+	spaceMargin float64
+	ignoreSpace bool
+	// Show desktop notifications of downloads, watched movies and the queue
+	notifyDesktop bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -56,23 +124,33 @@ var rootCmd = &cobra.Command{
 	Short: "A CLI for downloading movies from different sources",
 	Long:  `Gophie`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		// Log only errors except in Verbose mode
+		// Verbose mode logs everything whatever the log level
+		level := viper.GetString("log-level")
 		if verbose {
-			log.SetLevel(log.DebugLevel)
-		} else {
-			log.SetLevel(log.InfoLevel)
+			level = "debug"
+		}
+		if err := logging.Configure(level, viper.GetString("log-format")); err != nil {
+			log.Fatal(err)
 		}
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		// keep printed results parsable
+		if outputFormat != "" {
+			return
+		}
 		fmt.Println("\n\nGophie - Bisoncorp (2020) (https://github.com/go-phie/gophie)")
 	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// Interrupting or terminating the process cancels the context passed down to the engines
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Println(err)
+		stop()
 		os.Exit(1)
 	}
 }
@@ -90,21 +168,108 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(
 		&seleniumURL, "selenium-url", "s", "", "The URL of selenium instance to use")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Display Verbose logs")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Level of the logs: debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatText, "Format of the logs: text or json")
 	rootCmd.PersistentFlags().StringVarP(&outputPath, "output-dir", "o", "", "Path to download files to")
 	rootCmd.PersistentFlags().BoolVar(&ignoreCache, "ignore-cache", false, "Ignore Cache and makes new requests")
-	rootCmd.PersistentFlags().BoolVar(&useChromeDriver, "use-chrome-driver", false, "Use Selenium Driver")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Do not serve or store search results in the result cache")
+	rootCmd.PersistentFlags().DurationVar(&cacheTTL, "cache-ttl", time.Hour, "How long search results are cached for")
+	rootCmd.PersistentFlags().BoolVar(&noIndex, "no-index", false, "Do not add the movies found to the local index searched with search --offline")
+	rootCmd.PersistentFlags().StringVar(&tmdbAPIKey, "tmdb-api-key", "", "TMDB API key used to enrich results with metadata")
+	rootCmd.PersistentFlags().StringVar(&omdbAPIKey, "omdb-api-key", "", "OMDB API key used to enrich results with metadata")
+	rootCmd.PersistentFlags().StringVar(&openSubtitlesAPIKey, "opensubtitles-api-key", "", "OpenSubtitles API key used to search subtitles")
+	rootCmd.PersistentFlags().BoolVar(&useChromeDriver, "use-chrome-driver", false, "Solve the anti-bot challenges of sites with a headless Chrome")
+	rootCmd.PersistentFlags().IntVar(&chunks, "chunks", 1, "Number of parts to download files in concurrently")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file (default is $HOME/.config/gophie/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&quality, "quality", "", "Only show results of a resolution or format e.g 1080p, BluRay")
+	rootCmd.PersistentFlags().StringVar(&year, "year", "", "Search for movies of a year or range of years e.g 2019 or 2015-2020, also year:2019 in the query")
+	rootCmd.PersistentFlags().StringSliceVar(&genres, "genre", nil, "Search for movies of one of these genres e.g action,comedy, also genre:action in the query")
+	rootCmd.PersistentFlags().IntVar(&yearFrom, "year-from", 0, "Only show results released in or after a year")
+	rootCmd.PersistentFlags().IntVar(&yearTo, "year-to", 0, "Only show results released in or before a year")
+	rootCmd.PersistentFlags().StringVar(&minSize, "min-size", "", "Only show results of at least a size e.g 700MB")
+	rootCmd.PersistentFlags().StringVar(&maxSize, "max-size", "", "Only show results of at most a size e.g 2GB")
+	rootCmd.PersistentFlags().StringSliceVar(&sources, "source", nil, "Only show results from these engines e.g NetNaija,FzMovies")
+	rootCmd.PersistentFlags().StringSliceVar(&languages, "lang", nil, "Only show results in these languages e.g en,ko or english,korean")
+	rootCmd.PersistentFlags().BoolVar(&seriesOnly, "series-only", false, "Only show series")
+	rootCmd.PersistentFlags().StringVar(&sortBy, "sort", "", "Sort results by year or size")
+	rootCmd.PersistentFlags().BoolVar(&descending, "desc", false, "Sort results in descending order")
+	rootCmd.PersistentFlags().StringVar(&rankBy, "rank-by", "", "Rank results by score, title, year, quality or source, results of all engines are ranked by score")
+	rootCmd.PersistentFlags().BoolVar(&groupDuplicates, "group-duplicates", false, "List the copies of a movie found on several engines or in several qualities once")
+	rootCmd.PersistentFlags().StringVar(&proxy, "proxy", "", "HTTP or SOCKS5 proxy to reach the source sites through e.g socks5://127.0.0.1:9050")
+	rootCmd.PersistentFlags().BoolVar(&useTor, "tor", false, "Reach the source sites and download through Tor, on circuits of their own for every engine")
+	rootCmd.PersistentFlags().StringVar(&torProxy, "tor-proxy", "", "SOCKS address of Tor used with --tor (default 127.0.0.1:9050)")
+	rootCmd.PersistentFlags().StringVar(&filenameTemplate, "filename-template", string(downloader.DefaultTemplate), "Path movies are saved at in the output directory")
+	rootCmd.PersistentFlags().StringVar(&episodeTemplate, "episode-template", string(downloader.DefaultEpisodeTemplate), "Path episodes of series are saved at in the output directory")
+	rootCmd.PersistentFlags().StringVar(&limitRate, "limit-rate", "", "Limit the bandwidth of downloads e.g 2M or 500K per second")
+	rootCmd.PersistentFlags().StringSliceVar(&rateSchedule, "rate-schedule", nil, "Limit the bandwidth of downloads at times of day e.g 08:00-23:00=500K,23:00-08:00=0")
+	rootCmd.PersistentFlags().StringVar(&minFree, "min-free", "", "Free space downloads must leave on the disk e.g 10G, downloads which would leave less are refused")
+	rootCmd.PersistentFlags().Float64Var(&spaceMargin, "space-margin", downloader.DefaultSpaceMargin*100, "Percent of the size of a download needed free on top of it")
+	rootCmd.PersistentFlags().BoolVar(&ignoreSpace, "ignore-space", false, "Start downloads which do not fit on the disk, with a warning")
+	rootCmd.PersistentFlags().BoolVar(&verifyLinks, "verify-links", false, "Check the download links of results and drop movies whose links are dead")
+	rootCmd.PersistentFlags().BoolVar(&notifyDesktop, "notify", false, "Show desktop notifications when downloads finish, watched movies are uploaded and the download queue is done")
+	rootCmd.PersistentFlags().BoolVar(&resolveLinks, "resolve-links", false, "Follow the download links of results through their landing pages to the links of the files")
 
 	viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+	viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level"))
+	viper.BindPFlag("log-format", rootCmd.PersistentFlags().Lookup("log-format"))
 	viper.BindPFlag("engine", rootCmd.PersistentFlags().Lookup("engine"))
 	viper.BindPFlag("selenium-url", rootCmd.PersistentFlags().Lookup("selenium-url"))
 	viper.BindPFlag("output-dir", rootCmd.PersistentFlags().Lookup("output-dir"))
 	viper.BindPFlag("cache-dir", rootCmd.PersistentFlags().Lookup("cache-dir"))
 	viper.BindPFlag("ignore-cache", rootCmd.PersistentFlags().Lookup("ignore-cache"))
+	viper.BindPFlag("no-cache", rootCmd.PersistentFlags().Lookup("no-cache"))
+	viper.BindPFlag("cache-ttl", rootCmd.PersistentFlags().Lookup("cache-ttl"))
+	viper.BindPFlag("no-index", rootCmd.PersistentFlags().Lookup("no-index"))
+	viper.BindPFlag("tmdb-api-key", rootCmd.PersistentFlags().Lookup("tmdb-api-key"))
+	viper.BindPFlag("omdb-api-key", rootCmd.PersistentFlags().Lookup("omdb-api-key"))
+	viper.BindPFlag("opensubtitles-api-key", rootCmd.PersistentFlags().Lookup("opensubtitles-api-key"))
 	viper.BindPFlag("use-chrome-driver", rootCmd.PersistentFlags().Lookup("use-chrome-driver"))
+	viper.BindPFlag("chunks", rootCmd.PersistentFlags().Lookup("chunks"))
+	viper.BindPFlag("quality", rootCmd.PersistentFlags().Lookup("quality"))
+	viper.BindPFlag("year", rootCmd.PersistentFlags().Lookup("year"))
+	viper.BindPFlag("genre", rootCmd.PersistentFlags().Lookup("genre"))
+	viper.BindPFlag("year-from", rootCmd.PersistentFlags().Lookup("year-from"))
+	viper.BindPFlag("year-to", rootCmd.PersistentFlags().Lookup("year-to"))
+	viper.BindPFlag("min-size", rootCmd.PersistentFlags().Lookup("min-size"))
+	viper.BindPFlag("max-size", rootCmd.PersistentFlags().Lookup("max-size"))
+	viper.BindPFlag("source", rootCmd.PersistentFlags().Lookup("source"))
+	viper.BindPFlag("lang", rootCmd.PersistentFlags().Lookup("lang"))
+	viper.BindPFlag("series-only", rootCmd.PersistentFlags().Lookup("series-only"))
+	viper.BindPFlag("sort", rootCmd.PersistentFlags().Lookup("sort"))
+	viper.BindPFlag("desc", rootCmd.PersistentFlags().Lookup("desc"))
+	viper.BindPFlag("rank-by", rootCmd.PersistentFlags().Lookup("rank-by"))
+	viper.BindPFlag("group-duplicates", rootCmd.PersistentFlags().Lookup("group-duplicates"))
+	viper.BindPFlag("proxy", rootCmd.PersistentFlags().Lookup("proxy"))
+	viper.BindPFlag("tor", rootCmd.PersistentFlags().Lookup("tor"))
+	viper.BindPFlag("tor-proxy", rootCmd.PersistentFlags().Lookup("tor-proxy"))
+	viper.BindPFlag("filename-template", rootCmd.PersistentFlags().Lookup("filename-template"))
+	viper.BindPFlag("episode-template", rootCmd.PersistentFlags().Lookup("episode-template"))
+	viper.BindPFlag("verify-links", rootCmd.PersistentFlags().Lookup("verify-links"))
+	viper.BindPFlag("resolve-links", rootCmd.PersistentFlags().Lookup("resolve-links"))
+	viper.BindPFlag("limit-rate", rootCmd.PersistentFlags().Lookup("limit-rate"))
+	viper.BindPFlag("rate-schedule", rootCmd.PersistentFlags().Lookup("rate-schedule"))
+	viper.BindPFlag("min-free", rootCmd.PersistentFlags().Lookup("min-free"))
+	viper.BindPFlag("space-margin", rootCmd.PersistentFlags().Lookup("space-margin"))
+	viper.BindPFlag("ignore-space", rootCmd.PersistentFlags().Lookup("ignore-space"))
+	viper.BindPFlag("notify", rootCmd.PersistentFlags().Lookup("notify"))
+}
+
+// configPaths : config files read when --config is not set, from the most to the
+// least preferred. $HOME/.gophie.yaml is still read for older setups
+func configPaths(home string) []string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = path.Join(home, ".config")
+	}
+	return []string{
+		path.Join(configHome, "gophie", "config.yaml"),
+		path.Join(home, ".gophie.yaml"),
+	}
 }
 
 // initConfig reads in config file and ENV variables if set.
+// Flags override environment variables which override the config file
 func initConfig() {
 	// Find home directory.
 	home, err := homedir.Dir()
@@ -119,13 +284,64 @@ func initConfig() {
 	// Defaults for Gophie Configs
 	viper.SetDefault("selenium-url", "http://localhost:4444")
 	viper.SetDefault("output-dir", path.Join(home, "Downloads", "Gophie"))
-	viper.Set("cache-dir", path.Join(home, ".gophie_cache"))
-	if err := os.MkdirAll(viper.GetString("cache-dir"), os.ModePerm); err != nil {
-		log.Fatal(err)
-	}
+	viper.SetDefault("cache-dir", path.Join(home, ".gophie_cache"))
+	viper.SetDefault("plugins-dir", path.Join(path.Dir(configPaths(home)[0]), "plugins"))
+	viper.SetDefault("plugin-timeout", plugins.DefaultTimeout)
 	// Configs From Env
 	replacer := strings.NewReplacer("-", "_")
 	viper.SetEnvKeyReplacer(replacer)
 	viper.SetEnvPrefix("gophie") // will be uppercased automatically
 	viper.AutomaticEnv()         // read in environment variables that match
+
+	// Configs From File
+	file := configFile
+	if file == "" {
+		for _, candidate := range configPaths(home) {
+			if _, err := os.Stat(candidate); err == nil {
+				file = candidate
+				break
+			}
+		}
+	}
+	if file != "" {
+		viper.SetConfigFile(file)
+		if err := viper.ReadInConfig(); err != nil {
+			log.Fatal(err)
+		}
+		log.Debug("Using config file: ", viper.ConfigFileUsed())
+	}
+	if err := os.MkdirAll(viper.GetString("cache-dir"), os.ModePerm); err != nil {
+		log.Fatal(err)
+	}
+	for _, template := range []downloader.Template{downloader.MovieTemplate(), downloader.EpisodeTemplate()} {
+		if err := template.Validate(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if _, _, err := downloader.ParseRateConfig(viper.GetString("limit-rate"), viper.GetStringSlice("rate-schedule")); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := downloader.ParseSize(viper.GetString("min-free")); err != nil {
+		log.Fatal(err)
+	}
+	if year := viper.GetString("year"); year != "" {
+		if _, _, err := engine.ParseYearRange(year); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if tor := engine.TorConfig(torConfig()); tor.Enabled {
+		if err := transport.CheckTor(tor); err != nil {
+			log.Warn(err)
+		}
+		downloader.SetTransport(transport.NewTorTransport(tor, "downloads"))
+	}
+}
+
+// torConfig : the tor section of the http config
+func torConfig() transport.TorConfig {
+	var tor transport.TorConfig
+	if err := viper.UnmarshalKey("http.tor", &tor); err != nil {
+		log.Fatalf("invalid http config: %v", err)
+	}
+	return tor
 }
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+	if paths := configPaths("/home/gophie"); paths[0] != filepath.Join(dir, "gophie", "config.yaml") || paths[1] != "/home/gophie/.gophie.yaml" {
+		t.Errorf("Unexpected config paths %v", paths)
+	}
+
+	file := filepath.Join(dir, "config.yaml")
+	config := "engine: yts\ncache-ttl: 10m\ncache-dir: " + filepath.Join(dir, "cache") + "\n"
+	if err := ioutil.WriteFile(file, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.yaml")
+	if err := ioutil.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	configFile = file
+	defer func() {
+		configFile = ""
+		viper.SetConfigFile(empty)
+		viper.ReadInConfig()
+	}()
+
+	initConfig()
+	if viper.GetString("engine") != "yts" || viper.GetString("cache-dir") != filepath.Join(dir, "cache") || viper.GetDuration("cache-ttl").Minutes() != 10 {
+		t.Errorf("Expected values from the config file, got %s, %s and %s",
+			viper.GetString("engine"), viper.GetString("cache-dir"), viper.GetDuration("cache-ttl"))
+	}
+
+	// flags override the config file
+	flag := rootCmd.PersistentFlags().Lookup("engine")
+	flag.Value.Set("fzmovies")
+	flag.Changed = true
+	defer func() {
+		flag.Value.Set(flag.DefValue)
+		flag.Changed = false
+	}()
+	if viper.GetString("engine") != "fzmovies" {
+		t.Errorf("Expected the engine flag to override the config file, got %s", viper.GetString("engine"))
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/logging"
+)
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link,omitempty"`
+	Description string        `xml:"description,omitempty"`
+	Category    string        `xml:"category,omitempty"`
+	GUID        *rssGUID      `xml:"guid,omitempty"`
+	PubDate     string        `xml:"pubDate,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Generator   string    `xml:"generator"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// uploadDateLayouts : layouts of the upload dates shown by the engines
+var uploadDateLayouts = []string{
+	"2006-01-02 15:04:05", "2006-01-02", "02/01/2006", "January 2, 2006", "Jan 2, 2006", "2 January 2006",
+}
+
+// parseUploadDate : the time of an upload date, false when it is not in a known layout
+func parseUploadDate(date string) (time.Time, bool) {
+	for _, layout := range uploadDateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(date)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// enclosureType : the mime type of a download link
+func enclosureType(link string) string {
+	if strings.HasPrefix(link, "magnet:") {
+		return "application/x-bittorrent"
+	}
+	ext := path.Ext(strings.SplitN(link, "?", 2)[0])
+	if ext == ".torrent" {
+		return "application/x-bittorrent"
+	}
+	if kind := mime.TypeByExtension(ext); kind != "" {
+		return kind
+	}
+	return "application/octet-stream"
+}
+
+// rssItemOf : the item of movie, with its download (or magnet) link as the enclosure
+func rssItemOf(movie engine.Movie) rssItem {
+	item := rssItem{
+		Title:       movie.Title,
+		Description: movie.Description,
+		Category:    movie.Category,
+	}
+	if movie.Year > 0 && !strings.Contains(movie.Title, strconv.Itoa(movie.Year)) {
+		item.Title = fmt.Sprintf("%s (%d)", movie.Title, movie.Year)
+	}
+	link := movie.MagnetLink
+	if link == "" && movie.DownloadLink != nil {
+		link = movie.DownloadLink.String()
+	}
+	if link != "" {
+		item.GUID = &rssGUID{Value: link}
+		item.Enclosure = &rssEnclosure{URL: link, Length: movie.SizeBytes, Type: enclosureType(link)}
+		if !strings.HasPrefix(link, "magnet:") {
+			item.Link = link
+		}
+	}
+	if t, ok := parseUploadDate(movie.UploadDate); ok {
+		item.PubDate = t.Format(time.RFC1123Z)
+	}
+	return item
+}
+
+// newRSSFeed : the RSS 2.0 feed of the movies listed by an engine
+func newRSSFeed(title, link string, movies []engine.Movie) rssFeed {
+	feed := rssFeed{Version: "2.0", Channel: rssChannel{
+		Title:       title,
+		Link:        link,
+		Description: title + " listed by gophie",
+		Generator:   "gophie " + Version,
+		Items:       []rssItem{},
+	}}
+	for _, movie := range movies {
+		feed.Channel.Items = append(feed.Channel.Items, rssItemOf(movie))
+	}
+	return feed
+}
+
+// RSSHandler : serves the movies listed by the engine of /rss/{engine} as an RSS feed
+func RSSHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	eng := strings.Trim(strings.TrimPrefix(r.URL.Path, "/rss"), "/")
+	if eng == "" {
+		eng = r.URL.Query().Get("engine")
+	}
+	if eng == "" {
+		eng = "fzmovies"
+	}
+	site, _ := getEngine(eng)
+	if site == nil {
+		http.Error(w, "Invalid Engine Param", http.StatusBadRequest)
+		return
+	}
+	filter, err := requestFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pageNum := 1
+	if page := r.URL.Query().Get("page"); page != "" {
+		if pageNum, err = strconv.Atoi(page); err != nil {
+			http.Error(w, "Page must be a number", http.StatusBadRequest)
+			return
+		}
+	}
+	mode, err := engine.ParseScrapeMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !engine.Supports(site, mode) {
+		http.Error(w, fmt.Sprintf("%s does not list %s movies", eng, mode), http.StatusBadRequest)
+		return
+	}
+
+	result, err := engine.ListBy(r.Context(), site, mode, pageNum)
+	if err != nil {
+		engineErrorHandler(w, r, err)
+		return
+	}
+	result = filter.apply(result)
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	link := fmt.Sprintf("%s://%s/list?engine=%s&mode=%s", scheme, r.Host, eng, mode)
+	feed := newRSSFeed(fmt.Sprintf("%s %s movies", site.String(), mode), link, result.Movies)
+	b, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to serialize feed: ", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(b)
+}
@@ -16,12 +16,16 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"reflect"
 	"strconv"
 	"strings"
 
-	"github.com/go-phie/gophie/downloader"
 	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/index"
+	"github.com/go-phie/gophie/metadata"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -35,65 +39,187 @@ var searchCmd = &cobra.Command{
 			gophie search The Longests Nights
 	
 	Search returns a list of movies which can be selected using arrowkeys on the keyboard
+	With --output json|csv|table the results are printed instead, e.g to pipe them into jq
+	With --pages 3 the first three result pages of the site are searched and merged, --all searches every page
+	The words year:2019, year:2015-2020 and genre:action,comedy of the query, or --year and --genre,
+	narrow the search on the sites which support it and filter the results of the others
+	With --imdb tt4154796 every engine (or the one set with --engine) is searched for the title of the IMDB id
+	as known by the metadata provider, dropping results released in other years
+	With --offline the movies found by earlier searches and lists of every engine are searched in the local
+	index instead of the sites, instantly and without a connection
 	`,
-	Args: cobra.MinimumNArgs(1),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if imdbID != "" {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if imdbID != "" {
+			searchIMDB(cmd, imdbID)
+			return
+		}
 		// Engine is set from root.go
 		page := strconv.Itoa(pageNum)
 		query := strings.Join(args, " ")
+		if offline {
+			searchOffline(cmd.Context(), query)
+			return
+		}
+		if outputFormat != "" {
+			printSearch(cmd.Context(), query, page)
+			return
+		}
 		// only run pagination for
-		if strings.ToLower(viper.GetString("engine")) == "tvseries" {
-			searchPager(query, page)
+		if strings.ToLower(viper.GetString("engine")) == "tvseries" && !walkPages() {
+			searchPager(cmd.Context(), query, page)
 		} else {
-			searchPager(query)
+			searchPager(cmd.Context(), query)
 		}
 	},
 }
 
 func init() {
 	searchCmd.Flags().IntVarP(&pageNum, "page", "p", 1, "Page Number to search and return from")
+	searchCmd.Flags().IntVar(&searchPages, "pages", 1, "Number of result pages to search and merge")
+	searchCmd.Flags().BoolVar(&allPages, "all", false, fmt.Sprintf("Search and merge all result pages, up to %d", engine.MaxPages))
+	searchCmd.Flags().StringVar(&imdbID, "imdb", "", "IMDB id (or link) of the title to search for, needs tmdb-api-key or omdb-api-key")
+	searchCmd.Flags().BoolVar(&offline, "offline", false, "Search the local index of the movies found before instead of the sites")
+	searchCmd.Flags().IntVar(&offlineLimit, "limit", index.DefaultLimit, "Movies returned by --offline searches")
+	addOutputFlags(searchCmd)
 	rootCmd.AddCommand(searchCmd)
 }
 
-func searchPager(params ...string) {
-	selectedEngine, err := engine.GetEngine(viper.GetString("engine"))
+var (
+	searchPages int
+	allPages     bool
+	imdbID       string
+	offline      bool
+	offlineLimit int
+)
+
+// walkPages : whether several result pages are searched and merged
+func walkPages() bool {
+	return allPages || searchPages > 1
+}
+
+// searchEngine : Search e with params and the operators of --year and --genre,
+// walking the result pages asked for with --pages or --all when no page is given
+func searchEngine(ctx context.Context, e engine.Engine, params ...string) (engine.SearchResult, error) {
+	params = append([]string{cliQuery(params[0])}, params[1:]...)
+	if len(params) == 1 && walkPages() {
+		pages := searchPages
+		if allPages {
+			pages = 0
+		}
+		return engine.SearchPages(ctx, e, params[0], pages)
+	}
+	return engine.Search(ctx, e, params...)
+}
+
+// printSearch : Print the results of a search in the chosen output format
+func printSearch(ctx context.Context, query, page string) {
+	selectedEngine, err := getEngine(viper.GetString("engine"))
 	if err != nil {
 		log.Fatal(err)
 	}
-	selectedMovie := processSearch(selectedEngine, compResult, params...)
-	// Start Movie Download
-	if len(selectedMovie.SDownloadLink) < 1 {
-		if err = downloader.DownloadMovie(&selectedMovie, viper.GetString("output-dir")); err != nil {
+	params := []string{query}
+	if page != "1" {
+		params = append(params, page)
+	}
+	err = printResult(ctx, os.Stdout, func() (engine.SearchResult, error) { return searchEngine(ctx, selectedEngine, params...) })
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// searchIMDB : Search for the title of an IMDB id and download the selected movie,
+// or print the results in the chosen output format
+func searchIMDB(cmd *cobra.Command, id string) {
+	ctx := cmd.Context()
+	id, err := metadata.ParseIMDBID(id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	provider, _ := getMetadataProvider().(metadata.IDProvider)
+	loadPlugins()
+	search := engine.SearchAll
+	if cmd.Flags().Changed("engine") {
+		e, err := getEngine(viper.GetString("engine"))
+		if err != nil {
 			log.Fatal(err)
 		}
-	} else {
-		var movieArray []engine.Movie
-		index := 0
-		for key, val := range selectedMovie.SDownloadLink {
-			movieArray = append(movieArray,
-				engine.Movie{
-					Index:          index,
-					Title:          key,
-					IsSeries:       false,
-					Source:         selectedMovie.Source,
-					DownloadLink:   val,
-					CoverPhotoLink: selectedMovie.CoverPhotoLink,
-					Description:    selectedMovie.Description,
-				})
-			index++
+		search = func(ctx context.Context, query string) (engine.SearchResult, error) { return searchEngine(ctx, e, query) }
+	}
+	fetch := func() (engine.SearchResult, error) {
+		result, m, err := metadata.SearchByID(ctx, provider, id, search)
+		if m != nil && m.Year > 0 {
+			result.Query = fmt.Sprintf("%s (%d)", m.Title, m.Year)
+		}
+		return result, err
+	}
+	selectAndDownload(ctx, fetch)
+}
+
+// selectAndDownload : Print the result of fetch in the chosen output format, or
+// ask which of its movies, and which episode of series, to download
+func selectAndDownload(ctx context.Context, fetch fetchFunc) {
+	if outputFormat != "" {
+		if err := printResult(ctx, os.Stdout, fetch); err != nil {
+			log.Fatal(err)
 		}
-		searchResult := engine.SearchResult{
-			Query:  selectedMovie.Title + " EPISODES",
-			Movies: movieArray,
+		return
+	}
+
+	result := ProcessFetchTask(ctx, fetch)
+	selectedMovie, err := pickMovie(result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(selectedMovie.SDownloadLink) > 0 || len(selectedMovie.Seasons) > 0 {
+		episodes := episodesResult(selectedMovie)
+		_, choice := SelectOpts(episodes.Query, episodes.Titles())
+		if selectedMovie, err = episodes.GetMovieByTitle(choice); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err = downloadSelectedMovie(ctx, &selectedMovie); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// searchOffline : Search the local index for query instead of the sites
+func searchOffline(ctx context.Context, query string) {
+	idx := openIndex()
+	if idx == nil {
+		log.Fatal("The local index is unavailable, it is kept in the cache directory")
+	}
+	selectAndDownload(ctx, func() (engine.SearchResult, error) {
+		return idx.Search(ctx, cliQuery(query), offlineLimit)
+	})
+}
+
+func searchPager(ctx context.Context, params ...string) {
+	selectedEngine, err := getEngine(viper.GetString("engine"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	selectedMovie := processSearch(ctx, selectedEngine, compResult, params...)
+	// Start Movie Download
+	if len(selectedMovie.SDownloadLink) < 1 && len(selectedMovie.Seasons) < 1 {
+		if err = downloadSelectedMovie(ctx, &selectedMovie); err != nil {
+			log.Fatal(err)
 		}
-		selectedMovie = processSearch(selectedEngine, searchResult, params...)
-		if err = downloader.DownloadMovie(&selectedMovie, viper.GetString("output-dir")); err != nil {
+	} else {
+		searchResult := episodesResult(selectedMovie)
+		selectedMovie = processSearch(ctx, selectedEngine, searchResult, params...)
+		if err = downloadSelectedMovie(ctx, &selectedMovie); err != nil {
 			log.Fatal(err)
 		}
 	}
 }
 
-func processSearch(e engine.Engine, retrievedResult engine.SearchResult, params ...string) engine.Movie {
+func processSearch(ctx context.Context, e engine.Engine, retrievedResult engine.SearchResult, params ...string) engine.Movie {
 	// Initialize process and show loader on terminal and store result in result
 	var (
 		choice      string
@@ -104,7 +230,7 @@ func processSearch(e engine.Engine, retrievedResult engine.SearchResult, params
 	query := params[0]
 	if len(params) > 1 {
 		pageNum, _ = strconv.Atoi(params[1])
-		result = ProcessFetchTask(func() engine.SearchResult { return e.Search(params...) })
+		result = ProcessFetchTask(ctx, func() (engine.SearchResult, error) { return searchEngine(ctx, e, params...) })
 		items = append(result.Titles(), []string{">>> Next Page"}...)
 		log.Debug(result)
 		if pageNum != 1 {
@@ -114,21 +240,25 @@ func processSearch(e engine.Engine, retrievedResult engine.SearchResult, params
 
 		if choiceIndex != len(items)-1 {
 			if choiceIndex == 0 && pageNum != 1 {
-				searchPager(query, strconv.Itoa(pageNum-1))
+				searchPager(ctx, query, strconv.Itoa(pageNum-1))
 			}
 		} else {
-			searchPager(query, strconv.Itoa(pageNum+1))
+			searchPager(ctx, query, strconv.Itoa(pageNum+1))
 		}
 	} else {
 		if reflect.DeepEqual(retrievedResult, compResult) {
-			result = ProcessFetchTask(func() engine.SearchResult { return e.Search(query) })
-			_, choice = SelectOpts(result.Query, result.Titles())
+			result = ProcessFetchTask(ctx, func() (engine.SearchResult, error) { return searchEngine(ctx, e, query) })
+			selectedMovie, err := pickMovie(result)
+			if err != nil {
+				log.Fatal(err)
+			}
+			return selectedMovie
 		} else {
 			result = retrievedResult
 			items = append([]string{"<<< MAIN PAGE"}, result.Titles()...)
 			choiceIndex, choice = SelectOpts(result.Query, items)
 			if choiceIndex == 0 {
-				searchPager(query, strconv.Itoa(pageNum))
+				searchPager(ctx, query, strconv.Itoa(pageNum))
 			}
 		}
 	}
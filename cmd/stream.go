@@ -16,16 +16,25 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
 	"strings"
 
 	"github.com/bisoncorps/mplayer"
+	"github.com/go-phie/gophie/downloader"
 	"github.com/go-phie/gophie/engine"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-var selectedPlayer string
+var (
+	selectedPlayer string
+	serveStream    bool
+	streamAddr     string
+)
 
 const DEFAULT_PLAYER = "browser"
 
@@ -39,31 +48,108 @@ Gophie stream allows streaming videos using browser player. You can pass a query
 example:
   gophie stream Jumanji --player vlc (stream Jumanji using VLC Media Player)
   gophie stream -e fzmovies (check for latest movies on fzmovies for streaming)
+  gophie stream Jumanji --serve (serve Jumanji on a local URL to open in VLC or mpv)
+
+With --serve the movie is served on a local HTTP URL at --addr which can be opened
+in any player, seeking is passed through to the source site. The selected player is
+only started when --player is given. Stop with ctrl-C
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		selectedEngine, err := engine.GetEngine(viper.GetString("engine"))
-		if err != nil {
-			log.Fatal(err)
-		}
-		query := strings.Join(args, " ")
-		var movie engine.Movie
-		if query == "" {
-			movie = processList(1, selectedEngine, compResult)
-		} else {
-			movie = processSearch(selectedEngine, compResult, query, "1")
+		movie := selectStreamMovie(cmd.Context(), strings.Join(args, " "))
+		if serveStream {
+			if err := serveMovie(cmd.Context(), movie, streamAddr, cmd.Flags().Changed("player")); err != nil {
+				log.Fatal(err)
+			}
+			return
 		}
-		p, err := mplayer.GetPlayer(selectedPlayer)
-		if err != nil {
-			log.Fatal(err)
-		}
-		p.SetURL(movie.DownloadLink.String())
-		p.SetTitle(movie.Title)
-		p.Play()
+		playMovie(movie.Link().String(), movie.Title)
 	},
 }
 
+// selectStreamMovie : Ask which movie to stream out of the results of query or
+// the recent uploads when query is empty
+func selectStreamMovie(ctx context.Context, query string) engine.Movie {
+	selectedEngine, err := getEngine(viper.GetString("engine"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var movie engine.Movie
+	if query == "" {
+		movie = processList(ctx, 1, selectedEngine, compResult)
+	} else {
+		movie = processSearch(ctx, selectedEngine, compResult, query, "1")
+	}
+	if movie.MagnetLink != "" {
+		log.Fatal("Torrent results cannot be streamed, download them with gophie search instead")
+	}
+	return movie
+}
+
+// playMovie : Open link in the selected player
+func playMovie(link, title string) {
+	p, err := mplayer.GetPlayer(selectedPlayer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	p.SetURL(link)
+	p.SetTitle(title)
+	p.Play()
+}
+
+// serveMovie : Serve movie on a local URL at addr until ctx is cancelled, opening
+// the URL in the selected player when play is set
+func serveMovie(ctx context.Context, movie engine.Movie, addr string, play bool) error {
+	listener, proxy, err := listenStream(movie, addr)
+	if err != nil {
+		return err
+	}
+	link := streamLink(listener.Addr().String(), proxy)
+	fmt.Printf("Streaming %s at %s\n", movie.Title, link)
+	if play {
+		go playMovie(link, movie.Title)
+	}
+	return serveStreamUntil(ctx, listener, proxy)
+}
+
+// listenStream : Listen on addr for players of movie
+func listenStream(movie engine.Movie, addr string) (net.Listener, *downloader.Proxy, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return listener, downloader.NewProxy(movie.Link().String(), movie.Title), nil
+}
+
+// streamLink : URL of the file of proxy served at host
+func streamLink(host string, proxy *downloader.Proxy) string {
+	return "http://" + host + proxy.EscapedPath()
+}
+
+// serveStreamUntil : Serve proxy on listener until ctx is cancelled. Requests are
+// matched against the decoded path of the file rather than routed by a ServeMux,
+// whose patterns give spaces and braces of titles a meaning
+func serveStreamUntil(ctx context.Context, listener net.Listener, proxy *downloader.Proxy) error {
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != proxy.Path() {
+			http.NotFound(w, r)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	})}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
 func init() {
 	streamCmd.Flags().StringVarP(
 		&selectedPlayer, "player", "p", DEFAULT_PLAYER, "Player to use for streaming")
+	streamCmd.Flags().BoolVar(&serveStream, "serve", false, "Serve the movie on a local URL to open in any player")
+	streamCmd.Flags().StringVar(&streamAddr, "addr", "127.0.0.1:8765", "Address to serve the movie on with --serve")
 	rootCmd.AddCommand(streamCmd)
 }
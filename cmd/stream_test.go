@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+// streamSource : a movie whose file, content, is served until the test ends
+func streamSource(t *testing.T, content string) engine.Movie {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, content)
+	}))
+	t.Cleanup(source.Close)
+	link, _ := url.Parse(source.URL + "/files/movie.mp4")
+	return engine.Movie{Title: "Jumanji Welcome To The Jungle", DownloadLink: link}
+}
+
+func TestServeStream(t *testing.T) {
+	movie := streamSource(t, "movie")
+	listener, proxy, err := listenStream(movie, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error)
+	go func() { served <- serveStreamUntil(ctx, listener, proxy) }()
+
+	// titles with spaces are served at their escaped link
+	resp, err := http.Get(streamLink(listener.Addr().String(), proxy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "movie" {
+		t.Errorf("Expected the movie to be streamed, got %s %q", resp.Status, body)
+	}
+	resp, err = http.Get("http://" + listener.Addr().String() + "/other.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected other paths not to be found, got %s", resp.Status)
+	}
+
+	stop()
+	if err := <-served; err != nil {
+		t.Errorf("Expected the stream to stop with its context, got %v", err)
+	}
+}
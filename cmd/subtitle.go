@@ -0,0 +1,79 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-phie/gophie/downloader"
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/subtitle"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var subtitleLang string
+
+// subtitleCmd represents the subtitle command
+var subtitleCmd = &cobra.Command{
+	Use:   "subtitle",
+	Short: "search and download subtitles for a movie",
+	Long: `Subtitle
+			gophie subtitle The Longest Nights
+			gophie subtitle The Longest Nights --lang fr
+
+	Subtitles are searched for on OpenSubtitles and the selected one is saved next to the
+	movie in the output directory. An OpenSubtitles API key must be set with
+	--opensubtitles-api-key or the GOPHIE_OPENSUBTITLES_API_KEY environment variable.
+	`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		movie := engine.Movie{Title: strings.Join(args, " ")}
+		downloadSubtitle(cmd.Context(), movie)
+	},
+}
+
+func init() {
+	subtitleCmd.Flags().StringVarP(&subtitleLang, "lang", "l", "en", "Language of the subtitles as an ISO 639-1 code")
+	rootCmd.AddCommand(subtitleCmd)
+}
+
+// downloadSubtitle : search subtitles for movie, prompt for one and save it in the movie directory
+func downloadSubtitle(ctx context.Context, movie engine.Movie) {
+	provider := getSubtitleProvider()
+	query := subtitle.QueryForMovie(movie, subtitleLang)
+
+	log.Debugf("Searching subtitles for %+v", query)
+	subtitles, err := provider.Search(ctx, query)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	items := make([]string, len(subtitles))
+	for i, sub := range subtitles {
+		items[i] = sub.String()
+	}
+	index, _ := SelectOpts("Subtitles for "+query.Title, items)
+
+	dir := downloader.MovieDir(viper.GetString("output-dir"), &movie)
+	dest, err := subtitle.Save(ctx, provider, subtitles[index], dir, movie.Title)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Info("Subtitle saved to ", dest)
+}
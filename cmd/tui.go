@@ -0,0 +1,337 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/go-phie/gophie/engine"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const tuiHelp = "[yellow]enter[white] search/open  [yellow]tab[white] switch pane  " +
+	"[yellow]n/p[white] next/previous page  [yellow]a[white] queue  [yellow]d[white] download queue  " +
+	"[yellow]esc[white] back  [yellow]q[white] quit"
+
+// tui : Full screen interface to search, browse and queue downloads
+type tui struct {
+	ctx     context.Context
+	engine  engine.Engine
+	filter  resultFilter
+	app     *tview.Application
+	search  *tview.InputField
+	list    *tview.List
+	details *tview.TextView
+	status  *tview.TextView
+
+	query  string
+	page   int
+	result engine.SearchResult
+	series *engine.SearchResult // episodes of the opened series
+	queue  []engine.Movie
+	cancel context.CancelFunc // cancels the fetch in progress
+	done   chan struct{}      // closed once the fetch in progress returns
+}
+
+// newTUI : Build the interface for searching e
+func newTUI(ctx context.Context, e engine.Engine, filter resultFilter) *tui {
+	t := &tui{
+		ctx:     ctx,
+		engine:  e,
+		filter:  filter,
+		app:     tview.NewApplication(),
+		search:  tview.NewInputField(),
+		list:    tview.NewList(),
+		details: tview.NewTextView(),
+		status:  tview.NewTextView(),
+		page:    1,
+	}
+	t.search.SetLabel("Search: ").SetPlaceholder("leave empty to list recent movies")
+	t.search.SetBorder(true).SetTitle(" " + e.String() + " ")
+	t.search.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			t.query = strings.TrimSpace(t.search.GetText())
+			t.fetch(1)
+		}
+	})
+
+	t.list.ShowSecondaryText(false).SetBorder(true).SetTitle(" Results ")
+	t.list.SetChangedFunc(func(index int, _, _ string, _ rune) {
+		if movie, ok := t.movieAt(index); ok {
+			t.details.SetText(movieDetails(movie)).ScrollToBeginning()
+		}
+	})
+	t.list.SetSelectedFunc(func(index int, _, _ string, _ rune) {
+		if movie, ok := t.movieAt(index); ok && movie.IsSeries && t.series == nil {
+			episodes := episodesResult(movie)
+			t.series = &episodes
+			t.showResult()
+		}
+	})
+	t.list.SetInputCapture(t.listKeys)
+
+	t.details.SetDynamicColors(true).SetWordWrap(true).SetBorder(true).SetTitle(" Details ")
+	t.status.SetDynamicColors(true).SetText(tuiHelp)
+
+	panes := tview.NewFlex().
+		AddItem(t.list, 0, 1, true).
+		AddItem(t.details, 0, 1, false)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(t.search, 3, 0, true).
+		AddItem(panes, 0, 1, false).
+		AddItem(t.status, 1, 0, false)
+	t.app.SetRoot(layout, true).SetFocus(t.search)
+	t.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab {
+			if t.search.HasFocus() {
+				t.app.SetFocus(t.list)
+			} else {
+				t.app.SetFocus(t.search)
+			}
+			return nil
+		}
+		return event
+	})
+	return t
+}
+
+// listKeys : Keybindings of the result list
+func (t *tui) listKeys(event *tcell.EventKey) *tcell.EventKey {
+	switch {
+	case event.Key() == tcell.KeyEscape:
+		if t.series != nil {
+			t.series = nil
+			t.showResult()
+		}
+	case event.Rune() == 'n' && t.series == nil:
+		t.fetch(t.page + 1)
+	case event.Rune() == 'p' && t.series == nil && t.page > 1:
+		t.fetch(t.page - 1)
+	case event.Rune() == 'a':
+		t.enqueue()
+	case event.Rune() == 'd':
+		if len(t.queue) == 0 {
+			t.enqueue()
+		}
+		if len(t.queue) > 0 {
+			t.app.Stop()
+		}
+	case event.Rune() == 'q':
+		t.queue = nil
+		t.app.Stop()
+	default:
+		return event
+	}
+	return nil
+}
+
+// fetch : Search the query or list recent movies on page without blocking the
+// interface. The fetch in progress is cancelled, and waited for before the
+// engine is used again since it keeps the URL of what it scrapes
+func (t *tui) fetch(page int) {
+	if t.cancel != nil {
+		t.cancel()
+	}
+	ctx, cancel := context.WithCancel(t.ctx)
+	t.cancel = cancel
+	previous, done := t.done, make(chan struct{})
+	t.done = done
+	query := t.query
+	t.setStatus(fmt.Sprintf("[yellow]Fetching page %d...", page))
+	go func() {
+		defer close(done)
+		if previous != nil {
+			<-previous
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		var (
+			result engine.SearchResult
+			err    error
+		)
+		if query == "" {
+			result, err = t.engine.List(ctx, page)
+		} else {
+			result, err = engine.Search(ctx, t.engine, cliQuery(query), strconv.Itoa(page))
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		t.app.QueueUpdateDraw(func() {
+			if err != nil {
+				t.setStatus("[red]" + tview.Escape(err.Error()))
+				return
+			}
+			t.page = page
+			t.result = t.filter.apply(result)
+			t.series = nil
+			t.showResult()
+			t.app.SetFocus(t.list)
+		})
+	}()
+}
+
+// current : The result shown, the episodes of a series when one is opened
+func (t *tui) current() *engine.SearchResult {
+	if t.series != nil {
+		return t.series
+	}
+	return &t.result
+}
+
+// movieAt : The movie at index of the result shown
+func (t *tui) movieAt(index int) (engine.Movie, bool) {
+	result := t.current()
+	if index < 0 || index >= len(result.Movies) {
+		return engine.Movie{}, false
+	}
+	return result.Movies[index], true
+}
+
+// showResult : Fill the list with the result shown
+func (t *tui) showResult() {
+	result := t.current()
+	t.list.Clear()
+	t.details.Clear()
+	for _, movie := range result.Movies {
+		t.list.AddItem(tview.Escape(movie.Title), "", 0, nil)
+	}
+	title := fmt.Sprintf(" Results - page %d ", t.page)
+	if t.series != nil {
+		title = " " + tview.Escape(result.Query) + " "
+	}
+	t.list.SetTitle(title)
+	if len(result.Movies) == 0 {
+		t.setStatus("[yellow]No Results Found")
+		return
+	}
+	t.list.SetCurrentItem(0)
+	if movie, ok := t.movieAt(0); ok {
+		t.details.SetText(movieDetails(movie))
+	}
+	t.setStatus("")
+}
+
+// enqueue : Queue the selected movie for download
+func (t *tui) enqueue() {
+	movie, ok := t.movieAt(t.list.GetCurrentItem())
+	if !ok {
+		return
+	}
+	if movie.IsSeries {
+		t.setStatus("[yellow]Open the series with enter to queue its episodes")
+		return
+	}
+	for _, queued := range t.queue {
+		if queued.Title == movie.Title && queued.Source == movie.Source {
+			t.setStatus("[yellow]Already queued")
+			return
+		}
+	}
+	t.queue = append(t.queue, movie)
+	t.setStatus(fmt.Sprintf("[green]Queued %s (%d in queue)", tview.Escape(movie.Title), len(t.queue)))
+}
+
+// setStatus : Show message in the status bar followed by the keybindings
+func (t *tui) setStatus(message string) {
+	if message == "" {
+		t.status.SetText(tuiHelp)
+		return
+	}
+	t.status.SetText(message + "[white]  |  " + tuiHelp)
+}
+
+// movieDetails : Describe a movie in the detail pane
+func movieDetails(movie engine.Movie) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[yellow::b]%s[-::-]\n\n", tview.Escape(movie.Title))
+	field := func(name, value string) {
+		if value != "" && value != "0" {
+			fmt.Fprintf(&b, "[yellow]%s:[white] %s\n", name, tview.Escape(value))
+		}
+	}
+	field("Title", movie.CanonicalTitle)
+	field("Year", strconv.Itoa(movie.Year))
+	field("Quality", movie.Quality.String())
+	field("Size", movie.Size)
+	field("Source", movie.Source)
+	field("Genres", movie.Genres)
+	if movie.Rating > 0 {
+		field("Rating", fmt.Sprintf("%.1f/10", movie.Rating))
+	}
+	field("Cast", movie.Cast)
+	if movie.PosterLink != "" {
+		field("Cover", movie.PosterLink)
+	} else {
+		field("Cover", movie.CoverPhotoLink)
+	}
+	if movie.IsSeries {
+		field("Episodes", strconv.Itoa(len(episodesResult(movie).Movies)))
+	}
+	if movie.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", tview.Escape(movie.Description))
+	}
+	return b.String()
+}
+
+// tuiCmd represents the tui command
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "search, browse and download movies in a full screen interface",
+	Long: `Search and browse the results of an engine in a full screen interface.
+Movies queued with "a" are downloaded one after another when "d" is pressed`,
+	Run: func(cmd *cobra.Command, args []string) {
+		e, err := getEngine(viper.GetString("engine"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		filter := cliFilter()
+		if err := filter.validate(); err != nil {
+			log.Fatal(err)
+		}
+		t := newTUI(cmd.Context(), e, filter)
+		// logs written while the interface is drawn would garble it
+		output := log.StandardLogger().Out
+		log.SetOutput(ioutil.Discard)
+		err = t.app.Run()
+		log.SetOutput(output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// downloads write their progress to the terminal so they start once the interface is closed
+		for i := range t.queue {
+			if cmd.Context().Err() != nil {
+				return
+			}
+			if err := downloadSelectedMovie(cmd.Context(), &t.queue[i]); err != nil {
+				log.Error(err)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/go-phie/gophie/engine"
+)
+
+// pagingEngine : lists pages slowly, recording the pages listed at the same time
+type pagingEngine struct {
+	mu      sync.Mutex
+	listing int
+	most    int
+	pages   []int
+}
+
+func (e *pagingEngine) Search(ctx context.Context, param ...string) (engine.SearchResult, error) {
+	return engine.SearchResult{Query: param[0]}, nil
+}
+func (e *pagingEngine) List(ctx context.Context, page int) (engine.SearchResult, error) {
+	e.mu.Lock()
+	e.listing++
+	if e.listing > e.most {
+		e.most = e.listing
+	}
+	e.pages = append(e.pages, page)
+	e.mu.Unlock()
+	// the scrape does not stop right away when cancelled
+	time.Sleep(20 * time.Millisecond)
+	e.mu.Lock()
+	e.listing--
+	e.mu.Unlock()
+	return engine.SearchResult{Page: page}, ctx.Err()
+}
+func (e *pagingEngine) Capabilities() engine.Capabilities {
+	return engine.Capabilities{Pagination: true}
+}
+func (e *pagingEngine) String() string { return "Paging" }
+
+func TestTUIFetch(t *testing.T) {
+	e := &pagingEngine{}
+	ui := newTUI(context.Background(), e, resultFilter{})
+	screen := tcell.NewSimulationScreen("UTF-8")
+	ui.app.SetScreen(screen)
+	go ui.app.Run()
+	defer ui.app.Stop()
+
+	ui.app.QueueUpdate(func() { ui.fetch(1) })
+	time.Sleep(5 * time.Millisecond)
+	// paging on cancels the fetch of page 1 and waits for it before listing page 3
+	ui.app.QueueUpdate(func() {
+		ui.fetch(2)
+		ui.fetch(3)
+	})
+	var done chan struct{}
+	ui.app.QueueUpdate(func() { done = ui.done })
+	<-done
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.most != 1 {
+		t.Errorf("Expected the engine to list one page at a time, listed %d", e.most)
+	}
+	if len(e.pages) != 2 || e.pages[0] != 1 || e.pages[1] != 3 {
+		t.Errorf("Expected page 1 then the last page asked for to be listed, got %v", e.pages)
+	}
+	var page int
+	ui.app.QueueUpdate(func() { page = ui.page })
+	if page != 3 {
+		t.Errorf("Expected page 3 to be shown, got %d", page)
+	}
+}
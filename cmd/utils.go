@@ -1,40 +1,452 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/briandowns/spinner"
+	"github.com/go-phie/gophie/downloader"
 	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/index"
+	"github.com/go-phie/gophie/metadata"
+	"github.com/go-phie/gophie/metrics"
+	"github.com/go-phie/gophie/plugins"
+	"github.com/go-phie/gophie/subtitle"
 	"github.com/manifoldco/promptui"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
+var (
+	resultCache     *engine.ResultCache
+	resultCacheOnce sync.Once
+	localIndex      *index.Index
+	localIndexOnce  sync.Once
+	pluginsOnce     sync.Once
+)
+
+// loadPlugins : Register the engines of the plugins directory the first time an
+// engine is needed, so that commands using none do not run the plugins
+func loadPlugins() {
+	pluginsOnce.Do(func() {
+		plugins.Load(context.Background(), plugins.Dir())
+	})
+}
+
+// allEngines : every enabled engine, those of the plugins included
+func allEngines() map[string]engine.Engine {
+	loadPlugins()
+	return engine.GetEngines()
+}
+
+// findEngine : the engine named name, plugins included, without the wrappers of getEngine
+func findEngine(name string) (engine.Engine, error) {
+	loadPlugins()
+	return engine.GetEngine(name)
+}
+
+// getEngine : Return the named engine, serving its results from the result cache
+// unless caching has been disabled with --no-cache. Results are enriched with
+// metadata when a TMDB or OMDB API key is configured and added to the local index
+// unless it has been disabled with --no-index. Searches, lists and cache
+// lookups are recorded in the metrics
+func getEngine(name string) (engine.Engine, error) {
+	e, err := findEngine(name)
+	if err != nil {
+		return e, err
+	}
+	e = metrics.NewInstrumentedEngine(name, e)
+	if provider := getMetadataProvider(); provider != nil {
+		e = metadata.NewEnrichedEngine(e, provider)
+	}
+	if idx := openIndex(); idx != nil && !viper.GetBool("no-index") {
+		e = index.NewIndexedEngine(e, idx)
+	}
+	// Without a cache directory (e.g when initConfig has not run) there is nowhere to keep results
+	if viper.GetBool("no-cache") || viper.GetString("cache-dir") == "" {
+		return e, nil
+	}
+	resultCacheOnce.Do(func() {
+		cachePath := path.Join(viper.GetString("cache-dir"), "results.db")
+		resultCache, err = engine.OpenResultCache(cachePath, viper.GetDuration("cache-ttl"))
+		if err != nil {
+			log.Warnf("Result cache unavailable, results will not be cached: %v", err)
+		}
+	})
+	if resultCache == nil {
+		return e, nil
+	}
+	cached := engine.NewCachedEngine(name, e, resultCache)
+	cached.OnLookup = metrics.CacheLookup(name)
+	return cached, nil
+}
+
+// closeResultCache : Close the result cache, writing it out, if it was opened
+func closeResultCache() {
+	if resultCache == nil {
+		return
+	}
+	if err := resultCache.Close(); err != nil {
+		log.Warnf("Could not close the result cache: %v", err)
+	}
+}
+
+// openIndex : The local index of the movies found, in the cache directory. Nil
+// when there is no cache directory or it cannot be opened
+func openIndex() *index.Index {
+	if viper.GetString("cache-dir") == "" {
+		return nil
+	}
+	localIndexOnce.Do(func() {
+		var err error
+		localIndex, err = index.Open(path.Join(viper.GetString("cache-dir"), "index.db"))
+		if err != nil {
+			log.Warnf("Local index unavailable, movies found will not be indexed: %v", err)
+		}
+	})
+	return localIndex
+}
+
+// indexMovies : Add movies found by searching every engine at once, which are
+// not searched through getEngine, to the local index
+func indexMovies(movies []engine.Movie) {
+	if viper.GetBool("no-index") {
+		return
+	}
+	if idx := openIndex(); idx != nil {
+		if err := idx.Add(movies); err != nil {
+			log.Warnf("Could not index movies: %v", err)
+		}
+	}
+}
+
+// closeIndex : Close the local index if it was opened
+func closeIndex() {
+	if localIndex == nil {
+		return
+	}
+	if err := localIndex.Close(); err != nil {
+		log.Warnf("Could not close the local index: %v", err)
+	}
+}
+
+// getMetadataProvider : The configured metadata provider, TMDB is preferred
+// when both API keys are set. Returns nil when none is configured
+func getMetadataProvider() metadata.Provider {
+	if key := viper.GetString("tmdb-api-key"); key != "" {
+		return metadata.NewTMDB(key)
+	}
+	if key := viper.GetString("omdb-api-key"); key != "" {
+		return metadata.NewOMDB(key)
+	}
+	return nil
+}
+
+// getSubtitleProvider : The provider subtitles are searched and downloaded from
+func getSubtitleProvider() subtitle.Provider {
+	return subtitle.NewOpenSubtitles(viper.GetString("opensubtitles-api-key"))
+}
+
+// downloadSelectedMovie : Download a movie selected from results into the output
+// directory until ctx is cancelled. Torrent results are handed to the system
+// torrent client through their magnet link
+func downloadSelectedMovie(ctx context.Context, movie *engine.Movie) error {
+	if movie.MagnetLink != "" {
+		openMagnet(movie.MagnetLink)
+		return nil
+	}
+	if !confirmDownload(*movie) {
+		return nil
+	}
+	if err := engine.ResolveMovieLink(ctx, movie); err != nil {
+		log.Debugf("Could not resolve the link of %s: %v", movie.Title, err)
+	}
+	d, err := downloader.DownloadMovie(ctx, movie, viper.GetString("output-dir"))
+	if err != nil {
+		notifyDownload(*movie, "", err)
+		return err
+	}
+	finishDownload(*movie, d.Path(), d.Vars)
+	return nil
+}
+
+// openMagnet : Print the magnet link so it can be copied and open it with the
+// application registered for magnet links
+func openMagnet(magnet string) {
+	fmt.Println(magnet)
+	var opener *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		opener = exec.Command("rundll32", "url.dll,FileProtocolHandler", magnet)
+	case "darwin":
+		opener = exec.Command("open", magnet)
+	default:
+		opener = exec.Command("xdg-open", magnet)
+	}
+	if err := opener.Start(); err != nil {
+		log.Warnf("Could not open magnet link, copy it into a torrent client: %v", err)
+	}
+}
+
+// episodesResult : A SearchResult of the episodes of a series to select one to download
+// Episodes are grouped by season when the engine provides them
+func episodesResult(series engine.Movie) engine.SearchResult {
+	var movies []engine.Movie
+	episode := func(title string, link *url.URL) engine.Movie {
+		return engine.Movie{
+			Index:          len(movies),
+			Title:          title,
+			IsSeries:       false,
+			Source:         series.Source,
+			DownloadLink:   link,
+			CoverPhotoLink: series.CoverPhotoLink,
+			Description:    series.Description,
+		}
+	}
+	if len(series.Seasons) > 0 {
+		for _, season := range series.Seasons {
+			for _, e := range season.Episodes {
+				movies = append(movies, episode(season.Label(e), e.DownloadLink))
+			}
+		}
+	} else {
+		titles := make([]string, 0, len(series.SDownloadLink))
+		for title := range series.SDownloadLink {
+			titles = append(titles, title)
+		}
+		sort.Strings(titles)
+		for _, title := range titles {
+			movies = append(movies, episode(title, series.SDownloadLink[title]))
+		}
+	}
+	return engine.SearchResult{
+		Query:  series.Title + " EPISODES",
+		Movies: movies,
+	}
+}
+
 // fetchFunc : A function that performs initiates the fetching process of the
 // scrapers. It could be the `Search` or `List` function of the engine
-type fetchFunc func() engine.SearchResult
+type fetchFunc func() (engine.SearchResult, error)
 
 // ProcessFetchTask : Process a task in the Terminal and show processing
-func ProcessFetchTask(fn fetchFunc) engine.SearchResult {
-	var result engine.SearchResult
+// The process exits if ctx is cancelled (e.g ctrl-C) while fetching
+func ProcessFetchTask(ctx context.Context, fn fetchFunc) engine.SearchResult {
+	result := fetchResult(ctx, fn)
+	if len(result.Movies) <= 0 {
+		log.Info("No Results Found")
+		os.Exit(0)
+	}
+	return result
+}
+
+// fetchResult : Fetch a result showing a spinner meanwhile and apply the CLI filters
+// to it, dropping movies with dead links with --verify-links and resolving their
+// direct links with --resolve-links. Exits when the fetch
+// fails or is cancelled
+func fetchResult(ctx context.Context, fn fetchFunc) engine.SearchResult {
+	var (
+		result engine.SearchResult
+		err    error
+	)
+	filter := cliFilter()
+	if err := filter.validate(); err != nil {
+		log.Fatal(err)
+	}
 	if !viper.GetBool("verbose") {
 		s := spinner.New(spinner.CharSets[11], 100*time.Millisecond)
 		s.Suffix = " Fetching Data..."
 		s.Writer = os.Stderr
 		s.Start()
-		result = fn()
+		result, err = fn()
 		s.Stop()
 	} else {
-		result = fn()
+		result, err = fn()
 	}
-	if len(result.Movies) <= 0 {
-		log.Info("No Results Found")
-		os.Exit(0)
+	if ctx.Err() != nil {
+		log.Info("Fetch cancelled")
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	// the movies are partial when engines failed along the way
+	for _, engineErr := range result.Errors {
+		log.Warn(engineErr)
+	}
+	result = filter.apply(result)
+	if viper.GetBool("verify-links") {
+		result = pruneDeadLinks(ctx, result)
+	}
+	if viper.GetBool("resolve-links") {
+		result.Movies = engine.ResolveLinks(ctx, result.Movies, defaultVerifyParallel, defaultVerifyTimeout)
+	}
+	return result
+}
+
+// cliQuery : query with the operators of --year and --genre, see engine.ParseQuery
+func cliQuery(query string) string {
+	if year := viper.GetString("year"); year != "" {
+		query += " year:" + year
+	}
+	if genres := viper.GetStringSlice("genre"); len(genres) > 0 {
+		query += " genre:" + strings.ReplaceAll(strings.Join(genres, ","), " ", "_")
+	}
+	return query
+}
+
+// resultFilter : Filters and ordering of results chosen with the CLI flags or the
+// API query parameters
+type resultFilter struct {
+	Quality    string
+	YearFrom   int
+	YearTo     int
+	MinSize    string // e.g 700MB
+	MaxSize    string
+	Sources    []string
+	Languages  []string // ISO 639-1 codes or names
+	SeriesOnly bool
+	Sort       string // year or size
+	Descending bool
+	RankBy     string // criterion results are ranked by before sorting, see engine.RankCriterion
+}
+
+// errInvalidSort : the results cannot be sorted by what was asked
+var errInvalidSort = errors.New("sort must be year or size")
+
+// errInvalidLanguage : a language to filter by is neither a code nor a known name
+var errInvalidLanguage = errors.New("language must be an ISO 639-1 code e.g en or the name of a language e.g korean")
+
+// errInvalidSize : a size to filter by could not be parsed
+var errInvalidSize = errors.New("size must be a number of bytes with a unit e.g 700MB")
+
+// cliFilter : The result filter set with the CLI flags
+func cliFilter() resultFilter {
+	return resultFilter{
+		Quality:    viper.GetString("quality"),
+		YearFrom:   viper.GetInt("year-from"),
+		YearTo:     viper.GetInt("year-to"),
+		MinSize:    viper.GetString("min-size"),
+		MaxSize:    viper.GetString("max-size"),
+		Sources:    viper.GetStringSlice("source"),
+		Languages:  viper.GetStringSlice("lang"),
+		SeriesOnly: viper.GetBool("series-only"),
+		Sort:       viper.GetString("sort"),
+		Descending: viper.GetBool("desc"),
+		RankBy:     viper.GetString("rank-by"),
+	}
+}
+
+// validate : Check the filter can be applied
+func (f resultFilter) validate() error {
+	for _, size := range []string{f.MinSize, f.MaxSize} {
+		if size != "" && engine.ParseSize(size) == 0 {
+			return fmt.Errorf("%w, got %q", errInvalidSize, size)
+		}
+	}
+	for _, language := range f.Languages {
+		if _, ok := engine.LanguageCode(language); !ok {
+			return fmt.Errorf("%w, got %q", errInvalidLanguage, language)
+		}
+	}
+	if _, err := engine.ParseRankCriterion(f.RankBy); err != nil {
+		return err
+	}
+	switch strings.ToLower(f.Sort) {
+	case "", "year", "size":
+		return nil
+	}
+	return fmt.Errorf("%w, got %q", errInvalidSort, f.Sort)
+}
+
+// apply : Keep the movies of result matching the filter in the order asked for
+func (f resultFilter) apply(result engine.SearchResult) engine.SearchResult {
+	if f.Quality != "" {
+		result = result.FilterByQuality(f.Quality)
+	}
+	if f.YearFrom != 0 || f.YearTo != 0 {
+		result = result.FilterByYearRange(f.YearFrom, f.YearTo)
+	}
+	if f.MinSize != "" || f.MaxSize != "" {
+		result = result.FilterBySize(engine.ParseSize(f.MinSize), engine.ParseSize(f.MaxSize))
+	}
+	if len(f.Sources) > 0 {
+		result = result.FilterBySource(f.Sources...)
+	}
+	if len(f.Languages) > 0 {
+		result = result.FilterByLanguage(f.Languages...)
+	}
+	if f.SeriesOnly {
+		result = result.FilterSeriesOnly()
+	}
+	if f.RankBy != "" {
+		criterion, _ := engine.ParseRankCriterion(f.RankBy)
+		result.Rank(criterion)
+	}
+	switch strings.ToLower(f.Sort) {
+	case "year":
+		result.SortByYear(f.Descending)
+	case "size":
+		result.SortBySize(f.Descending)
 	}
 	return result
 }
 
+// keep : Whether the filter keeps movie
+func (f resultFilter) keep(movie engine.Movie) bool {
+	return len(f.apply(engine.SearchResult{Movies: []engine.Movie{movie}}).Movies) == 1
+}
+
+// copyLabel : a copy of a movie as listed among the others, by source, quality and size
+func copyLabel(movie engine.Movie) string {
+	label := movie.Source
+	if q := movie.Quality.String(); q != "" {
+		label += " " + q
+	}
+	if size := engine.FormatSize(movie.SizeBytes); size != "" {
+		label += " " + size
+	}
+	return strings.TrimSpace(label)
+}
+
+// pickMovie : Select a movie of result. With --group-duplicates the copies of a
+// movie are listed once, and one of them is selected next when there are several
+func pickMovie(result engine.SearchResult) (engine.Movie, error) {
+	if !viper.GetBool("group-duplicates") {
+		_, choice := SelectOpts(result.Query, result.Titles())
+		return result.GetMovieByTitle(choice)
+	}
+	groups := engine.GroupDuplicates(result.Movies)
+	items := make([]string, len(groups))
+	for i, group := range groups {
+		items[i] = group.Movie.Title
+		if n := len(group.Alternatives); n > 0 {
+			items[i] = fmt.Sprintf("%s (%d copies)", group.Movie.Title, n+1)
+		}
+	}
+	index, _ := SelectOpts(result.Query, items)
+	copies := groups[index].Copies()
+	if len(copies) == 1 {
+		return copies[0], nil
+	}
+	labels := make([]string, len(copies))
+	for i, movie := range copies {
+		labels[i] = fmt.Sprintf("%s - %s", copyLabel(movie), movie.Title)
+	}
+	index, _ = SelectOpts(copies[0].Title, labels)
+	return copies[index], nil
+}
+
 // SelectOpts : use promptui to select amongst options
 func SelectOpts(title string, options []string) (int, string) {
 	prompt := promptui.Select{
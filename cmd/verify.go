@@ -0,0 +1,133 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	verifyTimeout  time.Duration
+	verifyParallel int
+	verifyStats    bool
+)
+
+// Defaults of link checks made with --verify-links
+const (
+	defaultVerifyTimeout  = 10 * time.Second
+	defaultVerifyParallel = 8
+)
+
+// pruneDeadLinks : result without the movies whose download links are all dead
+func pruneDeadLinks(ctx context.Context, result engine.SearchResult) engine.SearchResult {
+	before := len(result.Movies)
+	result.Movies, _ = engine.VerifyLinks(ctx, result.Movies, defaultVerifyParallel, defaultVerifyTimeout, true)
+	if dropped := before - len(result.Movies); dropped > 0 {
+		log.Infof("Dropped %d movies with dead links", dropped)
+	}
+	return result
+}
+
+// printLinkReports : a line for every checked link of the movies
+func printLinkReports(w io.Writer, reports []engine.LinkReport) (dead int) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TITLE\tSOURCE\tLINK\tSTATUS\tERROR")
+	for _, report := range reports {
+		for _, check := range report.Links {
+			state := "ok"
+			if check.Dead {
+				state = "dead"
+				dead++
+			}
+			title := report.Title
+			if check.Key != "" {
+				title += " / " + check.Key
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", title, report.Source, check.Link, state, check.Error)
+		}
+	}
+	tw.Flush()
+	return dead
+}
+
+// printLinkRot : the link statistics of every engine
+func printLinkRot(w io.Writer, stats []engine.LinkStats) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENGINE\tCHECKED\tDEAD\tROT")
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.0f%%\n", s.Engine, s.Checked, s.Dead, 100*float64(s.Dead)/float64(s.Checked))
+	}
+	tw.Flush()
+}
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify [query]",
+	Short: "check which download links are dead",
+	Long: `Verify
+			gophie verify jumanji -e fzmovies
+			gophie verify --page 2
+			gophie verify --stats
+
+	The download links of the results of a search, or of the listed movies when there is
+	no query, are checked with HEAD requests. Dead links are counted against their engine
+	and engines with many dead links are ranked last when searching every engine.
+	--stats shows how many links of each engine were found dead
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if verifyStats {
+			printLinkRot(os.Stdout, engine.LinkRot())
+			return
+		}
+		ctx := cmd.Context()
+		selectedEngine, err := getEngine(viper.GetString("engine"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		result := ProcessFetchTask(ctx, func() (engine.SearchResult, error) {
+			if len(args) == 0 {
+				return selectedEngine.List(ctx, pageNum)
+			}
+			return searchEngine(ctx, selectedEngine, strings.Join(args, " "))
+		})
+		_, reports := engine.VerifyLinks(ctx, result.Movies, verifyParallel, verifyTimeout, false)
+		if ctx.Err() != nil {
+			log.Fatal(ctx.Err())
+		}
+		if dead := printLinkReports(os.Stdout, reports); dead > 0 {
+			fmt.Printf("\n%d links are dead\n", dead)
+		}
+	},
+}
+
+func init() {
+	verifyCmd.Flags().IntVarP(&pageNum, "page", "p", 1, "Page Number to list when there is no query")
+	verifyCmd.Flags().DurationVar(&verifyTimeout, "timeout", defaultVerifyTimeout, "How long every link has to respond")
+	verifyCmd.Flags().IntVar(&verifyParallel, "parallel", defaultVerifyParallel, "Number of links checked at once")
+	verifyCmd.Flags().BoolVar(&verifyStats, "stats", false, "Show how many links of each engine were found dead")
+	rootCmd.AddCommand(verifyCmd)
+}
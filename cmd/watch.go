@@ -0,0 +1,130 @@
+/*
+Copyright © 2020 Bisoncorps
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/watch"
+	"github.com/go-phie/gophie/webhook"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	watchInterval  time.Duration
+	watchDesktop   bool
+	watchWebhook   string
+	telegramToken  string
+	telegramChatID string
+)
+
+// watchNotifiers : The notifiers set with the watch flags, desktop notifications
+// when none is set or with --notify
+func watchNotifiers() []watch.Notifier {
+	var notifiers []watch.Notifier
+	if url := viper.GetString("webhook"); url != "" {
+		notifiers = append(notifiers, &watch.Webhook{URL: url})
+	}
+	if token := viper.GetString("telegram-token"); token != "" {
+		chatID := viper.GetString("telegram-chat-id")
+		if chatID == "" {
+			log.Fatal("--telegram-chat-id is needed to notify with a telegram bot")
+		}
+		notifiers = append(notifiers, &watch.Telegram{Token: token, ChatID: chatID})
+	}
+	if d := webhooks(); d != nil && d.Subscribed(webhook.WatchMatched) {
+		notifiers = append(notifiers, webhook.WatchNotifier{Dispatcher: d})
+	}
+	if viper.GetBool("desktop") || viper.GetBool("notify") || len(notifiers) == 0 {
+		notifiers = append(notifiers, watch.Desktop{})
+	}
+	return notifiers
+}
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "notify when a movie matching a title or keyword is uploaded",
+	Long: `Watch
+			gophie watch "The Batman"
+			gophie watch Batman --quality 1080p --interval 1h --telegram-token <token> --telegram-chat-id <chat>
+
+	The recent uploads and the search results of every engine, or of the engine
+	selected with --engine, are checked periodically. A desktop notification, a POST
+	to --webhook or a message from a telegram bot is sent when a new matching movie
+	appears, and a watch.matched event to the webhooks of the config file. Movies
+	already uploaded when a title is first watched are not notified
+	`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		engines := map[string]engine.Engine{}
+		names := []string{viper.GetString("engine")}
+		if !cmd.Flags().Changed("engine") {
+			names = names[:0]
+			for name := range allEngines() {
+				names = append(names, name)
+			}
+		}
+		for _, name := range names {
+			e, err := getEngine(name)
+			if err != nil {
+				log.Fatal(err)
+			}
+			engines[name] = e
+		}
+		filter := cliFilter()
+		if err := filter.validate(); err != nil {
+			log.Fatal(err)
+		}
+		seen, err := watch.OpenSeen(path.Join(viper.GetString("cache-dir"), "watch.db"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer seen.Close()
+
+		w := &watch.Watcher{
+			Query:     strings.Join(args, " "),
+			Engines:   engines,
+			Interval:  watchInterval,
+			Seen:      seen,
+			Notifiers: watchNotifiers(),
+			Filter:    filter.apply,
+			New:       getEngine,
+		}
+		log.Infof("Watching %d engines for %s every %s, stop with ctrl-C", len(engines), w.Query, watchInterval)
+		if err := w.Run(cmd.Context()); err != nil && cmd.Context().Err() == nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", watch.DefaultInterval, "How often engines are checked for new uploads")
+	watchCmd.Flags().BoolVar(&watchDesktop, "desktop", false, "Show desktop notifications, the default when no other notifier is set")
+	watchCmd.Flags().StringVar(&watchWebhook, "webhook", "", "URL new uploads are posted to as JSON")
+	watchCmd.Flags().StringVar(&telegramToken, "telegram-token", "", "Token of the telegram bot sending new uploads")
+	watchCmd.Flags().StringVar(&telegramChatID, "telegram-chat-id", "", "Telegram chat new uploads are sent to")
+	viper.BindPFlag("desktop", watchCmd.Flags().Lookup("desktop"))
+	viper.BindPFlag("webhook", watchCmd.Flags().Lookup("webhook"))
+	viper.BindPFlag("telegram-token", watchCmd.Flags().Lookup("telegram-token"))
+	viper.BindPFlag("telegram-chat-id", watchCmd.Flags().Lookup("telegram-chat-id"))
+	rootCmd.AddCommand(watchCmd)
+}
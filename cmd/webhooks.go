@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/queue"
+	"github.com/go-phie/gophie/webhook"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	webhookDispatcher *webhook.Dispatcher
+	webhookOnce       sync.Once
+)
+
+// webhooks : The dispatcher of the webhooks of the config file, nil when none is
+// configured. Invalid hooks are left out with a warning
+func webhooks() *webhook.Dispatcher {
+	webhookOnce.Do(func() {
+		var hooks, valid []webhook.Hook
+		if err := viper.UnmarshalKey("webhooks", &hooks); err != nil {
+			log.Warnf("Invalid webhooks config: %v", err)
+			return
+		}
+		for _, hook := range hooks {
+			if err := hook.Validate(); err != nil {
+				log.Warn(err)
+				continue
+			}
+			valid = append(valid, hook)
+		}
+		if len(valid) > 0 {
+			webhookDispatcher = webhook.NewDispatcher(valid)
+		}
+	})
+	return webhookDispatcher
+}
+
+// notifyDownload : Post a download.completed event for the movie downloaded to
+// file to the webhooks, or a download.failed event when err is set, and show it
+// on the desktop with --notify
+func notifyDownload(movie engine.Movie, file string, err error) {
+	alertDownload(movie, file, err)
+	d := webhooks()
+	if d == nil {
+		return
+	}
+	p := webhook.Payload{Event: webhook.DownloadCompleted, Movie: &movie, Path: file}
+	if err != nil {
+		p = webhook.Payload{Event: webhook.DownloadFailed, Movie: &movie, Error: err.Error()}
+	}
+	if err := d.Send(context.Background(), p); err != nil {
+		log.Warnf("Could not notify the webhooks of %s: %v", movie.Title, err)
+	}
+}
+
+// notifyQueued : Post the downloads of the queue which failed to the webhooks,
+// those completed are posted once filed into the media library
+func notifyQueued(worker *queue.Worker) {
+	worker.OnFailed = func(item queue.Item) {
+		notifyDownload(item.Movie, "", errors.New(item.Error))
+	}
+}
@@ -0,0 +1,103 @@
+// Package crawl walks the lists of engines page after page for deep scrapes of
+// their catalogs, remembering the page and the movie every crawl got to so that
+// one interrupted by ctrl-C, a failing site or a limit of pages continues from
+// there with `gophie list --continue` rather than from the first page again
+package crawl
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+// State : How far the crawl of the list of an engine in a mode got
+type State struct {
+	Engine    string
+	Mode      engine.ScrapeMode
+	Page      int    // the page the crawl continues from
+	Item      int    // movies of Page already scraped
+	LastKey   string `json:",omitempty"` // link of the last movie scraped, found again when the site shifted its list
+	Movies    int    // movies scraped since the crawl started
+	Done      bool   // the last page of the site was scraped
+	StartedAt time.Time
+	UpdatedAt time.Time
+}
+
+// New : the state of a crawl of e in mode starting at page
+func New(e engine.Engine, mode engine.ScrapeMode, page int) State {
+	if page < 1 {
+		page = 1
+	}
+	return State{Engine: e.String(), Mode: mode, Page: page, StartedAt: time.Now()}
+}
+
+// id : the key of the crawls of the list of engine in mode
+func id(engineName string, mode engine.ScrapeMode) string {
+	return strings.ToLower(engineName) + "/" + mode.String()
+}
+
+// movieKey : what tells the movies of a list apart
+func movieKey(movie engine.Movie) string {
+	if movie.DownloadLink != nil {
+		return movie.DownloadLink.String()
+	}
+	return movie.Source + "\x00" + movie.Title
+}
+
+// resumeAt : The index of the first movie of the page the crawl has not scraped,
+// after its last movie when the page still has it as lists of new uploads shift
+// their movies to the next pages, else after the movies already scraped
+func (s State) resumeAt(movies []engine.Movie) int {
+	if s.LastKey != "" {
+		for i, movie := range movies {
+			if movieKey(movie) == s.LastKey {
+				return i + 1
+			}
+		}
+	}
+	if s.Item > len(movies) {
+		return len(movies)
+	}
+	return s.Item
+}
+
+// Crawl : Scrape the list of e from where state got to, at most pages pages or
+// up to the last page when pages is 0, calling fn with every movie not scraped
+// yet. The state is stored after every page and when the crawl stops early, with
+// the movie it stopped at, and is returned with the error which stopped it
+func Crawl(ctx context.Context, store *Store, e engine.Engine, state State, pages int, fn func(engine.Movie) error) (State, error) {
+	save := func(err error) (State, error) {
+		state.UpdatedAt = time.Now()
+		if saveErr := store.Save(state); saveErr != nil && err == nil {
+			err = saveErr
+		}
+		return state, err
+	}
+	for fetched := 0; !state.Done && (pages <= 0 || fetched < pages); fetched++ {
+		result, err := engine.ListBy(ctx, e, state.Mode, state.Page)
+		if err != nil {
+			return save(err)
+		}
+		for i := state.resumeAt(result.Movies); i < len(result.Movies); i++ {
+			if ctx.Err() != nil {
+				return save(ctx.Err())
+			}
+			if err := fn(result.Movies[i]); err != nil {
+				return save(err)
+			}
+			state.Item, state.LastKey = i+1, movieKey(result.Movies[i])
+			state.Movies++
+		}
+		if !result.HasNext || len(result.Movies) == 0 {
+			state.Done = true
+		} else {
+			state.Page, state.Item, state.LastKey = state.Page+1, 0, ""
+		}
+		if _, err := save(nil); err != nil {
+			return state, err
+		}
+	}
+	return state, nil
+}
@@ -0,0 +1,88 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+// listEngine : lists pages of 3 movies, the newest first, failing on the page in fail
+type listEngine struct {
+	pages    int
+	uploaded int // movies uploaded since the crawl started, shifting the list
+	fail     int
+}
+
+func (e *listEngine) Search(ctx context.Context, param ...string) (engine.SearchResult, error) {
+	return engine.SearchResult{}, nil
+}
+
+func (e *listEngine) List(ctx context.Context, page int) (engine.SearchResult, error) {
+	if page == e.fail {
+		return engine.SearchResult{}, engine.ErrEngineUnavailable
+	}
+	result := engine.SearchResult{Page: page, HasNext: page < e.pages}
+	for i := 0; i < 3; i++ {
+		n := (page-1)*3 + i - e.uploaded
+		link, _ := url.Parse(fmt.Sprintf("https://list.example/%d.mp4", n))
+		result.Movies = append(result.Movies, engine.Movie{Title: fmt.Sprint("Movie ", n), DownloadLink: link})
+	}
+	return result, nil
+}
+
+func (e *listEngine) Capabilities() engine.Capabilities { return engine.Capabilities{Pagination: true} }
+func (e *listEngine) String() string                    { return "List" }
+
+func TestCrawl(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "crawl.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &listEngine{pages: 4, fail: 3}
+	var titles []string
+	collect := func(movie engine.Movie) error {
+		titles = append(titles, movie.Title)
+		if len(titles) == 2 {
+			return errors.New("interrupted")
+		}
+		return nil
+	}
+	state, err := Crawl(context.Background(), store, e, New(e, engine.Latest, 1), 0, collect)
+	if err == nil || state.Page != 1 || state.Item != 1 {
+		t.Fatalf("Expected the crawl stopped before the second movie, got %+v %v", state, err)
+	}
+
+	// the rest of page 1 and page 2 are scraped before page 3 fails
+	stored, found, err := store.Get("list", engine.Latest)
+	if err != nil || !found || stored.Page != 1 || stored.LastKey != "https://list.example/0.mp4" {
+		t.Fatalf("Expected the state stored, got %+v %t %v", stored, found, err)
+	}
+	state, err = Crawl(context.Background(), store, e, stored, 0, collect)
+	if !errors.Is(err, engine.ErrEngineUnavailable) || state.Page != 3 || state.Item != 0 || len(titles) != 7 || titles[2] != "Movie 1" {
+		t.Fatalf("Expected the crawl stopped at page 3, got %+v %v %v", state, err, titles)
+	}
+
+	// an upload shifts the list by one movie, the last one scraped is found again
+	e.fail, e.uploaded = 0, 1
+	state.Item, state.LastKey = 1, "https://list.example/6.mp4"
+	titles = nil
+	state, err = Crawl(context.Background(), store, e, state, 1, collect)
+	if err != nil || state.Page != 4 || len(titles) != 1 || titles[0] != "Movie 7" {
+		t.Fatalf("Expected the movie after the last one scraped, got %+v %v %v", state, err, titles)
+	}
+	state, err = Crawl(context.Background(), store, e, state, 0, func(engine.Movie) error { return nil })
+	if err != nil || !state.Done || state.Movies != 10 {
+		t.Errorf("Expected the crawl done at the last page, got %+v %v", state, err)
+	}
+	if err = store.Delete("List", engine.Latest); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ = store.Get("List", engine.Latest); found {
+		t.Error("Expected the crawl deleted")
+	}
+}
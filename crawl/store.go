@@ -0,0 +1,87 @@
+package crawl
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	bolt "go.etcd.io/bbolt"
+)
+
+var crawlsBucket = []byte("crawls")
+
+// Store : The state of the crawl of every engine and mode, the database is only
+// opened for the duration of an operation like the jobs
+type Store struct {
+	path string
+}
+
+// Open : Open (or create) the crawls stored at path
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := s.withDB(true, func(b *bolt.Bucket) error { return nil }); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// withDB : run fn in a transaction on the crawls bucket
+func (s *Store) withDB(writable bool, fn func(*bolt.Bucket) error) error {
+	db, err := bolt.Open(s.path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if !writable {
+		return db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket(crawlsBucket)
+			if b == nil {
+				return nil
+			}
+			return fn(b)
+		})
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(crawlsBucket)
+		if err != nil {
+			return err
+		}
+		return fn(b)
+	})
+}
+
+// Get : The state of the crawl of the list of engineName in mode, and whether
+// one was stored
+func (s *Store) Get(engineName string, mode engine.ScrapeMode) (State, bool, error) {
+	var (
+		state State
+		found bool
+	)
+	err := s.withDB(false, func(b *bolt.Bucket) error {
+		v := b.Get([]byte(id(engineName, mode)))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &state)
+	})
+	return state, found, err
+}
+
+// Save : Store state, replacing the crawl of its engine and mode
+func (s *Store) Save(state State) error {
+	v, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.withDB(true, func(b *bolt.Bucket) error {
+		return b.Put([]byte(id(state.Engine, state.Mode)), v)
+	})
+}
+
+// Delete : Forget the crawl of the list of engineName in mode
+func (s *Store) Delete(engineName string, mode engine.ScrapeMode) error {
+	return s.withDB(true, func(b *bolt.Bucket) error {
+		return b.Delete([]byte(id(engineName, mode)))
+	})
+}
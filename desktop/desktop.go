@@ -0,0 +1,42 @@
+// Package desktop shows notifications in the notification center of Linux, macOS
+// and Windows, so that gophie can be left running in the background and still
+// tell when a download finished, a watched movie was uploaded or the download
+// queue is done
+package desktop
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/gen2brain/beeep"
+	"github.com/go-phie/gophie/engine"
+)
+
+// send : shows a notification, replaced in tests
+var send = func(title, message string) error {
+	return beeep.Notify(title, message, "")
+}
+
+// Notify : Show a notification with title and message
+func Notify(title, message string) error {
+	return send(title, message)
+}
+
+// Download : Tell that the movie was downloaded to file, or that its download
+// failed with err
+func Download(movie engine.Movie, file string, err error) error {
+	if err != nil {
+		return Notify("Gophie: download failed", fmt.Sprintf("%s could not be downloaded: %v", movie.Title, err))
+	}
+	return Notify("Gophie: download finished", fmt.Sprintf("%s was saved as %s", movie.Title, filepath.Base(file)))
+}
+
+// QueueDrained : Tell that the downloads of the queue are done, completed of
+// them and failed ones
+func QueueDrained(completed, failed int) error {
+	message := fmt.Sprintf("%d downloads completed", completed)
+	if failed > 0 {
+		message += fmt.Sprintf(", %d failed", failed)
+	}
+	return Notify("Gophie: download queue finished", message)
+}
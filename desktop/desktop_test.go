@@ -0,0 +1,36 @@
+package desktop
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+func TestNotifications(t *testing.T) {
+	var titles, messages []string
+	defer func(s func(string, string) error) { send = s }(send)
+	send = func(title, message string) error {
+		titles, messages = append(titles, title), append(messages, message)
+		return nil
+	}
+	movie := engine.Movie{Title: "Jumanji"}
+	Download(movie, "/movies/Jumanji (2017).mp4", nil)
+	Download(movie, "", errors.New("connection reset"))
+	QueueDrained(3, 1)
+
+	expected := []struct{ title, message string }{
+		{"Gophie: download finished", "Jumanji was saved as Jumanji (2017).mp4"},
+		{"Gophie: download failed", "Jumanji could not be downloaded: connection reset"},
+		{"Gophie: download queue finished", "3 downloads completed, 1 failed"},
+	}
+	if len(titles) != len(expected) {
+		t.Fatalf("Expected %d notifications, got %v", len(expected), titles)
+	}
+	for i, e := range expected {
+		if titles[i] != e.title || !strings.Contains(messages[i], e.message) {
+			t.Errorf("Expected %q %q, got %q %q", e.title, e.message, titles[i], messages[i])
+		}
+	}
+}
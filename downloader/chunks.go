@@ -0,0 +1,204 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// errRangeMismatch : the server answered a range request with other bytes than
+// those asked for, so its ranges cannot be trusted
+var errRangeMismatch = errors.New("server returned another range than requested")
+
+// contentRange : the first and last bytes of the response to a range request,
+// told by its Content-Range such as "bytes 100-199/1000"
+func contentRange(resp *http.Response) (start, end int64, ok bool) {
+	header := strings.TrimPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	bytesRange := strings.SplitN(strings.SplitN(header, "/", 2)[0], "-", 2)
+	if len(bytesRange) != 2 {
+		return 0, 0, false
+	}
+	start, startErr := strconv.ParseInt(strings.TrimSpace(bytesRange[0]), 10, 64)
+	end, endErr := strconv.ParseInt(strings.TrimSpace(bytesRange[1]), 10, 64)
+	return start, end, startErr == nil && endErr == nil
+}
+
+// partName : the part file of bytes start-end of dest, named after its range so
+// that parts of a run with another number of chunks are not resumed
+func partName(dest string, start, end int64) string {
+	return fmt.Sprintf("%s.part%d-%d", dest, start, end)
+}
+
+// removeStaleParts : remove the part files of dest which are not in parts, left
+// by a run with another number of chunks or size of the file
+func removeStaleParts(dest string, parts []string) {
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	if err != nil {
+		return
+	}
+	current := map[string]bool{}
+	for _, part := range parts {
+		current[filepath.Base(part)] = true
+	}
+	prefix := filepath.Base(dest) + ".part"
+	for _, entry := range entries {
+		if name := entry.Name(); strings.HasPrefix(name, prefix) && !current[name] {
+			log.Debugf("Removing %s of another download of %s", name, dest)
+			os.Remove(filepath.Join(filepath.Dir(dest), name))
+		}
+	}
+}
+
+// downloadChunks : download the file as f.Chunks ranges concurrently and join them into dest
+// Each range is written to its own part file so interrupted chunks can be resumed
+func (f *Downloader) downloadChunks(ctx context.Context, dest string) error {
+	chunkSize := f.Size / int64(f.Chunks)
+	if chunkSize == 0 {
+		return f.downloadStream(ctx, dest, 0)
+	}
+	log.Debugf("Downloading %s in %d chunks of %d bytes", f.Name, f.Chunks, chunkSize)
+
+	var (
+		downloaded int64
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+	)
+	// progress is reported from every chunk so calls have to be serialized
+	progress := func(n int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		downloaded += n
+		if f.OnProgress != nil {
+			f.OnProgress(downloaded, f.Size)
+		}
+	}
+
+	parts := make([]string, f.Chunks)
+	errs := make([]error, f.Chunks)
+	starts, ends := make([]int64, f.Chunks), make([]int64, f.Chunks)
+	for i := range parts {
+		starts[i] = int64(i) * chunkSize
+		ends[i] = starts[i] + chunkSize - 1
+		if i == f.Chunks-1 {
+			ends[i] = f.Size - 1
+		}
+		parts[i] = partName(dest, starts[i], ends[i])
+	}
+	removeStaleParts(dest, parts)
+	for i := range parts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = f.downloadChunk(ctx, parts[i], starts[i], ends[i], progress)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if errors.Is(err, errRangeMismatch) {
+			// the parts may hold bytes of other ranges, download the file whole
+			log.Warnf("%v, downloading %s in one request", err, f.Name)
+			for _, part := range parts {
+				os.Remove(part)
+			}
+			return f.downloadStream(ctx, dest, 0)
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return joinParts(dest, parts)
+}
+
+// downloadChunk : download bytes start-end (inclusive) of the file into part
+func (f *Downloader) downloadChunk(ctx context.Context, part string, start, end int64, progress func(int64)) error {
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+	if offset > end-start+1 {
+		// more bytes than the range holds, the part cannot be trusted
+		log.Debugf("Part %s holds %d bytes of a %d byte range, downloading it again", part, offset, end-start+1)
+		if err := os.Remove(part); err != nil {
+			return err
+		}
+		offset = 0
+	}
+	progress(offset)
+	if offset == end-start+1 {
+		return nil
+	}
+
+	req, err := f.newRequest(ctx, http.MethodGet)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start+offset, end))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("Chunk %d-%d of %s failed with status %s", start, end, f.URL, resp.Status)
+	}
+	if from, to, ok := contentRange(resp); !ok || from != start+offset || to != end {
+		return fmt.Errorf("%w: asked for byte %d of %s, got %q", errRangeMismatch, start+offset, f.Name, resp.Header.Get("Content-Range"))
+	}
+
+	file, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	written, err := io.Copy(file, &progressReader{
+		reader: limit(ctx, resp.Body, f.Limiter),
+		onProgress: func(downloaded, _ int64) {
+			progress(downloaded)
+		},
+		delta: true,
+	})
+	if err != nil {
+		return err
+	}
+	if offset+written < end-start+1 {
+		return fmt.Errorf("Chunk %d-%d of %s stopped short", start, end, f.Name)
+	}
+	return nil
+}
+
+// joinParts : concatenate the part files in order into dest and remove them
+func joinParts(dest string, parts []string) error {
+	file, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, part := range parts {
+		p, err := os.Open(part)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(file, p)
+		p.Close()
+		if err != nil {
+			return err
+		}
+	}
+	for _, part := range parts {
+		os.Remove(part)
+	}
+	return nil
+}
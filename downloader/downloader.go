@@ -1,12 +1,20 @@
 package downloader
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/go-phie/gophie/engine"
-	"github.com/iawia002/annie/downloader"
 	"github.com/iawia002/annie/extractors/types"
 	"github.com/iawia002/annie/request"
 	"github.com/iawia002/annie/utils"
@@ -55,70 +63,276 @@ func Extract(url, source string) ([]*types.Data, error) {
 
 // Downloader : pausable downloader
 type Downloader struct {
-	URL       string // URL Source
-	Dir       string // Directory to store the file
-	Name      string // Name of file
-	Source    string // Name of the Source
-	Size      int64  // Size of the file
-	Completed bool   // Status of Download
+	URL        string       // URL Source
+	Dir        string       // Directory to store the file
+	Name       string       // Name of file
+	FileName   string       // Path of the file in Dir, derived from the server response when empty
+	Template   Template     // Template FileName is rendered with from Vars once the extension is known, if set
+	Vars       TemplateVars // Details of the download the template is rendered with
+	Source     string       // Name of the Source
+	Size       int64        // Size of the file
+	Completed  bool         // Status of Download
+	Chunks     int          // Number of ranges to download concurrently, 1 or less for a single stream
+	Limiter    *Limiter     `json:"-"` // Limits the bandwidth of the download, shared with the downloads limited along with it
+	Checksum   string       // Expected hash of the file as sha256:<hex> or md5:<hex>, if known
+	Verified   Verification // How the downloaded file was checked
+	OnProgress ProgressFunc `json:"-"` // Called as bytes are written to disk
+	Space      *SpaceGuard  `json:"-"` // Refuses the download when the disk it is saved to would fill up, if set
+
+	acceptRanges bool // whether the server supports range requests
+}
+
+// Default client used for all downloads
+var httpClient = &http.Client{}
+
+// SetTransport : Send the requests of all downloads through rt e.g to download through Tor
+func SetTransport(rt http.RoundTripper) {
+	httpClient.Transport = rt
 }
 
-//TODO:  Check if Download is completed and ask for redownload confirmation
+// Path : Where the file is saved, known once the download started
+func (f *Downloader) Path() string {
+	return filepath.Join(f.Dir, f.FileName)
+}
 
-// DownloadFile : Download Files using Annie Downloader
+// DownloadFile : Download the file over HTTP into Dir
+// If a partial file already exists it is resumed using a range request.
+// When Chunks is more than 1 and the server supports ranges, the file is
+// downloaded in Chunks parts concurrently and reassembled
 func (f *Downloader) DownloadFile() error {
-	var (
-		err  error
-		data []*types.Data
-	)
+	return f.DownloadFileContext(context.Background())
+}
 
-	// Extract data to be downloaded with the streams
-	data, err = Extract(f.URL, f.Source)
+// DownloadFileContext : DownloadFile stopping when ctx is cancelled
+// The partial file is kept so the download can be resumed later
+func (f *Downloader) DownloadFileContext(ctx context.Context) error {
+	if err := f.probe(ctx); err != nil {
+		return err
+	}
+	dest := f.Path()
+	// the template may save the file in directories of its own
+	err := os.MkdirAll(filepath.Dir(dest), os.ModePerm)
 	if err != nil {
 		return err
 	}
 
-	err = os.MkdirAll(f.Dir, os.ModePerm)
+	var offset int64
+	if info, err := os.Stat(dest); err == nil {
+		offset = info.Size()
+	}
+	if f.Size > 0 && offset >= f.Size {
+		log.Infof("%s has already been downloaded to %s", f.Name, dest)
+		return f.verify()
+	}
+
+	chunked := f.Chunks > 1 && f.acceptRanges && f.Size > 0 && offset == 0
+	var needed int64
+	if f.Size > 0 {
+		needed = f.Size - offset
+	}
+	if chunked {
+		// the parts are joined into the file before they are removed
+		needed = 2 * f.Size
+	}
+	release, err := f.Space.Reserve(filepath.Dir(dest), needed)
+	if err != nil {
+		return fmt.Errorf("Could not download %s: %w", f.Name, err)
+	}
+	defer release()
+
+	if chunked {
+		err = f.downloadChunks(ctx, dest)
+	} else {
+		err = f.downloadStream(ctx, dest, offset)
+	}
 	if err != nil {
 		return err
 	}
+	log.Infof("Downloaded %s to %s", f.Name, dest)
+	return f.verify()
+}
 
-	if f.Size == 0 {
-		f.Size, err = request.Size(f.URL, f.URL)
-		if err != nil {
-			return err
+// verify : Verify the downloaded file, deleting it when corrupted so that it is
+// downloaded again from the start rather than resumed
+func (f *Downloader) verify() error {
+	verified, err := f.Verify()
+	f.Verified = verified
+	if errors.Is(err, ErrCorrupted) {
+		log.Errorf("%v, deleting it", err)
+		os.Remove(f.Path())
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	f.Completed = true
+	if verified == Unverified {
+		log.Warnf("%s could not be verified, the source gave neither a checksum nor a size", f.Name)
+	} else {
+		log.Infof("Verified %s by its %s", f.Name, verified)
+	}
+	return nil
+}
+
+// downloadStream : download the file in a single request starting at offset
+func (f *Downloader) downloadStream(ctx context.Context, dest string, offset int64) error {
+	req, err := f.newRequest(ctx, http.MethodGet)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if start, _, ok := contentRange(resp); !ok || start != offset {
+			err := fmt.Errorf("%w: asked for byte %d of %s, got %q", errRangeMismatch, offset, f.Name, resp.Header.Get("Content-Range"))
+			if offset == 0 {
+				return err
+			}
+			// appending bytes of another range would corrupt the file
+			resp.Body.Close()
+			log.Warnf("%v, downloading it again", err)
+			return f.downloadStream(ctx, dest, 0)
 		}
+		log.Debugf("Resuming %s from byte %d", f.Name, offset)
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// Server ignored the range, start over
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil
+	default:
+		return fmt.Errorf("Download of %s failed with status %s", f.URL, resp.Status)
 	}
+	if f.Size <= 0 && resp.ContentLength > 0 {
+		f.Size = offset + resp.ContentLength
+	}
+
+	file, err := os.OpenFile(dest, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
 
-	for _, item := range data {
-		if item.Err != nil {
-			// if this error occurs, the preparation step is normal, but the data extraction is wrong.
-			// the data is an empty struct.
-			return item.Err
+	written, err := io.Copy(file, &progressReader{
+		reader:     limit(ctx, resp.Body, f.Limiter),
+		downloaded: offset,
+		total:      f.Size,
+		onProgress: f.OnProgress,
+	})
+	if err != nil {
+		return err
+	}
+	if f.Size > 0 && offset+written < f.Size {
+		return fmt.Errorf("Download of %s stopped at %d of %d bytes", f.Name, offset+written, f.Size)
+	}
+	return nil
+}
+
+func (f *Downloader) newRequest(ctx context.Context, method string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, f.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	return req, nil
+}
+
+// probe : retrieve the size, filename and range support of the download without fetching the body
+func (f *Downloader) probe(ctx context.Context) error {
+	req, err := f.newRequest(ctx, http.MethodHead)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK && f.Size <= 0 && resp.ContentLength > 0 {
+			f.Size = resp.ContentLength
 		}
-		movieDownloader := downloader.New(downloader.Options{
-			OutputPath: f.Dir,
-			Stream:     "default",
-		})
-		err = movieDownloader.Download(item)
-		if err != nil {
-			return err
+		f.acceptRanges = resp.Header.Get("Accept-Ranges") == "bytes"
+	}
+	if f.FileName == "" {
+		f.FileName = fileNameFromResponse(f, resp)
+		if f.Template != "" {
+			f.FileName = f.Template.Render(f.Vars, fileExt(f.FileName))
 		}
 	}
-	log.Infof("Downloaded %s to %s", f.Name, f.Dir)
 	return nil
 }
 
-// DownloadMovie : Download the movie
-func DownloadMovie(movie *engine.Movie, outputDir string) error {
-	url := movie.DownloadLink.String()
-	downloadHandler := &Downloader{
-		URL:    url,
-		Name:   movie.Title,
-		Source: movie.Source,
+// fileExt : the extension of a file named name, .mp4 for names of pages such as
+// download.php which are not the name of the file
+func fileExt(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if !fileExtRe.MatchString(ext) || isPageExt(ext) {
+		return ".mp4"
+	}
+	return ext
+}
+
+// Work out a name for the file on disk using the Content-Disposition header,
+// falling back to the last part of the URL path and finally the download Name
+func fileNameFromResponse(f *Downloader, resp *http.Response) string {
+	var name string
+	if resp != nil {
+		if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+			name = params["filename"]
+		}
+	}
+	if name == "" {
+		if u, err := url.Parse(f.URL); err == nil {
+			name, _ = url.PathUnescape(path.Base(u.Path))
+		}
+	}
+	if name == "" || name == "/" || name == "." {
+		name = f.Name
+	}
+	if path.Ext(name) == "" && resp != nil {
+		if exts, err := mime.ExtensionsByType(resp.Header.Get("Content-Type")); err == nil && len(exts) > 0 {
+			name += exts[0]
+		}
+	}
+	return SanitizeFilename(name, FilenameOptions{})
+}
+
+// MovieDir : Directory in outputDir that files of movie are downloaded to
+func MovieDir(outputDir string, movie *engine.Movie) string {
+	return filepath.Join(outputDir, filepath.Dir(MovieTemplate().Render(MovieVars(movie), "")))
+}
+
+// NewMovieDownloader : Downloader of the movie into outputDir, at the path of the filename template
+// The file is downloaded from the direct link of the movie once it is resolved
+func NewMovieDownloader(movie *engine.Movie, outputDir string) *Downloader {
+	return &Downloader{
+		URL:      movie.Link().String(),
+		Dir:      outputDir,
+		Template: MovieTemplate(),
+		Vars:     MovieVars(movie),
+		Name:     movie.Title,
+		Source:   movie.Source,
+		Chunks:   viper.GetInt("chunks"),
+		Checksum: movie.Checksum,
+		Limiter:  ConfiguredLimiter(),
+		Space:    ConfiguredSpaceGuard(),
 	}
+}
 
-	downloadHandler.Dir = path.Join(outputDir, downloadHandler.Name)
+// DownloadMovie : Download the movie until ctx is cancelled, the returned
+// downloader tells where it was saved
+func DownloadMovie(ctx context.Context, movie *engine.Movie, outputDir string) (*Downloader, error) {
+	url := movie.Link().String()
+	downloadHandler := NewMovieDownloader(movie, outputDir)
+	downloadHandler.OnProgress = NewProgressBar()
 	downloadListFile := path.Join(viper.GetString("gophie_cache"), "downloadList.json")
 
 	var (
@@ -130,7 +344,7 @@ func DownloadMovie(movie *engine.Movie, outputDir string) error {
 	//get file size
 	size, err = request.Size(url, url)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	downloadHandler.Size = size
 
@@ -138,21 +352,21 @@ func DownloadMovie(movie *engine.Movie, outputDir string) error {
 	if _, err = os.Stat(downloadListFile); err == nil {
 		downloadsFile, err = os.OpenFile(downloadListFile, os.O_RDONLY, os.ModePerm)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		dec := json.NewDecoder(downloadsFile)
 		if err = dec.Decode(&downloads); err != nil {
-			return err
+			return nil, err
 		}
 		downloadsFile.Close()
 	} else if os.IsNotExist(err) {
 		// Create Download List File if it does not exists
 		_, err = os.Create(downloadListFile)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	} else {
-		return err
+		return nil, err
 	}
 
 	// Load Config
@@ -173,10 +387,10 @@ func DownloadMovie(movie *engine.Movie, outputDir string) error {
 		downloadsFile, err = os.OpenFile(downloadListFile, os.O_WRONLY, os.ModePerm)
 		enc := json.NewEncoder(downloadsFile)
 		if err = enc.Encode(downloads); err != nil {
-			return err
+			return nil, err
 		}
 	}
 	downloadsFile.Close()
 
-	return downloadHandler.DownloadFile()
+	return downloadHandler, downloadHandler.DownloadFileContext(ctx)
 }
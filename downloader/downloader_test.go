@@ -1,7 +1,25 @@
 package downloader
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-phie/gophie/engine"
 )
 
 var f = &Downloader{
@@ -14,3 +32,497 @@ var f = &Downloader{
 func TestFileSize(t *testing.T) {
 	f.DownloadFile()
 }
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		title string
+		opts  FilenameOptions
+		want  string
+	}{
+		{"Avengers: Endgame", FilenameOptions{OS: "windows"}, "Avengers_ Endgame"},
+		{"Avengers: Endgame", FilenameOptions{OS: "linux"}, "Avengers: Endgame"},
+		{"CON", FilenameOptions{OS: "windows"}, "_CON"},
+		{"con.mp4", FilenameOptions{OS: "windows"}, "_con.mp4"},
+		{"CON", FilenameOptions{OS: "linux"}, "CON"},
+		{"../../etc/passwd", FilenameOptions{OS: "linux"}, "_.._etc_passwd"},
+		{`..\..\Windows\System32`, FilenameOptions{OS: "windows"}, "_.._Windows_System32"},
+		{"Movie Night 🍿🎬", FilenameOptions{OS: "linux"}, "Movie Night"},
+		{"Trailing dots...", FilenameOptions{OS: "windows"}, "Trailing dots"},
+		{"Jumanji", FilenameOptions{OS: "linux", Year: 2019, Quality: "720p"}, "Jumanji (2019) [720p]"},
+		{"Jumanji (2019)", FilenameOptions{OS: "linux", Year: 2019}, "Jumanji (2019)"},
+		{"🍿", FilenameOptions{OS: "linux"}, "untitled"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.title, tt.opts); got != tt.want {
+			t.Errorf("SanitizeFilename(%q, %+v) = %q, want %q", tt.title, tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeFilenameLength(t *testing.T) {
+	title := strings.Repeat("é", 300)
+	got := SanitizeFilename(title, FilenameOptions{OS: "linux", MaxLength: 101})
+	if len(got) > 101 {
+		t.Errorf("Filename length %d exceeds max length 101", len(got))
+	}
+	if !utf8.ValidString(got) {
+		t.Errorf("Truncated filename %q is not valid UTF-8", got)
+	}
+
+	// releases of a long title keep the year and quality telling them apart
+	long := strings.Repeat("Jumanji ", 40)
+	hd := SanitizeFilename(long, FilenameOptions{OS: "linux", Year: 2019, Quality: "1080p", MaxLength: 100})
+	sd := SanitizeFilename(long, FilenameOptions{OS: "linux", Year: 2019, Quality: "480p", MaxLength: 100})
+	if len(hd) > 100 || !strings.HasSuffix(hd, " (2019) [1080p]") || !strings.HasSuffix(sd, " (2019) [480p]") || hd == sd {
+		t.Errorf("Expected the titles truncated before the year and quality, got %q and %q", hd, sd)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	vars := TemplateVars{Title: "Avengers: Endgame", Year: 2019, Quality: "1080p", Series: "Devs", Season: 1, Episode: 3}
+	tests := []struct {
+		template Template
+		vars     TemplateVars
+		ext      string
+		want     string
+	}{
+		{DefaultTemplate, vars, ".mkv", "Avengers_ Endgame (2019) [1080p]/Avengers_ Endgame (2019) [1080p].mkv"},
+		{"{title} ({year}) [{quality}].{ext}", TemplateVars{Title: "Jumanji"}, ".mp4", "Jumanji.mp4"},
+		{"{series}/S{season:02}E{episode:02}", vars, ".mp4", "Devs/S01E03.mp4"},
+		{"{series} - S{season:02}E{episode:02} - {episode_title}.{ext}", vars, ".mp4", "Devs - S01E03.mp4"},
+		{"S{season:02}E{episode:02}.{ext}", TemplateVars{Season: 1, Episode: 1, LastEpisode: 12}, ".mp4", "S01E01-E12.mp4"},
+		{"{title}/{title}.{ext}", TemplateVars{Title: "AC/DC: Live"}, ".mp4", "AC_DC_ Live/AC_DC_ Live.mp4"},
+		{"{title}.{ext}", TemplateVars{Title: "CON"}, ".mp4", "_CON.mp4"},
+		{"{title}.{ext}", TemplateVars{}, ".mp4", "untitled.mp4"},
+	}
+	for _, tt := range tests {
+		if got := tt.template.Render(tt.vars, tt.ext); got != tt.want {
+			t.Errorf("%q.Render(%+v) = %q, want %q", tt.template, tt.vars, got, tt.want)
+		}
+	}
+	if err := Template("{title} {resolutions}.{ext}").Validate(); err == nil {
+		t.Error("Expected an unknown placeholder to be invalid")
+	}
+	if err := DefaultEpisodeTemplate.Validate(); err != nil {
+		t.Errorf("Expected the default episode template to be valid, got %v", err)
+	}
+}
+
+func TestResumeDownload(t *testing.T) {
+	content := []byte(strings.Repeat("gophie", 1000))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "movie.mp4", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	// half downloaded file from a previous run
+	if err := os.WriteFile(filepath.Join(dir, "movie.mp4"), content[:2500], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var lastProgress int64
+	d := &Downloader{
+		URL:  ts.URL + "/movie.mp4",
+		Dir:  dir,
+		Name: "movie",
+		OnProgress: func(downloaded, total int64) {
+			lastProgress = downloaded
+		},
+	}
+	if err := d.DownloadFile(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "movie.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Resumed file does not match source, got %d bytes want %d", len(got), len(content))
+	}
+	if !d.Completed {
+		t.Errorf("Download should be marked as completed")
+	}
+	if lastProgress != int64(len(content)) {
+		t.Errorf("Expected last progress report of %d, got %d", len(content), lastProgress)
+	}
+}
+
+func TestChunkedDownload(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1003))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "movie.mp4", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "movie.mp4")
+	// second chunk was partly downloaded in a previous run
+	chunkSize := int64(len(content) / 4)
+	if err := os.WriteFile(partName(dest, chunkSize, 2*chunkSize-1), content[chunkSize:chunkSize+100], 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the third has more bytes than its range, and a run in 2 chunks left another part
+	stale := map[string][]byte{
+		partName(dest, 2*chunkSize, 3*chunkSize-1): bytes.Repeat([]byte("x"), int(chunkSize)+1),
+		partName(dest, 0, 2*chunkSize-1):           bytes.Repeat([]byte("y"), 100),
+	}
+	for part, data := range stale {
+		if err := os.WriteFile(part, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var lastProgress int64
+	d := &Downloader{
+		URL:    ts.URL + "/movie.mp4",
+		Dir:    dir,
+		Name:   "movie",
+		Chunks: 4,
+		OnProgress: func(downloaded, total int64) {
+			lastProgress = downloaded
+		},
+	}
+	if err := d.DownloadFile(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Reassembled file does not match source, got %d bytes want %d", len(got), len(content))
+	}
+	if lastProgress != int64(len(content)) {
+		t.Errorf("Expected last progress report of %d, got %d", len(content), lastProgress)
+	}
+	if parts, _ := filepath.Glob(dest + ".part*"); len(parts) != 0 {
+		t.Errorf("Part files were not cleaned up: %v", parts)
+	}
+}
+
+func TestMisalignedRange(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1003))
+	// the server answers every range with the file from its first byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") == "" {
+			http.ServeContent(w, r, "movie.mp4", time.Time{}, bytes.NewReader(content))
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(content)
+	}))
+	defer ts.Close()
+
+	for _, chunks := range []int{1, 4} {
+		dir := t.TempDir()
+		dest := filepath.Join(dir, "movie.mp4")
+		if chunks == 1 {
+			// half downloaded file from a previous run
+			if err := os.WriteFile(dest, content[:2500], 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		d := &Downloader{URL: ts.URL + "/movie.mp4", Dir: dir, Name: "movie", Chunks: chunks}
+		if err := d.DownloadFileContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := os.ReadFile(dest); !bytes.Equal(got, content) {
+			t.Errorf("Expected the file downloaded whole in %d chunks, got %d bytes want %d", chunks, len(got), len(content))
+		}
+		if parts, _ := filepath.Glob(dest + ".part*"); len(parts) != 0 {
+			t.Errorf("Part files were not cleaned up: %v", parts)
+		}
+	}
+}
+
+func TestVerifyDownload(t *testing.T) {
+	content := []byte(strings.Repeat("gophie", 100))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "movie.mp4", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+	sum := sha256.Sum256(content)
+
+	tests := []struct {
+		checksum string
+		verified Verification
+		err      error
+	}{
+		{"sha256:" + hex.EncodeToString(sum[:]), VerifiedChecksum, nil},
+		{"md5:d41d8cd98f00b204e9800998ecf8427e", VerifiedChecksum, ErrCorrupted},
+		{"", VerifiedSize, nil},
+	}
+	for _, test := range tests {
+		d := &Downloader{URL: ts.URL + "/movie.mp4", Dir: t.TempDir(), Name: "movie", Checksum: test.checksum}
+		err := d.DownloadFile()
+		if !errors.Is(err, test.err) || d.Verified != test.verified {
+			t.Errorf("Checksum %q: expected %s and %v, got %s and %v", test.checksum, test.verified, test.err, d.Verified, err)
+		}
+		_, statErr := os.Stat(d.Path())
+		if corrupted := test.err != nil; corrupted != os.IsNotExist(statErr) || corrupted == d.Completed {
+			t.Errorf("Checksum %q: corrupted downloads should be deleted and not completed, got %v", test.checksum, statErr)
+		}
+	}
+
+	// a truncated file left over is checked against the size
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "movie.mp4"), content[:10], 0644)
+	d := &Downloader{URL: ts.URL + "/movie.mp4", Dir: dir, Name: "movie", FileName: "movie.mp4", Size: 10}
+	d.DownloadFile()
+	d.Size = int64(len(content))
+	if verified, err := d.Verify(); verified != VerifiedSize || !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Expected a truncated file to fail the size check, got %s and %v", verified, err)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 100))
+	var userAgent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		http.ServeContent(w, r, "movie.mp4", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	p := NewProxy(ts.URL+"/files/movie.mp4", "Jumanji Welcome To The Jungle")
+	if p.Path() != "/Jumanji Welcome To The Jungle.mp4" || p.EscapedPath() != "/Jumanji%20Welcome%20To%20The%20Jungle.mp4" {
+		t.Errorf("Unexpected path %s escaped as %s", p.Path(), p.EscapedPath())
+	}
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+p.EscapedPath(), nil)
+	req.Header.Set("Range", "bytes=100-199")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, content[100:200]) {
+		t.Errorf("Expected bytes 100-199 to be passed through, got %s and %d bytes", resp.Status, len(body))
+	}
+	if resp.Header.Get("Content-Range") != "bytes 100-199/1000" || resp.Header.Get("Accept-Ranges") != "bytes" {
+		t.Errorf("Expected range headers of the source, got %v", resp.Header)
+	}
+	if !strings.HasPrefix(userAgent, "Mozilla") {
+		t.Errorf("Expected the source to be fetched with a browser user agent, got %q", userAgent)
+	}
+
+	resp, _ = http.Post(server.URL+p.EscapedPath(), "text/plain", nil)
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected only GET and HEAD to be allowed, got %s", resp.Status)
+	}
+}
+
+func TestSeasonDownload(t *testing.T) {
+	content := []byte(strings.Repeat("episode ", 500))
+	var mu sync.Mutex
+	failures := map[string]int{"/e02.mkv": 1}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fail := r.Method == http.MethodGet && failures[r.URL.Path] > 0
+		if fail {
+			failures[r.URL.Path]--
+		}
+		mu.Unlock()
+		if fail {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+
+	link := func(p string) *url.URL {
+		u, _ := url.Parse(ts.URL + p)
+		return u
+	}
+	series := &engine.Movie{Title: "Devs", Year: 2020, IsSeries: true}
+	series.AddEpisode(1, engine.Episode{Number: 1, DownloadLink: link("/s01e01.mp4")})
+	series.AddEpisode(2, engine.Episode{Number: 1, Title: "Pilot", DownloadLink: link("/e01.mp4")})
+	series.AddEpisode(2, engine.Episode{Number: 2, Title: "Devs S02E02", DownloadLink: link("/e02.mkv")})
+	series.AddEpisode(2, engine.Episode{Number: 3, LastNumber: 8, DownloadLink: link("/download.php")})
+
+	dir := t.TempDir()
+	var (
+		episodes     []string
+		lastProgress int64
+	)
+	download := &SeasonDownload{
+		Series: series,
+		Season: 2,
+		Dir:    dir,
+		OnProgress: func(downloaded, total int64) {
+			lastProgress = downloaded
+		},
+		OnEpisode: func(d EpisodeDownload) { episodes = append(episodes, d.Label) },
+	}
+	downloads, err := download.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(downloads) != 3 || len(episodes) != 3 {
+		t.Fatalf("Expected 3 episodes to be downloaded, got %d reported as %v", len(downloads), episodes)
+	}
+	expected := []string{"Devs - S02E01 - Pilot.mp4", "Devs - S02E02.mkv", "Devs - S02E03-E08.mp4"}
+	for i, name := range expected {
+		path := filepath.Join(dir, "Devs (2020)", "Season 02", name)
+		if downloads[i].Downloader.Path() != path {
+			t.Errorf("Expected episode %d at %s, got %s", i+1, path, downloads[i].Downloader.Path())
+		}
+		if got, err := os.ReadFile(path); err != nil || !bytes.Equal(got, content) {
+			t.Errorf("Episode %d was not downloaded: %v", i+1, err)
+		}
+	}
+	if lastProgress != int64(3*len(content)) {
+		t.Errorf("Expected the progress of the 3 episodes combined, got %d", lastProgress)
+	}
+
+	download.Season = 3
+	if _, err = download.Run(context.Background()); !errors.Is(err, ErrSeasonNotFound) {
+		t.Errorf("Expected ErrSeasonNotFound, got %v", err)
+	}
+
+	// episodes failing on every pass are reported
+	failures["/s01e01.mp4"] = 2
+	download.Season = 1
+	downloads, err = download.Run(context.Background())
+	if err == nil || downloads[0].Err == nil {
+		t.Errorf("Expected the episode failing twice to fail, got %v", err)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	for rate, want := range map[string]Rate{"": 0, "0": 0, "2M": 2 << 20, "500K": 500 << 10, "1.5MB/s": 3 << 19, "300000": 300000} {
+		if got, err := ParseRate(rate); err != nil || got != want {
+			t.Errorf("ParseRate(%q) = %d, %v want %d", rate, got, err, want)
+		}
+	}
+	for _, invalid := range []string{"fast", "-2M"} {
+		if _, err := ParseRate(invalid); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+	for _, invalid := range []string{"08:00=1M", "8am-11pm=1M", "08:00-23:00=fast"} {
+		if _, err := ParseWindow(invalid); err == nil {
+			t.Errorf("Expected schedule %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	day, _ := ParseWindow("08:00-23:00=1K")
+	night, _ := ParseWindow("23:00-02:00=0")
+	limiter := NewLimiter(Rate(4<<10), day, night)
+	clock := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	var slept time.Duration
+	limiter.now = func() time.Time { return clock }
+	limiter.sleep = func(ctx context.Context, d time.Duration) error {
+		slept += d
+		clock = clock.Add(d)
+		return ctx.Err()
+	}
+
+	for at, want := range map[int]Rate{12: 1 << 10, 23: 0, 1: 0, 5: 4 << 10} {
+		if got := limiter.RateAt(time.Date(2021, 3, 1, at, 30, 0, 0, time.UTC)); got != want {
+			t.Errorf("Expected a rate of %s at %d:30, got %s", want, at, got)
+		}
+	}
+
+	// a second of the rate is let through then reads wait for the bucket to refill
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(ctx, 512); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if slept < 450*time.Millisecond || slept > 550*time.Millisecond {
+		t.Errorf("Expected to wait for half a second, waited %s", slept)
+	}
+
+	// downloads are at full speed during unlimited windows
+	clock, slept = time.Date(2021, 3, 1, 23, 30, 0, 0, time.UTC), 0
+	for i := 0; i < 10; i++ {
+		limiter.Wait(ctx, 64<<10)
+	}
+	if slept != 0 {
+		t.Errorf("Expected no wait at night, waited %s", slept)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	clock = time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter.Wait(cancelled, 1<<10)
+	if err := limiter.Wait(cancelled, 1<<10); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled wait to fail, got %v", err)
+	}
+
+	// a limited download is complete
+	content := []byte(strings.Repeat("0123456789", 1000))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "movie.mp4", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+	d := &Downloader{URL: ts.URL + "/movie.mp4", Dir: t.TempDir(), Name: "movie", Limiter: NewLimiter(Rate(1 << 20))}
+	if err := d.DownloadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(d.Path()); !bytes.Equal(got, content) {
+		t.Errorf("Expected the limited download to be complete, got %d bytes", len(got))
+	}
+}
+
+func TestSpaceGuard(t *testing.T) {
+	g := &SpaceGuard{MinFree: 10, Margin: 0.1, free: func(string) (int64, error) { return 100, nil }}
+	release, err := g.Reserve("/movies", 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the 55 bytes held back for the first download leave 35 for the second
+	if _, err = g.Reserve("/movies", 40); !errors.Is(err, ErrInsufficientSpace) {
+		t.Errorf("Expected ErrInsufficientSpace, got %v", err)
+	}
+	release()
+	release()
+	if _, err = g.Reserve("/movies", 40); err != nil {
+		t.Errorf("Expected the space to be released, got %v", err)
+	}
+	g.Warn = true
+	if _, err = g.Reserve("/movies", 1000); err != nil {
+		t.Errorf("Expected only a warning, got %v", err)
+	}
+
+	for size, want := range map[string]int64{"": 0, "10G": 10 << 30, "500M": 500 << 20, "1.5GB": 3 << 29} {
+		if got, err := ParseSize(size); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v want %d", size, got, err, want)
+		}
+	}
+	if _, err = ParseSize("lots"); err == nil {
+		t.Error("Expected an invalid size to be rejected")
+	}
+	if free, err := FreeSpace(t.TempDir()); err != nil || free <= 0 {
+		t.Errorf("Expected the free space of the temporary directory, got %d %v", free, err)
+	}
+
+	content := []byte(strings.Repeat("gophie", 1000))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "movie.mp4", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+	dir := t.TempDir()
+	d := &Downloader{
+		URL:   ts.URL + "/movie.mp4",
+		Dir:   dir,
+		Name:  "movie",
+		Space: &SpaceGuard{free: func(string) (int64, error) { return 1000, nil }},
+	}
+	if err = d.DownloadFile(); !errors.Is(err, ErrInsufficientSpace) {
+		t.Errorf("Expected the download to be refused, got %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "movie.mp4")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be downloaded, got %v", err)
+	}
+}
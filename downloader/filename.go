@@ -0,0 +1,128 @@
+package downloader
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Defaults for filename sanitization
+const (
+	defaultMaxFilenameLength   = 200
+	defaultFilenameReplacement = "_"
+)
+
+// Characters which are not allowed in filenames on Windows
+// https://docs.microsoft.com/en-us/windows/win32/fileio/naming-a-file
+var windowsReservedChars = `<>:"/\|?*`
+
+// Device names Windows refuses as filenames, with or without an extension
+var windowsReservedNames = []string{
+	"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+}
+
+// FilenameOptions : options for turning a movie title into a filename
+type FilenameOptions struct {
+	OS          string // Target OS as in runtime.GOOS. Defaults to the current OS
+	Year        int    // Appended as " (Year)" when not zero
+	Quality     string // Appended as " [Quality]" when not empty
+	MaxLength   int    // Maximum length in bytes. Defaults to 200
+	Replacement string // Replacement for illegal characters. Defaults to "_"
+	KeepEmoji   bool   // Keep emoji and other symbols instead of stripping them
+}
+
+// SanitizeFilename : Turn a title into a filename that is valid on the target OS
+func SanitizeFilename(title string, opts FilenameOptions) string {
+	if opts.OS == "" {
+		opts.OS = runtime.GOOS
+	}
+	if opts.MaxLength <= 0 {
+		opts.MaxLength = defaultMaxFilenameLength
+	}
+	if opts.Replacement == "" || containsIllegal(opts.Replacement, opts.OS) {
+		opts.Replacement = defaultFilenameReplacement
+	}
+
+	var b strings.Builder
+	for _, r := range title {
+		switch {
+		case r == '/' || r == 0:
+			b.WriteString(opts.Replacement)
+		case opts.OS == "windows" && strings.ContainsRune(windowsReservedChars, r):
+			b.WriteString(opts.Replacement)
+		case unicode.IsControl(r):
+			// drop control characters entirely
+		case unicode.IsSpace(r):
+			b.WriteRune(' ')
+		case !opts.KeepEmoji && unicode.Is(unicode.So, r):
+			// drop emoji and other pictographs
+		case r == '\uFE0F' || r == '\u200D':
+			// drop emoji variation selectors and joiners
+		default:
+			b.WriteRune(r)
+		}
+	}
+	name := strings.Join(strings.Fields(b.String()), " ")
+
+	// the year and quality tell releases of a title apart, so the title is
+	// truncated to the length they leave rather than cutting them off
+	var suffix string
+	if opts.Year > 0 && !strings.Contains(name, fmt.Sprintf("(%d)", opts.Year)) {
+		suffix += fmt.Sprintf(" (%d)", opts.Year)
+	}
+	if opts.Quality != "" {
+		suffix += fmt.Sprintf(" [%s]", SanitizeFilename(opts.Quality, FilenameOptions{OS: opts.OS}))
+	}
+
+	// Prevent names that resolve to the current or parent directory and
+	// hidden files created from titles beginning with a dot
+	name = strings.TrimLeft(name, ".")
+	budget := opts.MaxLength - len(suffix)
+	if budget < 0 {
+		budget = 0
+	}
+	name = strings.TrimSpace(truncateUTF8(name, budget))
+	if opts.OS == "windows" {
+		// Windows silently strips trailing dots and spaces
+		name = strings.TrimRight(name, ". ")
+	}
+	name = strings.TrimSpace(truncateUTF8(name+suffix, opts.MaxLength))
+
+	if opts.OS == "windows" {
+		base := strings.ToUpper(strings.SplitN(name, ".", 2)[0])
+		for _, reserved := range windowsReservedNames {
+			if strings.TrimSpace(base) == reserved {
+				name = opts.Replacement + name
+				break
+			}
+		}
+	}
+
+	if name == "" {
+		name = "untitled"
+	}
+	return name
+}
+
+// check if any character in s would itself be illegal in a filename
+func containsIllegal(s, goos string) bool {
+	if strings.ContainsAny(s, "/\x00") {
+		return true
+	}
+	return goos == "windows" && strings.ContainsAny(s, windowsReservedChars)
+}
+
+// truncate s to at most n bytes without splitting a multibyte character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,74 @@
+package downloader
+
+import (
+	"io"
+	"os"
+
+	pb "gopkg.in/cheggaaa/pb.v1"
+)
+
+const userAgent = `Mozilla/5.0 (Windows NT 6.1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/41.0.2228.0 Safari/537.36`
+
+// ProgressFunc : reports the number of bytes downloaded so far out of total
+// total is 0 when the server does not report the size of the file
+type ProgressFunc func(downloaded, total int64)
+
+// NewProgressBar : returns a ProgressFunc that renders a progress bar on stderr
+func NewProgressBar() ProgressFunc {
+	var bar *pb.ProgressBar
+	return func(downloaded, total int64) {
+		if bar == nil {
+			bar = pb.New64(total).SetUnits(pb.U_BYTES)
+			bar.Output = os.Stderr
+			bar.Start()
+		}
+		bar.Set64(downloaded)
+		if total > 0 && downloaded >= total {
+			bar.Finish()
+		}
+	}
+}
+
+// NewCombinedProgressBar : returns a ProgressFunc that renders the combined progress
+// of several downloads on stderr, whose total grows as the sizes of the downloads
+// are known, and a func to call once they are all done
+func NewCombinedProgressBar() (ProgressFunc, func()) {
+	var bar *pb.ProgressBar
+	onProgress := func(downloaded, total int64) {
+		if bar == nil {
+			bar = pb.New64(total).SetUnits(pb.U_BYTES)
+			bar.Output = os.Stderr
+			bar.Start()
+		}
+		bar.SetTotal64(total)
+		bar.Set64(downloaded)
+	}
+	finish := func() {
+		if bar != nil {
+			bar.Finish()
+		}
+	}
+	return onProgress, finish
+}
+
+// progressReader : reports progress while reading from the wrapped reader
+type progressReader struct {
+	reader     io.Reader
+	downloaded int64
+	total      int64
+	onProgress ProgressFunc
+	delta      bool // report only the bytes read in each call instead of the running total
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.downloaded += int64(n)
+	if r.onProgress != nil && n > 0 {
+		if r.delta {
+			r.onProgress(int64(n), r.total)
+		} else {
+			r.onProgress(r.downloaded, r.total)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,93 @@
+package downloader
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// proxiedRequestHeaders : headers of players passed on to the source so that
+// seeking works and cached parts are not fetched again
+var proxiedRequestHeaders = []string{"Range", "If-Range", "If-Modified-Since", "If-None-Match"}
+
+// proxiedResponseHeaders : headers of the source passed back to players
+var proxiedResponseHeaders = []string{
+	"Accept-Ranges", "Content-Length", "Content-Range", "Content-Type",
+	"ETag", "Last-Modified",
+}
+
+// Proxy : Serves a remote file over local HTTP while it is fetched, passing
+// range requests through so players can seek and start before it is downloaded
+type Proxy struct {
+	URL    string      // URL Source
+	Name   string      // Name of the file served, shown by players
+	Header http.Header // sent to the source, such as the cookies its host requires
+}
+
+// NewProxy : Proxy of the file at url
+func NewProxy(url, name string) *Proxy {
+	return &Proxy{URL: url, Name: name}
+}
+
+// Path : path the file is served at, ending in the name of the file so that
+// players can tell its format. It is the path of requests once decoded, links
+// to the file use EscapedPath
+func (p *Proxy) Path() string {
+	name := SanitizeFilename(p.Name, FilenameOptions{})
+	if u, err := url.Parse(p.URL); err == nil && path.Ext(name) == "" {
+		name += path.Ext(u.Path)
+	}
+	return "/" + name
+}
+
+// EscapedPath : Path escaped for use in the URL of the file
+func (p *Proxy) EscapedPath() string {
+	return "/" + url.PathEscape(p.Path()[1:])
+}
+
+// ServeHTTP : Fetch the requested range of the file from the source and copy it to w
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, p.URL, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set("User-Agent", userAgent)
+	for key := range p.Header {
+		req.Header.Set(key, p.Header.Get(key))
+	}
+	for _, header := range proxiedRequestHeaders {
+		if value := r.Header.Get(header); value != "" {
+			req.Header.Set(header, value)
+		}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		log.Debugf("Could not fetch %s for streaming: %v", p.URL, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, header := range proxiedResponseHeaders {
+		if value := resp.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if r.Method == http.MethodHead {
+		return
+	}
+	// players close the connection when seeking so errors are expected here
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		log.Debugf("Stopped streaming %s: %v", p.Name, err)
+	}
+}
@@ -0,0 +1,252 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/spf13/viper"
+)
+
+// Rate : A bandwidth in bytes per second, 0 for no limit
+type Rate int64
+
+// ParseRate : The rate of a speed such as "2M", "500K", "1.5MB" or "300000" bytes per
+// second, in binary units. "0" and "" are no limit
+func ParseRate(rate string) (Rate, error) {
+	rate = strings.TrimSuffix(strings.TrimSpace(rate), "/s")
+	bytes, ok := parseBytes(rate)
+	if !ok {
+		return 0, fmt.Errorf("Invalid rate %q, use a speed such as 2M or 500K", rate)
+	}
+	return Rate(bytes), nil
+}
+
+// parseBytes : the bytes of a size such as "10G", "500K", "1.5MB" or "300000" in
+// binary units, "0" and "" are 0
+func parseBytes(size string) (int64, bool) {
+	if size == "" || size == "0" {
+		return 0, true
+	}
+	if bytes, err := strconv.ParseInt(size, 10, 64); err == nil && bytes >= 0 {
+		return bytes, true
+	}
+	withUnit := size
+	if !strings.HasSuffix(strings.ToUpper(withUnit), "B") {
+		withUnit += "B"
+	}
+	bytes := engine.ParseSize(withUnit)
+	if bytes <= 0 || size[0] < '0' || size[0] > '9' {
+		return 0, false
+	}
+	return bytes, true
+}
+
+func (r Rate) String() string {
+	if r <= 0 {
+		return "unlimited"
+	}
+	return engine.FormatSize(int64(r)) + "/s"
+}
+
+// Window : A time of day from Start to End, which wraps past midnight when End is
+// before Start, during which downloads are limited to Rate
+type Window struct {
+	Start, End time.Duration // since midnight
+	Rate       Rate
+}
+
+// contains : whether the time of day of t is in the window
+func (w Window) contains(t time.Time) bool {
+	day := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.Start <= w.End {
+		return day >= w.Start && day < w.End
+	}
+	return day >= w.Start || day < w.End
+}
+
+// parseClock : the time since midnight of a time of day such as "08:00"
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return 0, fmt.Errorf("Invalid time of day %q, use HH:MM", clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ParseWindow : The window of "08:00-23:00=500K", limiting downloads to 500K from 8am to 11pm
+func ParseWindow(window string) (Window, error) {
+	var w Window
+	span := strings.SplitN(window, "=", 2)
+	clocks := strings.SplitN(span[0], "-", 2)
+	if len(span) != 2 || len(clocks) != 2 {
+		return w, fmt.Errorf("Invalid schedule %q, use HH:MM-HH:MM=RATE", window)
+	}
+	var err error
+	if w.Start, err = parseClock(clocks[0]); err != nil {
+		return w, err
+	}
+	if w.End, err = parseClock(clocks[1]); err != nil {
+		return w, err
+	}
+	w.Rate, err = ParseRate(span[1])
+	return w, err
+}
+
+// Limiter : A token bucket limiting the bandwidth of the downloads sharing it to
+// the rate of the window of the time of day, or to its Rate outside of them
+type Limiter struct {
+	Rate     Rate
+	Schedule []Window // the first window containing the time of day applies
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+// NewLimiter : A limiter to rate outside of the windows of schedule
+func NewLimiter(rate Rate, schedule ...Window) *Limiter {
+	return &Limiter{Rate: rate, Schedule: schedule, now: time.Now, sleep: sleepContext}
+}
+
+// sleepContext : wait for d unless ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RateAt : The rate downloads are limited to at t
+func (l *Limiter) RateAt(t time.Time) Rate {
+	for _, w := range l.Schedule {
+		if w.contains(t) {
+			return w.Rate
+		}
+	}
+	return l.Rate
+}
+
+// maxWait : longest wait between checks of the rate, so that a new window of the
+// schedule applies without waiting out the previous rate
+const maxWait = time.Second
+
+// Wait : Wait until n bytes can be downloaded. A burst of up to a second at the
+// current rate is allowed
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	need := float64(n)
+	for {
+		l.mu.Lock()
+		now := l.now()
+		rate := float64(l.RateAt(now))
+		if rate <= 0 {
+			l.last = time.Time{}
+			l.mu.Unlock()
+			return nil
+		}
+		if l.last.IsZero() {
+			l.tokens = rate
+		} else {
+			l.tokens += rate * now.Sub(l.last).Seconds()
+		}
+		if l.tokens > rate {
+			l.tokens = rate
+		}
+		l.last = now
+		// reads larger than the burst are let through once the bucket is full
+		if l.tokens >= need || l.tokens >= rate {
+			l.tokens -= need
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((need - l.tokens) / rate * float64(time.Second))
+		l.mu.Unlock()
+		if wait > maxWait {
+			wait = maxWait
+		}
+		if err := l.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// limitedReader : reads from the wrapped reader no faster than its limiter allows
+type limitedReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *Limiter
+}
+
+// maxLimitedRead : most bytes read at once, so that slow rates are kept smooth
+const maxLimitedRead = 32 << 10
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > maxLimitedRead {
+		p = p[:maxLimitedRead]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.Wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// limit : reader limited by limiter, reader itself when limiter is nil
+func limit(ctx context.Context, reader io.Reader, limiter *Limiter) io.Reader {
+	if limiter == nil {
+		return reader
+	}
+	return &limitedReader{ctx: ctx, reader: reader, limiter: limiter}
+}
+
+var (
+	configuredMu      sync.Mutex
+	configuredLimiter *Limiter
+	configuredKey     string
+)
+
+// ConfiguredLimiter : The limiter shared by every download, to limit-rate and the
+// windows of rate-schedule in the config. nil when neither is set or valid
+func ConfiguredLimiter() *Limiter {
+	rate, schedule, err := ParseRateConfig(viper.GetString("limit-rate"), viper.GetStringSlice("rate-schedule"))
+	if err != nil || (rate == 0 && len(schedule) == 0) {
+		return nil
+	}
+	configuredMu.Lock()
+	defer configuredMu.Unlock()
+	key := fmt.Sprint(rate, schedule)
+	if configuredLimiter == nil || configuredKey != key {
+		configuredLimiter, configuredKey = NewLimiter(rate, schedule...), key
+	}
+	return configuredLimiter
+}
+
+// ParseRateConfig : The rate of limit and the windows of schedule
+func ParseRateConfig(limit string, schedule []string) (Rate, []Window, error) {
+	rate, err := ParseRate(limit)
+	if err != nil {
+		return 0, nil, err
+	}
+	var windows []Window
+	for _, window := range schedule {
+		w, err := ParseWindow(window)
+		if err != nil {
+			return 0, nil, err
+		}
+		windows = append(windows, w)
+	}
+	return rate, windows, nil
+}
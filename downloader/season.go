@@ -0,0 +1,219 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// ErrSeasonNotFound : the series has no episodes in the requested season
+var ErrSeasonNotFound = errors.New("Season not found")
+
+// Defaults of season downloads
+const (
+	DefaultParallel = 3
+	DefaultRetries  = 1
+)
+
+// extensions of links which are pages rather than the files of episodes
+var (
+	fileExtRe = regexp.MustCompile(`^\.[A-Za-z0-9]{2,4}$`)
+	pageExts  = []string{".php", ".html", ".htm", ".asp", ".aspx", ".jsp"}
+)
+
+// EpisodeDownload : The download of an episode of a season
+type EpisodeDownload struct {
+	Episode    engine.Episode
+	Label      string // season and episode e.g S02E05
+	Downloader *Downloader
+	Err        error // why the episode could not be downloaded, nil once it is
+}
+
+// SeasonDownload : Downloads every episode of a season of a series concurrently
+// into a directory of the season, retrying the episodes which failed
+type SeasonDownload struct {
+	Series     *engine.Movie
+	Season     int
+	Dir        string                // Output directory, episodes are saved at the path of the episode template in it
+	Parallel   int                   // Episodes downloaded at once, DefaultParallel when 0
+	Retries    int                   // Passes over the episodes which failed, DefaultRetries when 0, none when negative
+	OnProgress ProgressFunc          // Called with the bytes downloaded of every episode combined
+	OnEpisode  func(EpisodeDownload) // Called when an episode is downloaded or fails in the last pass
+}
+
+// SeasonDir : Directory in outputDir that the episodes of a season of series are
+// downloaded to with the episode template
+func SeasonDir(outputDir string, series *engine.Movie, season int) string {
+	path := EpisodeTemplate().Render(EpisodeVars(series, season, engine.Episode{}), "")
+	return filepath.Join(outputDir, filepath.Dir(path))
+}
+
+// episodeLabel : the season and episode of episode e.g S01E01 or S01E01-E12 for batches
+func episodeLabel(season int, episode engine.Episode) string {
+	label := fmt.Sprintf("S%02dE%02d", season, episode.Number)
+	if episode.LastNumber > episode.Number {
+		label += fmt.Sprintf("-E%02d", episode.LastNumber)
+	}
+	return label
+}
+
+func isPageExt(ext string) bool {
+	for _, pageExt := range pageExts {
+		if ext == pageExt {
+			return true
+		}
+	}
+	return false
+}
+
+// episodes : the episodes of the season, ErrSeasonNotFound when there are none
+func (s *SeasonDownload) episodes() ([]engine.Episode, error) {
+	var episodes []engine.Episode
+	for _, season := range s.Series.Seasons {
+		if season.Number != s.Season {
+			continue
+		}
+		for _, episode := range season.Episodes {
+			if episode.DownloadLink != nil {
+				episodes = append(episodes, episode)
+			}
+		}
+	}
+	if len(episodes) > 0 {
+		return episodes, nil
+	}
+	return nil, fmt.Errorf("%w: %s has no season %d", ErrSeasonNotFound, s.Series.Title, s.Season)
+}
+
+// Run : Download the episodes of the season, returning the download of every
+// episode. The error tells how many episodes could not be downloaded
+func (s *SeasonDownload) Run(ctx context.Context) ([]EpisodeDownload, error) {
+	episodes, err := s.episodes()
+	if err != nil {
+		return nil, err
+	}
+	parallel := s.Parallel
+	if parallel <= 0 {
+		parallel = DefaultParallel
+	}
+	retries := s.Retries
+	if retries == 0 {
+		retries = DefaultRetries
+	} else if retries < 0 {
+		retries = 0
+	}
+	progress := newCombinedProgress(len(episodes), s.OnProgress)
+	template := EpisodeTemplate()
+	limiter := ConfiguredLimiter()
+	space := ConfiguredSpaceGuard()
+	downloads := make([]EpisodeDownload, len(episodes))
+	pending := make([]int, len(episodes))
+	for i, episode := range episodes {
+		downloads[i] = EpisodeDownload{
+			Episode: episode,
+			Label:   episodeLabel(s.Season, episode),
+			Downloader: &Downloader{
+				URL:        episode.DownloadLink.String(),
+				Dir:        s.Dir,
+				Template:   template,
+				Vars:       EpisodeVars(s.Series, s.Season, episode),
+				Name:       s.Series.Title + " " + episodeLabel(s.Season, episode),
+				Source:     s.Series.Source,
+				Chunks:     viper.GetInt("chunks"),
+				Limiter:    limiter,
+				Space:      space,
+				OnProgress: progress.episode(i),
+			},
+		}
+		pending[i] = i
+	}
+
+	for pass := 0; pass <= retries && len(pending) > 0 && ctx.Err() == nil; pass++ {
+		if pass > 0 {
+			log.Infof("Retrying %d episodes which failed", len(pending))
+		}
+		pending = s.downloadAll(ctx, downloads, pending, parallel, pass == retries)
+	}
+	if len(pending) > 0 {
+		return downloads, fmt.Errorf("%d of %d episodes of season %d could not be downloaded", len(pending), len(episodes), s.Season)
+	}
+	return downloads, nil
+}
+
+// downloadAll : download the episodes at the indexes of pending parallel at a
+// time, returning the indexes of those which failed
+func (s *SeasonDownload) downloadAll(ctx context.Context, downloads []EpisodeDownload, pending []int, parallel int, last bool) []int {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []int
+	)
+	indexes := make(chan int)
+	for w := 0; w < parallel && w < len(pending); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				d := &downloads[i]
+				d.Err = d.Downloader.DownloadFileContext(ctx)
+				if d.Err != nil {
+					log.Errorf("%s failed: %v", d.Label, d.Err)
+					mu.Lock()
+					failed = append(failed, i)
+					mu.Unlock()
+				}
+				if s.OnEpisode != nil && (d.Err == nil || last || ctx.Err() != nil) {
+					mu.Lock()
+					s.OnEpisode(*d)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, i := range pending {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	// failed episodes are retried in order
+	sort.Ints(failed)
+	return failed
+}
+
+// combinedProgress : reports the progress of several downloads as one
+type combinedProgress struct {
+	mu         sync.Mutex
+	downloaded []int64
+	totals     []int64
+	onProgress ProgressFunc
+}
+
+func newCombinedProgress(n int, onProgress ProgressFunc) *combinedProgress {
+	return &combinedProgress{downloaded: make([]int64, n), totals: make([]int64, n), onProgress: onProgress}
+}
+
+// episode : the ProgressFunc of the download at index i
+func (p *combinedProgress) episode(i int) ProgressFunc {
+	if p.onProgress == nil {
+		return nil
+	}
+	return func(downloaded, total int64) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.downloaded[i], p.totals[i] = downloaded, total
+		var sumDownloaded, sumTotal int64
+		for j := range p.downloaded {
+			sumDownloaded += p.downloaded[j]
+			sumTotal += p.totals[j]
+		}
+		p.onProgress(sumDownloaded, sumTotal)
+	}
+}
@@ -0,0 +1,122 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// ErrInsufficientSpace : a download would fill the disk it is saved to
+var ErrInsufficientSpace = errors.New("Not enough free space")
+
+// DefaultSpaceMargin : the share of the size of downloads kept spare, as the
+// sizes sites show are rounded
+const DefaultSpaceMargin = 0.05
+
+// ParseSize : The bytes of a size such as "10G", "500M" or "1.5GB" in binary units
+func ParseSize(size string) (int64, error) {
+	size = strings.TrimSpace(size)
+	bytes, ok := parseBytes(size)
+	if !ok {
+		return 0, fmt.Errorf("Invalid size %q, use a size such as 10G or 500M", size)
+	}
+	return bytes, nil
+}
+
+// SpaceGuard : Refuses downloads which would leave less than MinFree bytes free
+// on the disk they are saved to. The space of the downloads in progress is held
+// back for them until they end, so downloads running at the same time (e.g of
+// the queue) do not count the same free space
+type SpaceGuard struct {
+	MinFree int64
+	Margin  float64 // share of the size of a download needed on top of it
+	Warn    bool    // only warn of downloads which do not fit, and start them anyway
+
+	mu       sync.Mutex
+	reserved int64
+	free     func(dir string) (int64, error) // FreeSpace, replaced in tests
+}
+
+// Reserve : Check that size more bytes fit in dir and hold them back until
+// release is called. Downloads of unknown size (0) only need MinFree to be free.
+// When the free space cannot be read, downloads are let through
+func (g *SpaceGuard) Reserve(dir string, size int64) (release func(), err error) {
+	if g == nil {
+		return func() {}, nil
+	}
+	freeSpace := g.free
+	if freeSpace == nil {
+		freeSpace = FreeSpace
+	}
+	free, err := freeSpace(dir)
+	if err != nil {
+		log.Debugf("Could not check the free space of %s: %v", dir, err)
+		return func() {}, nil
+	}
+	needed := size + int64(float64(size)*g.Margin)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if available := free - g.reserved - g.MinFree; needed > available {
+		kept := ""
+		if g.MinFree > 0 {
+			kept = fmt.Sprintf(" and %s kept free", formatSize(g.MinFree))
+		}
+		err = fmt.Errorf("%w in %s: %s needed, %s free%s", ErrInsufficientSpace, dir,
+			formatSize(needed), formatSize(free-g.reserved), kept)
+		if !g.Warn {
+			return nil, err
+		}
+		log.Warn(err)
+	}
+	g.reserved += needed
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.mu.Lock()
+			g.reserved -= needed
+			g.mu.Unlock()
+		})
+	}, nil
+}
+
+// formatSize : bytes as a size, 0 B rather than empty
+func formatSize(bytes int64) string {
+	if bytes <= 0 {
+		return "0 B"
+	}
+	return engine.FormatSize(bytes)
+}
+
+var (
+	configuredSpaceMu sync.Mutex
+	configuredSpace   *SpaceGuard
+	configuredSpaceAt string
+)
+
+// ConfiguredSpaceGuard : The guard shared by every download, keeping min-free
+// free with space-margin percent of every download spare. ignore-space only
+// warns of downloads which do not fit
+func ConfiguredSpaceGuard() *SpaceGuard {
+	minFree, err := ParseSize(viper.GetString("min-free"))
+	if err != nil {
+		minFree = 0
+	}
+	margin := DefaultSpaceMargin
+	if viper.IsSet("space-margin") {
+		margin = viper.GetFloat64("space-margin") / 100
+	}
+	warn := viper.GetBool("ignore-space")
+	configuredSpaceMu.Lock()
+	defer configuredSpaceMu.Unlock()
+	key := fmt.Sprint(minFree, margin, warn)
+	if configuredSpace == nil || configuredSpaceAt != key {
+		configuredSpace, configuredSpaceAt = &SpaceGuard{MinFree: minFree, Margin: margin, Warn: warn}, key
+	}
+	return configuredSpace
+}
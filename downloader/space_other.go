@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package downloader
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// FreeSpace : The free space of dir is not known on this platform
+func FreeSpace(dir string) (int64, error) {
+	return 0, fmt.Errorf("free space is not known on %s", runtime.GOOS)
+}
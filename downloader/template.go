@@ -0,0 +1,188 @@
+package downloader
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/spf13/viper"
+)
+
+// Template : A template of the path a download is saved at in the output directory
+// Placeholders such as {title} are replaced by the details of the download and
+// numbers can be padded with zeros e.g {season:02}. Directories are separated by /
+// and the extension is added to the filename when {ext} is left out
+//
+// {title} {year} {quality} {resolution} {format} {source} {ext}
+// {series} {season} {episode} {episode_title} for the episodes of a series
+type Template string
+
+// Default templates, used unless filename-template and episode-template are set
+const (
+	DefaultTemplate        Template = "{title} ({year}) [{quality}]/{title} ({year}) [{quality}].{ext}"
+	DefaultEpisodeTemplate Template = "{series} ({year}) [{quality}]/Season {season:02}/{series} - S{season:02}E{episode:02} - {episode_title}.{ext}"
+)
+
+// TemplateVars : The details of a download placeholders are replaced with
+type TemplateVars struct {
+	Title        string
+	Year         int
+	Quality      string
+	Resolution   string
+	Format       string
+	Source       string
+	Series       string
+	Season       int
+	Episode      int
+	LastEpisode  int // last episode of a batch, rendered as {episode} e.g 01-E12
+	EpisodeTitle string
+}
+
+var (
+	placeholderRe = regexp.MustCompile(`\{(\w+)(?::(\d+))?\}`)
+	// brackets and separators left around placeholders which were empty
+	emptyGroupRe     = regexp.MustCompile(`\s*(\(\s*\)|\[\s*\]|\{\s*\})`)
+	danglingSepRe    = regexp.MustCompile(`(\s+-)+\s*$|(\s+-)+\s*(\.[^.]*)$`)
+	repeatedSpacesRe = regexp.MustCompile(`\s{2,}`)
+)
+
+// MovieTemplate : The template movies are saved with, filename-template in the config
+func MovieTemplate() Template {
+	if t := viper.GetString("filename-template"); t != "" {
+		return Template(t)
+	}
+	return DefaultTemplate
+}
+
+// EpisodeTemplate : The template episodes of a series are saved with, episode-template in the config
+func EpisodeTemplate() Template {
+	if t := viper.GetString("episode-template"); t != "" {
+		return Template(t)
+	}
+	return DefaultEpisodeTemplate
+}
+
+// MovieVars : The details of movie placeholders are replaced with
+func MovieVars(movie *engine.Movie) TemplateVars {
+	return TemplateVars{
+		Title:      movie.Title,
+		Year:       movie.Year,
+		Quality:    movie.Quality.String(),
+		Resolution: movie.Quality.Resolution,
+		Format:     movie.Quality.Format,
+		Source:     movie.Source,
+		Series:     movie.Title,
+	}
+}
+
+// EpisodeVars : The details of an episode of a season of series placeholders are replaced with
+func EpisodeVars(series *engine.Movie, season int, episode engine.Episode) TemplateVars {
+	vars := MovieVars(series)
+	vars.Title = series.Title + " " + episodeLabel(season, episode)
+	vars.Season = season
+	vars.Episode = episode.Number
+	vars.LastEpisode = episode.LastNumber
+	// titles copied from the labels of links repeat the episode number
+	if _, number := engine.ParseEpisodeLabel(episode.Title); number == 0 {
+		vars.EpisodeTitle = strings.TrimSpace(episode.Title)
+	}
+	return vars
+}
+
+// value : the value of the placeholder name padded to width, and whether it is known
+func (v TemplateVars) value(name string, width int, ext string) (string, bool) {
+	number := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%0*d", width, n)
+	}
+	switch name {
+	case "title":
+		return v.Title, true
+	case "year":
+		return number(v.Year), true
+	case "quality":
+		return v.Quality, true
+	case "resolution":
+		return v.Resolution, true
+	case "format":
+		return v.Format, true
+	case "source":
+		return v.Source, true
+	case "ext":
+		return strings.TrimPrefix(ext, "."), true
+	case "series":
+		return v.Series, true
+	case "season":
+		return number(v.Season), true
+	case "episode":
+		if v.LastEpisode > v.Episode {
+			return number(v.Episode) + "-E" + number(v.LastEpisode), true
+		}
+		return number(v.Episode), true
+	case "episode_title":
+		return v.EpisodeTitle, true
+	}
+	return "", false
+}
+
+// Validate : Check that every placeholder of the template is known
+func (t Template) Validate() error {
+	for _, match := range placeholderRe.FindAllStringSubmatch(string(t), -1) {
+		if _, ok := (TemplateVars{}).value(match[1], 0, ""); !ok {
+			return fmt.Errorf("Unknown placeholder %s in filename template %q", match[0], t)
+		}
+	}
+	if strings.TrimSpace(string(t)) == "" {
+		return fmt.Errorf("Empty filename template")
+	}
+	return nil
+}
+
+// Render : The path of a download in the output directory, with ext the extension
+// of the file e.g ".mp4". Every directory and the filename are sanitized so they
+// are also valid on Windows, and brackets left empty by unknown details are dropped
+func (t Template) Render(vars TemplateVars, ext string) string {
+	var parts []string
+	templateParts := strings.Split(string(t), "/")
+	if !strings.Contains(string(t), "{ext}") {
+		// the file keeps its extension when the template leaves it out
+		templateParts[len(templateParts)-1] += ext
+	}
+	for i, part := range templateParts {
+		part = placeholderRe.ReplaceAllStringFunc(part, func(placeholder string) string {
+			match := placeholderRe.FindStringSubmatch(placeholder)
+			width, _ := strconv.Atoi(match[2])
+			value, ok := vars.value(match[1], width, ext)
+			if !ok {
+				return placeholder
+			}
+			// values are not allowed to add directories
+			return strings.NewReplacer("/", "_", `\`, "_").Replace(value)
+		})
+		part = emptyGroupRe.ReplaceAllString(part, "")
+		part = danglingSepRe.ReplaceAllString(part, "$3")
+		part = repeatedSpacesRe.ReplaceAllString(strings.TrimSpace(part), " ")
+		part = strings.TrimSuffix(part, ".")
+		if part == "" {
+			continue
+		}
+		// the extension of the filename is kept when a long name is truncated
+		partExt := ""
+		if i == len(templateParts)-1 && ext != "" && strings.HasSuffix(part, ext) {
+			partExt = ext
+		}
+		parts = append(parts, SanitizeFilename(strings.TrimSuffix(part, partExt), FilenameOptions{
+			OS:        "windows",
+			MaxLength: defaultMaxFilenameLength - len(partExt),
+		})+partExt)
+	}
+	if len(parts) == 0 {
+		return "untitled" + ext
+	}
+	return path.Join(parts...)
+}
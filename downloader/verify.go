@@ -0,0 +1,77 @@
+package downloader
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrCorrupted : a downloaded file does not match its checksum or size
+var ErrCorrupted = errors.New("Download corrupted")
+
+// Verification : how a downloaded file was checked
+type Verification string
+
+// Verifications of downloads
+const (
+	VerifiedChecksum Verification = "checksum"   // matches the checksum shown by the source
+	VerifiedSize     Verification = "size"       // has the size of the Content-Length
+	Unverified       Verification = "unverified" // neither a checksum nor a size to check
+)
+
+// checksumHash : the hash and expected hex digest of a checksum such as
+// sha256:<hex> or md5:<hex>. A digest without an algorithm is told by its length
+func checksumHash(checksum string) (hash.Hash, string, error) {
+	algorithm, digest := "", strings.ToLower(checksum)
+	if i := strings.Index(digest, ":"); i >= 0 {
+		algorithm, digest = digest[:i], digest[i+1:]
+	}
+	switch {
+	case algorithm == "sha256" || (algorithm == "" && len(digest) == 64):
+		return sha256.New(), digest, nil
+	case algorithm == "md5" || (algorithm == "" && len(digest) == 32):
+		return md5.New(), digest, nil
+	}
+	return nil, "", fmt.Errorf("unsupported checksum %q, use sha256:<hex> or md5:<hex>", checksum)
+}
+
+// Verify : Check the downloaded file against Checksum, or against Size when
+// there is no checksum. Returns ErrCorrupted when it does not match
+func (f *Downloader) Verify() (Verification, error) {
+	file, err := os.Open(f.Path())
+	if err != nil {
+		return Unverified, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return Unverified, err
+	}
+
+	if f.Checksum != "" {
+		h, digest, err := checksumHash(f.Checksum)
+		if err != nil {
+			return Unverified, err
+		}
+		if _, err = io.Copy(h, file); err != nil {
+			return Unverified, err
+		}
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != digest {
+			return VerifiedChecksum, fmt.Errorf("%w: %s has checksum %s, expected %s", ErrCorrupted, f.Name, actual, digest)
+		}
+		return VerifiedChecksum, nil
+	}
+	if f.Size > 0 {
+		if info.Size() != f.Size {
+			return VerifiedSize, fmt.Errorf("%w: %s has %d bytes, expected %d", ErrCorrupted, f.Name, info.Size(), f.Size)
+		}
+		return VerifiedSize, nil
+	}
+	return Unverified, nil
+}
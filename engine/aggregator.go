@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-phie/gophie/logging"
+)
+
+// SearchAll : Searches all engines returned by GetEngines concurrently
+// and merges the results into a single SearchResult, with the most relevant
+// movies first. See ScoreMovie
+// Engines that fail are skipped and reported in the Errors of the result, an
+// error is only returned when all of them fail
+func SearchAll(ctx context.Context, query string) (SearchResult, error) {
+	// the searches of every engine share a trace ID
+	ctx = logging.EnsureTraceID(ctx)
+	q, err := ParseSearch(query)
+	if err != nil {
+		return SearchResult{Query: query}, err
+	}
+	if q.Filtered() {
+		ctx, query = WithQuery(ctx, q), q.Text
+	}
+	engines := GetEngines()
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	// keep results in a stable order regardless of which engine responds first,
+	// movies scoring the same are ranked with those of reliable engines first
+	sort.Strings(names)
+	sort.SliceStable(names, func(i, j int) bool { return linkRotOf(names[i]) < linkRotOf(names[j]) })
+
+	results := make([]SearchResult, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string, e Engine) {
+			defer wg.Done()
+			start := time.Now()
+			text := q.searchText(e)
+			result, err := e.Search(ctx, text)
+			LogOperation(ctx, name, SearchMode, text, start, len(result.Movies), err)
+			errs[i] = err
+			for j := range result.Movies {
+				if result.Movies[j].Source == "" {
+					result.Movies[j].Source = name
+				}
+			}
+			results[i] = result
+		}(i, name, engines[name])
+	}
+	wg.Wait()
+
+	merged := q.Filter(MergeResults(query, results...))
+	merged.Rank(RankByScore)
+	for i, err := range errs {
+		if err != nil {
+			merged.Errors = append(merged.Errors, EngineError{Engine: names[i], Stage: SearchStage, Message: err.Error()})
+		}
+	}
+	for _, err := range errs {
+		if err == nil {
+			return merged, nil
+		}
+	}
+	if len(names) == 0 {
+		return merged, nil
+	}
+	if ctx.Err() != nil {
+		return merged, ctx.Err()
+	}
+	return merged, fmt.Errorf("%w: all %d engines failed", ErrEngineUnavailable, len(names))
+}
+
+// MergeResults : Merge several results into one, dropping movies with the same
+// download link and renumbering the Index of the merged movies. The errors of
+// the results are kept
+func MergeResults(query string, results ...SearchResult) SearchResult {
+	merged := SearchResult{
+		Query:  query,
+		Movies: []Movie{},
+	}
+	seen := map[string]bool{}
+	for _, result := range results {
+		merged.Errors = append(merged.Errors, result.Errors...)
+		for _, movie := range result.Movies {
+			if movie.DownloadLink != nil {
+				link := movie.DownloadLink.String()
+				if seen[link] {
+					continue
+				}
+				seen[link] = true
+			}
+			movie.Index = len(merged.Movies)
+			merged.Movies = append(merged.Movies, movie)
+		}
+	}
+	return merged
+}
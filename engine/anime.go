@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Translation : whether the audio of an anime is the original with subtitles or dubbed
+type Translation string
+
+const (
+	// Subbed : original audio with subtitles
+	Subbed Translation = "Sub"
+	// Dubbed : audio dubbed in English
+	Dubbed Translation = "Dub"
+)
+
+var (
+	// titles of some sites have their words separated by underscores
+	translationRe = regexp.MustCompile(`(?i)(?:^|[^a-z])(dub(?:bed)?|sub(?:bed|s)?)(?:[^a-z]|$)`)
+	// batches are labelled like "Episodes 01-12", "001 ~ 220" or "S01E01-E12"
+	episodeRangeRe = regexp.MustCompile(`(?i)(?:^|[^\d])(\d{1,3})\s*(?:-|~|to)\s*(?:E|Ep\s*)?(\d{1,3})(?:[^\d]|$)`)
+)
+
+// ParseTranslation : The translation marked in a title such as "Naruto (Dub)" or
+// "One Piece - 1000 [Subbed]", empty when the title has none
+func ParseTranslation(title string) Translation {
+	match := translationRe.FindStringSubmatch(title)
+	if match == nil {
+		return ""
+	}
+	if strings.HasPrefix(strings.ToLower(match[1]), "dub") {
+		return Dubbed
+	}
+	return Subbed
+}
+
+// ParseEpisodeRange : The first and last episode of a batch label such as
+// "Episodes 01-12", last is first for a single episode and both are zero when
+// no episode is found
+func ParseEpisodeRange(label string) (first, last int) {
+	if match := episodeRangeRe.FindStringSubmatch(label); match != nil {
+		first, _ = strconv.Atoi(match[1])
+		last, _ = strconv.Atoi(match[2])
+		if last > first {
+			return first, last
+		}
+	}
+	_, first = ParseEpisodeLabel(label)
+	return first, first
+}
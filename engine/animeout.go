@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"path"
@@ -16,6 +17,10 @@ type AnimeOut struct {
 	Props
 }
 
+func init() {
+	RegisterEngine("animeout", func() Engine { return NewAnimeOutEngine() })
+}
+
 // NewAnimeOutEngine : create a new engine for scraping latest anime from chia-anime
 func NewAnimeOutEngine() *AnimeOut {
 	base := "https://animeout.xyz"
@@ -41,6 +46,9 @@ func NewAnimeOutEngine() *AnimeOut {
 	animeOutEngine.Name = "AnimeOut"
 	animeOutEngine.BaseURL = baseURL
 	animeOutEngine.Description = `Search from over 1000's of encoded anime available`
+	animeOutEngine.Features = Capabilities{Series: true, Pagination: true}
+	animeOutEngine.Languages = []string{"ja"}
+	animeOutEngine.Regions = []string{"Japan"}
 	animeOutEngine.SearchURL = searchURL
 	animeOutEngine.ListURL = listURL
 	return &animeOutEngine
@@ -69,7 +77,7 @@ func (engine *AnimeOut) parseSingleMovie(el *colly.HTMLElement, index int) (Movi
 	downloadLink, err := url.Parse(el.Request.AbsoluteURL(el.ChildAttr("a", "href")))
 
 	if err != nil {
-		log.Fatal(err)
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 	movie.DownloadLink = downloadLink
 	return movie, nil
@@ -77,7 +85,11 @@ func (engine *AnimeOut) parseSingleMovie(el *colly.HTMLElement, index int) (Movi
 
 func (engine *AnimeOut) updateDownloadProps(downloadCollector *colly.Collector, movies *[]Movie) {
 	downloadCollector.OnHTML("div.article-content", func(e *colly.HTMLElement) {
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		description := e.ChildText("div.spaceit")
 		episodeMap := map[string]*url.URL{}
 		if description == "" {
@@ -122,23 +134,19 @@ func (engine *AnimeOut) updateDownloadProps(downloadCollector *colly.Collector,
 }
 
 // List : list all the movies on a page
-func (engine *AnimeOut) List(page int) SearchResult {
+func (engine *AnimeOut) List(ctx context.Context, page int) (SearchResult, error) {
 	engine.mode = ListMode
 	result := SearchResult{
 		Query: "List of Recent Uploads - Page " + strconv.Itoa(page),
 	}
 	pageParam := fmt.Sprintf("page/%v", strconv.Itoa(page))
-	engine.ListURL.Path = path.Join(engine.ListURL.Path, pageParam)
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	engine.ListURL.Path = engine.listPath(pageParam)
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, page), err
 }
 
 // Search : Searches fzmovies for a particular query and return an array of movies
-func (engine *AnimeOut) Search(param ...string) SearchResult {
+func (engine *AnimeOut) Search(ctx context.Context, param ...string) (SearchResult, error) {
 	query := param[0]
 	engine.mode = SearchMode
 	result := SearchResult{
@@ -146,11 +154,8 @@ func (engine *AnimeOut) Search(param ...string) SearchResult {
 	}
 	q := engine.SearchURL.Query()
 	q.Set("s", query)
+	setPage(q, "paged", param)
 	engine.SearchURL.RawQuery = q.Encode()
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, searchPage(param)), err
 }
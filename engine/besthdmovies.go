@@ -1,12 +1,15 @@
 package engine
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gocolly/colly/v2"
 	log "github.com/sirupsen/logrus"
@@ -17,6 +20,10 @@ type BestHDEngine struct {
 	Props
 }
 
+func init() {
+	RegisterEngine("besthdmovies", func() Engine { return NewBestHDEngine() })
+}
+
 // NewBestHDEngine : A Movie Engine Constructor for BestHDEngine
 func NewBestHDEngine() *BestHDEngine {
 	base := "https://www.besthdmovies.fit/"
@@ -41,6 +48,9 @@ func NewBestHDEngine() *BestHDEngine {
 	bestEngine.Name = "BestHDMovies"
 	bestEngine.BaseURL = baseURL
 	bestEngine.Description = `BestHDMovies is a site where you can find high quality Hollywood and Bollywood mkv movies`
+	bestEngine.Features = Capabilities{Pagination: true, SearchByYear: true}
+	bestEngine.Languages = []string{"en", "hi"}
+	bestEngine.Regions = []string{"Hollywood", "Bollywood"}
 	bestEngine.SearchURL = searchURL
 	bestEngine.ListURL = listURL
 	return &bestEngine
@@ -65,7 +75,7 @@ func (engine *BestHDEngine) parseSingleMovie(el *colly.HTMLElement, index int) (
 	}
 	cover, err := url.Parse(el.Request.AbsoluteURL(el.ChildAttr("img", "src")))
 	if err != nil {
-		log.Fatal(err)
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 	re := regexp.MustCompile(`\d+`)
 	movieYear := re.FindStringSubmatch(el.ChildText("div.categories"))
@@ -80,7 +90,7 @@ func (engine *BestHDEngine) parseSingleMovie(el *colly.HTMLElement, index int) (
 	downloadLink, err := url.Parse(el.ChildAttr("a", "href"))
 
 	if err != nil {
-		log.Fatal(err)
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 	// download link is current link path + /download
 	downloadLink.Path = path.Join(engine.BaseURL.Path, downloadLink.Path)
@@ -93,7 +103,11 @@ func (engine *BestHDEngine) updateDownloadProps(downloadCollector *colly.Collect
 	//  submissionDetails := make(map[string]string)
 	// Update movie download link if div.post-single-content  on page
 	downloadCollector.OnHTML("div.post-single-content", func(e *colly.HTMLElement) {
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		ptags := e.ChildTexts("p")
 		if ptags[len(ptags)-3] >= ptags[len(ptags)-2] {
 			movie.Description = strings.TrimSpace(ptags[len(ptags)-3])
@@ -116,14 +130,19 @@ func (engine *BestHDEngine) updateDownloadProps(downloadCollector *colly.Collect
 					movie.DownloadLink = downloadlink
 					downloadCollector.Visit(downloadlink.String())
 				} else {
-					log.Fatal(err)
+					log.Error(err)
+					return
 				}
 			}
 		}
 	})
 
 	downloadCollector.OnHTML("div.content-area", func(e *colly.HTMLElement) {
-		movieIndex := getMovieIndexFromCtx(e.Request)
+		movieIndex, err := getMovieIndexFromCtx(e.Request)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		movie := &(*movies)[movieIndex]
 		links := e.ChildAttrs("a", "href")
 		for _, link := range links {
@@ -138,14 +157,19 @@ func (engine *BestHDEngine) updateDownloadProps(downloadCollector *colly.Collect
 					movie.DownloadLink = downloadlink
 					downloadCollector.Visit(downloadlink.String())
 				} else {
-					log.Fatal(err)
+					log.Error(err)
+					return
 				}
 			}
 		}
 	})
 
 	downloadCollector.OnHTML("div.freeDownload", func(e *colly.HTMLElement) {
-		movieIndex := getMovieIndexFromCtx(e.Request)
+		movieIndex, err := getMovieIndexFromCtx(e.Request)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		movie := &(*movies)[movieIndex]
 		if e.ChildAttr("a.link_button", "href") != "" {
 			downloadlink, err := url.Parse(e.ChildAttr("a.link_button", "href"))
@@ -156,14 +180,18 @@ func (engine *BestHDEngine) updateDownloadProps(downloadCollector *colly.Collect
 			zeesubmission := getFormDetails(e)
 			err := downloadCollector.Post(movie.DownloadLink.String(), zeesubmission)
 			if err != nil {
-				log.Fatal(err)
+				log.Error(err)
+				return
 			}
 		}
 	})
 
 	downloadCollector.OnHTML("form[method=post]", func(e *colly.HTMLElement) {
-		movieIndex := getMovieIndexFromCtx(e.Request)
-		var err error
+		movieIndex, err := getMovieIndexFromCtx(e.Request)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		movie := &(*movies)[movieIndex]
 		downloadlink := movie.DownloadLink
 		submissionDetails := getFormDetails(e)
@@ -175,14 +203,19 @@ func (engine *BestHDEngine) updateDownloadProps(downloadCollector *colly.Collect
 			}
 			err = downloadCollector.Post(downloadlink.String(), submissionDetails)
 			if err != nil {
-				log.Fatal(err)
+				log.Error(err)
+				return
 			}
 		}
 	})
 
 	downloadCollector.OnHTML("meta[http-equiv=refresh]", func(e *colly.HTMLElement) {
 		// Retrieve link when on freeload.fun/downloading
-		movieIndex := getMovieIndexFromCtx(e.Request)
+		movieIndex, err := getMovieIndexFromCtx(e.Request)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		movie := &(*movies)[movieIndex]
 		content := e.Attr("content")
 		re := regexp.MustCompile(`url=(.*)`)
@@ -195,7 +228,11 @@ func (engine *BestHDEngine) updateDownloadProps(downloadCollector *colly.Collect
 
 	downloadCollector.OnHTML("div.freeDownload", func(e *colly.HTMLElement) {
 		// Retrieve link when on zeefiles.download/id
-		movieIndex := getMovieIndexFromCtx(e.Request)
+		movieIndex, err := getMovieIndexFromCtx(e.Request)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		movie := &(*movies)[movieIndex]
 		linkButton := e.ChildAttr("a.link_button", "href")
 		if linkButton != "" {
@@ -206,7 +243,8 @@ func (engine *BestHDEngine) updateDownloadProps(downloadCollector *colly.Collect
 			if !strings.Contains(movie.DownloadLink.String(), "download_token") {
 				err := downloadCollector.Post(movie.DownloadLink.String(), submissionDetails)
 				if err != nil {
-					log.Fatal(err)
+					log.Error(err)
+					return
 				}
 			}
 		}
@@ -214,31 +252,39 @@ func (engine *BestHDEngine) updateDownloadProps(downloadCollector *colly.Collect
 
 	downloadCollector.OnHTML("video", func(e *colly.HTMLElement) {
 		downloadlink := e.ChildAttr("source", "src")
-		movieIndex := getMovieIndexFromCtx(e.Request)
+		movieIndex, err := getMovieIndexFromCtx(e.Request)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		movie := &(*movies)[movieIndex]
 		movie.DownloadLink, _ = url.Parse(downloadlink)
 	})
 }
 
+// bestHDResolver : follows the freeload landing pages of BestHDMovies
+var bestHDResolver = &PageResolver{Selectors: []string{`a[href^="https://freeload"]`}}
+
+// ResolveLink : Follow the landing pages of BestHDMovies to the file
+func (engine *BestHDEngine) ResolveLink(ctx context.Context, client *http.Client, link *url.URL) (*url.URL, time.Time, error) {
+	return bestHDResolver.ResolveLink(ctx, client, link)
+}
+
 // List : list all the movies on a page
-func (engine *BestHDEngine) List(page int) SearchResult {
+func (engine *BestHDEngine) List(ctx context.Context, page int) (SearchResult, error) {
 	engine.mode = ListMode
 	result := SearchResult{
 		Query: "List of Recent Uploads - Page " + strconv.Itoa(page),
 	}
 
 	pageParam := fmt.Sprintf("page/%v", strconv.Itoa(page))
-	engine.ListURL.Path = path.Join(engine.ListURL.Path, pageParam)
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	engine.ListURL.Path = engine.listPath(pageParam)
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, page), err
 }
 
 // Search : Searches netnaija for a particular query and return an array of movies
-func (engine *BestHDEngine) Search(param ...string) SearchResult {
+func (engine *BestHDEngine) Search(ctx context.Context, param ...string) (SearchResult, error) {
 	query := param[0]
 	engine.mode = SearchMode
 	result := SearchResult{
@@ -246,11 +292,8 @@ func (engine *BestHDEngine) Search(param ...string) SearchResult {
 	}
 	q := engine.SearchURL.Query()
 	q.Set("s", query)
+	setPage(q, "paged", param)
 	engine.SearchURL.RawQuery = q.Encode()
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, searchPage(param)), err
 }
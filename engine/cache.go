@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+var resultsBucket = []byte("results")
+
+// ResultCache : A persistent store of search results with a time to live
+type ResultCache struct {
+	db  *bolt.DB
+	TTL time.Duration // How long a stored result stays valid
+}
+
+// cacheEntry : JSON structure stored for every cached result
+type cacheEntry struct {
+	StoredAt time.Time
+	Result   SearchResult
+}
+
+// OpenResultCache : Open (or create) a result cache database at path
+func OpenResultCache(path string, ttl time.Duration) (*ResultCache, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(resultsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &ResultCache{db: db, TTL: ttl}, nil
+}
+
+// CacheKey : key under which the result of an engine search or list is stored
+func CacheKey(engine string, mode Mode, query string, page int) string {
+	return strings.Join([]string{
+		strings.ToLower(engine), mode.String(), strings.ToLower(query), strconv.Itoa(page),
+	}, "|")
+}
+
+// Get : Retrieve a result which has not yet expired
+func (c *ResultCache) Get(key string) (SearchResult, bool) {
+	var entry cacheEntry
+	err := c.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(resultsBucket).Get([]byte(key))
+		if v == nil {
+			return nil
+		}
+		return json.Unmarshal(v, &entry)
+	})
+	if err != nil || entry.StoredAt.IsZero() || time.Since(entry.StoredAt) > c.TTL {
+		return SearchResult{}, false
+	}
+	return entry.Result, true
+}
+
+// Put : Store a result under key
+func (c *ResultCache) Put(key string, result SearchResult) error {
+	v, err := json.Marshal(cacheEntry{StoredAt: time.Now(), Result: result})
+	if err != nil {
+		return err
+	}
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(resultsBucket).Put([]byte(key), v)
+	})
+}
+
+// Clear : Remove all stored results
+func (c *ResultCache) Clear() error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(resultsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(resultsBucket)
+		return err
+	})
+}
+
+// Close : Close the underlying database
+func (c *ResultCache) Close() error {
+	return c.db.Close()
+}
+
+type refreshKey struct{}
+
+// WithRefresh : ctx whose searches and lists are scraped from the sites even when
+// cached, bypassing the page cache as well, and stored in the result cache again
+func WithRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshKey{}, true)
+}
+
+// Refreshing : whether the results of ctx are being refreshed
+func Refreshing(ctx context.Context) bool {
+	refresh, _ := ctx.Value(refreshKey{}).(bool)
+	return refresh
+}
+
+// CachedEngine : An Engine which serves results from a ResultCache before scraping
+type CachedEngine struct {
+	Engine
+	name  string
+	cache *ResultCache
+	// OnLookup is called with whether each lookup was served from the cache, e.g to count hits
+	OnLookup func(hit bool)
+}
+
+// NewCachedEngine : Wrap the engine registered as name so its results are cached
+func NewCachedEngine(name string, e Engine, cache *ResultCache) *CachedEngine {
+	return &CachedEngine{Engine: e, name: name, cache: cache}
+}
+
+// MarshalJSON : engines are described by the engine being cached
+func (c *CachedEngine) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Engine)
+}
+
+// Search : Search using the cached result for the query and page if still valid
+func (c *CachedEngine) Search(ctx context.Context, param ...string) (SearchResult, error) {
+	page := 1
+	if len(param) > 1 {
+		if p, err := strconv.Atoi(param[1]); err == nil {
+			page = p
+		}
+	}
+	query := param[0]
+	// engines may narrow the search with the filters of the query
+	if q, ok := QueryFrom(ctx); ok {
+		query = fmt.Sprintf("%s|%s", query, q)
+	}
+	key := CacheKey(c.name, SearchMode, query, page)
+	return c.fetch(ctx, key, func() (SearchResult, error) { return c.Engine.Search(ctx, param...) })
+}
+
+// List : List using the cached result for the page if still valid
+func (c *CachedEngine) List(ctx context.Context, page int) (SearchResult, error) {
+	key := CacheKey(c.name, ListMode, "", page)
+	return c.fetch(ctx, key, func() (SearchResult, error) { return c.Engine.List(ctx, page) })
+}
+
+// Modes : the modes of the engine being cached
+func (c *CachedEngine) Modes() []ScrapeMode {
+	return Modes(c.Engine)
+}
+
+// ListBy : List in mode using the cached result for the page if still valid
+func (c *CachedEngine) ListBy(ctx context.Context, mode ScrapeMode, page int) (SearchResult, error) {
+	if mode == Latest {
+		return c.List(ctx, page)
+	}
+	key := CacheKey(c.name, ListMode, mode.String(), page)
+	return c.fetch(ctx, key, func() (SearchResult, error) { return ListBy(ctx, c.Engine, mode, page) })
+}
+
+func (c *CachedEngine) fetch(ctx context.Context, key string, fn func() (SearchResult, error)) (SearchResult, error) {
+	if !Refreshing(ctx) {
+		result, ok := c.cache.Get(key)
+		if c.OnLookup != nil {
+			c.OnLookup(ok)
+		}
+		if ok {
+			log.Debugf("Serving %s from result cache", key)
+			return result, nil
+		}
+	}
+	result, err := fn()
+	// Only complete results are cached so failures, also those of the pages
+	// and movies of partial results, are retried on the next call
+	if err == nil && len(result.Movies) > 0 && len(result.Errors) == 0 {
+		if cacheErr := c.cache.Put(key, result); cacheErr != nil {
+			log.Errorf("could not cache %s: %v", key, cacheErr)
+		}
+	}
+	return result, err
+}
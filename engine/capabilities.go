@@ -0,0 +1,43 @@
+package engine
+
+import "sort"
+
+// Capabilities : What the source site of an engine supports, so clients can
+// adjust to each engine
+type Capabilities struct {
+	Series       bool // lists the seasons and episodes of series
+	Pagination   bool // searches page by page
+	SearchByYear bool // narrows searches with the year in a query such as "Joker 2019"
+	Magnet       bool // returns magnet links of torrents rather than direct download links
+	NeedsProxy   bool // blocked in some countries, so it may only be reached through a proxy
+	AntiBot      bool // behind an anti-bot service such as Cloudflare answering with challenge pages
+	JavaScript   bool // lists movies with JavaScript, so its pages are rendered in a headless browser
+}
+
+// Capabilities : the capabilities of engines embedding Props
+func (p *Props) Capabilities() Capabilities {
+	return p.Features
+}
+
+// CapabilityReport : What an engine can do, so callers know which modes to ask for
+type CapabilityReport struct {
+	Engine    string
+	Modes     []ScrapeMode
+	Languages []string `json:",omitempty"` // usual languages of the movies, see Props
+	Regions   []string `json:",omitempty"`
+	Capabilities
+}
+
+// ReportCapabilities : The capabilities of the engines by name, ordered by name
+func ReportCapabilities(engines map[string]Engine) []CapabilityReport {
+	reports := make([]CapabilityReport, 0, len(engines))
+	for name, e := range engines {
+		report := CapabilityReport{Engine: name, Modes: Modes(e), Capabilities: e.Capabilities()}
+		if p, ok := e.(interface{ getProps() *Props }); ok {
+			report.Languages, report.Regions = p.getProps().Languages, p.getProps().Regions
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Engine < reports[j].Engine })
+	return reports
+}
@@ -0,0 +1,23 @@
+package engine
+
+import (
+	"regexp"
+	"strings"
+)
+
+// checksumRe : a hash of a file shown on a download page such as "MD5: d41d8c..."
+// or "<b>SHA-256</b> = e3b0c4...", possibly with tags between the label and hash
+var checksumRe = regexp.MustCompile(`(?i)\b(sha-?256|md5)\b(?:\s*(?:hash|checksum|sum))?(?:<[^>]*>|\s|[:=])*\b([a-f0-9]{64}|[a-f0-9]{32})\b`)
+
+// ParseChecksum : The first file hash shown in a page as "sha256:<hex>" or "md5:<hex>",
+// empty when there is none
+func ParseChecksum(page string) string {
+	for _, match := range checksumRe.FindAllStringSubmatch(page, -1) {
+		algorithm := strings.ToLower(strings.ReplaceAll(match[1], "-", ""))
+		hash := strings.ToLower(match[2])
+		if (algorithm == "sha256" && len(hash) == 64) || (algorithm == "md5" && len(hash) == 32) {
+			return algorithm + ":" + hash
+		}
+	}
+	return ""
+}
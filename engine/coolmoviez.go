@@ -1,9 +1,9 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"net/url"
-	"path"
 	"regexp"
 	"strconv"
 	"strings"
@@ -17,6 +17,10 @@ type CoolMoviez struct {
 	Props
 }
 
+func init() {
+	RegisterEngine("coolmoviez", func() Engine { return NewCoolMoviezEngine() })
+}
+
 // NewCoolMoviezEngine : create a new engine for scraping mynewcoolmovies
 func NewCoolMoviezEngine() *CoolMoviez {
 	base := "https://coolmoviez.buzz"
@@ -42,6 +46,8 @@ func NewCoolMoviezEngine() *CoolMoviez {
 	coolMoviesEngine.Name = "CoolMoviez"
 	coolMoviesEngine.BaseURL = baseURL
 	coolMoviesEngine.Description = `Self reported best download site for mobile, tablets and pc`
+	coolMoviesEngine.Languages = []string{"en"}
+	coolMoviesEngine.Regions = []string{"Hollywood"}
 	coolMoviesEngine.SearchURL = searchURL
 	coolMoviesEngine.ListURL = listURL
 	return &coolMoviesEngine
@@ -80,7 +86,7 @@ func (engine *CoolMoviez) parseSingleMovie(el *colly.HTMLElement, index int) (Mo
 	movie.CoverPhotoLink = el.ChildAttr("img", "src")
 
 	if err != nil {
-		log.Fatal(err)
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 
 	movie.DownloadLink = downloadLink
@@ -91,7 +97,11 @@ func (engine *CoolMoviez) updateDownloadProps(downloadCollector *colly.Collector
 
 	downloadCollector.OnHTML("div.M1,div.M2", func(e *colly.HTMLElement) {
 		reArray := []string{"Quality", "Genre", "Description", "Starcast"}
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		for _, reString := range reArray {
 			re := regexp.MustCompile(reString + `:\s+(.*)`)
 			stringsub := re.FindStringSubmatch(e.Text)
@@ -99,7 +109,7 @@ func (engine *CoolMoviez) updateDownloadProps(downloadCollector *colly.Collector
 				value := stringsub[1]
 				switch reString {
 				case "Quality":
-					movie.Quality = value
+					movie.Quality = ParseQuality(value)
 				case "Description":
 					movie.Description = value
 				case "Genre":
@@ -112,7 +122,11 @@ func (engine *CoolMoviez) updateDownloadProps(downloadCollector *colly.Collector
 	})
 
 	downloadCollector.OnHTML("a.fileName", func(e *colly.HTMLElement) {
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		initialLink := e.Attr("href")
 		re := regexp.MustCompile(`Size:\s+(.*)`)
 		stringsub := re.FindStringSubmatch(e.Text)
@@ -131,7 +145,11 @@ func (engine *CoolMoviez) updateDownloadProps(downloadCollector *colly.Collector
 	})
 
 	downloadCollector.OnHTML("a.dwnLink", func(e *colly.HTMLElement) {
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		downloadLink, err := url.Parse(e.Attr("href"))
 		if err == nil {
 			movie.DownloadLink = downloadLink
@@ -140,23 +158,19 @@ func (engine *CoolMoviez) updateDownloadProps(downloadCollector *colly.Collector
 }
 
 // List : list all the movies on a page
-func (engine *CoolMoviez) List(page int) SearchResult {
+func (engine *CoolMoviez) List(ctx context.Context, page int) (SearchResult, error) {
 	engine.mode = ListMode
 	result := SearchResult{
 		Query: "List of Recent Uploads - Page " + strconv.Itoa(page),
 	}
 	pageParam := fmt.Sprintf("%v.html", strconv.Itoa(page))
-	engine.ListURL.Path = path.Join(engine.ListURL.Path, pageParam) + "/"
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	engine.ListURL.Path = engine.listPath(pageParam) + "/"
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, page), err
 }
 
 // Search : Searches fzmovies for a particular query and return an array of movies
-func (engine *CoolMoviez) Search(param ...string) SearchResult {
+func (engine *CoolMoviez) Search(ctx context.Context, param ...string) (SearchResult, error) {
 	query := param[0]
 	engine.mode = SearchMode
 	result := SearchResult{
@@ -166,10 +180,6 @@ func (engine *CoolMoviez) Search(param ...string) SearchResult {
 	q.Set("find", query)
 	q.Set("per_page", "1")
 	engine.SearchURL.RawQuery = q.Encode()
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, searchPage(param)), err
 }
@@ -1,9 +1,22 @@
 package engine
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/go-phie/gophie/transport"
+	"github.com/spf13/viper"
 )
 
 func testResults(t *testing.T, engine Engine) {
@@ -23,9 +36,11 @@ func testResults(t *testing.T, engine Engine) {
 	default:
 		searchTerm = "jumanji"
 	}
-	result = engine.Search(searchTerm)
+	result, err := engine.Search(context.Background(), searchTerm)
 
-	if len(result.Movies) < 1 {
+	if err != nil {
+		t.Errorf("Search on %v failed: %v", engine.String(), err)
+	} else if len(result.Movies) < 1 {
 		t.Errorf("No movies returned from %v", engine.String())
 	} else {
 		for _, movie := range result.Movies {
@@ -54,3 +69,1301 @@ func TestEngines(t *testing.T) {
 		}
 	}
 }
+
+func TestMergeResults(t *testing.T) {
+	link := func(s string) *url.URL {
+		u, _ := url.Parse(s)
+		return u
+	}
+	first := SearchResult{Movies: []Movie{
+		{Index: 0, Title: "Jumanji", Source: "NetNaija", DownloadLink: link("https://a.example/jumanji.mp4")},
+		{Index: 1, Title: "Jumanji 2", Source: "NetNaija", DownloadLink: link("https://a.example/jumanji2.mp4")},
+	}}
+	second := SearchResult{Movies: []Movie{
+		{Index: 0, Title: "Jumanji", Source: "FzMovies", DownloadLink: link("https://a.example/jumanji.mp4")},
+		{Index: 1, Title: "Jumanji (1995)", Source: "FzMovies", DownloadLink: link("https://b.example/jumanji.mp4")},
+	}}
+
+	merged := MergeResults("jumanji", first, second)
+	if merged.Query != "jumanji" {
+		t.Errorf("Expected query jumanji, got %s", merged.Query)
+	}
+	if len(merged.Movies) != 3 {
+		t.Fatalf("Expected 3 movies after merging, got %d", len(merged.Movies))
+	}
+	for i, movie := range merged.Movies {
+		if movie.Index != i {
+			t.Errorf("Expected movie %s to have index %d, got %d", movie.Title, i, movie.Index)
+		}
+	}
+	if merged.Movies[2].Source != "FzMovies" {
+		t.Errorf("Expected last movie to come from FzMovies, got %s", merged.Movies[2].Source)
+	}
+}
+
+// pagedEngine : serves two movies on each of its pages and its first page past the last
+type pagedEngine struct {
+	stubEngine
+	pages    int
+	searched []string
+}
+
+func (e *pagedEngine) Search(ctx context.Context, param ...string) (SearchResult, error) {
+	page := "1"
+	if len(param) > 1 {
+		page = param[1]
+	}
+	e.searched = append(e.searched, page)
+	if n, _ := strconv.Atoi(page); n > e.pages {
+		page = "1"
+	}
+	var movies []Movie
+	for i := 0; i < 2; i++ {
+		link, _ := url.Parse(fmt.Sprintf("https://a.example/%s-%d.mp4", page, i))
+		movies = append(movies, Movie{Index: i, Title: page, DownloadLink: link})
+	}
+	return SearchResult{Query: param[0], Movies: movies}, nil
+}
+
+func TestSearchPages(t *testing.T) {
+	e := &pagedEngine{pages: 3}
+	result, err := SearchPages(context.Background(), e, "jumanji", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Movies) != 4 || result.Movies[3].Index != 3 || result.Movies[3].Title != "2" {
+		t.Errorf("Expected the movies of 2 pages renumbered, got %+v", result.Movies)
+	}
+
+	// all pages until the site repeats its first page
+	e = &pagedEngine{pages: 3}
+	result, _ = SearchPages(context.Background(), e, "jumanji", 0)
+	if len(result.Movies) != 6 || strings.Join(e.searched, ",") != "1,2,3,4" {
+		t.Errorf("Expected 3 pages of movies, searched %v and got %d movies", e.searched, len(result.Movies))
+	}
+	for i, movie := range result.Movies {
+		if movie.Index != i {
+			t.Errorf("Expected movie %d to have index %d, got %d", i, i, movie.Index)
+		}
+	}
+}
+
+func TestTitleMatching(t *testing.T) {
+	result := SearchResult{Movies: []Movie{
+		{Index: 0, Title: "Jumanji: The Next Level (2019) [NetNaija] 1080p WEB-DL"},
+		{Index: 1, Title: "Jumanji (1995)"},
+		{Index: 2, Title: "Joker (2019) x264"},
+		{Index: 3, Title: "jumanji"},
+	}}
+	if got := NormalizeTitle(result.Movies[0].Title); got != "jumanji the next level 2019" {
+		t.Errorf("Unexpected normalized title %q", got)
+	}
+	tests := map[string]int{
+		"Jumanji (1995)":                   1,
+		"jumanji":                          3,
+		"JUMANJI - THE NEXT LEVEL (2019)":  0,
+		"Jumanji the Nxet Level 2019":      0,
+		"joker 2019 [FzMovies] 720p":       2,
+		"Jumanji: The Next Level (2019) .": 0,
+	}
+	for title, index := range tests {
+		movie, err := result.GetMovieByTitle(title)
+		if err != nil || movie.Index != index {
+			t.Errorf("GetMovieByTitle(%q) = %d, %v, expected %d", title, movie.Index, err, index)
+		}
+		if got, _ := result.GetIndexFromTitle(title); got != index {
+			t.Errorf("GetIndexFromTitle(%q) = %d, expected %d", title, got, index)
+		}
+	}
+	if _, err := result.GetMovieByTitle("The Batman"); !errors.Is(err, ErrMovieNotFound) {
+		t.Errorf("Expected unrelated title not to match, got %v", err)
+	}
+
+	var titles []string
+	for _, movie := range result.GetClosestMatches("jumanji", 3) {
+		titles = append(titles, movie.Title)
+	}
+	if strings.Join(titles, "|") != "jumanji|Jumanji (1995)|Jumanji: The Next Level (2019) [NetNaija] 1080p WEB-DL" {
+		t.Errorf("Unexpected closest matches %v", titles)
+	}
+	if len(result.GetClosestMatches("jumanji", 10)) != 4 {
+		t.Errorf("Expected all movies when asking for more than there are")
+	}
+}
+
+// stubEngine : a minimal engine as a third-party package would register it
+type stubEngine struct{}
+
+func (stubEngine) Search(ctx context.Context, param ...string) (SearchResult, error) {
+	return SearchResult{Query: param[0]}, nil
+}
+func (stubEngine) List(ctx context.Context, page int) (SearchResult, error) {
+	return SearchResult{}, nil
+}
+func (stubEngine) Capabilities() Capabilities { return Capabilities{} }
+func (stubEngine) String() string             { return "Stub" }
+
+func TestRegisterEngine(t *testing.T) {
+	RegisterEngine("Stub", func() Engine { return stubEngine{} })
+
+	e, err := GetEngine("stub")
+	if err != nil {
+		t.Fatalf("Registered engine not found: %v", err)
+	}
+	if e.String() != "Stub" {
+		t.Errorf("Expected Stub engine, got %s", e.String())
+	}
+	if _, ok := GetEngines()["stub"]; !ok {
+		t.Errorf("Registered engine missing from GetEngines")
+	}
+	if _, ok := GetEngines()["netnaija"]; !ok {
+		t.Errorf("Built-in engines should be registered on init")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Registering an engine twice should panic")
+		}
+	}()
+	RegisterEngine("stub", func() Engine { return stubEngine{} })
+}
+
+// countingEngine : counts how many times the source site would have been scraped
+type countingEngine struct {
+	stubEngine
+	searches int
+	partial  bool // movies are found with the errors of the pages which failed
+}
+
+func (e *countingEngine) Search(ctx context.Context, param ...string) (SearchResult, error) {
+	e.searches++
+	link, _ := url.Parse("https://a.example/" + param[0] + ".mp4")
+	result := SearchResult{Query: param[0], Movies: []Movie{{Title: param[0], DownloadLink: link}}}
+	if e.partial {
+		result.Errors = []EngineError{{Engine: "Counting", Stage: ParseStage, Message: "2 movies could not be parsed"}}
+	}
+	return result, nil
+}
+
+func TestResultCache(t *testing.T) {
+	cache, err := OpenResultCache(filepath.Join(t.TempDir(), "results.db"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	source := &countingEngine{}
+	e := NewCachedEngine("counting", source, cache)
+	for i := 0; i < 3; i++ {
+		result, err := e.Search(context.Background(), "jumanji")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Movies) != 1 || result.Movies[0].DownloadLink.String() != "https://a.example/jumanji.mp4" {
+			t.Errorf("Unexpected cached result %+v", result.Movies)
+		}
+	}
+	if source.searches != 1 {
+		t.Errorf("Expected 1 search on the source engine, got %d", source.searches)
+	}
+
+	// different pages are cached separately
+	e.Search(context.Background(), "jumanji", "2")
+	if source.searches != 2 {
+		t.Errorf("Expected page 2 to be searched on the source engine")
+	}
+
+	// expired entries are not served
+	cache.TTL = 0
+	e.Search(context.Background(), "jumanji")
+	if source.searches != 3 {
+		t.Errorf("Expected expired result to be searched again")
+	}
+
+	cache.TTL = time.Hour
+	if err = cache.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(CacheKey("counting", SearchMode, "jumanji", 1)); ok {
+		t.Errorf("Cleared cache should not return results")
+	}
+
+	// partial results are fetched again until the site answers in full
+	source.partial = true
+	for i := 0; i < 2; i++ {
+		if result, _ := e.Search(context.Background(), "jumanji"); len(result.Errors) != 1 {
+			t.Errorf("Expected the errors of the partial result, got %+v", result)
+		}
+	}
+	if source.searches != 5 {
+		t.Errorf("Expected partial results not to be cached, searched %d times", source.searches)
+	}
+}
+
+func TestYTS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("query_term") != "jumanji" {
+			fmt.Fprint(w, `{"status":"ok","data":{"movie_count":0}}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":"ok","data":{"movie_count":41,"limit":20,"page_number":2,"movies":[
+			{"id":1,"title":"Jumanji","title_long":"Jumanji (1995)","year":1995,"rating":7,"genres":["Adventure","Family"],
+			 "imdb_code":"tt0113497","torrents":[
+				{"url":"https://yts.example/torrent/720","quality":"720p","type":"bluray","size":"800 MB"},
+				{"url":"https://yts.example/torrent/1080","hash":"ABC123","quality":"1080p","type":"bluray","size":"1.6 GB"}]},
+			{"id":2,"title":"No Torrents","torrents":[]}]}}`)
+	}))
+	defer server.Close()
+
+	yts := NewYtsEngine()
+	yts.SearchURL, _ = url.Parse(server.URL)
+	result, err := yts.Search(context.Background(), "jumanji", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Page != 2 || result.TotalPages != 3 || !result.HasNext || result.NextCursor != "3" {
+		t.Errorf("Expected page 2 of 3, got page %d of %d, next %t %q", result.Page, result.TotalPages, result.HasNext, result.NextCursor)
+	}
+	if len(result.Movies) != 1 {
+		t.Fatalf("Expected movies without torrents to be skipped, got %d movies", len(result.Movies))
+	}
+	movie := result.Movies[0]
+	if movie.DownloadLink.String() != "https://yts.example/torrent/1080" || movie.Quality.String() != "1080p BluRay" {
+		t.Errorf("Expected the 1080p torrent, got %s (%s)", movie.DownloadLink, movie.Quality)
+	}
+	if !strings.HasPrefix(movie.MagnetLink, "magnet:?xt=urn:btih:") {
+		t.Errorf("Expected a magnet link, got %q", movie.MagnetLink)
+	}
+	if movie.Genres != "Adventure,Family" || movie.ImdbLink != "https://www.imdb.com/title/tt0113497" {
+		t.Errorf("Unexpected metadata %+v", movie)
+	}
+
+	result, err = yts.Search(context.Background(), "nothing")
+	if err != nil || len(result.Movies) != 0 || result.HasNext {
+		t.Errorf("Expected no movies and no error, got %d movies and %v", len(result.Movies), err)
+	}
+}
+
+func TestScrapeModes(t *testing.T) {
+	for name, expected := range map[string]ScrapeMode{"": Latest, "Trending": Trending, "top-rated": TopRated, "toprated": TopRated} {
+		if mode, err := ParseScrapeMode(name); err != nil || mode != expected {
+			t.Errorf("ParseScrapeMode(%q) = %v, %v, expected %v", name, mode, err, expected)
+		}
+	}
+	if _, err := ParseScrapeMode("newest"); err == nil {
+		t.Error("Expected an invalid mode to be rejected")
+	}
+
+	var sortBy []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sortBy = append(sortBy, r.URL.Query().Get("sort_by"))
+		fmt.Fprint(w, `{"status":"ok","data":{"movies":[
+			{"id":1,"title":"Jumanji","year":1995,"torrents":[{"url":"https://yts.example/torrent/1080","quality":"1080p"}]}]}}`)
+	}))
+	defer server.Close()
+	cache, err := OpenResultCache(filepath.Join(t.TempDir(), "results.db"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+	yts := NewYtsEngine()
+	yts.ListURL, _ = url.Parse(server.URL)
+	cached := NewCachedEngine("yts", yts, cache)
+	for _, mode := range []ScrapeMode{Trending, TopRated, TopRated, Latest} {
+		result, err := ListBy(context.Background(), cached, mode, 1)
+		if err != nil || len(result.Movies) != 1 {
+			t.Fatalf("Expected a movie listed in %s, got %+v %v", mode, result.Movies, err)
+		}
+	}
+	// the second top rated list is served from the cache
+	if strings.Join(sortBy, ",") != "peers,rating,date_added" {
+		t.Errorf("Unexpected lists requested %v", sortBy)
+	}
+
+	// engines without modes only list the latest movies
+	if _, err := ListBy(context.Background(), stubEngine{}, Popular, 1); !errors.Is(err, ErrUnsupportedMode) {
+		t.Errorf("Expected ErrUnsupportedMode, got %v", err)
+	}
+	reports := ReportCapabilities(map[string]Engine{"yts": cached, "stub": stubEngine{}})
+	if len(reports) != 2 || reports[0].Engine != "stub" || len(reports[0].Modes) != 1 || len(reports[1].Modes) != 4 {
+		t.Errorf("Unexpected capabilities %+v", reports)
+	}
+	if !reports[1].Magnet || !reports[1].Pagination || reports[1].Series {
+		t.Errorf("Expected the capabilities of YTS through the cache, got %+v", reports[1])
+	}
+	b, _ := json.Marshal(reports[0])
+	expected := `{"Engine":"stub","Modes":["latest"],"Series":false,"Pagination":false,"SearchByYear":false,"Magnet":false,"NeedsProxy":false,"AntiBot":false,"JavaScript":false}`
+	if string(b) != expected {
+		t.Errorf("Unexpected capability report %s", b)
+	}
+	// engines describe their capabilities with their properties
+	b, _ = json.Marshal(NewX1337Engine())
+	if !strings.Contains(string(b), `"Capabilities":{"Series":false,"Pagination":true,"SearchByYear":true,"Magnet":true,"NeedsProxy":true,"AntiBot":true,"JavaScript":false}`) {
+		t.Errorf("Expected the capabilities in the description of the engine, got %s", b)
+	}
+}
+
+func TestX1337(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/category-search/jumanji/Movies/1/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><table class="table-list"><tbody><tr>
+			<td class="coll-1 name"><a href="/sub/42/0/" class="icon"></a><a href="/torrent/1/jumanji-1995/">Jumanji (1995) 1080p BluRay</a></td>
+			<td class="coll-date">Oct. 1st '20</td>
+			<td class="coll-4 size">1.6 GB<span class="seeds">12</span></td>
+		</tr></tbody></table>
+		<div class="pagination"><ul><li class="active"><a href="/category-search/jumanji/Movies/1/">1</a></li>
+			<li><a href="/category-search/jumanji/Movies/2/">2</a></li><li class="last"><a href="/category-search/jumanji/Movies/2/">Last</a></li></ul></div>
+		</body></html>`)
+	})
+	mux.HandleFunc("/torrent/1/jumanji-1995/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><a href="magnet:?xt=urn:btih:abc">Magnet Download</a></body></html>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	x1337 := NewX1337Engine()
+	x1337.SearchURL, _ = url.Parse(server.URL)
+	result, err := x1337.Search(context.Background(), "jumanji")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Movies) != 1 {
+		t.Fatalf("Expected 1 movie, got %d", len(result.Movies))
+	}
+	movie := result.Movies[0]
+	if movie.Title != "Jumanji (1995) 1080p BluRay" || movie.Year != 1995 || movie.Size != "1.6 GB" {
+		t.Errorf("Unexpected movie %+v", movie)
+	}
+	if movie.MagnetLink != "magnet:?xt=urn:btih:abc" {
+		t.Errorf("Expected magnet link from the torrent page, got %q", movie.MagnetLink)
+	}
+	if result.Page != 1 || result.TotalPages != 2 || !result.HasNext || result.NextCursor != "2" {
+		t.Errorf("Expected the pages linked by the site, got page %d of %d, next %t %q",
+			result.Page, result.TotalPages, result.HasNext, result.NextCursor)
+	}
+
+	// movies are streamed complete, as soon as their torrent page is scraped
+	var streamed []Movie
+	_, err = StreamSearch(context.Background(), x1337, func(m Movie) { streamed = append(streamed, m) }, "jumanji")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streamed) != 1 || streamed[0].MagnetLink != "magnet:?xt=urn:btih:abc" {
+		t.Errorf("Unexpected streamed movies %+v", streamed)
+	}
+}
+
+// failingEngine : finds movies pages before failing, with them when partial
+type failingEngine struct {
+	stubEngine
+	pages   int
+	partial bool
+}
+
+func (e *failingEngine) Search(ctx context.Context, param ...string) (SearchResult, error) {
+	link, _ := url.Parse("https://a.example/" + strings.Join(param, "-") + ".mp4")
+	result := SearchResult{Query: param[0], Movies: []Movie{{Title: param[0], DownloadLink: link}}}
+	if page := searchPage(param); page > e.pages {
+		if !e.partial {
+			result.Movies = nil
+		}
+		return result, fmt.Errorf("%w: timed out", ErrEngineUnavailable)
+	}
+	return result, nil
+}
+
+func TestPartialResults(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/category-search/jumanji/Movies/1/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><table class="table-list"><tbody>
+			<tr><td class="coll-1 name"><a class="icon"></a><a href="/torrent/1/jumanji-1995/">Jumanji (1995)</a></td></tr>
+			<tr><td class="coll-1 name"><a class="icon"></a>Jumanji (2017)</td></tr>
+			<tr><td class="coll-1 name"><a class="icon"></a><a href="/torrent/3/jumanji-2019/">Jumanji (2019)</a></td></tr>
+		</tbody></table></body></html>`)
+	})
+	mux.HandleFunc("/torrent/1/jumanji-1995/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><a href="magnet:?xt=urn:btih:abc">Magnet Download</a></body></html>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	x1337 := NewX1337Engine()
+	x1337.SearchURL, _ = url.Parse(server.URL)
+	result, err := x1337.Search(context.Background(), "jumanji")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Movies) != 2 {
+		t.Errorf("Expected the movies which could be parsed, got %+v", result.Movies)
+	}
+	if len(result.Errors) != 2 || result.Errors[0].Stage != ParseStage || result.Errors[1].Stage != DownloadStage ||
+		result.Errors[0].Engine != "1337x" {
+		t.Errorf("Expected the movie without a link and the missing torrent page reported, got %+v", result.Errors)
+	}
+
+	// movies found before a search failed are returned with the failure
+	result, err = Search(context.Background(), &failingEngine{partial: true}, "jumanji")
+	if err != nil || len(result.Movies) != 1 || len(result.Errors) != 1 || result.Errors[0].Stage != SearchStage ||
+		result.Errors[0].Engine != "stub" {
+		t.Errorf("Expected a partial result, got %+v %v", result, err)
+	}
+	if _, err = Search(context.Background(), &failingEngine{}, "jumanji"); !errors.Is(err, ErrEngineUnavailable) {
+		t.Errorf("Expected the search without movies to fail, got %v", err)
+	}
+	result, err = SearchPages(context.Background(), &failingEngine{pages: 1}, "jumanji", 3)
+	if err != nil || len(result.Movies) != 1 || len(result.Errors) != 1 || !strings.HasPrefix(result.Errors[0].Message, "page 2") ||
+		result.Errors[0].Engine != "stub" {
+		t.Errorf("Expected the movies of the first page with the failure of the second, got %+v %v", result, err)
+	}
+	merged := MergeResults("jumanji", result, SearchResult{Errors: []EngineError{{Engine: "Other", Stage: SearchStage}}})
+	if len(merged.Errors) != 2 {
+		t.Errorf("Expected the errors of the results merged, got %+v", merged.Errors)
+	}
+}
+
+func TestO2TvSeries(t *testing.T) {
+	pages := map[string]string{
+		"/search": `<div class="data_list">
+			<div class="data"><img src="/img/devs.jpg"><a href="/Devs/index.html">Devs (2020)</a></div>
+		</div>`,
+		"/Devs/index.html": `<div class="data_list">
+			<div class="data"><a href="/Devs/Season-01/index.html">Season 01</a></div>
+			<div class="data"><a href="/Devs/Season-02/index.html">Season 02</a></div>
+		</div>`,
+		"/Devs/Season-01/index.html": `<div class="data_list">
+			<div class="data"><a href="/Devs/Season-01/Episode-02/index.html">Episode 02</a></div>
+			<div class="data"><a href="/Devs/Season-01/Episode-01/index.html">Episode 01 - Pilot</a></div>
+		</div>`,
+		"/Devs/Season-01/Episode-01/index.html": `<div class="data_list">
+			<div class="data"><a href="/dl/devs-s01e01.mp4">Devs - S01E01 (O2TvSeries.Com).mp4</a> (180.5 MB)</div>
+			<div class="data"><a href="/dl/devs-s01e01-hd.mp4">Devs - S01E01 HD (O2TvSeries.Com).mp4</a> (400 MB)</div>
+		</div>`,
+		"/Devs/Season-01/Episode-02/index.html": `<div class="data_list">
+			<div class="data"><a href="/dl/devs-s01e02.mkv">Devs - S01E02 (O2TvSeries.Com).mkv</a></div>
+		</div>`,
+		"/Devs/Season-02/index.html": `<div class="data_list">
+			<div class="data"><a href="/Devs/Season-02/Episode-01/index.html">Episode 01</a></div>
+		</div>`,
+		"/Devs/Season-02/Episode-01/index.html": `<div class="data_list">
+			<div class="data"><a href="/dl/devs-s02e01.mp4">Devs - S02E01 (O2TvSeries.Com).mp4</a> (1.2 GB)</div>
+		</div>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>"+page+"</body></html>")
+	}))
+	defer server.Close()
+
+	o2 := NewO2TvSeriesEngine()
+	o2.useBaseURL(&url.URL{Scheme: "http", Host: server.Listener.Addr().String(), Path: "/"})
+	result, err := o2.Search(context.Background(), "devs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Movies) != 1 {
+		t.Fatalf("Expected 1 series, got %+v", result.Movies)
+	}
+	series := result.Movies[0]
+	if series.Title != "Devs (2020)" || series.Year != 2020 || !series.IsSeries || series.Source != "O2TvSeries" {
+		t.Errorf("Unexpected series %+v", series)
+	}
+	var episodes []string
+	for _, season := range series.Seasons {
+		for _, e := range season.Episodes {
+			episodes = append(episodes, season.Label(e)+" "+strings.TrimPrefix(e.DownloadLink.String(), server.URL))
+		}
+	}
+	expected := []string{
+		"S01E01 - Pilot (180.5 MB) /dl/devs-s01e01.mp4",
+		"S01E02 /dl/devs-s01e02.mkv",
+		"S02E01 (1.2 GB) /dl/devs-s02e01.mp4",
+	}
+	if strings.Join(episodes, "|") != strings.Join(expected, "|") {
+		t.Errorf("Unexpected episodes\n%s\nexpected\n%s", strings.Join(episodes, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestNkiriAnime(t *testing.T) {
+	button := func(label, link string) string {
+		return `<section class="elementor-section"><div class="elementor-button-wrapper"><a href="` + link +
+			`"><span class="elementor-button-text">` + label + `</span></a></div></section>`
+	}
+	pages := map[string]string{
+		"/naruto-dub/": `<div class="elementor-section-wrap">` +
+			button("Download Episodes 01-12", "/dl/naruto-01-12.zip") +
+			button("Download Episode 13", "/dl/naruto-13.mkv") +
+			button("Download Episode", "/dl/naruto-14.mkv") +
+			`</div>`,
+	}
+	var category string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Path == "/" {
+			category = r.URL.Query().Get("category_name")
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>"+page+"</body></html>")
+	}))
+	defer server.Close()
+	// nkiri links to the pages of movies with absolute links
+	pages["/"] = `<div class="site-content">
+		<article><h2>Naruto Shippuden (Dub) | Download Anime</h2><a href="` + server.URL + `/naruto-dub/"><img src="/naruto.jpg"></a></article>
+	</div>`
+
+	anime := NewNkiriAnime()
+	anime.useBaseURL(&url.URL{Scheme: "http", Host: server.Listener.Addr().String(), Path: "/"})
+	result, err := anime.Search(context.Background(), "naruto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if category != "anime" {
+		t.Errorf("Expected the anime category to be searched, got %q", category)
+	}
+	if len(result.Movies) != 1 {
+		t.Fatalf("Expected 1 anime, got %+v", result.Movies)
+	}
+	series := result.Movies[0]
+	if series.Translation != Dubbed || !series.IsSeries || series.Source != "NkiriAnime" {
+		t.Errorf("Unexpected anime %+v", series)
+	}
+	var episodes []string
+	for _, season := range series.Seasons {
+		for _, e := range season.Episodes {
+			episodes = append(episodes, season.Label(e)+" "+strings.TrimPrefix(e.DownloadLink.String(), server.URL))
+		}
+	}
+	expected := []string{
+		"S01E01-E12 /dl/naruto-01-12.zip",
+		"S01E13 /dl/naruto-13.mkv",
+		"S01E14 /dl/naruto-14.mkv",
+	}
+	if strings.Join(episodes, "|") != strings.Join(expected, "|") {
+		t.Errorf("Unexpected episodes\n%s\nexpected\n%s", strings.Join(episodes, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestParseTranslation(t *testing.T) {
+	cases := map[string]Translation{
+		"Naruto Shippuden (Dub)":             Dubbed,
+		"One_Piece_Episode_1000_(Subbed)":    Subbed,
+		"[AnimeOut] Bleach [English Dubbed]": Dubbed,
+		"Jujutsu Kaisen - 05 [Eng Subs]":     Subbed,
+		"Attack on Titan [720p]":             "",
+		"Subtitled Submarine (2019)":         "",
+	}
+	for title, expected := range cases {
+		if got := ParseTranslation(title); got != expected {
+			t.Errorf("ParseTranslation(%q) = %q, expected %q", title, got, expected)
+		}
+	}
+}
+
+func TestParseEpisodeRange(t *testing.T) {
+	cases := map[string][2]int{
+		"Download Episodes 01-12":     {1, 12},
+		"[AnimeOut] Naruto 001 ~ 220": {1, 220},
+		"S01E01-E12":                  {1, 12},
+		"Download Episode 5":          {5, 5},
+		"Dragon Ball (1986-1989)":     {0, 0},
+		"Download Episode":            {0, 0},
+	}
+	for label, expected := range cases {
+		if first, last := ParseEpisodeRange(label); first != expected[0] || last != expected[1] {
+			t.Errorf("ParseEpisodeRange(%q) = %d, %d, expected %v", label, first, last, expected)
+		}
+	}
+}
+
+func TestStreamSearchAll(t *testing.T) {
+	engines := map[string]Engine{"one": &countingEngine{}, "two": &countingEngine{}, "stub": stubEngine{}}
+	var streamed []Movie
+	err := StreamSearchAll(context.Background(), engines, func(m Movie) { streamed = append(streamed, m) }, "jumanji")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// both counting engines return the same link
+	if len(streamed) != 1 || streamed[0].Index != 0 {
+		t.Errorf("Expected 1 deduplicated movie, got %+v", streamed)
+	}
+}
+
+func TestParseEpisodeLabel(t *testing.T) {
+	tests := []struct {
+		label           string
+		season, episode int
+	}{
+		{"Devs S01E05", 1, 5},
+		{"Season 2 Episode 10", 2, 10},
+		{"Episode 3", 0, 3},
+		{"Attack on Titan - 07 [720p]", 0, 7},
+		{"4", 0, 4},
+		{"Trailer", 0, 0},
+	}
+	for _, tt := range tests {
+		season, episode := ParseEpisodeLabel(tt.label)
+		if season != tt.season || episode != tt.episode {
+			t.Errorf("ParseEpisodeLabel(%q) = %d, %d; want %d, %d", tt.label, season, episode, tt.season, tt.episode)
+		}
+	}
+}
+
+func TestSeasons(t *testing.T) {
+	link := func(s string) *url.URL {
+		u, _ := url.Parse(s)
+		return u
+	}
+	movie := Movie{Title: "Devs Season 2", SDownloadLink: map[string]*url.URL{
+		"Episode 2":   link("https://a.example/s2e2.mp4"),
+		"Episode 1":   link("https://a.example/s2e1.mp4"),
+		"S01E08 Gone": link("https://a.example/s1e8.mp4"),
+	}}
+	movie.buildSeasons()
+	if len(movie.Seasons) != 2 || movie.Seasons[0].Number != 1 || movie.Seasons[1].Number != 2 {
+		t.Fatalf("Expected seasons 1 and 2, got %+v", movie.Seasons)
+	}
+	second := movie.Seasons[1]
+	if len(second.Episodes) != 2 || second.Episodes[0].Number != 1 || second.Episodes[1].Number != 2 {
+		t.Errorf("Expected episodes of season 2 in order, got %+v", second.Episodes)
+	}
+	if label := second.Label(second.Episodes[0]); label != "S02E01 - Episode 1" {
+		t.Errorf("Unexpected episode label %s", label)
+	}
+
+	movie.DownloadLink = link("https://a.example/devs")
+	b, err := json.Marshal(&movie)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Movie
+	if err = json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.Seasons[0].Episodes[0].DownloadLink.String(); got != "https://a.example/s1e8.mp4" {
+		t.Errorf("Episode link not preserved in JSON, got %s", got)
+	}
+}
+
+func TestParseQuality(t *testing.T) {
+	tests := []struct {
+		title   string
+		quality Quality
+	}{
+		{"Joker (2019) 1080p WEB-DL x264", Quality{Resolution: "1080p", Format: "WEB-DL"}},
+		{"Tenet.2020.2160p.BluRay.HEVC", Quality{Resolution: "2160p", Format: "BluRay"}},
+		{"Dune 2021 4K WEBRip", Quality{Resolution: "2160p", Format: "WEBRip"}},
+		{"Morbius (2022) HDCAM", Quality{Format: "CAM"}},
+		{"Jumanji 480p", Quality{Resolution: "480p"}},
+		{"HD", Quality{Raw: "HD"}},
+	}
+	for _, test := range tests {
+		if q := ParseQuality(test.title); q != test.quality {
+			t.Errorf("ParseQuality(%q) = %+v, expected %+v", test.title, q, test.quality)
+		}
+	}
+
+	result := SearchResult{Movies: []Movie{
+		{Title: "A", Quality: ParseQuality("720p BluRay")},
+		{Title: "B", Quality: ParseQuality("1080p WEB-DL")},
+		{Title: "C", Quality: ParseQuality("1080p BluRay")},
+	}}
+	if filtered := result.FilterByQuality("1080p"); len(filtered.Titles()) != 2 || filtered.Titles()[0] != "B" {
+		t.Errorf("Expected the 1080p movies, got %v", filtered.Titles())
+	}
+	if filtered := result.FilterByQuality("bluray"); len(filtered.Titles()) != 2 || filtered.Titles()[1] != "C" {
+		t.Errorf("Expected the BluRay movies, got %v", filtered.Titles())
+	}
+	if filtered := result.FilterByQuality("1080p bluray"); len(filtered.Titles()) != 1 || filtered.Titles()[0] != "C" {
+		t.Errorf("Expected the 1080p BluRay movie, got %v", filtered.Titles())
+	}
+
+	b, err := json.Marshal(result.Movies[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var movie Movie
+	if err := json.Unmarshal(b, &movie); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"Quality":"1080p WEB-DL"`) || movie.Quality != result.Movies[1].Quality {
+		t.Errorf("Expected quality to round trip as a string, got %s", b)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size  string
+		bytes int64
+	}{
+		{"(277.36MB)", 290833039},
+		{"1.2 GB", 1288490188},
+		{"734MB", 734 << 20},
+		{"1,024 KB", 1 << 20},
+		{"1,5 GiB", 3 << 29},
+		{"512 bytes", 512},
+		{"", 0},
+		{"Unknown", 0},
+	}
+	for _, test := range tests {
+		if bytes := ParseSize(test.size); bytes != test.bytes {
+			t.Errorf("ParseSize(%q) = %d, expected %d", test.size, bytes, test.bytes)
+		}
+	}
+	for bytes, size := range map[int64]string{0: "", 512: "512 B", 1288490188: "1.2 GB", 734 << 20: "734.0 MB"} {
+		if FormatSize(bytes) != size {
+			t.Errorf("FormatSize(%d) = %q, expected %q", bytes, FormatSize(bytes), size)
+		}
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	sha := "3f786850e387550fdab836ed7e6dc881de23001b8c5f8eb0b9a4f4ab7a1f6c3e"
+	tests := map[string]string{
+		"<p>SHA256: " + sha + "</p>":                       "sha256:" + sha,
+		"<b>SHA-256</b> = " + strings.ToUpper(sha):         "sha256:" + sha,
+		"MD5 checksum: D41D8CD98F00B204E9800998ECF8427E":   "md5:d41d8cd98f00b204e9800998ecf8427e",
+		"Size: 700MB, id d41d8cd98f00b204e9800998ecf8427e": "",
+		"md5: " + sha: "",
+	}
+	for page, checksum := range tests {
+		if got := ParseChecksum(page); got != checksum {
+			t.Errorf("ParseChecksum(%q) = %q, expected %q", page, got, checksum)
+		}
+	}
+}
+
+func TestSortAndFilter(t *testing.T) {
+	result := SearchResult{Movies: []Movie{
+		{Title: "A", Year: 2019, Size: "(700MB)", Source: "NetNaija"},
+		{Title: "B", Year: 0, Size: "", Source: "FzMovies", IsSeries: true},
+		{Title: "C", Year: 2021, Size: "1.2 GB", Source: "FzMovies"},
+		{Title: "D", Year: 2015, Size: "350 MB", Source: "TvSeries", IsSeries: true},
+	}}
+	for i := range result.Movies {
+		result.Movies[i].SizeBytes = ParseSize(result.Movies[i].Size)
+	}
+	titles := func(r SearchResult) string {
+		return strings.Join(r.Titles(), "")
+	}
+
+	result.SortByYear(false)
+	if got := titles(result); got != "DACB" {
+		t.Errorf("Expected oldest first with unknown years last, got %s", got)
+	}
+	result.SortBySize(true)
+	if got := titles(result); got != "CADB" {
+		t.Errorf("Expected biggest first with unknown sizes last, got %s", got)
+	}
+	if got := titles(result.FilterByYearRange(2016, 0)); got != "CA" {
+		t.Errorf("Expected movies from 2016, got %s", got)
+	}
+	if got := titles(result.FilterByYearRange(0, 2019)); got != "AD" {
+		t.Errorf("Expected movies up to 2019, got %s", got)
+	}
+	if got := titles(result.FilterBySize(ParseSize("500MB"), ParseSize("1GB"))); got != "A" {
+		t.Errorf("Expected movies from 500MB to 1GB, got %s", got)
+	}
+	if got := titles(result.FilterBySource("fzmovies", "TvSeries")); got != "CDB" {
+		t.Errorf("Expected movies from FzMovies and TvSeries, got %s", got)
+	}
+	if got := titles(result.FilterSeriesOnly()); got != "DB" {
+		t.Errorf("Expected series, got %s", got)
+	}
+}
+
+func TestRank(t *testing.T) {
+	linkState.loaded = false
+	defer func() { linkState.loaded = false }()
+	RecordLinkChecks("Rotten", 10, 10)
+
+	year := time.Now().Year()
+	result := SearchResult{Query: "the batman", Movies: []Movie{
+		{Title: "Batman Begins", Year: year - 15, Quality: Quality{Resolution: "1080p"}, Source: "Up"},
+		{Title: "The Batman CAM", Year: year, Quality: Quality{Format: "CAM"}, Source: "Up"},
+		{Title: "The Batman", Year: year, Quality: Quality{Resolution: "2160p"}, Source: "Rotten"},
+		{Title: "The Batman", Year: year, Quality: Quality{Resolution: "2160p"}, Source: "Up"},
+		{Title: "Superman", Source: "Up"},
+	}}
+	describe := func(r SearchResult) string {
+		var described []string
+		for _, m := range r.Movies {
+			described = append(described, m.Title+"@"+m.Source)
+		}
+		return strings.Join(described, ", ")
+	}
+
+	result.Rank(RankByScore)
+	expected := "The Batman@Up, The Batman@Rotten, The Batman CAM@Up, Batman Begins@Up, Superman@Up"
+	if got := describe(result); got != expected {
+		t.Errorf("Expected ranking by score %s, got %s", expected, got)
+	}
+	for i, m := range result.Movies {
+		if m.Score <= 0 || m.Score > 1 || (i > 0 && m.Score > result.Movies[i-1].Score) {
+			t.Errorf("Expected descending scores from 0 to 1, got %v for %s", m.Score, m.Title)
+		}
+	}
+	result.Rank(RankByQuality)
+	if got := result.Movies[0].Title + ", " + result.Movies[2].Title; got != "The Batman, Batman Begins" {
+		t.Errorf("Expected the highest resolutions first, got %s", describe(result))
+	}
+	result.Rank(RankByYear)
+	if got := result.Movies[3].Title + ", " + result.Movies[4].Title; got != "Batman Begins, Superman" {
+		t.Errorf("Expected the oldest and unknown years last, got %s", describe(result))
+	}
+
+	if _, err := ParseRankCriterion("size"); err == nil {
+		t.Error("Expected an unknown criterion to be invalid")
+	}
+	if criterion, err := ParseRankCriterion(" Quality "); err != nil || criterion != RankByQuality {
+		t.Errorf("Expected the quality criterion, got %v %v", criterion, err)
+	}
+}
+
+func TestRateLimitConfig(t *testing.T) {
+	viper.Set("http", map[string]interface{}{
+		"rate-limit": map[string]interface{}{"requests-per-second": 2, "random-delay": "1s"},
+		"engines": map[string]interface{}{
+			"netnaija": map[string]interface{}{"rate-limit": map[string]interface{}{"parallelism": 1}},
+		},
+	})
+	defer viper.Set("http", nil)
+
+	config, err := clientConfig("FzMovies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := limitRule(config.RateLimit)
+	if rule == nil || rule.Delay != 500*time.Millisecond || rule.RandomDelay != time.Second {
+		t.Errorf("Unexpected limit rule %+v", rule)
+	}
+
+	// engine limits replace the global ones
+	config, err = clientConfig("NetNaija")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule = limitRule(config.RateLimit)
+	if rule == nil || rule.Parallelism != 1 || rule.Delay != 0 {
+		t.Errorf("Unexpected engine limit rule %+v", rule)
+	}
+
+	if limitRule(transport.RateLimit{}) != nil {
+		t.Errorf("Expected no limit rule without limits")
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/get-only":
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}
+	}))
+	defer server.Close()
+
+	engines := map[string]Engine{}
+	for _, path := range []string{"up", "down", "get-only"} {
+		yts := NewYtsEngine()
+		yts.BaseURL, _ = url.Parse(server.URL + "/" + path)
+		engines[path] = yts
+	}
+	engines["stub"] = stubEngine{}
+
+	up := map[string]bool{}
+	for _, status := range CheckHealth(context.Background(), engines, time.Second) {
+		up[status.Engine] = status.Up
+		if !status.Up && status.Error == "" {
+			t.Errorf("Expected the error of %s to be reported", status.Engine)
+		}
+	}
+	expected := map[string]bool{"up": true, "down": false, "get-only": true, "stub": false}
+	for name, isUp := range expected {
+		if up[name] != isUp {
+			t.Errorf("Expected %s up to be %v", name, isUp)
+		}
+	}
+}
+
+func TestMirrorFailover(t *testing.T) {
+	viper.Set("cache-dir", t.TempDir())
+	viper.Set("http", map[string]interface{}{"max-attempts": 1})
+	defer viper.Set("cache-dir", "")
+	defer viper.Set("http", nil)
+	defer func() { mirrorState.loaded = false }()
+
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	}))
+	defer mirror.Close()
+	gone := httptest.NewServer(http.NotFoundHandler())
+	defer gone.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	fz := NewFzEngine()
+	fz.useBaseURL(&url.URL{Scheme: "http", Host: dead.Listener.Addr().String(), Path: "/"})
+	fz.Mirrors = parseMirrors(gone.URL+"/", mirror.URL+"/")
+	if _, err := fz.Search(context.Background(), "jumanji"); err != nil {
+		t.Fatalf("Expected the search to fail over to the mirror, got %v", err)
+	}
+	if fz.BaseURL.String() != mirror.URL+"/" || !strings.HasPrefix(fz.SearchURL.String(), mirror.URL+"/csearch.php") {
+		t.Errorf("Expected the engine to be moved to the mirror, got %s and %s", fz.BaseURL, fz.SearchURL)
+	}
+
+	// the working mirror is used straight away from then on
+	mirrorState.loaded = false
+	fz = NewFzEngine()
+	fz.Mirrors = parseMirrors(mirror.URL + "/")
+	if _, err := fz.List(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if fz.BaseURL.String() != mirror.URL+"/" {
+		t.Errorf("Expected the remembered mirror to be used, got %s", fz.BaseURL)
+	}
+
+	// other failures are not failed over
+	if siteMoved(fmt.Errorf("%w", ErrParseFailure)) || !siteMoved(&visitError{fmt.Errorf("Not Found")}) {
+		t.Errorf("Expected only failed visits to count as moved sites")
+	}
+}
+
+func TestListPagePaths(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		fmt.Fprint(w, `<html><body></body></html>`)
+	}))
+	defer server.Close()
+	site := &url.URL{Scheme: "http", Host: server.Listener.Addr().String(), Path: "/"}
+
+	netNaija, coolMoviez := NewNetNaijaEngine(), NewCoolMoviezEngine()
+	netNaija.Mirrors, coolMoviez.Mirrors = nil, nil
+	netNaija.useBaseURL(site)
+	coolMoviez.useBaseURL(site)
+	for _, e := range []Engine{netNaija, coolMoviez} {
+		for page := 1; page <= 2; page++ {
+			if _, err := e.List(context.Background(), page); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// the pages are listed from the list path, not from the page listed before
+	want := "/videos/movies/page/1 /videos/movies/page/2 /movielist/13/Hollywood_movies/default/1.html/ /movielist/13/Hollywood_movies/default/2.html/"
+	if got := strings.Join(paths, " "); got != want {
+		t.Errorf("Expected the pages %s, got %s", want, got)
+	}
+}
+
+func TestEngineEnabled(t *testing.T) {
+	viper.Set("disabled-engines", []string{"BestHDMovies"})
+	defer viper.Set("disabled-engines", nil)
+	if _, err := GetEngine("besthdmovies"); err == nil {
+		t.Errorf("Expected disabled engines to be unavailable")
+	}
+	if _, ok := GetEngines()["besthdmovies"]; ok {
+		t.Errorf("Expected disabled engines to be left out of GetEngines")
+	}
+
+	viper.Set("enabled-engines", "yts,fzmovies")
+	defer viper.Set("enabled-engines", nil)
+	if engines := GetEngines(); len(engines) != 2 || engines["yts"] == nil || engines["fzmovies"] == nil {
+		t.Errorf("Expected only the enabled engines, got %v", engines)
+	}
+}
+
+func TestHosts(t *testing.T) {
+	hosts := strings.Join(Hosts(), ",")
+	// mirrors are included, the www. prefix is not
+	if !strings.Contains(hosts, "thenetnaija.com") || !strings.Contains(hosts, "thenetnaija.net") || strings.Contains(hosts, "www.") {
+		t.Errorf("Unexpected hosts %s", hosts)
+	}
+}
+
+func TestVerifyLinks(t *testing.T) {
+	viper.Set("cache-dir", t.TempDir())
+	defer viper.Set("cache-dir", "")
+	defer func() { linkState.loaded = false }()
+	linkState.loaded = false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/get-only":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	link := func(p string) *url.URL {
+		u, _ := url.Parse(server.URL + p)
+		return u
+	}
+	movies := []Movie{
+		{Title: "Alive", Source: "Up", DownloadLink: link("/ok"), SDownloadLink: map[string]*url.URL{
+			"Episode 1": link("/gone"),
+			"Episode 2": link("/get-only"),
+		}},
+		{Index: 1, Title: "Dead", Source: "Rotten", DownloadLink: link("/gone")},
+		{Index: 2, Title: "Also Alive", Source: "Rotten", DownloadLink: link("/get-only")},
+	}
+	kept, reports := VerifyLinks(context.Background(), movies, 2, time.Second, true)
+	if len(reports) != 3 || len(reports[0].Links) != 3 || !reports[1].Dead() || reports[2].Dead() {
+		t.Fatalf("Unexpected reports %+v", reports)
+	}
+	if len(kept) != 2 || kept[0].Title != "Alive" || kept[1].Title != "Also Alive" || kept[1].Index != 1 {
+		t.Errorf("Expected the dead movie to be pruned, got %+v", kept)
+	}
+	if _, ok := kept[0].SDownloadLink["Episode 1"]; ok || len(kept[0].SDownloadLink) != 1 {
+		t.Errorf("Expected the dead episode link to be removed, got %v", kept[0].SDownloadLink)
+	}
+
+	// statistics are kept for later runs
+	linkState.loaded = false
+	stats := LinkRot()
+	expected := []LinkStats{{Engine: "rotten", Checked: 2, Dead: 1}, {Engine: "up", Checked: 3, Dead: 1}}
+	if len(stats) != len(expected) || stats[0] != expected[0] || stats[1] != expected[1] {
+		t.Errorf("Expected link statistics %+v, got %+v", expected, stats)
+	}
+	// too few checks to rank the engine by
+	if linkRotOf("Rotten") != 0 {
+		t.Errorf("Expected the link rot of an engine with few checks to be ignored")
+	}
+	RecordLinkChecks("Rotten", 8, 8)
+	if rot := linkRotOf("rotten"); rot != 0.9 {
+		t.Errorf("Expected a link rot of 0.9, got %v", rot)
+	}
+}
+
+func TestResolveLinks(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Unix()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download":
+			http.Redirect(w, r, "/landing", http.StatusFound)
+		case "/landing":
+			fmt.Fprint(w, `<html><a href="#">Share</a><a href="/wait">Click here to download</a></html>`)
+		case "/wait":
+			fmt.Fprintf(w, `<html><meta http-equiv="Refresh" content="5; url=/files/movie.mp4?expires=%d"></html>`, expires)
+		case "/files/movie.mp4":
+			w.Header().Set("Content-Type", "video/mp4")
+		case "/nowhere":
+			fmt.Fprint(w, `<html><a href="/nowhere">Download</a></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	link := func(p string) *url.URL {
+		u, _ := url.Parse(server.URL + p)
+		return u
+	}
+	movies := ResolveLinks(context.Background(), []Movie{
+		{Title: "Landing", DownloadLink: link("/download")},
+		{Title: "Nowhere", DownloadLink: link("/nowhere")},
+		{Title: "Torrent", DownloadLink: link("/download"), MagnetLink: "magnet:?xt=urn:btih:6a1c0e5b3d"},
+	}, 2, time.Second)
+	if movies[0].DirectLink == nil || movies[0].DirectLink.Path != "/files/movie.mp4" || movies[0].LinkExpires.Unix() != expires {
+		t.Fatalf("Expected the link of the file found, got %v expiring %v", movies[0].DirectLink, movies[0].LinkExpires)
+	}
+	if movies[0].Link() != movies[0].DirectLink {
+		t.Errorf("Expected the file downloaded from the direct link")
+	}
+	if referer := LinkHeaders(&movies[0]).Get("Referer"); referer != movies[0].DownloadLink.String() {
+		t.Errorf("Expected the download link as Referer of the direct link, got %q", referer)
+	}
+	if movies[1].DirectLink != nil || movies[1].Link() != movies[1].DownloadLink || movies[2].DirectLink != nil {
+		t.Errorf("Expected the movies without direct links to keep their download links, got %+v", movies[1:])
+	}
+
+	// expired links are resolved again
+	movies[0].LinkExpires = time.Now().Add(-time.Minute)
+	if movies[0].Link() != movies[0].DownloadLink {
+		t.Errorf("Expected the download link of an expired direct link")
+	}
+	if err := ResolveMovieLink(context.Background(), &movies[1]); !errors.Is(err, ErrNoDirectLink) {
+		t.Errorf("Expected ErrNoDirectLink, got %v", err)
+	}
+}
+
+func TestLinkExpiry(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"https://cdn.example/a.mp4?expires=1591020000":                            time.Unix(1591020000, 0),
+		"https://cdn.example/a.mp4?e=3600":                                        now.Add(time.Hour),
+		"https://s3.example/a.mp4?X-Amz-Date=20200601T110000Z&X-Amz-Expires=7200": now.Add(time.Hour),
+		"https://cdn.example/a.mp4?quality=720":                                   {},
+	}
+	for link, expected := range tests {
+		u, _ := url.Parse(link)
+		if got := LinkExpiry(u, now); !got.Equal(expected) {
+			t.Errorf("Expected %s to expire at %v, got %v", link, expected, got)
+		}
+	}
+}
+
+func TestGroupDuplicates(t *testing.T) {
+	var movies []Movie
+	for _, m := range []Movie{
+		{Title: "Joker (2019) [NetNaija] 720p", Source: "NetNaija"},
+		{Title: "Blade Runner 2049", Year: 2017, Source: "FzMovies"},
+		{Title: "Joker", Year: 2019, Source: "FzMovies", SizeBytes: 700 << 20},
+		{Title: "Joker.2019.1080p.WEB-DL.x264", Source: "1337x"},
+		{Title: "Joker", Year: 1999, Source: "YTS"},
+		{Title: "Blade Runner 2049 (2017) 1080p", Source: "YTS"},
+		{Title: "Joker", Year: 2019, IsSeries: true, Source: "O2TvSeries"},
+	} {
+		m.Quality = ParseQuality(m.Title)
+		movies = append(movies, m)
+	}
+	groups := GroupDuplicates(movies)
+	var got []string
+	for _, group := range groups {
+		var sources []string
+		for _, copy := range group.Copies() {
+			sources = append(sources, copy.Source)
+		}
+		got = append(got, strings.Join(sources, ","))
+	}
+	// copies of better quality are listed first
+	want := "NetNaija,1337x,FzMovies FzMovies,YTS YTS O2TvSeries"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Expected the groups %s, got %s", want, strings.Join(got, " "))
+	}
+}
+
+// yearEngine : narrows its searches with a year, recording what it searched for
+type yearEngine struct {
+	stubEngine
+	searched []string
+	query    Query
+}
+
+func (e *yearEngine) Search(ctx context.Context, param ...string) (SearchResult, error) {
+	e.searched = append(e.searched, param[0])
+	e.query, _ = QueryFrom(ctx)
+	return SearchResult{Query: param[0], Movies: []Movie{
+		{Title: "Avengers: Endgame", Year: 2019, Genres: "Action,Adventure"},
+		{Title: "The Avengers", Year: 2012, Genres: "Action"},
+		{Title: "Avengers Assemble", Year: 2019, Category: "Animation"},
+		{Title: "Avengers of Justice", Year: 2019},
+		{Title: "Avengers Grimm", Genres: "Action,Fantasy"},
+	}}, nil
+}
+func (e *yearEngine) Capabilities() Capabilities { return Capabilities{SearchByYear: true} }
+
+func TestQuery(t *testing.T) {
+	q, err := ParseQuery("avengers year:2015-2020 Genre:action,science_fiction")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Text != "avengers" || q.YearFrom != 2015 || q.YearTo != 2020 || strings.Join(q.Genres, ",") != "action,science fiction" {
+		t.Errorf("Unexpected query %+v", q)
+	}
+	if got := q.String(); got != "avengers year:2015-2020 genre:action,science_fiction" {
+		t.Errorf("Expected the query to be written back, got %q", got)
+	}
+	// words which only look like operators are searched for
+	if q, _ = ParseQuery("mission: impossible http://a.example"); q.Filtered() || q.Text != "mission: impossible http://a.example" {
+		t.Errorf("Expected no operators in %+v", q)
+	}
+	for _, years := range []string{"2019", "2015-2020", "2015-", "-2020"} {
+		if _, _, err = ParseYearRange(years); err != nil {
+			t.Errorf("Expected %s to be a year range, got %v", years, err)
+		}
+	}
+	for _, query := range []string{"avengers year:nineteen", "avengers year:2020-2015", "avengers year:-", "year:2019"} {
+		if _, err = Search(context.Background(), &yearEngine{}, query); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Expected %q to be an invalid query, got %v", query, err)
+		}
+	}
+
+	// a single year is searched on sites narrowing searches with it
+	e := &yearEngine{}
+	result, err := Search(context.Background(), e, "avengers year:2019 genre:action")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.searched[0] != "avengers 2019" || e.query.Year() != 2019 || result.Query != "avengers" {
+		t.Errorf("Expected the year to be searched for, searched %v with %+v", e.searched, e.query)
+	}
+	// movies whose genres or year are unknown are kept
+	if got := strings.Join(result.Titles(), ","); got != "Avengers: Endgame,Avengers of Justice,Avengers Grimm" {
+		t.Errorf("Expected the action movies of 2019, got %s", got)
+	}
+
+	// ranges are only filtered
+	e = &yearEngine{}
+	if result, err = SearchPages(context.Background(), e, "avengers year:2010-2015", 1); err != nil {
+		t.Fatal(err)
+	}
+	if e.searched[0] != "avengers" || len(result.Movies) != 2 || result.Movies[0].Year != 2012 || result.Movies[1].Year != 0 {
+		t.Errorf("Expected the movies of 2010 to 2015, searched %v and got %+v", e.searched, result.Movies)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	if code, region := ParseLanguage("Download Korean Movie"); code != "ko" || region != "Korea" {
+		t.Errorf("Expected a Korean movie, got %s %s", code, region)
+	}
+	if code, region := ParseLanguage("Nollywood"); code != "" || region != "Nollywood" {
+		t.Errorf("Expected the region only, got %s %s", code, region)
+	}
+	for name, want := range map[string]string{"ko": "ko", "Korean": "ko", "it": "it"} {
+		if code, ok := LanguageCode(name); !ok || code != want {
+			t.Errorf("Expected %s to be %s, got %s %v", name, want, code, ok)
+		}
+	}
+	if _, ok := LanguageCode("klingon"); ok {
+		t.Error("Expected an unknown language")
+	}
+
+	// movies whose language is not shown are in the usual one of the engine
+	p := &Props{Languages: []string{"en", "hi"}, Regions: []string{"Hollywood", "Bollywood"}}
+	movies := []Movie{
+		{Title: "Jumanji (2019)"},
+		{Title: "Dangal (2016)", Category: "Bollywood"},
+		{Title: "Parasite (2019)", Language: "ko"},
+	}
+	for i := range movies {
+		p.tagLanguage(&movies[i])
+	}
+	for i, want := range []string{"en Hollywood", "hi Bollywood", "ko Korea"} {
+		if got := movies[i].Language + " " + movies[i].Region; got != want {
+			t.Errorf("Expected %s to be tagged %s, got %s", movies[i].Title, want, got)
+		}
+	}
+	anime := Movie{Title: "Naruto (Dub)", Translation: Dubbed}
+	(&Props{Languages: []string{"ja"}, Regions: []string{"Japan"}}).tagLanguage(&anime)
+	if anime.Language != "en" || anime.Region != "Japan" {
+		t.Errorf("Expected dubbed anime in English, got %s %s", anime.Language, anime.Region)
+	}
+
+	result := SearchResult{Movies: append(movies, Movie{Title: "Unknown"})}
+	if filtered := result.FilterByLanguage("korean", "hi"); len(filtered.Movies) != 2 || filtered.Movies[0].Title != "Dangal (2016)" {
+		t.Errorf("Expected the Hindi and Korean movies, got %+v", filtered.Movies)
+	}
+}
@@ -1,14 +1,19 @@
 package engine
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/go-phie/gophie/logging"
 	"github.com/go-phie/gophie/transport"
 	"github.com/gocolly/colly/v2"
 	log "github.com/sirupsen/logrus"
@@ -34,11 +39,18 @@ func (m Mode) String() string {
 
 // Engine : interface for all engines
 type Engine interface {
+	Search(ctx context.Context, param ...string) (SearchResult, error)
+	List(ctx context.Context, page int) (SearchResult, error)
+	Capabilities() Capabilities
+	String() string
+}
+
+// ScrapingEngine : interface for engines that scrape their results from html pages using Scrape
+type ScrapingEngine interface {
+	Engine
 	getName() string
+	getProps() *Props
 	getParseURL() *url.URL
-	Search(param ...string) SearchResult
-	List(page int) SearchResult
-	String() string
 
 	// parseSingleMovie: parses the result of a colly HTMLElement and returns a movie
 	// The input el is usually the block of code from the article specified in getParseAttrs
@@ -55,14 +67,197 @@ type Engine interface {
 	updateDownloadProps(downloadCollector *colly.Collector, movies *[]Movie)
 }
 
+// contextTransport : binds every request made through it to ctx so that
+// in-flight requests are dropped when ctx is cancelled
+type contextTransport struct {
+	ctx      context.Context
+	upstream http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.upstream.RoundTrip(req.WithContext(t.ctx))
+}
+
+// TransportWrapper : Wraps the transport the requests of engines are sent through
+type TransportWrapper func(upstream http.RoundTripper) http.RoundTripper
+
+type transportWrapperKey struct{}
+
+// WithTransportWrapper : ctx whose engine requests are sent through the transport
+// returned by wrap, e.g to record and replay the responses of sites in tests
+func WithTransportWrapper(ctx context.Context, wrap TransportWrapper) context.Context {
+	return context.WithValue(ctx, transportWrapperKey{}, wrap)
+}
+
+// wrapTransport : upstream wrapped by the TransportWrapper of ctx, if any
+func wrapTransport(ctx context.Context, upstream http.RoundTripper) http.RoundTripper {
+	if wrap, ok := ctx.Value(transportWrapperKey{}).(TransportWrapper); ok && wrap != nil {
+		return wrap(upstream)
+	}
+	return upstream
+}
+
+// clientConfig : configuration of the requests of the named engine from the http
+// section of the config file. The proxy can also be set with --proxy or GOPHIE_PROXY
+// and Tor enabled with --tor
+func clientConfig(name string) (transport.Config, error) {
+	var config transport.Config
+	if err := viper.UnmarshalKey("http", &config); err != nil {
+		return config, fmt.Errorf("invalid http config: %v", err)
+	}
+	if proxy := viper.GetString("proxy"); proxy != "" {
+		config.Proxy = proxy
+	}
+	config.Tor = TorConfig(config.Tor)
+	if config.CookieFile == "" && viper.GetString("cache-dir") != "" {
+		config.CookieFile = filepath.Join(viper.GetString("cache-dir"), "cookies.json")
+	}
+	// challenges were solved with --use-chrome-driver before the browser could be configured
+	if viper.GetBool("use-chrome-driver") {
+		config.Browser.Enabled = true
+	}
+	return config.For(name), nil
+}
+
+// TorConfig : tor of the config file with --tor and --tor-proxy applied
+func TorConfig(tor transport.TorConfig) transport.TorConfig {
+	if viper.GetBool("tor") {
+		tor.Enabled = true
+	}
+	if proxy := viper.GetString("tor-proxy"); proxy != "" {
+		tor.Proxy = proxy
+	}
+	return tor
+}
+
+// propsClientConfig : configuration of the requests of the engine with props,
+// detecting challenge pages of engines behind anti-bot services and rendering
+// the pages of engines listing movies with JavaScript
+func propsClientConfig(props *Props) (transport.Config, error) {
+	config, err := clientConfig(props.Name)
+	if props.Features.AntiBot {
+		config.AntiBot = true
+	}
+	if props.Features.JavaScript {
+		config.Render = true
+	}
+	return config, err
+}
+
+// newClientTransport : transport for the requests of the named engine
+func newClientTransport(name string) (*transport.HeaderTransport, error) {
+	config, err := clientConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	return transport.NewTransport(config)
+}
+
+// limitRule : colly rule enforcing a rate limit, nil when nothing is limited
+func limitRule(limit transport.RateLimit) *colly.LimitRule {
+	if limit.RequestsPerSecond <= 0 && limit.Parallelism <= 0 && limit.RandomDelay <= 0 {
+		return nil
+	}
+	rule := &colly.LimitRule{
+		DomainGlob:  "*",
+		Parallelism: limit.Parallelism,
+		RandomDelay: limit.RandomDelay,
+	}
+	if limit.RequestsPerSecond > 0 {
+		rule.Delay = time.Duration(float64(time.Second) / limit.RequestsPerSecond)
+	}
+	return rule
+}
+
 // Scrape : Parse queries a url and return results
-func Scrape(engine Engine) ([]Movie, error) {
+// When ctx is cancelled, pending visits are aborted and the movies parsed so far
+// are returned along with the context error
+// When the site cannot be resolved or is not found, the mirrors of the engine are
+// tried in turn and the one that works is used from then on
+func Scrape(ctx context.Context, engine ScrapingEngine) ([]Movie, error) {
+	props := engine.getProps()
+	bases := props.useRememberedMirror()
+	movies, err := scrape(ctx, engine)
+	tried := map[string]bool{props.BaseURL.String(): true}
+	for _, base := range bases {
+		if !siteMoved(err) || ctx.Err() != nil {
+			break
+		}
+		if tried[base.String()] {
+			continue
+		}
+		tried[base.String()] = true
+		logging.FromContext(ctx).WithField("engine", props.Name).Warnf("Failed at %s (%v), trying mirror %s", props.BaseURL, err, base)
+		props.useBaseURL(base)
+		if movies, err = scrape(ctx, engine); err == nil {
+			rememberMirror(props.Name, base)
+		}
+	}
+	var failedVisit *visitError
+	if err != nil && !errors.As(err, &failedVisit) {
+		return movies, err
+	}
+	if ctx.Err() != nil {
+		return movies, ctx.Err()
+	}
+	if failedVisit != nil {
+		return movies, fmt.Errorf("%w: %s: %v", ErrEngineUnavailable, engine.getName(), failedVisit.err)
+	}
+	return movies, nil
+}
+
+// Keys of the colly request contexts carrying the trace ID of the operation a
+// request is made for and the name of the engine making it
+const (
+	traceCtxKey  = "traceID"
+	engineCtxKey = "engine"
+)
+
+// requestLog : An entry logging the trace ID and engine of the operation r was made for
+func requestLog(r *colly.Request) *log.Entry {
+	entry := logging.WithTrace(r.Ctx.Get(traceCtxKey))
+	if name := r.Ctx.Get(engineCtxKey); name != "" {
+		entry = entry.WithField("engine", name)
+	}
+	return entry
+}
+
+// LogOperation : Log a search or list of the engine named name which started at
+// start, with the trace ID of ctx, how long it took and how many movies it found.
+// query is empty for lists
+func LogOperation(ctx context.Context, name string, mode Mode, query string, start time.Time, movies int, err error) {
+	entry := logging.FromContext(ctx).WithFields(log.Fields{
+		"engine":   name,
+		"mode":     mode.String(),
+		"duration": time.Since(start).Round(time.Millisecond).String(),
+		"results":  movies,
+	})
+	if query != "" {
+		entry = entry.WithField("query", query)
+	}
+	if err != nil {
+		entry.WithError(err).Warnf("%s failed", mode)
+		return
+	}
+	entry.Debugf("%s completed", mode)
+}
+
+// visitError : the error of visiting the parse URL of an engine
+type visitError struct {
+	err error
+}
+
+func (e *visitError) Error() string { return e.err.Error() }
+func (e *visitError) Unwrap() error { return e.err }
+
+// scrape : Scrape the current parse URL of engine once. Errors of the visit
+// itself are returned as a visitError
+func scrape(ctx context.Context, engine ScrapingEngine) ([]Movie, error) {
 	// Config Vars
 	//  seleniumURL := fmt.Sprintf("%s/wd/hub", viper.GetString("selenium-url"))
 	cacheDir := viper.GetString("cache-dir")
-	ignoreCache := viper.GetBool("ignore-cache")
+	ignoreCache := viper.GetBool("ignore-cache") || Refreshing(ctx)
 	var (
-		t   *transport.ChromeDpTransport
 		err error
 		c   *colly.Collector
 	)
@@ -78,24 +273,22 @@ func Scrape(engine Engine) ([]Movie, error) {
 		)
 	}
 
-	useChromeDriver := viper.GetBool("use-chrome-driver")
-	// Add Cloud Flare scraper bypasser
-	if useChromeDriver && engine.getName() == "NetNaija" {
-		log.Debug("Switching to ChromeDpTransport")
-		t, err = transport.NewChromeDpTransport(http.DefaultTransport)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		c.WithTransport(t)
+	config, err := propsClientConfig(engine.getProps())
+	if err != nil {
+		return nil, err
+	}
+	client, err := transport.NewTransport(config)
+	if err != nil {
+		return nil, err
 	}
-	// Close the WebDriver Instance
-	defer func() {
-		if useChromeDriver && engine.getName() == "NetNaija" {
-			t.RemoteAllocCancel()
-			t.Cancel()
+	defer client.CloseIdleConnections()
+	// download collectors are clones sharing the limits
+	if rule := limitRule(config.RateLimit); rule != nil {
+		if err = c.Limit(rule); err != nil {
+			return nil, err
 		}
-	}()
+	}
+	c.WithTransport(&contextTransport{ctx: ctx, upstream: wrapTransport(ctx, client)})
 
 	// Another collector for download Links
 	downloadLinkCollector := c.Clone()
@@ -106,9 +299,17 @@ func Scrape(engine Engine) ([]Movie, error) {
 	// Any Extras setup for downloads using can be specified in the function
 	engine.updateDownloadProps(downloadLinkCollector, &movies)
 
+	onMovie := MovieFuncFromContext(ctx)
+	traceID := logging.TraceID(ctx)
+	props := engine.getProps()
+	props.pagination = pagination{}
+	props.errors = nil
+	c.OnHTML(paginationSelector, func(e *colly.HTMLElement) {
+		props.pagination.add(e)
+	})
 	main, article, err := engine.getParseAttrs()
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 
 	//  c.OnHTML("div", func(e *colly.HTMLElement) {
@@ -119,32 +320,53 @@ func Scrape(engine Engine) ([]Movie, error) {
 		e.ForEach(article, func(_ int, el *colly.HTMLElement) {
 			movie, err := engine.parseSingleMovie(el, movieIndex)
 			if err != nil {
-				log.Errorf("%v could not be parsed", movie)
+				requestLog(el.Request).Errorf("%v could not be parsed: %v", movie, err)
+				countParseError(ctx)
+				props.addError(ParseStage, err)
 			} else {
 				movies = append(movies, movie)
 				downloadLinkCollector.Visit(movie.DownloadLink.String())
 				movieIndex++
+				// download pages are visited synchronously so the movie is complete here
+				if onMovie != nil && ctx.Err() == nil {
+					scraped := movies[len(movies)-1]
+					scraped.complete()
+					props.tagLanguage(&scraped)
+					onMovie(scraped)
+				}
 			}
 		})
 	})
 
 	c.OnRequest(func(r *colly.Request) {
+		if ctx.Err() != nil {
+			r.Abort()
+			return
+		}
 		r.Headers.Set("Accept", "text/html")
-		log.Debugf("Visiting %v", r.URL.String())
+		r.Ctx.Put(traceCtxKey, traceID)
+		r.Ctx.Put(engineCtxKey, engine.getName())
+		requestLog(r).Debugf("Visiting %v", r.URL.String())
 	})
 
 	c.OnResponse(func(r *colly.Response) {
-		log.Debugf("Done %v", r.Request.URL.String())
+		requestLog(r.Request).Debugf("Done %v", r.Request.URL.String())
 	})
 
 	// Attach Movie Index to Context before making visits
 	// Adding Movie Index to context ensures we can fetch a reference to the
 	// movie details when we need it
 	downloadLinkCollector.OnRequest(func(r *colly.Request) {
+		if ctx.Err() != nil {
+			r.Abort()
+			return
+		}
 		r.Headers.Set("Accept", "text/html,application/xhtml+xml,application/xml")
+		r.Ctx.Put(traceCtxKey, traceID)
+		r.Ctx.Put(engineCtxKey, engine.getName())
 		for i, movie := range movies {
 			if movie.DownloadLink.String() == r.URL.String() {
-				log.Debugf("Retrieving Download Link %v\n", movie.DownloadLink)
+				requestLog(r).Debugf("Retrieving Download Link %v", movie.DownloadLink)
 				r.Ctx.Put("movieIndex", strconv.Itoa(i))
 			}
 		}
@@ -155,15 +377,39 @@ func Scrape(engine Engine) ([]Movie, error) {
 	downloadLinkCollector.OnResponseHeaders(func(r *colly.Response) {
 		if !strings.Contains(r.Headers.Get("Content-Type"), "text") {
 			r.Request.Abort()
-			log.Debugf("Response %s is not text/html. Aborting request", r.Request.URL)
+			requestLog(r.Request).Debugf("Response %s is not text/html. Aborting request", r.Request.URL)
+		}
+	})
+
+	downloadLinkCollector.OnError(func(r *colly.Response, err error) {
+		// pages which are not text were aborted on purpose
+		if errors.Is(err, colly.ErrAbortedAfterHeaders) || ctx.Err() != nil {
+			return
 		}
+		requestLog(r.Request).Warnf("Could not get the download page %s: %v", r.Request.URL, err)
+		props.addError(DownloadStage, fmt.Errorf("%s: %v", r.Request.URL, err))
 	})
 
 	downloadLinkCollector.OnResponse(func(r *colly.Response) {
-		movie := &movies[getMovieIndexFromCtx(r.Request)]
-		log.Debugf("Retrieved Download Link %v\n", movie.DownloadLink)
+		movie, err := getMovieFromCtx(r.Request, &movies)
+		if err != nil {
+			requestLog(r.Request).Error(err)
+			props.addError(DownloadStage, err)
+			return
+		}
+		if movie.Checksum == "" {
+			movie.Checksum = ParseChecksum(string(r.Body))
+		}
+		requestLog(r.Request).Debugf("Retrieved Download Link %v", movie.DownloadLink)
 	})
-	c.Visit(engine.getParseURL().String())
+	err = c.Visit(engine.getParseURL().String())
+	for i := range movies {
+		movies[i].complete()
+		props.tagLanguage(&movies[i])
+	}
+	if err != nil {
+		return movies, &visitError{err}
+	}
 	return movies, nil
 }
 
@@ -174,11 +420,14 @@ type Movie struct {
 	CoverPhotoLink string
 	Description    string
 	Size           string
+	SizeBytes      int64 // Size in bytes, 0 when unknown
 	DownloadLink   *url.URL
+	DirectLink     *url.URL  // the file DownloadLink leads to once resolved, see ResolveLinks
+	LinkExpires    time.Time // when DirectLink stops working, zero when it does not or it is unknown
 	Year           int
 	IsSeries       bool
 	SDownloadLink  map[string]*url.URL // Other links for downloads if movies is series
-	Quality        string
+	Quality        Quality
 	Category       string // csv of categories
 	Cast           string // csv of actors in movie
 	UploadDate     string
@@ -187,12 +436,37 @@ type Movie struct {
 	SubtitleLinks  map[string]*url.URL // Subtitle links for a series
 	ImdbLink       string              // imdb link if available
 	Tags           string              // csv of words that are linked to the movie if available
+	CanonicalTitle string              // title from a metadata provider if enriched
+	Genres         string              // csv of genres from a metadata provider if enriched
+	Rating         float64             // rating out of 10 from a metadata provider if enriched
+	Runtime        int                 // runtime in minutes from a metadata provider if enriched
+	PosterLink     string              // poster from a metadata provider if enriched
+	MagnetLink     string              // magnet link for torrent results
+	Score          float64             // relevance to the query from 0 to 1 once ranked
+	Checksum       string              // hash of the file as sha256:<hex> or md5:<hex> when the source shows it
+	Translation    Translation         // sub or dub for anime if known
+	Language       string              // ISO 639-1 code of the spoken language e.g en, hi, ko if known
+	Region         string              // film industry or country e.g Hollywood, Nollywood, Korea if known
+	Seasons        []Season            // seasons and episodes if movie is series
+}
+
+// complete : Derive the details of a scraped movie which are not scraped directly
+func (m *Movie) complete() {
+	m.buildSeasons()
+	m.Quality = m.Quality.merge(ParseQuality(m.Title))
+	if m.SizeBytes == 0 {
+		m.SizeBytes = ParseSize(m.Size)
+	}
+	if m.Translation == "" {
+		m.Translation = ParseTranslation(m.Title)
+	}
 }
 
 // MovieJSON : JSON structure of all downloadable movies
 type MovieJSON struct {
 	Movie
 	DownloadLink  string
+	DirectLink    string `json:",omitempty"`
 	SDownloadLink map[string]string
 	SubtitleLinks map[string]string
 }
@@ -219,14 +493,69 @@ func (m *Movie) MarshalJSON() ([]byte, error) {
 		SubtitleLinks: subtitleLinks,
 	}
 
+	if m.DirectLink != nil {
+		movie.DirectLink = m.DirectLink.String()
+	}
 	return json.Marshal(movie)
 
 }
 
+// UnmarshalJSON : Rebuild a movie from the structure returned by MarshalJSON
+func (m *Movie) UnmarshalJSON(data []byte) error {
+	type movie Movie
+	aux := struct {
+		*movie
+		DownloadLink  string
+		DirectLink    string
+		SDownloadLink map[string]string
+		SubtitleLinks map[string]string
+	}{movie: (*movie)(m)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	if m.DownloadLink, err = url.Parse(aux.DownloadLink); err != nil {
+		return err
+	}
+	if aux.DirectLink != "" {
+		if m.DirectLink, err = url.Parse(aux.DirectLink); err != nil {
+			return err
+		}
+	}
+	m.SDownloadLink, err = parseURLMap(aux.SDownloadLink)
+	if err != nil {
+		return err
+	}
+	m.SubtitleLinks, err = parseURLMap(aux.SubtitleLinks)
+	return err
+}
+
+func parseURLMap(links map[string]string) (map[string]*url.URL, error) {
+	if len(links) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[string]*url.URL, len(links))
+	for key, val := range links {
+		u, err := url.Parse(val)
+		if err != nil {
+			return nil, err
+		}
+		parsed[key] = u
+	}
+	return parsed, nil
+}
+
 // SearchResult : the results of search from engine
 type SearchResult struct {
 	Query  string
 	Movies []Movie
+	// Page of the results on the site, and the pages after it where the engine knows them
+	Page       int           `json:",omitempty"`
+	TotalPages int           `json:",omitempty"` // 0 when the site does not tell
+	HasNext    bool          `json:",omitempty"`
+	NextCursor string        `json:",omitempty"` // the page param of the next page, empty on the last page
+	Errors     []EngineError `json:",omitempty"` // failures of the engines which did not stop the search
 }
 
 // Titles : Get a slice of the titles of movies
@@ -239,57 +568,123 @@ func (s *SearchResult) Titles() []string {
 }
 
 // GetMovieByTitle : Return a movie object from title passed
+// Titles that differ only in case, punctuation, scraped tags like [NetNaija] or
+// a typo or two also match, the closest one is returned
 func (s *SearchResult) GetMovieByTitle(title string) (Movie, error) {
-	for _, movie := range s.Movies {
-		if movie.Title == title {
-			return movie, nil
-		}
+	if index, ok := s.findTitle(title); ok {
+		return s.Movies[index], nil
 	}
-	return Movie{}, errors.New("Movie not Found")
+	return Movie{}, fmt.Errorf("%w: %s", ErrMovieNotFound, title)
 }
 
-// GetIndexFromTitle : return movieIndex from title
+// GetIndexFromTitle : return movieIndex from title, matched as in GetMovieByTitle
 func (s *SearchResult) GetIndexFromTitle(title string) (int, error) {
-	for index, movie := range s.Movies {
-		if movie.Title == title {
-			return index, nil
-		}
+	if index, ok := s.findTitle(title); ok {
+		return index, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrMovieNotFound, title)
+}
+
+// EngineFactory : creates a new instance of an engine
+type EngineFactory func() Engine
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]EngineFactory)
+)
+
+// RegisterEngine : Makes an engine available under name to GetEngines and GetEngine
+// It is meant to be called from the init function of the package providing the
+// engine, so engines can be left out of a build by excluding their file (e.g with
+// build tags) or added by importing a third-party package for its side effects.
+// Registering the same name twice or a nil factory panics
+func RegisterEngine(name string, factory EngineFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	name = strings.ToLower(name)
+	if factory == nil {
+		panic("engine: RegisterEngine factory is nil for " + name)
+	}
+	if _, dup := factories[name]; dup {
+		panic("engine: RegisterEngine called twice for " + name)
 	}
-	return 0, errors.New("Movie not Found")
+	factories[name] = factory
+}
+
+// EngineRegistered : Whether an engine was registered as name, enabled or not
+func EngineRegistered(name string) bool {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	_, ok := factories[strings.ToLower(name)]
+	return ok
 }
 
 // GetEngines : Returns all the usable engines in the application
 func GetEngines() map[string]Engine {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
 	engines := make(map[string]Engine)
-	engines["netnaija"] = NewNetNaijaEngine()
-	engines["fzmovies"] = NewFzEngine()
-	engines["besthdmovies"] = NewBestHDEngine()
-	engines["tvseries"] = NewTvSeriesEngine()
-	engines["mycoolmoviez"] = NewMyCoolMoviezEngine()
-	engines["coolmoviez"] = NewCoolMoviezEngine()
-	engines["animeout"] = NewAnimeOutEngine()
-	engines["takanimelist"] = NewTakanimeListEngine()
-	engines["kdramahood"] = NewKDramaHoodEngine()
-	engines["nkiri"] = NewNkiriEngine()
+	for name, factory := range factories {
+		if EngineEnabled(name) {
+			engines[name] = factory()
+		}
+	}
 	return engines
 }
 
 // GetEngine : Return an engine
 func GetEngine(engine string) (Engine, error) {
-	e := GetEngines()[strings.ToLower(engine)]
-	if e == nil {
+	factoriesMu.RLock()
+	factory := factories[strings.ToLower(engine)]
+	factoriesMu.RUnlock()
+	if factory == nil {
 		return nil, fmt.Errorf("Engine %s Does not exist", engine)
 	}
-	return e, nil
+	if !EngineEnabled(engine) {
+		return nil, fmt.Errorf("Engine %s is disabled", engine)
+	}
+	return factory(), nil
+}
+
+// EngineEnabled : Whether the named engine may be used. Only the engines in the
+// enabled-engines config are when it is set, and never those in disabled-engines
+func EngineEnabled(name string) bool {
+	name = strings.ToLower(name)
+	contains := func(key string) bool {
+		for _, value := range viper.GetStringSlice(key) {
+			for _, item := range strings.Split(value, ",") {
+				if strings.ToLower(strings.TrimSpace(item)) == name {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if contains("disabled-engines") {
+		return false
+	}
+	return len(viper.GetStringSlice("enabled-engines")) == 0 || contains("enabled-engines")
 }
 
 // Get the movie index context stored in Request
-func getMovieIndexFromCtx(r *colly.Request) int {
+func getMovieIndexFromCtx(r *colly.Request) (int, error) {
 	movieIndex, err := strconv.Atoi(r.Ctx.Get("movieIndex"))
 	if err != nil {
-		log.Fatal(err)
+		return 0, fmt.Errorf("%w: movie index for %s: %v", ErrParseFailure, r.URL, err)
+	}
+	return movieIndex, nil
+}
+
+// Get the movie referenced by the movie index stored in Request
+func getMovieFromCtx(r *colly.Request, movies *[]Movie) (*Movie, error) {
+	movieIndex, err := getMovieIndexFromCtx(r)
+	if err != nil {
+		return nil, err
+	}
+	if movieIndex < 0 || movieIndex >= len(*movies) {
+		return nil, fmt.Errorf("%w: movie index %d out of range for %s", ErrMovieNotFound, movieIndex, r.URL)
 	}
-	return movieIndex
+	return &(*movies)[movieIndex], nil
 }
 
 // Get all form details into a neat map
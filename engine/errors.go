@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Errors returned by engines. They are usually wrapped with more details
+// so they should be checked using errors.Is
+var (
+	// ErrMovieNotFound : no movie matched the lookup
+	ErrMovieNotFound = errors.New("Movie not Found")
+	// ErrParseFailure : a page or value returned by the source site could not be parsed
+	ErrParseFailure = errors.New("Parse failure")
+	// ErrEngineUnavailable : the source site could not be reached or returned an error
+	ErrEngineUnavailable = errors.New("Engine unavailable")
+	// ErrUnsupportedMode : the engine does not list movies in the requested mode
+	ErrUnsupportedMode = errors.New("Unsupported mode")
+)
+
+type parseErrorsKey struct{}
+
+// WithParseErrorCount : ctx whose scrapes add the movies which could not be
+// parsed, and were left out of their results, to n
+func WithParseErrorCount(ctx context.Context, n *int64) context.Context {
+	return context.WithValue(ctx, parseErrorsKey{}, n)
+}
+
+// countParseError : count a movie of a scrape of ctx which could not be parsed
+func countParseError(ctx context.Context) {
+	if n, ok := ctx.Value(parseErrorsKey{}).(*int64); ok {
+		atomic.AddInt64(n, 1)
+	}
+}
+
+// Stage : The step of a search an EngineError happened at
+type Stage string
+
+// Stages of a search
+const (
+	SearchStage   Stage = "search"   // the page of results could not be fetched
+	ParseStage    Stage = "parse"    // a movie of the page could not be parsed and was left out
+	DownloadStage Stage = "download" // the download page of a movie could not be fetched
+)
+
+// maxScrapeErrors : errors kept of a scrape, every movie fails once the layout of a site changes
+const maxScrapeErrors = 10
+
+// EngineError : A failure of an engine which did not stop its search, the movies
+// it parsed and those of the other engines searched are still returned
+type EngineError struct {
+	Engine  string
+	Stage   Stage
+	Message string
+}
+
+func (e EngineError) Error() string {
+	return fmt.Sprintf("%s: %s failed: %s", e.Engine, e.Stage, e.Message)
+}
+
+// addError : record a failure of the scrape of the engine of p at stage
+func (p *Props) addError(stage Stage, err error) {
+	if len(p.errors) < maxScrapeErrors {
+		p.errors = append(p.errors, EngineError{Engine: strings.ToLower(p.Name), Stage: stage, Message: err.Error()})
+	}
+}
+
+// registryName : the name e is registered under, that of SearchAll errors, which
+// is the name of its site in lower case. Engines are described as "<name> (<site>)"
+func registryName(e Engine) string {
+	name := e.String()
+	if i := strings.Index(name, " ("); i >= 0 {
+		name = name[:i]
+	}
+	return strings.ToLower(name)
+}
+
+// Partial : The result of e which failed with err, with the failure added to its
+// Errors when movies were still found so that they are returned rather than
+// err. err is returned as it is when they were cancelled or none were found
+func Partial(e Engine, result SearchResult, err error) (SearchResult, error) {
+	if err == nil || len(result.Movies) == 0 || errors.Is(err, context.Canceled) {
+		return result, err
+	}
+	result.Errors = append(result.Errors, EngineError{Engine: registryName(e), Stage: SearchStage, Message: err.Error()})
+	return result, nil
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+)
+
+// filter : Return the movies of the result for which keep is true
+func (s *SearchResult) filter(keep func(Movie) bool) SearchResult {
+	filtered := *s
+	filtered.Movies = nil
+	for _, movie := range s.Movies {
+		if keep(movie) {
+			filtered.Movies = append(filtered.Movies, movie)
+		}
+	}
+	return filtered
+}
+
+// FilterByQuality : Return the movies of the result matching quality which can be a
+// resolution (1080p), a format (BluRay) or both (1080p WEB-DL)
+func (s *SearchResult) FilterByQuality(quality string) SearchResult {
+	return s.filter(func(movie Movie) bool {
+		return movie.Quality.Matches(quality)
+	})
+}
+
+// FilterByYearRange : Return the movies of the result released from one year to
+// another inclusive. A zero year leaves that end of the range open
+func (s *SearchResult) FilterByYearRange(from, to int) SearchResult {
+	return s.filter(func(movie Movie) bool {
+		if movie.Year == 0 {
+			return from == 0 && to == 0
+		}
+		return (from == 0 || movie.Year >= from) && (to == 0 || movie.Year <= to)
+	})
+}
+
+// FilterBySize : Return the movies of the result of a size in bytes from min to max
+// inclusive. A zero size leaves that end of the range open
+func (s *SearchResult) FilterBySize(min, max int64) SearchResult {
+	return s.filter(func(movie Movie) bool {
+		if movie.SizeBytes == 0 {
+			return min == 0 && max == 0
+		}
+		return (min == 0 || movie.SizeBytes >= min) && (max == 0 || movie.SizeBytes <= max)
+	})
+}
+
+// FilterBySource : Return the movies of the result gotten from one of the engines named
+func (s *SearchResult) FilterBySource(sources ...string) SearchResult {
+	return s.filter(func(movie Movie) bool {
+		for _, source := range sources {
+			if strings.EqualFold(movie.Source, source) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// FilterSeriesOnly : Return the series of the result
+func (s *SearchResult) FilterSeriesOnly() SearchResult {
+	return s.filter(func(movie Movie) bool {
+		return movie.IsSeries
+	})
+}
+
+// SortByYear : Sort the movies of the result by year, oldest first unless descending
+// Movies without a year are placed last
+func (s *SearchResult) SortByYear(descending bool) {
+	s.sortBy(descending, func(movie Movie) int64 {
+		return int64(movie.Year)
+	})
+}
+
+// SortBySize : Sort the movies of the result by size, smallest first unless descending
+// Movies without a size are placed last
+func (s *SearchResult) SortBySize(descending bool) {
+	s.sortBy(descending, func(movie Movie) int64 {
+		return movie.SizeBytes
+	})
+}
+
+// sortBy : Stable sort of the movies by key keeping the movies with a zero key last
+func (s *SearchResult) sortBy(descending bool, key func(Movie) int64) {
+	sort.SliceStable(s.Movies, func(i, j int) bool {
+		a, b := key(s.Movies[i]), key(s.Movies[j])
+		if a == 0 || b == 0 {
+			return b == 0 && a != 0
+		}
+		if descending {
+			return a > b
+		}
+		return a < b
+	})
+}
@@ -1,12 +1,15 @@
 package engine
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gocolly/colly/v2"
 	log "github.com/sirupsen/logrus"
@@ -17,6 +20,10 @@ type FzEngine struct {
 	Props
 }
 
+func init() {
+	RegisterEngine("fzmovies", func() Engine { return NewFzEngine() })
+}
+
 // NewFzEngine : A Movie Engine Constructor for FzEngine
 func NewFzEngine() *FzEngine {
 	base := "https://www.fzmovies.net/"
@@ -42,8 +49,12 @@ func NewFzEngine() *FzEngine {
 	fzEngine.Name = "FzMovies"
 	fzEngine.BaseURL = baseURL
 	fzEngine.Description = `FzMovies is a site where you can find Bollywood, Hollywood and DHollywood Movies.`
+	fzEngine.Features = Capabilities{Pagination: true}
+	fzEngine.Languages = []string{"en", "hi"}
+	fzEngine.Regions = []string{"Hollywood", "Bollywood"}
 	fzEngine.SearchURL = searchURL
 	fzEngine.ListURL = listURL
+	fzEngine.Mirrors = parseMirrors("https://fzmovies.net/")
 	return &fzEngine
 }
 
@@ -66,7 +77,7 @@ func (engine *FzEngine) parseSingleMovie(el *colly.HTMLElement, index int) (Movi
 	}
 	cover, err := url.Parse(el.Request.AbsoluteURL(el.ChildAttr("img", "src")))
 	if err != nil {
-		log.Fatal(err)
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 	movie.CoverPhotoLink = cover.String()
 	// Remove all Video: or Movie: Prefixes
@@ -94,7 +105,7 @@ func (engine *FzEngine) parseSingleMovie(el *colly.HTMLElement, index int) (Movi
 	downloadLink, err := url.Parse(el.Request.AbsoluteURL(el.ChildAttr("a", "href")))
 
 	if err != nil {
-		log.Fatal(err)
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 	downloadLink.Path = path.Join(engine.BaseURL.Path, downloadLink.Path)
 
@@ -105,11 +116,16 @@ func (engine *FzEngine) parseSingleMovie(el *colly.HTMLElement, index int) (Movi
 func (engine *FzEngine) updateDownloadProps(downloadCollector *colly.Collector, movies *[]Movie) {
 	// Update movie download link if ul.downloadlinks on page
 	downloadCollector.OnHTML("ul.ptype", func(e *colly.HTMLElement) {
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		link := strings.Replace(e.ChildAttr("a", "href"), "download1.php", "download.php", 1)
 		downloadLink, err := url.Parse(e.Request.AbsoluteURL(link + "&pt=jRGarGzOo2"))
 		if err != nil {
-			log.Fatal(err)
+			log.Error(err)
+			return
 		}
 		movie.DownloadLink = downloadLink
 		re := regexp.MustCompile(`(.* MB)`)
@@ -131,12 +147,17 @@ func (engine *FzEngine) updateDownloadProps(downloadCollector *colly.Collector,
 	})
 
 	downloadCollector.OnHTML("ul.downloadlinks", func(e *colly.HTMLElement) {
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		links := e.ChildAttrs("a", "href")
 		if len(links) > 1 {
 			downloadLink, err := url.Parse(e.Request.AbsoluteURL(links[len(links)-1]))
 			if err != nil {
-				log.Fatal(err)
+				log.Error(err)
+				return
 			}
 			movie.DownloadLink = downloadLink
 			downloadCollector.Visit(downloadLink.String())
@@ -149,15 +170,30 @@ func (engine *FzEngine) updateDownloadProps(downloadCollector *colly.Collector,
 		if strings.HasSuffix(trimmedValue, "mp4") || strings.HasSuffix(trimmedValue, "mp4?fromwebsite") {
 			downloadLink, err := url.Parse(e.Request.AbsoluteURL(e.Attr("value")))
 			if err != nil {
-				log.Fatal(err)
+				log.Error(err)
+				return
+			}
+			movie, err := getMovieFromCtx(e.Request, movies)
+			if err != nil {
+				log.Error(err)
+				return
 			}
-			(*movies)[getMovieIndexFromCtx(e.Request)].DownloadLink = downloadLink
+			movie.DownloadLink = downloadLink
 		}
 	})
 }
 
+// fzResolver : follows the download pages of FzMovies, the last of the links of
+// ul.downloadlinks and then the download1 input leading to the file
+var fzResolver = &PageResolver{Selectors: []string{"ul.downloadlinks li:last-child a", "input[name=download1]"}}
+
+// ResolveLink : Follow the download pages of FzMovies to the file
+func (engine *FzEngine) ResolveLink(ctx context.Context, client *http.Client, link *url.URL) (*url.URL, time.Time, error) {
+	return fzResolver.ResolveLink(ctx, client, link)
+}
+
 // List : list all the movies on a page
-func (engine *FzEngine) List(page int) SearchResult {
+func (engine *FzEngine) List(ctx context.Context, page int) (SearchResult, error) {
 	engine.mode = ListMode
 	result := SearchResult{
 		Query: "List of Recent Uploads - Page " + strconv.Itoa(page),
@@ -167,16 +203,12 @@ func (engine *FzEngine) List(page int) SearchResult {
 	q.Set("by", "date")
 	q.Set("pg", strconv.Itoa(page))
 	engine.ListURL.RawQuery = q.Encode()
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, page), err
 }
 
 // Search : Searches fzmovies for a particular query and return an array of movies
-func (engine *FzEngine) Search(param ...string) SearchResult {
+func (engine *FzEngine) Search(ctx context.Context, param ...string) (SearchResult, error) {
 	query := param[0]
 	engine.mode = SearchMode
 	result := SearchResult{
@@ -184,11 +216,8 @@ func (engine *FzEngine) Search(param ...string) SearchResult {
 	}
 	q := engine.SearchURL.Query()
 	q.Set("searchname", query)
+	setPage(q, "pg", param)
 	engine.SearchURL.RawQuery = q.Encode()
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, searchPage(param)), err
 }
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MovieGroup : The copies of the same movie found on several engines or in several
+// qualities, listed once with its best ranked copy as the canonical entry
+type MovieGroup struct {
+	Movie        Movie
+	Alternatives []Movie // the other copies, best quality first
+}
+
+// Copies : Every copy of the movie, the canonical one first
+func (g MovieGroup) Copies() []Movie {
+	return append([]Movie{g.Movie}, g.Alternatives...)
+}
+
+// groupKey : what copies of the same movie share, their normalized title and year
+func groupKey(m Movie) string {
+	title := m.Title
+	if m.CanonicalTitle != "" {
+		title = m.CanonicalTitle
+	}
+	year := m.Year
+	words := strings.Fields(NormalizeTitle(title))
+	// the year is only part of the titles of some engines, but not of titles
+	// such as Blade Runner 2049 (2017)
+	if n := len(words); n > 1 {
+		if y, err := strconv.Atoi(words[n-1]); err == nil && y >= 1900 && y <= 2100 && (year == 0 || year == y) {
+			year = y
+			words = words[:n-1]
+		}
+	}
+	return fmt.Sprintf("%s|%d|%t", strings.Join(words, " "), year, m.IsSeries)
+}
+
+// GroupDuplicates : Group the movies which are copies of the same movie, in the
+// order of their best ranked copy
+func GroupDuplicates(movies []Movie) []MovieGroup {
+	groups := []MovieGroup{}
+	index := map[string]int{}
+	for _, movie := range movies {
+		key := groupKey(movie)
+		if i, ok := index[key]; ok {
+			groups[i].Alternatives = append(groups[i].Alternatives, movie)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, MovieGroup{Movie: movie})
+	}
+	for _, group := range groups {
+		alternatives := group.Alternatives
+		sort.SliceStable(alternatives, func(i, j int) bool {
+			qi, qj := qualityScore(alternatives[i].Quality), qualityScore(alternatives[j].Quality)
+			if qi != qj {
+				return qi > qj
+			}
+			return alternatives[i].SizeBytes > alternatives[j].SizeBytes
+		})
+	}
+	return groups
+}
@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-phie/gophie/transport"
+)
+
+// HealthChecker : Engines which check their source site themselves rather than
+// with a request to their BaseURL
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
+// HealthStatus : whether an engine is up and how long it took to respond
+type HealthStatus struct {
+	Engine    string
+	Up        bool
+	Latency   time.Duration `json:"-"`
+	LatencyMS int64
+	Error     string `json:",omitempty"`
+}
+
+// Health : Check that the source site of an engine responds with a lightweight
+// request to its BaseURL. Returns ErrEngineUnavailable when it does not, or while
+// the engine is skipped after too many failures
+func Health(ctx context.Context, e Engine) error {
+	if checker, ok := e.(HealthChecker); ok {
+		return checker.Health(ctx)
+	}
+	withProps, ok := e.(interface{ getProps() *Props })
+	if !ok || withProps.getProps().BaseURL == nil {
+		return fmt.Errorf("%w: %s has no URL to check", ErrEngineUnavailable, e)
+	}
+	props := withProps.getProps()
+	props.useRememberedMirror()
+	config, err := propsClientConfig(props)
+	if err != nil {
+		return err
+	}
+	// a single attempt so the latency is that of one request
+	config.MaxAttempts = 1
+	clientTransport, err := transport.NewTransport(config)
+	if err != nil {
+		return err
+	}
+	defer clientTransport.CloseIdleConnections()
+	client := &http.Client{Transport: clientTransport}
+
+	status, err := probe(ctx, client, http.MethodHead, props.BaseURL.String())
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		// some sites only answer GET
+		status, err = probe(ctx, client, http.MethodGet, props.BaseURL.String())
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %s: %v", ErrEngineUnavailable, props.Name, err)
+	}
+	if status >= http.StatusBadRequest {
+		return fmt.Errorf("%w: %s returned %d %s", ErrEngineUnavailable, props.Name, status, http.StatusText(status))
+	}
+	return nil
+}
+
+// probe : the status code of a request, the body is not read
+func probe(ctx context.Context, client *http.Client, method, link string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// CheckHealth : Check every engine concurrently, giving each at most timeout to
+// respond. Statuses are sorted by engine name
+func CheckHealth(ctx context.Context, engines map[string]Engine, timeout time.Duration) []HealthStatus {
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := make([]HealthStatus, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string, e Engine) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			err := Health(checkCtx, e)
+			status := HealthStatus{Engine: name, Up: err == nil, Latency: time.Since(start)}
+			status.LatencyMS = status.Latency.Milliseconds()
+			if err != nil {
+				status.Error = err.Error()
+			}
+			statuses[i] = status
+		}(i, name, engines[name])
+	}
+	wg.Wait()
+	return statuses
+}
@@ -1,9 +1,9 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"net/url"
-	"path"
 	"strconv"
 	"strings"
 
@@ -16,6 +16,10 @@ type KDramaHood struct {
 	Props
 }
 
+func init() {
+	RegisterEngine("kdramahood", func() Engine { return NewKDramaHoodEngine() })
+}
+
 // NewKDramaHoodEngine : create a new engine for scraping latest korean drama
 func NewKDramaHoodEngine() *KDramaHood {
 	base := "https://kdramahood.com"
@@ -41,6 +45,9 @@ func NewKDramaHoodEngine() *KDramaHood {
 	dramaFeverEngine.Name = "KDramaHood"
 	dramaFeverEngine.BaseURL = baseURL
 	dramaFeverEngine.Description = `Watch your favourite korean movie all in one place`
+	dramaFeverEngine.Features = Capabilities{Series: true, Pagination: true}
+	dramaFeverEngine.Languages = []string{"ko"}
+	dramaFeverEngine.Regions = []string{"Korea"}
 	dramaFeverEngine.SearchURL = searchURL
 	dramaFeverEngine.ListURL = listURL
 	return &dramaFeverEngine
@@ -77,7 +84,7 @@ func (engine *KDramaHood) parseSingleMovie(el *colly.HTMLElement, index int) (Mo
 	downloadLink, err := url.Parse(link)
 
 	if err != nil {
-		log.Fatal(err)
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 	movie.DownloadLink = downloadLink
 	movie.Category = "kdrama"
@@ -99,7 +106,11 @@ func (engine *KDramaHood) updateDownloadProps(downloadCollector *colly.Collector
 		// create local targets
 		targetepisode := make(map[string]*url.URL)
 		targetsub := make(map[string]*url.URL)
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		e.ForEach("li", func(_ int, inn *colly.HTMLElement) {
 			innerCollector.Visit(inn.ChildAttr("a", "href"))
 		})
@@ -131,23 +142,19 @@ func (engine *KDramaHood) updateDownloadProps(downloadCollector *colly.Collector
 }
 
 // List : list all the movies on a page
-func (engine *KDramaHood) List(page int) SearchResult {
+func (engine *KDramaHood) List(ctx context.Context, page int) (SearchResult, error) {
 	engine.mode = ListMode
 	result := SearchResult{
 		Query: "List of Recent Uploads - Page " + strconv.Itoa(page),
 	}
 	pageParam := fmt.Sprintf("page/%v", strconv.Itoa(page))
-	engine.ListURL.Path = path.Join(engine.ListURL.Path, pageParam)
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	engine.ListURL.Path = engine.listPath(pageParam)
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, page), err
 }
 
 // Search : Searches fzmovies for a particular query and return an array of movies
-func (engine *KDramaHood) Search(param ...string) SearchResult {
+func (engine *KDramaHood) Search(ctx context.Context, param ...string) (SearchResult, error) {
 	query := param[0]
 	engine.mode = SearchMode
 	result := SearchResult{
@@ -155,11 +162,8 @@ func (engine *KDramaHood) Search(param ...string) SearchResult {
 	}
 	q := engine.SearchURL.Query()
 	q.Set("s", query)
+	setPage(q, "paged", param)
 	engine.SearchURL.RawQuery = q.Encode()
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, searchPage(param)), err
 }
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"regexp"
+	"strings"
+)
+
+// language : a spoken language, the words marking it in titles and categories
+// and the film industry its movies are usually from
+type language struct {
+	Code   string // ISO 639-1
+	Name   string
+	Region string
+	Words  []string // lowercase words of titles, categories and tags marking it
+}
+
+// languages : the languages of the movies of the engines
+var languages = []language{
+	{Code: "en", Name: "English", Region: "Hollywood", Words: []string{"english", "hollywood", "international"}},
+	{Code: "hi", Name: "Hindi", Region: "Bollywood", Words: []string{"hindi", "bollywood", "dhollywood", "indian"}},
+	{Code: "ta", Name: "Tamil", Region: "Kollywood", Words: []string{"tamil", "kollywood"}},
+	{Code: "te", Name: "Telugu", Region: "Tollywood", Words: []string{"telugu", "tollywood"}},
+	{Code: "yo", Name: "Yoruba", Region: "Nollywood", Words: []string{"yoruba"}},
+	{Code: "ko", Name: "Korean", Region: "Korea", Words: []string{"korean", "kdrama", "k-drama"}},
+	{Code: "ja", Name: "Japanese", Region: "Japan", Words: []string{"japanese", "anime"}},
+	{Code: "zh", Name: "Chinese", Region: "China", Words: []string{"chinese", "mandarin", "cantonese"}},
+	{Code: "fr", Name: "French", Region: "France", Words: []string{"french"}},
+	{Code: "es", Name: "Spanish", Region: "Spain", Words: []string{"spanish"}},
+}
+
+// regionWords : words marking the film industry of a movie but not its language
+var regionWords = map[string]string{
+	"nollywood": "Nollywood",
+	"nigerian":  "Nollywood",
+	"african":   "Nollywood",
+}
+
+// wordRe : the words of titles, categories and tags
+var wordRe = regexp.MustCompile(`[a-zA-Z-]+`)
+
+// codeRe : an ISO 639-1 code
+var codeRe = regexp.MustCompile(`^[a-z]{2}$`)
+
+// LanguageCode : The ISO 639-1 code of a language given by its code or its name
+// e.g en, english or English, and whether it is a language. Any code is accepted
+// as some sites, such as YTS, tell the language of their movies
+func LanguageCode(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, l := range languages {
+		if name == strings.ToLower(l.Name) {
+			return l.Code, true
+		}
+	}
+	return name, codeRe.MatchString(name)
+}
+
+// ParseLanguage : The language and the film industry marked by the words of s,
+// such as a category "Bollywood" or a title "Parasite (2019) [Korean]", empty
+// when s marks none
+func ParseLanguage(s string) (code, region string) {
+	for _, word := range wordRe.FindAllString(strings.ToLower(s), -1) {
+		if region == "" {
+			region = regionWords[word]
+		}
+		if code != "" {
+			continue
+		}
+		for _, l := range languages {
+			for _, w := range l.Words {
+				if word == w {
+					code = l.Code
+					if region == "" {
+						region = l.Region
+					}
+				}
+			}
+		}
+	}
+	return code, region
+}
+
+// tagLanguage : Set the language and region of a movie scraped by the engine of
+// p from its category, tags and title, else from the usual ones of the engine
+func (p *Props) tagLanguage(m *Movie) {
+	for _, s := range []string{m.Category, m.Tags, m.Title} {
+		code, region := ParseLanguage(s)
+		if m.Language == "" {
+			m.Language = code
+		}
+		if m.Region == "" {
+			m.Region = region
+		}
+	}
+	if m.Language == "" && len(p.Languages) > 0 {
+		m.Language = p.Languages[0]
+	}
+	if m.Region == "" {
+		// movies in a language other than the usual one of the engine are
+		// from the region of their language
+		if len(p.Regions) > 0 && (len(p.Languages) == 0 || m.Language == p.Languages[0]) {
+			m.Region = p.Regions[0]
+		} else {
+			for _, l := range languages {
+				if l.Code == m.Language {
+					m.Region = l.Region
+				}
+			}
+		}
+	}
+	// dubbed anime is in English
+	if m.Translation == Dubbed && m.Language == "ja" {
+		m.Language = "en"
+	}
+}
+
+// FilterByLanguage : Return the movies of the result in one of the languages,
+// given by their ISO 639-1 codes or names. Movies of an unknown language are left out
+func (s *SearchResult) FilterByLanguage(names ...string) SearchResult {
+	codes := map[string]bool{}
+	for _, name := range names {
+		if code, ok := LanguageCode(name); ok {
+			codes[code] = true
+		}
+	}
+	return s.filter(func(movie Movie) bool {
+		return codes[movie.Language]
+	})
+}
@@ -0,0 +1,306 @@
+package engine
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-phie/gophie/transport"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+)
+
+var linksBucket = []byte("links")
+
+// minLinkChecks : checks of the links of an engine before its link rot is trusted
+const minLinkChecks = 10
+
+// LinkCheck : Whether a download link of a movie still works
+type LinkCheck struct {
+	Link   string
+	Key    string // key of the link in SDownloadLink, empty for the DownloadLink
+	Status int    `json:",omitempty"`
+	Dead   bool
+	Error  string `json:",omitempty"`
+}
+
+// LinkReport : The checked links of a movie
+type LinkReport struct {
+	Title  string
+	Source string
+	Links  []LinkCheck
+}
+
+// Dead : whether every checked link of the movie is dead
+func (r LinkReport) Dead() bool {
+	for _, check := range r.Links {
+		if !check.Dead {
+			return false
+		}
+	}
+	return len(r.Links) > 0
+}
+
+// LinkStats : How many of the links of an engine were checked and found dead
+type LinkStats struct {
+	Engine  string
+	Checked int
+	Dead    int
+}
+
+// Rot : the share of the checked links which were dead, 0 until enough were checked
+func (s LinkStats) Rot() float64 {
+	if s.Checked < minLinkChecks {
+		return 0
+	}
+	return float64(s.Dead) / float64(s.Checked)
+}
+
+// linkState : the link statistics of every engine, loaded from and saved to the
+// links database of the cache directory
+var linkState struct {
+	sync.Mutex
+	loaded bool
+	stats  map[string]LinkStats
+}
+
+// linksPath : path of the database of link statistics, empty when there is no
+// cache directory to keep it in
+func linksPath() string {
+	cacheDir := viper.GetString("cache-dir")
+	if cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(cacheDir, "links.db")
+}
+
+// loadLinkStats : load the saved statistics, linkState must be locked
+func loadLinkStats() {
+	if linkState.loaded {
+		return
+	}
+	linkState.loaded = true
+	linkState.stats = map[string]LinkStats{}
+	path := linksPath()
+	if path == "" {
+		return
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		log.Debugf("Could not load link statistics: %v", err)
+		return
+	}
+	defer db.Close()
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(linksBucket)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			if len(v) == 16 {
+				linkState.stats[string(k)] = LinkStats{
+					Engine:  string(k),
+					Checked: int(binary.BigEndian.Uint64(v[:8])),
+					Dead:    int(binary.BigEndian.Uint64(v[8:])),
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		log.Debugf("Could not load link statistics: %v", err)
+	}
+}
+
+// RecordLinkChecks : Add checked links of the engine named source to its statistics,
+// also kept for later runs
+func RecordLinkChecks(source string, checked, dead int) {
+	name := strings.ToLower(source)
+	linkState.Lock()
+	defer linkState.Unlock()
+	loadLinkStats()
+	stats := linkState.stats[name]
+	stats.Engine = name
+	stats.Checked += checked
+	stats.Dead += dead
+	linkState.stats[name] = stats
+
+	path := linksPath()
+	if path == "" {
+		return
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		log.Debugf("Could not save link statistics of %s: %v", name, err)
+		return
+	}
+	defer db.Close()
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(linksBucket)
+		if err != nil {
+			return err
+		}
+		v := make([]byte, 16)
+		binary.BigEndian.PutUint64(v[:8], uint64(stats.Checked))
+		binary.BigEndian.PutUint64(v[8:], uint64(stats.Dead))
+		return b.Put([]byte(name), v)
+	})
+	if err != nil {
+		log.Debugf("Could not save link statistics of %s: %v", name, err)
+	}
+}
+
+// LinkRot : The link statistics of every engine whose links were checked, sorted by engine
+func LinkRot() []LinkStats {
+	linkState.Lock()
+	defer linkState.Unlock()
+	loadLinkStats()
+	stats := make([]LinkStats, 0, len(linkState.stats))
+	for _, s := range linkState.stats {
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Engine < stats[j].Engine })
+	return stats
+}
+
+// linkRotOf : the link rot of the engine named source
+func linkRotOf(source string) float64 {
+	linkState.Lock()
+	defer linkState.Unlock()
+	loadLinkStats()
+	return linkState.stats[strings.ToLower(source)].Rot()
+}
+
+// CheckLink : Check that link still works with a HEAD request, falling back to
+// GET for servers which do not answer HEAD. Links responding with an error
+// status or not responding are dead
+func CheckLink(ctx context.Context, client *http.Client, link string) LinkCheck {
+	check := LinkCheck{Link: link}
+	status, err := probe(ctx, client, http.MethodHead, link)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = probe(ctx, client, http.MethodGet, link)
+	}
+	check.Status = status
+	switch {
+	case err != nil:
+		check.Dead = true
+		check.Error = err.Error()
+	case status >= http.StatusBadRequest:
+		check.Dead = true
+		check.Error = fmt.Sprintf("%d %s", status, http.StatusText(status))
+	}
+	return check
+}
+
+// VerifyLinks : Check the DownloadLink and SDownloadLink of the movies, parallel
+// links at a time with at most timeout for each. Dead links are removed from
+// SDownloadLink and the checks are added to the link statistics of the engines.
+// With prune, movies whose links are all dead are dropped from movies
+func VerifyLinks(ctx context.Context, movies []Movie, parallel int, timeout time.Duration, prune bool) ([]Movie, []LinkReport) {
+	type job struct {
+		movie int
+		check LinkCheck
+	}
+	reports := make([]LinkReport, len(movies))
+	var jobs []job
+	for i, movie := range movies {
+		reports[i] = LinkReport{Title: movie.Title, Source: movie.Source}
+		if movie.DownloadLink != nil {
+			jobs = append(jobs, job{movie: i, check: LinkCheck{Link: movie.DownloadLink.String()}})
+		}
+		keys := make([]string, 0, len(movie.SDownloadLink))
+		for key := range movie.SDownloadLink {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if link := movie.SDownloadLink[key]; link != nil {
+				jobs = append(jobs, job{movie: i, check: LinkCheck{Link: link.String(), Key: key}})
+			}
+		}
+	}
+
+	if parallel <= 0 {
+		parallel = 1
+	}
+	clients := map[string]*http.Client{}
+	for _, movie := range movies {
+		if _, ok := clients[movie.Source]; ok {
+			continue
+		}
+		// links are reached the way the engine reaches its site, e.g through its proxy
+		config, err := clientConfig(movie.Source)
+		if err != nil {
+			log.Warn(err)
+		}
+		config.MaxAttempts = 1
+		clientTransport, err := transport.NewTransport(config)
+		if err != nil {
+			log.Warn(err)
+			clients[movie.Source] = http.DefaultClient
+			continue
+		}
+		defer clientTransport.CloseIdleConnections()
+		clients[movie.Source] = &http.Client{Transport: clientTransport}
+	}
+
+	var wg sync.WaitGroup
+	indexes := make(chan int)
+	for w := 0; w < parallel && w < len(jobs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				checkCtx, cancel := context.WithTimeout(ctx, timeout)
+				check := CheckLink(checkCtx, clients[movies[jobs[i].movie].Source], jobs[i].check.Link)
+				cancel()
+				check.Key = jobs[i].check.Key
+				jobs[i].check = check
+			}
+		}()
+	}
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	if ctx.Err() != nil {
+		// links which could not be checked are not held against the engines
+		return movies, reports
+	}
+
+	checked, dead := map[string]int{}, map[string]int{}
+	for _, j := range jobs {
+		movie := &movies[j.movie]
+		reports[j.movie].Links = append(reports[j.movie].Links, j.check)
+		checked[movie.Source]++
+		if j.check.Dead {
+			dead[movie.Source]++
+			if j.check.Key != "" {
+				delete(movie.SDownloadLink, j.check.Key)
+			}
+		}
+	}
+	for source, n := range checked {
+		RecordLinkChecks(source, n, dead[source])
+	}
+	if !prune {
+		return movies, reports
+	}
+	kept := movies[:0]
+	for i, movie := range movies {
+		if reports[i].Dead() {
+			continue
+		}
+		movie.Index = len(kept)
+		kept = append(kept, movie)
+	}
+	return kept, reports
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	// bracketRe : tags scraped along with titles such as [NetNaija] or {HD}
+	bracketRe = regexp.MustCompile(`\[[^\]]*\]|\{[^}]*\}`)
+	codecRe   = regexp.MustCompile(`(?i)\b(x26[45]|h\.?26[45]|hevc|avc|10bit|aac|mp4|mkv)\b`)
+)
+
+// NormalizeTitle : Fold a title for comparison, dropping bracketed tags,
+// resolution, format and codec markers and punctuation
+// "Joker (2019) [NetNaija] 1080p WEB-DL" becomes "joker 2019"
+func NormalizeTitle(title string) string {
+	title = bracketRe.ReplaceAllString(title, " ")
+	title = resolutionRe.ReplaceAllString(title, " ")
+	title = uhdRe.ReplaceAllString(title, " ")
+	for _, format := range formats {
+		title = format.re.ReplaceAllString(title, " ")
+	}
+	title = codecRe.ReplaceAllString(title, " ")
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// levenshtein : number of single rune edits turning a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// maxTitleDistance : edits allowed for a normalized title to still match,
+// about one typo for every four characters
+func maxTitleDistance(title string) int {
+	return len([]rune(title)) / 4
+}
+
+// titleMatch : how closely a movie title matches a looked up title
+type titleMatch struct {
+	index    int
+	distance int  // edits between the normalized titles
+	contains bool // the normalized title contains the looked up one
+}
+
+// rankTitles : The movies ranked by how closely their title matches title,
+// closest first
+func (s *SearchResult) rankTitles(title string) []titleMatch {
+	wanted := NormalizeTitle(title)
+	matches := make([]titleMatch, len(s.Movies))
+	for i, movie := range s.Movies {
+		normalized := NormalizeTitle(movie.Title)
+		matches[i] = titleMatch{
+			index:    i,
+			distance: levenshtein(wanted, normalized),
+			contains: wanted != "" && strings.Contains(" "+normalized+" ", " "+wanted+" "),
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if (a.distance == 0) != (b.distance == 0) {
+			return a.distance == 0
+		}
+		if a.contains != b.contains {
+			return a.contains
+		}
+		return a.distance < b.distance
+	})
+	return matches
+}
+
+// findTitle : index of the movie with title, an exact title is preferred over
+// the closest normalized one within maxTitleDistance
+func (s *SearchResult) findTitle(title string) (int, bool) {
+	for index, movie := range s.Movies {
+		if movie.Title == title {
+			return index, true
+		}
+	}
+	matches := s.rankTitles(title)
+	if len(matches) == 0 || matches[0].distance > maxTitleDistance(NormalizeTitle(title)) {
+		return 0, false
+	}
+	return matches[0].index, true
+}
+
+// GetClosestMatches : The n movies whose titles are closest to title, closest first
+func (s *SearchResult) GetClosestMatches(title string, n int) []Movie {
+	matches := s.rankTitles(title)
+	if n < len(matches) {
+		matches = matches[:n]
+	}
+	movies := make([]Movie, 0, len(matches))
+	for _, match := range matches {
+		movies = append(movies, s.Movies[match.index])
+	}
+	return movies
+}
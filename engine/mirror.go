@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+)
+
+var mirrorsBucket = []byte("mirrors")
+
+// mirrorState : the base URL that last worked for every engine which failed over
+// to a mirror, loaded from and saved to the mirrors database of the cache directory
+var mirrorState struct {
+	sync.Mutex
+	loaded bool
+	bases  map[string]string
+}
+
+// mirrorsPath : path of the database remembering the working mirrors, empty when
+// there is no cache directory to keep it in
+func mirrorsPath() string {
+	cacheDir := viper.GetString("cache-dir")
+	if cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(cacheDir, "mirrors.db")
+}
+
+// rememberedMirror : the base URL which last worked for the named engine
+func rememberedMirror(name string) string {
+	mirrorState.Lock()
+	defer mirrorState.Unlock()
+	if !mirrorState.loaded {
+		mirrorState.loaded = true
+		mirrorState.bases = map[string]string{}
+		if path := mirrorsPath(); path != "" {
+			if err := loadMirrors(path, mirrorState.bases); err != nil {
+				log.Debugf("Could not load mirrors: %v", err)
+			}
+		}
+	}
+	return mirrorState.bases[strings.ToLower(name)]
+}
+
+// rememberMirror : Keep using base for the named engine, also in later runs
+func rememberMirror(name string, base *url.URL) {
+	rememberedMirror(name) // load the saved mirrors first
+	mirrorState.Lock()
+	defer mirrorState.Unlock()
+	mirrorState.bases[strings.ToLower(name)] = base.String()
+	path := mirrorsPath()
+	if path == "" {
+		return
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		log.Debugf("Could not save mirror of %s: %v", name, err)
+		return
+	}
+	defer db.Close()
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(mirrorsBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(strings.ToLower(name)), []byte(base.String()))
+	})
+	if err != nil {
+		log.Debugf("Could not save mirror of %s: %v", name, err)
+	}
+}
+
+func loadMirrors(path string, bases map[string]string) error {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(mirrorsBucket)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			bases[string(k)] = string(v)
+			return nil
+		})
+	})
+}
+
+// parseMirrors : Parse the mirrors of an engine constructor
+func parseMirrors(mirrors ...string) []*url.URL {
+	urls := make([]*url.URL, 0, len(mirrors))
+	for _, mirror := range mirrors {
+		u, err := url.Parse(mirror)
+		if err != nil {
+			log.Fatal(err)
+		}
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+// baseURLs : the base URL of the engine followed by its mirrors and the mirrors
+// set for it in the mirrors section of the config file
+func (p *Props) baseURLs() []*url.URL {
+	bases := []*url.URL{p.BaseURL}
+	seen := map[string]bool{p.BaseURL.String(): true}
+	add := func(base *url.URL) {
+		if base != nil && !seen[base.String()] {
+			seen[base.String()] = true
+			bases = append(bases, base)
+		}
+	}
+	for _, mirror := range p.Mirrors {
+		add(mirror)
+	}
+	for _, mirror := range viper.GetStringSlice("mirrors." + strings.ToLower(p.Name)) {
+		base, err := url.Parse(mirror)
+		if err != nil || base.Host == "" {
+			log.Warnf("Ignoring invalid mirror %q of %s", mirror, p.Name)
+			continue
+		}
+		add(base)
+	}
+	return bases
+}
+
+// useRememberedMirror : Point the engine at the base URL that last worked and
+// return all its base URLs
+func (p *Props) useRememberedMirror() []*url.URL {
+	bases := p.baseURLs()
+	if remembered := rememberedMirror(p.Name); remembered != "" {
+		for _, base := range bases {
+			if base.String() == remembered {
+				p.useBaseURL(base)
+			}
+		}
+	}
+	return bases
+}
+
+// useBaseURL : Point the engine at another base URL, moving the search and
+// list URLs on the same site along with it
+func (p *Props) useBaseURL(base *url.URL) {
+	if p.BaseURL.String() == base.String() {
+		return
+	}
+	old := p.BaseURL
+	for _, link := range []*url.URL{p.SearchURL, p.ListURL} {
+		if link != nil && link.Host == old.Host {
+			link.Scheme, link.Host = base.Scheme, base.Host
+		}
+	}
+	moved := *base
+	p.BaseURL = &moved
+}
+
+// siteMoved : whether a visit failed like it would for a site which changed domains
+func siteMoved(err error) bool {
+	var failedVisit *visitError
+	if !errors.As(err, &failedVisit) {
+		return false
+	}
+	err = failedVisit.err
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	if errors.As(err, &dnsErr) || (errors.As(err, &opErr) && opErr.Op == "dial") {
+		return true
+	}
+	// colly reports error statuses with their text
+	switch err.Error() {
+	case http.StatusText(http.StatusNotFound), http.StatusText(http.StatusGone):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ScrapeMode : The ordering of the movies listed by an engine
+type ScrapeMode int
+
+const (
+	// Latest : the most recently uploaded movies, listed by every engine
+	Latest ScrapeMode = iota
+	// Trending : the movies most downloaded recently
+	Trending
+	// Popular : the movies most downloaded of all time
+	Popular
+	// TopRated : the movies with the best ratings
+	TopRated
+)
+
+var scrapeModeNames = [...]string{"latest", "trending", "popular", "top_rated"}
+
+func (m ScrapeMode) String() string {
+	if m < 0 || int(m) >= len(scrapeModeNames) {
+		return fmt.Sprintf("ScrapeMode(%d)", int(m))
+	}
+	return scrapeModeNames[m]
+}
+
+// listName : the name of the list of movies in the mode e.g "Trending Movies"
+func (m ScrapeMode) listName() string {
+	switch m {
+	case Trending:
+		return "Trending Movies"
+	case Popular:
+		return "Popular Movies"
+	case TopRated:
+		return "Top Rated Movies"
+	}
+	return "Recent Uploads"
+}
+
+// MarshalText : modes are serialized by name
+func (m ScrapeMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText : parse a mode serialized by name
+func (m *ScrapeMode) UnmarshalText(text []byte) error {
+	mode, err := ParseScrapeMode(string(text))
+	if err == nil {
+		*m = mode
+	}
+	return err
+}
+
+// ParseScrapeMode : The mode named name such as "trending" or "top-rated"
+// An empty name is Latest
+func ParseScrapeMode(name string) (ScrapeMode, error) {
+	name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+	if name == "" {
+		return Latest, nil
+	}
+	for i, modeName := range scrapeModeNames {
+		if name == modeName || name == strings.ReplaceAll(modeName, "_", "") {
+			return ScrapeMode(i), nil
+		}
+	}
+	return Latest, fmt.Errorf("Invalid mode %q, use one of %s", name, strings.Join(scrapeModeNames[:], ", "))
+}
+
+// ModeLister : Engines which list movies in other orders than the latest uploads
+type ModeLister interface {
+	// Modes : the modes the engine lists movies in, including Latest
+	Modes() []ScrapeMode
+	// ListBy : list the movies on a page in a mode returned by Modes
+	ListBy(ctx context.Context, mode ScrapeMode, page int) (SearchResult, error)
+}
+
+// Modes : The modes e lists movies in, every engine lists the Latest movies
+func Modes(e Engine) []ScrapeMode {
+	if lister, ok := e.(ModeLister); ok {
+		return lister.Modes()
+	}
+	return []ScrapeMode{Latest}
+}
+
+// Supports : whether e lists movies in mode
+func Supports(e Engine, mode ScrapeMode) bool {
+	for _, m := range Modes(e) {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// ListBy : List the movies of e on a page in mode. Returns ErrUnsupportedMode
+// when e does not list movies in mode. The movies listed before e failed are
+// returned, see Partial
+func ListBy(ctx context.Context, e Engine, mode ScrapeMode, page int) (SearchResult, error) {
+	if !Supports(e, mode) {
+		return SearchResult{}, fmt.Errorf("%w: %s does not list %s movies", ErrUnsupportedMode, e, mode)
+	}
+	var (
+		result SearchResult
+		err    error
+	)
+	if lister, ok := e.(ModeLister); ok {
+		result, err = lister.ListBy(ctx, mode, page)
+	} else {
+		result, err = e.List(ctx, page)
+	}
+	return Partial(e, result, err)
+}
@@ -1,9 +1,9 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"net/url"
-	"path"
 	"regexp"
 	"strconv"
 	"strings"
@@ -17,6 +17,10 @@ type MyCoolMoviez struct {
 	Props
 }
 
+func init() {
+	RegisterEngine("mycoolmoviez", func() Engine { return NewMyCoolMoviezEngine() })
+}
+
 // NewMyCoolMoviezEngine : create a new engine for scraping mynewcoolmovies
 func NewMyCoolMoviezEngine() *MyCoolMoviez {
 	base := "https://mycoolmoviez.website"
@@ -42,6 +46,8 @@ func NewMyCoolMoviezEngine() *MyCoolMoviez {
 	coolMoviesEngine.Name = "MyCoolMoviez"
 	coolMoviesEngine.BaseURL = baseURL
 	coolMoviesEngine.Description = `MyCoolMoviez is a site that collects movies from across the web in believed to be in a public domain`
+	coolMoviesEngine.Languages = []string{"en"}
+	coolMoviesEngine.Regions = []string{"Hollywood"}
 	coolMoviesEngine.SearchURL = searchURL
 	coolMoviesEngine.ListURL = listURL
 	return &coolMoviesEngine
@@ -74,7 +80,7 @@ func (engine *MyCoolMoviez) parseSingleMovie(el *colly.HTMLElement, index int) (
 	downloadLink, err := url.Parse(el.Request.AbsoluteURL(el.ChildAttr("a", "href")))
 
 	if err != nil {
-		log.Fatal(err)
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 
 	movie.DownloadLink = downloadLink
@@ -83,17 +89,26 @@ func (engine *MyCoolMoviez) parseSingleMovie(el *colly.HTMLElement, index int) (
 
 func (engine *MyCoolMoviez) updateDownloadProps(downloadCollector *colly.Collector, movies *[]Movie) {
 	downloadCollector.OnHTML("img.movie-poster", func(e *colly.HTMLElement) {
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		coverphotolink, err := url.Parse(e.Attr("src"))
 		if err != nil {
-			log.Fatal(err)
+			log.Error(err)
+			return
 		}
 		movie.CoverPhotoLink = coverphotolink.String()
 	})
 
 	downloadCollector.OnHTML("div.panel-body", func(e *colly.HTMLElement) {
 		var genre string
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		listTexts := e.ChildTexts("li")
 		for _, text := range listTexts {
 			if strings.HasPrefix(text, "Description :") {
@@ -107,7 +122,11 @@ func (engine *MyCoolMoviez) updateDownloadProps(downloadCollector *colly.Collect
 	})
 
 	downloadCollector.OnHTML("div.download", func(e *colly.HTMLElement) {
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		listHrefs := e.ChildAttrs("a", "href")
 		for _, link := range listHrefs {
 			if strings.HasPrefix(link, "https://") {
@@ -124,7 +143,11 @@ func (engine *MyCoolMoviez) updateDownloadProps(downloadCollector *colly.Collect
 	})
 
 	downloadCollector.OnHTML(`a[rel="nofollow"]`, func(e *colly.HTMLElement) {
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		if strings.HasPrefix(e.Attr("title"), "Download from") {
 			downloadLink, _ := url.Parse(e.Attr("href"))
 			movie.DownloadLink = downloadLink
@@ -133,23 +156,19 @@ func (engine *MyCoolMoviez) updateDownloadProps(downloadCollector *colly.Collect
 }
 
 // List : list all the movies on a page
-func (engine *MyCoolMoviez) List(page int) SearchResult {
+func (engine *MyCoolMoviez) List(ctx context.Context, page int) (SearchResult, error) {
 	engine.mode = ListMode
 	result := SearchResult{
 		Query: "List of Recent Uploads - Page " + strconv.Itoa(page),
 	}
 	pageParam := fmt.Sprintf("%v/", strconv.Itoa(page-1))
-	engine.ListURL.Path = path.Join(engine.ListURL.Path, pageParam) + "/"
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	engine.ListURL.Path = engine.listPath(pageParam) + "/"
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, page), err
 }
 
 // Search : Searches fzmovies for a particular query and return an array of movies
-func (engine *MyCoolMoviez) Search(param ...string) SearchResult {
+func (engine *MyCoolMoviez) Search(ctx context.Context, param ...string) (SearchResult, error) {
 	query := param[0]
 	engine.mode = SearchMode
 	result := SearchResult{
@@ -158,10 +177,6 @@ func (engine *MyCoolMoviez) Search(param ...string) SearchResult {
 	q := engine.SearchURL.Query()
 	q.Set("movie", query)
 	engine.SearchURL.RawQuery = q.Encode()
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, searchPage(param)), err
 }
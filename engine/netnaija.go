@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,16 +11,24 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gocolly/colly/v2"
 	log "github.com/sirupsen/logrus"
 )
 
+// sabiShareAPI : the API giving the file of a SabiShare download token
+const sabiShareAPI = "https://api.sabishare.com/token/download/"
+
 // NetNaijaEngine : An Engine for  NetNaija
 type NetNaijaEngine struct {
 	Props
 }
 
+func init() {
+	RegisterEngine("netnaija", func() Engine { return NewNetNaijaEngine() })
+}
+
 // NewNetNaijaEngine : A Movie Engine Constructor for NetNaija
 func NewNetNaijaEngine() *NetNaijaEngine {
 	base := "https://www.thenetnaija.com/"
@@ -47,8 +56,12 @@ func NewNetNaijaEngine() *NetNaijaEngine {
 	netNaijaEngine.Description = `
 			Nigerian forum and media download center.
 			Developed and owned by Analike Emmanuel Bridge`
+	netNaijaEngine.Features = Capabilities{Series: true, Pagination: true, SearchByYear: true, AntiBot: true}
+	netNaijaEngine.Languages = []string{"en"}
+	netNaijaEngine.Regions = []string{"Hollywood", "Nollywood"}
 	netNaijaEngine.SearchURL = searchURL
 	netNaijaEngine.ListURL = listURL
+	netNaijaEngine.Mirrors = parseMirrors("https://www.thenetnaija.net/", "https://thenetnaija.com/")
 	return &netNaijaEngine
 }
 
@@ -102,7 +115,7 @@ func (engine *NetNaijaEngine) parseSingleMovie(el *colly.HTMLElement, index int)
 	downloadLink, err := url.Parse(el.ChildAttr("a", "href"))
 
 	if err != nil {
-		log.Fatal(err)
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 
 	if strings.HasPrefix(downloadLink.Path, "/videos/series") {
@@ -133,9 +146,56 @@ func (engine *NetNaijaEngine) getDownloadToken(sabiShareURL string) string {
 	return partsSplitBySlash[index]
 }
 
+// sabiShareLink : the link of the file of the SabiShare token, from its download API
+func sabiShareLink(ctx context.Context, client *http.Client, token string) (*url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sabiShareAPI+token, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var downloadResp struct {
+		Status int `json:"status"`
+		Data   struct {
+			URL string `json:"url"`
+		} `json:"data"`
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &downloadResp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if downloadResp.Status != http.StatusOK || downloadResp.Data.URL == "" {
+		return nil, fmt.Errorf("%w for token %s", ErrNoDirectLink, token)
+	}
+	return url.Parse(downloadResp.Data.URL)
+}
+
+// ResolveLink : Get the file of SabiShare links from the token API, the links of
+// the file the API gives expire so that downloads resolve them again
+func (engine *NetNaijaEngine) ResolveLink(ctx context.Context, client *http.Client, link *url.URL) (*url.URL, time.Time, error) {
+	if !strings.Contains(link.Host, "sabishare") {
+		return (&PageResolver{}).ResolveLink(ctx, client, link)
+	}
+	direct, err := sabiShareLink(ctx, client, engine.getDownloadToken(link.String()))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return direct, LinkExpiry(direct, time.Now()), nil
+}
+
 func (engine *NetNaijaEngine) updateDownloadProps(downloadCollector *colly.Collector, movies *[]Movie) {
+	// the token API is reached like the site, through the proxy or Tor
+	client := http.DefaultClient
+	if rt, err := newClientTransport(engine.Name); err == nil {
+		client = &http.Client{Transport: rt}
+	}
 
-	sabiShareAPI := "https://api.sabishare.com/token/download/"
 	sabiShareURL := ""
 
 	downloadCollector.OnHTML(`meta[property="og:url"]`, func(e *colly.HTMLElement) {
@@ -153,44 +213,43 @@ func (engine *NetNaijaEngine) updateDownloadProps(downloadCollector *colly.Colle
 	downloadCollector.OnScraped(func(r *colly.Response) {
 		// Do this operation only when we are on the download page.
 		if strings.HasSuffix(r.Request.URL.Path, "download") {
-			movieIndex := getMovieIndexFromCtx(r.Request)
+			movieIndex, err := getMovieIndexFromCtx(r.Request)
+			if err != nil {
+				log.Error(err)
+				return
+			}
 			movie := &((*movies)[movieIndex])
 			// Start by setting the default downloadURL to the sabiShare URL
 			downloadURL, _ := url.Parse(sabiShareURL)
 			movie.DownloadLink = downloadURL
 
-			token := engine.getDownloadToken(sabiShareURL)
-			resp, tokenErr := http.Get(fmt.Sprintf("%s%s", sabiShareAPI, token))
-			type DownloadResponse struct {
-				Status int `json:"status"`
-				Data   struct {
-					URL string `json:"url"`
-				} `json:"data"`
-			}
-
-			var downloadResp DownloadResponse
-
-			defer resp.Body.Close()
-			body, err := io.ReadAll(resp.Body)
-			err = json.Unmarshal(body, &downloadResp)
-			if tokenErr == nil {
-				if err == nil && downloadResp.Status == 200 {
-					downloadURL, _ := url.Parse(downloadResp.Data.URL)
-					movie.DownloadLink = downloadURL
-					sabiShareURL = ""
-				}
+			downloadURL, err = sabiShareLink(context.Background(), client, engine.getDownloadToken(sabiShareURL))
+			if err != nil {
+				log.Debugf("Could not get the download link of %s: %v", movie.Title, err)
+				return
 			}
+			movie.DownloadLink = downloadURL
+			sabiShareURL = ""
 		}
 	})
 
 	// Update movie size
 	downloadCollector.OnHTML("div.file-size", func(e *colly.HTMLElement) {
-		(*movies)[getMovieIndexFromCtx(e.Request)].Size = strings.TrimSpace(e.ChildText("span.size-number"))
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		movie.Size = strings.TrimSpace(e.ChildText("span.size-number"))
 	})
 
 	// Fetch Movie details from movie detail page
 	downloadCollector.OnHTML("article.post-body", func(e *colly.HTMLElement) {
-		movieIndex := getMovieIndexFromCtx(e.Request)
+		movieIndex, err := getMovieIndexFromCtx(e.Request)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		movie := &((*movies)[movieIndex])
 		description := e.ChildText("p")
 		if description != "" {
@@ -240,39 +299,41 @@ func (engine *NetNaijaEngine) updateDownloadProps(downloadCollector *colly.Colle
 
 	//for series or parts
 	downloadCollector.OnHTML("div.video-series-latest-episodes", func(inn *colly.HTMLElement) {
-		movie := &((*movies)[getMovieIndexFromCtx(inn.Request)])
+		movie, err := getMovieFromCtx(inn.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		movie.IsSeries = true
 		video_map := map[string]*url.URL{}
 		inn.ForEach("a", func(num int, e *colly.HTMLElement) {
 			downloadLink, err := url.Parse(e.Attr("href"))
 			if err != nil {
-				log.Fatal(err)
+				log.Error(err)
+				return
 			}
 			downloadLink.Path = path.Join(downloadLink.Path, "download")
 			video_map[strconv.Itoa(num)] = downloadLink
+			movie.AddEpisode(1, Episode{Number: num + 1, Title: strings.TrimSpace(e.Text), DownloadLink: downloadLink})
 		})
 		movie.SDownloadLink = video_map
 	})
 }
 
 // List : list all the movies on a page
-func (engine *NetNaijaEngine) List(page int) SearchResult {
+func (engine *NetNaijaEngine) List(ctx context.Context, page int) (SearchResult, error) {
 	engine.mode = ListMode
 	result := SearchResult{
 		Query: "List of Recent Uploads - Page " + strconv.Itoa(page),
 	}
 	pageParam := fmt.Sprintf("page/%v", strconv.Itoa(page))
-	engine.ListURL.Path = path.Join(engine.ListURL.Path, pageParam)
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	engine.ListURL.Path = engine.listPath(pageParam)
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, page), err
 }
 
 // Search : Searches netnaija for a particular query and return an array of movies
-func (engine *NetNaijaEngine) Search(param ...string) SearchResult {
+func (engine *NetNaijaEngine) Search(ctx context.Context, param ...string) (SearchResult, error) {
 	query := param[0]
 	engine.mode = SearchMode
 	result := SearchResult{
@@ -281,11 +342,8 @@ func (engine *NetNaijaEngine) Search(param ...string) SearchResult {
 	q := engine.SearchURL.Query()
 	q.Set("t", query)
 	q.Set("folder", "videos")
+	setPage(q, "page", param)
 	engine.SearchURL.RawQuery = q.Encode()
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, searchPage(param)), err
 }
@@ -1,9 +1,9 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"net/url"
-	"path"
 	"regexp"
 	"strconv"
 	"strings"
@@ -18,6 +18,10 @@ type NkiriEngine struct {
 	ListCategories []string
 }
 
+func init() {
+	RegisterEngine("nkiri", func() Engine { return NewNkiriEngine() })
+}
+
 // NewNkiriEngine : A Movie Engine Constructor for Nkiri
 func NewNkiriEngine() *NkiriEngine {
 	base := "https://nkiri.com/"
@@ -41,6 +45,9 @@ func NewNkiriEngine() *NkiriEngine {
 	nkiriEngine.Name = "Nkiri"
 	nkiriEngine.BaseURL = baseURL
 	nkiriEngine.Description = `Nkiri is an entertainment website where you can download Hollywood, Korean, Chinese and other movies, TV Series and Dramas freely and easily.`
+	nkiriEngine.Features = Capabilities{Series: true, Pagination: true, SearchByYear: true}
+	nkiriEngine.Languages = []string{"en", "ko", "hi", "zh"}
+	nkiriEngine.Regions = []string{"Hollywood", "Nollywood", "Korea", "Bollywood", "China"}
 	nkiriEngine.SearchURL = searchURL
 	nkiriEngine.ListURL = listURL
 	nkiriEngine.ListCategories = []string{
@@ -81,7 +88,7 @@ func (engine *NkiriEngine) parseSingleMovie(el *colly.HTMLElement, index int) (M
 	yearRe := regexp.MustCompile(`\((.*)\)`)
 	removeCaratRe, err := regexp.Compile(`[^\w()]`)
 	if err != nil {
-		log.Fatal(err)
+		return Movie{}, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 	movie := Movie{
 		Index:    index,
@@ -91,8 +98,8 @@ func (engine *NkiriEngine) parseSingleMovie(el *colly.HTMLElement, index int) (M
 	movie.CoverPhotoLink = el.ChildAttr("img", "src")
 	// Split with '|': Title at Index 0
 	titleSplit := strings.Split(el.ChildText("h2"), " | ")
-	if len(titleSplit) >= 1 {
-		movie.Title = removeCaratRe.ReplaceAllString(titleSplit[0], "_")
+	movie.Title = removeCaratRe.ReplaceAllString(titleSplit[0], "_")
+	if len(titleSplit) > 1 {
 		movie.Category = strings.TrimSuffix(strings.TrimPrefix(titleSplit[1], "Download"), "Movie")
 	}
 	//Fetch UploadDate for ListMode Items
@@ -102,7 +109,7 @@ func (engine *NkiriEngine) parseSingleMovie(el *colly.HTMLElement, index int) (M
 	//Fetch DownloadLink
 	downloadLink, err := url.Parse(el.ChildAttr("a", "href"))
 	if err != nil {
-		log.Fatal(err)
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 	movie.DownloadLink = downloadLink
 	if movie.Title != "" {
@@ -120,7 +127,11 @@ func (engine *NkiriEngine) parseSingleMovie(el *colly.HTMLElement, index int) (M
 func (engine *NkiriEngine) updateDownloadProps(downloadCollector *colly.Collector, movies *[]Movie) {
 	sizeRe := regexp.MustCompile(`(\d.*)`)
 	downloadCollector.OnHTML("div.elementor-section-wrap", func(e *colly.HTMLElement) {
-		movieIndex := getMovieIndexFromCtx(e.Request)
+		movieIndex, err := getMovieIndexFromCtx(e.Request)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		movie := &((*movies)[movieIndex])
 		seriesMap := map[string]*url.URL{}
 		episode := 0
@@ -129,17 +140,38 @@ func (engine *NkiriEngine) updateDownloadProps(downloadCollector *colly.Collecto
 			switch {
 			//Fetch Download Link For Series
 			case strings.HasPrefix(inner.ChildText("span.elementor-button-text"), "Download Episode"):
-				episode++
 				downloadLink, err := url.Parse(inner.ChildAttr("div.elementor-button-wrapper > a", "href"))
 				if err != nil {
-					log.Fatal(err)
+					log.Error(err)
+					return
+				}
+				// buttons are labelled "Download Episode 05" or "Download Episodes 01-12"
+				// for batches, unnumbered buttons follow the previous episode
+				first, last := ParseEpisodeRange(inner.ChildText("span.elementor-button-text"))
+				if first == 0 {
+					first, last = episode+1, episode+1
+				}
+				label := strconv.Itoa(first)
+				if last > first {
+					label += "-" + strconv.Itoa(last)
 				}
-				seriesMap[strconv.Itoa(episode)] = downloadLink
+				seriesMap[label] = downloadLink
+				season, _ := ParseEpisodeLabel(movie.Title)
+				if season == 0 {
+					season = 1
+				}
+				ep := Episode{Number: first, DownloadLink: downloadLink}
+				if last > first {
+					ep.LastNumber = last
+				}
+				movie.AddEpisode(season, ep)
+				episode = last
 			//Fetch DownloadLink For Movies
 			case strings.HasPrefix(inner.ChildText("span.elementor-button-text"), "Download Movie"):
 				downloadLink, err := url.Parse(inner.ChildAttr("div.elementor-button-wrapper > a", "href"))
 				if err != nil {
-					log.Fatal(err)
+					log.Error(err)
+					return
 				}
 				movie.DownloadLink = downloadLink
 			}
@@ -168,28 +200,31 @@ func (engine *NkiriEngine) updateDownloadProps(downloadCollector *colly.Collecto
 }
 
 // List : list all the movies on a page
-func (engine *NkiriEngine) List(page int) SearchResult {
+func (engine *NkiriEngine) List(ctx context.Context, page int) (SearchResult, error) {
 	engine.mode = ListMode
 	result := SearchResult{
 		Query: "List of Recent Uploads - Page " + strconv.Itoa(page),
 	}
 	pageParam := fmt.Sprintf("page/%v", strconv.Itoa(page))
 	movies := []Movie{}
-	listCategoryPath := engine.ListURL.Path
+	// there is a next page while any category has one
+	var pages pagination
 	for _, category := range engine.ListCategories {
-		engine.ListURL.Path = path.Join(listCategoryPath, category, pageParam)
-		listResult, err := Scrape(engine)
+		engine.ListURL.Path = engine.listPath(category, pageParam)
+		listResult, err := Scrape(ctx, engine)
+		movies = append(movies, listResult...)
+		pages = pages.merge(engine.pagination)
 		if err != nil {
-			log.Fatal(err)
+			result.Movies = movies
+			return result, err
 		}
-		movies = append(movies, listResult...)
 	}
-	result.Movies = movies
-	return result
+	engine.pagination = pages
+	return engine.paged(result, movies, page), nil
 }
 
 // Search : Searches nkiri for a particular query and return an array of movies
-func (engine *NkiriEngine) Search(param ...string) SearchResult {
+func (engine *NkiriEngine) Search(ctx context.Context, param ...string) (SearchResult, error) {
 	query := param[0]
 	engine.mode = SearchMode
 	result := SearchResult{
@@ -198,11 +233,8 @@ func (engine *NkiriEngine) Search(param ...string) SearchResult {
 	q := engine.SearchURL.Query()
 	q.Set("s", query)
 	q.Set("post_type", "post")
+	setPage(q, "paged", param)
 	engine.SearchURL.RawQuery = q.Encode()
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, searchPage(param)), err
 }
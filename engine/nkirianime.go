@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+)
+
+// NkiriAnime : An Engine for the anime section of Nkiri
+// Anime are listed as series whose episodes may be batch downloads, and are
+// tagged as subbed or dubbed from their titles
+type NkiriAnime struct {
+	NkiriEngine
+}
+
+func init() {
+	RegisterEngine("nkirianime", func() Engine { return NewNkiriAnime() })
+}
+
+// NewNkiriAnime : A Movie Engine Constructor for the anime section of Nkiri
+func NewNkiriAnime() *NkiriAnime {
+	nkiriAnime := NkiriAnime{NkiriEngine: *NewNkiriEngine()}
+	nkiriAnime.Name = "NkiriAnime"
+	nkiriAnime.Description = `The anime section of Nkiri, with subbed and dubbed anime series and episode batches to download.`
+	nkiriAnime.ListCategories = []string{"anime"}
+	return &nkiriAnime
+}
+
+func (engine *NkiriAnime) String() string {
+	return fmt.Sprintf("%s (%s)", engine.Name, engine.BaseURL)
+}
+
+// Search : Searches the anime section of nkiri for a particular query
+func (engine *NkiriAnime) Search(ctx context.Context, param ...string) (SearchResult, error) {
+	q := engine.SearchURL.Query()
+	q.Set("category_name", "anime")
+	engine.SearchURL.RawQuery = q.Encode()
+	return engine.NkiriEngine.Search(ctx, param...)
+}
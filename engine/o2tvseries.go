@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+// O2TvSeriesEngine : An Engine for O2TvSeries
+// Series are listed with a page per season and a page per episode, which are
+// walked to fill in the Seasons of each series
+type O2TvSeriesEngine struct {
+	Props
+}
+
+func init() {
+	RegisterEngine("o2tvseries", func() Engine { return NewO2TvSeriesEngine() })
+}
+
+var (
+	o2SeasonRe  = regexp.MustCompile(`(?i)^season\s*(\d{1,2})\b`)
+	o2EpisodeRe = regexp.MustCompile(`(?i)^episode\s*(\d{1,3})\b`)
+	o2YearRe    = regexp.MustCompile(`\((\d{4})\)`)
+	o2SizeRe    = regexp.MustCompile(`\(\s*(\d[\d.,]*\s*[KMG]i?B)\s*\)`)
+	// the files of an episode are named after the video format e.g "MP4" or "HD"
+	o2FileRe = regexp.MustCompile(`(?i)\.(mp4|mkv|avi|3gp|webm)\b`)
+)
+
+// NewO2TvSeriesEngine : A Movie Engine Constructor for O2TvSeries
+func NewO2TvSeriesEngine() *O2TvSeriesEngine {
+	base := "https://o2tvseries.com/"
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Search URL
+	searchURL, err := url.Parse(base)
+	if err != nil {
+		log.Fatal(err)
+	}
+	searchURL.Path = "/search"
+
+	// List URL
+	listURL, err := url.Parse(base)
+	if err != nil {
+		log.Fatal(err)
+	}
+	listURL.Path = "/latest-updates"
+
+	o2Engine := O2TvSeriesEngine{}
+	o2Engine.Name = "O2TvSeries"
+	o2Engine.BaseURL = baseURL
+	o2Engine.Description = `O2TvSeries is a site dedicated to TV series, with every season and episode of a show available for download`
+	o2Engine.Features = Capabilities{Series: true, Pagination: true}
+	o2Engine.Languages = []string{"en"}
+	o2Engine.Regions = []string{"Hollywood"}
+	o2Engine.SearchURL = searchURL
+	o2Engine.ListURL = listURL
+	return &o2Engine
+}
+
+// Engine Interface Methods
+
+func (engine *O2TvSeriesEngine) String() string {
+	return fmt.Sprintf("%s (%s)", engine.Name, engine.BaseURL)
+}
+
+func (engine *O2TvSeriesEngine) getParseAttrs() (string, string, error) {
+	return "div.data_list", "div.data", nil
+}
+
+func (engine *O2TvSeriesEngine) parseSingleMovie(el *colly.HTMLElement, index int) (Movie, error) {
+	movie := Movie{
+		Index:    index,
+		IsSeries: true,
+		Source:   engine.Name,
+	}
+	title := strings.TrimSpace(el.ChildText("a"))
+	if title == "" {
+		return movie, fmt.Errorf("%w: series without a title", ErrParseFailure)
+	}
+	// recent uploads are listed as "Show - Season 02 - Episode 05", the show is scraped
+	if i := strings.Index(title, " - Season"); i > 0 {
+		title = title[:i]
+	}
+	movie.Title = title
+	movie.CoverPhotoLink = el.Request.AbsoluteURL(el.ChildAttr("img", "src"))
+	movie.Description = strings.TrimSpace(el.ChildText("div.series_info"))
+	if match := o2YearRe.FindStringSubmatch(title); match != nil {
+		movie.Year, _ = strconv.Atoi(match[1])
+	}
+	link := el.ChildAttr("a", "href")
+	if engine.mode == ListMode {
+		// the show is the page above the season of the uploaded episode
+		if i := strings.Index(strings.ToLower(link), "/season-"); i > 0 {
+			link = link[:i] + "/index.html"
+		}
+	}
+	downloadLink, err := url.Parse(el.Request.AbsoluteURL(link))
+	if err != nil {
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
+	}
+	movie.DownloadLink = downloadLink
+	return movie, nil
+}
+
+func (engine *O2TvSeriesEngine) updateDownloadProps(downloadCollector *colly.Collector, movies *[]Movie) {
+	downloadCollector.OnHTML("div.data_list", func(e *colly.HTMLElement) {
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		e.ForEach("div.data", func(_ int, el *colly.HTMLElement) {
+			label := strings.TrimSpace(el.ChildText("a"))
+			link := el.Request.AbsoluteURL(el.ChildAttr("a", "href"))
+			switch {
+			case o2SeasonRe.MatchString(label):
+				// the pages of seasons and episodes are visited in order so the
+				// numbers in the context belong to the page being scraped
+				season := o2SeasonRe.FindStringSubmatch(label)[1]
+				e.Request.Ctx.Put("season", season)
+				e.Request.Visit(link)
+			case o2EpisodeRe.MatchString(label):
+				e.Request.Ctx.Put("episode", o2EpisodeRe.FindStringSubmatch(label)[1])
+				e.Request.Ctx.Put("episodeTitle", strings.TrimSpace(o2EpisodeRe.ReplaceAllString(label, "")))
+				e.Request.Visit(link)
+			case o2FileRe.MatchString(label):
+				season, _ := strconv.Atoi(e.Request.Ctx.Get("season"))
+				number, _ := strconv.Atoi(e.Request.Ctx.Get("episode"))
+				if season == 0 || number == 0 || movie.hasEpisode(season, number) {
+					// only the first format of an episode is kept
+					return
+				}
+				downloadLink, err := url.Parse(link)
+				if err != nil {
+					log.Error(err)
+					return
+				}
+				episode := Episode{
+					Number:       number,
+					Title:        strings.Trim(e.Request.Ctx.Get("episodeTitle"), " -"),
+					DownloadLink: downloadLink,
+				}
+				if match := o2SizeRe.FindStringSubmatch(el.Text); match != nil {
+					episode.Size = match[1]
+				}
+				movie.AddEpisode(season, episode)
+			}
+		})
+	})
+}
+
+// List : list the series with new episodes on a page
+func (engine *O2TvSeriesEngine) List(ctx context.Context, page int) (SearchResult, error) {
+	engine.mode = ListMode
+	result := SearchResult{
+		Query: "List of Recent Uploads - Page " + strconv.Itoa(page),
+	}
+	q := engine.ListURL.Query()
+	q.Set("page", strconv.Itoa(page))
+	engine.ListURL.RawQuery = q.Encode()
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, page), err
+}
+
+// Search : Searches o2tvseries for a series and return its seasons and episodes
+func (engine *O2TvSeriesEngine) Search(ctx context.Context, param ...string) (SearchResult, error) {
+	query := param[0]
+	engine.mode = SearchMode
+	result := SearchResult{
+		Query: query,
+	}
+	q := engine.SearchURL.Query()
+	q.Set("q", query)
+	setPage(q, "page", param)
+	engine.SearchURL.RawQuery = q.Encode()
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, searchPage(param)), err
+}
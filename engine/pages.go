@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-phie/gophie/logging"
+	"github.com/gocolly/colly/v2"
+)
+
+// MaxPages : most result pages walked by SearchPages when all pages are requested
+const MaxPages = 20
+
+// setPage : Set the page of a search in q from the optional page in param,
+// page 1 is left out as that is what sites show without one
+func setPage(q url.Values, key string, param []string) {
+	if len(param) > 1 && param[1] != "1" {
+		q.Set(key, param[1])
+	} else {
+		q.Del(key)
+	}
+}
+
+// searchPage : the page of a search from its optional page in param
+func searchPage(param []string) int {
+	if len(param) > 1 {
+		if page, err := strconv.Atoi(param[1]); err == nil && page > 0 {
+			return page
+		}
+	}
+	return 1
+}
+
+// paginationSelector : the elements sites link their result pages with, the
+// next page link of the document head and the page lists of WordPress and others
+const paginationSelector = `link[rel=next], a[rel=next], .pagination a, .page-numbers, .wp-pagenavi a, .nav-links a`
+
+// listPath : The path of ListURL the engine was created with joined with elem,
+// so that listing pages one after the other on the same engine does not add the
+// page of one to the path of the next
+func (p *Props) listPath(elem ...string) string {
+	if p.listBase == nil {
+		base := p.ListURL.Path
+		p.listBase = &base
+	}
+	return path.Join(append([]string{*p.listBase}, elem...)...)
+}
+
+// pagination : the pages linked by a scraped page
+type pagination struct {
+	linked bool // the page links other result pages
+	next   bool // the page links the next one
+	last   int  // highest page number linked
+}
+
+// add : the page link e to the pages linked
+func (p *pagination) add(e *colly.HTMLElement) {
+	p.linked = true
+	text := strings.TrimSpace(e.Text)
+	class := " " + e.Attr("class") + " "
+	switch {
+	case e.Attr("rel") == "next", strings.Contains(class, " next "), strings.HasPrefix(strings.ToLower(text), "next"),
+		text == "»", text == "›", text == ">>":
+		p.next = true
+	}
+	if page, err := strconv.Atoi(strings.ReplaceAll(text, ",", "")); err == nil && page > p.last {
+		p.last = page
+	}
+}
+
+// merge : the pages linked by either p or other
+func (p pagination) merge(other pagination) pagination {
+	if other.last > p.last {
+		p.last = other.last
+	}
+	p.linked = p.linked || other.linked
+	p.next = p.next || other.next
+	return p
+}
+
+// paged : result with the movies scraped for page and the pages linked by the
+// site. Where the site does not link its pages, engines with pagination are
+// taken to have a next page as long as a page has movies
+func (p *Props) paged(result SearchResult, movies []Movie, page int) SearchResult {
+	result.Movies = movies
+	result.Page = page
+	result.Errors = append(result.Errors, p.errors...)
+	pages := p.pagination
+	if pages.linked {
+		result.HasNext = pages.next || pages.last > page
+		if pages.last >= page {
+			result.TotalPages = pages.last
+		}
+	} else {
+		result.HasNext = p.Features.Pagination && len(movies) > 0
+	}
+	if result.HasNext {
+		result.NextCursor = strconv.Itoa(page + 1)
+	}
+	return result
+}
+
+// pageKey : what tells movies of different result pages apart
+func pageKey(movie Movie) string {
+	if movie.DownloadLink != nil {
+		return movie.DownloadLink.String()
+	}
+	return movie.Source + "\x00" + movie.Title
+}
+
+// SearchPages : Search e for query walking the result pages of the site and
+// merging them into one SearchResult with the Index renumbered across pages.
+// At most pages pages are fetched, or MaxPages when pages is 0 or less. It stops
+// at the last page of the site, or at the first page without new movies, which
+// is where sites without paging repeat their first page. Failures after the
+// first page end the walk early
+func SearchPages(ctx context.Context, e Engine, query string, pages int) (SearchResult, error) {
+	if pages <= 0 || pages > MaxPages {
+		pages = MaxPages
+	}
+	q, err := ParseSearch(query)
+	if err != nil {
+		return SearchResult{Query: query}, err
+	}
+	if q.Filtered() {
+		ctx, query = WithQuery(ctx, q), q.searchText(e)
+	}
+	var (
+		results []SearchResult
+		errs    []EngineError
+	)
+	seen := map[string]bool{}
+	for page := 1; page <= pages; page++ {
+		param := []string{query}
+		if page > 1 {
+			param = append(param, strconv.Itoa(page))
+		}
+		result, err := e.Search(ctx, param...)
+		if err != nil {
+			if page == 1 {
+				return Partial(e, result, err)
+			}
+			logging.FromContext(ctx).WithField("engine", e.String()).Warnf("Stopped at page %d: %v", page, err)
+			errs = append(errs, EngineError{Engine: registryName(e), Stage: SearchStage, Message: fmt.Sprintf("page %d: %v", page, err)})
+			break
+		}
+		found := false
+		for _, movie := range result.Movies {
+			if key := pageKey(movie); !seen[key] {
+				seen[key] = true
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+		results = append(results, result)
+		if result.TotalPages > 0 && !result.HasNext {
+			break
+		}
+	}
+	if q.Filtered() {
+		query = q.Text
+	}
+	merged := MergeResults(query, results...)
+	merged.Errors = append(merged.Errors, errs...)
+	if q.Filtered() {
+		return q.Filter(merged), nil
+	}
+	return merged, nil
+}
@@ -3,17 +3,26 @@ package engine
 import (
 	"encoding/json"
 	"net/url"
+	"sort"
+	"strings"
 )
 
 // Props : The scraping engine Properties and description about the engine (e.g NetNaijaEngine)
 type Props struct {
 	// Struct attributes
 	Name        string
-	BaseURL     *url.URL // The Base URL for the engine
-	SearchURL   *url.URL // URL for searching
-	ListURL     *url.URL // URL to return movie lists
+	BaseURL     *url.URL   // The Base URL for the engine
+	SearchURL   *url.URL   // URL for searching
+	ListURL     *url.URL   // URL to return movie lists
+	Mirrors     []*url.URL // Other base URLs of the site, tried when the BaseURL stops resolving or responding
 	Description string
-	mode        Mode // The mode of the operations (list, search)
+	Languages   []string      // ISO 639-1 codes of the languages of the movies, the first for movies whose language is not shown
+	Regions     []string      // film industries or countries the movies are from, the first for movies whose region is not shown
+	Features    Capabilities  `json:"Capabilities"` // What the source site supports
+	mode        Mode          // The mode of the operations (list, search)
+	pagination  pagination    // The pages linked by the page scraped last
+	errors      []EngineError // The failures of the scrape made last which did not stop it
+	listBase    *string       // The path of ListURL before pages were listed, see listPath
 }
 
 // PropsJSON : JSON structure of all downloadable movies
@@ -22,6 +31,7 @@ type PropsJSON struct {
 	BaseURL   string
 	SearchURL string
 	ListURL   string
+	Mirrors   []string
 }
 
 // MarshalJSON Props structure to return from api
@@ -31,11 +41,20 @@ func (p *Props) MarshalJSON() ([]byte, error) {
 		BaseURL:   p.BaseURL.String(),
 		SearchURL: p.SearchURL.String(),
 		ListURL:   p.ListURL.String(),
+		Mirrors:   []string{},
+	}
+	for _, mirror := range p.Mirrors {
+		props.Mirrors = append(props.Mirrors, mirror.String())
 	}
 
 	return json.Marshal(props)
 }
 
+// getProps : the properties of engines embedding Props
+func (p *Props) getProps() *Props {
+	return p
+}
+
 func (p *Props) getParseURL() *url.URL {
 	if p.mode == SearchMode {
 		return p.SearchURL
@@ -46,3 +65,29 @@ func (p *Props) getParseURL() *url.URL {
 func (p *Props) getName() string {
 	return p.Name
 }
+
+// Hosts : The hosts of the sites of every enabled engine and of their mirrors,
+// without their www. prefix
+func Hosts() []string {
+	seen := map[string]bool{}
+	var hosts []string
+	for _, e := range GetEngines() {
+		p, ok := e.(interface{ getProps() *Props })
+		if !ok {
+			continue
+		}
+		props := p.getProps()
+		for _, u := range append([]*url.URL{props.BaseURL}, props.Mirrors...) {
+			if u == nil {
+				continue
+			}
+			host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+			if host != "" && !seen[host] {
+				seen[host] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Quality : resolution and release format of a movie e.g 1080p WEB-DL
+// It is serialized as a string for compatibility with clients of the API
+type Quality struct {
+	Resolution string // 480p, 576p, 720p, 1080p or 2160p
+	Format     string // WEB-DL, WEBRip, BluRay, HDRip, DVDRip, HDTV or CAM
+	Raw        string // quality given by the source site when it could not be parsed
+}
+
+var (
+	resolutionRe = regexp.MustCompile(`(?i)\b(480|576|720|1080|2160)p\b`)
+	uhdRe        = regexp.MustCompile(`(?i)\b(4k|uhd)\b`)
+	formats      = []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		{"WEB-DL", regexp.MustCompile(`(?i)\bweb-?dl\b`)},
+		{"WEBRip", regexp.MustCompile(`(?i)\bweb-?rip\b`)},
+		{"BluRay", regexp.MustCompile(`(?i)\b(blu-?ray|bdrip|brrip)\b`)},
+		{"HDRip", regexp.MustCompile(`(?i)\bhdrip\b`)},
+		{"DVDRip", regexp.MustCompile(`(?i)\b(dvdrip|dvdscr)\b`)},
+		{"HDTV", regexp.MustCompile(`(?i)\bhdtv\b`)},
+		{"CAM", regexp.MustCompile(`(?i)\b(cam|camrip|hdcam)\b`)},
+	}
+)
+
+// ParseQuality : Parse the quality markers of a title or quality string such as
+// "Joker (2019) 1080p WEB-DL"
+func ParseQuality(s string) Quality {
+	var q Quality
+	if match := resolutionRe.FindStringSubmatch(s); match != nil {
+		q.Resolution = match[1] + "p"
+	} else if uhdRe.MatchString(s) {
+		q.Resolution = "2160p"
+	}
+	for _, format := range formats {
+		if format.re.MatchString(s) {
+			q.Format = format.name
+			break
+		}
+	}
+	if q.IsZero() {
+		q.Raw = strings.TrimSpace(s)
+	}
+	return q
+}
+
+// IsZero : whether nothing is known about the quality
+func (q Quality) IsZero() bool {
+	return q.Resolution == "" && q.Format == "" && q.Raw == ""
+}
+
+func (q Quality) String() string {
+	if q.Resolution == "" && q.Format == "" {
+		return q.Raw
+	}
+	return strings.TrimSpace(q.Resolution + " " + q.Format)
+}
+
+// Matches : whether the quality has the resolution (1080p, 4k) or format (BluRay, web-dl) filter
+func (q Quality) Matches(filter string) bool {
+	wanted := ParseQuality(filter)
+	switch {
+	case wanted.Resolution != "" && wanted.Format != "":
+		return wanted.Resolution == q.Resolution && wanted.Format == q.Format
+	case wanted.Resolution != "":
+		return wanted.Resolution == q.Resolution
+	case wanted.Format != "":
+		return wanted.Format == q.Format
+	}
+	return strings.EqualFold(strings.TrimSpace(filter), q.Raw)
+}
+
+// merge : fill in the parts of q which are not known from other
+func (q Quality) merge(other Quality) Quality {
+	if q.Resolution == "" {
+		q.Resolution = other.Resolution
+	}
+	if q.Format == "" {
+		q.Format = other.Format
+	}
+	if q.Resolution != "" || q.Format != "" {
+		q.Raw = ""
+	}
+	return q
+}
+
+// MarshalJSON : qualities are serialized as strings e.g "1080p WEB-DL"
+func (q Quality) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.String())
+}
+
+// UnmarshalJSON : parse a quality serialized by MarshalJSON
+func (q *Quality) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*q = Quality{}
+		return nil
+	}
+	*q = ParseQuality(s)
+	return nil
+}
@@ -0,0 +1,221 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidQuery : an operator of a query could not be parsed
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Query : A search with its operators parsed, e.g "avengers year:2019 genre:action"
+type Query struct {
+	Text     string   // searched on the sites of the engines
+	YearFrom int      // 0 leaves that end of the range open
+	YearTo   int      // 0 leaves that end of the range open
+	Genres   []string // lowercase, movies of any of them are kept
+}
+
+// ParseYearRange : The years of a range such as 2019, 2015-2020, 2015- or -2020.
+// A zero year leaves that end of the range open
+func ParseYearRange(years string) (from, to int, err error) {
+	years = strings.TrimSpace(years)
+	parse := func(year string) (int, error) {
+		if year == "" {
+			return 0, nil
+		}
+		y, err := strconv.Atoi(year)
+		if err != nil || y < 1800 || y > 2200 {
+			return 0, fmt.Errorf("%w: year must be a year or a range such as 2015-2020, got %q", ErrInvalidQuery, years)
+		}
+		return y, nil
+	}
+	if i := strings.Index(years, "-"); i >= 0 {
+		if from, err = parse(years[:i]); err == nil {
+			to, err = parse(years[i+1:])
+		}
+		if err == nil && from == 0 && to == 0 {
+			err = fmt.Errorf("%w: empty year range", ErrInvalidQuery)
+		}
+		if err == nil && to != 0 && from > to {
+			err = fmt.Errorf("%w: year range %q ends before it starts", ErrInvalidQuery, years)
+		}
+		return from, to, err
+	}
+	from, err = parse(years)
+	if err == nil && from == 0 {
+		err = fmt.Errorf("%w: empty year", ErrInvalidQuery)
+	}
+	return from, from, err
+}
+
+// ParseQuery : The query of a search with the operators year:2019, year:2015-2020
+// and genre:action,comedy taken out of the words searched for
+func ParseQuery(query string) (Query, error) {
+	var (
+		q    Query
+		text []string
+	)
+	for _, word := range strings.Fields(query) {
+		i := strings.Index(word, ":")
+		if i <= 0 || i == len(word)-1 {
+			text = append(text, word)
+			continue
+		}
+		switch strings.ToLower(word[:i]) {
+		case "year":
+			from, to, err := ParseYearRange(word[i+1:])
+			if err != nil {
+				return q, err
+			}
+			q.YearFrom, q.YearTo = from, to
+		case "genre":
+			// genres of several words are written science_fiction
+			for _, genre := range strings.Split(strings.ReplaceAll(word[i+1:], "_", " "), ",") {
+				if genre = strings.ToLower(strings.TrimSpace(genre)); genre != "" {
+					q.Genres = append(q.Genres, genre)
+				}
+			}
+		default:
+			text = append(text, word)
+		}
+	}
+	q.Text = strings.Join(text, " ")
+	return q, nil
+}
+
+// Year : the single year of the query, 0 when it has none or a range
+func (q Query) Year() int {
+	if q.YearFrom == q.YearTo {
+		return q.YearFrom
+	}
+	return 0
+}
+
+// Filtered : whether the query has operators
+func (q Query) Filtered() bool {
+	return q.YearFrom != 0 || q.YearTo != 0 || len(q.Genres) > 0
+}
+
+// String : the query with its operators, as parsed by ParseQuery
+func (q Query) String() string {
+	words := []string{q.Text}
+	switch {
+	case q.Year() != 0:
+		words = append(words, fmt.Sprintf("year:%d", q.Year()))
+	case q.YearFrom != 0 || q.YearTo != 0:
+		from, to := "", ""
+		if q.YearFrom != 0 {
+			from = strconv.Itoa(q.YearFrom)
+		}
+		if q.YearTo != 0 {
+			to = strconv.Itoa(q.YearTo)
+		}
+		words = append(words, fmt.Sprintf("year:%s-%s", from, to))
+	}
+	if len(q.Genres) > 0 {
+		words = append(words, "genre:"+strings.ReplaceAll(strings.Join(q.Genres, ","), " ", "_"))
+	}
+	return strings.TrimSpace(strings.Join(words, " "))
+}
+
+// HasGenre : whether movie is of one of the genres of the query. Movies whose
+// genres are unknown, as the sites of most engines only show them on the page
+// of the movie, are kept
+func (q Query) HasGenre(movie Movie) bool {
+	if len(q.Genres) == 0 {
+		return true
+	}
+	var known []string
+	for _, csv := range []string{movie.Genres, movie.Category, movie.Tags} {
+		for _, genre := range strings.Split(csv, ",") {
+			if genre = strings.ToLower(strings.TrimSpace(genre)); genre != "" {
+				known = append(known, genre)
+			}
+		}
+	}
+	if len(known) == 0 {
+		return true
+	}
+	for _, want := range q.Genres {
+		for _, genre := range known {
+			if genre == want || strings.Contains(genre, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Keep : whether movie matches the operators of the query. Movies whose year
+// or genres are unknown are kept, as most engines do not tell them
+func (q Query) Keep(movie Movie) bool {
+	if movie.Year != 0 && (q.YearFrom != 0 || q.YearTo != 0) {
+		if (q.YearFrom != 0 && movie.Year < q.YearFrom) || (q.YearTo != 0 && movie.Year > q.YearTo) {
+			return false
+		}
+	}
+	return q.HasGenre(movie)
+}
+
+// Filter : Return the movies of the result matching the operators of the query
+func (q Query) Filter(result SearchResult) SearchResult {
+	if !q.Filtered() {
+		return result
+	}
+	return result.filter(q.Keep)
+}
+
+// searchText : the text searched on the site of e. Sites narrowing searches with
+// a year in the query get the single year of q
+func (q Query) searchText(e Engine) string {
+	if year := q.Year(); year != 0 && e.Capabilities().SearchByYear {
+		return fmt.Sprintf("%s %d", q.Text, year)
+	}
+	return q.Text
+}
+
+type queryKey struct{}
+
+// WithQuery : ctx whose searches are for q, so that engines with filters of
+// their own can narrow the search with the operators of q
+func WithQuery(ctx context.Context, q Query) context.Context {
+	return context.WithValue(ctx, queryKey{}, q)
+}
+
+// QueryFrom : the query of the searches of ctx, if any
+func QueryFrom(ctx context.Context) (Query, bool) {
+	q, ok := ctx.Value(queryKey{}).(Query)
+	return q, ok && q.Filtered()
+}
+
+// ParseSearch : The query of a search, which must have words to search for
+// besides its operators
+func ParseSearch(query string) (Query, error) {
+	q, err := ParseQuery(query)
+	if err == nil && q.Filtered() && q.Text == "" {
+		err = fmt.Errorf("%w: %q has nothing to search for besides its operators", ErrInvalidQuery, query)
+	}
+	return q, err
+}
+
+// Search : Search e for the query of param[0] with its operators, see ParseQuery,
+// and the page of param[1] if any. The operators are passed to engines which
+// filter their searches natively and applied to the movies found. The movies
+// found before e failed are returned, see Partial
+func Search(ctx context.Context, e Engine, param ...string) (SearchResult, error) {
+	q, err := ParseSearch(param[0])
+	if err != nil {
+		return SearchResult{Query: param[0]}, err
+	}
+	if !q.Filtered() {
+		result, err := e.Search(ctx, param...)
+		return Partial(e, result, err)
+	}
+	result, err := e.Search(WithQuery(ctx, q), append([]string{q.searchText(e)}, param[1:]...)...)
+	result.Query = q.Text
+	return Partial(e, q.Filter(result), err)
+}
@@ -0,0 +1,321 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-phie/gophie/transport"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNoDirectLink : the landing page a download link leads to has no link to the file
+var ErrNoDirectLink = errors.New("no direct link found")
+
+// DefaultMaxHops : landing pages followed before a download link is given up on
+const DefaultMaxHops = 4
+
+// LinkResolver : Finds the file the download link of a movie leads to, for sites
+// whose links lead to a landing page with a "click to download" link rather than
+// to the file. Engines whose pages need more than PageResolver implement it
+type LinkResolver interface {
+	// ResolveLink : the direct link of the file link leads to and when it
+	// expires, zero when it does not or it is unknown
+	ResolveLink(ctx context.Context, client *http.Client, link *url.URL) (*url.URL, time.Time, error)
+}
+
+// fileExtensions : extensions of the links of files rather than pages
+var fileExtensions = map[string]bool{
+	".mp4": true, ".mkv": true, ".avi": true, ".m4v": true, ".webm": true, ".3gp": true,
+	".mov": true, ".wmv": true, ".flv": true, ".ts": true, ".zip": true, ".rar": true, ".7z": true,
+}
+
+// downloadTextRe : the text of the buttons and links of landing pages leading to the file
+var downloadTextRe = regexp.MustCompile(`(?i)download|get link|continue|click here`)
+
+// refreshRe : the URL of a meta refresh such as "5; url=https://..."
+var refreshRe = regexp.MustCompile(`(?i)url\s*=\s*['"]?([^'"]+)`)
+
+// PageResolver : Follows the redirects and the landing pages of a download link
+// until it reaches a file, the default resolver of engines
+type PageResolver struct {
+	// Selectors : CSS selectors of the link to the file on the landing pages of
+	// the site, tried before the links which look like downloads. The href of
+	// links or the value of inputs is followed
+	Selectors []string
+	MaxHops   int // DefaultMaxHops when 0
+}
+
+// ResolveLink : Get link, and the link to the file its page leads to until the
+// response is not a page
+func (r *PageResolver) ResolveLink(ctx context.Context, client *http.Client, link *url.URL) (*url.URL, time.Time, error) {
+	hops := r.MaxHops
+	if hops <= 0 {
+		hops = DefaultMaxHops
+	}
+	visited := map[string]bool{}
+	for hop := 0; hop <= hops; hop++ {
+		visited[link.String()] = true
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.String(), nil)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*")
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		// redirects were followed to the file or to the next page
+		final := resp.Request.URL
+		if resp.StatusCode >= http.StatusBadRequest {
+			resp.Body.Close()
+			return nil, time.Time{}, fmt.Errorf("%s responded with %s", final, resp.Status)
+		}
+		if !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+			// only the headers of the file are needed
+			resp.Body.Close()
+			return final, LinkExpiry(final, time.Now()), nil
+		}
+		doc, err := goquery.NewDocumentFromReader(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		next := r.next(doc, final)
+		if next == nil || visited[next.String()] {
+			return nil, time.Time{}, fmt.Errorf("%w on %s", ErrNoDirectLink, final)
+		}
+		link = next
+	}
+	return nil, time.Time{}, fmt.Errorf("%w after %d pages", ErrNoDirectLink, hops)
+}
+
+// next : the link of the landing page doc at base leading on to the file
+func (r *PageResolver) next(doc *goquery.Document, base *url.URL) *url.URL {
+	var links []string
+	doc.Find("meta[http-equiv]").Each(func(_ int, s *goquery.Selection) {
+		if strings.EqualFold(s.AttrOr("http-equiv", ""), "refresh") {
+			if match := refreshRe.FindStringSubmatch(s.AttrOr("content", "")); match != nil {
+				links = append(links, match[1])
+			}
+		}
+	})
+	for _, selector := range r.Selectors {
+		doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
+			if href, ok := s.Attr("href"); ok {
+				links = append(links, href)
+			} else if value, ok := s.Attr("value"); ok {
+				links = append(links, value)
+			}
+		})
+	}
+	var files, buttons []string
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		_, download := s.Attr("download")
+		switch {
+		case download || fileExtensions[strings.ToLower(path.Ext(strings.SplitN(href, "?", 2)[0]))]:
+			files = append(files, href)
+		case downloadTextRe.MatchString(s.Text()):
+			buttons = append(buttons, href)
+		}
+	})
+	links = append(append(links, files...), buttons...)
+	for _, link := range links {
+		link = strings.TrimSpace(link)
+		if link == "" || strings.HasPrefix(link, "#") || strings.HasPrefix(strings.ToLower(link), "javascript:") {
+			continue
+		}
+		u, err := base.Parse(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.String() == base.String() {
+			continue
+		}
+		return u
+	}
+	return nil
+}
+
+// LinkExpiry : When the signed link stops working, told by its query such as
+// expires=<unix time> or the X-Amz-Date and X-Amz-Expires of S3, zero when it
+// does not tell. Lifetimes in seconds rather than times are counted from now
+func LinkExpiry(link *url.URL, now time.Time) time.Time {
+	q := link.Query()
+	if date, err := time.Parse("20060102T150405Z", q.Get("X-Amz-Date")); err == nil {
+		if seconds, err := strconv.Atoi(q.Get("X-Amz-Expires")); err == nil {
+			return date.Add(time.Duration(seconds) * time.Second)
+		}
+	}
+	for _, key := range []string{"expires", "Expires", "expire", "exp", "e"} {
+		n, err := strconv.ParseInt(q.Get(key), 10, 64)
+		if err != nil || n <= 0 {
+			continue
+		}
+		// unix times are after 2001, smaller numbers are lifetimes
+		if n > 1e9 {
+			return time.Unix(n, 0)
+		}
+		return now.Add(time.Duration(n) * time.Second)
+	}
+	return time.Time{}
+}
+
+// ResolverOf : The link resolver of e, a PageResolver unless e has its own
+func ResolverOf(e Engine) LinkResolver {
+	if r, ok := e.(LinkResolver); ok {
+		return r
+	}
+	return &PageResolver{}
+}
+
+// Resolved : whether the movie has a direct link which has not expired at t
+func (m *Movie) Resolved(t time.Time) bool {
+	return m.DirectLink != nil && (m.LinkExpires.IsZero() || t.Before(m.LinkExpires))
+}
+
+// Link : the link the file of the movie is downloaded from, its direct link
+// unless it expired
+func (m *Movie) Link() *url.URL {
+	if m.Resolved(time.Now()) {
+		return m.DirectLink
+	}
+	return m.DownloadLink
+}
+
+// LinkHeaders : The headers the host of the link of the movie is sent by the
+// engine, its configured headers and the cookies and User-Agent granted by its
+// anti-bot challenge, with the download link the direct link came from as Referer
+func LinkHeaders(movie *Movie) http.Header {
+	header := http.Header{}
+	link := movie.Link()
+	if link == nil {
+		return header
+	}
+	config, err := clientConfig(movie.Source)
+	if err != nil {
+		log.Warn(err)
+	}
+	for key, val := range config.Headers {
+		header.Set(key, val)
+	}
+	if header.Get("User-Agent") == "" && len(config.UserAgents) > 0 {
+		header.Set("User-Agent", config.UserAgents[0])
+	}
+	if config.CookieFile != "" {
+		store := transport.OpenCookieStore(config.CookieFile)
+		var cookies []string
+		for _, cookie := range store.Cookies(link) {
+			cookies = append(cookies, cookie.String())
+		}
+		if len(cookies) > 0 {
+			header.Set("Cookie", strings.Join(cookies, "; "))
+		}
+		if userAgent := store.UserAgent(link.Hostname()); userAgent != "" {
+			header.Set("User-Agent", userAgent)
+		}
+	}
+	if movie.DownloadLink != nil && link != movie.DownloadLink {
+		header.Set("Referer", movie.DownloadLink.String())
+	}
+	return header
+}
+
+// resolvable : whether the download link of the movie may lead to a landing page
+func (m *Movie) resolvable() bool {
+	return m.DownloadLink != nil && m.MagnetLink == "" && !m.IsSeries &&
+		(m.DownloadLink.Scheme == "http" || m.DownloadLink.Scheme == "https")
+}
+
+// resolverClient : the resolver of the engine named source and the client its
+// links are reached with, the way the engine reaches its site
+func resolverClient(source string) (LinkResolver, *http.Client, func()) {
+	var resolver LinkResolver = &PageResolver{}
+	if e, err := GetEngine(source); err == nil {
+		resolver = ResolverOf(e)
+	}
+	clientTransport, err := newClientTransport(source)
+	if err != nil {
+		log.Warn(err)
+		return resolver, http.DefaultClient, func() {}
+	}
+	return resolver, &http.Client{Transport: clientTransport}, clientTransport.CloseIdleConnections
+}
+
+// ResolveMovieLink : Set the direct link of the movie and when it expires with
+// the resolver of its engine, unless it has one which has not expired
+func ResolveMovieLink(ctx context.Context, movie *Movie) error {
+	if movie.Resolved(time.Now()) || !movie.resolvable() {
+		return nil
+	}
+	resolver, client, closeClient := resolverClient(movie.Source)
+	defer closeClient()
+	link, expires, err := resolver.ResolveLink(ctx, client, movie.DownloadLink)
+	if err != nil {
+		return err
+	}
+	movie.DirectLink, movie.LinkExpires = link, expires
+	return nil
+}
+
+// ResolveLinks : Set the direct links of the movies, parallel at a time with at
+// most timeout for each. Movies whose links cannot be resolved keep their
+// download link
+func ResolveLinks(ctx context.Context, movies []Movie, parallel int, timeout time.Duration) []Movie {
+	type client struct {
+		resolver LinkResolver
+		client   *http.Client
+	}
+	clients := map[string]client{}
+	var pending []int
+	for i := range movies {
+		if movies[i].Resolved(time.Now()) || !movies[i].resolvable() {
+			continue
+		}
+		pending = append(pending, i)
+		if _, ok := clients[movies[i].Source]; !ok {
+			resolver, c, closeClient := resolverClient(movies[i].Source)
+			defer closeClient()
+			clients[movies[i].Source] = client{resolver, c}
+		}
+	}
+	if parallel <= 0 {
+		parallel = 1
+	}
+	var wg sync.WaitGroup
+	indexes := make(chan int)
+	for w := 0; w < parallel && w < len(pending); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				movie := &movies[i]
+				c := clients[movie.Source]
+				resolveCtx, cancel := context.WithTimeout(ctx, timeout)
+				link, expires, err := c.resolver.ResolveLink(resolveCtx, c.client, movie.DownloadLink)
+				cancel()
+				if err != nil {
+					log.Debugf("Could not resolve the link of %s: %v", movie.Title, err)
+					continue
+				}
+				movie.DirectLink, movie.LinkExpires = link, expires
+			}
+		}()
+	}
+	for _, i := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return movies
+}
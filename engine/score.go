@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RankCriterion : What the movies of a result are ranked by, best first
+type RankCriterion string
+
+const (
+	// RankByScore : the relevance Score combining every other criterion
+	RankByScore RankCriterion = "score"
+	// RankByTitle : how closely the title matches the query
+	RankByTitle RankCriterion = "title"
+	// RankByYear : the most recent releases
+	RankByYear RankCriterion = "year"
+	// RankByQuality : the highest resolutions, cam recordings last
+	RankByQuality RankCriterion = "quality"
+	// RankBySource : the engines whose links are least often dead
+	RankBySource RankCriterion = "source"
+)
+
+var rankCriteria = []RankCriterion{RankByScore, RankByTitle, RankByYear, RankByQuality, RankBySource}
+
+// ParseRankCriterion : The criterion named name, RankByScore when name is empty
+func ParseRankCriterion(name string) (RankCriterion, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return RankByScore, nil
+	}
+	for _, criterion := range rankCriteria {
+		if name == string(criterion) {
+			return criterion, nil
+		}
+	}
+	names := make([]string, len(rankCriteria))
+	for i, criterion := range rankCriteria {
+		names[i] = string(criterion)
+	}
+	return RankByScore, fmt.Errorf("Invalid rank criterion %q, use one of %s", name, strings.Join(names, ", "))
+}
+
+// weights of the criteria in the Score, adding up to 1
+var scoreWeights = map[RankCriterion]float64{
+	RankByTitle:   0.5,
+	RankByQuality: 0.2,
+	RankByYear:    0.15,
+	RankBySource:  0.15,
+}
+
+// resolutionScores : how good every resolution is, from 0 to 1
+var resolutionScores = map[string]float64{
+	"2160p": 1,
+	"1080p": 0.85,
+	"720p":  0.65,
+	"576p":  0.45,
+	"480p":  0.35,
+}
+
+// titleScore : how closely title matches query from 0 to 1, 1 for the same
+// normalized title and less for titles containing it or with typos
+func titleScore(query, title string) float64 {
+	wanted, normalized := NormalizeTitle(query), NormalizeTitle(title)
+	if wanted == "" || normalized == "" {
+		return 0
+	}
+	if wanted == normalized {
+		return 1
+	}
+	if strings.Contains(" "+normalized+" ", " "+wanted+" ") {
+		// the fewer other words the title has, the closer it matches
+		return 0.7 + 0.25*float64(len(wanted))/float64(len(normalized))
+	}
+	length := len([]rune(wanted))
+	if l := len([]rune(normalized)); l > length {
+		length = l
+	}
+	score := 0.7 * (1 - float64(levenshtein(wanted, normalized))/float64(length))
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+// yearScore : how recent a release of year is from 0 to 1, 0 when unknown
+func yearScore(year int) float64 {
+	if year == 0 {
+		return 0
+	}
+	age := time.Now().Year() - year
+	switch {
+	case age <= 0:
+		return 1
+	case age >= 50:
+		return 0.01
+	}
+	return 1 - float64(age)/50
+}
+
+// qualityScore : how good a quality is from 0 to 1, 0 when unknown
+func qualityScore(q Quality) float64 {
+	score := resolutionScores[q.Resolution]
+	if score == 0 && q.Format != "" {
+		score = 0.5
+	}
+	if q.Format == "CAM" {
+		score /= 4
+	}
+	return score
+}
+
+// sourceScore : how reliable the links of the engine named source are from 0 to 1
+func sourceScore(source string) float64 {
+	return 1 - linkRotOf(source)
+}
+
+// criterionScore : the score of movie for a single criterion
+func criterionScore(criterion RankCriterion, query string, movie Movie) float64 {
+	switch criterion {
+	case RankByTitle:
+		return titleScore(query, movie.Title)
+	case RankByYear:
+		return yearScore(movie.Year)
+	case RankByQuality:
+		return qualityScore(movie.Quality)
+	case RankBySource:
+		return sourceScore(movie.Source)
+	}
+	// summed in the same order every time so equal movies score the same
+	var score float64
+	for _, c := range rankCriteria[1:] {
+		score += scoreWeights[c] * criterionScore(c, query, movie)
+	}
+	return score
+}
+
+// ScoreMovie : The relevance of movie to query from 0 to 1, weighing how closely
+// the title matches, how recent and good the release is and how reliable its
+// source is. Without a query the title is not weighed
+func ScoreMovie(query string, movie Movie) float64 {
+	score := criterionScore(RankByScore, query, movie)
+	if strings.TrimSpace(query) == "" {
+		return score / (1 - scoreWeights[RankByTitle])
+	}
+	return score
+}
+
+// Rank : Set the Score of every movie of the result for its query and sort the
+// movies by criterion, best first. Movies ranking the same keep their order
+func (s *SearchResult) Rank(criterion RankCriterion) {
+	keys := make([]float64, len(s.Movies))
+	for i := range s.Movies {
+		s.Movies[i].Score = ScoreMovie(s.Query, s.Movies[i])
+		keys[i] = s.Movies[i].Score
+		if criterion != RankByScore {
+			keys[i] = criterionScore(criterion, s.Query, s.Movies[i])
+		}
+	}
+	order := make([]int, len(s.Movies))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return keys[order[i]] > keys[order[j]] })
+	ranked := make([]Movie, len(s.Movies))
+	for i, index := range order {
+		ranked[i] = s.Movies[index]
+	}
+	s.Movies = ranked
+}
@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Episode : a single downloadable episode of a series
+type Episode struct {
+	Number       int
+	LastNumber   int // last episode of a batch download, 0 for a single episode
+	Title        string
+	Size         string
+	DownloadLink *url.URL
+	SubtitleLink *url.URL
+}
+
+// Season : the episodes of a series in a season, ordered by episode number
+type Season struct {
+	Number   int
+	Episodes []Episode
+}
+
+// EpisodeJSON : JSON structure of an episode
+type EpisodeJSON struct {
+	Episode
+	DownloadLink string
+	SubtitleLink string
+}
+
+// MarshalJSON : Json structure to return from api
+func (e *Episode) MarshalJSON() ([]byte, error) {
+	episode := EpisodeJSON{
+		Episode:      *e,
+		DownloadLink: e.DownloadLink.String(),
+	}
+	if e.SubtitleLink != nil {
+		episode.SubtitleLink = e.SubtitleLink.String()
+	}
+	return json.Marshal(episode)
+}
+
+// UnmarshalJSON : Rebuild an episode from the structure returned by MarshalJSON
+func (e *Episode) UnmarshalJSON(data []byte) error {
+	type episode Episode
+	aux := struct {
+		*episode
+		DownloadLink string
+		SubtitleLink string
+	}{episode: (*episode)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	if e.DownloadLink, err = url.Parse(aux.DownloadLink); err != nil {
+		return err
+	}
+	if aux.SubtitleLink != "" {
+		e.SubtitleLink, err = url.Parse(aux.SubtitleLink)
+	}
+	return err
+}
+
+// Label : Season and episode of episode in the SxxExx form
+func (s *Season) Label(e Episode) string {
+	label := fmt.Sprintf("S%02dE%02d", s.Number, e.Number)
+	if e.LastNumber > e.Number {
+		label += fmt.Sprintf("-E%02d", e.LastNumber)
+	}
+	if e.Title != "" && e.Title != strconv.Itoa(e.Number) {
+		label += " - " + e.Title
+	}
+	if e.Size != "" {
+		label += " (" + e.Size + ")"
+	}
+	return label
+}
+
+var (
+	seasonEpisodeRe = regexp.MustCompile(`(?i)\bS(\d{1,2})\s*E(\d{1,3})\b`)
+	seasonRe        = regexp.MustCompile(`(?i)\b(?:season|series|S)\s*(\d{1,2})\b`)
+	episodeRe       = regexp.MustCompile(`(?i)\b(?:episode|ep|E)\s*\.?\s*(\d{1,3})\b`)
+	// anime releases number episodes like "Attack on Titan - 01 [720p]"
+	trailingNumberRe = regexp.MustCompile(`(?:^|[\s\-_])(\d{1,3})(?:[\s\[\(v]|$)`)
+)
+
+// ParseEpisodeLabel : Extract the season and episode numbers from a label such as
+// "S02E05", "Season 2 Episode 5", "Episode 5" or "Naruto - 05 [720p]"
+// A zero is returned for numbers which are not found
+func ParseEpisodeLabel(label string) (season, episode int) {
+	if match := seasonEpisodeRe.FindStringSubmatch(label); match != nil {
+		season, _ = strconv.Atoi(match[1])
+		episode, _ = strconv.Atoi(match[2])
+		return season, episode
+	}
+	if match := seasonRe.FindStringSubmatch(label); match != nil {
+		season, _ = strconv.Atoi(match[1])
+	}
+	if match := episodeRe.FindStringSubmatch(label); match != nil {
+		episode, _ = strconv.Atoi(match[1])
+	} else if match := trailingNumberRe.FindAllStringSubmatch(label, -1); match != nil {
+		episode, _ = strconv.Atoi(match[len(match)-1][1])
+	}
+	return season, episode
+}
+
+// AddEpisode : Add episode to the season numbered season, creating the season if needed
+// Seasons and their episodes are kept in order
+func (m *Movie) AddEpisode(season int, episode Episode) {
+	m.IsSeries = true
+	i := sort.Search(len(m.Seasons), func(i int) bool { return m.Seasons[i].Number >= season })
+	if i == len(m.Seasons) || m.Seasons[i].Number != season {
+		m.Seasons = append(m.Seasons, Season{})
+		copy(m.Seasons[i+1:], m.Seasons[i:])
+		m.Seasons[i] = Season{Number: season}
+	}
+	s := &m.Seasons[i]
+	j := sort.Search(len(s.Episodes), func(j int) bool { return s.Episodes[j].Number > episode.Number })
+	s.Episodes = append(s.Episodes, Episode{})
+	copy(s.Episodes[j+1:], s.Episodes[j:])
+	s.Episodes[j] = episode
+}
+
+// hasEpisode : whether the series has the episode numbered number in season
+func (m *Movie) hasEpisode(season, number int) bool {
+	for _, s := range m.Seasons {
+		if s.Number != season {
+			continue
+		}
+		for _, e := range s.Episodes {
+			if e.Number == number {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildSeasons : Populate the seasons of a series from its SDownloadLink labels
+// for engines which only know the labels of the episodes
+func (m *Movie) buildSeasons() {
+	if len(m.Seasons) > 0 || len(m.SDownloadLink) == 0 {
+		return
+	}
+	defaultSeason, _ := ParseEpisodeLabel(m.Title)
+	if defaultSeason == 0 {
+		defaultSeason = 1
+	}
+	labels := make([]string, 0, len(m.SDownloadLink))
+	for label := range m.SDownloadLink {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for i, label := range labels {
+		season, _ := ParseEpisodeLabel(label)
+		if season == 0 {
+			season = defaultSeason
+		}
+		number, last := ParseEpisodeRange(label)
+		if number == 0 {
+			number = i + 1
+		}
+		episode := Episode{
+			Number:       number,
+			Title:        label,
+			DownloadLink: m.SDownloadLink[label],
+			SubtitleLink: m.SubtitleLinks[label],
+		}
+		if last > number {
+			episode.LastNumber = last
+		}
+		m.AddEpisode(season, episode)
+	}
+}
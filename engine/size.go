@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var sizeRe = regexp.MustCompile(`(?i)(\d[\d,]*(?:\.\d+)?)\s*(bytes|[KMGT]i?B|B)\b`)
+
+// Multipliers of size units, source sites mean binary units by KB, MB and GB
+var sizeUnits = map[byte]float64{'B': 1, 'K': 1 << 10, 'M': 1 << 20, 'G': 1 << 30, 'T': 1 << 40}
+
+// ParseSize : The number of bytes of a scraped size such as "(277.36MB)", "1.2 GB"
+// or "1,5 GiB". Sizes which cannot be parsed are 0
+func ParseSize(size string) int64 {
+	match := sizeRe.FindStringSubmatch(size)
+	if match == nil {
+		return 0
+	}
+	number := match[1]
+	if strings.Contains(number, ".") {
+		// 1,024.5 MB
+		number = strings.ReplaceAll(number, ",", "")
+	} else if i := strings.LastIndex(number, ","); i >= 0 && len(number)-i-1 == 3 {
+		// 1,024 MB
+		number = strings.ReplaceAll(number, ",", "")
+	} else {
+		// 1,5 GB
+		number = strings.Replace(number, ",", ".", 1)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(value * sizeUnits[strings.ToUpper(match[2])[0]])
+}
+
+// FormatSize : A number of bytes as a size such as "1.2 GB" using the binary units
+// ParseSize reads. Unknown sizes (0 or less) are empty
+func FormatSize(bytes int64) string {
+	if bytes <= 0 {
+		return ""
+	}
+	value := float64(bytes)
+	for _, unit := range []string{"B", "KB", "MB", "GB"} {
+		if value < 1024 {
+			if unit == "B" {
+				return strconv.FormatInt(bytes, 10) + " B"
+			}
+			return strconv.FormatFloat(value, 'f', 1, 64) + " " + unit
+		}
+		value /= 1024
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64) + " TB"
+}
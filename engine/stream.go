@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-phie/gophie/logging"
+)
+
+// MovieFunc : called with every movie streamed from a search
+type MovieFunc func(movie Movie)
+
+type movieHandlerKey struct{}
+
+// WithMovieFunc : ctx whose scrapes call fn with each movie as soon as its
+// download page has been processed. Wrapping engines can use it to see movies as they are streamed
+func WithMovieFunc(ctx context.Context, fn MovieFunc) context.Context {
+	return context.WithValue(ctx, movieHandlerKey{}, fn)
+}
+
+// MovieFuncFromContext : the MovieFunc set with WithMovieFunc, nil if none is set
+func MovieFuncFromContext(ctx context.Context) MovieFunc {
+	fn, _ := ctx.Value(movieHandlerKey{}).(MovieFunc)
+	return fn
+}
+
+// StreamSearch : Search e and call fn with every movie as soon as it is scraped
+// Engines which do not scrape with Scrape (or results served from a cache)
+// have their movies passed to fn once the search returns
+// The operators of the query, see ParseQuery, are applied to the movies streamed
+func StreamSearch(ctx context.Context, e Engine, fn MovieFunc, param ...string) (SearchResult, error) {
+	q, err := ParseSearch(param[0])
+	if err != nil {
+		return SearchResult{Query: param[0]}, err
+	}
+	if q.Filtered() {
+		ctx = WithQuery(ctx, q)
+		param = append([]string{q.searchText(e)}, param[1:]...)
+	}
+	streamed, scraped := 0, 0
+	result, err := e.Search(WithMovieFunc(ctx, func(movie Movie) {
+		scraped++
+		if !q.Keep(movie) {
+			return
+		}
+		movie.Index = streamed
+		streamed++
+		fn(movie)
+	}), param...)
+	if scraped < len(result.Movies) {
+		for _, movie := range result.Movies[scraped:] {
+			if !q.Keep(movie) {
+				continue
+			}
+			movie.Index = streamed
+			streamed++
+			fn(movie)
+		}
+	}
+	if q.Filtered() {
+		result.Query = q.Text
+		result = q.Filter(result)
+	}
+	return result, err
+}
+
+// StreamSearchAll : Search the engines concurrently calling fn with every movie
+// as soon as it is scraped. Calls to fn are serialized, movies with the same
+// download link are only passed once and Index counts the movies streamed
+// An error is only returned when all the engines fail
+func StreamSearchAll(ctx context.Context, engines map[string]Engine, fn MovieFunc, param ...string) error {
+	ctx = logging.EnsureTraceID(ctx)
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		index    int
+		failures int
+		seen     = map[string]bool{}
+	)
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string, e Engine) {
+			defer wg.Done()
+			_, err := StreamSearch(ctx, e, func(movie Movie) {
+				mu.Lock()
+				defer mu.Unlock()
+				if movie.DownloadLink != nil {
+					link := movie.DownloadLink.String()
+					if seen[link] {
+						return
+					}
+					seen[link] = true
+				}
+				if movie.Source == "" {
+					movie.Source = name
+				}
+				movie.Index = index
+				index++
+				fn(movie)
+			}, param...)
+			if err != nil {
+				logging.FromContext(ctx).WithField("engine", name).Warnf("Search failed: %v", err)
+				mu.Lock()
+				failures++
+				mu.Unlock()
+			}
+		}(name, engines[name])
+	}
+	wg.Wait()
+
+	if len(names) == 0 || failures < len(names) {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("%w: all %d engines failed", ErrEngineUnavailable, len(names))
+}
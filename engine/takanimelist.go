@@ -1,9 +1,9 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"net/url"
-	"path"
 	"regexp"
 	"strconv"
 	"strings"
@@ -17,6 +17,10 @@ type TakanimeList struct {
 	Props
 }
 
+func init() {
+	RegisterEngine("takanimelist", func() Engine { return NewTakanimeListEngine() })
+}
+
 // NewTakanimeListEngine : create a new engine for scraping latest anime from chia-anime
 func NewTakanimeListEngine() *TakanimeList {
 	base := "https://takanimelist.live"
@@ -42,6 +46,9 @@ func NewTakanimeListEngine() *TakanimeList {
 	takanimeListEngine.Name = "TakanimeList"
 	takanimeListEngine.BaseURL = baseURL
 	takanimeListEngine.Description = `Anime in 480p, 720p and 1080p format`
+	takanimeListEngine.Features = Capabilities{Series: true, Pagination: true}
+	takanimeListEngine.Languages = []string{"ja"}
+	takanimeListEngine.Regions = []string{"Japan"}
 	takanimeListEngine.SearchURL = searchURL
 	takanimeListEngine.ListURL = listURL
 	return &takanimeListEngine
@@ -87,7 +94,7 @@ func (engine *TakanimeList) parseSingleMovie(el *colly.HTMLElement, index int) (
 	downloadLink, err := url.Parse(el.Request.AbsoluteURL(el.ChildAttr("a", "href")))
 
 	if err != nil {
-		log.Fatal(err)
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 	log.Debug(movie.Title)
 	movie.DownloadLink = downloadLink
@@ -115,7 +122,11 @@ func retrieveSingle(downloadCollector *colly.Collector, initialLink string) stri
 func (engine *TakanimeList) updateDownloadProps(downloadCollector *colly.Collector, movies *[]Movie) {
 	internaldownloadCollector := downloadCollector.Clone()
 	downloadCollector.OnHTML("div.entry-content", func(e *colly.HTMLElement) {
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		episodeMap := map[string]*url.URL{}
 		linkArray := e.ChildAttrs("a", "href")
 		titleArray := e.ChildTexts("a")
@@ -133,23 +144,19 @@ func (engine *TakanimeList) updateDownloadProps(downloadCollector *colly.Collect
 }
 
 // List : list all the movies on a page
-func (engine *TakanimeList) List(page int) SearchResult {
+func (engine *TakanimeList) List(ctx context.Context, page int) (SearchResult, error) {
 	engine.mode = ListMode
 	result := SearchResult{
 		Query: "List of Recent Uploads - Page " + strconv.Itoa(page),
 	}
 	pageParam := fmt.Sprintf("page/%v", strconv.Itoa(page))
-	engine.ListURL.Path = path.Join(engine.ListURL.Path, pageParam)
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	engine.ListURL.Path = engine.listPath(pageParam)
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, page), err
 }
 
 // Search : Searches takanimelist for a particular query and return an array of movies
-func (engine *TakanimeList) Search(param ...string) SearchResult {
+func (engine *TakanimeList) Search(ctx context.Context, param ...string) (SearchResult, error) {
 	query := param[0]
 	engine.mode = SearchMode
 	result := SearchResult{
@@ -157,11 +164,8 @@ func (engine *TakanimeList) Search(param ...string) SearchResult {
 	}
 	q := engine.SearchURL.Query()
 	q.Set("s", query)
+	setPage(q, "paged", param)
 	engine.SearchURL.RawQuery = q.Encode()
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, searchPage(param)), err
 }
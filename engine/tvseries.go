@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -16,6 +17,10 @@ type TvSeriesEngine struct {
 	Props
 }
 
+func init() {
+	RegisterEngine("tvseries", func() Engine { return NewTvSeriesEngine() })
+}
+
 // NewTvSeriesEngine : A Movie Engine Constructor for TvSeriesEngine
 func NewTvSeriesEngine() *TvSeriesEngine {
 	base := "https://tvseries.in/"
@@ -41,6 +46,9 @@ func NewTvSeriesEngine() *TvSeriesEngine {
 	TvSeriesEngine.Name = "TvSeries"
 	TvSeriesEngine.BaseURL = baseURL
 	TvSeriesEngine.Description = `TvSeries is a site owned by the fzmovies group where shows are available`
+	TvSeriesEngine.Features = Capabilities{Series: true, Pagination: true}
+	TvSeriesEngine.Languages = []string{"en"}
+	TvSeriesEngine.Regions = []string{"Hollywood"}
 	TvSeriesEngine.SearchURL = searchURL
 	TvSeriesEngine.ListURL = listURL
 	return &TvSeriesEngine
@@ -65,7 +73,7 @@ func (engine *TvSeriesEngine) parseSingleMovie(el *colly.HTMLElement, index int)
 	}
 	cover, err := url.Parse(el.Request.AbsoluteURL(el.ChildAttr("img", "src")))
 	if err != nil {
-		log.Fatal(err)
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 	movie.CoverPhotoLink = cover.String()
 	titleAndDescription := el.ChildTexts("small")
@@ -82,7 +90,7 @@ func (engine *TvSeriesEngine) parseSingleMovie(el *colly.HTMLElement, index int)
 	downloadLink, err := url.Parse(link + "&ftype=2")
 
 	if err != nil {
-		log.Fatal(err)
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
 	}
 
 	movie.DownloadLink = downloadLink
@@ -92,7 +100,11 @@ func (engine *TvSeriesEngine) parseSingleMovie(el *colly.HTMLElement, index int)
 func (engine *TvSeriesEngine) updateDownloadProps(downloadCollector *colly.Collector, movies *[]Movie) {
 	// For listing movies and retrieving the most recently updated episode
 	downloadCollector.OnHTML("div[itemprop=episode]", func(e *colly.HTMLElement) {
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		if len(e.ChildTexts("b")) > 1 {
 			movie.Title = e.ChildTexts("b")[0]
 		}
@@ -104,7 +116,8 @@ func (engine *TvSeriesEngine) updateDownloadProps(downloadCollector *colly.Colle
 			link := e.Request.AbsoluteURL(e.ChildAttr("a", "href")) + "&ftype=2" 	
 			downloadLink, err := url.Parse(link)
 			if err != nil {
-				log.Fatal(err)
+				log.Error(err)
+				return
 			} else {
 				movie.DownloadLink = downloadLink
 			}
@@ -128,11 +141,16 @@ func (engine *TvSeriesEngine) updateDownloadProps(downloadCollector *colly.Colle
 	for _, iden := range [...]string{ "a[id=dlink3]",  "a[id=dlink4]", "a[id=dlink2]"} {
 		// Update movie download link if ul.downloadlinks on page
 		downloadCollector.OnHTML(iden, func(e *colly.HTMLElement) {
-			movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+			movie, err := getMovieFromCtx(e.Request, movies)
+			if err != nil {
+				log.Error(err)
+				return
+			}
 			link := e.Request.AbsoluteURL(e.Attr("href")) 	
 			downloadLink, err := url.Parse(link)
 			if err != nil {
-				log.Fatal(err)
+				log.Error(err)
+				return
 			} else {
 				movie.DownloadLink = downloadLink
 			}
@@ -144,7 +162,11 @@ func (engine *TvSeriesEngine) updateDownloadProps(downloadCollector *colly.Colle
 
 	// Update Download Link if "Download" HTML on page
 	downloadCollector.OnHTML("div.filedownload", func(e *colly.HTMLElement) {
-		movie := &(*movies)[getMovieIndexFromCtx(e.Request)]
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
 		re := regexp.MustCompile(`(.* MB)`)
 		size := re.FindStringSubmatch(e.ChildText("textcolor2"))[0]
 		if e.ChildAttr("a[id=flink1]", "href") !=  "" {
@@ -163,7 +185,7 @@ func (engine *TvSeriesEngine) updateDownloadProps(downloadCollector *colly.Colle
 }
 
 // List : list all the movies on a page
-func (engine *TvSeriesEngine) List(page int) SearchResult {
+func (engine *TvSeriesEngine) List(ctx context.Context, page int) (SearchResult, error) {
 	engine.mode = ListMode
 	result := SearchResult{
 		Query: "Series From A to Z latest episode each - Page " + strconv.Itoa(page),
@@ -172,16 +194,12 @@ func (engine *TvSeriesEngine) List(page int) SearchResult {
 	q.Set("alpha", "AtoZ")
 	q.Set("pg", strconv.Itoa(page))
 	engine.ListURL.RawQuery = q.Encode()
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, page), err
 }
 
 // Search : Searches tvseries for a particular query and return an array of movies
-func (engine *TvSeriesEngine) Search(param ...string) SearchResult {
+func (engine *TvSeriesEngine) Search(ctx context.Context, param ...string) (SearchResult, error) {
 	query := param[0]
 	engine.mode = SearchMode
 	result := SearchResult{
@@ -196,10 +214,6 @@ func (engine *TvSeriesEngine) Search(param ...string) SearchResult {
 		q.Set("pg", param[1])
 	}
 	engine.SearchURL.RawQuery = q.Encode()
-	movies, err := Scrape(engine)
-	if err != nil {
-		log.Fatal(err)
-	}
-	result.Movies = movies
-	return result
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, searchPage(param)), err
 }
@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+// X1337 : An Engine for the 1337x torrent index
+type X1337 struct {
+	Props
+}
+
+func init() {
+	RegisterEngine("1337x", func() Engine { return NewX1337Engine() })
+}
+
+// NewX1337Engine : A Movie Engine Constructor for 1337x
+func NewX1337Engine() *X1337 {
+	base := "https://1337x.to/"
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Search URL
+	searchURL, err := url.Parse(base)
+	if err != nil {
+		log.Fatal(err)
+	}
+	searchURL.Path = "/category-search/"
+
+	// List URL
+	listURL, err := url.Parse(base)
+	if err != nil {
+		log.Fatal(err)
+	}
+	listURL.Path = "/cat/Movies/"
+
+	x1337Engine := X1337{}
+	x1337Engine.Name = "1337x"
+	x1337Engine.BaseURL = baseURL
+	x1337Engine.Description = `1337x is a torrent index, results are returned as magnet links`
+	x1337Engine.Features = Capabilities{Pagination: true, SearchByYear: true, Magnet: true, NeedsProxy: true, AntiBot: true}
+	x1337Engine.SearchURL = searchURL
+	x1337Engine.ListURL = listURL
+	return &x1337Engine
+}
+
+// Engine Interface Methods
+
+func (engine *X1337) String() string {
+	st := fmt.Sprintf("%s (%s)", engine.Name, engine.BaseURL)
+	return st
+}
+
+func (engine *X1337) getParseAttrs() (string, string, error) {
+	return "table.table-list tbody", "tr", nil
+}
+
+func (engine *X1337) parseSingleMovie(el *colly.HTMLElement, index int) (Movie, error) {
+	movie := Movie{
+		Index:    index,
+		IsSeries: false,
+		Source:   engine.Name,
+	}
+	// the first link in the name column is the category icon
+	movie.Title = strings.TrimSpace(el.ChildText("td.name a:nth-of-type(2)"))
+	re := regexp.MustCompile(`\b(19|20)\d{2}\b`)
+	if year := re.FindString(movie.Title); year != "" {
+		movie.Year, _ = strconv.Atoi(year)
+	}
+	// the size column also holds the seeders in a span
+	movie.Size = strings.TrimSpace(strings.TrimSuffix(el.ChildText("td.size"), el.ChildText("td.size span")))
+	movie.UploadDate = strings.TrimSpace(el.ChildText("td.coll-date"))
+
+	href := el.ChildAttr("td.name a:nth-of-type(2)", "href")
+	if href == "" {
+		return movie, fmt.Errorf("%w: no torrent link for %s", ErrParseFailure, movie.Title)
+	}
+	downloadLink, err := url.Parse(el.Request.AbsoluteURL(href))
+	if err != nil {
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
+	}
+	movie.DownloadLink = downloadLink
+	return movie, nil
+}
+
+func (engine *X1337) updateDownloadProps(downloadCollector *colly.Collector, movies *[]Movie) {
+	downloadCollector.OnHTML(`a[href^="magnet:"]`, func(e *colly.HTMLElement) {
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		if movie.MagnetLink == "" {
+			movie.MagnetLink = e.Attr("href")
+		}
+	})
+
+	downloadCollector.OnHTML("div.torrent-detail-info img", func(e *colly.HTMLElement) {
+		movie, err := getMovieFromCtx(e.Request, movies)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		movie.CoverPhotoLink = e.Request.AbsoluteURL(e.Attr("src"))
+	})
+}
+
+// x1337Charts : the pages listing the movies of each mode besides Latest
+// The charts are a single page of the top 100 movies
+var x1337Charts = map[ScrapeMode]string{
+	Trending: "/trending/w/movies/",
+	Popular:  "/popular-movies",
+	TopRated: "/top-100-movies",
+}
+
+// List : list all the movies on a page
+func (engine *X1337) List(ctx context.Context, page int) (SearchResult, error) {
+	return engine.ListBy(ctx, Latest, page)
+}
+
+// Modes : 1337x lists the trending, popular and top movies besides the latest
+func (engine *X1337) Modes() []ScrapeMode {
+	return []ScrapeMode{Latest, Trending, Popular, TopRated}
+}
+
+// ListBy : list the movies on a page in mode
+func (engine *X1337) ListBy(ctx context.Context, mode ScrapeMode, page int) (SearchResult, error) {
+	engine.mode = ListMode
+	result := SearchResult{
+		Query: "List of " + mode.listName() + " - Page " + strconv.Itoa(page),
+	}
+	if mode == Latest {
+		engine.ListURL.Path = path.Join("/cat/Movies", strconv.Itoa(page)) + "/"
+	} else {
+		if page > 1 {
+			return result, nil
+		}
+		engine.ListURL.Path = x1337Charts[mode]
+	}
+	movies, err := Scrape(ctx, engine)
+	result = engine.paged(result, movies, page)
+	if mode != Latest {
+		// the charts are a single page
+		result.TotalPages, result.HasNext, result.NextCursor = 1, false, ""
+	}
+	return result, err
+}
+
+// Search : Searches 1337x for a particular query and return an array of movies
+func (engine *X1337) Search(ctx context.Context, param ...string) (SearchResult, error) {
+	query := param[0]
+	engine.mode = SearchMode
+	result := SearchResult{
+		Query: query,
+	}
+	page := "1"
+	if len(param) > 1 {
+		page = param[1]
+	}
+	engine.SearchURL.Path = path.Join("/category-search", query, "Movies", page) + "/"
+	movies, err := Scrape(ctx, engine)
+	return engine.paged(result, movies, searchPage(param)), err
+}
@@ -0,0 +1,310 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// YTS : An Engine for YTS (YIFY) backed by its JSON API rather than scraping
+type YTS struct {
+	Props
+	DetailsURL *url.URL     // URL of the movie_details endpoint
+	Client     *http.Client // Defaults to a client configured like the scraping engines
+}
+
+func init() {
+	RegisterEngine("yts", func() Engine { return NewYtsEngine() })
+}
+
+// NewYtsEngine : A Movie Engine Constructor for YTS
+func NewYtsEngine() *YTS {
+	base := "https://yts.mx/"
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Search and List are both served by list_movies
+	searchURL, err := url.Parse(base + "api/v2/list_movies.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	listURL, err := url.Parse(base + "api/v2/list_movies.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	detailsURL, err := url.Parse(base + "api/v2/movie_details.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ytsEngine := YTS{}
+	ytsEngine.Name = "YTS"
+	ytsEngine.BaseURL = baseURL
+	ytsEngine.Description = `YTS (YIFY) releases high quality movies in small sizes as torrents`
+	ytsEngine.Features = Capabilities{Pagination: true, Magnet: true, NeedsProxy: true}
+	ytsEngine.Languages = []string{"en"}
+	ytsEngine.Regions = []string{"Hollywood"}
+	ytsEngine.SearchURL = searchURL
+	ytsEngine.ListURL = listURL
+	ytsEngine.DetailsURL = detailsURL
+	return &ytsEngine
+}
+
+// Engine Interface Methods
+
+func (engine *YTS) String() string {
+	st := fmt.Sprintf("%s (%s)", engine.Name, engine.BaseURL)
+	return st
+}
+
+// ytsMovie : a movie as returned by the YTS API
+type ytsMovie struct {
+	ID               int
+	Title            string
+	TitleLong        string `json:"title_long"`
+	Year             int
+	Rating           float64
+	Runtime          int
+	Genres           []string
+	Summary          string
+	DescriptionFull  string `json:"description_full"`
+	MediumCoverImage string `json:"medium_cover_image"`
+	LargeCoverImage  string `json:"large_cover_image"`
+	ImdbCode         string `json:"imdb_code"`
+	DateUploaded     string `json:"date_uploaded"`
+	Language         string // ISO 639-1 code of the spoken language
+	Torrents         []struct {
+		URL       string
+		Hash      string
+		Quality   string
+		Type      string
+		Size      string
+		SizeBytes int64 `json:"size_bytes"`
+	}
+}
+
+// ytsResponse : envelope of every YTS API response
+type ytsResponse struct {
+	Status        string
+	StatusMessage string `json:"status_message"`
+	Data          struct {
+		MovieCount int `json:"movie_count"`
+		Limit      int
+		PageNumber int `json:"page_number"`
+		Movies     []ytsMovie
+		Movie      ytsMovie
+	}
+}
+
+// ytsSortBy : the sort_by of list_movies.json listing each mode
+// YTS has no trending list, the movies with the most peers are listed instead
+var ytsSortBy = map[ScrapeMode]string{
+	Latest:   "date_added",
+	Trending: "peers",
+	Popular:  "download_count",
+	TopRated: "rating",
+}
+
+// List : list the most recently added movies on a page
+func (engine *YTS) List(ctx context.Context, page int) (SearchResult, error) {
+	return engine.ListBy(ctx, Latest, page)
+}
+
+// Modes : YTS lists its movies in every mode
+func (engine *YTS) Modes() []ScrapeMode {
+	return []ScrapeMode{Latest, Trending, Popular, TopRated}
+}
+
+// ListBy : list the movies on a page sorted for mode
+func (engine *YTS) ListBy(ctx context.Context, mode ScrapeMode, page int) (SearchResult, error) {
+	engine.mode = ListMode
+	result := SearchResult{
+		Query: "List of " + mode.listName() + " - Page " + strconv.Itoa(page),
+	}
+	q := url.Values{}
+	q.Set("sort_by", ytsSortBy[mode])
+	q.Set("page", strconv.Itoa(page))
+	movies, err := engine.fetchMovies(ctx, *engine.ListURL, q)
+	return engine.paged(result, movies, page), err
+}
+
+// Search : Searches YTS for a particular query and return an array of movies
+func (engine *YTS) Search(ctx context.Context, param ...string) (SearchResult, error) {
+	query := param[0]
+	engine.mode = SearchMode
+	result := SearchResult{
+		Query: query,
+	}
+	q := url.Values{}
+	q.Set("query_term", query)
+	// the API filters movies of a single genre
+	if filters, ok := QueryFrom(ctx); ok && len(filters.Genres) == 1 {
+		q.Set("genre", filters.Genres[0])
+	}
+	if len(param) > 1 {
+		q.Set("page", param[1])
+	}
+	movies, err := engine.fetchMovies(ctx, *engine.SearchURL, q)
+	return engine.paged(result, movies, searchPage(param)), err
+}
+
+// MovieDetails : Retrieve a single movie by its YTS id
+func (engine *YTS) MovieDetails(ctx context.Context, id int) (Movie, error) {
+	q := url.Values{}
+	q.Set("movie_id", strconv.Itoa(id))
+	response, err := engine.get(ctx, *engine.DetailsURL, q)
+	if err != nil {
+		return Movie{}, err
+	}
+	if response.Data.Movie.ID == 0 {
+		return Movie{}, fmt.Errorf("%w: YTS movie %d", ErrMovieNotFound, id)
+	}
+	return engine.parseSingleMovie(response.Data.Movie, 0)
+}
+
+// fetchMovies : the movies of list_movies.json, the pages of the results are
+// worked out from the number of movies found
+func (engine *YTS) fetchMovies(ctx context.Context, endpoint url.URL, q url.Values) ([]Movie, error) {
+	engine.pagination = pagination{}
+	response, err := engine.get(ctx, endpoint, q)
+	if err != nil {
+		return []Movie{}, err
+	}
+	if data := response.Data; data.Limit > 0 {
+		last := (data.MovieCount + data.Limit - 1) / data.Limit
+		engine.pagination = pagination{linked: true, last: last, next: data.PageNumber < last}
+	}
+	movies := []Movie{}
+	for _, m := range response.Data.Movies {
+		movie, err := engine.parseSingleMovie(m, len(movies))
+		if err != nil {
+			log.Debug(err)
+			continue
+		}
+		movies = append(movies, movie)
+	}
+	return movies, nil
+}
+
+func (engine *YTS) get(ctx context.Context, endpoint url.URL, q url.Values) (ytsResponse, error) {
+	var response ytsResponse
+	endpoint.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return response, err
+	}
+	client := engine.Client
+	if client == nil {
+		clientTransport, err := newClientTransport(engine.Name)
+		if err != nil {
+			return response, err
+		}
+		defer clientTransport.CloseIdleConnections()
+		client = &http.Client{Transport: wrapTransport(ctx, clientTransport)}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return response, ctx.Err()
+		}
+		return response, fmt.Errorf("%w: %s: %v", ErrEngineUnavailable, engine.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return response, fmt.Errorf("%w: %s returned %s", ErrEngineUnavailable, engine.Name, resp.Status)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return response, fmt.Errorf("%w: %v", ErrParseFailure, err)
+	}
+	if response.Status != "ok" {
+		return response, fmt.Errorf("%w: %s: %s", ErrEngineUnavailable, engine.Name, response.StatusMessage)
+	}
+	return response, nil
+}
+
+// ytsQualities : torrent qualities from the most to the least preferred
+var ytsQualities = []string{"1080p", "720p", "2160p", "3D", "480p"}
+
+func (engine *YTS) parseSingleMovie(m ytsMovie, index int) (Movie, error) {
+	if len(m.Torrents) == 0 {
+		return Movie{}, fmt.Errorf("%w: %s has no torrents", ErrParseFailure, m.Title)
+	}
+	movie := Movie{
+		Index:          index,
+		Title:          m.TitleLong,
+		CanonicalTitle: m.Title,
+		Year:           m.Year,
+		CoverPhotoLink: m.MediumCoverImage,
+		PosterLink:     m.LargeCoverImage,
+		Description:    m.DescriptionFull,
+		Genres:         strings.Join(m.Genres, ","),
+		Category:       strings.Join(m.Genres, ","),
+		Rating:         m.Rating,
+		Runtime:        m.Runtime,
+		UploadDate:     m.DateUploaded,
+		Source:         engine.Name,
+		IsSeries:       false,
+	}
+	if movie.Description == "" {
+		movie.Description = m.Summary
+	}
+	movie.Language = strings.ToLower(m.Language)
+	engine.tagLanguage(&movie)
+	if m.ImdbCode != "" {
+		movie.ImdbLink = "https://www.imdb.com/title/" + m.ImdbCode
+	}
+
+	torrent := m.Torrents[0]
+	for _, quality := range ytsQualities {
+		found := false
+		for _, t := range m.Torrents {
+			if t.Quality == quality {
+				torrent, found = t, true
+				break
+			}
+		}
+		if found {
+			break
+		}
+	}
+	downloadLink, err := url.Parse(torrent.URL)
+	if err != nil {
+		return movie, fmt.Errorf("%w: %v", ErrParseFailure, err)
+	}
+	movie.DownloadLink = downloadLink
+	movie.MagnetLink = ytsMagnet(torrent.Hash, m.TitleLong)
+	movie.Quality = ParseQuality(torrent.Quality + " " + torrent.Type)
+	// YTS types WEB releases as "web" which is too common a word to parse from titles
+	if movie.Quality.Format == "" && torrent.Type == "web" {
+		movie.Quality.Format = "WEB-DL"
+	}
+	movie.Size = torrent.Size
+	movie.SizeBytes = torrent.SizeBytes
+	return movie, nil
+}
+
+// ytsTrackers : trackers YTS recommends adding to magnet links
+var ytsTrackers = []string{
+	"udp://open.demonii.com:1337/announce",
+	"udp://tracker.openbittorrent.com:80",
+	"udp://tracker.coppersurfer.tk:6969",
+	"udp://tracker.opentrackr.org:1337/announce",
+}
+
+// ytsMagnet : build the magnet link of a torrent from its info hash
+func ytsMagnet(hash, name string) string {
+	if hash == "" {
+		return ""
+	}
+	q := url.Values{}
+	q.Set("dn", name)
+	q["tr"] = ytsTrackers
+	return "magnet:?xt=urn:btih:" + hash + "&" + q.Encode()
+}
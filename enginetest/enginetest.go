@@ -0,0 +1,314 @@
+// Package enginetest records the responses of the sites of engines into fixtures
+// and replays them, so that engines can be tested without reaching the sites.
+//
+// A test searches an engine through a Recorder and compares the movies found
+// with a golden file:
+//
+//	func TestNetNaija(t *testing.T) {
+//		result := enginetest.Search(t, engine.NewNetNaijaEngine(), "jumanji")
+//		enginetest.Golden(t, result.Movies)
+//	}
+//
+// The responses are replayed from testdata/fixtures/<test>.json and the movies
+// compared with testdata/golden/<test>.json. Run the test with GOPHIE_RECORD=1
+// to record the fixtures from the live site, and with GOPHIE_UPDATE_GOLDEN=1 to
+// write the golden file from the movies found
+package enginetest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/spf13/viper"
+)
+
+// Environment variables switching from replaying fixtures to recording them,
+// and from comparing golden files to writing them
+const (
+	RecordEnv       = "GOPHIE_RECORD"
+	UpdateGoldenEnv = "GOPHIE_UPDATE_GOLDEN"
+)
+
+// recordedHeaders : the headers of responses kept in fixtures, others may hold
+// cookies or change on every request
+var recordedHeaders = []string{"Content-Type", "Location"}
+
+// Interaction : A request made by an engine and the response of the site
+type Interaction struct {
+	Method     string
+	URL        string
+	Status     int
+	Header     http.Header `json:",omitempty"`
+	Body       string      `json:",omitempty"`
+	BodyBase64 string      `json:",omitempty"` // bodies which are not text
+}
+
+// Cassette : The interactions of a test, in the order they were recorded
+type Cassette struct {
+	Interactions []Interaction
+}
+
+// Recorder : A transport replaying the responses of a cassette, or recording
+// those of the site into it when Record is set
+type Recorder struct {
+	Path   string // of the cassette
+	Record bool
+
+	mu       sync.Mutex
+	cassette Cassette
+	replayed map[int]bool
+}
+
+// NewRecorder : A recorder of the cassette at path, loaded unless recording
+func NewRecorder(path string, record bool) (*Recorder, error) {
+	r := &Recorder{Path: path, Record: record, replayed: map[int]bool{}}
+	if record {
+		return r, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("No fixtures at %s, record them with %s=1: %w", path, RecordEnv, err)
+	}
+	if err = json.Unmarshal(b, &r.cassette); err != nil {
+		return nil, fmt.Errorf("Invalid fixtures at %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Wrap : The transport of requests sent through upstream, for engine.WithTransportWrapper
+func (r *Recorder) Wrap(upstream http.RoundTripper) http.RoundTripper {
+	return roundTripper(func(req *http.Request) (*http.Response, error) {
+		if r.Record {
+			return r.record(upstream, req)
+		}
+		return r.replay(req)
+	})
+}
+
+// Context : ctx whose engine requests are recorded or replayed by r
+func (r *Recorder) Context(ctx context.Context) context.Context {
+	return engine.WithTransportWrapper(ctx, r.Wrap)
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// replay : the first response recorded for the method and URL of req not replayed yet,
+// requests repeated more often than recorded get the last response again
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	found := -1
+	for i, interaction := range r.cassette.Interactions {
+		if interaction.Method != req.Method || interaction.URL != req.URL.String() {
+			continue
+		}
+		found = i
+		if !r.replayed[i] {
+			break
+		}
+	}
+	if found < 0 {
+		return nil, fmt.Errorf("No fixture for %s %s in %s, record them again with %s=1", req.Method, req.URL, r.Path, RecordEnv)
+	}
+	r.replayed[found] = true
+	interaction := r.cassette.Interactions[found]
+	body := []byte(interaction.Body)
+	if interaction.BodyBase64 != "" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(interaction.BodyBase64); err != nil {
+			return nil, err
+		}
+	}
+	header := http.Header{}
+	for key, values := range interaction.Header {
+		header[key] = values
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// record : send req through upstream and keep its response
+func (r *Recorder) record(upstream http.RoundTripper, req *http.Request) (*http.Response, error) {
+	resp, err := upstream.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	interaction := Interaction{Method: req.Method, URL: req.URL.String(), Status: resp.StatusCode}
+	for _, key := range recordedHeaders {
+		if values := resp.Header.Values(key); len(values) > 0 {
+			if interaction.Header == nil {
+				interaction.Header = http.Header{}
+			}
+			interaction.Header[key] = values
+		}
+	}
+	if utf8.Valid(body) {
+		interaction.Body = string(body)
+	} else {
+		interaction.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Save : Write the recorded interactions to the cassette
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(r.Path), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.Path, append(b, '\n'), 0644)
+}
+
+// fileName : the name of the files of the test t, subtests are kept in a directory
+func fileName(t testing.TB) string {
+	return filepath.FromSlash(t.Name()) + ".json"
+}
+
+// enabled : whether the environment variable is set to a true value
+func enabled(env string) bool {
+	switch strings.ToLower(os.Getenv(env)) {
+	case "", "0", "false", "no":
+		return false
+	}
+	return true
+}
+
+// New : The recorder of the fixtures of t at testdata/fixtures/<test>.json, saved
+// when t ends if GOPHIE_RECORD is set. Engines do not use the page cache meanwhile,
+// so that every request is recorded or replayed
+func New(t testing.TB) *Recorder {
+	t.Helper()
+	record := enabled(RecordEnv)
+	r, err := NewRecorder(filepath.Join("testdata", "fixtures", fileName(t)), record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ignoreCache := viper.Get("ignore-cache")
+	viper.Set("ignore-cache", true)
+	t.Cleanup(func() {
+		viper.Set("ignore-cache", ignoreCache)
+		if record {
+			if err := r.Save(); err != nil {
+				t.Errorf("Could not save the fixtures: %v", err)
+			}
+		}
+	})
+	return r
+}
+
+// Search : Search e with params, replaying the fixtures of t
+func Search(t testing.TB, e engine.Engine, params ...string) engine.SearchResult {
+	t.Helper()
+	result, err := e.Search(New(t).Context(context.Background()), params...)
+	if err != nil {
+		t.Fatalf("Search of %s failed: %v", e, err)
+	}
+	return result
+}
+
+// List : List page of e in mode, replaying the fixtures of t
+func List(t testing.TB, e engine.Engine, mode engine.ScrapeMode, page int) engine.SearchResult {
+	t.Helper()
+	result, err := engine.ListBy(New(t).Context(context.Background()), e, mode, page)
+	if err != nil {
+		t.Fatalf("List of %s failed: %v", e, err)
+	}
+	return result
+}
+
+// Golden : Compare movies with the golden file of t at testdata/golden/<test>.json,
+// writing it instead when GOPHIE_UPDATE_GOLDEN is set
+func Golden(t testing.TB, movies []engine.Movie) {
+	t.Helper()
+	if movies == nil {
+		movies = []engine.Movie{}
+	}
+	got, err := json.MarshalIndent(movies, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+	path := filepath.Join("testdata", "golden", fileName(t))
+	if enabled(UpdateGoldenEnv) {
+		if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err == nil {
+			err = ioutil.WriteFile(path, got, 0644)
+		}
+		if err != nil {
+			t.Fatalf("Could not write the golden file: %v", err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("No golden file at %s, write it with %s=1: %v", path, UpdateGoldenEnv, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("The movies do not match %s, check the differences and write it again with %s=1\n%s",
+			path, UpdateGoldenEnv, diff(string(want), string(got)))
+	}
+}
+
+// diff : the lines of want and got which differ, up to 20 of them
+func diff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var b strings.Builder
+	shown := 0
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w == g {
+			continue
+		}
+		fmt.Fprintf(&b, "line %d:\n- %s\n+ %s\n", i+1, w, g)
+		if shown++; shown == 20 {
+			b.WriteString("...\n")
+			break
+		}
+	}
+	return b.String()
+}
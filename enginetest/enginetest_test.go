@@ -0,0 +1,75 @@
+package enginetest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+func TestX1337(t *testing.T) {
+	result := Search(t, engine.NewX1337Engine(), "jumanji")
+	Golden(t, result.Movies)
+}
+
+func TestRecorder(t *testing.T) {
+	var requests int
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Set-Cookie", "session=secret")
+		fmt.Fprintf(w, "<html>%s %d</html>", r.URL.Path, requests)
+	}))
+	defer site.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	get := func(r *Recorder, page string) string {
+		client := &http.Client{Transport: r.Wrap(http.DefaultTransport)}
+		resp, err := client.Get(site.URL + page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.Header.Get("Content-Type") + " " + string(body)
+	}
+
+	recorder, _ := NewRecorder(path, true)
+	for _, page := range []string{"/a", "/a", "/b"} {
+		get(recorder, page)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(saved), "secret") {
+		t.Errorf("Expected cookies to be left out of the fixtures, got %s", saved)
+	}
+
+	replayer, err := NewRecorder(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// repeated requests get their responses in the order they were recorded
+	var got []string
+	for _, page := range []string{"/b", "/a", "/a", "/a"} {
+		got = append(got, get(replayer, page))
+	}
+	want := "text/html <html>/b 3</html>|text/html <html>/a 1</html>|text/html <html>/a 2</html>|text/html <html>/a 2</html>"
+	if strings.Join(got, "|") != want || requests != 3 {
+		t.Errorf("Expected the recorded responses %s, got %s", want, strings.Join(got, "|"))
+	}
+	client := &http.Client{Transport: replayer.Wrap(nil)}
+	if _, err := client.Get(site.URL + "/c"); err == nil || !strings.Contains(err.Error(), "No fixture for GET") {
+		t.Errorf("Expected requests without fixtures to fail, got %v", err)
+	}
+
+	if _, err := NewRecorder(filepath.Join(t.TempDir(), "missing.json"), false); err == nil {
+		t.Error("Expected missing fixtures to fail")
+	}
+}
@@ -0,0 +1,118 @@
+// Package export saves search results and download lists to files and reads them
+// back, so that movies can be found on one machine and downloaded on another
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+// Version : the version of the files written, files of later versions are refused
+const Version = 1
+
+// ErrInvalidFile : a file is not an export of gophie
+var ErrInvalidFile = errors.New("Not a file exported by gophie")
+
+// File : Movies exported, with where and when they were found
+type File struct {
+	Version  int
+	Exported time.Time
+	Host     string `json:",omitempty"` // the machine the movies were found on
+	Query    string `json:",omitempty"` // of the search or list they were found by
+	Movies   []engine.Movie
+}
+
+// New : The export of the movies of result
+func New(result engine.SearchResult) File {
+	host, _ := os.Hostname()
+	movies := result.Movies
+	if movies == nil {
+		movies = []engine.Movie{}
+	}
+	return File{Version: Version, Exported: time.Now().UTC(), Host: host, Query: result.Query, Movies: movies}
+}
+
+// Result : the movies of f as a SearchResult
+func (f File) Result() engine.SearchResult {
+	return engine.SearchResult{Query: f.Query, Movies: f.Movies}
+}
+
+// Write : Write f to w as indented JSON
+func Write(w io.Writer, f File) error {
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// Read : The export read from r. A SearchResult or a list of movies, as printed
+// by gophie search --output json, is read as an export too
+func Read(r io.Reader) (File, error) {
+	br := bufio.NewReader(r)
+	var first byte
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return File{}, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		if b != ' ' && b != '\n' && b != '\r' && b != '\t' {
+			first = b
+			br.UnreadByte()
+			break
+		}
+	}
+	var f File
+	dec := json.NewDecoder(br)
+	switch first {
+	case '[':
+		if err := dec.Decode(&f.Movies); err != nil {
+			return File{}, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+	case '{':
+		if err := dec.Decode(&f); err != nil {
+			return File{}, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		if f.Movies == nil {
+			return File{}, fmt.Errorf("%w: it has no movies", ErrInvalidFile)
+		}
+	default:
+		return File{}, ErrInvalidFile
+	}
+	if f.Version > Version {
+		return File{}, fmt.Errorf("The file was exported by a later version of gophie (%d), update gophie to import it", f.Version)
+	}
+	return f, nil
+}
+
+// WriteFile : Write f to the file at path
+func WriteFile(path string, f File) error {
+	var buf bytes.Buffer
+	if err := Write(&buf, f); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// ReadFile : The export in the file at path
+func ReadFile(path string) (File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return File{}, err
+	}
+	defer file.Close()
+	f, err := Read(file)
+	if err != nil {
+		return File{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,59 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+func TestExport(t *testing.T) {
+	link, _ := url.Parse("https://downloads.example/jumanji.mp4")
+	result := engine.SearchResult{
+		Query:  "jumanji",
+		Movies: []engine.Movie{{Title: "Jumanji", Source: "NetNaija", DownloadLink: link, Size: "700 MB"}},
+	}
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := WriteFile(path, New(result)); err != nil {
+		t.Fatal(err)
+	}
+	f, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Version != Version || f.Query != "jumanji" || f.Exported.IsZero() {
+		t.Errorf("Unexpected export %+v", f)
+	}
+	movies := f.Result().Movies
+	if len(movies) != 1 || movies[0].Title != "Jumanji" || movies[0].DownloadLink.String() != link.String() {
+		t.Errorf("Expected the movie exported, got %+v", movies)
+	}
+
+	// results printed by gophie search --output json are read too
+	var buf bytes.Buffer
+	if err = Write(&buf, New(result)); err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{
+		`  [{"Title": "Jumanji", "DownloadLink": "https://downloads.example/jumanji.mp4"}]`,
+		`{"Query": "jumanji", "Movies": [{"Title": "Jumanji"}]}`,
+		buf.String(),
+	} {
+		if f, err = Read(strings.NewReader(body)); err != nil || len(f.Movies) != 1 {
+			t.Errorf("Expected a movie read from %s, got %+v %v", body, f, err)
+		}
+	}
+
+	for _, body := range []string{"", "movies", `{"Title": "Jumanji"}`} {
+		if _, err = Read(strings.NewReader(body)); !errors.Is(err, ErrInvalidFile) {
+			t.Errorf("Expected %q to be refused as invalid, got %v", body, err)
+		}
+	}
+	if _, err = Read(strings.NewReader(`{"Version": 2, "Movies": []}`)); err == nil {
+		t.Error("Expected an export of a later version to be refused")
+	}
+}
@@ -4,17 +4,28 @@ module github.com/go-phie/gophie
 go 1.16
 
 require (
+	github.com/PuerkitoBio/goquery v1.5.1
 	github.com/bisoncorps/mplayer v0.0.0-20200330192254-e2f647162350
 	github.com/briandowns/spinner v1.11.1
+	github.com/chromedp/cdproto v0.0.0-20200116234248-4da64dd111ac
 	github.com/chromedp/chromedp v0.5.3
+	github.com/gdamore/tcell/v2 v2.4.1-0.20210905002822-f057f0a857a1
+	github.com/gen2brain/beeep v0.0.0-20220402123239-6a3042f4b71a
 	github.com/gocolly/colly/v2 v2.1.0
-	github.com/gorilla/handlers v1.5.1
 	github.com/iawia002/annie v0.0.0-20200720035628-03c160f28b4b
 	github.com/manifoldco/promptui v0.7.0
 	github.com/mitchellh/go-homedir v1.1.0
+	github.com/prometheus/client_golang v1.12.2
+	github.com/rivo/tview v0.0.0-20211202162923-2a6de950f73b
 	github.com/sirupsen/logrus v1.6.0
 	github.com/smartystreets/goconvey v1.6.4
 	github.com/spf13/cobra v1.0.0
 	github.com/spf13/viper v1.7.0
 	github.com/tebeka/selenium v0.9.9
+	go.etcd.io/bbolt v1.3.6
+	golang.org/x/net v0.0.0-20210525063256-abc453219eb5
+	google.golang.org/grpc v1.46.2
+	google.golang.org/protobuf v1.28.1
+	gopkg.in/cheggaaa/pb.v1 v1.0.28
+	modernc.org/sqlite v1.14.8
 )
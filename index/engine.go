@@ -0,0 +1,66 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+)
+
+// IndexedEngine : An Engine adding the movies it finds to an Index
+type IndexedEngine struct {
+	engine.Engine
+	index *Index
+}
+
+// NewIndexedEngine : Wrap e so its results are added to idx
+func NewIndexedEngine(e engine.Engine, idx *Index) *IndexedEngine {
+	return &IndexedEngine{Engine: e, index: idx}
+}
+
+// add : Index the movies of result, failures are only logged as the result is
+// still good
+func (e *IndexedEngine) add(result engine.SearchResult) {
+	movies := make([]engine.Movie, len(result.Movies))
+	for i, movie := range result.Movies {
+		if movie.Source == "" {
+			movie.Source = e.Engine.String()
+		}
+		movies[i] = movie
+	}
+	if err := e.index.Add(movies); err != nil {
+		log.Warnf("Could not index the movies of %s: %v", e.Engine, err)
+	}
+}
+
+// Search : Search the wrapped engine and index the results
+func (e *IndexedEngine) Search(ctx context.Context, param ...string) (engine.SearchResult, error) {
+	result, err := e.Engine.Search(ctx, param...)
+	e.add(result)
+	return result, err
+}
+
+// List : List the wrapped engine and index the results
+func (e *IndexedEngine) List(ctx context.Context, page int) (engine.SearchResult, error) {
+	result, err := e.Engine.List(ctx, page)
+	e.add(result)
+	return result, err
+}
+
+// Modes : the modes of the wrapped engine
+func (e *IndexedEngine) Modes() []engine.ScrapeMode {
+	return engine.Modes(e.Engine)
+}
+
+// ListBy : List the wrapped engine in mode and index the results
+func (e *IndexedEngine) ListBy(ctx context.Context, mode engine.ScrapeMode, page int) (engine.SearchResult, error) {
+	result, err := engine.ListBy(ctx, e.Engine, mode, page)
+	e.add(result)
+	return result, err
+}
+
+// MarshalJSON : engines are described by the engine being indexed
+func (e *IndexedEngine) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Engine)
+}
@@ -0,0 +1,185 @@
+// Package index keeps every movie scraped in a local SQLite database with an
+// FTS5 full-text index of their titles, descriptions, cast and genres, so that
+// everything seen before is searched instantly and offline
+package index
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	// the pure Go SQLite driver, built with FTS5
+	_ "modernc.org/sqlite"
+)
+
+// DefaultLimit : movies returned by a search when no limit is given
+const DefaultLimit = 50
+
+const schema = `
+CREATE TABLE IF NOT EXISTS movies (
+	id      INTEGER PRIMARY KEY,
+	key     TEXT NOT NULL UNIQUE,
+	source  TEXT NOT NULL,
+	movie   TEXT NOT NULL,
+	seen_at INTEGER NOT NULL
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS movies_fts USING fts5(
+	title, description, people, genres, category,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+`
+
+// Index : The movies scraped by the engines, searched with FTS5
+type Index struct {
+	db *sql.DB
+}
+
+// Open : Open (or create) the index stored at path
+func Open(path string) (*Index, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// writes are serialized by SQLite, other processes are waited for
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{"PRAGMA busy_timeout = 5000", "PRAGMA journal_mode = WAL", schema} {
+		if _, err = db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("could not open the index at %s: %w", path, err)
+		}
+	}
+	return &Index{db: db}, nil
+}
+
+// Close : Close the database of the index
+func (idx *Index) Close() error {
+	return idx.db.Close()
+}
+
+// key : what the movie is known by in the index, its engine and its link
+func key(movie engine.Movie) string {
+	link := movie.MagnetLink
+	if link == "" {
+		link = movie.DownloadLink.String()
+	}
+	return strings.ToLower(movie.Source) + " " + link
+}
+
+// Add : Index the movies, replacing those of the same engine and link which
+// were indexed before. Movies without download links are left out
+func (idx *Index) Add(movies []engine.Movie) error {
+	tx, err := idx.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	now := time.Now().UnixNano()
+	for _, movie := range movies {
+		if movie.DownloadLink == nil {
+			continue
+		}
+		data, err := json.Marshal(&movie)
+		if err != nil {
+			return err
+		}
+		var id int64
+		err = tx.QueryRow(`INSERT INTO movies (key, source, movie, seen_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT (key) DO UPDATE SET movie = excluded.movie, seen_at = excluded.seen_at
+			RETURNING id`, key(movie), movie.Source, string(data), now).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(`DELETE FROM movies_fts WHERE rowid = ?`, id); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO movies_fts (rowid, title, description, people, genres, category) VALUES (?, ?, ?, ?, ?, ?)`,
+			id, movie.Title, movie.Description, movie.Cast, movie.Genres, movie.Category)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Count : How many movies are indexed
+func (idx *Index) Count() (int, error) {
+	var count int
+	err := idx.db.QueryRow(`SELECT count(*) FROM movies`).Scan(&count)
+	return count, err
+}
+
+// termRe : the words of queries
+var termRe = regexp.MustCompile(`[\pL\pN]+`)
+
+// match : the FTS5 query matching movies with every word of text, the last
+// word may be the start of a word
+func match(text string) string {
+	terms := termRe.FindAllString(text, -1)
+	for i, term := range terms {
+		terms[i] = strconv.Quote(term)
+	}
+	if len(terms) > 0 {
+		terms[len(terms)-1] += "*"
+	}
+	return strings.Join(terms, " ")
+}
+
+// Search : The indexed movies matching query, best matches first, at most limit
+// of them. Titles weigh the most, the year: and genre: operators of the query
+// filter the movies as they do those of the engines. Without words the movies
+// seen last are returned
+func (idx *Index) Search(ctx context.Context, query string, limit int) (engine.SearchResult, error) {
+	result := engine.SearchResult{Query: query}
+	q, err := engine.ParseSearch(query)
+	if err != nil {
+		return result, err
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	// filtered movies are limited once filtered
+	sqlLimit := limit
+	if q.Filtered() {
+		sqlLimit = -1
+	}
+	var rows *sql.Rows
+	if terms := match(q.Text); terms != "" {
+		rows, err = idx.db.QueryContext(ctx, `SELECT m.movie FROM movies_fts JOIN movies m ON m.id = movies_fts.rowid
+			WHERE movies_fts MATCH ? ORDER BY bm25(movies_fts, 10.0, 1.0, 2.0, 2.0, 1.0) LIMIT ?`, terms, sqlLimit)
+	} else {
+		rows, err = idx.db.QueryContext(ctx, `SELECT movie FROM movies ORDER BY seen_at DESC, id DESC LIMIT ?`, sqlLimit)
+	}
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err = rows.Scan(&data); err != nil {
+			return result, err
+		}
+		var movie engine.Movie
+		if err = json.Unmarshal([]byte(data), &movie); err != nil {
+			return result, err
+		}
+		result.Movies = append(result.Movies, movie)
+	}
+	if err = rows.Err(); err != nil {
+		return result, err
+	}
+	result = q.Filter(result)
+	result.Query = query
+	if len(result.Movies) > limit {
+		result.Movies = result.Movies[:limit]
+	}
+	for i := range result.Movies {
+		result.Movies[i].Index = i
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package index
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+func movie(source, title string, year int, genres string) engine.Movie {
+	link, _ := url.Parse("https://" + source + ".example/" + url.PathEscape(title) + ".mp4")
+	return engine.Movie{Title: title, Year: year, Genres: genres, Source: source, DownloadLink: link}
+}
+
+func TestIndex(t *testing.T) {
+	idx, err := Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	jumanji := movie("FzMovies", "Jumanji: Welcome to the Jungle", 2017, "Action,Comedy")
+	jumanji.Description = "Four teenagers are sucked into a video game"
+	err = idx.Add([]engine.Movie{
+		jumanji,
+		movie("NetNaija", "Jumanji", 1995, "Adventure"),
+		movie("NetNaija", "The Jungle Book", 2016, "Adventure"),
+		{Title: "No Link"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// movies seen again replace those indexed before
+	again := movie("NetNaija", "Jumanji", 1995, "Adventure,Family")
+	if err = idx.Add([]engine.Movie{again}); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := idx.Count(); count != 3 {
+		t.Errorf("Expected 3 movies indexed, got %d", count)
+	}
+
+	result, err := idx.Search(context.Background(), "jumanji", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Movies) != 2 || result.Movies[0].Title != "Jumanji" || result.Movies[0].Genres != "Adventure,Family" {
+		t.Errorf("Expected the closest title first, got %+v", result.Movies)
+	}
+	// the last word is matched as the start of a word, descriptions are searched
+	if result, _ = idx.Search(context.Background(), "video gam", 0); len(result.Movies) != 1 || result.Movies[0].Year != 2017 {
+		t.Errorf("Expected the movie of the description, got %+v", result.Movies)
+	}
+	if result, _ = idx.Search(context.Background(), "jungle year:2016", 0); len(result.Movies) != 1 || result.Movies[0].Title != "The Jungle Book" {
+		t.Errorf("Expected the movies filtered by year, got %+v", result.Movies)
+	}
+	if result, _ = idx.Search(context.Background(), "", 1); len(result.Movies) != 1 || result.Movies[0].Genres != "Adventure,Family" {
+		t.Errorf("Expected the movie seen last, got %+v", result.Movies)
+	}
+}
@@ -0,0 +1,212 @@
+// Package jobs runs searches in the background of the API server, so that slow
+// scrapes of many pages or of every engine are not cut short by the timeouts of
+// HTTP clients and proxies. Jobs are stored in a bbolt database, those still
+// pending or running when the server stops are run again once it is restarted
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	bolt "go.etcd.io/bbolt"
+)
+
+var jobsBucket = []byte("jobs")
+
+// ErrNotFound : there is no job with the ID
+var ErrNotFound = errors.New("Job not found")
+
+// Status : state of a job
+type Status string
+
+// Statuses of jobs
+const (
+	Pending Status = "pending" // waiting for a worker
+	Running Status = "running"
+	Done    Status = "done"
+	Failed  Status = "failed" // see Error
+)
+
+// Finished : whether the job is done or failed
+func (s Status) Finished() bool {
+	return s == Done || s == Failed
+}
+
+// Request : What a job searches for
+type Request struct {
+	Query   string
+	Engine  string     // all for every engine
+	Pages   int        // of the engine searched, 1 when 0
+	Filter  url.Values `json:",omitempty"` // query parameters filtering and sorting the movies, as those of /search
+	Webhook string     `json:",omitempty"` // the job is POSTed to it once finished
+}
+
+// Job : A search run in the background
+type Job struct {
+	ID string
+	Request
+	Status     Status
+	Movies     []engine.Movie `json:",omitempty"`
+	Error      string         `json:",omitempty"`
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Store : The jobs of the API server, the database is only opened for the
+// duration of an operation like the download queue
+type Store struct {
+	path string
+}
+
+// Open : Open (or create) the jobs stored at path
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := s.update(func(b *bolt.Bucket) error { return nil }); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// withDB : run fn in a transaction on the jobs bucket
+func (s *Store) withDB(writable bool, fn func(*bolt.Bucket) error) error {
+	db, err := bolt.Open(s.path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if !writable {
+		return db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket(jobsBucket)
+			if b == nil {
+				return nil
+			}
+			return fn(b)
+		})
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(jobsBucket)
+		if err != nil {
+			return err
+		}
+		return fn(b)
+	})
+}
+
+func (s *Store) update(fn func(*bolt.Bucket) error) error {
+	return s.withDB(true, fn)
+}
+
+func putJob(b *bolt.Bucket, job *Job) error {
+	v, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(job.ID), v)
+}
+
+func getJob(b *bolt.Bucket, id string) (Job, error) {
+	var job Job
+	v := b.Get([]byte(id))
+	if v == nil {
+		return job, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return job, json.Unmarshal(v, &job)
+}
+
+// newID : a random ID, so that the jobs of other clients cannot be guessed
+func newID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Add : Store a pending job for req
+func (s *Store) Add(req Request) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+	job := Job{ID: id, Request: req, Status: Pending, CreatedAt: time.Now()}
+	return job, s.update(func(b *bolt.Bucket) error { return putJob(b, &job) })
+}
+
+// Get : The job with id
+func (s *Store) Get(id string) (Job, error) {
+	var job Job
+	err := s.withDB(false, func(b *bolt.Bucket) (err error) {
+		job, err = getJob(b, id)
+		return err
+	})
+	if err == nil && job.ID == "" {
+		err = fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return job, err
+}
+
+// List : The jobs in the order they were created
+func (s *Store) List() ([]Job, error) {
+	var jobs []Job
+	err := s.withDB(false, func(b *bolt.Bucket) error {
+		return b.ForEach(func(_, v []byte) error {
+			var job Job
+			if err := json.Unmarshal(v, &job); err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return jobs, err
+}
+
+// Update : Change the job with id using fn
+func (s *Store) Update(id string, fn func(*Job)) error {
+	return s.update(func(b *bolt.Bucket) error {
+		job, err := getJob(b, id)
+		if err != nil {
+			return err
+		}
+		fn(&job)
+		return putJob(b, &job)
+	})
+}
+
+// Prune : Remove the jobs finished before t and return how many were removed
+func (s *Store) Prune(t time.Time) (int, error) {
+	removed := 0
+	err := s.update(func(b *bolt.Bucket) error {
+		var ids [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var job Job
+			if err := json.Unmarshal(v, &job); err != nil {
+				return err
+			}
+			if job.Status.Finished() && job.FinishedAt.Before(t) {
+				ids = append(ids, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err = b.Delete(id); err != nil {
+				return err
+			}
+		}
+		removed = len(ids)
+		return nil
+	})
+	return removed, err
+}
@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+func TestStore(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.Add(Request{Query: "jumanji", Engine: "all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != Pending || len(job.ID) != 24 {
+		t.Errorf("Expected a pending job with a random ID, got %+v", job)
+	}
+	if _, err = s.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	old, _ := s.Add(Request{Query: "avengers"})
+	s.Update(old.ID, func(job *Job) {
+		job.Status = Done
+		job.FinishedAt = time.Now().Add(-2 * time.Hour)
+	})
+	if removed, err := s.Prune(time.Now().Add(-time.Hour)); err != nil || removed != 1 {
+		t.Errorf("Expected the old job pruned, removed %d %v", removed, err)
+	}
+	if jobs, _ := s.List(); len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Errorf("Expected the pending job kept, got %+v", jobs)
+	}
+}
+
+func TestRunner(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a job running when the server stopped is run again
+	interrupted, _ := s.Add(Request{Query: "interrupted"})
+	s.Update(interrupted.ID, func(job *Job) { job.Status = Running })
+
+	notified := make(chan Job, 2)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var job Job
+		json.NewDecoder(r.Body).Decode(&job)
+		notified <- job
+	}))
+	defer hook.Close()
+
+	r := NewRunner(s, 2, func(ctx context.Context, req Request) ([]engine.Movie, error) {
+		if req.Query == "fails" {
+			return nil, engine.ErrEngineUnavailable
+		}
+		link, _ := url.Parse("https://movies.example/" + req.Query + ".mp4")
+		return []engine.Movie{{Title: req.Query, DownloadLink: link}}, nil
+	})
+	r.PollInterval = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- r.Run(ctx) }()
+
+	submitted, err := r.Submit(Request{Query: "jumanji", Webhook: hook.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing, _ := r.Submit(Request{Query: "fails", Webhook: hook.URL})
+	for i := 0; i < 2; i++ {
+		select {
+		case job := <-notified:
+			switch job.ID {
+			case submitted.ID:
+				if job.Status != Done || len(job.Movies) != 1 || job.Movies[0].Title != "jumanji" {
+					t.Errorf("Expected the movie found, got %+v", job)
+				}
+			case failing.ID:
+				if job.Status != Failed || job.Error != engine.ErrEngineUnavailable.Error() {
+					t.Errorf("Expected the job failed, got %+v", job)
+				}
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the webhooks of the jobs to be called")
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	job, _ := s.Get(interrupted.ID)
+	for !job.Status.Finished() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		job, _ = s.Get(interrupted.ID)
+	}
+	if job.Status != Done || len(job.Movies) != 1 {
+		t.Errorf("Expected the interrupted job run again, got %+v", job)
+	}
+	cancel()
+	<-stopped
+}
@@ -0,0 +1,206 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+)
+
+// Defaults of Runner
+const (
+	DefaultWorkers      = 2
+	DefaultPollInterval = 2 * time.Second
+	DefaultTTL          = 24 * time.Hour
+)
+
+// SearchFunc : Run the search of req until ctx is cancelled
+type SearchFunc func(ctx context.Context, req Request) ([]engine.Movie, error)
+
+// Runner : Runs the pending jobs of a store a few at a time
+type Runner struct {
+	Store        *Store
+	Search       SearchFunc
+	Workers      int           // jobs run at the same time
+	PollInterval time.Duration // how often the store is checked for jobs submitted by other processes
+	TTL          time.Duration // how long finished jobs are kept
+	Client       *http.Client  // posts to the webhooks of jobs, http.DefaultClient when nil
+
+	wake     chan struct{}
+	wakeOnce sync.Once
+}
+
+// NewRunner : A runner of the jobs of s searching with search
+func NewRunner(s *Store, workers int, search SearchFunc) *Runner {
+	return &Runner{Store: s, Search: search, Workers: workers, PollInterval: DefaultPollInterval, TTL: DefaultTTL}
+}
+
+func (r *Runner) wakeup() chan struct{} {
+	r.wakeOnce.Do(func() { r.wake = make(chan struct{}, 1) })
+	return r.wake
+}
+
+// Submit : Store a job for req, which is run as soon as a worker is free
+func (r *Runner) Submit(req Request) (Job, error) {
+	job, err := r.Store.Add(req)
+	if err != nil {
+		return job, err
+	}
+	select {
+	case r.wakeup() <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Run : Run pending jobs until ctx is cancelled
+// Jobs interrupted by a previous run, or by the cancellation of ctx, are run again
+func (r *Runner) Run(ctx context.Context) error {
+	workers := r.Workers
+	if workers < 1 {
+		workers = DefaultWorkers
+	}
+	interval := r.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	jobs, err := r.Store.List()
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.Status == Running {
+			r.Store.Update(job.ID, func(job *Job) { job.Status = Pending })
+		}
+	}
+
+	var wg sync.WaitGroup
+	running := map[string]bool{}
+	done := make(chan string)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.prune()
+		jobs, err := r.Store.List()
+		if err != nil {
+			log.Errorf("Could not read jobs: %v", err)
+		}
+		for _, job := range jobs {
+			if len(running) >= workers {
+				break
+			}
+			if job.Status != Pending || running[job.ID] {
+				continue
+			}
+			err := r.Store.Update(job.ID, func(job *Job) {
+				job.Status = Running
+				job.StartedAt = time.Now()
+			})
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			running[job.ID] = true
+			wg.Add(1)
+			go func(job Job) {
+				defer wg.Done()
+				r.run(ctx, job)
+				done <- job.ID
+			}(job)
+		}
+
+		select {
+		case <-ctx.Done():
+			go func() {
+				for range done {
+				}
+			}()
+			wg.Wait()
+			close(done)
+			return ctx.Err()
+		case id := <-done:
+			delete(running, id)
+		case <-r.wakeup():
+		case <-ticker.C:
+		}
+	}
+}
+
+// prune : remove the jobs finished more than TTL ago
+func (r *Runner) prune() {
+	ttl := r.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if _, err := r.Store.Prune(time.Now().Add(-ttl)); err != nil {
+		log.Errorf("Could not remove finished jobs: %v", err)
+	}
+}
+
+// run : Search for job and record what was found, then call its webhook. Jobs
+// stopped by the cancellation of ctx are left pending
+func (r *Runner) run(ctx context.Context, job Job) {
+	log.Infof("Running job %s searching %s for %s", job.ID, job.Engine, job.Query)
+	movies, searchErr := r.Search(ctx, job.Request)
+	if ctx.Err() != nil {
+		r.Store.Update(job.ID, func(job *Job) { job.Status = Pending })
+		return
+	}
+	err := r.Store.Update(job.ID, func(j *Job) {
+		j.FinishedAt = time.Now()
+		j.Movies = movies
+		if searchErr != nil {
+			j.Status = Failed
+			j.Error = searchErr.Error()
+		} else {
+			j.Status = Done
+		}
+		job = *j
+	})
+	if err != nil {
+		log.Errorf("Could not record job %s: %v", job.ID, err)
+		return
+	}
+	if searchErr != nil {
+		log.Warnf("Job %s failed: %v", job.ID, searchErr)
+	} else {
+		log.Infof("Job %s found %d movies", job.ID, len(movies))
+	}
+	if job.Webhook != "" {
+		if err = r.notify(ctx, job); err != nil {
+			log.Warnf("Could not call the webhook of job %s: %v", job.ID, err)
+		}
+	}
+}
+
+// notify : POST job to its webhook
+func (r *Runner) notify(ctx context.Context, job Job) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
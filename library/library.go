@@ -0,0 +1,221 @@
+// Package library records every movie gophie downloaded, where it was saved and
+// its checksum, so movies are not downloaded twice. Like the download queue the
+// library is a bbolt database only opened for the duration of an operation
+package library
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	bolt "go.etcd.io/bbolt"
+)
+
+var entriesBucket = []byte("movies")
+
+// ErrNotFound : there is no movie with the ID in the library
+var ErrNotFound = errors.New("Movie not in library")
+
+// Entry : a downloaded movie
+type Entry struct {
+	ID           uint64
+	Title        string
+	Year         int
+	Source       string
+	Link         string // link the movie was downloaded from
+	Path         string // where the movie was saved
+	Size         int64
+	Checksum     string // SHA-256 of the file
+	DownloadedAt time.Time
+}
+
+// NewEntry : The entry of movie downloaded to path, with the size and checksum of the file
+func NewEntry(movie engine.Movie, path string) (Entry, error) {
+	entry := Entry{
+		Title:        movie.Title,
+		Year:         movie.Year,
+		Source:       movie.Source,
+		Path:         path,
+		DownloadedAt: time.Now(),
+	}
+	if movie.DownloadLink != nil {
+		entry.Link = movie.DownloadLink.String()
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return entry, err
+	}
+	entry.Size = info.Size()
+	entry.Checksum, err = Checksum(path)
+	return entry, err
+}
+
+// Checksum : The hex encoded SHA-256 of the file at path
+func Checksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Library : The movies downloaded so far
+type Library struct {
+	path string
+}
+
+// Open : Open (or create) the library stored at path
+func Open(path string) (*Library, error) {
+	l := &Library{path: path}
+	if err := l.withDB(true, func(b *bolt.Bucket) error { return nil }); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// withDB : run fn in a transaction on the entries bucket
+func (l *Library) withDB(writable bool, fn func(*bolt.Bucket) error) error {
+	db, err := bolt.Open(l.path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if !writable {
+		return db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket(entriesBucket)
+			if b == nil {
+				return nil
+			}
+			return fn(b)
+		})
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(entriesBucket)
+		if err != nil {
+			return err
+		}
+		return fn(b)
+	})
+}
+
+func entryKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+// Add : Record a downloaded movie. A movie downloaded again to the same path
+// replaces its previous entry
+func (l *Library) Add(entry Entry) (Entry, error) {
+	err := l.withDB(true, func(b *bolt.Bucket) error {
+		err := b.ForEach(func(k, v []byte) error {
+			var existing Entry
+			if err := json.Unmarshal(v, &existing); err != nil {
+				return err
+			}
+			if existing.Path == entry.Path {
+				entry.ID = existing.ID
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if entry.ID == 0 {
+			if entry.ID, err = b.NextSequence(); err != nil {
+				return err
+			}
+		}
+		v, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return b.Put(entryKey(entry.ID), v)
+	})
+	return entry, err
+}
+
+// List : The movies in the order they were downloaded
+func (l *Library) List() ([]Entry, error) {
+	var entries []Entry
+	err := l.withDB(false, func(b *bolt.Bucket) error {
+		return b.ForEach(func(_, v []byte) error {
+			var entry Entry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].DownloadedAt.Before(entries[j].DownloadedAt) })
+	return entries, err
+}
+
+// Search : The movies with every word of query in their title
+func (l *Library) Search(query string) ([]Entry, error) {
+	entries, err := l.List()
+	if err != nil {
+		return nil, err
+	}
+	words := strings.Fields(strings.ToLower(query))
+	matches := []Entry{}
+	for _, entry := range entries {
+		title := strings.ToLower(entry.Title)
+		matched := true
+		for _, word := range words {
+			if !strings.Contains(title, word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
+
+// Find : The entry of movie if it was downloaded before, matched by its download
+// link or its title and year
+func (l *Library) Find(movie engine.Movie) (Entry, bool, error) {
+	entries, err := l.List()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	link := ""
+	if movie.DownloadLink != nil {
+		link = movie.DownloadLink.String()
+	}
+	for _, entry := range entries {
+		if (link != "" && entry.Link == link) ||
+			(strings.EqualFold(entry.Title, movie.Title) && entry.Year == movie.Year) {
+			return entry, true, nil
+		}
+	}
+	return Entry{}, false, nil
+}
+
+// Remove : Forget the movie with id, the file itself is kept
+func (l *Library) Remove(id uint64) error {
+	return l.withDB(true, func(b *bolt.Bucket) error {
+		if b.Get(entryKey(id)) == nil {
+			return fmt.Errorf("%w: %d", ErrNotFound, id)
+		}
+		return b.Delete(entryKey(id))
+	})
+}
@@ -0,0 +1,69 @@
+package library
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+func TestLibrary(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(filepath.Join(dir, "library.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "jumanji.mp4")
+	if err = os.WriteFile(file, []byte("gophie"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link, _ := url.Parse("https://example.com/jumanji.mp4")
+	movie := engine.Movie{Title: "Jumanji", Year: 1995, Source: "FzMovies", DownloadLink: link}
+
+	entry, err := NewEntry(movie, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sha256 of "gophie"
+	if entry.Size != 6 || entry.Checksum != "025abfcb17fe0079394594916adfa714c361d8b48a7283320d58aa8b011a82a2" {
+		t.Errorf("Unexpected size and checksum %d %s", entry.Size, entry.Checksum)
+	}
+	if entry, err = l.Add(entry); err != nil || entry.ID == 0 {
+		t.Fatalf("Expected the movie to be added, got %+v, %v", entry, err)
+	}
+	// downloading to the same path again replaces the entry
+	if again, _ := l.Add(entry); again.ID != entry.ID {
+		t.Errorf("Expected the entry to be replaced, got ID %d", again.ID)
+	}
+	other, _ := l.Add(Entry{Title: "The Batman", Path: filepath.Join(dir, "batman.mp4")})
+
+	if entries, _ := l.List(); len(entries) != 2 {
+		t.Errorf("Expected 2 entries, got %+v", entries)
+	}
+	if entries, _ := l.Search("batman the"); len(entries) != 1 || entries[0].ID != other.ID {
+		t.Errorf("Expected The Batman to be found, got %+v", entries)
+	}
+
+	// matched by link, or by title and year when the link changed
+	if _, found, _ := l.Find(movie); !found {
+		t.Errorf("Expected the movie to be found by link")
+	}
+	movie.DownloadLink, _ = url.Parse("https://mirror.example/jumanji.mp4")
+	if _, found, _ := l.Find(movie); !found {
+		t.Errorf("Expected the movie to be found by title and year")
+	}
+	movie.Year = 2017
+	if _, found, _ := l.Find(movie); found {
+		t.Errorf("Expected movies of another year not to match")
+	}
+
+	if err = l.Remove(entry.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err = l.Remove(entry.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
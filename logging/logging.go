@@ -0,0 +1,150 @@
+// Package logging configures the logs of gophie and ties the log entries of a
+// search or list to a trace ID, so the requests an API call made can be told
+// apart from those of the calls running alongside it
+package logging
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Formats of the logs
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// TraceHeader : header carrying the trace ID of API requests, set on their responses
+const TraceHeader = "X-Trace-Id"
+
+// TraceField : field of the trace ID in log entries
+const TraceField = "trace_id"
+
+// Configure : Log at level e.g debug, info, warn or error in format
+func Configure(level, format string) error {
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("Invalid log level %q, use debug, info, warn or error", level)
+	}
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		log.SetFormatter(&log.TextFormatter{})
+	case FormatJSON:
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("Invalid log format %q, use text or json", format)
+	}
+	log.SetLevel(lvl)
+	return nil
+}
+
+type traceKey struct{}
+
+// NewTraceID : A random trace ID
+func NewTraceID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithTraceID : ctx carrying the trace ID id
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceKey{}, id)
+}
+
+// TraceID : The trace ID carried by ctx, empty when it has none
+func TraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceKey{}).(string)
+	return id
+}
+
+// EnsureTraceID : ctx with a new trace ID unless it already carries one
+func EnsureTraceID(ctx context.Context) context.Context {
+	if TraceID(ctx) != "" {
+		return ctx
+	}
+	return WithTraceID(ctx, NewTraceID())
+}
+
+// FromContext : An entry logging the trace ID of ctx, if any
+func FromContext(ctx context.Context) *log.Entry {
+	return WithTrace(TraceID(ctx))
+}
+
+// WithTrace : An entry logging the trace ID id, if set
+func WithTrace(id string) *log.Entry {
+	if id == "" {
+		return log.NewEntry(log.StandardLogger())
+	}
+	return log.WithField(TraceField, id)
+}
+
+// statusWriter : records the status of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush : flush the response, so Server-Sent Events are still streamed
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack : take over the connection, so WebSockets can be served
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response cannot be hijacked")
+	}
+	w.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Handler : Give every request a trace ID, the one of its TraceHeader if set,
+// and log it once served with its method, path, status and duration
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(TraceHeader)
+		if id == "" || len(id) > 64 {
+			id = NewTraceID()
+		}
+		w.Header().Set(TraceHeader, id)
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(WithTraceID(r.Context(), id)))
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		WithTrace(id).WithFields(log.Fields{
+			"method":   r.Method,
+			"path":     r.URL.Path,
+			"status":   sw.status,
+			"duration": time.Since(start).Round(time.Millisecond).String(),
+			"remote":   r.RemoteAddr,
+		}).Info("Served request")
+	})
+}
@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestConfigure(t *testing.T) {
+	defer Configure("info", FormatText)
+	if err := Configure("loud", FormatText); err == nil {
+		t.Error("Expected an invalid level to fail")
+	}
+	if err := Configure("info", "xml"); err == nil {
+		t.Error("Expected an invalid format to fail")
+	}
+	if err := Configure("warn", FormatJSON); err != nil || log.GetLevel() != log.WarnLevel {
+		t.Errorf("Expected the level to be set, got %v %v", log.GetLevel(), err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	Configure("debug", FormatJSON)
+	defer Configure("info", FormatText)
+
+	var traced string
+	handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traced = TraceID(r.Context())
+		// a trace ID is kept by the operations of the request
+		if EnsureTraceID(r.Context()) != r.Context() {
+			t.Error("Expected the trace ID of the request to be kept")
+		}
+		FromContext(r.Context()).WithField("engine", "NetNaija").Debug("Searching")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?query=jumanji", nil))
+	if traced == "" || w.Header().Get(TraceHeader) != traced {
+		t.Fatalf("Expected the trace ID %q in the response, got %q", traced, w.Header().Get(TraceHeader))
+	}
+	decoder := json.NewDecoder(&logs)
+	var entries []map[string]interface{}
+	for decoder.More() {
+		var entry map[string]interface{}
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected an entry of the search and of the request, got %v", entries)
+	}
+	for _, entry := range entries {
+		if entry[TraceField] != traced {
+			t.Errorf("Expected the trace ID in %v", entry)
+		}
+	}
+	if entries[0]["engine"] != "NetNaija" || entries[1]["status"] != float64(http.StatusTeapot) || entries[1]["path"] != "/search" {
+		t.Errorf("Unexpected entries %v", entries)
+	}
+
+	// the trace ID of a caller is reused
+	r := httptest.NewRequest(http.MethodGet, "/list", nil)
+	r.Header.Set(TraceHeader, "caller-trace")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if traced != "caller-trace" {
+		t.Errorf("Expected the trace ID of the caller, got %q", traced)
+	}
+	if TraceID(context.Background()) != "" {
+		t.Error("Expected no trace ID without one set")
+	}
+}
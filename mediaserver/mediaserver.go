@@ -0,0 +1,155 @@
+// Package mediaserver files downloads into the media library of a Jellyfin or
+// Plex server, in the folders they expect, and asks the servers to scan it so
+// that downloaded movies show up without any manual step
+package mediaserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-phie/gophie/downloader"
+)
+
+// Mode : how downloads are put into the library
+type Mode string
+
+// Modes of putting downloads into the library
+const (
+	// Hardlink : the download is linked into the library, keeping both paths
+	// without using more space. Copied when the library is on another disk
+	Hardlink Mode = "hardlink"
+	Move     Mode = "move"
+	Copy     Mode = "copy"
+)
+
+// Default templates of the paths of downloads in the library, the naming
+// Jellyfin and Plex recommend
+const (
+	DefaultMovieTemplate   downloader.Template = "Movies/{title} ({year})/{title} ({year}).{ext}"
+	DefaultEpisodeTemplate downloader.Template = "Shows/{series} ({year})/Season {season:02}/{series} - S{season:02}E{episode:02}.{ext}"
+)
+
+// Library : A media library downloads are filed into
+type Library struct {
+	Path            string
+	Mode            Mode                // Hardlink when empty
+	MovieTemplate   downloader.Template `mapstructure:"movie-template"`   // DefaultMovieTemplate when empty
+	EpisodeTemplate downloader.Template `mapstructure:"episode-template"` // DefaultEpisodeTemplate when empty
+	Scanners        []Scanner           `mapstructure:"-"`                // asked to scan the library once a download is filed
+}
+
+// Validate : an error unless the library has a path, a known mode and valid templates
+func (l *Library) Validate() error {
+	if l.Path == "" {
+		return errors.New("media library path must be set")
+	}
+	switch l.Mode {
+	case "", Hardlink, Move, Copy:
+	default:
+		return fmt.Errorf("unknown media library mode %q, use hardlink, move or copy", l.Mode)
+	}
+	for _, t := range []downloader.Template{l.MovieTemplate, l.EpisodeTemplate} {
+		if t != "" {
+			if err := t.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Dest : where the file of a download with vars is filed in the library
+func (l *Library) Dest(file string, vars downloader.TemplateVars, episode bool) string {
+	t := l.MovieTemplate
+	if t == "" {
+		t = DefaultMovieTemplate
+	}
+	if episode {
+		t = l.EpisodeTemplate
+		if t == "" {
+			t = DefaultEpisodeTemplate
+		}
+	}
+	return filepath.Join(l.Path, filepath.FromSlash(t.Render(vars, filepath.Ext(file))))
+}
+
+// Import : File the downloaded file, whose details are vars, into the library
+// and return its path there. A file already at the path is replaced
+func (l *Library) Import(file string, vars downloader.TemplateVars, episode bool) (string, error) {
+	dest := l.Dest(file, vars, episode)
+	if same, err := samePath(file, dest); err != nil || same {
+		return dest, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+	os.Remove(dest)
+	var err error
+	switch l.Mode {
+	case Move:
+		// renames fail across disks
+		if err = os.Rename(file, dest); err != nil {
+			if err = copyFile(file, dest); err == nil {
+				err = os.Remove(file)
+			}
+		}
+	case Copy:
+		err = copyFile(file, dest)
+	default:
+		if err = os.Link(file, dest); err != nil {
+			err = copyFile(file, dest)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not file %s into the media library: %w", file, err)
+	}
+	return dest, nil
+}
+
+// samePath : whether a and b are the same file, downloads may be saved in the library
+func samePath(a, b string) (bool, error) {
+	a, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	b, err = filepath.Abs(b)
+	return a == b, err
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dest)
+		return err
+	}
+	return out.Close()
+}
+
+// Scan : Ask every scanner of the library to scan it. The errors of the servers
+// which could not be reached are returned together
+func (l *Library) Scan(ctx context.Context) error {
+	var errs []string
+	for _, scanner := range l.Scanners {
+		if err := scanner.Scan(ctx); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", scanner, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package mediaserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-phie/gophie/downloader"
+)
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "downloads", "Jumanji.2017.720p.mp4")
+	os.MkdirAll(filepath.Dir(src), 0755)
+	os.WriteFile(src, []byte("movie"), 0644)
+	vars := downloader.TemplateVars{Title: "Jumanji: Welcome to the Jungle", Year: 2017, Quality: "720p"}
+
+	for _, mode := range []Mode{"", Copy, Move} {
+		l := &Library{Path: filepath.Join(dir, "library", string(mode)), Mode: mode}
+		dest, err := l.Import(src, vars, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := filepath.Join(l.Path, "Movies", "Jumanji_ Welcome to the Jungle (2017)", "Jumanji_ Welcome to the Jungle (2017).mp4")
+		if dest != expected {
+			t.Errorf("Expected %s, got %s", expected, dest)
+		}
+		if b, err := os.ReadFile(dest); err != nil || string(b) != "movie" {
+			t.Errorf("Expected the file imported with %q, got %q %v", mode, b, err)
+		}
+		if _, err := os.Stat(src); (err == nil) == (mode == Move) {
+			t.Errorf("Expected the download kept unless moved, got %v with %q", err, mode)
+		}
+	}
+
+	l := &Library{Path: filepath.Join(dir, "library")}
+	episode := downloader.TemplateVars{Title: "Loki S01E02", Series: "Loki", Year: 2021, Season: 1, Episode: 2}
+	src = filepath.Join(dir, "Loki.S01E02.mkv")
+	os.WriteFile(src, []byte("episode"), 0644)
+	dest, err := l.Import(src, episode, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(l.Path, "Shows", "Loki (2021)", "Season 01", "Loki - S01E02.mkv"); dest != expected {
+		t.Errorf("Expected %s, got %s", expected, dest)
+	}
+	if err = (&Library{Path: dir, Mode: "symlink"}).Validate(); err == nil {
+		t.Error("Expected an unknown mode to be invalid")
+	}
+}
+
+func TestScan(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("X-Emby-Token")+r.Header.Get("X-Plex-Token"))
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/library/sections":
+			w.Write([]byte(`<MediaContainer><Directory key="1" type="movie"/><Directory key="2" type="show"/></MediaContainer>`))
+		case "/denied/Library/Refresh":
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	l := &Library{Scanners: []Scanner{
+		&Jellyfin{URL: server.URL + "/", APIKey: "key"},
+		&Plex{URL: server.URL, Token: "token"},
+		&Plex{URL: server.URL, Token: "token", Sections: []string{"3"}},
+	}}
+	if err := l.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"POST /Library/Refresh key",
+		"GET /library/sections token",
+		"GET /library/sections/1/refresh token",
+		"GET /library/sections/2/refresh token",
+		"GET /library/sections/3/refresh token",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected the servers asked to scan\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(requests, "\n"))
+	}
+
+	l.Scanners = []Scanner{&Jellyfin{URL: server.URL + "/denied"}}
+	if err := l.Scan(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected the refused scan to fail, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package mediaserver
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Scanner : A media server which scans the library for new files when asked
+type Scanner interface {
+	Scan(ctx context.Context) error
+	String() string
+}
+
+// Jellyfin : A Jellyfin server, scanned through its API with an API key made in
+// the dashboard
+type Jellyfin struct {
+	URL    string
+	APIKey string `mapstructure:"api-key"`
+	Client *http.Client
+}
+
+func (j *Jellyfin) String() string {
+	return "jellyfin " + j.URL
+}
+
+// Scan : refresh every library of the server
+func (j *Jellyfin) Scan(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(j.URL, "/")+"/Library/Refresh", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Emby-Token", j.APIKey)
+	return do(j.Client, req, nil)
+}
+
+// Plex : A Plex Media Server, scanned through its API with an X-Plex-Token
+type Plex struct {
+	URL      string
+	Token    string
+	Sections []string // keys of the library sections scanned, every section when empty
+	Client   *http.Client
+}
+
+func (p *Plex) String() string {
+	return "plex " + p.URL
+}
+
+// request : a GET of path of the server with the token
+func (p *Plex) request(ctx context.Context, path string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.URL, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Plex-Token", p.Token)
+	req.Header.Set("Accept", "application/xml")
+	return req, nil
+}
+
+// sections : the keys of the library sections of the server
+func (p *Plex) sections(ctx context.Context) ([]string, error) {
+	req, err := p.request(ctx, "/library/sections")
+	if err != nil {
+		return nil, err
+	}
+	var container struct {
+		Directories []struct {
+			Key string `xml:"key,attr"`
+		} `xml:"Directory"`
+	}
+	if err = do(p.Client, req, &container); err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(container.Directories))
+	for i, dir := range container.Directories {
+		keys[i] = dir.Key
+	}
+	return keys, nil
+}
+
+// Scan : refresh the sections of the library
+func (p *Plex) Scan(ctx context.Context) error {
+	sections := p.Sections
+	if len(sections) == 0 {
+		var err error
+		if sections, err = p.sections(ctx); err != nil {
+			return err
+		}
+	}
+	for _, section := range sections {
+		req, err := p.request(ctx, "/library/sections/"+url.PathEscape(section)+"/refresh")
+		if err != nil {
+			return err
+		}
+		if err = do(p.Client, req, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do : send req, decoding the XML response into v when set
+func do(client *http.Client, req *http.Request, v interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("responded with %s", resp.Status)
+	}
+	if v != nil {
+		return xml.NewDecoder(resp.Body).Decode(v)
+	}
+	return nil
+}
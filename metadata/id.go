@@ -0,0 +1,90 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+// IDProvider : a provider which can also look up a title by its IMDB id
+type IDProvider interface {
+	Provider
+	// LookupID : find the metadata of the title of an IMDB id such as tt4154796
+	LookupID(ctx context.Context, imdbID string) (*Metadata, error)
+}
+
+var (
+	imdbIDRe    = regexp.MustCompile(`^tt\d{7,}$`)
+	imdbLinkRe  = regexp.MustCompile(`imdb\.com/title/(tt\d{7,})`)
+	titleYearRe = regexp.MustCompile(`\b(19\d{2}|20\d{2})\b`)
+)
+
+// ParseIMDBID : The IMDB id of id or of the link of an IMDB title page
+func ParseIMDBID(id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if match := imdbLinkRe.FindStringSubmatch(id); len(match) > 1 {
+		return match[1], nil
+	}
+	if !imdbIDRe.MatchString(id) {
+		return "", fmt.Errorf("Invalid IMDB id %q, use an id such as tt4154796", id)
+	}
+	return id, nil
+}
+
+// movieYear : the year of movie, parsed from its title when it was not scraped
+func movieYear(movie engine.Movie) int {
+	if movie.Year > 0 {
+		return movie.Year
+	}
+	if _, year := CleanTitle(movie.Title); year > 0 {
+		return year
+	}
+	// titles of release names such as Joker.2019.1080p
+	years := titleYearRe.FindAllString(movie.Title, -1)
+	if len(years) == 0 {
+		return 0
+	}
+	year, _ := strconv.Atoi(years[len(years)-1])
+	return year
+}
+
+// MatchYear : The movies of result released in year, or linked to the title
+// of imdbID. Movies whose year is unknown are kept
+func MatchYear(result engine.SearchResult, year int, imdbID string) engine.SearchResult {
+	movies := make([]engine.Movie, 0, len(result.Movies))
+	for _, movie := range result.Movies {
+		linked := imdbID != "" && strings.Contains(movie.ImdbLink, imdbID)
+		if y := movieYear(movie); linked || y == 0 || y == year {
+			movies = append(movies, movie)
+		}
+	}
+	result.Movies = movies
+	return result
+}
+
+// SearchByID : Search for the title of an IMDB id with search, using the canonical
+// title of the provider as the query and dropping the movies of other years.
+// The years of series are not matched as their seasons are released in later years
+func SearchByID(ctx context.Context, provider IDProvider, imdbID string,
+	search func(ctx context.Context, query string) (engine.SearchResult, error)) (engine.SearchResult, *Metadata, error) {
+	if provider == nil {
+		return engine.SearchResult{}, nil, errors.New("Searching by IMDB id needs a metadata provider, set tmdb-api-key or omdb-api-key")
+	}
+	m, err := provider.LookupID(ctx, imdbID)
+	if err != nil {
+		return engine.SearchResult{}, nil, err
+	}
+	result, err := search(ctx, m.Title)
+	if err != nil {
+		return result, m, err
+	}
+	if m.Year > 0 && !m.Series {
+		result = MatchYear(result, m.Year, imdbID)
+	}
+	return result, m, nil
+}
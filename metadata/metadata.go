@@ -0,0 +1,184 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNoMatch : the provider does not know the title
+var ErrNoMatch = errors.New("No metadata match")
+
+// Number of movies looked up at the same time when enriching a result
+const enrichWorkers = 4
+
+// Metadata : canonical details of a movie from a metadata provider
+type Metadata struct {
+	Title      string
+	Year       int
+	Genres     []string
+	Rating     float64 // out of 10
+	Runtime    int     // minutes
+	PosterLink string
+	ImdbID     string
+	Series     bool // the title is a tv series
+}
+
+// Provider : a source of movie metadata such as TMDB or OMDB
+type Provider interface {
+	// Lookup : find the metadata of a title. year and series narrow the search when known
+	Lookup(ctx context.Context, title string, year int, series bool) (*Metadata, error)
+}
+
+var (
+	yearRe      = regexp.MustCompile(`\((\d{4})\)`)
+	bracketRe   = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`)
+	qualityRe   = regexp.MustCompile(`(?i)\b(480p|720p|1080p|2160p|4k|hdrip|web-?dl|webrip|bluray|brrip|dvdrip|hdtv|cam|x264|x265|hevc)\b`)
+	separatorRe = regexp.MustCompile(`[._]+`)
+)
+
+// CleanTitle : strip the cruft added by source sites (years, tags, resolutions)
+// from a scraped title and return it with the year found in it
+func CleanTitle(title string) (string, int) {
+	var year int
+	if match := yearRe.FindStringSubmatch(title); len(match) > 1 {
+		year, _ = strconv.Atoi(match[1])
+	}
+	title = bracketRe.ReplaceAllString(title, " ")
+	title = qualityRe.ReplaceAllString(title, " ")
+	title = separatorRe.ReplaceAllString(title, " ")
+	title = strings.Trim(strings.Join(strings.Fields(title), " "), " -|")
+	return title, year
+}
+
+// Apply : fill in the movie with the metadata, keeping scraped values where
+// the provider has nothing
+func (m *Metadata) Apply(movie *engine.Movie) {
+	movie.CanonicalTitle = m.Title
+	if movie.Year == 0 {
+		movie.Year = m.Year
+	}
+	if len(m.Genres) > 0 {
+		movie.Genres = strings.Join(m.Genres, ",")
+	}
+	movie.Rating = m.Rating
+	movie.Runtime = m.Runtime
+	movie.PosterLink = m.PosterLink
+	if movie.ImdbLink == "" && m.ImdbID != "" {
+		movie.ImdbLink = "https://www.imdb.com/title/" + m.ImdbID
+	}
+}
+
+// Enrich : look up the metadata of every movie in result and apply it
+// Movies the provider does not know are left as they are
+func Enrich(ctx context.Context, provider Provider, result *engine.SearchResult) {
+	jobs := make(chan *engine.Movie)
+	var wg sync.WaitGroup
+	for i := 0; i < enrichWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for movie := range jobs {
+				if m := lookup(ctx, provider, movie); m != nil {
+					m.Apply(movie)
+				}
+			}
+		}()
+	}
+	for i := range result.Movies {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- &result.Movies[i]
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// lookup : the metadata of movie, nil if the provider does not know it
+func lookup(ctx context.Context, provider Provider, movie *engine.Movie) *Metadata {
+	title, year := CleanTitle(movie.Title)
+	if movie.Year != 0 {
+		year = movie.Year
+	}
+	m, err := provider.Lookup(ctx, title, year, movie.IsSeries)
+	if err != nil {
+		log.Debugf("No metadata for %s: %v", movie.Title, err)
+		return nil
+	}
+	return m
+}
+
+// EnrichedEngine : An Engine whose results are enriched by a metadata provider
+type EnrichedEngine struct {
+	engine.Engine
+	provider Provider
+}
+
+// NewEnrichedEngine : Wrap e so its results are enriched using provider
+func NewEnrichedEngine(e engine.Engine, provider Provider) *EnrichedEngine {
+	return &EnrichedEngine{Engine: e, provider: provider}
+}
+
+// Search : Search the wrapped engine and enrich the results
+// Movies streamed with engine.StreamSearch are enriched before they are streamed
+func (e *EnrichedEngine) Search(ctx context.Context, param ...string) (engine.SearchResult, error) {
+	fn := engine.MovieFuncFromContext(ctx)
+	if fn == nil {
+		result, err := e.Engine.Search(ctx, param...)
+		Enrich(ctx, e.provider, &result)
+		return result, err
+	}
+
+	// movies are streamed in the order of the result
+	var streamed []*Metadata
+	result, err := e.Engine.Search(engine.WithMovieFunc(ctx, func(movie engine.Movie) {
+		m := lookup(ctx, e.provider, &movie)
+		if m != nil {
+			m.Apply(&movie)
+		}
+		streamed = append(streamed, m)
+		fn(movie)
+	}), param...)
+	for i, m := range streamed {
+		if i < len(result.Movies) && m != nil {
+			m.Apply(&result.Movies[i])
+		}
+	}
+	if len(streamed) < len(result.Movies) {
+		rest := engine.SearchResult{Movies: result.Movies[len(streamed):]}
+		Enrich(ctx, e.provider, &rest)
+	}
+	return result, err
+}
+
+// List : List the wrapped engine and enrich the results
+func (e *EnrichedEngine) List(ctx context.Context, page int) (engine.SearchResult, error) {
+	result, err := e.Engine.List(ctx, page)
+	Enrich(ctx, e.provider, &result)
+	return result, err
+}
+
+// Modes : the modes of the wrapped engine
+func (e *EnrichedEngine) Modes() []engine.ScrapeMode {
+	return engine.Modes(e.Engine)
+}
+
+// ListBy : List the wrapped engine in mode and enrich the results
+func (e *EnrichedEngine) ListBy(ctx context.Context, mode engine.ScrapeMode, page int) (engine.SearchResult, error) {
+	result, err := engine.ListBy(ctx, e.Engine, mode, page)
+	Enrich(ctx, e.provider, &result)
+	return result, err
+}
+
+// MarshalJSON : engines are described by the engine being enriched
+func (e *EnrichedEngine) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Engine)
+}
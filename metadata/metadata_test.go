@@ -0,0 +1,138 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		in    string
+		title string
+		year  int
+	}{
+		{"The Matrix (1999)", "The Matrix", 1999},
+		{"Joker.2019.1080p.WEB-DL.x264", "Joker 2019", 0},
+		{"Parasite (2019) [Korean] 720p", "Parasite", 2019},
+		{"Breaking Bad - Season 1", "Breaking Bad - Season 1", 0},
+	}
+	for _, tt := range tests {
+		title, year := CleanTitle(tt.in)
+		if title != tt.title || year != tt.year {
+			t.Errorf("CleanTitle(%q) = %q, %d; want %q, %d", tt.in, title, year, tt.title, tt.year)
+		}
+	}
+}
+
+func TestOMDBLookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("t") != "The Matrix" {
+			fmt.Fprint(w, `{"Response":"False","Error":"Movie not found!"}`)
+			return
+		}
+		fmt.Fprint(w, `{"Response":"True","Title":"The Matrix","Year":"1999","Runtime":"136 min",
+			"Genre":"Action, Sci-Fi","Poster":"N/A","imdbRating":"8.7","imdbID":"tt0133093"}`)
+	}))
+	defer server.Close()
+
+	omdb := NewOMDB("key")
+	omdb.BaseURL = server.URL
+	m, err := omdb.Lookup(context.Background(), "The Matrix", 1999, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Year != 1999 || m.Runtime != 136 || m.Rating != 8.7 || len(m.Genres) != 2 || m.PosterLink != "" {
+		t.Errorf("unexpected metadata %+v", m)
+	}
+	if _, err = omdb.Lookup(context.Background(), "Unknown", 0, false); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("expected ErrNoMatch, got %v", err)
+	}
+}
+
+func TestTMDBEnrich(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search/movie", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"results":[{"id":603}]}`)
+	})
+	mux.HandleFunc("/movie/603", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":603,"title":"The Matrix","release_date":"1999-03-30","vote_average":8.2,
+			"poster_path":"/matrix.jpg","runtime":136,"imdb_id":"tt0133093","genres":[{"name":"Action"}]}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tmdb := NewTMDB("key")
+	tmdb.BaseURL = server.URL
+	result := engine.SearchResult{Movies: []engine.Movie{{Title: "The.Matrix.720p"}}}
+	Enrich(context.Background(), tmdb, &result)
+
+	movie := result.Movies[0]
+	if movie.CanonicalTitle != "The Matrix" || movie.Year != 1999 || movie.Genres != "Action" ||
+		movie.Runtime != 136 || movie.PosterLink != tmdbImageURL+"/matrix.jpg" {
+		t.Errorf("movie was not enriched: %+v", movie)
+	}
+	if movie.ImdbLink != "https://www.imdb.com/title/tt0133093" {
+		t.Errorf("unexpected imdb link %s", movie.ImdbLink)
+	}
+}
+
+func TestSearchByID(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/find/tt4154796", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("external_source") != "imdb_id" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		fmt.Fprint(w, `{"movie_results":[{"id":299534}],"tv_results":[]}`)
+	})
+	mux.HandleFunc("/movie/299534", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":299534,"title":"Avengers: Endgame","release_date":"2019-04-24"}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	tmdb := NewTMDB("key")
+	tmdb.BaseURL = server.URL
+
+	id, err := ParseIMDBID("https://www.imdb.com/title/tt4154796/")
+	if err != nil || id != "tt4154796" {
+		t.Fatalf("Expected the id of the link, got %q %v", id, err)
+	}
+	if _, err = ParseIMDBID("4154796"); err == nil {
+		t.Error("Expected ids without tt to be rejected")
+	}
+
+	var query string
+	result, m, err := SearchByID(context.Background(), tmdb, id, func(ctx context.Context, q string) (engine.SearchResult, error) {
+		query = q
+		return engine.SearchResult{Query: q, Movies: []engine.Movie{
+			{Title: "Avengers: Endgame (2019)"},
+			{Title: "Avengers.Endgame.2019.1080p.BluRay"},
+			{Title: "Avengers Endgame Fan Edit", Year: 2021, ImdbLink: "https://www.imdb.com/title/tt4154796"},
+			{Title: "Avengers (2012)"},
+			{Title: "Avengers Assemble", Year: 2013},
+			{Title: "Avengers Endgame"},
+		}}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "Avengers: Endgame" || m.Year != 2019 || m.ImdbID != id {
+		t.Errorf("Expected the canonical title to be searched, got %q %+v", query, m)
+	}
+	var titles []string
+	for _, movie := range result.Movies {
+		titles = append(titles, movie.Title)
+	}
+	if got := fmt.Sprint(titles); got != "[Avengers: Endgame (2019) Avengers.Endgame.2019.1080p.BluRay Avengers Endgame Fan Edit Avengers Endgame]" {
+		t.Errorf("Expected movies of other years to be dropped, got %s", got)
+	}
+
+	if _, _, err = SearchByID(context.Background(), tmdb, "tt0000001", nil); err == nil {
+		t.Error("Expected unknown ids to fail")
+	}
+}
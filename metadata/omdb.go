@@ -0,0 +1,105 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const omdbURL = "https://www.omdbapi.com/"
+
+// OMDB : Provider backed by the Open Movie Database (https://www.omdbapi.com)
+type OMDB struct {
+	APIKey  string
+	BaseURL string // Defaults to the public OMDB API
+	Client  *http.Client
+}
+
+// NewOMDB : OMDB provider using apiKey
+func NewOMDB(apiKey string) *OMDB {
+	return &OMDB{APIKey: apiKey, BaseURL: omdbURL, Client: http.DefaultClient}
+}
+
+type omdbResponse struct {
+	Response   string
+	Error      string
+	Title      string
+	Type       string
+	Year       string
+	Runtime    string
+	Genre      string
+	Poster     string
+	ImdbRating string `json:"imdbRating"`
+	ImdbID     string `json:"imdbID"`
+}
+
+// Lookup : Find a title on OMDB
+func (o *OMDB) Lookup(ctx context.Context, title string, year int, series bool) (*Metadata, error) {
+	q := url.Values{}
+	q.Set("apikey", o.APIKey)
+	q.Set("t", title)
+	if year > 0 {
+		q.Set("y", strconv.Itoa(year))
+	}
+	if series {
+		q.Set("type", "series")
+	}
+	return o.get(ctx, q)
+}
+
+// LookupID : Find a title on OMDB by its IMDB id
+func (o *OMDB) LookupID(ctx context.Context, imdbID string) (*Metadata, error) {
+	q := url.Values{}
+	q.Set("apikey", o.APIKey)
+	q.Set("i", imdbID)
+	return o.get(ctx, q)
+}
+
+func (o *OMDB) get(ctx context.Context, q url.Values) (*Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.BaseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OMDB returned %s", resp.Status)
+	}
+
+	var body omdbResponse
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if body.Response != "True" {
+		return nil, fmt.Errorf("%w: %s", ErrNoMatch, body.Error)
+	}
+
+	m := &Metadata{
+		Title:      body.Title,
+		PosterLink: body.Poster,
+		ImdbID:     body.ImdbID,
+		Series:     body.Type == "series",
+	}
+	// Series have years like 2008–2013
+	if len(body.Year) >= 4 {
+		m.Year, _ = strconv.Atoi(body.Year[:4])
+	}
+	m.Runtime, _ = strconv.Atoi(strings.TrimSuffix(body.Runtime, " min"))
+	m.Rating, _ = strconv.ParseFloat(body.ImdbRating, 64)
+	if m.PosterLink == "N/A" {
+		m.PosterLink = ""
+	}
+	for _, genre := range strings.Split(body.Genre, ",") {
+		if genre = strings.TrimSpace(genre); genre != "" && genre != "N/A" {
+			m.Genres = append(m.Genres, genre)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,148 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	tmdbURL      = "https://api.themoviedb.org/3"
+	tmdbImageURL = "https://image.tmdb.org/t/p/w500"
+)
+
+// TMDB : Provider backed by The Movie Database (https://www.themoviedb.org)
+type TMDB struct {
+	APIKey  string
+	BaseURL string // Defaults to the public TMDB v3 API
+	Client  *http.Client
+}
+
+// NewTMDB : TMDB provider using apiKey
+func NewTMDB(apiKey string) *TMDB {
+	return &TMDB{APIKey: apiKey, BaseURL: tmdbURL, Client: http.DefaultClient}
+}
+
+// Fields shared by movies and tv shows, tv shows use Name and FirstAirDate
+type tmdbTitle struct {
+	ID             int
+	Title          string
+	Name           string
+	ReleaseDate    string  `json:"release_date"`
+	FirstAirDate   string  `json:"first_air_date"`
+	VoteAverage    float64 `json:"vote_average"`
+	PosterPath     string  `json:"poster_path"`
+	Runtime        int
+	EpisodeRunTime []int  `json:"episode_run_time"`
+	ImdbID         string `json:"imdb_id"`
+	Genres         []struct {
+		Name string
+	}
+}
+
+// Lookup : Search TMDB for a title and fetch the details of the best match
+func (t *TMDB) Lookup(ctx context.Context, title string, year int, series bool) (*Metadata, error) {
+	kind, yearParam := "movie", "year"
+	if series {
+		kind, yearParam = "tv", "first_air_date_year"
+	}
+	q := url.Values{}
+	q.Set("query", title)
+	if year > 0 {
+		q.Set(yearParam, strconv.Itoa(year))
+	}
+
+	var search struct {
+		Results []tmdbTitle
+	}
+	if err := t.get(ctx, "/search/"+kind, q, &search); err != nil {
+		return nil, err
+	}
+	if len(search.Results) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoMatch, title)
+	}
+
+	var details tmdbTitle
+	if err := t.get(ctx, fmt.Sprintf("/%s/%d", kind, search.Results[0].ID), url.Values{}, &details); err != nil {
+		return nil, err
+	}
+	m := details.metadata()
+	m.Series = series
+	return m, nil
+}
+
+// LookupID : Find the movie or tv show of an IMDB id on TMDB and fetch its details
+func (t *TMDB) LookupID(ctx context.Context, imdbID string) (*Metadata, error) {
+	q := url.Values{}
+	q.Set("external_source", "imdb_id")
+	var found struct {
+		MovieResults []tmdbTitle `json:"movie_results"`
+		TvResults    []tmdbTitle `json:"tv_results"`
+	}
+	if err := t.get(ctx, "/find/"+url.PathEscape(imdbID), q, &found); err != nil {
+		return nil, err
+	}
+	kind, results := "movie", found.MovieResults
+	if len(results) == 0 {
+		kind, results = "tv", found.TvResults
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoMatch, imdbID)
+	}
+
+	var details tmdbTitle
+	if err := t.get(ctx, fmt.Sprintf("/%s/%d", kind, results[0].ID), url.Values{}, &details); err != nil {
+		return nil, err
+	}
+	m := details.metadata()
+	// the details of tv shows do not hold their imdb id
+	m.ImdbID, m.Series = imdbID, kind == "tv"
+	return m, nil
+}
+
+func (t *TMDB) get(ctx context.Context, endpoint string, q url.Values, v interface{}) error {
+	q.Set("api_key", t.APIKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.BaseURL+endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("TMDB returned %s for %s", resp.Status, endpoint)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (d *tmdbTitle) metadata() *Metadata {
+	m := &Metadata{
+		Title:   d.Title,
+		Rating:  d.VoteAverage,
+		Runtime: d.Runtime,
+		ImdbID:  d.ImdbID,
+	}
+	date := d.ReleaseDate
+	if m.Title == "" {
+		m.Title = d.Name
+		date = d.FirstAirDate
+	}
+	if len(date) >= 4 {
+		m.Year, _ = strconv.Atoi(date[:4])
+	}
+	if m.Runtime == 0 && len(d.EpisodeRunTime) > 0 {
+		m.Runtime = d.EpisodeRunTime[0]
+	}
+	if d.PosterPath != "" {
+		m.PosterLink = tmdbImageURL + d.PosterPath
+	}
+	for _, genre := range d.Genres {
+		m.Genres = append(m.Genres, genre.Name)
+	}
+	return m
+}
@@ -0,0 +1,161 @@
+// Package metrics records Prometheus metrics of the engines and the API server
+// so operators can tell which source sites are degrading
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry : the registry every gophie metric is registered in
+var Registry = prometheus.NewRegistry()
+
+var (
+	engineRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gophie",
+		Name:      "engine_requests_total",
+		Help:      "Searches and lists made to engines by status (ok, error, unavailable or cancelled)",
+	}, []string{"engine", "mode", "status"})
+	engineDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gophie",
+		Name:      "engine_request_duration_seconds",
+		Help:      "Time taken to scrape the results of searches and lists",
+		Buckets:   []float64{0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60},
+	}, []string{"engine", "mode"})
+	engineMovies = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gophie",
+		Name:      "engine_results",
+		Help:      "Number of movies in the results of successful searches and lists",
+		Buckets:   []float64{0, 1, 5, 10, 20, 50},
+	}, []string{"engine", "mode"})
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gophie",
+		Name:      "cache_lookups_total",
+		Help:      "Lookups of results in the result cache by result (hit or miss)",
+	}, []string{"engine", "result"})
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gophie",
+		Name:      "http_requests_total",
+		Help:      "Requests to the API by handler and status code",
+	}, []string{"handler", "code"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gophie",
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to respond to requests to the API",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"handler"})
+)
+
+func init() {
+	Registry.MustRegister(
+		engineRequests, engineDuration, engineMovies, cacheLookups, httpRequests, httpDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler : serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// InstrumentHandler : Wrap the API handler named name to record its requests
+func InstrumentHandler(name string, handler http.HandlerFunc) http.HandlerFunc {
+	labels := prometheus.Labels{"handler": name}
+	return promhttp.InstrumentHandlerDuration(httpDuration.MustCurryWith(labels),
+		promhttp.InstrumentHandlerCounter(httpRequests.MustCurryWith(labels), handler))
+}
+
+// CacheLookup : A function counting the cache hits and misses of the engine registered as name
+// It is meant to be set as the OnLookup of an engine.CachedEngine
+func CacheLookup(name string) func(hit bool) {
+	hits, misses := cacheLookups.WithLabelValues(name, "hit"), cacheLookups.WithLabelValues(name, "miss")
+	return func(hit bool) {
+		if hit {
+			hits.Inc()
+		} else {
+			misses.Inc()
+		}
+	}
+}
+
+// status : label of the outcome of a search or list
+func status(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "cancelled"
+	case errors.Is(err, engine.ErrEngineUnavailable):
+		return "unavailable"
+	}
+	return "error"
+}
+
+// InstrumentedEngine : An Engine recording metrics of its searches and lists
+type InstrumentedEngine struct {
+	engine.Engine
+	name string
+}
+
+// NewInstrumentedEngine : Wrap the engine registered as name to record its metrics
+func NewInstrumentedEngine(name string, e engine.Engine) *InstrumentedEngine {
+	return &InstrumentedEngine{Engine: e, name: name}
+}
+
+func (e *InstrumentedEngine) observe(ctx context.Context, mode engine.Mode, query string, start time.Time, result engine.SearchResult, err error) {
+	engine.LogOperation(ctx, e.name, mode, query, start, len(result.Movies), err)
+	engineRequests.WithLabelValues(e.name, mode.String(), status(err)).Inc()
+	if err == nil {
+		engineDuration.WithLabelValues(e.name, mode.String()).Observe(time.Since(start).Seconds())
+		engineMovies.WithLabelValues(e.name, mode.String()).Observe(float64(len(result.Movies)))
+	}
+}
+
+// Search : Search the wrapped engine recording the outcome, under a new trace ID
+// unless ctx carries one
+func (e *InstrumentedEngine) Search(ctx context.Context, param ...string) (engine.SearchResult, error) {
+	ctx = logging.EnsureTraceID(ctx)
+	start := time.Now()
+	result, err := e.Engine.Search(ctx, param...)
+	e.observe(ctx, engine.SearchMode, param[0], start, result, err)
+	return result, err
+}
+
+// List : List the wrapped engine recording the outcome, under a new trace ID
+// unless ctx carries one
+func (e *InstrumentedEngine) List(ctx context.Context, page int) (engine.SearchResult, error) {
+	ctx = logging.EnsureTraceID(ctx)
+	start := time.Now()
+	result, err := e.Engine.List(ctx, page)
+	e.observe(ctx, engine.ListMode, "", start, result, err)
+	return result, err
+}
+
+// Modes : the modes of the wrapped engine
+func (e *InstrumentedEngine) Modes() []engine.ScrapeMode {
+	return engine.Modes(e.Engine)
+}
+
+// ListBy : List the wrapped engine in mode recording the outcome
+func (e *InstrumentedEngine) ListBy(ctx context.Context, mode engine.ScrapeMode, page int) (engine.SearchResult, error) {
+	ctx = logging.EnsureTraceID(ctx)
+	start := time.Now()
+	result, err := engine.ListBy(ctx, e.Engine, mode, page)
+	e.observe(ctx, engine.ListMode, "", start, result, err)
+	return result, err
+}
+
+// MarshalJSON : engines are described by the engine being instrumented
+func (e *InstrumentedEngine) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Engine)
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fakeEngine struct {
+	err error
+}
+
+func (e *fakeEngine) Search(ctx context.Context, param ...string) (engine.SearchResult, error) {
+	return engine.SearchResult{Query: param[0], Movies: []engine.Movie{{Title: "Jumanji"}}}, e.err
+}
+
+func (e *fakeEngine) List(ctx context.Context, page int) (engine.SearchResult, error) {
+	return engine.SearchResult{}, e.err
+}
+
+func (e *fakeEngine) Capabilities() engine.Capabilities {
+	return engine.Capabilities{}
+}
+
+func (e *fakeEngine) String() string {
+	return "Fake"
+}
+
+func TestInstrumentedEngine(t *testing.T) {
+	up := NewInstrumentedEngine("up", &fakeEngine{})
+	down := NewInstrumentedEngine("down", &fakeEngine{err: fmt.Errorf("%w: down", engine.ErrEngineUnavailable)})
+	up.Search(context.Background(), "jumanji")
+	up.Search(context.Background(), "jumanji")
+	up.List(context.Background(), 1)
+	down.Search(context.Background(), "jumanji")
+
+	if n := testutil.ToFloat64(engineRequests.WithLabelValues("up", "Search", "ok")); n != 2 {
+		t.Errorf("Expected 2 searches, got %v", n)
+	}
+	if n := testutil.ToFloat64(engineRequests.WithLabelValues("down", "Search", "unavailable")); n != 1 {
+		t.Errorf("Expected 1 failed search, got %v", n)
+	}
+
+	lookup := CacheLookup("up")
+	lookup(true)
+	lookup(false)
+	lookup(true)
+	if n := testutil.ToFloat64(cacheLookups.WithLabelValues("up", "hit")); n != 2 {
+		t.Errorf("Expected 2 cache hits, got %v", n)
+	}
+
+	api := httptest.NewServer(InstrumentHandler("search", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+	}))
+	defer api.Close()
+	http.Get(api.URL)
+	if n := testutil.ToFloat64(httpRequests.WithLabelValues("search", "400")); n != 1 {
+		t.Errorf("Expected 1 bad request, got %v", n)
+	}
+
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	for _, metric := range []string{
+		`gophie_engine_requests_total{engine="up",mode="List",status="ok"} 1`,
+		`gophie_engine_request_duration_seconds_count{engine="up",mode="Search"} 2`,
+		`gophie_cache_lookups_total{engine="up",result="miss"} 1`,
+		`gophie_http_request_duration_seconds_count{handler="search"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(b), metric) {
+			t.Errorf("Expected %s in the metrics", metric)
+		}
+	}
+}
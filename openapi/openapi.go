@@ -0,0 +1,282 @@
+// Package openapi builds OpenAPI 3 documents describing HTTP APIs. Schemas are
+// derived from Go types so the document follows the JSON actually served
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Version : the OpenAPI version documents are written in
+const Version = "3.0.3"
+
+// Document : an OpenAPI document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	names map[reflect.Type]string // types described by components
+}
+
+// Info : what the API is
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem : the operations of a path
+type PathItem struct {
+	Get  *Operation `json:"get,omitempty"`
+	Post *Operation `json:"post,omitempty"`
+}
+
+// Operation : a request which can be made to a path
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter : a parameter of an operation
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // query, header or path
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Response : a response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header : a header of a response
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType : the body of a response
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema : the shape of a value
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// Components : the schemas and security schemes operations refer to
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme : a way of authenticating requests
+type SecurityScheme struct {
+	Type   string `json:"type"` // http or apiKey
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+// New : An empty document of the API
+func New(title, version, description string) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version, Description: description},
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas:         map[string]*Schema{},
+			SecuritySchemes: map[string]*SecurityScheme{},
+		},
+		names: map[reflect.Type]string{},
+	}
+}
+
+// Get : Describe the GET operation of path
+func (d *Document) Get(path string, op *Operation) {
+	if d.Paths[path] == nil {
+		d.Paths[path] = &PathItem{}
+	}
+	d.Paths[path].Get = op
+}
+
+// Post : Describe the POST operation of path
+func (d *Document) Post(path string, op *Operation) {
+	if d.Paths[path] == nil {
+		d.Paths[path] = &PathItem{}
+	}
+	d.Paths[path].Post = op
+}
+
+// Register : Describe the JSON of value as the component schema name and return a
+// reference to it. Every type in also is described by the same schema, e.g a type
+// and the type its MarshalJSON serializes
+func (d *Document) Register(name string, value interface{}, also ...interface{}) *Schema {
+	t := reflect.TypeOf(value)
+	d.names[t] = name
+	for _, v := range also {
+		d.names[reflect.TypeOf(v)] = name
+	}
+	// set before describing the fields so recursive types refer to themselves
+	d.Components.Schemas[name] = &Schema{}
+	*d.Components.Schemas[name] = *d.describe(t)
+	return Ref(name)
+}
+
+// SchemaOf : The schema of the JSON of value, registered types are referred to
+func (d *Document) SchemaOf(value interface{}) *Schema {
+	return d.SchemaOfType(reflect.TypeOf(value))
+}
+
+// SchemaOfType : The schema of the JSON of values of t
+func (d *Document) SchemaOfType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if name, ok := d.names[t]; ok {
+		return Ref(name)
+	}
+	return d.describe(t)
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textType      = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// describe : the schema of t without referring to it by name
+func (d *Document) describe(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	if _, registered := d.names[t]; !registered && t.Kind() == reflect.Struct &&
+		(t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) ||
+			t.Implements(textType) || reflect.PtrTo(t).Implements(textType)) {
+		// types serialized on their own, such as a quality written as "1080p WEB-DL"
+		return &Schema{Type: "string"}
+	}
+	if t.Kind() != reflect.Struct && t.Implements(textType) {
+		// enums serialized by name, such as a mode written as "trending"
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		if t == reflect.TypeOf(time.Duration(0)) {
+			return &Schema{Type: "integer", Description: "nanoseconds"}
+		}
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return ArrayOf(d.SchemaOfType(t.Elem()))
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.SchemaOfType(t.Elem())}
+	case reflect.Struct:
+		schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		d.addFields(schema, t)
+		return schema
+	}
+	// interfaces, funcs and channels have no fixed shape
+	return &Schema{}
+}
+
+// addFields : add the JSON fields of struct t to schema following the rules of
+// encoding/json where fields of embedded structs are promoted unless shadowed
+func (d *Document) addFields(schema *Schema, t reflect.Type) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, tagged := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && !tagged {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		schema.Properties[name] = d.SchemaOfType(field.Type)
+	}
+	for _, et := range embedded {
+		promoted := &Schema{Properties: map[string]*Schema{}}
+		d.addFields(promoted, et)
+		for name, s := range promoted.Properties {
+			if _, shadowed := schema.Properties[name]; !shadowed {
+				schema.Properties[name] = s
+			}
+		}
+	}
+}
+
+// jsonName : the name of field in JSON and whether it was set with a tag
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "" {
+		return field.Name, false
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		return field.Name, false
+	}
+	return name, true
+}
+
+// Ref : A reference to the component schema name
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// ArrayOf : An array of items
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+// JSON : A response with a JSON body of schema
+func JSON(description string, schema *Schema) Response {
+	return Response{Description: description, Content: map[string]MediaType{"application/json": {Schema: schema}}}
+}
@@ -0,0 +1,65 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type quality struct{ Resolution string }
+
+func (q quality) MarshalJSON() ([]byte, error) { return json.Marshal(q.Resolution) }
+
+type mode int
+
+func (m mode) MarshalText() ([]byte, error) { return []byte("latest"), nil }
+
+type movie struct {
+	Title   string
+	Year    int    `json:"year,omitempty"`
+	Secret  string `json:"-"`
+	Link    *string
+	Quality quality
+	Added   time.Time
+	Similar []movie
+	private int
+}
+
+type movieJSON struct {
+	movie
+	Link string
+}
+
+func TestSchemaOf(t *testing.T) {
+	doc := New("Test", "1.0", "")
+	ref := doc.Register("Movie", movieJSON{}, movie{})
+	if ref.Ref != "#/components/schemas/Movie" {
+		t.Errorf("Expected a reference to Movie, got %q", ref.Ref)
+	}
+	schema := doc.Components.Schemas["Movie"]
+	expected := map[string]string{
+		"Title":   "string",
+		"year":    "integer",
+		"Link":    "string",
+		"Quality": "string",
+		"Added":   "string",
+		"Similar": "array",
+	}
+	if len(schema.Properties) != len(expected) {
+		t.Errorf("Expected %d properties, got %d", len(expected), len(schema.Properties))
+	}
+	for name, kind := range expected {
+		if property := schema.Properties[name]; property == nil || property.Type != kind {
+			t.Errorf("Expected %s to be a %s, got %+v", name, kind, property)
+		}
+	}
+	if items := schema.Properties["Similar"].Items; items == nil || items.Ref != ref.Ref {
+		t.Errorf("Expected Similar to refer to Movie, got %+v", items)
+	}
+	if s := doc.SchemaOf(map[string][]int{}); s.Type != "object" || s.AdditionalProperties.Type != "array" {
+		t.Errorf("Expected a map of arrays, got %+v", s)
+	}
+	if s := doc.SchemaOf([]mode{}); s.Items == nil || s.Items.Type != "string" {
+		t.Errorf("Expected an array of modes written as strings, got %+v", s)
+	}
+}
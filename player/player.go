@@ -0,0 +1,156 @@
+// Package player starts media players such as mpv and VLC on the links of
+// movies, passing them the headers the hosts of the links require
+package player
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrNoPlayer : none of the players gophie knows of is installed
+var ErrNoPlayer = errors.New("mpv or vlc must be installed, or a player given with --player-command")
+
+// Detected : the players looked for when no command is configured, in order
+var Detected = []string{"mpv", "vlc"}
+
+// Player : A media player started with its command, in which {url} and {title}
+// are replaced by the link played and the title of the movie. The link is
+// appended when the command has no {url}
+type Player struct {
+	Command []string
+}
+
+// Parse : The player started with command, split on spaces outside of quotes,
+// or the first installed of Detected when command is empty
+func Parse(command string) (*Player, error) {
+	args, err := splitCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 {
+		return &Player{Command: args}, nil
+	}
+	for _, name := range Detected {
+		if path, err := exec.LookPath(name); err == nil {
+			return &Player{Command: []string{path}}, nil
+		}
+	}
+	return nil, ErrNoPlayer
+}
+
+// splitCommand : the arguments of command, those in single or double quotes may
+// contain spaces
+func splitCommand(command string) ([]string, error) {
+	var (
+		args    []string
+		arg     strings.Builder
+		quote   rune
+		inField bool
+	)
+	for _, r := range command {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inField = r, true
+		case r == ' ' || r == '\t':
+			if inField {
+				args = append(args, arg.String())
+				arg.Reset()
+				inField = false
+			}
+		default:
+			arg.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in player command %q", command)
+	}
+	if inField {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// Name : the name of the executable of the player, lowercase e.g mpv
+func (p *Player) Name() string {
+	return strings.TrimSuffix(strings.ToLower(filepath.Base(p.Command[0])), ".exe")
+}
+
+// Supports : whether the player can send the headers to the host itself. mpv
+// sends any, VLC only the User-Agent and Referer and other players none
+func (p *Player) Supports(header http.Header) bool {
+	switch p.Name() {
+	case "mpv":
+		return true
+	case "vlc":
+		for key := range header {
+			if key != "User-Agent" && key != "Referer" {
+				return false
+			}
+		}
+		return true
+	}
+	return len(header) == 0
+}
+
+// headerArgs : the options of the player sending the headers, see Supports
+func (p *Player) headerArgs(header http.Header) []string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var args []string
+	for _, key := range keys {
+		value := header.Get(key)
+		switch name := p.Name(); {
+		case name == "mpv" && key == "User-Agent":
+			args = append(args, "--user-agent="+value)
+		case name == "mpv" && key == "Referer":
+			args = append(args, "--referrer="+value)
+		case name == "mpv":
+			args = append(args, "--http-header-fields-append="+key+": "+value)
+		case name == "vlc" && key == "User-Agent":
+			args = append(args, "--http-user-agent="+value)
+		case name == "vlc" && key == "Referer":
+			args = append(args, "--http-referrer="+value)
+		}
+	}
+	return args
+}
+
+// Args : the arguments the player is started with to play link
+func (p *Player) Args(link, title string, header http.Header) []string {
+	var args []string
+	switch p.Name() {
+	case "mpv":
+		args = append(args, "--force-media-title="+title)
+	case "vlc":
+		args = append(args, "--meta-title="+title)
+	}
+	args = append(args, p.headerArgs(header)...)
+	hasURL := false
+	for _, arg := range p.Command[1:] {
+		hasURL = hasURL || strings.Contains(arg, "{url}")
+		args = append(args, strings.NewReplacer("{url}", link, "{title}", title).Replace(arg))
+	}
+	if !hasURL {
+		args = append(args, link)
+	}
+	return args
+}
+
+// Cmd : the command playing link, killed when ctx is done
+func (p *Player) Cmd(ctx context.Context, link, title string, header http.Header) *exec.Cmd {
+	return exec.CommandContext(ctx, p.Command[0], p.Args(link, title, header)...)
+}
@@ -0,0 +1,51 @@
+package player
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	p, err := Parse(`"/Applications/VLC Player/vlc" --fullscreen`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.Command, []string{"/Applications/VLC Player/vlc", "--fullscreen"}) || p.Name() != "vlc" {
+		t.Errorf("Expected the quoted path kept whole, got %q", p.Command)
+	}
+	if _, err = Parse(`mpv "--title`); err == nil {
+		t.Errorf("Expected an error for an unterminated quote")
+	}
+}
+
+func TestArgs(t *testing.T) {
+	header := http.Header{}
+	header.Set("User-Agent", "Mozilla/5.0")
+	header.Set("Referer", "https://fzmovies.example/download.php")
+	link := "https://cdn.example/jumanji.mp4"
+
+	mpv := &Player{Command: []string{"mpv", "--fs"}}
+	expected := []string{"--force-media-title=Jumanji", "--referrer=https://fzmovies.example/download.php", "--user-agent=Mozilla/5.0", "--fs", link}
+	if args := mpv.Args(link, "Jumanji", header); !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %q, got %q", expected, args)
+	}
+
+	vlc := &Player{Command: []string{"vlc.exe"}}
+	if !vlc.Supports(header) {
+		t.Errorf("Expected VLC to send the User-Agent and Referer")
+	}
+	header.Set("Cookie", "cf_clearance=abc")
+	if vlc.Supports(header) || !mpv.Supports(header) {
+		t.Errorf("Expected only mpv to send cookies")
+	}
+	if args := mpv.Args(link, "Jumanji", header); args[1] != "--http-header-fields-append=Cookie: cf_clearance=abc" {
+		t.Errorf("Expected the cookie passed as a header, got %q", args)
+	}
+
+	custom := &Player{Command: []string{"iina", "--title={title}", "{url}"}}
+	expected = []string{"--title=Jumanji", link}
+	if args := custom.Args(link, "Jumanji", nil); !reflect.DeepEqual(args, expected) || custom.Supports(header) {
+		t.Errorf("Expected the placeholders replaced, got %q", args)
+	}
+}
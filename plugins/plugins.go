@@ -0,0 +1,270 @@
+// Package plugins loads engines shipped as separate executables, so engines of
+// region-specific sites can be installed without being compiled into gophie.
+//
+// A plugin is an executable named gophie-engine-<name> in the plugins directory.
+// It is run once for every request, reads a JSON Request on its standard input
+// and writes a JSON Response on its standard output. Anything it writes to its
+// standard error is logged
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// ProtocolVersion : version of the protocol spoken with plugins, sent in every request
+const ProtocolVersion = 1
+
+// Prefix : prefix of the names of plugin executables
+const Prefix = "gophie-engine-"
+
+// DefaultTimeout : how long a plugin has to respond unless plugin-timeout is set
+const DefaultTimeout = time.Minute
+
+// Methods of the requests sent to plugins
+const (
+	MethodDescribe = "describe"
+	MethodSearch   = "search"
+	MethodList     = "list"
+)
+
+// ErrInvalidPlugin : the executable does not speak the plugin protocol
+var ErrInvalidPlugin = errors.New("Invalid plugin")
+
+// Request : A request to a plugin
+type Request struct {
+	Version int
+	Method  string
+	Query   string `json:",omitempty"` // what to search for
+	Page    int    `json:",omitempty"` // page of the search or list, from 1
+	Proxy   string `json:",omitempty"` // proxy to reach the site through, if configured
+}
+
+// Description : How a plugin describes itself in response to a describe request
+type Description struct {
+	Name         string
+	Description  string
+	BaseURL      string
+	Capabilities engine.Capabilities
+}
+
+// Response : The response of a plugin, with the Description of describe
+// requests or the Result of searches and lists. Error is set when it failed
+type Response struct {
+	Description *Description         `json:",omitempty"`
+	Result      *engine.SearchResult `json:",omitempty"`
+	Error       string               `json:",omitempty"`
+}
+
+// Engine : An engine served by a plugin
+type Engine struct {
+	Path        string // path of the executable
+	Name        string
+	Description string
+	BaseURL     *url.URL
+	Features    engine.Capabilities
+	Timeout     time.Duration // how long the plugin has to respond, DefaultTimeout when 0
+}
+
+// Dir : The directory plugins are loaded from, plugins-dir in the config
+func Dir() string {
+	return viper.GetString("plugins-dir")
+}
+
+// Open : Describe the plugin at path
+func Open(ctx context.Context, path string) (*Engine, error) {
+	e := &Engine{Path: path, Timeout: viper.GetDuration("plugin-timeout")}
+	response, err := e.call(ctx, Request{Method: MethodDescribe})
+	if err != nil {
+		return nil, err
+	}
+	if response.Description == nil {
+		return nil, fmt.Errorf("%w: %s returned no description", ErrInvalidPlugin, path)
+	}
+	description := response.Description
+	e.Name = description.Name
+	if e.Name == "" {
+		e.Name = strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), Prefix), filepath.Ext(path))
+	}
+	e.Description = description.Description
+	e.Features = description.Capabilities
+	if description.BaseURL != "" {
+		if e.BaseURL, err = url.Parse(description.BaseURL); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidPlugin, path, err)
+		}
+	}
+	return e, nil
+}
+
+// Load : Open every plugin in dir and register it as an engine named after it.
+// Plugins which cannot be opened or are named like an engine already registered
+// are skipped with a warning. Returns the registered engines by name
+func Load(ctx context.Context, dir string) []*Engine {
+	paths, err := filepath.Glob(filepath.Join(dir, Prefix+"*"))
+	if err != nil || len(paths) == 0 {
+		return nil
+	}
+	sort.Strings(paths)
+	var loaded []*Engine
+	for _, path := range paths {
+		if !isExecutable(path) {
+			continue
+		}
+		e, err := Open(ctx, path)
+		if err != nil {
+			log.Warnf("Skipped plugin %s: %v", path, err)
+			continue
+		}
+		name := strings.ToLower(e.Name)
+		if engine.EngineRegistered(name) {
+			log.Warnf("Skipped plugin %s: an engine is already named %s", path, name)
+			continue
+		}
+		described := *e
+		engine.RegisterEngine(name, func() engine.Engine {
+			e := described
+			return &e
+		})
+		log.Debugf("Loaded plugin %s from %s", name, path)
+		loaded = append(loaded, e)
+	}
+	return loaded
+}
+
+// isExecutable : whether path is a file that can be run
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(path), ".exe")
+	}
+	return info.Mode()&0111 != 0
+}
+
+// call : run the plugin with request, failing with ErrEngineUnavailable when it
+// cannot be run and with the error the plugin responded with
+func (e *Engine) call(ctx context.Context, request Request) (Response, error) {
+	var response Response
+	request.Version = ProtocolVersion
+	request.Proxy = viper.GetString("proxy")
+	input, err := json.Marshal(request)
+	if err != nil {
+		return response, err
+	}
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.Path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if stderr.Len() > 0 {
+		log.Debugf("%s: %s", filepath.Base(e.Path), strings.TrimSpace(stderr.String()))
+	}
+	if ctx.Err() != nil {
+		return response, ctx.Err()
+	}
+	// plugins exiting with an error may still explain it in their response
+	if decodeErr := json.Unmarshal(stdout.Bytes(), &response); decodeErr != nil {
+		if err != nil {
+			return response, fmt.Errorf("%w: %s: %v", engine.ErrEngineUnavailable, e.Path, err)
+		}
+		return response, fmt.Errorf("%w: %s: %v", ErrInvalidPlugin, e.Path, decodeErr)
+	}
+	if response.Error != "" {
+		return response, fmt.Errorf("%s: %s", e.Name, response.Error)
+	}
+	if err != nil {
+		return response, fmt.Errorf("%w: %s: %v", engine.ErrEngineUnavailable, e.Path, err)
+	}
+	return response, nil
+}
+
+// result : the result of a search or list request
+func (e *Engine) result(ctx context.Context, request Request) (engine.SearchResult, error) {
+	response, err := e.call(ctx, request)
+	if err != nil {
+		return engine.SearchResult{}, err
+	}
+	if response.Result == nil {
+		return engine.SearchResult{}, fmt.Errorf("%w: %s returned no result", ErrInvalidPlugin, e.Name)
+	}
+	result := *response.Result
+	for i := range result.Movies {
+		result.Movies[i].Index = i
+		if result.Movies[i].Source == "" {
+			result.Movies[i].Source = e.Name
+		}
+		if result.Movies[i].Quality.IsZero() {
+			result.Movies[i].Quality = engine.ParseQuality(result.Movies[i].Title)
+		}
+	}
+	return result, nil
+}
+
+// Engine Interface Methods
+
+func (e *Engine) String() string {
+	if e.BaseURL == nil {
+		return fmt.Sprintf("%s (plugin %s)", e.Name, e.Path)
+	}
+	return fmt.Sprintf("%s (%s)", e.Name, e.BaseURL)
+}
+
+// Capabilities : what the plugin described its site as supporting
+func (e *Engine) Capabilities() engine.Capabilities {
+	return e.Features
+}
+
+// Search : Search with the plugin, param is the query and optionally the page
+func (e *Engine) Search(ctx context.Context, param ...string) (engine.SearchResult, error) {
+	request := Request{Method: MethodSearch, Query: param[0], Page: 1}
+	if len(param) > 1 {
+		if page, err := strconv.Atoi(param[1]); err == nil && page > 0 {
+			request.Page = page
+		}
+	}
+	result, err := e.result(ctx, request)
+	if result.Query == "" {
+		result.Query = request.Query
+	}
+	return result, err
+}
+
+// List : List the movies on a page with the plugin
+func (e *Engine) List(ctx context.Context, page int) (engine.SearchResult, error) {
+	return e.result(ctx, Request{Method: MethodList, Page: page})
+}
+
+// Health : Check that the plugin still runs and describes itself
+func (e *Engine) Health(ctx context.Context) error {
+	response, err := e.call(ctx, Request{Method: MethodDescribe})
+	if err == nil && response.Description == nil {
+		err = fmt.Errorf("%w: %s returned no description", ErrInvalidPlugin, e.Path)
+	}
+	return err
+}
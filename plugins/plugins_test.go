@@ -0,0 +1,115 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+// TestHelperPlugin : not a test, the plugin run by the other tests
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv("GOPHIE_TEST_PLUGIN") == "" {
+		return
+	}
+	var request Request
+	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+		os.Exit(2)
+	}
+	var response Response
+	switch request.Method {
+	case MethodDescribe:
+		response.Description = &Description{
+			Name:         os.Getenv("GOPHIE_TEST_PLUGIN"),
+			BaseURL:      "https://films.example/",
+			Capabilities: engine.Capabilities{Pagination: true},
+		}
+	case MethodSearch:
+		if request.Query == "broken" {
+			response.Error = "site is down"
+			break
+		}
+		link, _ := url.Parse("https://films.example/get/1")
+		response.Result = &engine.SearchResult{Movies: []engine.Movie{
+			{Title: fmt.Sprintf("%s 1080p WEB-DL", request.Query), Year: 2021, DownloadLink: link},
+			{Title: fmt.Sprintf("%s page %d", request.Query, request.Page), DownloadLink: link},
+		}}
+	default:
+		fmt.Print("not json")
+	}
+	json.NewEncoder(os.Stdout).Encode(response)
+	os.Exit(0)
+}
+
+// writePlugin : an executable in dir running TestHelperPlugin as the plugin named name
+func writePlugin(t *testing.T, dir, name string) {
+	script := fmt.Sprintf("#!/bin/sh\nGOPHIE_TEST_PLUGIN=%s exec %q -test.run=TestHelperPlugin\n", name, os.Args[0])
+	if err := os.WriteFile(filepath.Join(dir, Prefix+name), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPluginEngine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are run through a shell script")
+	}
+	dir := t.TempDir()
+	writePlugin(t, dir, "NollyFlix")
+	writePlugin(t, dir, "NollyFlix2")
+	// not plugins
+	os.WriteFile(filepath.Join(dir, Prefix+"readme.txt"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(dir, "other"), []byte("#!/bin/sh\n"), 0755)
+
+	loaded := Load(context.Background(), dir)
+	if len(loaded) != 2 || loaded[0].Name != "NollyFlix" || !loaded[0].Features.Pagination {
+		t.Fatalf("Expected the two plugins to be loaded, got %+v", loaded)
+	}
+	// loading again does not register the engines twice
+	if again := Load(context.Background(), dir); len(again) != 0 {
+		t.Errorf("Expected registered plugins to be skipped, got %+v", again)
+	}
+
+	e, err := engine.GetEngine("nollyflix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.String() != "NollyFlix (https://films.example/)" {
+		t.Errorf("Unexpected engine %s", e)
+	}
+	result, err := e.Search(context.Background(), "Living in Bondage", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Query != "Living in Bondage" || len(result.Movies) != 2 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	first, second := result.Movies[0], result.Movies[1]
+	if first.Source != "NollyFlix" || first.Quality.Resolution != "1080p" || first.DownloadLink.String() != "https://films.example/get/1" {
+		t.Errorf("Expected the movie to be completed, got %+v", first)
+	}
+	if second.Title != "Living in Bondage page 2" || second.Index != 1 {
+		t.Errorf("Expected the page to be sent to the plugin, got %+v", second)
+	}
+
+	if _, err = e.Search(context.Background(), "broken"); err == nil || err.Error() != "NollyFlix: site is down" {
+		t.Errorf("Expected the error of the plugin, got %v", err)
+	}
+	if _, err = e.List(context.Background(), 1); !errors.Is(err, ErrInvalidPlugin) {
+		t.Errorf("Expected an invalid response to fail, got %v", err)
+	}
+	if err = engine.Health(context.Background(), e); err != nil {
+		t.Errorf("Expected the plugin to be healthy, got %v", err)
+	}
+
+	missing := &Engine{Name: "Missing", Path: filepath.Join(dir, Prefix+"missing")}
+	if _, err = missing.List(context.Background(), 1); !errors.Is(err, engine.ErrEngineUnavailable) {
+		t.Errorf("Expected a plugin which cannot run to be unavailable, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package queue
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType : what changed about a download
+type EventType string
+
+// Types of events
+const (
+	ProgressEvent EventType = "progress" // more of the download was written
+	StatusEvent   EventType = "status"   // the download started, stopped, completed or failed
+	RemovedEvent  EventType = "removed"  // the download was removed from the queue
+)
+
+// Download : An item of the queue with the speed and the time left of its download
+type Download struct {
+	Item
+	Percent float64 // downloaded percentage, -1 while the size is unknown
+	Speed   int64   `json:",omitempty"` // bytes per second, while downloading
+	ETA     int64   `json:",omitempty"` // seconds left at Speed, while downloading
+}
+
+// Event : A change of a download, pushed to the subscribers of a Monitor
+type Event struct {
+	Type     EventType
+	Download Download
+}
+
+// subscriberBuffer : events a subscriber may fall behind by before missing some
+const subscriberBuffer = 64
+
+// meter : the speed of a download, averaged over its last progress reports
+type meter struct {
+	at         time.Time
+	downloaded int64
+	speed      float64
+}
+
+func (m *meter) update(downloaded int64, now time.Time) {
+	if !m.at.IsZero() && now.After(m.at) && downloaded >= m.downloaded {
+		speed := float64(downloaded-m.downloaded) / now.Sub(m.at).Seconds()
+		if m.speed == 0 {
+			m.speed = speed
+		} else {
+			m.speed = 0.7*m.speed + 0.3*speed
+		}
+	}
+	m.at, m.downloaded = now, downloaded
+}
+
+// Monitor : Measures the speed of the downloads of a Worker and pushes their
+// progress to its subscribers, e.g to show it in a web frontend. A nil Monitor
+// reports nothing
+type Monitor struct {
+	mu          sync.Mutex
+	meters      map[uint64]*meter
+	subscribers map[chan Event]bool
+	closed      bool
+	now         func() time.Time
+}
+
+// NewMonitor : A monitor without subscribers
+func NewMonitor() *Monitor {
+	return &Monitor{meters: map[uint64]*meter{}, subscribers: map[chan Event]bool{}, now: time.Now}
+}
+
+// Subscribe : The events of the downloads until unsubscribe is called or the
+// monitor is closed, which closes the channel. Subscribers which fall behind miss
+// events rather than slowing down the downloads
+func (m *Monitor) Subscribe() (events <-chan Event, unsubscribe func()) {
+	ch := make(chan Event, subscriberBuffer)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	m.subscribers[ch] = true
+	return ch, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.subscribers[ch] {
+			delete(m.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close : End the subscriptions
+func (m *Monitor) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for ch := range m.subscribers {
+		delete(m.subscribers, ch)
+		close(ch)
+	}
+}
+
+// download : item with its measured speed, the lock must be held
+func (m *Monitor) download(item Item) Download {
+	d := Download{Item: item, Percent: item.Progress()}
+	if item.Status != Downloading || m == nil {
+		return d
+	}
+	if speed, ok := m.meters[item.ID]; ok && speed.speed > 0 {
+		d.Speed = int64(speed.speed)
+		if item.Size > item.Downloaded {
+			d.ETA = int64(float64(item.Size-item.Downloaded) / speed.speed)
+		}
+	}
+	return d
+}
+
+// Download : item with the speed and the time left of its download
+func (m *Monitor) Download(item Item) Download {
+	if m == nil {
+		return m.download(item)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.download(item)
+}
+
+// Downloads : items with the speed and the time left of their downloads
+func (m *Monitor) Downloads(items []Item) []Download {
+	downloads := make([]Download, len(items))
+	for i, item := range items {
+		downloads[i] = m.Download(item)
+	}
+	return downloads
+}
+
+// publish : push event to the subscribers, the lock must be held
+func (m *Monitor) publish(event Event) {
+	for ch := range m.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// progress : Record that item is downloaded up to item.Downloaded
+func (m *Monitor) progress(item Item) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	speed, ok := m.meters[item.ID]
+	if !ok {
+		speed = &meter{}
+		m.meters[item.ID] = speed
+	}
+	speed.update(item.Downloaded, m.now())
+	m.publish(Event{Type: ProgressEvent, Download: m.download(item)})
+}
+
+// status : Record that the status of item changed
+func (m *Monitor) status(item Item) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if item.Status != Downloading {
+		delete(m.meters, item.ID)
+	}
+	m.publish(Event{Type: StatusEvent, Download: m.download(item)})
+}
+
+// removed : Record that the download id was removed from the queue
+func (m *Monitor) removed(id uint64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.meters, id)
+	m.publish(Event{Type: RemovedEvent, Download: Download{Item: Item{ID: id}, Percent: -1}})
+}
@@ -0,0 +1,214 @@
+// Package queue keeps a persistent queue of movie downloads which a Worker
+// downloads a few at a time. The queue is stored in a bbolt database which is
+// only opened for the duration of an operation, so the queue can be managed from
+// one gophie process while another runs the worker
+package queue
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	bolt "go.etcd.io/bbolt"
+)
+
+var itemsBucket = []byte("downloads")
+
+// ErrNotFound : there is no download with the ID in the queue
+var ErrNotFound = errors.New("Download not in queue")
+
+// ErrNotDownloadable : the movie cannot be downloaded by the queue e.g torrents and series
+var ErrNotDownloadable = errors.New("Movie cannot be queued")
+
+// Status : state of a download in the queue
+type Status string
+
+// Statuses of downloads
+const (
+	Queued      Status = "queued"      // waiting for a worker
+	Downloading Status = "downloading" // being downloaded by a worker
+	Paused      Status = "paused"      // left out by the worker until resumed
+	Completed   Status = "completed"
+	Failed      Status = "failed" // see Error, resuming retries it
+)
+
+// Item : a download in the queue
+type Item struct {
+	ID         uint64
+	Movie      engine.Movie
+	Status     Status
+	Downloaded int64  // bytes downloaded so far
+	Size       int64  // bytes of the file, 0 until known
+	Error      string // why the download failed
+	Path       string // where the movie was saved once completed
+	Retries    int    // times the download was queued again after being corrupted
+	AddedAt    time.Time
+	UpdatedAt  time.Time
+}
+
+// Progress : downloaded percentage of the item, -1 when the size is unknown
+func (i *Item) Progress() float64 {
+	if i.Size <= 0 {
+		return -1
+	}
+	return float64(i.Downloaded) * 100 / float64(i.Size)
+}
+
+// Queue : A persistent queue of downloads
+type Queue struct {
+	path string
+}
+
+// Open : Open (or create) the queue stored at path
+func Open(path string) (*Queue, error) {
+	q := &Queue{path: path}
+	err := q.update(func(b *bolt.Bucket) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// withDB : run fn in a transaction on the items bucket
+func (q *Queue) withDB(writable bool, fn func(*bolt.Bucket) error) error {
+	db, err := bolt.Open(q.path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if !writable {
+		return db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket(itemsBucket)
+			if b == nil {
+				return nil
+			}
+			return fn(b)
+		})
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(itemsBucket)
+		if err != nil {
+			return err
+		}
+		return fn(b)
+	})
+}
+
+func (q *Queue) update(fn func(*bolt.Bucket) error) error {
+	return q.withDB(true, fn)
+}
+
+func itemKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+func putItem(b *bolt.Bucket, item *Item) error {
+	item.UpdatedAt = time.Now()
+	v, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return b.Put(itemKey(item.ID), v)
+}
+
+func getItem(b *bolt.Bucket, id uint64) (Item, error) {
+	var item Item
+	v := b.Get(itemKey(id))
+	if v == nil {
+		return item, fmt.Errorf("%w: %d", ErrNotFound, id)
+	}
+	return item, json.Unmarshal(v, &item)
+}
+
+// Add : Queue the download of movie
+func (q *Queue) Add(movie engine.Movie) (Item, error) {
+	if movie.DownloadLink == nil || movie.MagnetLink != "" || movie.IsSeries {
+		return Item{}, fmt.Errorf("%w: %s", ErrNotDownloadable, movie.Title)
+	}
+	item := Item{Movie: movie, Status: Queued, AddedAt: time.Now()}
+	err := q.update(func(b *bolt.Bucket) error {
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		item.ID = id
+		return putItem(b, &item)
+	})
+	return item, err
+}
+
+// Get : The download with id
+func (q *Queue) Get(id uint64) (Item, error) {
+	var item Item
+	err := q.withDB(false, func(b *bolt.Bucket) (err error) {
+		item, err = getItem(b, id)
+		return err
+	})
+	if err == nil && item.ID == 0 {
+		err = fmt.Errorf("%w: %d", ErrNotFound, id)
+	}
+	return item, err
+}
+
+// List : The downloads in the order they were added
+func (q *Queue) List() ([]Item, error) {
+	var items []Item
+	err := q.withDB(false, func(b *bolt.Bucket) error {
+		return b.ForEach(func(_, v []byte) error {
+			var item Item
+			if err := json.Unmarshal(v, &item); err != nil {
+				return err
+			}
+			items = append(items, item)
+			return nil
+		})
+	})
+	return items, err
+}
+
+// Update : Change the download with id using fn
+func (q *Queue) Update(id uint64, fn func(*Item)) error {
+	return q.update(func(b *bolt.Bucket) error {
+		item, err := getItem(b, id)
+		if err != nil {
+			return err
+		}
+		fn(&item)
+		return putItem(b, &item)
+	})
+}
+
+// Pause : Stop the download with id until it is resumed
+// Downloads in progress are stopped by the worker, keeping what was downloaded
+func (q *Queue) Pause(id uint64) error {
+	return q.Update(id, func(item *Item) {
+		if item.Status == Queued || item.Status == Downloading {
+			item.Status = Paused
+		}
+	})
+}
+
+// Resume : Queue a paused or failed download again
+func (q *Queue) Resume(id uint64) error {
+	return q.Update(id, func(item *Item) {
+		if item.Status == Paused || item.Status == Failed {
+			item.Status = Queued
+			item.Error = ""
+		}
+	})
+}
+
+// Remove : Remove the download with id from the queue, downloaded files are kept
+func (q *Queue) Remove(id uint64) error {
+	return q.update(func(b *bolt.Bucket) error {
+		if b.Get(itemKey(id)) == nil {
+			return fmt.Errorf("%w: %d", ErrNotFound, id)
+		}
+		return b.Delete(itemKey(id))
+	})
+}
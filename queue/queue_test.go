@@ -0,0 +1,358 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-phie/gophie/downloader"
+	"github.com/go-phie/gophie/engine"
+)
+
+func testMovie(title, link string) engine.Movie {
+	u, _ := url.Parse(link)
+	return engine.Movie{Title: title, DownloadLink: u, Source: "Test"}
+}
+
+func openTestQueue(t *testing.T) *Queue {
+	q, err := Open(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+// runWorker : Run worker until the test ends, waiting for it to stop so that it
+// does not open the queue again once the directory of the test is removed
+func runWorker(t *testing.T, worker *Worker) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		worker.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+}
+
+// waitUntil : wait until done is true for the download with id
+func waitUntil(t *testing.T, q *Queue, id uint64, done func(Item) bool) Item {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		item, err := q.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done(item) {
+			return item
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	item, _ := q.Get(id)
+	t.Fatalf("Download %d timed out: %+v", id, item)
+	return item
+}
+
+// waitFor : wait until the download with id has status
+func waitFor(t *testing.T, q *Queue, id uint64, status Status) Item {
+	return waitUntil(t, q, id, func(item Item) bool { return item.Status == status })
+}
+
+func TestQueue(t *testing.T) {
+	q := openTestQueue(t)
+	first, err := q.Add(testMovie("First", "https://example.com/first.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := q.Add(testMovie("Second", "https://example.com/second.mp4"))
+	if first.ID != 1 || second.ID != 2 || first.Status != Queued {
+		t.Errorf("Expected queued downloads with increasing IDs, got %+v %+v", first, second)
+	}
+
+	torrent := testMovie("Torrent", "https://example.com/first.torrent")
+	torrent.MagnetLink = "magnet:?xt=urn:btih:abc"
+	if _, err := q.Add(torrent); !errors.Is(err, ErrNotDownloadable) {
+		t.Errorf("Expected torrents to be refused, got %v", err)
+	}
+
+	if err := q.Pause(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if item, _ := q.Get(first.ID); item.Status != Paused {
+		t.Errorf("Expected download to be paused, got %s", item.Status)
+	}
+	q.Resume(first.ID)
+	if item, _ := q.Get(first.ID); item.Status != Queued {
+		t.Errorf("Expected download to be queued again, got %s", item.Status)
+	}
+
+	if err := q.Remove(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Remove(second.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected removed download to be missing, got %v", err)
+	}
+	items, err := q.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Movie.Title != "First" || items[0].Movie.DownloadLink.String() != "https://example.com/first.mp4" {
+		t.Errorf("Unexpected queue %+v", items)
+	}
+}
+
+func TestWorker(t *testing.T) {
+	q := openTestQueue(t)
+	var (
+		mu      sync.Mutex
+		running int
+		most    int
+	)
+	release := make(chan struct{})
+	worker := &Worker{
+		Queue:        q,
+		Workers:      2,
+		PollInterval: 10 * time.Millisecond,
+		Download: func(ctx context.Context, item Item, progress downloader.ProgressFunc) (string, error) {
+			mu.Lock()
+			running++
+			if running > most {
+				most = running
+			}
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				running--
+				mu.Unlock()
+			}()
+			progress(50, 100)
+			if item.Movie.Title == "Broken" {
+				return "", errors.New("broken link")
+			}
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-release:
+			}
+			progress(100, 100)
+			return item.Movie.Title + ".mp4", nil
+		},
+	}
+	// left downloading by a previous run which was killed
+	interrupted, _ := q.Add(testMovie("Interrupted", "https://example.com/0.mp4"))
+	q.Update(interrupted.ID, func(item *Item) { item.Status = Downloading })
+	var ids []uint64
+	for _, title := range []string{"Broken", "A", "B"} {
+		item, _ := q.Add(testMovie(title, "https://example.com/"+title+".mp4"))
+		ids = append(ids, item.ID)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- worker.Run(ctx) }()
+
+	if item := waitFor(t, q, ids[0], Failed); item.Error != "broken link" {
+		t.Errorf("Expected the error to be recorded, got %q", item.Error)
+	}
+	// the progress of the download is recorded
+	waitUntil(t, q, interrupted.ID, func(item Item) bool {
+		return item.Status == Downloading && item.Downloaded == 50 && item.Size == 100
+	})
+	q.Pause(interrupted.ID)
+	waitFor(t, q, interrupted.ID, Paused)
+	close(release)
+	waitFor(t, q, ids[1], Completed)
+	waitFor(t, q, ids[2], Completed)
+
+	cancel()
+	if err := <-stopped; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the worker to stop, got %v", err)
+	}
+	if most > 2 {
+		t.Errorf("Expected at most 2 downloads at a time, got %d", most)
+	}
+	if item, _ := q.Get(interrupted.ID); item.Status != Paused {
+		t.Errorf("Expected paused download to stay paused, got %s", item.Status)
+	}
+}
+
+func TestWorkerRetry(t *testing.T) {
+	q := openTestQueue(t)
+	var attempts int
+	worker := &Worker{
+		Queue:        q,
+		Workers:      1,
+		PollInterval: 10 * time.Millisecond,
+		Download: func(ctx context.Context, item Item, progress downloader.ProgressFunc) (string, error) {
+			attempts++
+			if item.Movie.Title == "Corrupted" || attempts == 1 {
+				return "", fmt.Errorf("%w: checksum does not match", downloader.ErrCorrupted)
+			}
+			return "flaky.mp4", nil
+		},
+	}
+	failed := make(chan Item, 2)
+	worker.OnFailed = func(item Item) { failed <- item }
+	flaky, _ := q.Add(testMovie("Flaky", "https://example.com/flaky.mp4"))
+	runWorker(t, worker)
+
+	if item := waitFor(t, q, flaky.ID, Completed); item.Retries != 1 || item.Error != "" {
+		t.Errorf("Expected the corrupted download to be retried once, got %+v", item)
+	}
+	corrupted, _ := q.Add(testMovie("Corrupted", "https://example.com/corrupted.mp4"))
+	if item := waitFor(t, q, corrupted.ID, Failed); item.Retries != MaxRetries {
+		t.Errorf("Expected %d retries before failing, got %d", MaxRetries, item.Retries)
+	}
+	select {
+	case item := <-failed:
+		if item.ID != corrupted.ID || item.Error == "" || len(failed) != 0 {
+			t.Errorf("Expected the failure reported once, got %+v", item)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the failure to be reported")
+	}
+}
+
+func TestWorkerDrained(t *testing.T) {
+	q := openTestQueue(t)
+	worker := &Worker{
+		Queue:        q,
+		Workers:      1,
+		PollInterval: 10 * time.Millisecond,
+		Download: func(ctx context.Context, item Item, progress downloader.ProgressFunc) (string, error) {
+			return item.Movie.Title + ".mp4", nil
+		},
+	}
+	drained := make(chan struct{}, 4)
+	worker.OnDrained = func() { drained <- struct{}{} }
+
+	// downloads queued after the queue was drained are drained again
+	for i, batch := range [][]string{{"A", "B"}, {"C"}} {
+		var ids []uint64
+		for _, title := range batch {
+			item, _ := q.Add(testMovie(title, "https://example.com/"+title+".mp4"))
+			ids = append(ids, item.ID)
+		}
+		if i == 0 {
+			runWorker(t, worker)
+		}
+		select {
+		case <-drained:
+			for _, id := range ids {
+				if item, _ := q.Get(id); item.Status != Completed {
+					t.Errorf("Expected the queue drained once every download completed, got %+v", item)
+				}
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected the queue drained after %v", batch)
+		}
+	}
+	if len(drained) != 0 {
+		t.Errorf("Expected the queue drained once per batch, got %d more", len(drained))
+	}
+}
+
+func TestWorkerDownload(t *testing.T) {
+	content := strings.Repeat("gophie", 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="movie.mp4"`)
+		http.ServeContent(w, r, "movie.mp4", time.Now(), strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	q := openTestQueue(t)
+	item, _ := q.Add(testMovie("Jumanji", server.URL+"/movie.mp4"))
+	outputDir := t.TempDir()
+	worker := NewWorker(q, 1, outputDir)
+	worker.PollInterval = 10 * time.Millisecond
+	completed := make(chan Item, 1)
+	worker.OnComplete = func(item Item) { completed <- item }
+	runWorker(t, worker)
+
+	item = waitFor(t, q, item.ID, Completed)
+	if item.Downloaded != int64(len(content)) {
+		t.Errorf("Expected %d bytes downloaded, got %d", len(content), item.Downloaded)
+	}
+	path := filepath.Join(downloader.MovieDir(outputDir, &item.Movie), "Jumanji.mp4")
+	b, err := os.ReadFile(path)
+	if err != nil || string(b) != content {
+		t.Errorf("Expected the movie to be downloaded, got %v", err)
+	}
+	if done := <-completed; done.ID != item.ID || done.Path != path {
+		t.Errorf("Expected completion of %s to be reported, got %+v", path, done)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	// speeds are averaged over the progress reports
+	m := NewMonitor()
+	now := time.Unix(0, 0)
+	m.now = func() time.Time { return now }
+	item := Item{ID: 1, Status: Downloading, Size: 1000}
+	for _, downloaded := range []int64{0, 100, 200} {
+		item.Downloaded = downloaded
+		m.progress(item)
+		now = now.Add(time.Second)
+	}
+	if d := m.Download(item); d.Speed != 100 || d.ETA != 8 || d.Percent != 20 {
+		t.Errorf("Expected 100 bytes per second with 8 seconds left at 20%%, got %+v", d)
+	}
+	item.Status = Paused
+	m.status(item)
+	if d := m.Download(item); d.Speed != 0 || d.ETA != 0 {
+		t.Errorf("Expected no speed once paused, got %+v", d)
+	}
+	var nilMonitor *Monitor
+	if d := nilMonitor.Download(item); d.Percent != 20 {
+		t.Errorf("Expected the progress without a monitor, got %+v", d)
+	}
+
+	// the worker reports the downloads to the subscribers
+	q := openTestQueue(t)
+	m = NewMonitor()
+	events, unsubscribe := m.Subscribe()
+	defer unsubscribe()
+	worker := &Worker{
+		Queue:        q,
+		Workers:      1,
+		PollInterval: 10 * time.Millisecond,
+		Monitor:      m,
+		Download: func(ctx context.Context, item Item, progress downloader.ProgressFunc) (string, error) {
+			progress(100, 100)
+			return "movie.mp4", nil
+		},
+	}
+	added, _ := q.Add(testMovie("Movie", "https://example.com/movie.mp4"))
+	runWorker(t, worker)
+
+	var got []string
+	for len(got) < 3 {
+		select {
+		case event := <-events:
+			if event.Download.ID != added.ID {
+				t.Fatalf("Unexpected event of download %d", event.Download.ID)
+			}
+			got = append(got, fmt.Sprintf("%s %s %.0f", event.Type, event.Download.Status, event.Download.Percent))
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for events, got %v", got)
+		}
+	}
+	if want := "status downloading -1,progress downloading 100,status completed 100"; strings.Join(got, ",") != want {
+		t.Errorf("Expected the events %s, got %s", want, strings.Join(got, ","))
+	}
+	m.Close()
+	if _, ok := <-events; ok {
+		t.Error("Expected the events to end once the monitor is closed")
+	}
+}
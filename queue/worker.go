@@ -0,0 +1,231 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-phie/gophie/downloader"
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+)
+
+// Defaults of Worker
+const (
+	DefaultWorkers      = 2
+	DefaultPollInterval = 2 * time.Second
+	// MaxRetries : times a corrupted download is queued again before it fails
+	MaxRetries = 2
+)
+
+// DownloadFunc : Download the movie of item reporting progress until ctx is
+// cancelled and return where it was saved
+type DownloadFunc func(ctx context.Context, item Item, progress downloader.ProgressFunc) (string, error)
+
+// Worker : Downloads the queued movies a few at a time
+type Worker struct {
+	Queue        *Queue
+	Workers      int           // downloads at the same time
+	PollInterval time.Duration // how often the queue is checked for new, paused and removed downloads
+	Download     DownloadFunc
+	OnComplete   func(item Item) // called once a download is completed
+	OnFailed     func(item Item) // called once a download failed, with the Error of item
+	OnDrained    func()          // called once the downloads started are done and none are left queued
+	Monitor      *Monitor        // reports the progress of the downloads, if set
+}
+
+// NewWorker : A worker downloading the movies of q into outputDir
+func NewWorker(q *Queue, workers int, outputDir string) *Worker {
+	return &Worker{
+		Queue:        q,
+		Workers:      workers,
+		PollInterval: DefaultPollInterval,
+		Download: func(ctx context.Context, item Item, progress downloader.ProgressFunc) (string, error) {
+			// links to landing pages or which expired are resolved to the file
+			if err := engine.ResolveMovieLink(ctx, &item.Movie); err != nil {
+				log.Debugf("Could not resolve the link of %s: %v", item.Movie.Title, err)
+			}
+			d := downloader.NewMovieDownloader(&item.Movie, outputDir)
+			d.OnProgress = progress
+			err := d.DownloadFileContext(ctx)
+			return d.Path(), err
+		},
+	}
+}
+
+// Run : Download queued movies until ctx is cancelled
+// Downloads interrupted by a previous run are resumed where they stopped
+func (w *Worker) Run(ctx context.Context) error {
+	workers := w.Workers
+	if workers < 1 {
+		workers = DefaultWorkers
+	}
+	interval := w.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	items, err := w.Queue.List()
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if item.Status == Downloading {
+			w.Queue.Update(item.ID, func(item *Item) { item.Status = Queued })
+		}
+	}
+
+	var wg sync.WaitGroup
+	// whether downloads were started since the queue was last drained
+	busy := false
+	running := map[uint64]context.CancelFunc{}
+	done := make(chan uint64)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		items, err := w.Queue.List()
+		if err != nil {
+			log.Errorf("Could not read download queue: %v", err)
+		}
+		status := map[uint64]Status{}
+		for _, item := range items {
+			status[item.ID] = item.Status
+		}
+		// paused and removed downloads are stopped
+		for id, cancel := range running {
+			if status[id] != Downloading {
+				cancel()
+			}
+		}
+		for _, item := range items {
+			if len(running) >= workers {
+				break
+			}
+			if item.Status != Queued {
+				continue
+			}
+			if err := w.Queue.Update(item.ID, func(item *Item) { item.Status = Downloading }); err != nil {
+				log.Error(err)
+				continue
+			}
+			downloadCtx, cancel := context.WithCancel(ctx)
+			running[item.ID] = cancel
+			busy = true
+			wg.Add(1)
+			go func(item Item) {
+				defer wg.Done()
+				w.download(downloadCtx, item)
+				done <- item.ID
+			}(item)
+		}
+
+		if busy && len(running) == 0 && err == nil && !hasQueued(items) {
+			busy = false
+			if w.OnDrained != nil {
+				w.OnDrained()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			for _, cancel := range running {
+				cancel()
+			}
+			go func() {
+				for range done {
+				}
+			}()
+			wg.Wait()
+			close(done)
+			return ctx.Err()
+		case id := <-done:
+			running[id]()
+			delete(running, id)
+		case <-ticker.C:
+		}
+	}
+}
+
+// hasQueued : whether some of items wait to be downloaded
+func hasQueued(items []Item) bool {
+	for _, item := range items {
+		if item.Status == Queued {
+			return true
+		}
+	}
+	return false
+}
+
+// download : Download item recording its progress in the queue every few seconds
+func (w *Worker) download(ctx context.Context, item Item) {
+	log.Infof("Downloading %s", item.Movie.Title)
+	item.Status = Downloading
+	w.Monitor.status(item)
+	var lastSaved time.Time
+	path, downloadErr := w.Download(ctx, item, func(downloaded, total int64) {
+		if time.Since(lastSaved) < time.Second && downloaded < total {
+			return
+		}
+		lastSaved = time.Now()
+		w.Queue.Update(item.ID, func(item *Item) {
+			item.Downloaded, item.Size = downloaded, total
+		})
+		progress := item
+		progress.Downloaded, progress.Size = downloaded, total
+		w.Monitor.progress(progress)
+	})
+	cancelled := ctx.Err() != nil
+	completed, requeued := false, false
+	err := w.Queue.Update(item.ID, func(item *Item) {
+		// paused or removed while downloading
+		if item.Status != Downloading {
+			return
+		}
+		switch {
+		case cancelled:
+			// the worker stopped, resume with the next run
+			item.Status = Queued
+		case errors.Is(downloadErr, downloader.ErrCorrupted) && item.Retries < MaxRetries:
+			// the corrupted file was deleted so it is downloaded again from the start
+			item.Status = Queued
+			item.Retries++
+			item.Downloaded = 0
+			item.Error = downloadErr.Error()
+			requeued = true
+		case downloadErr != nil:
+			item.Status = Failed
+			item.Error = downloadErr.Error()
+		default:
+			item.Status = Completed
+			item.Error = ""
+			item.Path = path
+			if item.Size > 0 {
+				item.Downloaded = item.Size
+			}
+			completed = true
+		}
+	})
+	if err != nil {
+		log.Debugf("Download %d no longer in queue: %v", item.ID, err)
+		w.Monitor.removed(item.ID)
+	} else if updated, err := w.Queue.Get(item.ID); err == nil {
+		w.Monitor.status(updated)
+	}
+	switch {
+	case cancelled:
+		log.Infof("Stopped downloading %s", item.Movie.Title)
+	case requeued:
+		log.Warnf("%v, queued %s again", downloadErr, item.Movie.Title)
+	case downloadErr != nil:
+		log.Errorf("Download of %s failed: %v", item.Movie.Title, downloadErr)
+		if w.OnFailed != nil {
+			if item, err := w.Queue.Get(item.ID); err == nil && item.Status == Failed {
+				w.OnFailed(item)
+			}
+		}
+	case completed && w.OnComplete != nil:
+		if item, err := w.Queue.Get(item.ID); err == nil {
+			w.OnComplete(item)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package refresh keeps the result cache warm by listing the movies of engines on
+// a schedule, so that lists are answered from the cache instead of the sites
+package refresh
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultInterval : how often the lists are refreshed
+const DefaultInterval = 15 * time.Minute
+
+// List : a page of the movies of an engine in a mode
+type List struct {
+	Engine string
+	Mode   engine.ScrapeMode
+	Page   int
+}
+
+func (l List) String() string {
+	return fmt.Sprintf("%s %s page %d", l.Engine, l.Mode, l.Page)
+}
+
+// Report : what a refresh did
+type Report struct {
+	Refreshed []List
+	Failed    map[List]error
+	Took      time.Duration
+}
+
+// Refresher : Lists the movies of engines into the result cache every Interval.
+// The engines must be cached engines for their lists to be kept
+type Refresher struct {
+	Engines  map[string]engine.Engine
+	Modes    []engine.ScrapeMode // refreshed for the engines listing them, the latest movies when empty
+	Pages    int                 // refreshed in every mode, 1 when 0 or less
+	Interval time.Duration
+
+	// New : when set, every refresh lists a new engine of each name instead of
+	// the one in Engines, so that nothing an engine keeps between lists carries over
+	New func(name string) (engine.Engine, error)
+}
+
+// modes : the modes of e which are refreshed
+func (r *Refresher) modes(e engine.Engine) []engine.ScrapeMode {
+	if len(r.Modes) == 0 {
+		return []engine.ScrapeMode{engine.Latest}
+	}
+	var modes []engine.ScrapeMode
+	for _, mode := range r.Modes {
+		if engine.Supports(e, mode) {
+			modes = append(modes, mode)
+		}
+	}
+	return modes
+}
+
+// refreshEngine : list the pages of e in every mode, a mode stops at its last
+// page or at the first failure
+func (r *Refresher) refreshEngine(ctx context.Context, name string, e engine.Engine, done func(List, error)) {
+	pages := r.Pages
+	if pages <= 0 || !e.Capabilities().Pagination {
+		pages = 1
+	}
+	for _, mode := range r.modes(e) {
+		for page := 1; page <= pages && ctx.Err() == nil; page++ {
+			list := List{Engine: name, Mode: mode, Page: page}
+			result, err := engine.ListBy(ctx, e, mode, page)
+			if err == nil && len(result.Movies) == 0 {
+				err = fmt.Errorf("%w: no movies listed", engine.ErrEngineUnavailable)
+			}
+			done(list, err)
+			if err != nil || (result.TotalPages > 0 && !result.HasNext) {
+				break
+			}
+		}
+	}
+}
+
+// Refresh : List every engine once, scraping the sites even when the lists are
+// cached. Engines are refreshed at the same time, their pages one after another
+func (r *Refresher) Refresh(ctx context.Context) Report {
+	start := time.Now()
+	ctx = engine.WithRefresh(ctx)
+	report := Report{Failed: map[List]error{}}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	done := func(list List, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			report.Failed[list] = err
+			return
+		}
+		report.Refreshed = append(report.Refreshed, list)
+	}
+	for name, e := range r.Engines {
+		if r.New != nil {
+			fresh, err := r.New(name)
+			if err != nil {
+				log.Warnf("Could not create engine %s, refreshing the previous one: %v", name, err)
+			} else {
+				e = fresh
+			}
+		}
+		wg.Add(1)
+		go func(name string, e engine.Engine) {
+			defer wg.Done()
+			r.refreshEngine(ctx, name, e, done)
+		}(name, e)
+	}
+	wg.Wait()
+	sort.Slice(report.Refreshed, func(i, j int) bool {
+		return report.Refreshed[i].String() < report.Refreshed[j].String()
+	})
+	report.Took = time.Since(start)
+	return report
+}
+
+// Run : Refresh every Interval until ctx is cancelled, starting right away
+func (r *Refresher) Run(ctx context.Context) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report := r.Refresh(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for list, err := range report.Failed {
+			log.Warnf("Could not refresh %s: %v", list, err)
+		}
+		log.Infof("Refreshed %d lists in %s, %d failed", len(report.Refreshed), report.Took.Round(time.Millisecond), len(report.Failed))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
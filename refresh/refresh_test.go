@@ -0,0 +1,109 @@
+package refresh
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+// listEngine : lists 3 pages of latest movies and a page of trending ones
+type listEngine struct {
+	listed []string
+}
+
+func (e *listEngine) Search(ctx context.Context, param ...string) (engine.SearchResult, error) {
+	return engine.SearchResult{Query: param[0]}, nil
+}
+func (e *listEngine) List(ctx context.Context, page int) (engine.SearchResult, error) {
+	return e.ListBy(ctx, engine.Latest, page)
+}
+func (e *listEngine) ListBy(ctx context.Context, mode engine.ScrapeMode, page int) (engine.SearchResult, error) {
+	e.listed = append(e.listed, fmt.Sprintf("%s %d", mode, page))
+	total := 3
+	if mode == engine.Trending {
+		total = 1
+	}
+	link, _ := url.Parse(fmt.Sprintf("https://lists.example/%s/%d.mp4", mode, page))
+	return engine.SearchResult{
+		Movies:     []engine.Movie{{Title: fmt.Sprintf("%s %d", mode, page), DownloadLink: link}},
+		Page:       page,
+		TotalPages: total,
+		HasNext:    page < total,
+	}, nil
+}
+func (e *listEngine) Modes() []engine.ScrapeMode {
+	return []engine.ScrapeMode{engine.Latest, engine.Trending}
+}
+func (e *listEngine) Capabilities() engine.Capabilities { return engine.Capabilities{Pagination: true} }
+func (e *listEngine) String() string                    { return "Lists" }
+
+func TestRefresh(t *testing.T) {
+	cache, err := engine.OpenResultCache(filepath.Join(t.TempDir(), "results.db"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+	e := &listEngine{}
+	cached := engine.NewCachedEngine("lists", e, cache)
+	if _, err = cached.List(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Refresher{
+		Engines: map[string]engine.Engine{"lists": cached},
+		Modes:   []engine.ScrapeMode{engine.Latest, engine.Trending, engine.TopRated},
+		Pages:   5,
+	}
+	report := r.Refresh(context.Background())
+	if len(report.Refreshed) != 4 || len(report.Failed) != 0 {
+		t.Errorf("Expected 4 lists refreshed, got %v and failures %v", report.Refreshed, report.Failed)
+	}
+	// the cached first page is scraped again, and pages stop at the last one
+	if got := strings.Join(e.listed, ","); got != "latest 1,latest 1,latest 2,latest 3,trending 1" {
+		t.Errorf("Unexpected lists scraped %s", got)
+	}
+
+	// lists are then served from the cache
+	e.listed = nil
+	for page := 1; page <= 3; page++ {
+		if result, err := cached.List(context.Background(), page); err != nil || len(result.Movies) != 1 {
+			t.Errorf("Expected page %d from the cache, got %+v %v", page, result, err)
+		}
+	}
+	if _, err = engine.ListBy(context.Background(), cached, engine.Trending, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.listed) != 0 {
+		t.Errorf("Expected no scraping once the lists are warm, scraped %v", e.listed)
+	}
+}
+
+func TestRefreshNewEngines(t *testing.T) {
+	var created []*listEngine
+	r := &Refresher{
+		Engines: map[string]engine.Engine{"lists": &listEngine{}},
+		New: func(name string) (engine.Engine, error) {
+			e := &listEngine{}
+			created = append(created, e)
+			return e, nil
+		},
+	}
+	for i := 0; i < 2; i++ {
+		if report := r.Refresh(context.Background()); len(report.Refreshed) != 1 {
+			t.Fatalf("Expected the latest list refreshed, got %+v", report)
+		}
+	}
+	// every refresh lists a new engine, the one in Engines is not listed
+	if len(created) != 2 || len(created[0].listed) != 1 || len(created[1].listed) != 1 {
+		t.Errorf("Expected a new engine listed by each refresh, got %d engines", len(created))
+	}
+	if e := r.Engines["lists"].(*listEngine); len(e.listed) != 0 {
+		t.Errorf("Expected the engine in Engines to be replaced, it listed %v", e.listed)
+	}
+}
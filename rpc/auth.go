@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// NewGRPCServer : gRPC server serving the Gophie service
+// When keys are set, calls must send one of them as a bearer token in the
+// authorization metadata like requests to the HTTP API. Calls of every valid key
+// (or IP for calls without one) are let through only when allow, if set, allows them
+func NewGRPCServer(getEngine EngineGetter, keys map[string]bool, allow func(client string) bool) *grpc.Server {
+	var opts []grpc.ServerOption
+	if len(keys) > 0 || allow != nil {
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				if err := authorize(ctx, keys, allow); err != nil {
+					return nil, err
+				}
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := authorize(ss.Context(), keys, allow); err != nil {
+					return err
+				}
+				return handler(srv, ss)
+			}),
+		)
+	}
+	s := grpc.NewServer(opts...)
+	RegisterGophieServer(s, NewServer(getEngine))
+	return s
+}
+
+// authorize : Check the key of a call when keys are set, then its rate limit
+func authorize(ctx context.Context, keys map[string]bool, allow func(client string) bool) error {
+	var key string
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if token := strings.TrimPrefix(value, "Bearer "); keys[token] {
+			key = token
+			break
+		}
+	}
+	if len(keys) > 0 && key == "" {
+		return status.Error(codes.Unauthenticated, "Unauthorized Access")
+	}
+	if allow == nil {
+		return nil
+	}
+	var client string
+	if key != "" {
+		client = "key:" + key
+	} else if p, ok := peer.FromContext(ctx); ok {
+		client, _, _ = net.SplitHostPort(p.Addr.String())
+	}
+	if !allow(client) {
+		return status.Error(codes.ResourceExhausted, "Too Many Requests")
+	}
+	return nil
+}
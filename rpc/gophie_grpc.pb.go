@@ -0,0 +1,249 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.21.12
+// source: gophie.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// GophieClient is the client API for Gophie service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GophieClient interface {
+	// Search : search an engine, or all engines when engine is "all"
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// SearchStream : send every movie as soon as it is scraped
+	SearchStream(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (Gophie_SearchStreamClient, error)
+	// List : list the recent uploads on an engine
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// Engines : describe the available engines
+	Engines(ctx context.Context, in *EnginesRequest, opts ...grpc.CallOption) (*EnginesResponse, error)
+}
+
+type gophieClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGophieClient(cc grpc.ClientConnInterface) GophieClient {
+	return &gophieClient{cc}
+}
+
+func (c *gophieClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, "/gophie.v1.Gophie/Search", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophieClient) SearchStream(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (Gophie_SearchStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Gophie_ServiceDesc.Streams[0], "/gophie.v1.Gophie/SearchStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gophieSearchStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Gophie_SearchStreamClient interface {
+	Recv() (*Movie, error)
+	grpc.ClientStream
+}
+
+type gophieSearchStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *gophieSearchStreamClient) Recv() (*Movie, error) {
+	m := new(Movie)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gophieClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, "/gophie.v1.Gophie/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophieClient) Engines(ctx context.Context, in *EnginesRequest, opts ...grpc.CallOption) (*EnginesResponse, error) {
+	out := new(EnginesResponse)
+	err := c.cc.Invoke(ctx, "/gophie.v1.Gophie/Engines", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GophieServer is the server API for Gophie service.
+// All implementations must embed UnimplementedGophieServer
+// for forward compatibility
+type GophieServer interface {
+	// Search : search an engine, or all engines when engine is "all"
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// SearchStream : send every movie as soon as it is scraped
+	SearchStream(*SearchRequest, Gophie_SearchStreamServer) error
+	// List : list the recent uploads on an engine
+	List(context.Context, *ListRequest) (*SearchResponse, error)
+	// Engines : describe the available engines
+	Engines(context.Context, *EnginesRequest) (*EnginesResponse, error)
+	mustEmbedUnimplementedGophieServer()
+}
+
+// UnimplementedGophieServer must be embedded to have forward compatible implementations.
+type UnimplementedGophieServer struct {
+}
+
+func (UnimplementedGophieServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedGophieServer) SearchStream(*SearchRequest, Gophie_SearchStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method SearchStream not implemented")
+}
+func (UnimplementedGophieServer) List(context.Context, *ListRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedGophieServer) Engines(context.Context, *EnginesRequest) (*EnginesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Engines not implemented")
+}
+func (UnimplementedGophieServer) mustEmbedUnimplementedGophieServer() {}
+
+// UnsafeGophieServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GophieServer will
+// result in compilation errors.
+type UnsafeGophieServer interface {
+	mustEmbedUnimplementedGophieServer()
+}
+
+func RegisterGophieServer(s grpc.ServiceRegistrar, srv GophieServer) {
+	s.RegisterService(&Gophie_ServiceDesc, srv)
+}
+
+func _Gophie_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophieServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gophie.v1.Gophie/Search",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophieServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophie_SearchStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SearchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GophieServer).SearchStream(m, &gophieSearchStreamServer{stream})
+}
+
+type Gophie_SearchStreamServer interface {
+	Send(*Movie) error
+	grpc.ServerStream
+}
+
+type gophieSearchStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *gophieSearchStreamServer) Send(m *Movie) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Gophie_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophieServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gophie.v1.Gophie/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophieServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophie_Engines_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnginesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophieServer).Engines(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gophie.v1.Gophie/Engines",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophieServer).Engines(ctx, req.(*EnginesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Gophie_ServiceDesc is the grpc.ServiceDesc for Gophie service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Gophie_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gophie.v1.Gophie",
+	HandlerType: (*GophieServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Search",
+			Handler:    _Gophie_Search_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _Gophie_List_Handler,
+		},
+		{
+			MethodName: "Engines",
+			Handler:    _Gophie_Engines_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SearchStream",
+			Handler:       _Gophie_SearchStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gophie.proto",
+}
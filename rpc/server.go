@@ -0,0 +1,253 @@
+// Package rpc : gRPC API of gophie, see gophie.proto for the service definition
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative gophie.proto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-phie/gophie/engine"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultEngine : engine used when a request does not name one, the same as the HTTP API
+const defaultEngine = "fzmovies"
+
+// EngineGetter : returns the engine registered as name, e.g engine.GetEngine
+type EngineGetter func(name string) (engine.Engine, error)
+
+// Server : implementation of the Gophie gRPC service
+type Server struct {
+	UnimplementedGophieServer
+	getEngine EngineGetter
+}
+
+// NewServer : Gophie service looking up engines with getEngine
+func NewServer(getEngine EngineGetter) *Server {
+	return &Server{getEngine: getEngine}
+}
+
+// Search : search an engine, or all engines when engine is "all"
+func (s *Server) Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
+	if req.Query == "" {
+		return nil, status.Error(codes.InvalidArgument, "query must be set")
+	}
+	var (
+		result engine.SearchResult
+		err    error
+	)
+	if strings.ToLower(req.Engine) == "all" {
+		result, err = engine.SearchAll(ctx, req.Query)
+	} else {
+		var e engine.Engine
+		if e, err = s.engine(req.Engine); err != nil {
+			return nil, err
+		}
+		result, err = engine.Search(ctx, e, req.Query, strconv.Itoa(page(req.Page)))
+	}
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return resultToProto(result), nil
+}
+
+// SearchStream : send every movie as soon as it is scraped
+func (s *Server) SearchStream(req *SearchRequest, stream Gophie_SearchStreamServer) error {
+	if req.Query == "" {
+		return status.Error(codes.InvalidArgument, "query must be set")
+	}
+	ctx := stream.Context()
+	names := []string{req.Engine}
+	if strings.ToLower(req.Engine) == "all" {
+		names = names[:0]
+		for name := range engine.GetEngines() {
+			names = append(names, name)
+		}
+	}
+
+	engines := make(map[string]engine.Engine, len(names))
+	for _, name := range names {
+		e, err := s.engine(name)
+		if err != nil {
+			return err
+		}
+		engines[name] = e
+	}
+
+	var sendErr error
+	err := engine.StreamSearchAll(ctx, engines, func(movie engine.Movie) {
+		if sendErr == nil {
+			sendErr = stream.Send(movieToProto(movie))
+		}
+	}, req.Query, strconv.Itoa(page(req.Page)))
+	if sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		return statusFromError(err)
+	}
+	return nil
+}
+
+// List : list the recent uploads on an engine
+func (s *Server) List(ctx context.Context, req *ListRequest) (*SearchResponse, error) {
+	e, err := s.engine(req.Engine)
+	if err != nil {
+		return nil, err
+	}
+	result, err := e.List(ctx, page(req.Page))
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return resultToProto(result), nil
+}
+
+// Engines : describe the available engines
+func (s *Server) Engines(ctx context.Context, req *EnginesRequest) (*EnginesResponse, error) {
+	engines := engine.GetEngines()
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	response := &EnginesResponse{}
+	for _, name := range names {
+		response.Engines = append(response.Engines, engineToProto(name, engines[name]))
+	}
+	return response, nil
+}
+
+func (s *Server) engine(name string) (engine.Engine, error) {
+	if name == "" {
+		name = defaultEngine
+	}
+	e, err := s.getEngine(name)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return e, nil
+}
+
+func page(p int32) int {
+	if p < 1 {
+		return 1
+	}
+	return int(p)
+}
+
+// statusFromError : gRPC status matching an engine error
+func statusFromError(err error) error {
+	switch {
+	case errors.Is(err, engine.ErrEngineUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, engine.ErrInvalidQuery):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+func resultToProto(result engine.SearchResult) *SearchResponse {
+	response := &SearchResponse{Query: result.Query}
+	for _, movie := range result.Movies {
+		response.Movies = append(response.Movies, movieToProto(movie))
+	}
+	return response
+}
+
+func movieToProto(m engine.Movie) *Movie {
+	movie := &Movie{
+		Index:          int32(m.Index),
+		Title:          m.Title,
+		CoverPhotoLink: m.CoverPhotoLink,
+		Description:    m.Description,
+		Size:           m.Size,
+		SizeBytes:      m.SizeBytes,
+		DownloadLink:   linkString(m.DownloadLink),
+		Year:           int32(m.Year),
+		IsSeries:       m.IsSeries,
+		SDownloadLink:  linkStrings(m.SDownloadLink),
+		Quality:        m.Quality.String(),
+		Category:       m.Category,
+		Cast:           m.Cast,
+		UploadDate:     m.UploadDate,
+		Source:         m.Source,
+		SubtitleLink:   linkString(m.SubtitleLink),
+		SubtitleLinks:  linkStrings(m.SubtitleLinks),
+		ImdbLink:       m.ImdbLink,
+		Tags:           m.Tags,
+		CanonicalTitle: m.CanonicalTitle,
+		Genres:         m.Genres,
+		Rating:         m.Rating,
+		Runtime:        int32(m.Runtime),
+		PosterLink:     m.PosterLink,
+		MagnetLink:     m.MagnetLink,
+	}
+	for _, s := range m.Seasons {
+		season := &Season{Number: int32(s.Number)}
+		for _, e := range s.Episodes {
+			season.Episodes = append(season.Episodes, &Episode{
+				Number:       int32(e.Number),
+				Title:        e.Title,
+				Size:         e.Size,
+				DownloadLink: linkString(e.DownloadLink),
+				SubtitleLink: linkString(e.SubtitleLink),
+			})
+		}
+		movie.Seasons = append(movie.Seasons, season)
+	}
+	return movie
+}
+
+// engineToProto : describe an engine, built-in engines are described by their Props
+func engineToProto(key string, e engine.Engine) *EngineInfo {
+	info := &EngineInfo{Key: key, Name: e.String()}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return info
+	}
+	var props struct {
+		Name, Description, BaseURL, SearchURL, ListURL string
+	}
+	if err = json.Unmarshal(b, &props); err != nil {
+		return info
+	}
+	if props.Name != "" {
+		info.Name = props.Name
+	}
+	info.Description = props.Description
+	info.BaseUrl = props.BaseURL
+	info.SearchUrl = props.SearchURL
+	info.ListUrl = props.ListURL
+	return info
+}
+
+func linkString(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
+
+func linkStrings(links map[string]*url.URL) map[string]string {
+	if len(links) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(links))
+	for key, val := range links {
+		m[key] = linkString(val)
+	}
+	return m
+}
@@ -0,0 +1,148 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type stubEngine struct{}
+
+func (stubEngine) Search(ctx context.Context, param ...string) (engine.SearchResult, error) {
+	link, _ := url.Parse("https://a.example/" + param[0] + ".mp4")
+	return engine.SearchResult{Query: param[0], Movies: []engine.Movie{
+		{Title: param[0], DownloadLink: link, Source: "Stub"},
+	}}, nil
+}
+func (stubEngine) List(ctx context.Context, page int) (engine.SearchResult, error) {
+	return engine.SearchResult{}, engine.ErrEngineUnavailable
+}
+func (stubEngine) Capabilities() engine.Capabilities { return engine.Capabilities{} }
+func (stubEngine) String() string                    { return "Stub" }
+
+func getStubEngine(name string) (engine.Engine, error) {
+	if name != "stub" {
+		return nil, fmt.Errorf("Invalid engine %s", name)
+	}
+	return stubEngine{}, nil
+}
+
+func dial(t *testing.T, token string) GophieClient {
+	keys := map[string]bool{}
+	if token != "" {
+		keys[token] = true
+	}
+	return dialServer(t, NewGRPCServer(getStubEngine, keys, nil))
+}
+
+// dialServer : client of s served over an in-memory connection
+func dialServer(t *testing.T, s *grpc.Server) GophieClient {
+	lis := bufconn.Listen(1 << 20)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewGophieClient(conn)
+}
+
+func TestSearch(t *testing.T) {
+	client := dial(t, "")
+	ctx := context.Background()
+
+	response, err := client.Search(ctx, &SearchRequest{Engine: "stub", Query: "jumanji"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Movies) != 1 || response.Movies[0].DownloadLink != "https://a.example/jumanji.mp4" {
+		t.Errorf("Unexpected response %v", response)
+	}
+
+	stream, err := client.SearchStream(ctx, &SearchRequest{Engine: "stub", Query: "jumanji"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed []*Movie
+	for {
+		movie, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		streamed = append(streamed, movie)
+	}
+	if len(streamed) != 1 || streamed[0].Title != "jumanji" {
+		t.Errorf("Unexpected streamed movies %v", streamed)
+	}
+
+	if _, err = client.Search(ctx, &SearchRequest{Engine: "unknown", Query: "jumanji"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an unknown engine, got %v", err)
+	}
+	if _, err = client.List(ctx, &ListRequest{Engine: "stub"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable for a failing engine, got %v", err)
+	}
+}
+
+func TestEngines(t *testing.T) {
+	response, err := dial(t, "").Engines(context.Background(), &EnginesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range response.Engines {
+		if info.Key == "fzmovies" && info.Name == "FzMovies" && info.BaseUrl != "" {
+			return
+		}
+	}
+	t.Errorf("fzmovies missing from engines %v", response.Engines)
+}
+
+func TestAuthentication(t *testing.T) {
+	client := dial(t, "secret")
+	req := &SearchRequest{Engine: "stub", Query: "jumanji"}
+
+	if _, err := client.Search(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without a token, got %v", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	if _, err := client.Search(ctx, req); err != nil {
+		t.Errorf("Expected search with the token to succeed, got %v", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	var clients []string
+	allow := func(client string) bool {
+		clients = append(clients, client)
+		return len(clients) < 3
+	}
+	client := dialServer(t, NewGRPCServer(getStubEngine, map[string]bool{"secret": true}, allow))
+	req := &SearchRequest{Engine: "stub", Query: "jumanji"}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	for i, code := range []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted} {
+		if _, err := client.Search(ctx, req); status.Code(err) != code {
+			t.Errorf("Call %d: expected %s, got %v", i, code, err)
+		}
+	}
+	// calls are limited by their key, those without a valid one are not let through
+	if _, err := client.Search(context.Background(), req); status.Code(err) != codes.Unauthenticated || len(clients) != 3 || clients[0] != "key:secret" {
+		t.Errorf("Expected calls limited by key, got %v limiting %v", err, clients)
+	}
+}
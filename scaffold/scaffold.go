@@ -0,0 +1,140 @@
+// Package scaffold writes the skeleton of a new engine into a checkout of
+// gophie, with the Props, collector callbacks and Search and List methods the
+// engines share and a test of it replaying fixtures of the site, so that a site
+// is supported by filling in the selectors of its pages
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// ErrExists : the files of the engine are already in the checkout
+var ErrExists = errors.New("engine files already exist")
+
+// nameRe : the names engines can have, which name their Go type
+var nameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+// Engine : The engine generated
+type Engine struct {
+	Name       string // shown to users, e.g MovieHub
+	BaseURL    string
+	SearchPath string // relative to BaseURL, "/" when empty
+	ListPath   string // relative to BaseURL, "/" when empty
+	QueryParam string // query parameter of searches, "s" when empty
+}
+
+// Validate : check that e can be generated, with a name a Go type can have and
+// an http or https base URL
+func (e *Engine) Validate() error {
+	if !nameRe.MatchString(e.Name) {
+		return fmt.Errorf("invalid engine name %q, use letters and digits starting with a letter", e.Name)
+	}
+	u, err := url.Parse(e.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid base URL %q, use an http or https URL", e.BaseURL)
+	}
+	return nil
+}
+
+// data : the values of the templates
+func (e Engine) data() map[string]string {
+	data := map[string]string{
+		"Name":       e.Name,
+		"BaseURL":    strings.TrimSuffix(e.BaseURL, "/"),
+		"SearchPath": e.SearchPath,
+		"ListPath":   e.ListPath,
+		"QueryParam": e.QueryParam,
+		"Key":        strings.ToLower(e.Name),
+	}
+	for key, value := range map[string]string{"SearchPath": "/", "ListPath": "/", "QueryParam": "s"} {
+		if data[key] == "" {
+			data[key] = value
+		}
+	}
+	runes := []rune(e.Name)
+	data["Type"] = e.Type()
+	data["Var"] = string(unicode.ToLower(runes[0])) + string(runes[1:]) + "Engine"
+	return data
+}
+
+// Type : the Go type of the engine, its name starting with a capital
+func (e Engine) Type() string {
+	runes := []rune(e.Name)
+	if len(runes) == 0 {
+		return ""
+	}
+	return string(unicode.ToUpper(runes[0])) + string(runes[1:])
+}
+
+// Files : The paths of the files of e relative to the root of the checkout
+func (e Engine) Files() []string {
+	key := strings.ToLower(e.Name)
+	return []string{filepath.Join("engine", key+".go"), filepath.Join("enginetest", key+"_test.go")}
+}
+
+// Render : The formatted source of the files of e, by their paths
+func (e Engine) Render() (map[string][]byte, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	for i, name := range []string{"engine.go.tmpl", "engine_test.go.tmpl"} {
+		t, err := template.ParseFS(templates, "templates/"+name)
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		if err = t.Execute(&b, e.data()); err != nil {
+			return nil, err
+		}
+		src, err := format.Source(b.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("generated %s does not parse: %v", name, err)
+		}
+		files[e.Files()[i]] = src
+	}
+	return files, nil
+}
+
+// Write : Write the files of e into the checkout at root, not replacing those
+// already there unless overwrite is set. Returns the paths written
+func (e Engine) Write(root string, overwrite bool) ([]string, error) {
+	for _, dir := range []string{"engine", "enginetest"} {
+		if info, err := os.Stat(filepath.Join(root, dir)); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a checkout of gophie, it has no %s directory", root, dir)
+		}
+	}
+	files, err := e.Render()
+	if err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, file := range e.Files() {
+		path := filepath.Join(root, file)
+		if _, err := os.Stat(path); err == nil && !overwrite {
+			return nil, fmt.Errorf("%w: %s", ErrExists, path)
+		}
+		written = append(written, path)
+	}
+	for i, file := range e.Files() {
+		if err := ioutil.WriteFile(written[i], files[file], 0644); err != nil {
+			return written[:i], err
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,62 @@
+package scaffold
+
+import (
+	"errors"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	e := Engine{Name: "movieHub", BaseURL: "https://moviehub.example/", QueryParam: "q"}
+	files, err := e.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(files[filepath.Join("engine", "moviehub.go")])
+	for _, want := range []string{
+		"type MovieHub struct", `RegisterEngine("moviehub"`, "func NewMovieHubEngine() *MovieHub",
+		`url.Parse("https://moviehub.example")`, `q.Set("q", param[0])`, "var _ ScrapingEngine = (*MovieHub)(nil)",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("Expected the engine to have %s, got\n%s", want, src)
+		}
+	}
+	for path, src := range files {
+		if _, err := parser.ParseFile(token.NewFileSet(), path, src, 0); err != nil {
+			t.Errorf("Expected %s to parse: %v", path, err)
+		}
+	}
+	if !strings.Contains(string(files[filepath.Join("enginetest", "moviehub_test.go")]), "func TestMovieHubList(t *testing.T)") {
+		t.Error("Expected a test of the list of the engine")
+	}
+
+	for _, invalid := range []Engine{{Name: "1337x", BaseURL: "https://1337x.example"}, {Name: "Movie Hub", BaseURL: "https://a.example"}, {Name: "Hub", BaseURL: "ftp://a.example"}} {
+		if _, err := invalid.Render(); err == nil {
+			t.Errorf("Expected %+v to be invalid", invalid)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	root := t.TempDir()
+	e := Engine{Name: "MovieHub", BaseURL: "https://moviehub.example"}
+	if _, err := e.Write(root, false); err == nil {
+		t.Error("Expected a directory without engines to be refused")
+	}
+	os.Mkdir(filepath.Join(root, "engine"), 0755)
+	os.Mkdir(filepath.Join(root, "enginetest"), 0755)
+	written, err := e.Write(root, false)
+	if err != nil || len(written) != 2 {
+		t.Fatalf("Expected the engine and its test written, got %v %v", written, err)
+	}
+	if _, err = e.Write(root, false); !errors.Is(err, ErrExists) {
+		t.Errorf("Expected the files kept, got %v", err)
+	}
+	if _, err = e.Write(root, true); err != nil {
+		t.Errorf("Expected the files replaced, got %v", err)
+	}
+}
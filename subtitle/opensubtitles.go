@@ -0,0 +1,148 @@
+package subtitle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const openSubtitlesURL = "https://api.opensubtitles.com/api/v1"
+
+// ErrNoAPIKey : the provider cannot be used without an API key
+var ErrNoAPIKey = errors.New("An OpenSubtitles API key is required")
+
+// OpenSubtitles : Provider backed by the OpenSubtitles REST API (https://opensubtitles.stoplight.io)
+type OpenSubtitles struct {
+	APIKey  string
+	BaseURL string // Defaults to the public OpenSubtitles API
+	Client  *http.Client
+}
+
+// NewOpenSubtitles : OpenSubtitles provider using apiKey
+func NewOpenSubtitles(apiKey string) *OpenSubtitles {
+	return &OpenSubtitles{APIKey: apiKey, BaseURL: openSubtitlesURL, Client: http.DefaultClient}
+}
+
+type openSubtitlesSearch struct {
+	Data []struct {
+		Attributes struct {
+			Language      string
+			Release       string
+			DownloadCount int `json:"download_count"`
+			Files         []struct {
+				FileID   int    `json:"file_id"`
+				FileName string `json:"file_name"`
+			}
+		}
+	}
+}
+
+// Search : Search OpenSubtitles for subtitles matching q
+func (o *OpenSubtitles) Search(ctx context.Context, q Query) ([]Subtitle, error) {
+	params := url.Values{}
+	params.Set("query", strings.ToLower(q.Title))
+	if q.Year > 0 {
+		params.Set("year", strconv.Itoa(q.Year))
+	}
+	if q.Language != "" {
+		params.Set("languages", strings.ToLower(q.Language))
+	}
+	if q.IsSeries {
+		params.Set("type", "episode")
+	} else {
+		params.Set("type", "movie")
+	}
+
+	req, err := o.request(ctx, http.MethodGet, "/subtitles?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var search openSubtitlesSearch
+	if err = o.do(req, &search); err != nil {
+		return nil, err
+	}
+
+	var subtitles []Subtitle
+	for _, data := range search.Data {
+		attrs := data.Attributes
+		if len(attrs.Files) == 0 {
+			continue
+		}
+		release := attrs.Release
+		if release == "" {
+			release = attrs.Files[0].FileName
+		}
+		subtitles = append(subtitles, Subtitle{
+			ID:        strconv.Itoa(attrs.Files[0].FileID),
+			Release:   release,
+			Language:  attrs.Language,
+			Downloads: attrs.DownloadCount,
+			Source:    "OpenSubtitles",
+		})
+	}
+	if len(subtitles) == 0 {
+		return nil, fmt.Errorf("%w for %s", ErrNotFound, q.Title)
+	}
+	sort.SliceStable(subtitles, func(i, j int) bool {
+		return subtitles[i].Downloads > subtitles[j].Downloads
+	})
+	return subtitles, nil
+}
+
+// Link : Request a temporary download link for the subtitle file
+func (o *OpenSubtitles) Link(ctx context.Context, sub Subtitle) (*url.URL, error) {
+	fileID, err := strconv.Atoi(sub.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenSubtitles file id %q", sub.ID)
+	}
+	body, err := json.Marshal(map[string]int{"file_id": fileID})
+	if err != nil {
+		return nil, err
+	}
+	req, err := o.request(ctx, http.MethodPost, "/download", body)
+	if err != nil {
+		return nil, err
+	}
+	var download struct {
+		Link string
+	}
+	if err = o.do(req, &download); err != nil {
+		return nil, err
+	}
+	return url.Parse(download.Link)
+}
+
+func (o *OpenSubtitles) request(ctx context.Context, method, endpoint string, body []byte) (*http.Request, error) {
+	if o.APIKey == "" {
+		return nil, ErrNoAPIKey
+	}
+	req, err := http.NewRequestWithContext(ctx, method, o.BaseURL+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Api-Key", o.APIKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	// OpenSubtitles rejects requests without an identifying user agent
+	req.Header.Set("User-Agent", "gophie")
+	return req, nil
+}
+
+func (o *OpenSubtitles) do(req *http.Request, v interface{}) error {
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OpenSubtitles returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
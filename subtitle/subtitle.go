@@ -0,0 +1,97 @@
+package subtitle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-phie/gophie/downloader"
+	"github.com/go-phie/gophie/engine"
+	"github.com/go-phie/gophie/metadata"
+)
+
+// ErrNotFound : no subtitles matched the query
+var ErrNotFound = errors.New("No subtitles found")
+
+// Subtitle : a subtitle file found by a Provider
+type Subtitle struct {
+	ID        string // Provider specific identifier used to resolve the download link
+	Release   string // Release the subtitle was made for e.g The.Matrix.1999.720p.BluRay
+	Language  string
+	Downloads int
+	Source    string
+}
+
+// String : short description used when picking a subtitle
+func (s Subtitle) String() string {
+	return fmt.Sprintf("[%s] %s (%d downloads)", s.Language, s.Release, s.Downloads)
+}
+
+// Query : what to search subtitles for
+type Query struct {
+	Title    string
+	Year     int
+	Language string // ISO 639-1 code e.g en
+	IsSeries bool
+}
+
+// Provider : a source of subtitles such as OpenSubtitles
+type Provider interface {
+	// Search : find subtitles matching the query, most downloaded first
+	Search(ctx context.Context, q Query) ([]Subtitle, error)
+	// Link : resolve the link of the .srt file of a subtitle
+	Link(ctx context.Context, sub Subtitle) (*url.URL, error)
+}
+
+// QueryForMovie : build the subtitle Query of a scraped movie
+func QueryForMovie(movie engine.Movie, language string) Query {
+	title, year := metadata.CleanTitle(movie.Title)
+	if movie.CanonicalTitle != "" {
+		title = movie.CanonicalTitle
+	}
+	if movie.Year != 0 {
+		year = movie.Year
+	}
+	return Query{Title: title, Year: year, Language: language, IsSeries: movie.IsSeries}
+}
+
+// Save : download sub into dir as <name>.<language>.srt and return the path it was saved to
+func Save(ctx context.Context, p Provider, sub Subtitle, dir, name string) (string, error) {
+	link, err := p.Link(ctx, sub)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("subtitle download returned %s", resp.Status)
+	}
+
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	fileName := downloader.SanitizeFilename(name, downloader.FilenameOptions{})
+	dest := path.Join(dir, fileName+"."+strings.ToLower(sub.Language)+".srt")
+	file, err := os.Create(dest)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err = io.Copy(file, resp.Body); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
@@ -0,0 +1,68 @@
+package subtitle
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+func TestOpenSubtitles(t *testing.T) {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/subtitles", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Api-Key") != "key" {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if q := r.URL.Query(); q.Get("query") != "the matrix" || q.Get("year") != "1999" || q.Get("languages") != "en" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{"data":[
+			{"attributes":{"language":"en","release":"The.Matrix.720p","download_count":10,"files":[{"file_id":1}]}},
+			{"attributes":{"language":"en","release":"The.Matrix.1080p","download_count":20,"files":[{"file_id":2}]}}]}`)
+	})
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"link":"%s/file.srt"}`, server.URL)
+	})
+	mux.HandleFunc("/file.srt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "1\n00:00:01,000 --> 00:00:02,000\nWake up, Neo\n")
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	provider := NewOpenSubtitles("key")
+	provider.BaseURL = server.URL
+	query := QueryForMovie(engine.Movie{Title: "The Matrix (1999)"}, "en")
+	subtitles, err := provider.Search(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subtitles) != 2 || subtitles[0].ID != "2" {
+		t.Fatalf("expected most downloaded subtitle first, got %v", subtitles)
+	}
+
+	dir, err := ioutil.TempDir("", "gophie-subtitle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest, err := Save(context.Background(), provider, subtitles[0], dir, "The Matrix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dest != path.Join(dir, "The Matrix.en.srt") {
+		t.Errorf("unexpected subtitle path %s", dest)
+	}
+	if b, _ := ioutil.ReadFile(dest); len(b) == 0 {
+		t.Error("subtitle file is empty")
+	}
+
+	if _, err = NewOpenSubtitles("").Search(context.Background(), query); err != ErrNoAPIKey {
+		t.Errorf("expected ErrNoAPIKey, got %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// decoders of the formats of cover photos
+	_ "image/gif"
+	_ "image/png"
+)
+
+// Sizes : widths of the thumbnails served, images are never enlarged
+var Sizes = map[string]int{
+	"small":  154,
+	"medium": 342,
+	"large":  500,
+}
+
+// DefaultSize : size of the thumbnails when none is asked for
+const DefaultSize = "medium"
+
+// maxImageBytes : largest image fetched
+const maxImageBytes = 10 << 20
+
+var (
+	// ErrNotAllowed : the image is not on a host of the proxy
+	ErrNotAllowed = errors.New("Images can only be fetched from the sites of the engines")
+	// ErrInvalidSize : the size is not one of Sizes
+	ErrInvalidSize = errors.New("size must be small, medium or large")
+)
+
+// Proxy : Fetches cover photos from the hosts it allows, resizes them to thumbnails
+// and caches them in Dir, so that frontends get images which load fast and are
+// not refused for hotlinking
+type Proxy struct {
+	Dir    string   // where thumbnails are cached, nothing is cached when empty
+	Hosts  []string // hosts images are fetched from, subdomains included
+	Client *http.Client
+}
+
+// allowed : whether u is an http(s) URL on one of the hosts of the proxy
+func (p *Proxy) allowed(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range p.Hosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// path : the file the thumbnail of link at width is cached in
+func (p *Proxy) path(link string, width int) string {
+	sum := sha256.Sum256([]byte(link))
+	return filepath.Join(p.Dir, fmt.Sprintf("%s-%d.jpg", hex.EncodeToString(sum[:]), width))
+}
+
+// Thumbnail : The JPEG thumbnail of the image at link, at most width pixels wide
+func (p *Proxy) Thumbnail(ctx context.Context, link string, width int) ([]byte, error) {
+	u, err := url.Parse(link)
+	if err != nil || !p.allowed(u) {
+		return nil, ErrNotAllowed
+	}
+	if p.Dir != "" {
+		if b, err := ioutil.ReadFile(p.path(link, width)); err == nil {
+			return b, nil
+		}
+	}
+	img, err := p.fetch(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = jpeg.Encode(&buf, Resize(img, width), &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	if p.Dir != "" {
+		p.store(p.path(link, width), buf.Bytes())
+	}
+	return buf.Bytes(), nil
+}
+
+// fetch : download and decode the image at u
+func (p *Proxy) fetch(ctx context.Context, u *url.URL) (image.Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// sites refusing hotlinks serve images to their own pages
+	req.Header.Set("Referer", u.Scheme+"://"+u.Host+"/")
+	req.Header.Set("Accept", "image/jpeg,image/png,image/gif,image/*;q=0.8")
+	client := http.Client{}
+	if p.Client != nil {
+		client = *p.Client
+	}
+	// redirects must stay on the hosts of the proxy too
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		if !p.allowed(req.URL) {
+			return ErrNotAllowed
+		}
+		return nil
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Image responded with %s", resp.Status)
+	}
+	img, _, err := image.Decode(io.LimitReader(resp.Body, maxImageBytes))
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode image: %w", err)
+	}
+	return img, nil
+}
+
+// store : cache b in file, written to a temporary file first so that a
+// thumbnail being written is never served
+func (p *Proxy) store(file string, b []byte) {
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".thumbnail-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// ServeHTTP : Serve the thumbnail of ?url= at ?size=
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	link := r.URL.Query().Get("url")
+	if link == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	size := r.URL.Query().Get("size")
+	if size == "" {
+		size = DefaultSize
+	}
+	width, ok := Sizes[size]
+	if !ok {
+		http.Error(w, ErrInvalidSize.Error(), http.StatusBadRequest)
+		return
+	}
+	b, err := p.Thumbnail(r.Context(), link, width)
+	switch {
+	case errors.Is(err, ErrNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "public, max-age=604800")
+	w.Write(b)
+}
+
+// Resize : img scaled down to width, keeping its aspect ratio. Every pixel is the
+// average of the pixels it covers
+func Resize(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	if width <= 0 || b.Dx() <= width {
+		return img
+	}
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/height, b.Min.Y+(y+1)*b.Dy()/height
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/width, b.Min.X+(x+1)*b.Dx()/width
+			if x1 == x0 {
+				x1++
+			}
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,93 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestProxy(t *testing.T) {
+	cover := image.NewRGBA(image.Rect(0, 0, 800, 1200))
+	for y := 0; y < 1200; y++ {
+		for x := 0; x < 800; x++ {
+			cover.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var fetches int
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://films.example/cover.png", http.StatusFound)
+			return
+		}
+		// the cover refuses hotlinks
+		if r.Referer() != "http://"+r.Host+"/" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fetches++
+		png.Encode(w, cover)
+	}))
+	defer site.Close()
+	host, _ := url.Parse(site.URL)
+
+	proxy := &Proxy{Dir: t.TempDir(), Hosts: []string{host.Hostname()}}
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/image?"+query, nil))
+		return w
+	}
+
+	w := get("size=small&url=" + url.QueryEscape(site.URL+"/cover.png"))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("Expected a thumbnail, got %d %s", w.Code, w.Body)
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := thumb.Bounds().Size(); size.X != 154 || size.Y != 231 {
+		t.Errorf("Expected a 154x231 thumbnail, got %v", size)
+	}
+	if r, _, _, _ := thumb.At(70, 100).RGBA(); r>>8 < 190 {
+		t.Errorf("Expected the colour of the cover to be kept, got %d", r>>8)
+	}
+
+	// thumbnails are served from the cache
+	w = get("size=small&url=" + url.QueryEscape(site.URL+"/cover.png"))
+	if w.Code != http.StatusOK || fetches != 1 {
+		t.Errorf("Expected the cached thumbnail, got %d after %d fetches", w.Code, fetches)
+	}
+	files, _ := ioutil.ReadDir(proxy.Dir)
+	if len(files) != 1 {
+		t.Errorf("Expected a cached thumbnail, got %d files", len(files))
+	}
+
+	for query, code := range map[string]int{
+		"url=" + url.QueryEscape("http://films.example/cover.png"): http.StatusForbidden,
+		"url=" + url.QueryEscape(site.URL+"/redirect"):             http.StatusForbidden,
+		"url=" + url.QueryEscape("file:///etc/passwd"):             http.StatusForbidden,
+		"size=huge&url=" + url.QueryEscape(site.URL+"/cover.png"):  http.StatusBadRequest,
+		"": http.StatusBadRequest,
+	} {
+		if w := get(query); w.Code != code {
+			t.Errorf("Expected %d for %q, got %d", code, query, w.Code)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	small := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	if Resize(small, 342) != image.Image(small) {
+		t.Error("Expected small images not to be enlarged")
+	}
+	if size := Resize(image.NewGray(image.Rect(0, 0, 1000, 10)), 154).Bounds().Size(); size.X != 154 || size.Y != 1 {
+		t.Errorf("Unexpected size %v", size)
+	}
+}
@@ -0,0 +1,279 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrChallenge : the site answered with an anti-bot challenge page which could not be solved
+var ErrChallenge = errors.New("blocked by an anti-bot challenge")
+
+// maxChallengeBody : bytes of a response read to tell whether it is a challenge page
+const maxChallengeBody = 256 << 10
+
+var (
+	// challengeMarkers : text found in the challenge pages of Cloudflare and similar
+	// services, which are answered with an error status
+	challengeMarkers = []string{
+		"cf-chl-", "cf_chl_", "challenge-platform", "checking your browser", "<title>just a moment",
+		"attention required! | cloudflare", "ddos-guard",
+	}
+	// pageChallengeMarkers : text only found in challenge pages answered with 200 OK,
+	// the scripts of challenge-platform are also added to regular pages
+	pageChallengeMarkers = []string{"cf_chl_opt", "<title>just a moment", "checking your browser before accessing"}
+)
+
+// BrowserConfig : the headless browser solving challenge pages
+type BrowserConfig struct {
+	Enabled   bool          // solve challenges with a browser, otherwise they fail with ErrChallenge
+	RemoteURL string        `mapstructure:"remote-url"` // DevTools URL of a running browser e.g http://localhost:9222, a local Chrome is started when empty
+	ExecPath  string        `mapstructure:"exec-path"`  // Chrome executable, found on the PATH when empty
+	Timeout   time.Duration // how long a challenge has to be solved, defaults to 1m
+}
+
+// IsChallenge : Whether resp with body is the challenge page of an anti-bot service
+// rather than the page asked for
+func IsChallenge(resp *http.Response, body []byte) bool {
+	if resp == nil {
+		return false
+	}
+	if resp.Header.Get("Cf-Mitigated") == "challenge" {
+		return true
+	}
+	markers := challengeMarkers
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusServiceUnavailable, http.StatusTooManyRequests:
+	case http.StatusOK:
+		markers = pageChallengeMarkers
+	default:
+		return false
+	}
+	page := strings.ToLower(string(body))
+	for _, marker := range markers {
+		if strings.Contains(page, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Solver : Solves the challenge of a page, returning the cookies granting access
+// to the site and the User-Agent they were granted to
+type Solver interface {
+	Solve(ctx context.Context, pageURL string) (cookies []*http.Cookie, userAgent string, err error)
+}
+
+// CookieStore : A cookie jar kept in a file so that cookies granted by solved
+// challenges are reused in later runs. Cookies are kept per host along with the
+// User-Agent they were granted to
+type CookieStore struct {
+	mu    sync.Mutex
+	path  string
+	hosts map[string]*hostCookies
+}
+
+type hostCookies struct {
+	UserAgent string         `json:",omitempty"`
+	Cookies   []*http.Cookie // Name, Value and Expires are kept
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = map[string]*CookieStore{}
+)
+
+// OpenCookieStore : The cookie store kept at path, shared by every transport using
+// it. An empty path keeps the cookies in memory only
+func OpenCookieStore(path string) *CookieStore {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if store, ok := stores[path]; ok && path != "" {
+		return store
+	}
+	store := &CookieStore{path: path, hosts: map[string]*hostCookies{}}
+	if path != "" {
+		if data, err := ioutil.ReadFile(path); err == nil {
+			if err = json.Unmarshal(data, &store.hosts); err != nil {
+				log.Debugf("Could not load cookies from %s: %v", path, err)
+			}
+		}
+		stores[path] = store
+	}
+	return store
+}
+
+// SetCookies : Keep the cookies of u, replacing those of the same name
+func (s *CookieStore) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	s.set(u.Hostname(), cookies, "")
+}
+
+// Cookies : The cookies of u which have not expired
+func (s *CookieStore) Cookies(u *url.URL) []*http.Cookie {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	host := s.hosts[u.Hostname()]
+	if host == nil {
+		return nil
+	}
+	var cookies []*http.Cookie
+	for _, cookie := range host.Cookies {
+		if cookie.Expires.IsZero() || cookie.Expires.After(time.Now()) {
+			cookies = append(cookies, &http.Cookie{Name: cookie.Name, Value: cookie.Value})
+		}
+	}
+	return cookies
+}
+
+// UserAgent : The User-Agent the cookies of host were granted to, empty when any will do
+func (s *CookieStore) UserAgent(host string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cookies := s.hosts[host]; cookies != nil {
+		return cookies.UserAgent
+	}
+	return ""
+}
+
+// set : keep cookies of host, along with the User-Agent they were granted to when set
+func (s *CookieStore) set(host string, cookies []*http.Cookie, userAgent string) {
+	if len(cookies) == 0 && userAgent == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.hosts[host]
+	if kept == nil {
+		kept = &hostCookies{}
+		s.hosts[host] = kept
+	}
+	if userAgent != "" {
+		kept.UserAgent = userAgent
+	}
+	for _, cookie := range cookies {
+		expires := cookie.Expires
+		if cookie.MaxAge > 0 {
+			expires = time.Now().Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+		replaced := false
+		for i, old := range kept.Cookies {
+			if old.Name == cookie.Name {
+				kept.Cookies[i] = &http.Cookie{Name: cookie.Name, Value: cookie.Value, Expires: expires}
+				replaced = true
+			}
+		}
+		if !replaced {
+			kept.Cookies = append(kept.Cookies, &http.Cookie{Name: cookie.Name, Value: cookie.Value, Expires: expires})
+		}
+	}
+	s.save()
+}
+
+// save : write the cookies to the file of the store, s must be locked
+func (s *CookieStore) save() {
+	if s.path == "" {
+		return
+	}
+	data, err := json.Marshal(s.hosts)
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(s.path), os.ModePerm); err == nil {
+			err = ioutil.WriteFile(s.path, data, 0600)
+		}
+	}
+	if err != nil {
+		log.Debugf("Could not save cookies to %s: %v", s.path, err)
+	}
+}
+
+// challengeTransport : keeps the cookies of the sites and solves the challenge
+// pages they answer with, retrying the request with the granted cookies
+type challengeTransport struct {
+	upstream http.RoundTripper
+	store    *CookieStore
+	solver   Solver // nil when challenges are not solved
+	solving  sync.Mutex
+}
+
+func (t *challengeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.send(req)
+	if err != nil {
+		return nil, err
+	}
+	challenged, err := t.challenged(resp)
+	if err != nil || !challenged {
+		return resp, err
+	}
+	resp.Body.Close()
+	if t.solver == nil {
+		return nil, fmt.Errorf("%w: %s, enable the browser in the http config to solve it", ErrChallenge, req.URL.Host)
+	}
+
+	// a single challenge is solved at a time, the other requests then reuse its cookies
+	t.solving.Lock()
+	log.Infof("Solving the challenge of %s", req.URL.Host)
+	cookies, userAgent, err := t.solver.Solve(req.Context(), req.URL.String())
+	if err == nil {
+		t.store.set(req.URL.Hostname(), cookies, userAgent)
+	}
+	t.solving.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrChallenge, req.URL.Host, err)
+	}
+	if resp, err = t.send(req); err != nil {
+		return nil, err
+	}
+	if challenged, err = t.challenged(resp); challenged {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s is still challenged once solved", ErrChallenge, req.URL.Host)
+	}
+	return resp, err
+}
+
+// send : send req with the cookies of its site, keeping those it sets
+func (t *challengeTransport) send(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for _, cookie := range t.store.Cookies(req.URL) {
+		req.AddCookie(cookie)
+	}
+	// cookies granted by a challenge are only valid with the browser's User-Agent
+	if userAgent := t.store.UserAgent(req.URL.Hostname()); userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	resp, err := t.upstream.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.store.SetCookies(req.URL, resp.Cookies())
+	return resp, nil
+}
+
+// challenged : whether resp is a challenge page. Its body is read to tell and is
+// replaced so it can still be read
+func (t *challengeTransport) challenged(resp *http.Response) (bool, error) {
+	if !strings.Contains(resp.Header.Get("Content-Type"), "html") && resp.Header.Get("Cf-Mitigated") == "" {
+		return false, nil
+	}
+	head, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxChallengeBody))
+	if err != nil {
+		resp.Body.Close()
+		return false, err
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	return IsChallenge(resp, head), nil
+}
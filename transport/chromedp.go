@@ -1,150 +1,280 @@
 package transport
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"os"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 	log "github.com/sirupsen/logrus"
 )
 
-// ChromeDpTransport : structure for chromedp instance
-type ChromeDpTransport struct {
-	upstream          http.RoundTripper
-	Ctx               context.Context
-	Cancel            context.CancelFunc
-	RemoteAllocCancel context.CancelFunc
-}
+// defaultSolveTimeout : how long a challenge has to be solved unless configured
+const defaultSolveTimeout = time.Minute
 
-var (
-	baseCtx context.Context
-)
+// ChromeSolver : Solves challenges by loading the page in a headless Chrome until
+// the site lets it through, started for every challenge or reached at RemoteURL
+type ChromeSolver struct {
+	Config BrowserConfig
+	Proxy  string // proxy of the browser, that of the requests
+}
 
-func getDebugURL() string {
-	remoteUrl := fmt.Sprintf("%s/json/version", os.Getenv("GOPHIE_CHROMEDP_URL"))
-	resp, err := http.Get(remoteUrl)
+// remoteDebuggerURL : the websocket URL of the browser served at the DevTools URL
+// of c, which may already be a websocket URL
+func remoteDebuggerURL(ctx context.Context, devtoolsURL string) (string, error) {
+	if strings.HasPrefix(devtoolsURL, "ws") {
+		return devtoolsURL, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(devtoolsURL, "/")+"/json/version", nil)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
-
-	var result map[string]interface{}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		log.Fatal(err)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
 	}
-	return result["webSocketDebuggerUrl"].(string)
+	defer resp.Body.Close()
+	var version struct {
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return "", err
+	}
+	if version.WebSocketDebuggerURL == "" {
+		return "", fmt.Errorf("%s serves no browser", devtoolsURL)
+	}
+	return version.WebSocketDebuggerURL, nil
 }
 
-// NewChromeDpTransport : initialize a new transport
-func NewChromeDpTransport(upstream http.RoundTripper) (*ChromeDpTransport, error) {
-
-	//  devToolWsUrl := getDebugURL()
-	//  // create allocator context for use with creating a browser context later
-	//  allocatorContext, cancel := chromedp.NewRemoteAllocator(context.Background(), devToolWsUrl)
-	//  baseCtx, cancel = chromedp.NewContext(
-	//    allocatorContext,
-	//  )
-	// for local exec
-	baseCtx, cancel := chromedp.NewContext(
-		context.Background(),
-		chromedp.WithLogf(log.Debugf),
-	)
-
-	// start the browser without a timeout
-	if err := chromedp.Run(baseCtx); err != nil {
-		panic(err)
+// newBrowser : a browser context of the configured browser, a local Chrome is
+// started unless the config has the DevTools URL of a running one
+func newBrowser(ctx context.Context, config BrowserConfig, proxy string) (context.Context, context.CancelFunc, error) {
+	var allocCtx context.Context
+	var allocCancel context.CancelFunc
+	if config.RemoteURL != "" {
+		wsURL, err := remoteDebuggerURL(ctx, config.RemoteURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		allocCtx, allocCancel = chromedp.NewRemoteAllocator(ctx, wsURL)
+	} else {
+		opts := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+		// sites challenge browsers announcing they are automated
+		opts = append(opts, chromedp.Flag("enable-automation", false), chromedp.Flag("disable-blink-features", "AutomationControlled"))
+		if config.ExecPath != "" {
+			opts = append(opts, chromedp.ExecPath(config.ExecPath))
+		}
+		if proxy != "" {
+			opts = append(opts, chromedp.ProxyServer(proxy))
+		}
+		allocCtx, allocCancel = chromedp.NewExecAllocator(ctx, opts...)
 	}
-	return &ChromeDpTransport{
-		upstream:          upstream,
-		Ctx:               baseCtx,
-		Cancel:            cancel,
-		RemoteAllocCancel: cancel,
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx, chromedp.WithLogf(log.Debugf))
+	// the browser is started by the first action
+	if err := chromedp.Run(browserCtx); err != nil {
+		browserCancel()
+		allocCancel()
+		return nil, nil, err
+	}
+	return browserCtx, func() {
+		browserCancel()
+		allocCancel()
 	}, nil
 }
 
-type LogAction struct {
-	Message string
-}
-
-func (a LogAction) Do(ctx context.Context) error {
-	log.Debug(a.Message)
-	return nil
-}
+// Solve : Load pageURL until it is no longer a challenge page, returning the
+// cookies of the browser and its User-Agent
+func (s *ChromeSolver) Solve(ctx context.Context, pageURL string) ([]*http.Cookie, string, error) {
+	timeout := s.Config.Timeout
+	if timeout <= 0 {
+		timeout = defaultSolveTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-// RoundTrip: extends the RoundTrip API for usage as a colly transport
-func (t *ChromeDpTransport) RoundTrip(r *http.Request) (*http.Response, error) {
-	var (
-		body string
-		err  error
-	)
+	browserCtx, browserCancel, err := newBrowser(ctx, s.Config, s.Proxy)
+	if err != nil {
+		return nil, "", err
+	}
+	defer browserCancel()
 
-	if r.Header.Get("User-Agent") == "" {
-		r.Header.Set("User-Agent", userAgent)
+	var userAgent string
+	if err := chromedp.Run(browserCtx,
+		chromedp.Navigate(pageURL),
+		chromedp.Evaluate(`navigator.userAgent`, &userAgent),
+	); err != nil {
+		return nil, "", err
+	}
+	// the challenge page reloads into the site once it is solved
+	for {
+		var html string
+		if err := chromedp.Run(browserCtx, chromedp.OuterHTML("html", &html)); err != nil {
+			return nil, "", err
+		}
+		if !IsChallenge(&http.Response{StatusCode: http.StatusOK}, []byte(html)) {
+			break
+		}
+		select {
+		case <-browserCtx.Done():
+			return nil, "", fmt.Errorf("challenge not solved in %s", timeout)
+		case <-time.After(time.Second):
+		}
 	}
 
-	if r.Header.Get("Referer") == "" {
-		r.Header.Set("Referer", r.URL.String())
+	var browserCookies []*network.Cookie
+	if err := chromedp.Run(browserCtx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		browserCookies, err = network.GetAllCookies().Do(ctx)
+		return err
+	})); err != nil {
+		return nil, "", err
 	}
+	host := pageURL
+	if u, err := url.Parse(pageURL); err == nil {
+		host = u.Hostname()
+	}
+	cookies := make([]*http.Cookie, 0, len(browserCookies))
+	for _, c := range browserCookies {
+		// only the cookies of the site, not those of the scripts it loaded
+		domain := strings.TrimPrefix(c.Domain, ".")
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		cookie := &http.Cookie{Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path}
+		if !c.Session && c.Expires > 0 {
+			cookie.Expires = time.Unix(int64(c.Expires), 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	log.Debugf("Solved the challenge of %s with %d cookies", pageURL, len(cookies))
+	return cookies, userAgent, nil
+}
 
-	r.Header.Set("Content-Type", "text/html")
+// ChromeRenderer : Renders pages in a headless Chrome, started for the first page
+// and kept running for the following ones until closed
+type ChromeRenderer struct {
+	Config BrowserConfig
+	Proxy  string // proxy of the browser, that of the requests
 
-	log.Debug("Set Headers for page ", r.URL.String())
+	mu      sync.Mutex
+	browser context.Context
+	cancel  context.CancelFunc
+}
 
-	// Check if CloudFlare blocker exists
-	resp, err := http.Get(r.URL.String())
+// start : the running browser, started if needed
+func (r *ChromeRenderer) start() (context.Context, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.browser != nil && r.browser.Err() == nil {
+		return r.browser, nil
+	}
+	// the browser outlives the requests it renders
+	browser, cancel, err := newBrowser(context.Background(), r.Config, r.Proxy)
 	if err != nil {
-		return &http.Response{}, err
+		return nil, err
 	}
+	r.browser, r.cancel = browser, cancel
+	return browser, nil
+}
 
-	resp.Header.Set("Content-Type", "text/html")
-
-	defer resp.Body.Close()
-
-	respBody, err := ioutil.ReadAll(resp.Body)
+// Render : Load pageURL in a new tab as userAgent and return the page once its
+// scripts stopped changing it, along with the status it was served with
+func (r *ChromeRenderer) Render(ctx context.Context, pageURL, userAgent string) (int, string, error) {
+	browser, err := r.start()
 	if err != nil {
-		return &http.Response{}, err
-	}
-	body = string(respBody)
-
-	r.Header.Set("Content-Type", "text/html")
-
-	log.Debug("Set Headers for page ", r.URL.String())
-
-	// When `wait a moment` exists in the page, assume that's a cloudflare body
-	// and requery using chromeDP driver
-	if strings.Contains(strings.ToLower(string(respBody)), "wait a moment") {
-		log.Debug("Using ChromeDP driver")
-		// reuse baseCtx to create new context to reuse browser
-		ctx, cancel := chromedp.NewContext(baseCtx)
-		defer cancel()
-		if err = chromedp.Run(ctx,
-			chromedp.Navigate(r.URL.String()),
-			LogAction{Message: "Finished navigation"},
-			chromedp.WaitVisible(`#nnj-body, #top`),
-			chromedp.OuterHTML("html", &body),
-		); err != nil {
-			log.Fatal(err)
-			return &http.Response{}, err
+		return 0, "", err
+	}
+	timeout := r.Config.Timeout
+	if timeout <= 0 {
+		timeout = defaultSolveTimeout
+	}
+	tab, cancel := chromedp.NewContext(browser)
+	defer cancel()
+	tab, cancelTimeout := context.WithTimeout(tab, timeout)
+	defer cancelTimeout()
+	// closing the tab when the request is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancelTimeout()
+		case <-done:
+		}
+	}()
+
+	var (
+		statusMu sync.Mutex
+		status   int
+	)
+	chromedp.ListenTarget(tab, func(ev interface{}) {
+		if e, ok := ev.(*network.EventResponseReceived); ok && e.Type == network.ResourceTypeDocument {
+			statusMu.Lock()
+			// the status of the page loaded last, after any redirect
+			status = int(e.Response.Status)
+			statusMu.Unlock()
+		}
+	})
+	actions := []chromedp.Action{network.Enable()}
+	if userAgent != "" {
+		actions = append(actions, emulation.SetUserAgentOverride(userAgent))
+	}
+	var html string
+	actions = append(actions, chromedp.Navigate(pageURL), waitRendered(), chromedp.OuterHTML("html", &html))
+	if err := chromedp.Run(tab, actions...); err != nil {
+		if ctx.Err() != nil {
+			return 0, "", ctx.Err()
 		}
+		return 0, "", fmt.Errorf("could not render %s: %v", pageURL, err)
 	}
-	log.Debug("Successfully retrieved body")
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	return status, html, nil
+}
 
-	response := &http.Response{
-		Status:        "200 OK",
-		StatusCode:    200,
-		Proto:         "HTTP/1.1",
-		ProtoMajor:    1,
-		ProtoMinor:    1,
-		Body:          ioutil.NopCloser(bytes.NewBufferString(body)),
-		ContentLength: int64(len(body)),
-		Request:       r,
-		Header:        r.Header,
+// Close : Stop the browser
+func (r *ChromeRenderer) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+		r.browser, r.cancel = nil, nil
 	}
-	return response, nil
+	return nil
+}
+
+// renderPollInterval : how often a loading page is checked for changes
+const renderPollInterval = 250 * time.Millisecond
+
+// waitRendered : wait until the page is loaded and its size stopped changing,
+// as scripts listing movies may only run once it is loaded
+func waitRendered() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		last, stable := -1, 0
+		for stable < 2 {
+			var size int
+			if err := chromedp.Evaluate(`document.readyState === "complete" ? document.documentElement.outerHTML.length : -1`, &size).Do(ctx); err != nil {
+				return err
+			}
+			if size >= 0 && size == last {
+				stable++
+			} else {
+				stable = 0
+			}
+			last = size
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(renderPollInterval):
+			}
+		}
+		return nil
+	})
 }
@@ -0,0 +1,192 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Config : configuration of the HTTP client used to reach the source sites
+type Config struct {
+	Proxy      string                  // http, https or socks5 proxy URL e.g socks5://127.0.0.1:9050
+	UserAgents []string                `mapstructure:"user-agents"` // User-Agents rotated between requests
+	Headers    map[string]string       // headers added to every request
+	Engines    map[string]EngineConfig // overrides per engine, keyed by lowercase engine name
+
+	MaxAttempts      int           `mapstructure:"max-attempts"`      // attempts of a failing request, defaults to 3
+	RetryBackoff     time.Duration `mapstructure:"retry-backoff"`     // wait before the first retry, doubled on every retry
+	BreakerThreshold int           `mapstructure:"breaker-threshold"` // consecutive failures before an engine is skipped, defaults to 5
+	BreakerCooldown  time.Duration `mapstructure:"breaker-cooldown"`  // how long an engine is skipped for, defaults to 1m
+
+	RateLimit RateLimit `mapstructure:"rate-limit"` // politeness towards the source sites
+
+	AntiBot    bool          `mapstructure:"anti-bot"`    // keep the cookies of the sites and detect their challenge pages
+	Browser    BrowserConfig `mapstructure:"browser"`     // browser solving challenge pages of anti-bot sites
+	CookieFile string        `mapstructure:"cookie-file"` // file the cookies of anti-bot sites are kept in, in memory when empty
+	Render     bool          // load pages in the browser and scrape them once their scripts ran
+	Tor        TorConfig     // send requests through Tor instead of Proxy
+
+	engine string // engine the config is for, set by For
+}
+
+// EngineConfig : overrides of Config for a single engine
+type EngineConfig struct {
+	Proxy      string
+	UserAgents []string `mapstructure:"user-agents"`
+	Headers    map[string]string
+	RateLimit  *RateLimit `mapstructure:"rate-limit"`
+	AntiBot    *bool      `mapstructure:"anti-bot"`
+	Render     *bool
+}
+
+// RateLimit : limits on the requests made to a source site while scraping
+// e.g to keep List pagination from getting IPs banned
+type RateLimit struct {
+	RequestsPerSecond float64       `mapstructure:"requests-per-second"` // 0 for no limit
+	Parallelism       int           // maximum concurrent requests, 0 for no limit
+	RandomDelay       time.Duration `mapstructure:"random-delay"` // extra random delay added before requests
+}
+
+// For : the configuration to use for engine, with its overrides applied
+func (c Config) For(engine string) Config {
+	c.engine = strings.ToLower(engine)
+	override, ok := c.Engines[c.engine]
+	if !ok {
+		return c
+	}
+	merged := c
+	merged.Headers = map[string]string{}
+	if override.Proxy != "" {
+		merged.Proxy = override.Proxy
+	}
+	if len(override.UserAgents) > 0 {
+		merged.UserAgents = override.UserAgents
+	}
+	if override.RateLimit != nil {
+		merged.RateLimit = *override.RateLimit
+	}
+	if override.AntiBot != nil {
+		merged.AntiBot = *override.AntiBot
+	}
+	if override.Render != nil {
+		merged.Render = *override.Render
+	}
+	for key, val := range c.Headers {
+		merged.Headers[key] = val
+	}
+	for key, val := range override.Headers {
+		merged.Headers[key] = val
+	}
+	return merged
+}
+
+// NewTransport : RoundTripper sending requests through the configured proxy
+// with the configured headers and a rotating User-Agent. Failing requests are
+// retried and, for configs returned by For, the engine is skipped for a while
+// after too many consecutive failures. With AntiBot, challenge pages fail with
+// ErrChallenge unless the browser is enabled to solve them. With Render, pages
+// are rendered by the browser, which is stopped by CloseIdleConnections. With Tor,
+// every engine gets circuits of its own
+func NewTransport(c Config) (*HeaderTransport, error) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	var direct http.RoundTripper = base
+	browserProxy := c.Proxy
+	if c.Tor.Enabled {
+		name := c.engine
+		if name == "" {
+			name = "default"
+		}
+		direct = newTorTransport(base, c.Tor, name)
+		browserProxy = c.Tor.ProxyURL()
+	} else if c.Proxy != "" {
+		proxyURL, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %v", c.Proxy, err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", proxyURL.Scheme)
+		}
+		base.Proxy = http.ProxyURL(proxyURL)
+	}
+	userAgents := c.UserAgents
+	if len(userAgents) == 0 {
+		userAgents = []string{userAgent}
+	}
+	// config files may lowercase header names
+	headers := make(map[string]string, len(c.Headers))
+	for key, val := range c.Headers {
+		headers[http.CanonicalHeaderKey(key)] = val
+	}
+	upstream := direct
+	if c.AntiBot {
+		challenge := &challengeTransport{upstream: direct, store: OpenCookieStore(c.CookieFile)}
+		if c.Browser.Enabled {
+			challenge.solver = &ChromeSolver{Config: c.Browser, Proxy: browserProxy}
+		}
+		upstream = challenge
+	}
+	var renderer *ChromeRenderer
+	if c.Render {
+		renderer = &ChromeRenderer{Config: c.Browser, Proxy: browserProxy}
+		upstream = &renderTransport{upstream: upstream, renderer: renderer}
+	}
+	retry := &retryTransport{
+		upstream:    upstream,
+		maxAttempts: c.MaxAttempts,
+		backoff:     c.RetryBackoff,
+	}
+	if retry.maxAttempts < 1 {
+		retry.maxAttempts = defaultMaxAttempts
+	}
+	if retry.backoff <= 0 {
+		retry.backoff = defaultRetryBackoff
+	}
+	if c.engine != "" {
+		threshold, cooldown := c.BreakerThreshold, c.BreakerCooldown
+		if threshold < 1 {
+			threshold = defaultBreakerThreshold
+		}
+		if cooldown <= 0 {
+			cooldown = defaultBreakerCooldown
+		}
+		retry.breaker = breakerFor(c.engine, threshold, cooldown)
+	}
+	return &HeaderTransport{base: base, upstream: retry, renderer: renderer, headers: headers, userAgents: userAgents}, nil
+}
+
+// HeaderTransport : adds headers and a rotating User-Agent to every request
+type HeaderTransport struct {
+	base       *http.Transport
+	upstream   http.RoundTripper
+	renderer   *ChromeRenderer // nil unless pages are rendered
+	headers    map[string]string
+	userAgents []string
+	next       uint32
+}
+
+// RoundTrip : send the request with the configured headers
+func (t *HeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, val := range t.headers {
+		req.Header.Set(key, val)
+	}
+	if _, ok := t.headers["User-Agent"]; !ok {
+		i := atomic.AddUint32(&t.next, 1) - 1
+		req.Header.Set("User-Agent", t.userAgents[int(i)%len(t.userAgents)])
+	}
+	return t.upstream.RoundTrip(req)
+}
+
+// CloseIdleConnections : close the idle connections of the underlying transport
+// and stop the browser rendering pages
+func (t *HeaderTransport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+	if t.renderer != nil {
+		t.renderer.Close()
+	}
+}
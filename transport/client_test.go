@@ -0,0 +1,230 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClientConfig(t *testing.T) {
+	config := Config{
+		UserAgents: []string{"agent-1", "agent-2"},
+		Headers:    map[string]string{"accept-language": "en"},
+		Engines: map[string]EngineConfig{
+			"netnaija": {Headers: map[string]string{"Referer": "https://www.thenetnaija.com/"}},
+		},
+	}
+
+	var got []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Clone())
+	}))
+	defer server.Close()
+
+	rt, err := NewTransport(config.For("NetNaija"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: rt}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if got[0].Get("User-Agent") != "agent-1" || got[1].Get("User-Agent") != "agent-2" {
+		t.Errorf("Expected User-Agents to rotate, got %q and %q", got[0].Get("User-Agent"), got[1].Get("User-Agent"))
+	}
+	if got[0].Get("Accept-Language") != "en" || got[0].Get("Referer") != "https://www.thenetnaija.com/" {
+		t.Errorf("Expected global and engine headers, got %v", got[0])
+	}
+
+	if _, err = NewTransport(Config{Proxy: "ftp://127.0.0.1:21"}); err == nil {
+		t.Errorf("Expected unsupported proxy scheme to fail")
+	}
+	if _, err = NewTransport(Config{Proxy: "socks5://127.0.0.1:9050"}); err != nil {
+		t.Errorf("Expected socks5 proxy to be supported, got %v", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	rt, err := NewTransport(Config{RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: rt}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || requests != 3 {
+		t.Errorf("Expected success after 3 attempts, got %d after %d", resp.StatusCode, requests)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	config := Config{MaxAttempts: 1, BreakerThreshold: 2, BreakerCooldown: time.Hour}
+	rt, err := NewTransport(config.For("dead"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: rt}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// the breaker is shared by every transport of the engine
+	rt, _ = NewTransport(config.For("dead"))
+	if _, err = (&http.Client{Transport: rt}).Get(server.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected open circuit, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected requests to be skipped while the circuit is open, got %d requests", requests)
+	}
+}
+
+// stubSolver : grants the cookie the challenge asks for
+type stubSolver struct {
+	solved int
+}
+
+func (s *stubSolver) Solve(ctx context.Context, pageURL string) ([]*http.Cookie, string, error) {
+	s.solved++
+	return []*http.Cookie{{Name: "cf_clearance", Value: "granted", Expires: time.Now().Add(time.Hour)}}, "solver-agent", nil
+}
+
+func TestChallenge(t *testing.T) {
+	challenged := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("cf_clearance")
+		if err != nil || cookie.Value != "granted" || r.UserAgent() != "solver-agent" {
+			challenged++
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `<html><head><title>Just a moment...</title></head><body><script>window._cf_chl_opt={}</script></body></html>`)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>movies</body></html>`)
+	}))
+	defer server.Close()
+
+	// without a browser challenges fail instead of returning the challenge page
+	client, err := NewTransport(Config{AntiBot: true, MaxAttempts: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	if _, err = client.RoundTrip(req); !errors.Is(err, ErrChallenge) || challenged != 1 {
+		t.Errorf("Expected a single challenged request to fail, got %v after %d", err, challenged)
+	}
+
+	cookieFile := filepath.Join(t.TempDir(), "cookies.json")
+	solver := &stubSolver{}
+	transport := &challengeTransport{upstream: http.DefaultTransport, store: OpenCookieStore(cookieFile), solver: solver}
+	for i := 0; i < 2; i++ {
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != `<html><body>movies</body></html>` {
+			t.Errorf("Expected the page once solved, got %s", body)
+		}
+	}
+	if solver.solved != 1 {
+		t.Errorf("Expected the cookies to be reused, solved %d times", solver.solved)
+	}
+
+	// the granted cookies are kept for later runs
+	storesMu.Lock()
+	delete(stores, cookieFile)
+	storesMu.Unlock()
+	u, _ := url.Parse(server.URL)
+	store := OpenCookieStore(cookieFile)
+	if cookies := store.Cookies(u); len(cookies) != 2 || store.UserAgent(u.Hostname()) != "solver-agent" {
+		t.Errorf("Expected the cookies and User-Agent to be kept, got %v", cookies)
+	}
+
+	// regular pages using the challenge scripts of Cloudflare are not challenges
+	page := &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"text/html"}}}
+	if IsChallenge(page, []byte(`<script src="/cdn-cgi/challenge-platform/scripts/jsd/main.js"></script>`)) {
+		t.Error("Expected a regular page not to be a challenge")
+	}
+}
+
+// stubRenderer : renders every page as the page it was asked for
+type stubRenderer struct {
+	rendered []string
+}
+
+func (r *stubRenderer) Render(ctx context.Context, pageURL, userAgent string) (int, string, error) {
+	r.rendered = append(r.rendered, pageURL)
+	return 0, fmt.Sprintf(`<html><body><div class="movie">%s as %s</div></body></html>`, pageURL, userAgent), nil
+}
+
+func TestRender(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "file")
+	}))
+	defer server.Close()
+	renderer := &stubRenderer{}
+	transport := &renderTransport{upstream: http.DefaultTransport, renderer: renderer}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/movies.php?page=2", nil)
+	req.Header.Set("User-Agent", "agent-1")
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	want := fmt.Sprintf(`<html><body><div class="movie">%s/movies.php?page=2 as agent-1</div></body></html>`, server.URL)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/html; charset=utf-8" || string(body) != want {
+		t.Errorf("Expected the rendered page, got %d %s", resp.StatusCode, body)
+	}
+
+	// files and other methods are not rendered
+	for _, r := range []struct{ method, path string }{{http.MethodGet, "/movie.mp4"}, {http.MethodHead, "/movies"}} {
+		req, _ := http.NewRequest(r.method, server.URL+r.path, nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if len(renderer.rendered) != 1 {
+		t.Errorf("Expected only the page to be rendered, got %v", renderer.rendered)
+	}
+}
@@ -0,0 +1,58 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Renderer : Renders pages the way a browser shows them, running their scripts
+type Renderer interface {
+	Render(ctx context.Context, pageURL, userAgent string) (status int, html string, err error)
+}
+
+// pageExtensions : extensions of the paths of pages, other files are not rendered
+var pageExtensions = map[string]bool{
+	"": true, ".html": true, ".htm": true, ".shtml": true, ".php": true, ".asp": true, ".aspx": true, ".jsp": true,
+}
+
+// renderable : whether u is a page rather than a file such as a video
+func renderable(u *url.URL) bool {
+	return pageExtensions[strings.ToLower(path.Ext(u.Path))]
+}
+
+// renderTransport : answers GET requests of pages with the page rendered by a
+// Renderer, so sites listing movies with JavaScript are scraped like any other.
+// Other requests are sent upstream
+type renderTransport struct {
+	upstream http.RoundTripper
+	renderer Renderer
+}
+
+func (t *renderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || !renderable(req.URL) {
+		return t.upstream.RoundTrip(req)
+	}
+	status, page, err := t.renderer.Render(req.Context(), req.URL.String(), req.Header.Get("User-Agent"))
+	if err != nil {
+		return nil, err
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		Body:          ioutil.NopCloser(strings.NewReader(page)),
+		ContentLength: int64(len(page)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,148 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen : requests of an engine are skipped after too many consecutive failures
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Defaults of the retry policy and circuit breaker
+const (
+	defaultMaxAttempts      = 3
+	defaultRetryBackoff     = 500 * time.Millisecond
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = time.Minute
+)
+
+// retryTransport : retries requests failing with transient errors using
+// exponential backoff with jitter
+type retryTransport struct {
+	upstream    http.RoundTripper
+	maxAttempts int
+	backoff     time.Duration
+	breaker     *breaker
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.breaker != nil && !t.breaker.allow() {
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, req.URL.Host)
+	}
+	var (
+		resp *http.Response
+		err  error
+	)
+	for attempt := 1; ; attempt++ {
+		resp, err = t.upstream.RoundTrip(req)
+		if !retryable(resp, err) || attempt >= t.maxAttempts || req.Context().Err() != nil {
+			break
+		}
+		// the body of a request can only be sent again if it can be rebuilt
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				break
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				break
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		wait := t.backoff << uint(attempt-1)
+		wait += time.Duration(rand.Int63n(int64(wait)/2 + 1))
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+	if t.breaker != nil && req.Context().Err() == nil {
+		t.breaker.record(!retryable(resp, err))
+	}
+	return resp, err
+}
+
+// retryable : whether a request failed in a way that could succeed when retried
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		// challenges are solved once and unknown hosts will stay unknown
+		if errors.Is(err, ErrChallenge) {
+			return false
+		}
+		var dnsErr *net.DNSError
+		return !errors.As(err, &dnsErr) || dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// breaker : circuit breaker opening after threshold consecutive failures
+// and letting a request through again once cooldown has passed
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*breaker{}
+)
+
+// breakerFor : the circuit breaker of an engine, shared by all its transports
+func breakerFor(engine string, threshold int, cooldown time.Duration) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[engine]
+	if !ok {
+		b = &breaker{}
+		breakers[engine] = b
+	}
+	b.mu.Lock()
+	b.threshold, b.cooldown = threshold, cooldown
+	b.mu.Unlock()
+	return b
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() || time.Now().After(b.openUntil) {
+		return true
+	}
+	return false
+}
+
+func (b *breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+	b.failures++
+	// once open, a single failure after the cooldown opens the circuit again
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,176 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Defaults of the routing through Tor
+const (
+	DefaultTorProxy    = "127.0.0.1:9050"
+	defaultTorRenewals = 2
+	torCircuitUser     = "gophie-"
+)
+
+// TorConfig : routing of requests through a local Tor, for sites which are
+// blocked where gophie runs
+type TorConfig struct {
+	Enabled  bool
+	Proxy    string // SOCKS address of Tor, defaults to 127.0.0.1:9050
+	Renewals int    // new circuits tried when a site bans a request, defaults to 2
+}
+
+// address : the SOCKS address of Tor
+func (c TorConfig) address() string {
+	if c.Proxy == "" {
+		return DefaultTorProxy
+	}
+	return c.Proxy
+}
+
+// ProxyURL : the SOCKS URL of Tor, for browsers which use a single circuit
+func (c TorConfig) ProxyURL() string {
+	return "socks5://" + c.address()
+}
+
+// CheckTor : Whether Tor accepts connections at the SOCKS address of c
+func CheckTor(c TorConfig) error {
+	conn, err := net.DialTimeout("tcp", c.address(), 2*time.Second)
+	if err != nil {
+		return fmt.Errorf("Tor is not running at %s: %w", c.address(), err)
+	}
+	return conn.Close()
+}
+
+// circuit : The Tor circuit of an engine. Tor isolates streams with different
+// SOCKS credentials on different circuits, so every engine gets its own circuits
+// and bumping the generation of its credentials moves it to a new one
+type circuit struct {
+	mu         sync.Mutex
+	name       string
+	generation int
+}
+
+var (
+	circuitsMu sync.Mutex
+	circuits   = map[string]*circuit{}
+)
+
+// circuitFor : the circuit of name, shared by all its transports
+func circuitFor(name string) *circuit {
+	circuitsMu.Lock()
+	defer circuitsMu.Unlock()
+	c, ok := circuits[name]
+	if !ok {
+		c = &circuit{name: name}
+		circuits[name] = c
+	}
+	return c
+}
+
+func (c *circuit) current() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// renew : move to a new circuit unless another request already did since generation
+func (c *circuit) renew(generation int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.generation++
+		log.Debugf("Renewed the Tor circuit of %s", c.name)
+	}
+}
+
+// proxyURL : the SOCKS URL of Tor isolating the requests of generation
+func (c *circuit) proxyURL(address string, generation int) *url.URL {
+	return &url.URL{
+		Scheme: "socks5",
+		Host:   address,
+		User:   url.UserPassword(torCircuitUser+c.name, strconv.Itoa(generation)),
+	}
+}
+
+type generationKey struct{}
+
+// torTransport : sends requests through the circuit of an engine, moving to a new
+// circuit when a site bans the exit of the current one
+type torTransport struct {
+	base     *http.Transport
+	circuit  *circuit
+	renewals int
+}
+
+// NewTorTransport : RoundTripper sending requests through Tor on circuits of their
+// own, named by circuit, which are renewed when a site bans them
+func NewTorTransport(c TorConfig, circuit string) http.RoundTripper {
+	return newTorTransport(http.DefaultTransport.(*http.Transport).Clone(), c, circuit)
+}
+
+func newTorTransport(base *http.Transport, c TorConfig, name string) *torTransport {
+	t := &torTransport{base: base, circuit: circuitFor(name), renewals: c.Renewals}
+	if t.renewals <= 0 {
+		t.renewals = defaultTorRenewals
+	}
+	address := c.address()
+	// connections are kept per proxy URL, so new credentials open new connections
+	base.Proxy = func(req *http.Request) (*url.URL, error) {
+		generation, _ := req.Context().Value(generationKey{}).(int)
+		return t.circuit.proxyURL(address, generation), nil
+	}
+	return t
+}
+
+func (t *torTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for renewal := 0; ; renewal++ {
+		generation := t.circuit.current()
+		resp, err := t.base.RoundTrip(req.WithContext(context.WithValue(ctx, generationKey{}, generation)))
+		if !banned(resp, err) || renewal >= t.renewals || ctx.Err() != nil {
+			return resp, err
+		}
+		// the body of a request can only be sent again if it can be rebuilt
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		t.circuit.renew(generation)
+	}
+}
+
+// banned : whether the site refused the exit of the circuit, or the circuit failed
+func banned(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+// CloseIdleConnections : close the idle connections to Tor
+func (t *torTransport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+}
@@ -0,0 +1,136 @@
+package transport
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// socksServer : a SOCKS5 proxy standing in for Tor, keeping the credentials of
+// the connections made through it
+type socksServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	users    []string
+}
+
+func newSocksServer(t *testing.T) *socksServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &socksServer{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *socksServer) serve(conn net.Conn) {
+	defer conn.Close()
+	buf := make([]byte, 262)
+	// greeting, username and password authentication
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	io.ReadFull(conn, buf[:buf[1]])
+	conn.Write([]byte{5, 2})
+	io.ReadFull(conn, buf[:2])
+	user := make([]byte, buf[1])
+	io.ReadFull(conn, user)
+	io.ReadFull(conn, buf[:1])
+	password := make([]byte, buf[0])
+	io.ReadFull(conn, password)
+	conn.Write([]byte{1, 0})
+	s.mu.Lock()
+	s.users = append(s.users, string(user)+":"+string(password))
+	s.mu.Unlock()
+
+	// connect request
+	io.ReadFull(conn, buf[:4])
+	var host string
+	switch buf[3] {
+	case 1:
+		io.ReadFull(conn, buf[:4])
+		host = net.IP(buf[:4]).String()
+	case 3:
+		io.ReadFull(conn, buf[:1])
+		name := make([]byte, buf[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		return
+	}
+	io.ReadFull(conn, buf[:2])
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(buf[:2])))))
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+func TestTor(t *testing.T) {
+	socks := newSocksServer(t)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the exit of the first circuit is banned
+		if requests++; requests == 1 {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	config := Config{Tor: TorConfig{Enabled: true, Proxy: socks.listener.Addr().String()}}
+	if err := CheckTor(config.Tor); err != nil {
+		t.Fatal(err)
+	}
+	for _, engine := range []string{"TorNaija", "TorMovies"} {
+		rt, err := NewTransport(config.For(engine))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := (&http.Client{Transport: rt}).Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected %s to succeed on a new circuit, got %s", engine, resp.Status)
+		}
+		rt.CloseIdleConnections()
+	}
+
+	// engines are isolated on circuits of their own, renewed with new credentials
+	want := []string{"gophie-tornaija:0", "gophie-tornaija:1", "gophie-tormovies:0"}
+	socks.mu.Lock()
+	defer socks.mu.Unlock()
+	if len(socks.users) != len(want) {
+		t.Fatalf("Expected circuits %v, got %v", want, socks.users)
+	}
+	for i := range want {
+		if socks.users[i] != want[i] {
+			t.Errorf("Expected circuits %v, got %v", want, socks.users)
+			break
+		}
+	}
+
+	if err := CheckTor(TorConfig{Proxy: "127.0.0.1:1"}); err == nil {
+		t.Error("Expected Tor not to be running on port 1")
+	}
+}
@@ -0,0 +1,154 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-phie/gophie/desktop"
+	"github.com/go-phie/gophie/engine"
+)
+
+// Most movies listed in a notification
+const maxListed = 10
+
+// Notifier : a way of telling about new uploads found for a query
+type Notifier interface {
+	Notify(ctx context.Context, query string, movies []engine.Movie) error
+	String() string
+}
+
+// message : Describe the new uploads found for query
+func message(query string, movies []engine.Movie, links bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d new uploads for %s", len(movies), query)
+	for i, movie := range movies {
+		if i == maxListed {
+			fmt.Fprintf(&b, "\n...and %d more", len(movies)-maxListed)
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s (%s)", movie.Title, movie.Source)
+		if links && movie.DownloadLink != nil {
+			fmt.Fprintf(&b, " %s", movie.DownloadLink)
+		}
+	}
+	return b.String()
+}
+
+// Desktop : Notifies with the notification center of the desktop
+type Desktop struct{}
+
+func (Desktop) String() string {
+	return "desktop notifications"
+}
+
+// Notify : show a desktop notification listing the new uploads
+func (Desktop) Notify(ctx context.Context, query string, movies []engine.Movie) error {
+	title := fmt.Sprintf("Gophie: %d new uploads for %s", len(movies), query)
+	body := strings.SplitN(message(query, movies, false), "\n", 2)
+	text := ""
+	if len(body) > 1 {
+		text = body[1]
+	}
+	return desktop.Notify(title, text)
+}
+
+// Webhook : Notifies by posting the new uploads as JSON to a URL
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *Webhook) String() string {
+	return "webhook " + w.URL
+}
+
+// Notify : POST {"Query": query, "Movies": movies} to the webhook URL
+func (w *Webhook) Notify(ctx context.Context, query string, movies []engine.Movie) error {
+	body, err := json.Marshal(struct {
+		Query  string
+		Movies []engine.Movie
+	}{query, movies})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client(w.Client).Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// Telegram : Notifies by sending a message from a Telegram bot to a chat
+type Telegram struct {
+	Token   string
+	ChatID  string
+	BaseURL string // defaults to https://api.telegram.org
+	Client  *http.Client
+}
+
+func (t *Telegram) String() string {
+	return "telegram chat " + t.ChatID
+}
+
+// Notify : send the new uploads with their download links to the chat
+func (t *Telegram) Notify(ctx context.Context, query string, movies []engine.Movie) error {
+	base := t.BaseURL
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	form := url.Values{
+		"chat_id":                  {t.ChatID},
+		"text":                     {message(query, movies, true)},
+		"disable_web_page_preview": {"true"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimSuffix(base, "/"), t.Token),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client(t.Client).Do(req)
+	if err != nil {
+		// the URL holds the bot token, keep it out of logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram: %w", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		OK          bool
+		Description string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("telegram responded with %s", resp.Status)
+	}
+	if !body.OK {
+		return fmt.Errorf("telegram: %s", body.Description)
+	}
+	return nil
+}
+
+func client(c *http.Client) *http.Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c
+}
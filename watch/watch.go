@@ -0,0 +1,210 @@
+// Package watch periodically looks for new uploads matching a query on engines
+// and notifies when one appears
+package watch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultInterval : how often engines are checked for new uploads
+const DefaultInterval = 30 * time.Minute
+
+var seenBucket = []byte("seen")
+
+// Seen : A persistent record of the movies already found for queries
+type Seen struct {
+	db *bolt.DB
+}
+
+// OpenSeen : Open (or create) the record of seen movies at path
+func OpenSeen(path string) (*Seen, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(seenBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Seen{db: db}, nil
+}
+
+// movieKey : identity of a movie found for query
+func movieKey(query string, movie engine.Movie) []byte {
+	id := movie.Title
+	if movie.DownloadLink != nil {
+		id = movie.DownloadLink.String()
+	}
+	return []byte(strings.ToLower(query) + "|" + strings.ToLower(movie.Source) + "|" + id)
+}
+
+// queryKey : marks that query has been checked before
+func queryKey(query string) []byte {
+	return []byte(strings.ToLower(query))
+}
+
+// Diff : Record the movies found for query and return the ones not seen before
+// Nothing is new the first time a query is checked, so only later uploads are reported
+func (s *Seen) Diff(query string, movies []engine.Movie) ([]engine.Movie, error) {
+	var unseen []engine.Movie
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(seenBucket)
+		first := b.Get(queryKey(query)) == nil
+		now := []byte(time.Now().Format(time.RFC3339))
+		for _, movie := range movies {
+			key := movieKey(query, movie)
+			if b.Get(key) != nil {
+				continue
+			}
+			if !first {
+				unseen = append(unseen, movie)
+			}
+			if err := b.Put(key, now); err != nil {
+				return err
+			}
+		}
+		return b.Put(queryKey(query), now)
+	})
+	return unseen, err
+}
+
+// Close : Close the underlying database
+func (s *Seen) Close() error {
+	return s.db.Close()
+}
+
+// Watcher : Checks engines for new uploads matching a query
+type Watcher struct {
+	Query     string
+	Engines   map[string]engine.Engine
+	Interval  time.Duration
+	Seen      *Seen
+	Notifiers []Notifier
+	// Filter narrows down the movies found e.g by quality, nil keeps every movie
+	Filter func(engine.SearchResult) engine.SearchResult
+	// New : when set, every check uses a new engine of each name instead of the
+	// one in Engines, so that nothing an engine keeps between checks carries over
+	New func(name string) (engine.Engine, error)
+}
+
+// Matches : whether every word of query is in title
+func Matches(title, query string) bool {
+	title = strings.ToLower(title)
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(title, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// find : The movies matching the query among the recent uploads and the search
+// results of every engine. Engines which fail are skipped unless all of them fail.
+// The sites are scraped every time, new uploads are not in the cached pages
+func (w *Watcher) find(ctx context.Context) (engine.SearchResult, error) {
+	var failed int
+	ctx = engine.WithRefresh(ctx)
+	result := engine.SearchResult{Query: w.Query}
+	found := map[string]bool{}
+	add := func(movies []engine.Movie) {
+		for _, movie := range movies {
+			key := string(movieKey(w.Query, movie))
+			if !found[key] && Matches(movie.Title, w.Query) {
+				found[key] = true
+				result.Movies = append(result.Movies, movie)
+			}
+		}
+	}
+	for name, e := range w.Engines {
+		if ctx.Err() != nil {
+			break
+		}
+		if w.New != nil {
+			fresh, err := w.New(name)
+			if err != nil {
+				log.Warnf("Could not create engine %s, checking the previous one: %v", name, err)
+			} else {
+				e = fresh
+			}
+		}
+		recent, listErr := e.List(ctx, 1)
+		if listErr != nil {
+			log.Warnf("Could not list %s: %v", name, listErr)
+		}
+		add(recent.Movies)
+		searched, searchErr := e.Search(ctx, w.Query)
+		if searchErr != nil {
+			log.Warnf("Could not search %s: %v", name, searchErr)
+		}
+		add(searched.Movies)
+		if listErr != nil && searchErr != nil {
+			failed++
+		}
+	}
+	if failed > 0 && failed == len(w.Engines) {
+		return result, fmt.Errorf("%w: every engine failed", engine.ErrEngineUnavailable)
+	}
+	if w.Filter != nil {
+		result = w.Filter(result)
+	}
+	return result, nil
+}
+
+// Check : Look for new uploads once and notify of the ones found
+func (w *Watcher) Check(ctx context.Context) ([]engine.Movie, error) {
+	result, err := w.find(ctx)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		// not recording anything so a failing first check does not report every movie later
+		return nil, err
+	}
+	unseen, err := w.Seen.Diff(w.Query, result.Movies)
+	if err != nil || len(unseen) == 0 {
+		return unseen, err
+	}
+	for _, notifier := range w.Notifiers {
+		if err := notifier.Notify(ctx, w.Query, unseen); err != nil {
+			log.Errorf("Could not notify with %s: %v", notifier, err)
+		}
+	}
+	return unseen, nil
+}
+
+// Run : Check for new uploads every Interval until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		unseen, err := w.Check(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Error(err)
+		} else {
+			log.Infof("Found %d new uploads for %s", len(unseen), w.Query)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+// fakeEngine : an engine returning fixed recent uploads and search results
+type fakeEngine struct {
+	recent, searched []engine.Movie
+	err              error
+	cached           int // lists and searches which could be answered from a cache
+}
+
+func (e *fakeEngine) Search(ctx context.Context, param ...string) (engine.SearchResult, error) {
+	if !engine.Refreshing(ctx) {
+		e.cached++
+	}
+	return engine.SearchResult{Query: param[0], Movies: e.searched}, e.err
+}
+
+func (e *fakeEngine) List(ctx context.Context, page int) (engine.SearchResult, error) {
+	if !engine.Refreshing(ctx) {
+		e.cached++
+	}
+	return engine.SearchResult{Movies: e.recent}, e.err
+}
+
+func (e *fakeEngine) Capabilities() engine.Capabilities {
+	return engine.Capabilities{}
+}
+
+func (e *fakeEngine) String() string {
+	return "Fake"
+}
+
+// recorder : a notifier remembering what it was notified of
+type recorder struct {
+	notified [][]engine.Movie
+}
+
+func (r *recorder) Notify(ctx context.Context, query string, movies []engine.Movie) error {
+	r.notified = append(r.notified, movies)
+	return nil
+}
+
+func (r *recorder) String() string {
+	return "recorder"
+}
+
+func movie(title, link string) engine.Movie {
+	u, _ := url.Parse(link)
+	return engine.Movie{Title: title, DownloadLink: u, Source: "Fake"}
+}
+
+func TestWatcher(t *testing.T) {
+	seen, err := OpenSeen(filepath.Join(t.TempDir(), "watch.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer seen.Close()
+
+	fake := &fakeEngine{
+		recent:   []engine.Movie{movie("The Batman (2022)", "https://fake/1"), movie("Jumanji", "https://fake/2")},
+		searched: []engine.Movie{movie("Batman Begins (2005)", "https://fake/3")},
+	}
+	notified := &recorder{}
+	w := &Watcher{
+		Query:     "batman",
+		Engines:   map[string]engine.Engine{"fake": fake},
+		Seen:      seen,
+		Notifiers: []Notifier{notified},
+	}
+
+	// movies already uploaded when the watch starts are not new
+	if unseen, err := w.Check(context.Background()); err != nil || len(unseen) != 0 {
+		t.Fatalf("Expected the first check to only record movies, got %v %v", unseen, err)
+	}
+	fake.recent = append(fake.recent, movie("The Batman (2022) 1080p", "https://fake/4"), movie("Dune", "https://fake/5"))
+	unseen, err := w.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unseen) != 1 || unseen[0].Title != "The Batman (2022) 1080p" {
+		t.Errorf("Expected the new matching upload, got %v", unseen)
+	}
+	if len(notified.notified) != 1 || len(notified.notified[0]) != 1 {
+		t.Errorf("Expected one notification of one movie, got %v", notified.notified)
+	}
+	if unseen, _ := w.Check(context.Background()); len(unseen) != 0 {
+		t.Errorf("Expected movies to be notified once, got %v", unseen)
+	}
+	if fake.cached != 0 {
+		t.Errorf("Expected every check to scrape the sites, %d could be cached", fake.cached)
+	}
+
+	// checks use new engines when they can be created
+	w.New = func(name string) (engine.Engine, error) {
+		return &fakeEngine{recent: []engine.Movie{movie("Batman Begins", "https://fake/6")}}, nil
+	}
+	if unseen, _ := w.Check(context.Background()); len(unseen) != 1 || unseen[0].Title != "Batman Begins" {
+		t.Errorf("Expected the upload of the new engine, got %v", unseen)
+	}
+	w.New = nil
+
+	// nothing is recorded when every engine fails
+	down := &Watcher{Query: "dune", Engines: map[string]engine.Engine{"fake": &fakeEngine{err: errors.New("down")}}, Seen: seen}
+	if _, err := down.Check(context.Background()); !errors.Is(err, engine.ErrEngineUnavailable) {
+		t.Errorf("Expected the check to fail, got %v", err)
+	}
+	w.Query = "dune"
+	if unseen, _ := w.Check(context.Background()); len(unseen) != 0 {
+		t.Errorf("Expected a failed check not to count as the first check, got %v", unseen)
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	var (
+		webhook  map[string]json.RawMessage
+		telegram url.Values
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hook":
+			json.NewDecoder(r.Body).Decode(&webhook)
+		case "/bottoken/sendMessage":
+			r.ParseForm()
+			telegram = r.PostForm
+			w.Write([]byte(`{"ok":true}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	movies := []engine.Movie{movie("The Batman (2022)", "https://fake/1")}
+	hook := &Webhook{URL: server.URL + "/hook"}
+	if err := hook.Notify(context.Background(), "batman", movies); err != nil {
+		t.Fatal(err)
+	}
+	if string(webhook["Query"]) != `"batman"` || !strings.Contains(string(webhook["Movies"]), `"DownloadLink":"https://fake/1"`) {
+		t.Errorf("Unexpected webhook payload %v", webhook)
+	}
+
+	bot := &Telegram{Token: "token", ChatID: "42", BaseURL: server.URL}
+	if err := bot.Notify(context.Background(), "batman", movies); err != nil {
+		t.Fatal(err)
+	}
+	if telegram.Get("chat_id") != "42" || !strings.Contains(telegram.Get("text"), "The Batman (2022) (Fake) https://fake/1") {
+		t.Errorf("Unexpected telegram message %v", telegram)
+	}
+	bot.Token = "wrong"
+	if err := bot.Notify(context.Background(), "batman", movies); err == nil {
+		t.Error("Expected an error for an unknown bot")
+	}
+}
@@ -0,0 +1,211 @@
+// Package webhook posts signed JSON events to the URLs users configure when
+// downloads complete or fail and when watched titles are uploaded, so that
+// gophie can trigger home automation or the library rescans of media servers
+// such as Jellyfin and Plex
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+// Event : what happened
+type Event string
+
+// Events hooks subscribe to
+const (
+	DownloadCompleted Event = "download.completed"
+	DownloadFailed    Event = "download.failed"
+	WatchMatched      Event = "watch.matched" // a watched title was uploaded
+)
+
+// Events : every event, in the order they are documented
+var Events = []Event{DownloadCompleted, DownloadFailed, WatchMatched}
+
+// SignatureHeader : the header of the HMAC-SHA256 of the body of events, as
+// sha256=<hex>, keyed with the secret of the hook
+const SignatureHeader = "X-Gophie-Signature"
+
+// EventHeader : the header naming the event posted
+const EventHeader = "X-Gophie-Event"
+
+// attempts : posts of an event to a failing hook
+const attempts = 3
+
+// Hook : A URL events are posted to
+type Hook struct {
+	URL    string
+	Secret string  // signs the events posted, they are not signed when empty
+	Events []Event // the events posted, every event when empty
+}
+
+// Subscribed : whether the hook is posted event
+func (h Hook) Subscribed(event Event) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate : an error unless the hook has an http(s) URL and known events
+func (h Hook) Validate() error {
+	if !strings.HasPrefix(h.URL, "http://") && !strings.HasPrefix(h.URL, "https://") {
+		return fmt.Errorf("webhook URL %q must be an http or https URL", h.URL)
+	}
+	for _, e := range h.Events {
+		known := false
+		for _, event := range Events {
+			known = known || e == event
+		}
+		if !known {
+			return fmt.Errorf("unknown webhook event %q, use one of %v", e, Events)
+		}
+	}
+	return nil
+}
+
+// Payload : The JSON body of an event, movies are marshalled like those of the API
+type Payload struct {
+	Event  Event
+	Time   time.Time
+	Movie  *engine.Movie  `json:",omitempty"` // downloaded, or whose download failed
+	Path   string         `json:",omitempty"` // where the movie was downloaded
+	Error  string         `json:",omitempty"` // why the download failed
+	Query  string         `json:",omitempty"` // watched
+	Movies []engine.Movie `json:",omitempty"` // uploads matching Query
+}
+
+// Sign : the signature of body with secret, the value of SignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify : whether signature is the signature of body with secret, for receivers
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Dispatcher : Posts events to the hooks subscribed to them
+type Dispatcher struct {
+	Hooks   []Hook
+	Client  *http.Client
+	Backoff time.Duration // wait before posting again to a failing hook, doubled every time
+}
+
+// NewDispatcher : A dispatcher of events to hooks
+func NewDispatcher(hooks []Hook) *Dispatcher {
+	return &Dispatcher{Hooks: hooks, Client: &http.Client{Timeout: 30 * time.Second}, Backoff: time.Second}
+}
+
+// Subscribed : whether any of the hooks is posted event
+func (d *Dispatcher) Subscribed(event Event) bool {
+	for _, hook := range d.Hooks {
+		if hook.Subscribed(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send : Post p to every hook subscribed to its event, retrying failing hooks.
+// The errors of the hooks which could not be reached are returned together
+func (d *Dispatcher) Send(ctx context.Context, p Payload) error {
+	if p.Time.IsZero() {
+		p.Time = time.Now()
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	var errs []string
+	for _, hook := range d.Hooks {
+		if !hook.Subscribed(p.Event) {
+			continue
+		}
+		if err := d.post(ctx, hook, p.Event, body); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// post : POST body to hook, up to attempts times while it fails
+func (d *Dispatcher) post(ctx context.Context, hook Hook, event Event, body []byte) error {
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	backoff := d.Backoff
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = send(ctx, client, hook, event, body); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return fmt.Errorf("webhook %s: %w", hook.URL, err)
+}
+
+func send(ctx context.Context, client *http.Client, hook Hook, event Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(event))
+	if hook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(hook.Secret, body))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// WatchNotifier : A watch.Notifier posting the uploads found as WatchMatched events
+type WatchNotifier struct {
+	*Dispatcher
+}
+
+func (n WatchNotifier) String() string {
+	return fmt.Sprintf("%d webhooks", len(n.Hooks))
+}
+
+// Notify : post the uploads matching query
+func (n WatchNotifier) Notify(ctx context.Context, query string, movies []engine.Movie) error {
+	return n.Send(ctx, Payload{Event: WatchMatched, Query: query, Movies: movies})
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-phie/gophie/engine"
+)
+
+func TestDispatcher(t *testing.T) {
+	type received struct {
+		event     string
+		signature string
+		body      []byte
+	}
+	posted := make(chan received, 4)
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/flaky" && failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		posted <- received{r.Header.Get(EventHeader), r.Header.Get(SignatureHeader), body}
+	}))
+	defer server.Close()
+
+	d := NewDispatcher([]Hook{
+		{URL: server.URL + "/flaky", Secret: "s3cret", Events: []Event{DownloadCompleted}},
+		{URL: server.URL + "/watch", Events: []Event{WatchMatched}},
+	})
+	d.Backoff = 0
+	link, _ := url.Parse("https://downloads.example/jumanji.mp4")
+	err := d.Send(context.Background(), Payload{Event: DownloadCompleted, Movie: &engine.Movie{Title: "Jumanji", DownloadLink: link}, Path: "/movies/Jumanji.mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := <-posted
+	if got.event != string(DownloadCompleted) || !Verify("s3cret", got.body, got.signature) {
+		t.Errorf("Expected a signed download.completed event, got %+v", got)
+	}
+	var p struct {
+		Event Event
+		Path  string
+		Movie struct{ Title, DownloadLink string }
+	}
+	json.Unmarshal(got.body, &p)
+	if p.Path != "/movies/Jumanji.mp4" || p.Movie.DownloadLink != link.String() {
+		t.Errorf("Expected the movie in the payload, got %s", got.body)
+	}
+	if len(posted) != 0 {
+		t.Errorf("Expected hooks not subscribed to the event to be skipped")
+	}
+
+	if err = (WatchNotifier{d}).Notify(context.Background(), "jumanji", []engine.Movie{{Title: "Jumanji", DownloadLink: link}}); err != nil {
+		t.Fatal(err)
+	}
+	if got = <-posted; got.event != string(WatchMatched) || got.signature != "" {
+		t.Errorf("Expected an unsigned watch.matched event, got %+v", got)
+	}
+
+	if err = (Hook{URL: server.URL, Events: []Event{"download.started"}}).Validate(); err == nil {
+		t.Errorf("Expected unknown events to be refused")
+	}
+}